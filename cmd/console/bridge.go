@@ -104,6 +104,38 @@ func (b *bridge) Send(call otto.FunctionCall) (response otto.Value) {
 	return response
 }
 
+// Call invokes the RPC method with the params in an array, it returns the result or throws the error.
+func (b *bridge) Call(call otto.FunctionCall) otto.Value {
+	method, err := call.Argument(0).ToString()
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	paramsVal, err := JSON.Call("stringify", call.Argument(1))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	var params []interface{}
+	dec := json.NewDecoder(strings.NewReader(paramsVal.String()))
+	dec.UseNumber() // avoid float64s
+	if err := dec.Decode(&params); err != nil {
+		throwJSException(err.Error())
+	}
+
+	var result json.RawMessage
+	if err := b.client.Call(&result, method, params...); err != nil {
+		throwJSException(err.Error())
+	}
+	if result == nil {
+		return otto.NullValue()
+	}
+	resultVal, err := JSON.Call("parse", string(result))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return resultVal
+}
+
 func setError(resp *otto.Object, code int, msg string) {
 	resp.Set("error", map[string]interface{}{"code": code, "message": msg})
 }
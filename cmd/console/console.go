@@ -25,6 +25,9 @@ var (
 	exit           = regexp.MustCompile(`^\s*exit\s*;*\s*$`)
 )
 
+// scriptExit is thrown by exit(code) to stop the script
+const scriptExit = "exit"
+
 // HistoryFile is the file within the data directory to store input scrollback.
 const HistoryFile = "history"
 
@@ -100,6 +103,7 @@ func (c *Console) init(preload []string) error {
 	jViteObj, _ := c.jsre.Get("b_vite")
 	jViteObj.Object().Set("send", bridge.Send)
 	jViteObj.Object().Set("sendAsync", bridge.Send)
+	jViteObj.Object().Set("call", bridge.Call)
 
 	consoleObj, _ := c.jsre.Get("console")
 	consoleObj.Object().Set("log", c.consoleOutput)
@@ -123,9 +127,10 @@ func (c *Console) init(preload []string) error {
 	if _, err := c.jsre.Run("var vite = require('ViteJS').default;"); err != nil {
 		return fmt.Errorf("ViteJS require: %v", err)
 	}
-	// if _, err := c.jsre.Run("b_vite.send({Method:'ledger_getAccountByAccAddr',Params:['vite_09bd04d2d590196316be59367acb5bbacc956acce4650ff689']});"); err != nil {
-	// 	return fmt.Errorf("ViteJS requireffff: %v", err)
-	// }
+	// Build the namespaces from the modules of the node, the bundled methods are kept if the node is too old
+	if err := c.initNamespace(); err != nil {
+		log.Warn("keep the bundled vite.js methods", "err", err)
+	}
 	//The admin.sleep and admin.sleepBlocks are offered by the console and not by the RPC layer.
 	c.jsre.Set("admin", struct{}{})
 	admin, err := c.jsre.Get("admin")
@@ -146,6 +151,13 @@ func (c *Console) init(preload []string) error {
 		}
 		c.prompter.SetWordCompleter(c.AutoCompleteInput)
 	}
+
+	// Preload the JavaScript files after the namespaces are ready
+	for _, path := range preload {
+		if err := c.jsre.Exec(path); err != nil {
+			return fmt.Errorf("%s: %v", path, jsErrorString(err))
+		}
+	}
 	return nil
 }
 
@@ -156,23 +168,19 @@ func (c *Console) Welcome() {
 	// Print some generic Gvite metadata
 	fmt.Fprintf(c.printer, "Welcome to the Gvite JavaScript console!\n")
 
-	//List all the supported modules for the user to call
-	//if apis, err := c.client.SupportedModules(); err == nil {
-	//	modules := make([]string, 0, len(apis))
-	//	for api, version := range apis {
-	//		modules = append(modules, fmt.Sprintf("%s:%s", api, version))
-	//	}
-	//	sort.Strings(modules)
-	//	fmt.Fprintln(c.printer, " modules:", strings.Join(modules, " "))
-	//}
-	//fmt.Fprintln(c.printer)
+	// List the modules of the node for the user to call
+	if modules := c.moduleNames(); len(modules) > 0 {
+		fmt.Fprintln(c.printer, " modules:", strings.Join(modules, " "))
+	}
+	fmt.Fprintln(c.printer)
 }
 
-// Evaluate executes code and pretty prints the result to the specified output stream.
-func (c *Console) Evaluate(statement string) error {
+// Evaluate executes code and pretty prints the result to the specified output stream, the error thrown is returned.
+func (c *Console) Evaluate(statement string) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Fprintf(c.printer, "[native] error: %v\n", r)
+			err = fmt.Errorf("%v", r)
 		}
 	}()
 	return c.jsre.Evaluate(statement, c.printer)
@@ -301,6 +309,38 @@ func (c *Console) Execute(path string) error {
 	return c.jsre.Exec(path)
 }
 
+// RunScript runs the JavaScript file non-interactively and waits for its timers, it returns the exit code for the
+// automation: the code passed to exit(code) if the script calls it, or else 1 if the script throws and 0 if not.
+func (c *Console) RunScript(path string) int {
+	exitCode := -1
+	c.jsre.Set("exit", func(call otto.FunctionCall) otto.Value {
+		code, _ := call.Argument(0).ToInteger()
+		if exitCode < 0 {
+			exitCode = int(code)
+		}
+		return throwJSException(scriptExit)
+	})
+
+	err := c.jsre.Exec(path)
+	c.jsre.Stop(true)
+	if exitCode >= 0 {
+		return exitCode
+	}
+	if err != nil {
+		fmt.Fprintln(c.printer, jsErrorString(err))
+		return 1
+	}
+	return 0
+}
+
+// jsErrorString returns the message with the stack of the JavaScript error
+func jsErrorString(err error) string {
+	if jsErr, ok := err.(*otto.Error); ok {
+		return jsErr.String()
+	}
+	return err.Error()
+}
+
 // Stop cleans up the console and terminates the runtime environment.
 func (c *Console) Stop(graceful bool) error {
 	if err := ioutil.WriteFile(c.histPath, []byte(strings.Join(c.history, "\n")), 0600); err != nil {
@@ -0,0 +1,143 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/rpc"
+	"github.com/vitelabs/go-vite/rpcapi/api"
+)
+
+type TestApi struct{}
+
+func (t TestApi) Echo(s string, n *uint64) string {
+	if n == nil {
+		return s
+	}
+	return s + "!"
+}
+
+type testPrompter struct{}
+
+func (p *testPrompter) PromptInput(prompt string) (string, error)    { return "", nil }
+func (p *testPrompter) PromptPassword(prompt string) (string, error) { return "", nil }
+func (p *testPrompter) PromptConfirm(prompt string) (bool, error)    { return false, nil }
+func (p *testPrompter) SetHistory(history []string)                  {}
+func (p *testPrompter) AppendHistory(command string)                 {}
+func (p *testPrompter) ClearHistory()                                {}
+func (p *testPrompter) SetWordCompleter(completer WordCompleter)     {}
+
+func newTestConsole(t *testing.T) (*Console, *bytes.Buffer, func()) {
+	dir, err := ioutil.TempDir("", "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", TestApi{}); err != nil {
+		t.Fatal(err)
+	}
+	printer := new(bytes.Buffer)
+	c, err := New(Config{
+		DataDir:  dir,
+		Client:   rpc.DialInProc(server),
+		Printer:  printer,
+		Prompter: &testPrompter{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, printer, func() {
+		c.Stop(false)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestConsole_Namespace(t *testing.T) {
+	c, printer, stop := newTestConsole(t)
+	defer stop()
+
+	v, err := c.jsre.Run("vite.test.echo('a')")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v.String())
+	v, err = c.jsre.Run("vite.test.echo('a', 1)")
+	assert.NoError(t, err)
+	assert.Equal(t, "a!", v.String())
+	_, err = c.jsre.Run("vite.test.echo()")
+	assert.Error(t, err)
+	v, _ = c.jsre.Run("String(vite.test.echo)")
+	assert.Equal(t, "test.echo(string, *uint64)", v.String())
+
+	// the bundled methods are replaced
+	v, _ = c.jsre.Run("typeof vite.wallet_unlock")
+	assert.Equal(t, "undefined", v.String())
+	assert.Equal(t, []string{"rpc", "test", "utils"}, c.moduleNames())
+	assert.Equal(t, []string{"vite.test.echo"}, c.jsre.CompleteKeywords("vite.test.ec"))
+	assert.Equal(t, []string{"vite.test.echo("}, c.jsre.CompleteKeywords("vite.test.echo"))
+
+	assert.Error(t, c.Evaluate("vite.test.echo(1, 2, 3)"))
+	assert.Contains(t, printer.String(), "takes 1 to 2 arguments, but 3 given")
+}
+
+func TestConsole_Helpers(t *testing.T) {
+	c, _, stop := newTestConsole(t)
+	defer stop()
+
+	v, err := c.jsre.Run("vite.utils.toRaw('1.5')")
+	assert.NoError(t, err)
+	assert.Equal(t, "1500000000000000000", v.String())
+	v, _ = c.jsre.Run("vite.utils.fromRaw('1500', 3)")
+	assert.Equal(t, "1.5", v.String())
+	_, err = c.jsre.Run("vite.utils.toRaw('1.5', 0)")
+	assert.Error(t, err)
+
+	abiJson := `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]}]`
+	_, err = c.jsre.Run("var data = vite.utils.encodeCall('" + abiJson + "', 'transfer', ['vite_098dfae02679a4ca05a4c8bf5dd00a8757f0c622bfccce7d68', 100]);" +
+		"var decoded = vite.utils.decodeCall('" + abiJson + "', data);")
+	assert.NoError(t, err)
+	v, _ = c.jsre.Run("decoded.name + ' ' + decoded.args[1].value")
+	assert.Equal(t, "transfer 100", v.String())
+
+	_, priv, _ := ed25519.GenerateKey(nil)
+	_, err = c.jsre.Run(`var signed = vite.utils.signBlock({blockType: 2, height: "1", toAddress: "vite_098dfae02679a4ca05a4c8bf5dd00a8757f0c622bfccce7d68",
+		tokenId: "tti_5649544520544f4b454e6e40", amount: "1", fee: "0"}, '` + priv.Hex() + `')`)
+	assert.NoError(t, err)
+	v, _ = c.jsre.Run("JSON.stringify(signed)")
+	block := &api.AccountBlock{}
+	assert.NoError(t, json.Unmarshal([]byte(v.String()), block))
+	assert.Equal(t, types.PubkeyToAddress(priv.PubByte()), block.Address)
+	hash, err := block.ComputeHash()
+	assert.NoError(t, err)
+	assert.Equal(t, *hash, block.Hash)
+	assert.True(t, ed25519.Verify(priv.PubByte(), hash.Bytes(), block.Signature))
+}
+
+func TestConsole_RunScript(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "script")
+	defer os.RemoveAll(dir)
+	for script, code := range map[string]int{
+		"vite.test.echo('a');":                         0,
+		"vite.test.echo();":                            1,
+		"if (vite.test.echo('a') == 'a') { exit(3); }": 3,
+		"setTimeout(function() { exit(4); }, 10);":     4,
+	} {
+		c, _, stop := newTestConsole(t)
+		path := filepath.Join(dir, "test.js")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(script), 0600))
+		assert.Equal(t, code, c.RunScript(path), script)
+		stop()
+	}
+
+	raw, _ := ToRawAmount("-0.01", 2)
+	assert.Equal(t, big.NewInt(-1), raw)
+	assert.Equal(t, "-0.01", FromRawAmount(raw, 2))
+	assert.Equal(t, "12", FromRawAmount(big.NewInt(12), 0))
+}
@@ -0,0 +1,229 @@
+package console
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/ledger/abiregistry"
+	"github.com/vitelabs/go-vite/rpcapi/api"
+	"github.com/vitelabs/go-vite/vm/abi"
+)
+
+const (
+	// defaultDecimals is the decimals of VITE
+	defaultDecimals = 18
+	maxDecimals     = 77
+)
+
+// helpers are the offline utilities of the console under vite.utils, they never call the node.
+var helpers = map[string]func(call otto.FunctionCall) otto.Value{
+	"encodeCall": encodeCall,
+	"decodeCall": decodeCall,
+	"toRaw":      toRaw,
+	"fromRaw":    fromRaw,
+	"hashBlock":  hashBlock,
+	"signBlock":  signBlock,
+}
+
+// encodeCall(abi, method, params) returns the call data in base64 as the data of blocks, the params are strings and
+// the arrays are in json.
+func encodeCall(call otto.FunctionCall) otto.Value {
+	abiStr := argString(call, 0)
+	method := argString(call, 1)
+	var params []string
+	if v := call.Argument(2); v.IsDefined() && !v.IsNull() {
+		var raw []interface{}
+		exportJSON(call, v, &raw)
+		for _, p := range raw {
+			switch p := p.(type) {
+			case string:
+				params = append(params, p)
+			default:
+				data, _ := json.Marshal(p)
+				params = append(params, string(data))
+			}
+		}
+	}
+	data, err := api.PackCallData(abiStr, method, params)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return toJSValue(call, base64.StdEncoding.EncodeToString(data))
+}
+
+// decodeCall(abi, data) decodes the call data in base64 by the abi
+func decodeCall(call otto.FunctionCall) otto.Value {
+	contract, err := abi.JSONToABIContract(strings.NewReader(argString(call, 0)))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	data, err := base64.StdEncoding.DecodeString(argString(call, 1))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	decoded, err := abiregistry.DecodeCall(&contract, data)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return toJSValue(call, decoded)
+}
+
+// toRaw(amount, decimals) converts the amount in the token unit to the raw amount, the decimals is 18 by default
+func toRaw(call otto.FunctionCall) otto.Value {
+	raw, err := ToRawAmount(argString(call, 0), argDecimals(call, 1))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return toJSValue(call, raw.String())
+}
+
+// fromRaw(raw, decimals) converts the raw amount to the amount in the token unit, the decimals is 18 by default
+func fromRaw(call otto.FunctionCall) otto.Value {
+	raw, ok := new(big.Int).SetString(argString(call, 0), 10)
+	if !ok {
+		throwJSException("invalid raw amount")
+	}
+	return toJSValue(call, FromRawAmount(raw, argDecimals(call, 1)))
+}
+
+// hashBlock(block) computes the hash of the account block in the format of the rpc
+func hashBlock(call otto.FunctionCall) otto.Value {
+	block := &api.AccountBlock{}
+	exportJSON(call, call.Argument(0), block)
+	hash, err := block.ComputeHash()
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return toJSValue(call, hash.String())
+}
+
+// signBlock(block, privateKey) fills the address, the public key, the hash and the signature of the account block in
+// the format of the rpc by the private key in hex, the signed block can be sent by vite.tx.sendRawTx.
+func signBlock(call otto.FunctionCall) otto.Value {
+	block := &api.AccountBlock{}
+	exportJSON(call, call.Argument(0), block)
+	privKey, err := ed25519.HexToPrivateKey(argString(call, 1))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	if err := SignBlock(block, privKey); err != nil {
+		throwJSException(err.Error())
+	}
+	return toJSValue(call, block)
+}
+
+// SignBlock fills the address, the public key, the hash and the signature of the account block by the private key
+func SignBlock(block *api.AccountBlock, privKey ed25519.PrivateKey) error {
+	pubKey := privKey.PubByte()
+	addr := types.PubkeyToAddress(pubKey)
+	if !block.Address.IsZero() && block.Address != addr {
+		return fmt.Errorf("the private key is not of address %v", block.Address)
+	}
+	block.Address = addr
+	block.AccountAddress = addr
+	block.PublicKey = pubKey
+	hash, err := block.ComputeHash()
+	if err != nil {
+		return err
+	}
+	block.Hash = *hash
+	block.Signature = ed25519.Sign(privKey, hash.Bytes())
+	return nil
+}
+
+// ToRawAmount converts the decimal amount to the raw amount of the token with the decimals
+func ToRawAmount(amount string, decimals int) (*big.Int, error) {
+	if decimals < 0 || decimals > maxDecimals {
+		return nil, fmt.Errorf("decimals must be in [0, %v]", maxDecimals)
+	}
+	amount = strings.TrimSpace(amount)
+	intPart, fracPart := amount, ""
+	if i := strings.Index(amount, "."); i >= 0 {
+		intPart, fracPart = amount[:i], amount[i+1:]
+	}
+	if len(fracPart) > decimals {
+		return nil, fmt.Errorf("more than %v decimal places in %v", decimals, amount)
+	}
+	if intPart == "" || intPart == "-" {
+		intPart += "0"
+	}
+	raw, ok := new(big.Int).SetString(intPart+fracPart+strings.Repeat("0", decimals-len(fracPart)), 10)
+	if !ok || strings.ContainsAny(fracPart, "+-") {
+		return nil, fmt.Errorf("invalid amount %v", amount)
+	}
+	return raw, nil
+}
+
+// FromRawAmount converts the raw amount of the token with the decimals to the decimal amount
+func FromRawAmount(raw *big.Int, decimals int) string {
+	if decimals <= 0 {
+		return raw.String()
+	}
+	digits := new(big.Int).Abs(raw).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	intPart := digits[:len(digits)-decimals]
+	fracPart := strings.TrimRight(digits[len(digits)-decimals:], "0")
+	result := intPart
+	if fracPart != "" {
+		result += "." + fracPart
+	}
+	if raw.Sign() < 0 {
+		result = "-" + result
+	}
+	return result
+}
+
+func argString(call otto.FunctionCall, i int) string {
+	s, err := call.Argument(i).ToString()
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return s
+}
+
+func argDecimals(call otto.FunctionCall, i int) int {
+	v := call.Argument(i)
+	if !v.IsDefined() || v.IsNull() {
+		return defaultDecimals
+	}
+	decimals, err := v.ToInteger()
+	if err != nil || decimals < 0 || decimals > maxDecimals {
+		throwJSException(fmt.Sprintf("decimals must be in [0, %v]", maxDecimals))
+	}
+	return int(decimals)
+}
+
+// exportJSON converts the JS value to the Go value by json
+func exportJSON(call otto.FunctionCall, v otto.Value, out interface{}) {
+	JSON, _ := call.Otto.Object("JSON")
+	str, err := JSON.Call("stringify", v)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	if err := json.Unmarshal([]byte(str.String()), out); err != nil {
+		throwJSException(err.Error())
+	}
+}
+
+// toJSValue converts the Go value to the JS value by json
+func toJSValue(call otto.FunctionCall, v interface{}) otto.Value {
+	data, err := json.Marshal(v)
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	JSON, _ := call.Otto.Object("JSON")
+	value, err := JSON.Call("parse", string(data))
+	if err != nil {
+		throwJSException(err.Error())
+	}
+	return value
+}
@@ -0,0 +1,116 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+
+	"github.com/vitelabs/go-vite/rpc"
+)
+
+// methodJS returns a function calling the RPC method by the bridge. The number of the arguments is checked against
+// the argument types of the method, the trailing pointer arguments are optional.
+const methodJS = `(function(method, args) {
+	var required = args.length;
+	while (required > 0 && args[required - 1].charAt(0) == '*') {
+		required--;
+	}
+	var signature = method.replace('_', '.') + '(' + args.join(', ') + ')';
+	var fn = function() {
+		if (arguments.length < required || arguments.length > args.length) {
+			throw new TypeError(signature + ' takes ' + (required == args.length ? args.length : required + ' to ' + args.length) +
+				' arguments, but ' + arguments.length + ' given');
+		}
+		return b_vite.call(method, Array.prototype.slice.call(arguments));
+	};
+	fn.method = method;
+	fn.args = args;
+	fn.toString = function() {
+		return signature;
+	};
+	return fn;
+})`
+
+// initNamespace replaces the methods bundled in vite.js with the ones of the modules available on the node, each
+// module is an object under vite, e.g. vite.ledger.getAccountBlockByHash. The offline helpers are under vite.utils.
+func (c *Console) initNamespace() error {
+	modules, err := c.client.SupportedMethods()
+	if err != nil {
+		return fmt.Errorf("retrieve the rpc methods: %v", err)
+	}
+
+	var result error
+	c.jsre.Do(func(vm *otto.Otto) {
+		// the bundled methods are named as module_method
+		if _, err := vm.Run(`Object.keys(vite).forEach(function(k) {
+			if (k.indexOf('_') > 0) {
+				delete vite[k];
+			}
+		});`); err != nil {
+			result = err
+			return
+		}
+		newMethod, err := vm.Run(methodJS)
+		if err != nil {
+			result = err
+			return
+		}
+		vite, _ := vm.Object("vite")
+		for module, methods := range modules {
+			obj, _ := vm.Object("({})")
+			for _, m := range methods {
+				if m.Subscribe {
+					continue
+				}
+				args, _ := json.Marshal(methodArgs(m))
+				argsVal, err := vm.Call("JSON.parse", nil, string(args))
+				if err != nil {
+					result = err
+					return
+				}
+				fn, err := newMethod.Call(otto.NullValue(), module+"_"+m.Name, argsVal)
+				if err != nil {
+					result = err
+					return
+				}
+				obj.Set(m.Name, fn)
+			}
+			vite.Set(module, obj)
+		}
+
+		utils, _ := vm.Object("({})")
+		for name, fn := range helpers {
+			utils.Set(name, fn)
+		}
+		vite.Set("utils", utils)
+	})
+	return result
+}
+
+// methodArgs returns the argument types of the method for display, the braces are removed as the pretty printer
+// displays a function up to the first brace.
+func methodArgs(m *rpc.MethodInfo) []string {
+	args := make([]string, len(m.Args))
+	for i, arg := range m.Args {
+		args[i] = strings.Replace(arg, "interface {}", "any", -1)
+		args[i] = strings.Replace(args[i], "struct {}", "struct", -1)
+	}
+	return args
+}
+
+// moduleNames returns the names of the modules in vite, used by the welcome message
+func (c *Console) moduleNames() []string {
+	var names []string
+	c.jsre.Do(func(vm *otto.Otto) {
+		v, err := vm.Run(`JSON.stringify(Object.keys(vite).filter(function(k) {
+			return typeof vite[k] == 'object' && k != 'help' && k.charAt(0) != '_';
+		}).sort())`)
+		if err != nil {
+			return
+		}
+		json.Unmarshal([]byte(v.String()), &names)
+	})
+	return names
+}
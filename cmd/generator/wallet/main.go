@@ -1,16 +1,58 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
+	"github.com/vitelabs/go-vite/cmd/console"
 	"github.com/vitelabs/go-vite/wallet"
+	"github.com/vitelabs/go-vite/wallet/entropystore"
 )
 
-var num = flag.Int("num", 1, "num ")
+var (
+	num = flag.Int("num", 1, "num ")
+
+	cmd          = flag.String("cmd", "mnemonic", "mnemonic, info, passwd, upgrade, export or import")
+	file         = flag.String("file", "", "entropy store file of info, passwd and upgrade")
+	dir          = flag.String("dir", "", "keystore dir of export and import")
+	bundle       = flag.String("bundle", "", "bundle file of export and import")
+	overwrite    = flag.Bool("overwrite", false, "overwrite the existing files on import")
+	kdf          = flag.String("kdf", "", "kdf of passwd, upgrade and export: scrypt or argon2id, passwd keeps the kdf of the file if empty")
+	scryptN      = flag.Int("scryptn", 0, "scrypt N, 0 for the default")
+	argon2Time   = flag.Uint("argon2time", 0, "argon2id iterations, 0 for the default")
+	argon2Memory = flag.Uint("argon2memory", 0, "argon2id memory in KiB, 0 for the default")
+)
 
 func main() {
 	flag.Parse()
+	var err error
+	switch *cmd {
+	case "mnemonic":
+		printMnemonics()
+	case "info":
+		err = info()
+	case "passwd":
+		err = changePassphrase()
+	case "upgrade":
+		err = upgrade()
+	case "export":
+		err = export()
+	case "import":
+		err = importBundle()
+	default:
+		err = fmt.Errorf("unknown cmd %v", *cmd)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func printMnemonics() {
 	for i := 0; i < *num; i++ {
 		addr, key, mnemonic, err := wallet.RandomMnemonic24()
 		if err != nil {
@@ -19,3 +61,151 @@ func main() {
 		fmt.Printf("address:%s, key:%s, mnemonic:%s\n", addr, key.Hex(), mnemonic)
 	}
 }
+
+func kdfParams() *entropystore.KDFParams {
+	if *kdf == "" {
+		return nil
+	}
+	return &entropystore.KDFParams{
+		KDF:          *kdf,
+		ScryptN:      *scryptN,
+		Argon2Time:   uint32(*argon2Time),
+		Argon2Memory: uint32(*argon2Memory),
+	}
+}
+
+func entropyStore() (entropystore.CryptoStore, error) {
+	if *file == "" {
+		return entropystore.CryptoStore{}, fmt.Errorf("-file is required")
+	}
+	return entropystore.CryptoStore{EntropyStoreFilename: *file}, nil
+}
+
+func promptNewPassphrase(prompt string) (string, error) {
+	passphrase, err := console.Stdin.PromptPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+	confirm, err := console.Stdin.PromptPassword("Repeat: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirm {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func info() error {
+	ks, err := entropyStore()
+	if err != nil {
+		return err
+	}
+	storeInfo, err := ks.Info()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(storeInfo, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func changePassphrase() error {
+	ks, err := entropyStore()
+	if err != nil {
+		return err
+	}
+	oldPassphrase, err := console.Stdin.PromptPassword("Old passphrase: ")
+	if err != nil {
+		return err
+	}
+	newPassphrase, err := promptNewPassphrase("New passphrase: ")
+	if err != nil {
+		return err
+	}
+	if err := ks.ChangePassphrase(oldPassphrase, newPassphrase, kdfParams()); err != nil {
+		return err
+	}
+	fmt.Println("passphrase changed")
+	return nil
+}
+
+func upgrade() error {
+	ks, err := entropyStore()
+	if err != nil {
+		return err
+	}
+	passphrase, err := console.Stdin.PromptPassword("Passphrase: ")
+	if err != nil {
+		return err
+	}
+	params := kdfParams()
+	if params == nil {
+		params = entropystore.DefaultKDFParams()
+	}
+	if err := ks.ChangePassphrase(passphrase, passphrase, params); err != nil {
+		return err
+	}
+	fmt.Printf("upgraded with %v\n", params.KDF)
+	return nil
+}
+
+func export() error {
+	if *dir == "" || *bundle == "" {
+		return fmt.Errorf("-dir and -bundle are required")
+	}
+	infos, err := ioutil.ReadDir(*dir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, info := range infos {
+		path := filepath.Join(*dir, info.Name())
+		if info.IsDir() {
+			continue
+		}
+		if ok, _, err := entropystore.IsMayValidEntropystoreFile(path); err != nil || !ok {
+			continue
+		}
+		files = append(files, path)
+	}
+	passphrase, err := promptNewPassphrase("Bundle passphrase: ")
+	if err != nil {
+		return err
+	}
+	params := kdfParams()
+	if params == nil {
+		params = entropystore.DefaultKDFParams()
+	}
+	data, err := entropystore.ExportBundle(files, passphrase, params)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*bundle, data, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("%v entropy store files exported\n", len(files))
+	return nil
+}
+
+func importBundle() error {
+	if *dir == "" || *bundle == "" {
+		return fmt.Errorf("-dir and -bundle are required")
+	}
+	data, err := ioutil.ReadFile(*bundle)
+	if err != nil {
+		return err
+	}
+	passphrase, err := console.Stdin.PromptPassword("Bundle passphrase: ")
+	if err != nil {
+		return err
+	}
+	files, err := entropystore.ImportBundle(*dir, data, passphrase, *overwrite)
+	for _, f := range files {
+		fmt.Println(f)
+	}
+	return err
+}
@@ -15,12 +15,18 @@ import (
 	"github.com/vitelabs/go-vite/cmd/console"
 	"github.com/vitelabs/go-vite/cmd/nodemanager"
 	"github.com/vitelabs/go-vite/cmd/subcmd_attach"
+	"github.com/vitelabs/go-vite/cmd/subcmd_backup"
+	"github.com/vitelabs/go-vite/cmd/subcmd_db"
+	"github.com/vitelabs/go-vite/cmd/subcmd_devnet"
 	"github.com/vitelabs/go-vite/cmd/subcmd_export"
 	"github.com/vitelabs/go-vite/cmd/subcmd_ledger"
 	"github.com/vitelabs/go-vite/cmd/subcmd_loadledger"
+	"github.com/vitelabs/go-vite/cmd/subcmd_migrate_store"
 	"github.com/vitelabs/go-vite/cmd/subcmd_plugin_data"
+	"github.com/vitelabs/go-vite/cmd/subcmd_pow"
 	"github.com/vitelabs/go-vite/cmd/subcmd_recover"
 	"github.com/vitelabs/go-vite/cmd/subcmd_rpc"
+	"github.com/vitelabs/go-vite/cmd/subcmd_upgrade"
 	"github.com/vitelabs/go-vite/cmd/utils"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/version"
@@ -61,6 +67,13 @@ func init() {
 		subcmd_rpc.RpcCommand,
 		subcmd_loadledger.LoadLedgerCommand,
 		subcmd_ledger.QueryLedgerCommand,
+		subcmd_migrate_store.MigrateStoreCommand,
+		subcmd_db.DbCommand,
+		subcmd_devnet.DevnetCommand,
+		subcmd_upgrade.UpgradeCommand,
+		subcmd_backup.BackupCommand,
+		subcmd_backup.RestoreCommand,
+		subcmd_pow.PowWorkerCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
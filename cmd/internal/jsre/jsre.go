@@ -258,13 +258,14 @@ func (re *JSRE) loadScript(call otto.FunctionCall) otto.Value {
 	return otto.TrueValue()
 }
 
-// Evaluate executes code and pretty prints the result to the specified output stream.
+// Evaluate executes code and pretty prints the result to the specified output stream, the error thrown is returned.
 func (re *JSRE) Evaluate(code string, w io.Writer) error {
 	var fail error
 
 	re.Do(func(vm *otto.Otto) {
 		val, err := vm.Run(code)
 		if err != nil {
+			fail = err
 			prettyError(vm, err, w)
 		} else {
 			prettyPrint(vm, val, w)
@@ -56,7 +56,9 @@ func (maker FullNodeMaker) MakeNodeConfig(ctx *cli.Context) (*node.Config, error
 	log.Info(fmt.Sprintf("NodeServer.KeyStoreDir:%v", cfg.KeyStoreDir))
 
 	// 4: Config log to file
-	makeRunLogFile(&cfg)
+	if err := makeRunLogFile(&cfg); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
@@ -236,9 +238,26 @@ func IsExist(f string) bool {
 	return err == nil || os.IsExist(err)
 }
 
-func makeRunLogFile(cfg *node.Config) {
+func makeRunLogFile(cfg *node.Config) error {
+	if err := common.SetLogConfig(common.LogConfig{
+		Format:     cfg.LogFormat,
+		MaxSize:    cfg.LogMaxSize,
+		MaxAge:     cfg.LogMaxAge,
+		MaxBackups: cfg.LogMaxBackups,
+	}); err != nil {
+		return err
+	}
+	for module, lvl := range cfg.LogModuleLevels {
+		logLevel, err := log15.LvlFromString(lvl)
+		if err != nil {
+			return fmt.Errorf("log level of module %v: %v", module, err)
+		}
+		common.LogLevels.Set(module, logLevel)
+	}
+
 	defaultHandler := common.LogHandler(cfg.RunLogDir(), "", "vite.log", cfg.LogLevel)
-	errorHandler := common.LogHandler(cfg.RunLogDir(), "error", "vite.error.log", log15.LvlError.String())
+	errorHandler := common.LogFileHandler(cfg.RunLogDir(), "error", "vite.error.log", log15.LvlError)
 
 	log15.Root().SetHandler(log15.MultiHandler(defaultHandler, errorHandler))
+	return nil
 }
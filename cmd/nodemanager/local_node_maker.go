@@ -54,7 +54,9 @@ func (maker LocalNodeMaker) MakeNodeConfig(ctx *cli.Context) (*node.Config, erro
 	makeLocalNodeCfg(ctx, cfg)
 
 	// 4: Config log to file
-	makeRunLogFile(cfg)
+	if err := makeRunLogFile(cfg); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -27,7 +27,11 @@ var (
 The GVite console is an interactive shell for the JavaScript runtime environment
 which exposes a node admin interface as well as the Ðapp JavaScript API.
 See https://github.com/vitelabs/go-vite/wiki/JavaScript-Console.
-This command allows to open a console on a running gvite node.`,
+This command allows to open a console on a running gvite node.
+The vite object has a namespace for each module of the node, e.g. vite.ledger.getSnapshotChainHeight(),
+print a method to see the types of its arguments, the offline helpers are under vite.utils.
+Use --exec or --script to run non-interactively, the exit code is not zero if the statement or the script
+throws, a script can also call exit(code).`,
 	}
 	log = log15.New("module", "gvite/attach")
 )
@@ -59,11 +63,20 @@ func remoteConsoleAction(ctx *cli.Context) error {
 	console, err := console.New(config)
 	if err != nil {
 		log.Error(fmt.Sprintf("Failed to start the JavaScript console: %v", err))
+		return err
 	}
 	defer console.Stop(false)
 
 	if script := ctx.GlobalString(utils.ExecFlag.Name); script != "" {
-		console.Evaluate(script)
+		if err := console.Evaluate(script); err != nil {
+			return cli.NewExitError("", 1)
+		}
+		return nil
+	}
+	if file := ctx.GlobalString(utils.ScriptFlag.Name); file != "" {
+		if code := console.RunScript(utils.AbsolutePath(config.DocRoot, file)); code != 0 {
+			return cli.NewExitError("", code)
+		}
 		return nil
 	}
 
@@ -0,0 +1,152 @@
+package subcmd_backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/vitelabs/go-vite/cmd/nodemanager"
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/cmd/utils/flock"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	"github.com/vitelabs/go-vite/ledger/chain"
+	"github.com/vitelabs/go-vite/rpc"
+)
+
+var (
+	dirFlag = cli.StringFlag{
+		Name:  "dir",
+		Usage: "the backup directory",
+	}
+	hardLinkFlag = cli.BoolFlag{
+		Name:  "hardlink",
+		Usage: "hard-link the table files of the stores instead of copying, the backup directory must be on the same file system",
+	}
+	heightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "roll the restored ledger back to the snapshot height, the default is the height of the backup",
+	}
+
+	BackupCommand = cli.Command{
+		Action:    utils.MigrateFlags(backupAction),
+		Name:      "backup",
+		Usage:     "backup --dir dir [--hardlink] [endpoint]",
+		ArgsUsage: "[endpoint]",
+		Flags:     append([]cli.Flag{dirFlag, hardLinkFlag}, utils.ConfigFlags...),
+		Category:  "REMOTE COMMANDS",
+		Description: `
+Back up the ledger of a running node by the admin_backup RPC, the default endpoint is the IPC of the node.
+The node flushes the ledger, pauses the writing and copies the stores and the block files to the directory
+on its host, the directory must not exist or be empty. The backup is at the latest flushed snapshot height,
+which is saved in backup.json of the directory.
+`,
+	}
+
+	RestoreCommand = cli.Command{
+		Action:   utils.MigrateFlags(restoreAction),
+		Name:     "restore",
+		Usage:    "restore --dir dir [--height N]",
+		Flags:    append([]cli.Flag{dirFlag, heightFlag}, utils.ConfigFlags...),
+		Category: "RECOVER COMMANDS",
+		Description: `
+Restore the ledger of the stopped node from the backup directory, the ledger directory of the node must not exist.
+The restored ledger is rolled back to the height if it is lower than the height of the backup.
+`,
+	}
+)
+
+func backupAction(ctx *cli.Context) error {
+	dir := ctx.String(dirFlag.Name)
+	if len(dir) == 0 {
+		return fmt.Errorf("--%s is required", dirFlag.Name)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	endpoint := ctx.Args().First()
+	if endpoint == "" {
+		nodeConfig, err := nodemanager.LocalNodeMaker{}.MakeNodeConfig(ctx)
+		if err != nil {
+			return err
+		}
+		endpoint = nodeConfig.IPCEndpoint()
+	}
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return fmt.Errorf("dial %s failed. Error: %s", endpoint, err)
+	}
+	defer client.Close()
+
+	info := &chain.BackupInfo{}
+	if err := client.Call(info, "admin_backup", dir, ctx.Bool(hardLinkFlag.Name)); err != nil {
+		return err
+	}
+	return printJson(info)
+}
+
+func restoreAction(ctx *cli.Context) error {
+	dir := ctx.String(dirFlag.Name)
+	if len(dir) == 0 {
+		return fmt.Errorf("--%s is required", dirFlag.Name)
+	}
+
+	node, err := nodemanager.LocalNodeMaker{}.MakeNode(ctx)
+	if err != nil {
+		return err
+	}
+	viteConfig := node.ViteConfig()
+
+	// the node must be stopped
+	release, _, err := flock.New(filepath.Join(node.Config().DataDir, "LOCK"))
+	if err != nil {
+		return fmt.Errorf("lock the data directory failed, stop the node before restoring. Error: %s", err)
+	}
+	defer release.Release()
+
+	info, err := chain.RestoreBackup(dir, viteConfig.DataDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored the ledger at snapshot height %d\n", info.SnapshotHeight)
+
+	height := ctx.Uint64(heightFlag.Name)
+	if height <= 0 || height >= info.SnapshotHeight {
+		return nil
+	}
+
+	upgrade.InitUpgradeBox(viteConfig.Genesis.UpgradeCfg.MakeUpgradeBox())
+	c := chain.NewChain(viteConfig.DataDir, viteConfig.Chain, viteConfig.Genesis)
+	if err := c.Init(); err != nil {
+		return err
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	latest := c.GetLatestSnapshotBlock()
+	if latest.Height != info.SnapshotHeight || latest.Hash != info.SnapshotHash {
+		return fmt.Errorf("the restored ledger is at %d %s, but the backup is at %d %s",
+			latest.Height, latest.Hash, info.SnapshotHeight, info.SnapshotHash)
+	}
+
+	fmt.Printf("Rolling back to snapshot height %d, don't shut down\n", height)
+	if _, err := c.DeleteSnapshotBlocksToHeight(height + 1); err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back to snapshot height %d\n", c.GetLatestSnapshotBlock().Height)
+	return nil
+}
+
+func printJson(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
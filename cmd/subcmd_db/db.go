@@ -0,0 +1,85 @@
+package subcmd_db
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/vitelabs/go-vite/cmd/nodemanager"
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	chain_verify "github.com/vitelabs/go-vite/ledger/chain/verify"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+var (
+	repairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "rebuild the broken indexes",
+	}
+
+	DbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "db verify [--repair]",
+		Category: "LOCAL COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(verifyAction),
+				Name:   "verify",
+				Usage:  "verify [--repair]",
+				Flags:  append([]cli.Flag{repairFlag}, utils.ConfigFlags...),
+				Description: `
+Verify the ledger data directory offline. Stop the node before verifying.
+All blocks in the block files are walked, the indexes, account heights, onroad blocks and
+balances of user accounts are checked against the data recomputed from the blocks.
+The inconsistencies are printed in json. With --repair, the broken indexes are rebuilt.
+`,
+			},
+		},
+		Description: `Offline ledger database tools.`,
+	}
+	log = log15.New("module", "gvite/db")
+)
+
+func verifyAction(ctx *cli.Context) error {
+	node, err := nodemanager.LocalNodeMaker{}.MakeNode(ctx)
+	if err != nil {
+		return err
+	}
+	viteConfig := node.ViteConfig()
+
+	// block hashes are computed by the upgrade points
+	upgrade.InitUpgradeBox(viteConfig.Genesis.UpgradeCfg.MakeUpgradeBox())
+
+	verifier, err := chain_verify.NewVerifier(path.Join(viteConfig.DataDir, "ledger"), viteConfig.Chain)
+	if err != nil {
+		return err
+	}
+	defer verifier.Close()
+
+	repair := ctx.Bool(repairFlag.Name)
+	report, err := verifier.Verify(repair)
+	if err != nil {
+		log.Error(fmt.Sprintf("verify failed, error is %s", err))
+		return err
+	}
+
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+
+	unrepaired := 0
+	for _, issue := range report.Issues {
+		if !repair || !issue.Repairable {
+			unrepaired++
+		}
+	}
+	if unrepaired > 0 {
+		return fmt.Errorf("found %d unrepaired issues", unrepaired)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package subcmd_devnet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/wallet/entropystore"
+)
+
+const (
+	genesisFileName    = "genesis.json"
+	accountsFileName   = "accounts.json"
+	nodeConfigFileName = "node_config.json"
+	keystoreDirName    = "keystore"
+	nodeLogFileName    = "gvite.log"
+)
+
+var (
+	specFlag = cli.StringFlag{
+		Name:  "spec",
+		Usage: "the devnet spec file in json, the default spec is one node with one SBP",
+	}
+	dirFlag = cli.StringFlag{
+		Name:  "dir",
+		Usage: "the directory of the devnet",
+		Value: "devnet",
+	}
+	startFlag = cli.BoolFlag{
+		Name:  "start",
+		Usage: "start the nodes after init",
+	}
+
+	DevnetCommand = cli.Command{
+		Name:     "devnet",
+		Usage:    "devnet init|start",
+		Category: "LOCAL COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(initAction),
+				Name:   "init",
+				Usage:  "init [--spec file] [--dir devnet] [--start]",
+				Flags:  []cli.Flag{specFlag, dirFlag, startFlag},
+				Description: `
+Generate a local devnet from a compact spec. The keystores of the genesis account, the SBPs and the
+generated accounts are written to <dir>/keystore, the genesis to <dir>/genesis.json, the addresses and
+the mnemonics to <dir>/accounts.json, and the config of the i-th node to <dir>/node<i>/node_config.json.
+The nodes listen on 127.0.0.1 on different ports and connect to each other as static nodes.
+`,
+			},
+			{
+				Action: utils.MigrateFlags(startAction),
+				Name:   "start",
+				Usage:  "start [--dir devnet]",
+				Flags:  []cli.Flag{dirFlag},
+				Description: `
+Start all nodes of the devnet in the background, the output of the i-th node is written to <dir>/node<i>/gvite.log.
+The nodes are stopped on SIGINT or SIGTERM, or when any node exits.
+`,
+			},
+		},
+		Description: `Local devnet launcher.`,
+	}
+	log = log15.New("module", "gvite/devnet")
+)
+
+// Account is a generated account of the devnet, the primary address of the keystore
+type Account struct {
+	Role     string        `json:"role"`
+	Address  types.Address `json:"address"`
+	Mnemonic string        `json:"mnemonic"`
+	Keystore string        `json:"keystore"`
+}
+
+func initAction(ctx *cli.Context) error {
+	spec, err := loadSpec(ctx.String(specFlag.Name))
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(ctx.String(dirFlag.Name))
+	if err != nil {
+		return err
+	}
+	if files, err := ioutil.ReadDir(dir); err == nil && len(files) > 0 {
+		return fmt.Errorf("the devnet directory %s is not empty", dir)
+	}
+	keystoreDir := filepath.Join(dir, keystoreDirName)
+	if err := os.MkdirAll(keystoreDir, 0700); err != nil {
+		return err
+	}
+
+	// keystores
+	var accounts []*Account
+	newAccount := func(role string) (*Account, error) {
+		mnemonic, err := entropystore.NewMnemonic()
+		if err != nil {
+			return nil, err
+		}
+		em, err := entropystore.StoreNewEntropy(keystoreDir, mnemonic, spec.Password, entropystore.DefaultMaxIndex)
+		if err != nil {
+			return nil, err
+		}
+		account := &Account{
+			Role:     role,
+			Address:  em.GetPrimaryAddr(),
+			Mnemonic: mnemonic,
+			Keystore: em.GetEntropyStoreFile(),
+		}
+		accounts = append(accounts, account)
+		return account, nil
+	}
+
+	genesisAccount, err := newAccount("genesis")
+	if err != nil {
+		return err
+	}
+	sbps := make([]types.Address, 0, spec.SBPs)
+	for i := 0; i < spec.SBPs; i++ {
+		account, err := newAccount(fmt.Sprintf("sbp%d", i))
+		if err != nil {
+			return err
+		}
+		sbps = append(sbps, account.Address)
+	}
+	generated := make([]types.Address, 0, spec.GeneratedAccounts)
+	for i := 0; i < spec.GeneratedAccounts; i++ {
+		account, err := newAccount(fmt.Sprintf("account%d", i))
+		if err != nil {
+			return err
+		}
+		generated = append(generated, account.Address)
+	}
+
+	// genesis
+	genesis, err := spec.makeGenesis(genesisAccount.Address, sbps, generated)
+	if err != nil {
+		return err
+	}
+	genesisFile := filepath.Join(dir, genesisFileName)
+	if err := writeJson(genesisFile, genesis); err != nil {
+		return err
+	}
+	if err := writeJson(filepath.Join(dir, accountsFileName), accounts); err != nil {
+		return err
+	}
+
+	// node configs
+	peerKeys := make([]ed25519.PrivateKey, 0, spec.Nodes)
+	for i := 0; i < spec.Nodes; i++ {
+		_, peerKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		peerKeys = append(peerKeys, peerKey)
+	}
+	for i := 0; i < spec.Nodes; i++ {
+		nodeDir := filepath.Join(dir, fmt.Sprintf("node%d", i))
+		if err := os.MkdirAll(nodeDir, 0700); err != nil {
+			return err
+		}
+
+		var staticNodes []string
+		for j := range peerKeys {
+			if j != i {
+				staticNodes = append(staticNodes, fmt.Sprintf("%s@127.0.0.1:%d/%d", hex.EncodeToString(peerKeys[j].PubByte()), spec.BasePort+2*j, spec.NetID))
+			}
+		}
+		nodeConfig := map[string]interface{}{
+			"Identity":        fmt.Sprintf("devnet-node%d", i),
+			"NetID":           spec.NetID,
+			"DataDir":         nodeDir,
+			"KeyStoreDir":     nodeDir,
+			"GenesisFile":     genesisFile,
+			"Single":          spec.Nodes == 1,
+			"ListenInterface": "127.0.0.1",
+			"Port":            spec.BasePort + 2*i,
+			"FilePort":        spec.BasePort + 2*i + 1,
+			"PrivateKey":      peerKeys[i].Hex(),
+			"Discover":        false,
+			"StaticNodes":     staticNodes,
+			"RPCEnabled":      true,
+			"HttpHost":        "127.0.0.1",
+			"HttpPort":        spec.BaseHttpPort + i,
+			"WSEnabled":       true,
+			"WSHost":          "127.0.0.1",
+			"WSPort":          spec.BaseWSPort + i,
+			"IPCEnabled":      true,
+			"PublicModules": []string{
+				"ledger", "net", "contract", "util", "debug", "wallet", "sbpstats",
+			},
+			"LogLevel":         "info",
+			"OpenPlugins":      true,
+			"SubscribeEnabled": true,
+			"VmLogAll":         true,
+		}
+		if i < len(sbps) {
+			nodeConfig["Miner"] = true
+			nodeConfig["CoinBase"] = fmt.Sprintf("0:%s", sbps[i])
+			nodeConfig["EntropyStorePath"] = accounts[i+1].Keystore
+			nodeConfig["EntropyStorePassword"] = spec.Password
+		}
+		if err := writeJson(filepath.Join(nodeDir, nodeConfigFileName), nodeConfig); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("devnet %d is generated in %s, %d nodes, %d SBPs\n", spec.NetID, dir, spec.Nodes, spec.SBPs)
+	for _, account := range accounts {
+		fmt.Printf("%s\t%s\n", account.Role, account.Address)
+	}
+	for i := 0; i < spec.Nodes; i++ {
+		fmt.Printf("node%d\thttp://127.0.0.1:%d\n", i, spec.BaseHttpPort+i)
+	}
+
+	if ctx.Bool(startFlag.Name) {
+		return startNodes(dir)
+	}
+	return nil
+}
+
+func startAction(ctx *cli.Context) error {
+	dir, err := filepath.Abs(ctx.String(dirFlag.Name))
+	if err != nil {
+		return err
+	}
+	return startNodes(dir)
+}
+
+// startNodes runs a gvite process for every node config in the devnet directory, and waits until they exit
+func startNodes(dir string) error {
+	configFiles, err := filepath.Glob(filepath.Join(dir, "node*", nodeConfigFileName))
+	if err != nil {
+		return err
+	}
+	if len(configFiles) == 0 {
+		return fmt.Errorf("no node config is found in %s, run devnet init first", dir)
+	}
+	sort.Strings(configFiles)
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, len(configFiles))
+	var cmds []*exec.Cmd
+	stopAll := func() {
+		for _, cmd := range cmds {
+			cmd.Process.Signal(syscall.SIGINT)
+		}
+	}
+	for _, configFile := range configFiles {
+		logFile, err := os.OpenFile(filepath.Join(filepath.Dir(configFile), nodeLogFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			stopAll()
+			return err
+		}
+		defer logFile.Close()
+
+		cmd := exec.Command(executable, "--config", configFile)
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		if err := cmd.Start(); err != nil {
+			stopAll()
+			return fmt.Errorf("start node %s failed. Error: %s", configFile, err)
+		}
+		log.Info(fmt.Sprintf("node %s is started, pid is %d", configFile, cmd.Process.Pid))
+		fmt.Printf("start %s, pid %d\n", configFile, cmd.Process.Pid)
+
+		cmds = append(cmds, cmd)
+		go func(cmd *exec.Cmd) {
+			exited <- cmd.Wait()
+		}(cmd)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var result error
+	running := len(cmds)
+	select {
+	case sig := <-signals:
+		fmt.Printf("receive %s, stop the nodes\n", sig)
+	case err := <-exited:
+		result = fmt.Errorf("a node exited, stop the other nodes. Error: %v", err)
+		running--
+	}
+	stopAll()
+	for ; running > 0; running-- {
+		<-exited
+	}
+	return result
+}
+
+func writeJson(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0600)
+}
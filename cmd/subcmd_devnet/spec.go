@@ -0,0 +1,335 @@
+package subcmd_devnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+var (
+	// 1 VITE = 1e18
+	viteUnit = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+	defaultGenesisBalance   = new(big.Int).Mul(big.NewInt(1000000000), viteUnit)
+	defaultGeneratedBalance = new(big.Int).Mul(big.NewInt(1000000), viteUnit)
+	defaultRegisterAmount   = new(big.Int).Mul(big.NewInt(100000), viteUnit)
+	defaultStakeAmount      = new(big.Int).Mul(big.NewInt(10000), viteUnit)
+
+	maxSupply, _ = new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+)
+
+const (
+	defaultNetID        = 5
+	defaultPassword     = "123456"
+	defaultBasePort     = 8483
+	defaultBaseHttpPort = 48132
+	defaultBaseWSPort   = 41420
+	defaultQuotaRatio   = 10
+
+	registrationExpirationHeight = 7776000
+	stakeExpirationHeight        = 259200
+)
+
+// Spec is the compact description of a local devnet, the genesis and the node configs are generated from it
+type Spec struct {
+	NetID int
+
+	// the number of the local nodes
+	Nodes int
+	// the number of the snapshot block producers with generated keys, the i-th SBP produces on the i-th node
+	SBPs int
+	// the snapshot block interval in seconds
+	Interval int64
+
+	// the password of the generated keystores
+	Password string
+
+	// the p2p port of the first node, the p2p port and the file port of the i-th node are BasePort+2*i and BasePort+2*i+1
+	BasePort     int
+	BaseHttpPort int
+	BaseWSPort   int
+
+	UpgradeCfg *config.Upgrade
+
+	// the VITE balance of the generated genesis account
+	GenesisBalance *big.Int
+	// the number of the funded accounts with generated keys, every account has GeneratedBalance VITE
+	GeneratedAccounts int
+	GeneratedBalance  *big.Int
+	// the funded accounts with known keys, address - tokenId - balance
+	Accounts map[string]map[string]*big.Int
+
+	// the tokens besides VITE, tokenId - info. If no account holds the token, the total supply is given to the owner
+	Tokens map[string]*config.TokenInfo
+	// the pre-deployed contracts, contract address - info
+	Contracts map[string]*config.GenesisContractInfo
+}
+
+func loadSpec(file string) (*Spec, error) {
+	spec := &Spec{}
+	if len(file) > 0 {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, spec); err != nil {
+			return nil, fmt.Errorf("invalid devnet spec %s. Error: %s", file, err)
+		}
+	}
+	spec.setDefaults()
+	return spec, spec.check()
+}
+
+func (s *Spec) setDefaults() {
+	if s.NetID <= 0 {
+		s.NetID = defaultNetID
+	}
+	if s.Nodes <= 0 {
+		s.Nodes = 1
+	}
+	if s.SBPs <= 0 {
+		s.SBPs = s.Nodes
+	}
+	if s.Interval <= 0 {
+		s.Interval = 1
+	}
+	if len(s.Password) == 0 {
+		s.Password = defaultPassword
+	}
+	if s.BasePort <= 0 {
+		s.BasePort = defaultBasePort
+	}
+	if s.BaseHttpPort <= 0 {
+		s.BaseHttpPort = defaultBaseHttpPort
+	}
+	if s.BaseWSPort <= 0 {
+		s.BaseWSPort = defaultBaseWSPort
+	}
+	if s.UpgradeCfg == nil {
+		s.UpgradeCfg = &config.Upgrade{Level: "latest"}
+	}
+	if s.GenesisBalance == nil {
+		s.GenesisBalance = defaultGenesisBalance
+	}
+	if s.GeneratedBalance == nil {
+		s.GeneratedBalance = defaultGeneratedBalance
+	}
+}
+
+func (s *Spec) check() error {
+	if s.NetID < 3 {
+		return fmt.Errorf("the NetID %d is reserved by the mainnet and the testnet", s.NetID)
+	}
+	if s.SBPs > s.Nodes {
+		return fmt.Errorf("the SBPs %d is more than the nodes %d, every node produces for one SBP", s.SBPs, s.Nodes)
+	}
+	if s.SBPs > 255 {
+		return fmt.Errorf("too many SBPs %d", s.SBPs)
+	}
+	for addrStr := range s.Accounts {
+		if _, err := types.HexToAddress(addrStr); err != nil {
+			return fmt.Errorf("invalid account %s. Error: %s", addrStr, err)
+		}
+	}
+	for tokenIdStr := range s.Tokens {
+		tokenId, err := types.HexToTokenTypeId(tokenIdStr)
+		if err != nil {
+			return fmt.Errorf("invalid token id %s. Error: %s", tokenIdStr, err)
+		}
+		if tokenId == ledger.ViteTokenId {
+			return fmt.Errorf("VITE is issued by the devnet, remove it from the tokens")
+		}
+	}
+	for addrStr := range s.Contracts {
+		addr, err := types.HexToAddress(addrStr)
+		if err != nil {
+			return fmt.Errorf("invalid contract %s. Error: %s", addrStr, err)
+		}
+		if !types.IsContractAddr(addr) || types.IsBuiltinContractAddr(addr) {
+			return fmt.Errorf("%s is not a user contract address", addrStr)
+		}
+	}
+	return nil
+}
+
+// makeGenesis generates the genesis of the devnet with the generated addresses
+func (s *Spec) makeGenesis(genesisAddr types.Address, sbps []types.Address, generated []types.Address) (*config.Genesis, error) {
+	balances := make(map[string]map[string]*big.Int)
+	addBalance := func(addr types.Address, tokenId types.TokenTypeId, amount *big.Int) {
+		tokenBalances, ok := balances[addr.String()]
+		if !ok {
+			tokenBalances = make(map[string]*big.Int)
+			balances[addr.String()] = tokenBalances
+		}
+		if balance, ok := tokenBalances[tokenId.String()]; ok {
+			tokenBalances[tokenId.String()] = new(big.Int).Add(balance, amount)
+		} else {
+			tokenBalances[tokenId.String()] = new(big.Int).Set(amount)
+		}
+	}
+
+	addBalance(genesisAddr, ledger.ViteTokenId, s.GenesisBalance)
+	for _, addr := range generated {
+		addBalance(addr, ledger.ViteTokenId, s.GeneratedBalance)
+	}
+	for addrStr, tokenBalances := range s.Accounts {
+		addr, err := types.HexToAddress(addrStr)
+		if err != nil {
+			return nil, err
+		}
+		for tokenIdStr, amount := range tokenBalances {
+			tokenId, err := types.HexToTokenTypeId(tokenIdStr)
+			if err != nil {
+				return nil, err
+			}
+			addBalance(addr, tokenId, amount)
+		}
+	}
+
+	// the SBPs are registered with the stakes locked in the governance contract
+	gidStr := types.SNAPSHOT_GID.String()
+	registrations := make(map[string]*config.RegistrationInfo)
+	for i, addr := range sbps {
+		addr := addr
+		registrations[fmt.Sprintf("s%d", i+1)] = &config.RegistrationInfo{
+			BlockProducingAddress: &addr,
+			StakeAddress:          &addr,
+			Amount:                defaultRegisterAmount,
+			ExpirationHeight:      registrationExpirationHeight,
+			RewardTime:            1,
+			HistoryAddressList:    []types.Address{addr},
+		}
+		addBalance(types.AddressGovernance, ledger.ViteTokenId, defaultRegisterAmount)
+	}
+
+	// the genesis account stakes for the SBPs and the funded accounts to get quota
+	quotaInfo := &config.QuotaContractInfo{
+		StakeInfoMap:       make(map[string][]*config.StakeInfo),
+		StakeBeneficialMap: make(map[string]*big.Int),
+	}
+	beneficiaries := append(append([]types.Address{genesisAddr}, sbps...), generated...)
+	for addrStr := range s.Accounts {
+		addr, _ := types.HexToAddress(addrStr)
+		beneficiaries = append(beneficiaries, addr)
+	}
+	for _, addr := range beneficiaries {
+		if _, ok := quotaInfo.StakeBeneficialMap[addr.String()]; ok {
+			continue
+		}
+		addr := addr
+		quotaInfo.StakeInfoMap[genesisAddr.String()] = append(quotaInfo.StakeInfoMap[genesisAddr.String()], &config.StakeInfo{
+			Amount:           defaultStakeAmount,
+			ExpirationHeight: stakeExpirationHeight,
+			Beneficiary:      &addr,
+		})
+		quotaInfo.StakeBeneficialMap[addr.String()] = defaultStakeAmount
+		addBalance(types.AddressQuota, ledger.ViteTokenId, defaultStakeAmount)
+	}
+
+	// the total supplies are the sums of the balances
+	tokenInfos := map[string]*config.TokenInfo{
+		ledger.ViteTokenId.String(): {
+			TokenName:    "Vite Token",
+			TokenSymbol:  "VITE",
+			Decimals:     18,
+			Owner:        genesisAddr,
+			MaxSupply:    maxSupply,
+			IsReIssuable: true,
+		},
+	}
+	for tokenIdStr, tokenInfo := range s.Tokens {
+		info := *tokenInfo
+		if info.Owner == types.ZERO_ADDRESS {
+			info.Owner = genesisAddr
+		}
+		if info.MaxSupply == nil {
+			info.MaxSupply = maxSupply
+		}
+		tokenInfos[tokenIdStr] = &info
+	}
+	for tokenIdStr, info := range tokenInfos {
+		supply := big.NewInt(0)
+		for _, tokenBalances := range balances {
+			if balance, ok := tokenBalances[tokenIdStr]; ok {
+				supply.Add(supply, balance)
+			}
+		}
+		if supply.Sign() == 0 && info.TotalSupply != nil {
+			tokenId, _ := types.HexToTokenTypeId(tokenIdStr)
+			addBalance(info.Owner, tokenId, info.TotalSupply)
+			supply.Set(info.TotalSupply)
+		}
+		info.TotalSupply = supply
+	}
+
+	contracts := make(map[string]*config.GenesisContractInfo, len(s.Contracts))
+	for addrStr, contractInfo := range s.Contracts {
+		info := *contractInfo
+		if info.Gid == (types.Gid{}) {
+			info.Gid = types.DELEGATE_GID
+		}
+		if info.QuotaRatio == 0 {
+			info.QuotaRatio = defaultQuotaRatio
+		}
+		contracts[addrStr] = &info
+	}
+
+	groupInfos := make(map[string]*config.ConsensusGroupInfo)
+	for _, gid := range []types.Gid{types.SNAPSHOT_GID, types.DELEGATE_GID} {
+		groupInfo := &config.ConsensusGroupInfo{
+			NodeCount:           uint8(len(sbps)),
+			Interval:            s.Interval,
+			PerCount:            3,
+			RandCount:           2,
+			RandRank:            100,
+			Repeat:              1,
+			CheckLevel:          0,
+			CountingTokenId:     ledger.ViteTokenId,
+			RegisterConditionId: 1,
+			RegisterConditionParam: config.RegisterConditionParam{
+				StakeAmount: defaultRegisterAmount,
+				StakeToken:  ledger.ViteTokenId,
+				StakeHeight: 1,
+			},
+			VoteConditionId:  1,
+			Owner:            genesisAddr,
+			StakeAmount:      big.NewInt(0),
+			ExpirationHeight: 1,
+		}
+		if gid == types.DELEGATE_GID {
+			groupInfo.Interval = 3 * s.Interval
+			groupInfo.PerCount = 1
+			groupInfo.Repeat = 48
+			groupInfo.CheckLevel = 1
+		}
+		groupInfos[gid.String()] = groupInfo
+	}
+
+	genesis := &config.Genesis{
+		GenesisAccountAddress: &genesisAddr,
+		UpgradeCfg:            s.UpgradeCfg,
+		GovernanceInfo: &config.GovernanceContractInfo{
+			ConsensusGroupInfoMap: groupInfos,
+			RegistrationInfoMap: map[string]map[string]*config.RegistrationInfo{
+				gidStr: registrations,
+			},
+		},
+		AssetInfo: &config.AssetContractInfo{
+			TokenInfoMap: tokenInfos,
+			// the issue event of VITE
+			LogList: config.MockGenesis().AssetInfo.LogList,
+		},
+		QuotaInfo:         quotaInfo,
+		AccountBalanceMap: balances,
+		ContractInfoMap:   contracts,
+	}
+	if !config.IsCompleteGenesisConfig(genesis) {
+		return nil, fmt.Errorf("the generated genesis is not complete")
+	}
+	return genesis, nil
+}
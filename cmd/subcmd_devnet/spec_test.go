@@ -0,0 +1,94 @@
+package subcmd_devnet
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_genesis "github.com/vitelabs/go-vite/ledger/chain/genesis"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+func TestSpec_MakeGenesis(t *testing.T) {
+	tokenId := types.CreateTokenTypeId([]byte("devnet"))
+	contractAddr := types.CreateContractAddress([]byte("devnet"))
+	account, _, err := types.CreateAddress()
+	assert.NoError(t, err)
+
+	spec := &Spec{
+		Nodes:             3,
+		SBPs:              2,
+		GeneratedAccounts: 1,
+		Accounts: map[string]map[string]*big.Int{
+			account.String(): {ledger.ViteTokenId.String(): big.NewInt(100)},
+		},
+		Tokens: map[string]*config.TokenInfo{
+			tokenId.String(): {TokenName: "Devnet Token", TokenSymbol: "DEV", Decimals: 18, TotalSupply: big.NewInt(1000)},
+		},
+		Contracts: map[string]*config.GenesisContractInfo{
+			contractAddr.String(): {Code: "6080", Storage: map[string]string{"01": "02"}},
+		},
+	}
+	spec.setDefaults()
+	assert.NoError(t, spec.check())
+
+	var addrs []types.Address
+	for i := 0; i < 4; i++ {
+		addr, _, err := types.CreateAddress()
+		assert.NoError(t, err)
+		addrs = append(addrs, addr)
+	}
+	genesis, err := spec.makeGenesis(addrs[0], addrs[1:3], addrs[3:])
+	assert.NoError(t, err)
+
+	// the genesis is written and loaded as json
+	data, err := json.Marshal(genesis)
+	assert.NoError(t, err)
+	loaded := &config.Genesis{}
+	assert.NoError(t, json.Unmarshal(data, loaded))
+	assert.True(t, config.IsCompleteGenesisConfig(loaded))
+
+	assert.Equal(t, 2, len(loaded.GovernanceInfo.RegistrationInfoMap[types.SNAPSHOT_GID.String()]))
+	assert.Equal(t, uint8(2), loaded.GovernanceInfo.ConsensusGroupInfoMap[types.SNAPSHOT_GID.String()].NodeCount)
+	// the token without holders is given to the owner
+	assert.Equal(t, big.NewInt(1000), loaded.AccountBalanceMap[addrs[0].String()][tokenId.String()])
+	assert.Equal(t, types.DELEGATE_GID, loaded.ContractInfoMap[contractAddr.String()].Gid)
+
+	// the total supply of VITE is the sum of the balances
+	supply := big.NewInt(0)
+	for _, balances := range loaded.AccountBalanceMap {
+		if balance, ok := balances[ledger.ViteTokenId.String()]; ok {
+			supply.Add(supply, balance)
+		}
+	}
+	assert.Equal(t, supply, loaded.AssetInfo.TokenInfoMap[ledger.ViteTokenId.String()].TotalSupply)
+
+	upgrade.CleanupUpgradeBox(t)
+	upgrade.InitUpgradeBox(loaded.UpgradeCfg.MakeUpgradeBox())
+	blocks := chain_genesis.NewGenesisAccountBlocks(loaded)
+	found := false
+	for _, block := range blocks {
+		if block.AccountBlock.AccountAddress == contractAddr {
+			found = true
+			// the code on chain is prefixed with the contract type
+			assert.Equal(t, []byte{util.SolidityPPContractType, 0x60, 0x80}, block.VmDb.GetUnsavedContractCode())
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestSpec_Check(t *testing.T) {
+	spec := &Spec{Nodes: 1, SBPs: 2}
+	spec.setDefaults()
+	assert.Error(t, spec.check())
+
+	spec = &Spec{Contracts: map[string]*config.GenesisContractInfo{types.AddressQuota.String(): {}}}
+	spec.setDefaults()
+	assert.Error(t, spec.check())
+}
@@ -1,22 +1,50 @@
 package subcmd_ledger
 
 import (
+	"encoding/json"
 	"fmt"
+	"path"
 
 	"gopkg.in/urfave/cli.v1"
 
 	"github.com/vitelabs/go-vite/cmd/nodemanager"
 	"github.com/vitelabs/go-vite/cmd/utils"
 	"github.com/vitelabs/go-vite/common/types"
+	chain_stats "github.com/vitelabs/go-vite/ledger/chain/stats"
 )
 
 var (
+	topFlag = cli.IntFlag{
+		Name:  "top",
+		Usage: "the number of the contracts and the accounts listed",
+		Value: chain_stats.DefaultTopN,
+	}
+
 	QueryLedgerCommand = cli.Command{
-		Action:      utils.MigrateFlags(queryLedgerAction),
-		Name:        "ledger",
-		Usage:       "ledger accounts",
-		Flags:       utils.ConfigFlags,
-		Category:    "LOCAL COMMANDS",
+		Action:   utils.MigrateFlags(queryLedgerAction),
+		Name:     "ledger",
+		Usage:    "ledger accounts|stats",
+		Flags:    utils.ConfigFlags,
+		Category: "LOCAL COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(queryLedgerAction),
+				Name:   "accounts",
+				Usage:  "accounts",
+				Flags:  utils.ConfigFlags,
+			},
+			{
+				Action: utils.MigrateFlags(statsAction),
+				Name:   "stats",
+				Usage:  "stats [--top N]",
+				Flags:  append([]cli.Flag{topFlag}, utils.ConfigFlags...),
+				Description: `
+Print the disk usage of the ledger in json. Stop the node before running.
+The usage is broken down by the stores and the key prefixes, the top N storage-heavy contracts,
+the VM log volume per contract, the block size per account and the block file growth per day are listed.
+`,
+			},
+		},
 		Description: `Load ledger.`,
 	}
 )
@@ -39,3 +67,29 @@ func queryLedgerAction(ctx *cli.Context) error {
 	})
 	return nil
 }
+
+func statsAction(ctx *cli.Context) error {
+	node, err := nodemanager.LocalNodeMaker{}.MakeNode(ctx)
+	if err != nil {
+		return err
+	}
+	viteConfig := node.ViteConfig()
+
+	collector, err := chain_stats.NewCollector(path.Join(viteConfig.DataDir, "ledger"), viteConfig.Chain)
+	if err != nil {
+		return err
+	}
+	defer collector.Close()
+
+	report, err := collector.Collect(ctx.Int(topFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
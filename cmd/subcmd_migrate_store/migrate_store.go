@@ -0,0 +1,114 @@
+package subcmd_migrate_store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/vitelabs/go-vite/cmd/nodemanager"
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/common/config"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+var (
+	MigrateStoreCommand = cli.Command{
+		Action:    utils.MigrateFlags(migrateStoreAction),
+		Name:      "migrateStore",
+		Usage:     "migrateStore --store=plugins --backend=logstore",
+		ArgsUsage: "--store=plugins --backend=logstore",
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "store",
+				Usage: "the store to migrate: index, state, redo or plugins",
+			},
+			cli.StringFlag{
+				Name:  "backend",
+				Usage: "the target storage backend: leveldb, btree or logstore, the redo store can't use logstore",
+			},
+		}, utils.ConfigFlags...),
+		Category: "LOCAL COMMANDS",
+		Description: `
+Convert a store of the ledger to another storage backend. Stop the node before migrating,
+then set StoreBackends in the node config to the new backend.
+`,
+	}
+	log = log15.New("module", "gvite/migrate_store")
+
+	// directory of the stores, relative to the ledger directory
+	storeDirs = map[string]string{
+		config.StoreIndex:   "index",
+		config.StoreState:   "state",
+		config.StoreRedo:    "state_redo",
+		config.StorePlugins: "plugins",
+	}
+)
+
+func migrateStoreAction(ctx *cli.Context) error {
+	storeName := ctx.String("store")
+	backend := ctx.String("backend")
+
+	dirName, ok := storeDirs[storeName]
+	if !ok {
+		return fmt.Errorf("unknown store %q, should be one of index, state, redo and plugins", storeName)
+	}
+	if backend != chain_db.BackendLevelDb && backend != chain_db.BackendBTree && backend != chain_db.BackendLogStore {
+		return fmt.Errorf("unknown backend %q, should be one of %s, %s and %s", backend, chain_db.BackendLevelDb, chain_db.BackendBTree, chain_db.BackendLogStore)
+	}
+	// the same check as the node does when it starts with the migrated store
+	if err := (&config.Chain{StoreBackends: map[string]string{storeName: backend}}).CheckStoreBackends(); err != nil {
+		return err
+	}
+
+	nodeConfig, err := nodemanager.FullNodeMaker{}.MakeNodeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := nodeConfig.DataDirPathAbs(); err != nil {
+		return err
+	}
+
+	storeDir := filepath.Join(nodeConfig.DataDir, "ledger", dirName)
+	current, err := chain_db.DetectBackend(storeDir)
+	if err != nil {
+		return err
+	}
+	if len(current) <= 0 {
+		return fmt.Errorf("store %s is not found in %s", storeName, storeDir)
+	}
+	if current == backend {
+		fmt.Printf("Store %s is already a %s store\n", storeName, backend)
+		return nil
+	}
+
+	migratingDir := storeDir + ".migrating"
+	backupDir := storeDir + ".bak"
+	if _, err := os.Stat(backupDir); err == nil {
+		return fmt.Errorf("backup directory %s exists, remove it first", backupDir)
+	}
+	if err := os.RemoveAll(migratingDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrating store %s from %s to %s...\n", storeName, current, backend)
+	count, err := chain_db.MigrateStore(storeDir, migratingDir, backend)
+	if err != nil {
+		log.Error(fmt.Sprintf("migrate store %s failed, error is %s", storeName, err))
+		os.RemoveAll(migratingDir)
+		return err
+	}
+
+	if err := os.Rename(storeDir, backupDir); err != nil {
+		return err
+	}
+	if err := os.Rename(migratingDir, storeDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrated %d keys. The old data is moved to %s, remove it after the node runs well.\n", count, backupDir)
+	fmt.Printf("Set \"StoreBackends\": {\"%s\": \"%s\"} in the node config before starting the node.\n", storeName, backend)
+	return nil
+}
@@ -0,0 +1,72 @@
+package subcmd_pow
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/pow/remote"
+)
+
+var (
+	listenFlag = cli.StringFlag{
+		Name:  "listen",
+		Usage: "the listen address of the pow worker",
+		Value: "127.0.0.1:6007",
+	}
+	threadsFlag = cli.IntFlag{
+		Name:  "threads",
+		Usage: "the number of the workers of each work, the number of cpus is used if 0",
+	}
+	timeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "the longest time of a work",
+		Value: remote.DefaultWorkTimeout,
+	}
+
+	PowWorkerCommand = cli.Command{
+		Action:   utils.MigrateFlags(powWorkerAction),
+		Name:     "powworker",
+		Usage:    "powworker [--listen 127.0.0.1:6007] [--threads N] [--timeout 10m]",
+		Flags:    []cli.Flag{listenFlag, threadsFlag, timeoutFlag},
+		Category: "LOCAL COMMANDS",
+		Description: `
+Run a pow worker serving the generate_work, validate_work and cancel_work api of the remote pow service,
+the works are solved by parallel workers on the cpus. Set PowServerUrl of the node config to
+http://<listen> to use it. The worker is stopped on SIGINT or SIGTERM.
+`,
+	}
+)
+
+func powWorkerAction(ctx *cli.Context) error {
+	server := remote.NewServer(ctx.Int(threadsFlag.Name), ctx.Duration(timeoutFlag.Name))
+	listener, err := net.Listen("tcp", ctx.String(listenFlag.Name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("pow worker listens on %s with %d threads\n", listener.Addr(), server.Threads())
+
+	httpServer := &http.Server{Handler: server}
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		fmt.Printf("receive %s, stop the pow worker\n", sig)
+		return httpServer.Close()
+	case err := <-served:
+		return err
+	}
+}
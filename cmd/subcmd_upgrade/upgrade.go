@@ -0,0 +1,132 @@
+package subcmd_upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/vitelabs/go-vite/cmd/nodemanager"
+	"github.com/vitelabs/go-vite/cmd/utils"
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/upgrade"
+)
+
+var (
+	heightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "the snapshot height",
+	}
+	versionFlag = cli.UintFlag{
+		Name:  "version",
+		Usage: "the upgrade version to schedule",
+	}
+	nameFlag = cli.StringFlag{
+		Name:  "name",
+		Usage: "the name of the upgrade point",
+	}
+	genesisFlag = cli.StringFlag{
+		Name:  "genesis",
+		Usage: "the genesis file to rewrite",
+	}
+
+	UpgradeCommand = cli.Command{
+		Name:     "upgrade",
+		Usage:    "upgrade schedule|simulate",
+		Category: "LOCAL COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Action: utils.MigrateFlags(scheduleAction),
+				Name:   "schedule",
+				Usage:  "schedule [--height N]",
+				Flags:  append([]cli.Flag{heightFlag}, utils.ConfigFlags...),
+				Description: `
+Print the upgrade points of the configured genesis in json, whether they are active at the height,
+and the code paths gated by every version.
+`,
+			},
+			{
+				Action: utils.MigrateFlags(simulateAction),
+				Name:   "simulate",
+				Usage:  "simulate --genesis file --version V --height N [--name name]",
+				Flags:  []cli.Flag{genesisFlag, versionFlag, heightFlag, nameFlag},
+				Description: `
+Schedule the upgrade version at the height in the genesis file, the version is added if it doesn't exist.
+The upgrade config of the genesis is rewritten as a custom config. Run it on the genesis of a new devnet
+(see devnet init) before starting the nodes to simulate a network with a new upgrade point.
+`,
+			},
+		},
+		Description: `Upgrade (hard fork) schedule tools.`,
+	}
+)
+
+func scheduleAction(ctx *cli.Context) error {
+	node, err := nodemanager.LocalNodeMaker{}.MakeNode(ctx)
+	if err != nil {
+		return err
+	}
+	viteConfig := node.ViteConfig()
+
+	points := viteConfig.Genesis.UpgradeCfg.MakeUpgradeBox().UpgradePoints()
+	return printJson(upgrade.SimulatePointStatus(points, ctx.Uint64(heightFlag.Name)))
+}
+
+func simulateAction(ctx *cli.Context) error {
+	genesisFile := ctx.String(genesisFlag.Name)
+	if len(genesisFile) == 0 {
+		return fmt.Errorf("--%s is required", genesisFlag.Name)
+	}
+	version := ctx.Uint(versionFlag.Name)
+	if version == 0 {
+		return fmt.Errorf("--%s is required", versionFlag.Name)
+	}
+	height := ctx.Uint64(heightFlag.Name)
+	if height == 0 {
+		return fmt.Errorf("--%s is required", heightFlag.Name)
+	}
+
+	data, err := ioutil.ReadFile(genesisFile)
+	if err != nil {
+		return err
+	}
+	genesis := &config.Genesis{}
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return fmt.Errorf("invalid genesis file %s. Error: %s", genesisFile, err)
+	}
+	if genesis.UpgradeCfg == nil {
+		return fmt.Errorf("no upgrade config in the genesis file %s", genesisFile)
+	}
+
+	points, err := upgrade.SimulatePoint(genesis.UpgradeCfg.MakeUpgradeBox(), ctx.String(nameFlag.Name), uint32(version), height)
+	if err != nil {
+		return err
+	}
+	genesis.UpgradeCfg = &config.Upgrade{
+		Level:  "custom",
+		Points: make(map[string]*upgrade.UpgradePoint, len(points)),
+	}
+	for _, point := range points {
+		genesis.UpgradeCfg.Points[strconv.FormatUint(uint64(point.Version), 10)] = point
+	}
+
+	data, err = json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(genesisFile, data, 0600); err != nil {
+		return err
+	}
+	return printJson(upgrade.SimulatePointStatus(points, height))
+}
+
+func printJson(v interface{}) error {
+	output, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
@@ -122,6 +122,10 @@ var (
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
 	}
+	ScriptFlag = cli.StringFlag{
+		Name:  "script",
+		Usage: "Run the JavaScript file non-interactively, the exit code is set by exit(code) or 1 if the script throws",
+	}
 
 	//Producer
 	MinerFlag = cli.BoolFlag{
@@ -289,6 +293,7 @@ var (
 		JSPathFlag,
 		ExecFlag,
 		PreloadJSFlag,
+		ScriptFlag,
 	}
 
 	//Producer
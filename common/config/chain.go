@@ -1,6 +1,10 @@
 package config
 
-import "github.com/vitelabs/go-vite/common/types"
+import (
+	"fmt"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
 
 // chain config
 type Chain struct {
@@ -11,4 +15,126 @@ type Chain struct {
 
 	VmLogWhiteList []types.Address // contract address white list which save VM logs
 	VmLogAll       bool            // save all VM logs, it will cost more disk space
+
+	// storage backend of the stores, the key is one of "index", "state", "redo" and "plugins",
+	// the value is "leveldb", "btree" or "logstore". The default backend is leveldb. btree keeps the store in a
+	// copy-on-write B+tree file without background compaction. logstore keeps the whole store in memory and is
+	// meant for small stores with few deletes like plugins, it can't be used by the redo store.
+	StoreBackends map[string]string
+
+	// pruning mode of the ledger data, one of "archive", "full" and "pruned". The default mode is archive.
+	// full mode prunes the VM logs and the state history older than PruneRetainDays,
+	// pruned mode also prunes the block bodies and keeps the block headers.
+	PruneMode string
+	// days of the data to retain in full and pruned mode
+	PruneRetainDays uint64
+	// max keys deleted per second by the background pruner
+	PruneKeysPerSecond uint64
+
+	// compact the account chains in background, the confirmed account blocks of every 1000 heights are indexed by
+	// a checkpoint, so the range queries of the old heights don't iterate the index of every height
+	AccountCompaction bool
+	// the accounts to compact, all accounts are compacted if empty
+	AccountCompactionList []types.Address
+	// copy the account blocks of the checkpoints to the compressed segment files of the cold tier, the range queries
+	// of the old heights read one segment instead of every block from the block files. In pruned mode the block files
+	// are deleted by the pruner, the bodies of the blocks sealed in the segments are still read from the cold tier.
+	AccountColdTier bool
+
+	// the max interval between two flushes of the ledger data in milliseconds, the default is 900
+	FlushInterval uint64
+	// flush when the unflushed data reaches FlushDirtyBytes, 0 means no limit
+	FlushDirtyBytes uint64
+	// flush when FlushSnapshotCount snapshot blocks are inserted since the last flush, 0 means no limit
+	FlushSnapshotCount uint64
+	// fsync policy of the commits of the flushes, one of "redo", "always" and "none". The redo log is always synced
+	// before committing. The default is redo, which syncs the commits of the block files. always also syncs the
+	// commits of the other stores, none syncs no commit, the latest flushed data may be lost after a power failure.
+	FlushFsync string
+}
+
+const (
+	StoreIndex   = "index"
+	StoreState   = "state"
+	StoreRedo    = "redo"
+	StorePlugins = "plugins"
+)
+
+const (
+	StoreBackendLevelDb  = "leveldb"
+	StoreBackendBTree    = "btree"
+	StoreBackendLogStore = "logstore"
+)
+
+const (
+	PruneModeArchive = "archive"
+	PruneModeFull    = "full"
+	PruneModePruned  = "pruned"
+
+	DefaultPruneRetainDays    = 7
+	DefaultPruneKeysPerSecond = 10000
+)
+
+// GetStoreBackend returns the configured backend of the store, returns "" if not configured
+func (c *Chain) GetStoreBackend(store string) string {
+	if c == nil || c.StoreBackends == nil {
+		return ""
+	}
+	return c.StoreBackends[store]
+}
+
+// CheckStoreBackends returns an error if the storage backend config is invalid
+func (c *Chain) CheckStoreBackends() error {
+	if c == nil {
+		return nil
+	}
+	for store, backend := range c.StoreBackends {
+		switch store {
+		case StoreIndex, StoreState, StoreRedo, StorePlugins:
+		default:
+			return fmt.Errorf("unknown store %q in StoreBackends, should be one of %s, %s, %s and %s", store, StoreIndex, StoreState, StoreRedo, StorePlugins)
+		}
+		switch backend {
+		case "", StoreBackendLevelDb, StoreBackendBTree, StoreBackendLogStore:
+		default:
+			return fmt.Errorf("unknown backend %q of store %s, should be one of %s, %s and %s", backend, store, StoreBackendLevelDb, StoreBackendBTree, StoreBackendLogStore)
+		}
+	}
+	// the redo store deletes the logs of every flushed snapshot, logstore never frees them until the rewrite
+	if c.GetStoreBackend(StoreRedo) == StoreBackendLogStore {
+		return fmt.Errorf("the %s store can't use the %s backend, use %s or %s", StoreRedo, StoreBackendLogStore, StoreBackendLevelDb, StoreBackendBTree)
+	}
+	return nil
+}
+
+// GetPruneMode returns the pruning mode, returns archive if not configured
+func (c *Chain) GetPruneMode() string {
+	if c == nil || len(c.PruneMode) <= 0 {
+		return PruneModeArchive
+	}
+	return c.PruneMode
+}
+
+// CheckPruneMode returns an error if the pruning config is invalid
+func (c *Chain) CheckPruneMode() error {
+	switch c.GetPruneMode() {
+	case PruneModeArchive, PruneModeFull, PruneModePruned:
+	default:
+		return fmt.Errorf("unknown prune mode %q, should be one of %s, %s and %s", c.PruneMode, PruneModeArchive, PruneModeFull, PruneModePruned)
+	}
+	if c.GetPruneMode() != PruneModeArchive && c.PruneRetainDays <= 0 {
+		return fmt.Errorf("PruneRetainDays should be greater than 0 in %s mode", c.PruneMode)
+	}
+	return nil
+}
+
+// CheckAccountCompaction returns an error if the account compaction config is invalid
+func (c *Chain) CheckAccountCompaction() error {
+	if c == nil || !c.AccountColdTier {
+		return nil
+	}
+	if !c.AccountCompaction {
+		return fmt.Errorf("AccountColdTier requires AccountCompaction")
+	}
+	return nil
 }
@@ -15,6 +15,7 @@ type Config struct {
 	*Net        `json:"Net"`
 	*NodeReward `json:"Reward"`
 	*Genesis    `json:"Genesis"`
+	*Registry   `json:"Registry"`
 
 	// global keys
 	DataDir string `json:"DataDir"`
@@ -18,6 +18,7 @@ type Genesis struct {
 	QuotaInfo             *QuotaContractInfo
 	AccountBalanceMap     map[string]map[string]*big.Int // address - tokenId - balanceAmount
 	DexFundInfo           *DexFundContractInfo
+	ContractInfoMap       map[string]*GenesisContractInfo // pre-deployed contracts, contract address - info
 }
 
 func (g *Genesis) UnmarshalJSON(data []byte) error {
@@ -62,6 +63,16 @@ type AssetContractInfo struct {
 	LogList      []*GenesisVmLog       // issue events
 }
 
+// GenesisContractInfo is a contract deployed in the genesis, the balances of the contract are set in AccountBalanceMap
+type GenesisContractInfo struct {
+	Gid                types.Gid
+	Code               string            // runtime code in hex
+	Storage            map[string]string // storage key in hex - storage value in hex
+	SendConfirmedTimes uint8
+	SeedConfirmedTimes uint8
+	QuotaRatio         uint8
+}
+
 type DexFundContractInfo struct {
 	Owner *types.Address
 }
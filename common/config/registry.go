@@ -0,0 +1,6 @@
+package config
+
+type Registry struct {
+	AbiDir     string `json:"AbiDir"`     // directory of contract abi files loaded on startup, named by contract address
+	SolppcPath string `json:"SolppcPath"` // path of the local solppc used to verify contract sources
+}
@@ -3,4 +3,14 @@ package config
 type Wallet struct {
 	DataDir        string
 	MaxSearchIndex uint32
+
+	// kdf of the new and upgraded entropy store files, scrypt or argon2id, the zero costs use the defaults.
+	// The new files are written in the version 2 only if the kdf is set, otherwise in the first version.
+	KeystoreKDF          string
+	KeystoreScryptN      int
+	KeystoreArgon2Time   uint32
+	KeystoreArgon2Memory uint32 // in KiB
+
+	// upgrade the old version entropy store files to the latest version when they're unlocked
+	KeystoreUpgrade bool
 }
@@ -1,31 +1,126 @@
 package common
 
 import (
+	"fmt"
 	"io"
 	"path/filepath"
+	"sync"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/vitelabs/go-vite/log15"
 )
 
-func makeDefaultLogger(absFilePath string) io.Writer {
+const (
+	LogFormatLogfmt = "logfmt"
+	LogFormatJson   = "json"
+)
+
+// LogConfig is the format and the rotation of the log files, the files are rotated when they reach MaxSize MB, and
+// the rotated ones are removed after MaxAge days or when there are more than MaxBackups of them. MaxAge and
+// MaxBackups are pointers since 0 keeps the rotated files regardless of their age or number, nil is the default.
+type LogConfig struct {
+	Format     string
+	MaxSize    int
+	MaxAge     *int
+	MaxBackups *int
+}
+
+var (
+	// LogLevels are the levels of the modules shared by the log files, they can be changed at runtime
+	LogLevels = log15.NewModuleLevels()
+
+	logConfigMu sync.RWMutex
+	logConfig   = DefaultLogConfig()
+)
+
+func DefaultLogConfig() LogConfig {
+	maxAge, maxBackups := 14, 14
+	return LogConfig{
+		Format:     LogFormatLogfmt,
+		MaxSize:    100,
+		MaxAge:     &maxAge,
+		MaxBackups: &maxBackups,
+	}
+}
+
+// SetLogConfig sets the format and the rotation of the log files created later, the unset fields keep the defaults,
+// a MaxSize of 0 is unset too
+func SetLogConfig(c LogConfig) error {
+	def := DefaultLogConfig()
+	switch c.Format {
+	case "":
+		c.Format = def.Format
+	case LogFormatLogfmt, LogFormatJson:
+	default:
+		return fmt.Errorf("unknown log format %v", c.Format)
+	}
+	if c.MaxSize < 0 || (c.MaxAge != nil && *c.MaxAge < 0) || (c.MaxBackups != nil && *c.MaxBackups < 0) {
+		return fmt.Errorf("negative log rotation")
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = def.MaxSize
+	}
+	if c.MaxAge == nil {
+		c.MaxAge = def.MaxAge
+	} else {
+		maxAge := *c.MaxAge
+		c.MaxAge = &maxAge
+	}
+	if c.MaxBackups == nil {
+		c.MaxBackups = def.MaxBackups
+	} else {
+		maxBackups := *c.MaxBackups
+		c.MaxBackups = &maxBackups
+	}
+	logConfigMu.Lock()
+	defer logConfigMu.Unlock()
+	logConfig = c
+	return nil
+}
+
+func getLogConfig() LogConfig {
+	logConfigMu.RLock()
+	defer logConfigMu.RUnlock()
+	return logConfig
+}
+
+func makeDefaultLogger(absFilePath string, c LogConfig) io.Writer {
 	return &lumberjack.Logger{
 		Filename:   absFilePath,
-		MaxSize:    100,
-		MaxBackups: 14,
-		MaxAge:     14,
+		MaxSize:    c.MaxSize,
+		MaxBackups: *c.MaxBackups,
+		MaxAge:     *c.MaxAge,
 		Compress:   true,
 		LocalTime:  true,
 	}
 }
 
+func logFormat(c LogConfig) log15.Format {
+	if c.Format == LogFormatJson {
+		return log15.JsonFormat()
+	}
+	return log15.LogfmtFormat()
+}
+
+// LogHandler returns the handler writing into the rotated file, the records are filtered by the levels of their
+// modules in LogLevels, or by lvl if their modules have no levels.
 func LogHandler(path, subDir, filename, lvl string) log15.Handler {
 	logLevel, err := log15.LvlFromString(lvl)
 	if err != nil {
 		logLevel = log15.LvlInfo
 	}
+	return log15.ModuleLvlFilterHandler(LogLevels, logLevel, logFileHandler(path, subDir, filename))
+}
+
+// LogFileHandler returns the handler writing the records not above lvl into the rotated file regardless of the
+// module levels, e.g. for the error log.
+func LogFileHandler(path, subDir, filename string, lvl log15.Lvl) log15.Handler {
+	return log15.LvlFilterHandler(lvl, logFileHandler(path, subDir, filename))
+}
+
+func logFileHandler(path, subDir, filename string) log15.Handler {
+	c := getLogConfig()
 	absFilename := filepath.Join(path, subDir, filename)
-	out := makeDefaultLogger(absFilename)
-	return log15.LvlFilterHandler(logLevel, log15.StreamHandler(out, log15.LogfmtFormat()))
+	return log15.StreamHandler(makeDefaultLogger(absFilename, c), logFormat(c))
 }
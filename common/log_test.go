@@ -0,0 +1,67 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/vitelabs/go-vite/log15"
+)
+
+func TestLogHandler_Json(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log_test")
+	assert.NoError(t, err)
+	defer func() {
+		os.RemoveAll(dir)
+		SetLogConfig(DefaultLogConfig())
+		LogLevels.Unset("test")
+	}()
+
+	assert.Error(t, SetLogConfig(LogConfig{Format: "xml"}))
+	assert.NoError(t, SetLogConfig(LogConfig{Format: LogFormatJson}))
+	l := log15.New(log15.ModuleKey, "test")
+	l.SetHandler(LogHandler(dir, "", "test.log", "info"))
+
+	l.Debug("hidden")
+	LogLevels.Set("test", log15.LvlDebug)
+	l.Debug("shown", "height", 1)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "test.log"))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, 1, len(lines))
+	record := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "shown", record["msg"])
+	assert.Equal(t, "test", record["module"])
+	assert.Equal(t, float64(1), record["height"])
+}
+
+func TestSetLogConfig_Rotation(t *testing.T) {
+	defer SetLogConfig(DefaultLogConfig())
+
+	assert.NoError(t, SetLogConfig(LogConfig{}))
+	c := getLogConfig()
+	assert.Equal(t, 100, c.MaxSize)
+	assert.Equal(t, 14, *c.MaxAge)
+	assert.Equal(t, 14, *c.MaxBackups)
+
+	maxAge, maxBackups := 0, 3
+	assert.NoError(t, SetLogConfig(LogConfig{MaxAge: &maxAge, MaxBackups: &maxBackups}))
+	maxAge = 7
+	c = getLogConfig()
+	assert.Equal(t, 0, *c.MaxAge)
+	assert.Equal(t, 3, *c.MaxBackups)
+	writer := makeDefaultLogger("test.log", c).(*lumberjack.Logger)
+	assert.Equal(t, 0, writer.MaxAge)
+	assert.Equal(t, 3, writer.MaxBackups)
+
+	maxBackups = -1
+	assert.Error(t, SetLogConfig(LogConfig{MaxBackups: &maxBackups}))
+}
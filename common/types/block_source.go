@@ -12,3 +12,24 @@ const (
 	RemoteSync                  = 50
 	RemoteCache                 = 60
 )
+
+func (s BlockSource) String() string {
+	switch s {
+	case RemoteBroadcast:
+		return "broadcast"
+	case RemoteFetch:
+		return "fetch"
+	case Local:
+		return "local"
+	case RollbackChain:
+		return "rollback"
+	case QueryChain:
+		return "query"
+	case RemoteSync:
+		return "sync"
+	case RemoteCache:
+		return "cache"
+	default:
+		return "unknown"
+	}
+}
@@ -155,3 +155,13 @@ func IsVersionXUpgrade(sHeight uint64) bool {
 	assertUpgradeNotNil()
 	return upgrade.isActive(11, sHeight)
 }
+
+/*
+IsMultisigUpgrade checks whether current snapshot block height is over multisig hard fork.
+Features:
+  1. Built-in multi-signature contract, funds are held under an M-of-N owner set with time locks.
+*/
+func IsMultisigUpgrade(sHeight uint64) bool {
+	assertUpgradeNotNil()
+	return upgrade.isActive(12, sHeight)
+}
@@ -0,0 +1,167 @@
+package upgrade
+
+import (
+	"fmt"
+	"sort"
+)
+
+// versionGate is an upgrade version gated by the code of this binary
+type versionGate struct {
+	name        string
+	description string
+	codePaths   []string
+}
+
+// versionGates is the table of the upgrade versions gated by the code of this binary, the version of a gate is its
+// index plus 1. A gate must be appended when a new IsXUpgrade is added.
+var versionGates = []versionGate{
+	{"SeedFork", "Vm log list hash adds account address and prevHash, create contract params add seed count, SEED opcode",
+		[]string{"interfaces/core/vm_log_list.go", "ledger/generator/generator.go", "ledger/onroad/contract.go",
+			"ledger/verifier/account_verifier.go", "vm/interpreter.go", "vm/util/common.go", "vm/vm.go"}},
+	{"DexFork", "Dynamic quota acquisition, quota consumption adjustment, ViteX decentralized exchange",
+		[]string{"vm/contracts/contracts.go", "vm/quota/quota.go", "vm/util/quota.go"}},
+	{"DexFeeFork", "Emergency fix of the ViteX fee of a wrongly placed order",
+		[]string{"vm/contracts/dex/fund_helper.go"}},
+	{"StemFork", "Placing and cancelling orders via delegation, super VIP membership",
+		[]string{"vm/contracts/contracts.go", "vm/contracts/contracts_governance.go", "vm/contracts/dex/fund_helper.go", "vm/util/quota.go"}},
+	{"LeafFork", "Snapshot block version in the snapshot block hash",
+		[]string{"interfaces/core/snapshot_block.go", "producer/tools.go", "vm/contracts/contracts.go",
+			"vm/contracts/contracts_governance.go", "vm/contracts/dex/fund_helper.go"}},
+	{"EarthFork", "Earth fork",
+		[]string{"vm/contracts/contracts.go", "vm/contracts/contracts_asset.go", "vm/contracts/contracts_governance.go",
+			"vm/contracts/dex/fund_helper.go", "vm/gas_table.go", "vm/interpreter.go", "vm/util/quota.go", "vm/vm.go"}},
+	{"DexMiningFork", "ViteX mining",
+		[]string{"vm/contracts/dex/fund_helper.go"}},
+	{"DexRobotFork", "ViteX robot",
+		[]string{"vm/contracts/contracts.go", "vm/contracts/dex/fund_helper.go", "vm/util/quota.go"}},
+	{"DexStableMarketFork", "ViteX stable market",
+		[]string{"vm/contracts/contracts.go", "vm/contracts/dex/fund_helper.go", "vm/util/quota.go"}},
+	{"Version10", "ViteX mining rates of the fee and the maker and maintainer mining, new inviter fee, quota table adjustment",
+		[]string{"vm/contracts/contracts_dex_fund.go", "vm/contracts/dex/fund_helper.go", "vm/contracts/dex/fund_mine.go", "vm/util/quota.go"}},
+	{"VersionX", "ViteX transfer, agent deposit, assigned withdraw and order enrichment, burn method of the asset contract, quota table adjustment",
+		[]string{"vm/contracts/contracts.go", "vm/contracts/contracts_asset.go", "vm/contracts/contracts_dex_fund.go",
+			"vm/contracts/contracts_dex_trade.go", "vm/contracts/dex/fund_helper.go", "vm/contracts/dex/matcher.go", "vm/util/quota.go"}},
+	{"MultisigFork", "Multi-signature built-in contract",
+		[]string{"vm/contracts/contracts.go", "vm/contracts/contracts_multisig.go", "vm/util/quota.go"}},
+}
+
+// MaxSupportedVersion is the highest upgrade version gated by the code of this binary
+var MaxSupportedVersion = uint32(len(versionGates))
+
+// getVersionGate returns the gate of the version, the gate is empty if the version is not supported
+func getVersionGate(version uint32) versionGate {
+	if version == 0 || version > MaxSupportedVersion {
+		return versionGate{}
+	}
+	return versionGates[version-1]
+}
+
+// PointStatus is the state of an upgrade point at a snapshot height
+type PointStatus struct {
+	Name    string `json:"name"`
+	Version uint32 `json:"version"`
+	Height  uint64 `json:"height"`
+	Active  bool   `json:"active"`
+	// false if the point is not scheduled, the height is EndlessHeight
+	Scheduled bool `json:"scheduled"`
+	// false if no code of this binary is gated by the version
+	Supported   bool     `json:"supported"`
+	Description string   `json:"description"`
+	CodePaths   []string `json:"codePaths"`
+}
+
+// GetPointStatus returns the state of all upgrade points at the snapshot height
+func GetPointStatus(sHeight uint64) []*PointStatus {
+	assertUpgradeNotNil()
+	return pointStatus(upgrade.UpgradePoints(), sHeight)
+}
+
+func pointStatus(points []*UpgradePoint, sHeight uint64) []*PointStatus {
+	result := make([]*PointStatus, 0, len(points))
+	for _, point := range points {
+		gate := getVersionGate(point.Version)
+		result = append(result, &PointStatus{
+			Name:        point.Name,
+			Version:     point.Version,
+			Height:      point.Height,
+			Active:      sHeight >= point.Height,
+			Scheduled:   point.Height < EndlessHeight,
+			Supported:   point.Version <= MaxSupportedVersion,
+			Description: gate.description,
+			CodePaths:   gate.codePaths,
+		})
+	}
+	return result
+}
+
+// CheckProducerVersion returns an error if the version of the snapshot block at the height can't be run by the
+// local node, the producers set the version to the latest scheduled version of their binaries
+func CheckProducerVersion(sHeight uint64, version uint32) error {
+	assertUpgradeNotNil()
+	if version == 0 {
+		return nil
+	}
+	if version > MaxSupportedVersion {
+		return fmt.Errorf("the producers use the upgrade version %d at snapshot height %d, but this binary only supports the versions up to %d, upgrade gvite as soon as possible",
+			version, sHeight, MaxSupportedVersion)
+	}
+	point := upgrade.getUpgradePoint(version)
+	if point == nil || point.Height >= EndlessHeight {
+		return fmt.Errorf("the producers use the upgrade version %d at snapshot height %d, but it is not scheduled by the local upgrade config, upgrade gvite or the upgrade config as soon as possible",
+			version, sHeight)
+	}
+	return nil
+}
+
+// CheckUpgradePoints returns an error if the versions of the points are not continuous from 1 or the heights are decreasing
+func CheckUpgradePoints(points []*UpgradePoint) error {
+	sorted := make([]*UpgradePoint, len(points))
+	copy(sorted, points)
+	sort.Sort(byVersion(sorted))
+
+	lastHeight := uint64(0)
+	for index, point := range sorted {
+		if index != int(point.Version)-1 {
+			return fmt.Errorf("the upgrade version %d is missing", index+1)
+		}
+		if point.Height < lastHeight {
+			return fmt.Errorf("the height %d of the upgrade version %d is lower than the height %d of the last version", point.Height, point.Version, lastHeight)
+		}
+		lastHeight = point.Height
+	}
+	return nil
+}
+
+// SimulatePoint returns the points of the box with the version scheduled at the height, the version is added if it
+// doesn't exist. It is used to simulate a network with a new upgrade point.
+func SimulatePoint(box UpgradeBox, name string, version uint32, height uint64) ([]*UpgradePoint, error) {
+	var points []*UpgradePoint
+	found := false
+	for _, point := range box.UpgradePoints() {
+		p := *point
+		if p.Version == version {
+			p.Height = height
+			if name != "" {
+				p.Name = name
+			}
+			found = true
+		}
+		points = append(points, &p)
+	}
+	if !found {
+		if name == "" {
+			name = fmt.Sprintf("Version%d", version)
+		}
+		points = append(points, &UpgradePoint{Name: name, Version: version, Height: height})
+	}
+	if err := CheckUpgradePoints(points); err != nil {
+		return nil, err
+	}
+	sort.Sort(byVersion(points))
+	return points, nil
+}
+
+// SimulatePointStatus returns the state of the points at the snapshot height without the global upgrade box
+func SimulatePointStatus(points []*UpgradePoint, sHeight uint64) []*PointStatus {
+	return pointStatus(points, sHeight)
+}
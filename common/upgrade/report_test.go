@@ -0,0 +1,66 @@
+package upgrade
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionGates(t *testing.T) {
+	points := NewMainnetUpgradeBox().UpgradePoints()
+	assert.Equal(t, MaxSupportedVersion, points[len(points)-1].Version)
+
+	for index, gate := range versionGates {
+		// the names of the gates are the names of the mainnet points
+		assert.Equal(t, points[index].Name, gate.name)
+		assert.NotEmpty(t, gate.description, gate.name)
+		for _, codePath := range gate.codePaths {
+			_, err := os.Stat(path.Join("..", "..", codePath))
+			assert.NoError(t, err, codePath)
+		}
+	}
+}
+
+func TestCheckProducerVersion(t *testing.T) {
+	cleanupUpgradeBox()
+	InitUpgradeBox(NewMainnetUpgradeBox())
+	defer cleanupUpgradeBox()
+
+	assert.NoError(t, CheckProducerVersion(100, 0))
+	assert.NoError(t, CheckProducerVersion(100, 10))
+	// version 11 is not scheduled on the mainnet
+	assert.Error(t, CheckProducerVersion(100, 11))
+	assert.Error(t, CheckProducerVersion(100, MaxSupportedVersion+1))
+
+	status := GetPointStatus(9413600)
+	assert.Equal(t, 12, len(status))
+	assert.True(t, status[4].Active)
+	assert.False(t, status[5].Active)
+	assert.False(t, status[10].Scheduled)
+	assert.Equal(t, versionGates[9].description, status[9].Description)
+}
+
+func TestSimulatePoint(t *testing.T) {
+	points, err := SimulatePoint(NewMainnetUpgradeBox(), "", 11, 80000000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(80000000), points[10].Height)
+	assert.Equal(t, "VersionX", points[10].Name)
+	// the box is not changed
+	assert.Equal(t, EndlessHeight, NewMainnetUpgradeBox().UpgradePoints()[10].Height)
+
+	points, err = SimulatePoint(NewMainnetUpgradeBox(), "", 13, EndlessHeight)
+	assert.NoError(t, err)
+	assert.Equal(t, 13, len(points))
+	status := SimulatePointStatus(points, 100)
+	assert.True(t, status[11].Supported)
+	assert.False(t, status[12].Supported)
+
+	// lower than the last version
+	_, err = SimulatePoint(NewMainnetUpgradeBox(), "", 11, 100)
+	assert.Error(t, err)
+	// version 14 without 13
+	_, err = SimulatePoint(NewMainnetUpgradeBox(), "", 14, EndlessHeight)
+	assert.Error(t, err)
+}
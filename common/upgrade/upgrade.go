@@ -12,15 +12,8 @@ type upgradeBox struct {
 }
 
 func (box upgradeBox) checkBox() {
-	lastHeight := uint64(0)
-	for index, ele := range box.sortedPoints {
-		if index != int(ele.Version)-1 {
-			panic("error version in upgrade box")
-		}
-		if ele.Height < lastHeight {
-			panic("error height in upgrade box")
-		}
-		lastHeight = ele.Height
+	if err := CheckUpgradePoints(box.sortedPoints); err != nil {
+		panic(fmt.Sprintf("error upgrade box, %s", err))
 	}
 }
 
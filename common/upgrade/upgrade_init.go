@@ -1,52 +1,16 @@
 package upgrade
 
+// NewLatestUpgradeBox returns the box with all the versions supported by this binary active from the genesis
 func NewLatestUpgradeBox() *upgradeBox {
-	return newUpgradeBox([]*UpgradePoint{
-		{
+	points := make([]*UpgradePoint, 0, len(versionGates))
+	for index, gate := range versionGates {
+		points = append(points, &UpgradePoint{
+			Name:    gate.name,
 			Height:  1,
-			Version: 1,
-		},
-		{
-			Height:  1,
-			Version: 2,
-		},
-		{
-			Height:  1,
-			Version: 3,
-		},
-		{
-			Height:  1,
-			Version: 4,
-		},
-		{
-			Height:  1,
-			Version: 5,
-		},
-		{
-			Height:  1,
-			Version: 6,
-		},
-		{
-			Height:  1,
-			Version: 7,
-		},
-		{
-			Height:  1,
-			Version: 8,
-		},
-		{
-			Height:  1,
-			Version: 9,
-		},
-		{
-			Height:  1,
-			Version: 10,
-		},
-		{
-			Height:  1,
-			Version: 11,
-		},
-	})
+			Version: uint32(index + 1),
+		})
+	}
+	return newUpgradeBox(points)
 }
 
 func NewMainnetUpgradeBox() *upgradeBox {
@@ -106,6 +70,11 @@ func NewMainnetUpgradeBox() *upgradeBox {
 			Height:  EndlessHeight,
 			Version: 11,
 		},
+		{
+			Name:    "MultisigFork",
+			Height:  EndlessHeight,
+			Version: 12,
+		},
 	})
 }
 
@@ -72,6 +72,10 @@ func TestMainnetUpgradeBox(t *testing.T) {
 			IsVersionXUpgrade,
 			EndlessHeight,
 		},
+		{
+			IsMultisigUpgrade,
+			EndlessHeight,
+		},
 	}
 	for _, ele := range cases {
 		testUpgradePoint(t, ele.fc, ele.sHeight)
@@ -132,6 +136,10 @@ func TestLatestUpgradeBox(t *testing.T) {
 			IsVersionXUpgrade,
 			1,
 		},
+		{
+			IsMultisigUpgrade,
+			1,
+		},
 	}
 	for _, ele := range cases {
 		testUpgradePoint(t, ele.fc, ele.sHeight)
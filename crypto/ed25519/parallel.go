@@ -0,0 +1,58 @@
+package ed25519
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minSigsPerWorker is the least signatures a worker of VerifyParallel verifies, fewer are not worth the goroutines
+const minSigsPerWorker = 16
+
+// VerifyParallel reports whether each sigs[i] is a valid signature of messages[i] by publicKeys[i]. It is not a
+// batch verification in the cryptographic sense: every signature is still checked on its own by Verify, just by
+// the workers on all the cpus, so it's only faster with enough cores and gives the same result as Verify for each
+// entry. An entry whose public key is not PublicKeySize is invalid instead of panicking as Verify. It panics if the
+// lengths of the slices differ.
+//
+// The batch verification checking one random linear combination of the signatures is deferred on purpose. Verify
+// is cofactorless while a sound batch equation is cofactored, so a signature with a small order component can pass
+// one and fail the other, and without the cofactor the random coefficients can cancel such components of several
+// signatures. A node verifying in batches could accept a block the other nodes reject.
+func VerifyParallel(publicKeys []PublicKey, messages, sigs [][]byte) []bool {
+	n := len(publicKeys)
+	if len(messages) != n || len(sigs) != n {
+		panic("ed25519: the lengths of the signatures differ")
+	}
+	results := make([]bool, n)
+
+	workers := runtime.NumCPU()
+	if max := (n + minSigsPerWorker - 1) / minSigsPerWorker; workers > max {
+		workers = max
+	}
+	if workers <= 1 {
+		verifyRange(publicKeys, messages, sigs, results, 0, n)
+		return results
+	}
+
+	var wg sync.WaitGroup
+	size := (n + workers - 1) / workers
+	for from := 0; from < n; from += size {
+		to := from + size
+		if to > n {
+			to = n
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			verifyRange(publicKeys, messages, sigs, results, from, to)
+		}(from, to)
+	}
+	wg.Wait()
+	return results
+}
+
+func verifyRange(publicKeys []PublicKey, messages, sigs [][]byte, results []bool, from, to int) {
+	for i := from; i < to; i++ {
+		results[i] = len(publicKeys[i]) == PublicKeySize && Verify(publicKeys[i], messages[i], sigs[i])
+	}
+}
@@ -0,0 +1,41 @@
+package ed25519
+
+import (
+	"crypto/rand"
+	"strconv"
+	"testing"
+)
+
+func TestVerifyParallel(t *testing.T) {
+	n := 100
+	pubs := make([]PublicKey, n)
+	messages := make([][]byte, n)
+	sigs := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		pub, priv, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs[i] = pub
+		messages[i] = []byte("message " + strconv.Itoa(i))
+		sigs[i] = Sign(priv, messages[i])
+	}
+	// a wrong message, a bad public key and a short signature
+	messages[7] = []byte("forged")
+	pubs[42] = pubs[42][:10]
+	sigs[99] = sigs[99][:32]
+
+	for i, ok := range VerifyParallel(pubs, messages, sigs) {
+		expected := i != 7 && i != 42 && i != 99
+		if ok != expected {
+			t.Errorf("signature %d: expected %v, got %v", i, expected, ok)
+		}
+	}
+
+	if results := VerifyParallel(pubs[:1], messages[:1], sigs[:1]); !results[0] {
+		t.Error("a single signature should be verified")
+	}
+	if results := VerifyParallel(nil, nil, nil); len(results) != 0 {
+		t.Error("no signatures should have no results")
+	}
+}
@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"errors"
 	"io"
 	"math/big"
 	"strconv"
@@ -44,6 +45,9 @@ type Store interface {
 	Has([]byte) (bool, error)
 }
 
+// ErrPruned is returned when the block bodies or the data of the blocks have been pruned by the node
+var ErrPruned = errors.New("the data has been pruned")
+
 type LedgerReader interface {
 	Seg() Segment
 	Size() int
@@ -1,6 +1,9 @@
 package core
 
-import "github.com/vitelabs/go-vite/common/types"
+import (
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/common/upgrade"
+)
 
 type ContractMeta struct {
 	Gid types.Gid // belong to the consensus group id
@@ -55,14 +58,44 @@ func (cm *ContractMeta) Deserialize(buf []byte) error {
 	return nil
 }
 
-func GetBuiltinContractMeta(addr types.Address) *ContractMeta {
+// GetBuiltinContractMeta returns the meta of the built-in contract in use at the snapshot height, returns nil if
+// the address is not a built-in contract in use
+func GetBuiltinContractMeta(addr types.Address, sbHeight uint64) *ContractMeta {
 	if types.IsBuiltinContractAddrInUseWithSendConfirm(addr) {
 		return &ContractMeta{types.DELEGATE_GID, 1, types.Hash{}, getBuiltinContractQuotaRatio(addr), 0}
-	} else if types.IsBuiltinContractAddrInUse(addr) {
+	} else if IsBuiltinContractAddrInUse(addr, sbHeight) {
 		return &ContractMeta{types.DELEGATE_GID, 0, types.Hash{}, getBuiltinContractQuotaRatio(addr), 0}
 	}
 	return nil
 }
+
+// IsBuiltinContractAddrInUse returns true if the address is a built-in contract in use at the snapshot height,
+// the multisig contract is in use since the multisig upgrade
+func IsBuiltinContractAddrInUse(addr types.Address, sbHeight uint64) bool {
+	if addr == types.AddressMultisig {
+		return upgrade.IsMultisigUpgrade(sbHeight)
+	}
+	return types.IsBuiltinContractAddrInUse(addr)
+}
+
+// IsBuiltinContractAddrInUseWithoutQuota returns true if the address is a built-in contract in use at the snapshot
+// height which doesn't consume quota
+func IsBuiltinContractAddrInUseWithoutQuota(addr types.Address, sbHeight uint64) bool {
+	if addr == types.AddressMultisig {
+		return upgrade.IsMultisigUpgrade(sbHeight)
+	}
+	return types.IsBuiltinContractAddrInUseWithoutQuota(addr)
+}
+
+// GetBuiltinContractList returns the built-in contracts in use at the snapshot height
+func GetBuiltinContractList(sbHeight uint64) []types.Address {
+	list := make([]types.Address, 0, len(types.BuiltinContracts)+1)
+	list = append(list, types.BuiltinContracts...)
+	if upgrade.IsMultisigUpgrade(sbHeight) {
+		list = append(list, types.AddressMultisig)
+	}
+	return list
+}
 func getBuiltinContractQuotaRatio(addr types.Address) uint8 {
 	// TODO use special quota ratio for dex contracts
 	return 10
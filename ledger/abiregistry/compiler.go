@@ -0,0 +1,217 @@
+package abiregistry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	compileTimeout = 2 * time.Minute
+	sourceFileName = "contract.solpp"
+
+	labelBinary         = "Binary:"
+	labelRuntimeBinary  = "Binary of the runtime part:"
+	labelOffChainBinary = "OffChain Binary:"
+	labelAbi            = "Contract JSON ABI"
+)
+
+var (
+	ErrCompilerNotConfigured = errors.New("solppc path is not configured")
+	ErrCompilerVersion       = errors.New("version of the local solppc is not equal to the required compiler version")
+
+	contractHeaderRegexp = regexp.MustCompile(`^======= (.+):([^:\s]+) =======$`)
+	versionRegexp        = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)(\+commit\.[0-9a-fA-F]+)?`)
+)
+
+// CompileOptions are the solppc options used to compile a source
+type CompileOptions struct {
+	Optimize     bool `json:"optimize"`
+	OptimizeRuns int  `json:"optimizeRuns"`
+}
+
+// CompiledContract is the output of solppc for one contract
+type CompiledContract struct {
+	Name           string
+	Binary         []byte
+	RuntimeBinary  []byte
+	OffChainBinary []byte
+	Abi            json.RawMessage
+}
+
+// Compiler runs a local solppc binary. The binary is never downloaded, the path must be configured.
+type Compiler struct {
+	path string
+}
+
+func NewCompiler(path string) *Compiler {
+	return &Compiler{path: path}
+}
+
+// Version returns the output of `solppc --version`
+func (c *Compiler) Version() (string, error) {
+	if len(c.path) == 0 {
+		return "", ErrCompilerNotConfigured
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), compileTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, c.path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// parseVersion returns the semver and the commit, if any, of a version string such as
+// "Version: 0.4.3+commit.2f64e0ea.Linux.g++" or "v0.4.3"
+func parseVersion(version string) (semver string, commit string, ok bool) {
+	m := versionRegexp.FindStringSubmatch(version)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1] + "." + m[2] + "." + m[3], strings.TrimPrefix(m[4], "+commit."), true
+}
+
+// matchVersion reports whether the local compiler is the required version, the semvers must be equal, and the
+// commits too if the required version has one
+func matchVersion(localVersion, version string) bool {
+	semver, commit, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	localSemver, localCommit, ok := parseVersion(localVersion)
+	if !ok || semver != localSemver {
+		return false
+	}
+	return len(commit) == 0 || strings.EqualFold(commit, localCommit)
+}
+
+// Compile compiles the source with the required compiler version and returns contracts by name
+func (c *Compiler) Compile(source string, version string, options CompileOptions) (map[string]*CompiledContract, error) {
+	localVersion, err := c.Version()
+	if err != nil {
+		return nil, err
+	}
+	if !matchVersion(localVersion, version) {
+		return nil, ErrCompilerVersion
+	}
+
+	dir, err := ioutil.TempDir("", "solppc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	sourceFile := filepath.Join(dir, sourceFileName)
+	if err := ioutil.WriteFile(sourceFile, []byte(source), 0600); err != nil {
+		return nil, err
+	}
+
+	args := []string{"--bin", "--bin-runtime", "--abi"}
+	if options.Optimize {
+		args = append(args, "--optimize")
+		if options.OptimizeRuns > 0 {
+			args = append(args, "--optimize-runs", strconv.Itoa(options.OptimizeRuns))
+		}
+	}
+	args = append(args, sourceFile)
+
+	ctx, cancel := context.WithTimeout(context.Background(), compileTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.path, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("compile failed, %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseCompilerOutput(stdout.Bytes())
+}
+
+// parseCompilerOutput parses the combined output of `solppc --bin --bin-runtime --abi`
+func parseCompilerOutput(output []byte) (map[string]*CompiledContract, error) {
+	contracts := make(map[string]*CompiledContract)
+
+	var current *CompiledContract
+	var label string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if matches := contractHeaderRegexp.FindStringSubmatch(line); matches != nil {
+			current = &CompiledContract{Name: matches[2]}
+			contracts[current.Name] = current
+			label = ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch line {
+		case labelBinary, labelRuntimeBinary, labelOffChainBinary, labelAbi:
+			label = line
+			continue
+		}
+
+		var err error
+		switch label {
+		case labelBinary:
+			current.Binary, err = hex.DecodeString(line)
+		case labelRuntimeBinary:
+			current.RuntimeBinary, err = hex.DecodeString(line)
+		case labelOffChainBinary:
+			current.OffChainBinary, err = hex.DecodeString(line)
+		case labelAbi:
+			current.Abi = json.RawMessage(line)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of %s, %v", label, current.Name, err)
+		}
+		label = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(contracts) == 0 {
+		return nil, errors.New("no contract found in compiler output")
+	}
+	return contracts, nil
+}
+
+// stripMetadata removes the cbor encoded metadata appended by the compiler to the runtime code.
+// The last 2 bytes of the code are the length of the metadata.
+func stripMetadata(code []byte) []byte {
+	if len(code) < 2 {
+		return code
+	}
+	length := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	start := len(code) - 2 - length
+	if length == 0 || start < 0 {
+		return code
+	}
+	// cbor map with 1 or 2 items
+	if code[start] != 0xa1 && code[start] != 0xa2 {
+		return code
+	}
+	return code[:start]
+}
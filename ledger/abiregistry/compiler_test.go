@@ -0,0 +1,77 @@
+package abiregistry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCompilerOutput = `
+======= /tmp/solppc/contract.solpp:A =======
+Binary:
+6080604052
+OffChain Binary:
+6080
+Binary of the runtime part:
+60806040
+Contract JSON ABI
+[{"type":"function","name":"f","inputs":[]}]
+
+======= /tmp/solppc/contract.solpp:B =======
+Binary:
+6001
+Binary of the runtime part:
+6002
+Contract JSON ABI
+[]
+`
+
+func TestParseCompilerOutput(t *testing.T) {
+	contracts, err := parseCompilerOutput([]byte(testCompilerOutput))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(contracts))
+
+	a := contracts["A"]
+	assert.Equal(t, []byte{0x60, 0x80, 0x60, 0x40, 0x52}, a.Binary)
+	assert.Equal(t, []byte{0x60, 0x80, 0x60, 0x40}, a.RuntimeBinary)
+	assert.Equal(t, []byte{0x60, 0x80}, a.OffChainBinary)
+	assert.Equal(t, `[{"type":"function","name":"f","inputs":[]}]`, string(a.Abi))
+	assert.Equal(t, []byte{0x60, 0x02}, contracts["B"].RuntimeBinary)
+
+	_, err = selectContract(contracts, "")
+	assert.Equal(t, ErrContractNameEmpty, err)
+	b, err := selectContract(contracts, "B")
+	assert.NoError(t, err)
+	assert.Equal(t, "B", b.Name)
+
+	_, err = parseCompilerOutput([]byte("Binary:\n6080"))
+	assert.Error(t, err)
+}
+
+func TestMatchCode(t *testing.T) {
+	body := []byte{0x60, 0x80, 0x60, 0x40}
+	metadata1 := []byte{0xa1, 0x65, 0x01, 0x00, 0x03}
+	metadata2 := []byte{0xa1, 0x65, 0x02, 0x00, 0x03}
+
+	compiled := append(append([]byte{}, body...), metadata1...)
+	assert.Equal(t, body, stripMetadata(compiled))
+	assert.Equal(t, body, stripMetadata(body))
+
+	assert.Equal(t, MatchTypeFull, matchCode(compiled, compiled))
+	assert.Equal(t, MatchTypePartial, matchCode(append(append([]byte{}, body...), metadata2...), compiled))
+	assert.Equal(t, "", matchCode([]byte{0x60, 0x00}, compiled))
+	assert.Equal(t, "", matchCode(body, []byte{0x60, 0x80}))
+}
+
+func TestMatchVersion(t *testing.T) {
+	local := "solppc, the solidity++ compiler commandline interface\nVersion: 0.4.3+commit.2f64e0ea.Linux.g++"
+	assert.True(t, matchVersion(local, "0.4.3"))
+	assert.True(t, matchVersion(local, "v0.4.3"))
+	assert.True(t, matchVersion(local, "0.4.3+commit.2f64e0ea"))
+	assert.False(t, matchVersion(local, "0.4.3+commit.00000000"))
+	assert.False(t, matchVersion(local, "0"))
+	assert.False(t, matchVersion(local, "0.4"))
+	assert.False(t, matchVersion(local, "0.4.30"))
+	assert.False(t, matchVersion(local, ""))
+	assert.False(t, matchVersion("solppc", "0.4.3"))
+}
@@ -0,0 +1,133 @@
+package abiregistry
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/vm/abi"
+)
+
+// DecodedArg is a decoded argument of an event or a method call
+type DecodedArg struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// DecodedEvent is a vm log decoded by the event definition in abi
+type DecodedEvent struct {
+	Name      string        `json:"name"`
+	Signature string        `json:"signature"`
+	Args      []*DecodedArg `json:"args"`
+}
+
+// DecodedCall is the call data of a send block decoded by the method definition in abi
+type DecodedCall struct {
+	Name      string        `json:"name"`
+	Signature string        `json:"signature"`
+	Args      []*DecodedArg `json:"args"`
+}
+
+// DecodeVmLog decodes the vm log emitted by addr, returns nil if the abi or the event is not found
+func (r *Registry) DecodeVmLog(addr types.Address, log *ledger.VmLog) *DecodedEvent {
+	contract := r.GetAbi(addr)
+	if contract == nil || log == nil || len(log.Topics) == 0 {
+		return nil
+	}
+	for _, event := range contract.Events {
+		if event.Id() != log.Topics[0] {
+			continue
+		}
+		params, err := event.DirectUnPack(log.Topics, log.Data)
+		if err != nil {
+			r.log.Debug("unpack event failed", "addr", addr, "event", event.Name, "err", err)
+			return nil
+		}
+		return &DecodedEvent{
+			Name:      event.Name,
+			Signature: event.String(),
+			Args:      toDecodedArgs(event.Inputs, params),
+		}
+	}
+	return nil
+}
+
+// DecodeCallData decodes the data of a send call block to addr, returns nil if the abi or the method is not found
+func (r *Registry) DecodeCallData(addr types.Address, data []byte) *DecodedCall {
+	contract := r.GetAbi(addr)
+	if contract == nil {
+		return nil
+	}
+	call, err := DecodeCall(contract, data)
+	if err != nil {
+		r.log.Debug("decode call data failed", "addr", addr, "err", err)
+		return nil
+	}
+	return call
+}
+
+// DecodeCall decodes the call data by the method definition in the abi
+func DecodeCall(contract *abi.ABIContract, data []byte) (*DecodedCall, error) {
+	if len(data) < 4 {
+		return nil, errors.New("call data too short")
+	}
+	method, err := contract.MethodById(data[:4])
+	if err != nil {
+		return nil, err
+	}
+	params, err := method.Inputs.DirectUnpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	return &DecodedCall{
+		Name:      method.Name,
+		Signature: method.String(),
+		Args:      toDecodedArgs(method.Inputs, params),
+	}, nil
+}
+
+// DecodeAccountBlock decodes the call data of a send call block
+func (r *Registry) DecodeAccountBlock(block *ledger.AccountBlock) *DecodedCall {
+	if block == nil || block.BlockType != ledger.BlockTypeSendCall {
+		return nil
+	}
+	return r.DecodeCallData(block.ToAddress, block.Data)
+}
+
+func toDecodedArgs(arguments abi.Arguments, params []interface{}) []*DecodedArg {
+	args := make([]*DecodedArg, 0, len(params))
+	for i, param := range params {
+		if i >= len(arguments) {
+			break
+		}
+		args = append(args, &DecodedArg{
+			Name:  arguments[i].Name,
+			Type:  arguments[i].Type.String(),
+			Value: formatValue(param),
+		})
+	}
+	return args
+}
+
+// formatValue converts values which can not be represented well in json
+func formatValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case *big.Int:
+		return value.String()
+	case []*big.Int:
+		list := make([]string, len(value))
+		for i, item := range value {
+			list[i] = item.String()
+		}
+		return list
+	case []byte:
+		return hex.EncodeToString(value)
+	case [32]byte:
+		return hex.EncodeToString(value[:])
+	default:
+		return v
+	}
+}
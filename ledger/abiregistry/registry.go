@@ -0,0 +1,332 @@
+package abiregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/vm/abi"
+)
+
+const (
+	abiKeyPrefix            = byte(1)
+	verifiedSourceKeyPrefix = byte(2)
+
+	SourceRpc          = "rpc"
+	SourceFile         = "file"
+	SourceVerification = "verification"
+)
+
+var (
+	ErrAbiNotFound      = errors.New("abi of the contract is not registered")
+	ErrCodeHashMismatch = errors.New("code hash is not equal to the code hash of the contract on chain")
+	ErrSelectorMissing  = errors.New("method selector of abi is not found in the contract code")
+	ErrAbiVerified      = errors.New("abi of the contract is verified, it can only be replaced by a verification")
+)
+
+// Chain is the chain reader required by the registry
+type Chain interface {
+	GetContractCode(contractAddr types.Address) ([]byte, error)
+	GetContractMeta(contractAddress types.Address) (*ledger.ContractMeta, error)
+	GetAccountBlockByHash(blockHash types.Hash) (*ledger.AccountBlock, error)
+	GetConfirmSnapshotHeaderByAbHash(abHash types.Hash) (*ledger.SnapshotBlock, error)
+}
+
+// ContractAbi is an abi registered for a contract address
+type ContractAbi struct {
+	Address    types.Address   `json:"address"`
+	Abi        json.RawMessage `json:"abi"`
+	CodeHash   *types.Hash     `json:"codeHash"`
+	Verified   bool            `json:"verified"`
+	Source     string          `json:"source"`
+	UpdateTime int64           `json:"updateTime"`
+	// MissingEvents are the events of the abi whose ids are not found in the code, they may never be emitted
+	MissingEvents []string `json:"missingEvents,omitempty"`
+}
+
+// Registry stores the abi of contracts, and decodes vm logs and call data by them
+type Registry struct {
+	chain    Chain
+	db       *leveldb.DB
+	compiler *Compiler
+
+	cache   map[types.Address]*abi.ABIContract
+	cacheMu sync.RWMutex
+
+	log log15.Logger
+}
+
+func NewRegistry(chain Chain, db *leveldb.DB) (*Registry, error) {
+	r := &Registry{
+		chain: chain,
+		db:    db,
+		cache: make(map[types.Address]*abi.ABIContract),
+		log:   log15.New("module", "abi_registry"),
+	}
+	if err := r.loadFromDb(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetCompiler sets the local solppc used to verify contract sources
+func (r *Registry) SetCompiler(compiler *Compiler) {
+	r.compiler = compiler
+}
+
+func (r *Registry) Close() error {
+	return r.db.Close()
+}
+
+// Register parses the abi, checks it against the contract code on chain and stores it as unverified.
+// Every method selector of the abi must be pushed by the code, and the address must have code. The events whose
+// ids are not pushed are only reported in MissingEvents, since an event may be declared without being emitted.
+// If codeHash is not nil, it must be equal to the hash of the code on chain. The code hash can be read
+// by anyone, so it only pins the code the abi is meant for, the abi is verified only by Verify.
+func (r *Registry) Register(addr types.Address, abiJson []byte, codeHash *types.Hash, source string) (*ContractAbi, error) {
+	existing, err := r.GetContractAbi(addr)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Verified {
+		return nil, ErrAbiVerified
+	}
+	return r.register(addr, abiJson, codeHash, source, false)
+}
+
+func (r *Registry) register(addr types.Address, abiJson []byte, codeHash *types.Hash, source string, verified bool) (*ContractAbi, error) {
+	contract, err := abi.JSONToABIContract(bytes.NewReader(abiJson))
+	if err != nil {
+		return nil, fmt.Errorf("invalid abi, %v", err)
+	}
+
+	code, err := r.chain.GetContractCode(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, ErrContractNotFound
+	}
+	onChainHash := types.DataHash(code)
+	if codeHash != nil && onChainHash != *codeHash {
+		return nil, ErrCodeHashMismatch
+	}
+	missingEvents, err := checkAbiInCode(&contract, code)
+	if err != nil {
+		return nil, err
+	}
+	if len(missingEvents) > 0 {
+		r.log.Warn("events of abi not found in the contract code", "addr", addr, "events", missingEvents)
+	}
+
+	compacted := new(bytes.Buffer)
+	if err := json.Compact(compacted, abiJson); err != nil {
+		return nil, err
+	}
+	record := &ContractAbi{
+		Address:       addr,
+		Abi:           compacted.Bytes(),
+		CodeHash:      &onChainHash,
+		Verified:      verified,
+		Source:        source,
+		UpdateTime:    time.Now().Unix(),
+		MissingEvents: missingEvents,
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Put(createAbiKey(addr), value, nil); err != nil {
+		return nil, err
+	}
+
+	r.cacheMu.Lock()
+	r.cache[addr] = &contract
+	r.cacheMu.Unlock()
+	return record, nil
+}
+
+// Remove deletes the abi of the contract
+func (r *Registry) Remove(addr types.Address) error {
+	if err := r.db.Delete(createAbiKey(addr), nil); err != nil {
+		return err
+	}
+	r.cacheMu.Lock()
+	delete(r.cache, addr)
+	r.cacheMu.Unlock()
+	return nil
+}
+
+// GetContractAbi returns the registered abi record, nil if not registered
+func (r *Registry) GetContractAbi(addr types.Address) (*ContractAbi, error) {
+	value, err := r.db.Get(createAbiKey(addr), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	record := &ContractAbi{}
+	if err := json.Unmarshal(value, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetAbi returns the parsed abi of the contract, nil if not registered
+func (r *Registry) GetAbi(addr types.Address) *abi.ABIContract {
+	r.cacheMu.RLock()
+	defer r.cacheMu.RUnlock()
+	return r.cache[addr]
+}
+
+// Addresses returns all contract addresses which have a registered abi
+func (r *Registry) Addresses() []types.Address {
+	r.cacheMu.RLock()
+	defer r.cacheMu.RUnlock()
+	list := make([]types.Address, 0, len(r.cache))
+	for addr := range r.cache {
+		list = append(list, addr)
+	}
+	return list
+}
+
+// LoadDir registers every abi file in dir. The file name must be the contract address with a ".json"
+// extension, and the content is either an abi array or an object like {"abi": [...], "codeHash": "..."}.
+func (r *Registry) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		addr, err := types.HexToAddress(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			r.log.Warn(fmt.Sprintf("skip abi file %s, invalid address", file.Name()), "method", "LoadDir")
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		abiJson, codeHash, err := parseAbiFile(content)
+		if err != nil {
+			r.log.Warn(fmt.Sprintf("skip abi file %s, %v", file.Name(), err), "method", "LoadDir")
+			continue
+		}
+		if _, err := r.Register(addr, abiJson, codeHash, SourceFile); err != nil {
+			r.log.Warn(fmt.Sprintf("skip abi file %s, %v", file.Name(), err), "method", "LoadDir")
+			continue
+		}
+		r.log.Info(fmt.Sprintf("load abi of %s", addr), "method", "LoadDir")
+	}
+	return nil
+}
+
+func (r *Registry) loadFromDb() error {
+	iter := r.db.NewIterator(util.BytesPrefix([]byte{abiKeyPrefix}), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		record := &ContractAbi{}
+		if err := json.Unmarshal(iter.Value(), record); err != nil {
+			return err
+		}
+		contract, err := abi.JSONToABIContract(bytes.NewReader(record.Abi))
+		if err != nil {
+			return err
+		}
+		r.cache[record.Address] = &contract
+	}
+	return iter.Error()
+}
+
+func parseAbiFile(content []byte) ([]byte, *types.Hash, error) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return trimmed, nil, nil
+	}
+	var file struct {
+		Abi      json.RawMessage `json:"abi"`
+		CodeHash *types.Hash     `json:"codeHash"`
+	}
+	if err := json.Unmarshal(trimmed, &file); err != nil {
+		return nil, nil, err
+	}
+	if len(file.Abi) == 0 {
+		return nil, nil, errors.New("abi is empty")
+	}
+	return file.Abi, file.CodeHash, nil
+}
+
+// checkAbiInCode checks that the code pushes every method selector of the abi, it returns the names of the events
+// whose ids are not pushed, which is not an error since an event may be declared without being emitted
+func checkAbiInCode(contract *abi.ABIContract, code []byte) ([]string, error) {
+	pushed := pushedValues(code)
+	for _, method := range contract.Methods {
+		if !pushed[trimLeadingZeros(method.Id())] {
+			return nil, ErrSelectorMissing
+		}
+	}
+	var missingEvents []string
+	for name, event := range contract.Events {
+		if !pushed[trimLeadingZeros(event.Id().Bytes())] {
+			missingEvents = append(missingEvents, name)
+		}
+	}
+	sort.Strings(missingEvents)
+	return missingEvents, nil
+}
+
+// pushedValues returns the values pushed by the PUSH1 to PUSH32 instructions of code without their leading zeros,
+// the compiler pushes a selector or an event id with leading zero bytes by a shorter PUSH
+func pushedValues(code []byte) map[string]bool {
+	const (
+		push1  = byte(0x60)
+		push32 = byte(0x7f)
+	)
+	values := make(map[string]bool)
+	for i := 0; i < len(code); i++ {
+		op := code[i]
+		if op < push1 || op > push32 {
+			continue
+		}
+		end := i + 1 + int(op-push1) + 1
+		if end > len(code) {
+			end = len(code)
+		}
+		values[trimLeadingZeros(code[i+1:end])] = true
+		i = end - 1
+	}
+	return values
+}
+
+func trimLeadingZeros(value []byte) string {
+	return string(bytes.TrimLeft(value, "\x00"))
+}
+
+func createAbiKey(addr types.Address) []byte {
+	key := make([]byte, 0, 1+types.AddressSize)
+	key = append(key, abiKeyPrefix)
+	key = append(key, addr.Bytes()...)
+	return key
+}
@@ -0,0 +1,208 @@
+package abiregistry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/vm/abi"
+)
+
+const testAbiJson = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"amount","type":"uint256"}]}
+]`
+
+type testChain struct {
+	code    map[types.Address][]byte
+	metas   map[types.Address]*ledger.ContractMeta
+	blocks  map[types.Hash]*ledger.AccountBlock
+	confirm *ledger.SnapshotBlock
+}
+
+func (c *testChain) GetContractCode(addr types.Address) ([]byte, error) {
+	return c.code[addr], nil
+}
+
+func (c *testChain) GetContractMeta(addr types.Address) (*ledger.ContractMeta, error) {
+	return c.metas[addr], nil
+}
+
+func (c *testChain) GetAccountBlockByHash(blockHash types.Hash) (*ledger.AccountBlock, error) {
+	return c.blocks[blockHash], nil
+}
+
+func (c *testChain) GetConfirmSnapshotHeaderByAbHash(abHash types.Hash) (*ledger.SnapshotBlock, error) {
+	return c.confirm, nil
+}
+
+func newTestRegistry(t *testing.T, chain Chain) *Registry {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRegistry(chain, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// testCode returns a code pushing the selectors and the event ids of the test abi
+func testCode(t *testing.T) []byte {
+	contract, err := abi.JSONToABIContract(bytes.NewReader([]byte(testAbiJson)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := append([]byte{0x63}, contract.Methods["transfer"].Id()...)
+	code = append(code, 0x7f)
+	return append(code, contract.Events["Transfer"].Id().Bytes()...)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	addr := types.AddressDexFund
+	code := testCode(t)
+	chain := &testChain{code: map[types.Address][]byte{addr: code}}
+	r := newTestRegistry(t, chain)
+
+	wrongHash := types.DataHash([]byte{1})
+	_, err := r.Register(addr, []byte(testAbiJson), &wrongHash, SourceRpc)
+	assert.Equal(t, ErrCodeHashMismatch, err)
+
+	record, err := r.Register(addr, []byte(testAbiJson), nil, SourceRpc)
+	assert.NoError(t, err)
+	assert.False(t, record.Verified)
+
+	// the code hash on chain is public, it doesn't verify the abi
+	codeHash := types.DataHash(code)
+	record, err = r.Register(addr, []byte(testAbiJson), &codeHash, SourceRpc)
+	assert.NoError(t, err)
+	assert.False(t, record.Verified)
+
+	// an event declared but never emitted is only reported
+	chain.code[addr] = code[:5]
+	record, err = r.Register(addr, []byte(testAbiJson), nil, SourceRpc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Transfer"}, record.MissingEvents)
+	chain.code[addr] = code
+	record, err = r.Register(addr, []byte(testAbiJson), nil, SourceRpc)
+	assert.NoError(t, err)
+	assert.Empty(t, record.MissingEvents)
+	chain.code[addr] = code[5:]
+	_, err = r.Register(addr, []byte(testAbiJson), &codeHash, SourceRpc)
+	assert.Equal(t, ErrCodeHashMismatch, err)
+	_, err = r.Register(addr, []byte(testAbiJson), nil, SourceRpc)
+	assert.Equal(t, ErrSelectorMissing, err)
+	_, err = r.Register(types.AddressQuota, []byte(testAbiJson), nil, SourceRpc)
+	assert.Equal(t, ErrContractNotFound, err)
+
+	stored, err := r.GetContractAbi(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, codeHash, *stored.CodeHash)
+	assert.Equal(t, []types.Address{addr}, r.Addresses())
+
+	// a verified abi is only replaced by a verification
+	chain.code[addr] = code
+	_, err = r.register(addr, []byte(testAbiJson), nil, SourceVerification, true)
+	assert.NoError(t, err)
+	_, err = r.Register(addr, []byte(testAbiJson), nil, SourceRpc)
+	assert.Equal(t, ErrAbiVerified, err)
+
+	assert.NoError(t, r.Remove(addr))
+	assert.Nil(t, r.GetAbi(addr))
+}
+
+func TestCheckAbiInCode(t *testing.T) {
+	// a method whose selector has a leading zero byte, which the compiler pushes by PUSH3
+	var name string
+	var selector []byte
+	for i := 0; ; i++ {
+		name = "f" + strconv.Itoa(i)
+		contract, err := abi.JSONToABIContract(strings.NewReader(`[{"type":"function","name":"` + name + `","inputs":[]}]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if selector = contract.Methods[name].Id(); selector[0] == 0 && selector[1] != 0 {
+			break
+		}
+	}
+	abiJson := `[{"type":"function","name":"` + name + `","inputs":[]},{"type":"event","name":"Unused","inputs":[]}]`
+	contract, err := abi.JSONToABIContract(strings.NewReader(abiJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	push3 := append([]byte{0x62}, selector[1:]...)
+	missingEvents, err := checkAbiInCode(&contract, push3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Unused"}, missingEvents)
+
+	// the full width push matches too
+	_, err = checkAbiInCode(&contract, append([]byte{0x63}, selector...))
+	assert.NoError(t, err)
+
+	// the selector in the data of another push is not pushed
+	_, err = checkAbiInCode(&contract, append([]byte{0x64, 0x01}, push3...))
+	assert.Equal(t, ErrSelectorMissing, err)
+	_, err = checkAbiInCode(&contract, push3[:len(push3)-1])
+	assert.Equal(t, ErrSelectorMissing, err)
+}
+
+func TestRegistry_Decode(t *testing.T) {
+	contract, err := abi.JSONToABIContract(bytes.NewReader([]byte(testAbiJson)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := types.AddressDexFund
+	r := newTestRegistry(t, &testChain{code: map[types.Address][]byte{addr: testCode(t)}})
+	_, err = r.Register(addr, []byte(testAbiJson), nil, SourceRpc)
+	assert.NoError(t, err)
+
+	data, err := contract.PackMethod("transfer", types.AddressQuota, big.NewInt(100))
+	assert.NoError(t, err)
+	call := r.DecodeAccountBlock(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, ToAddress: addr, Data: data})
+	assert.NotNil(t, call)
+	assert.Equal(t, "transfer", call.Name)
+	assert.Equal(t, types.AddressQuota, call.Args[0].Value)
+	assert.Equal(t, "100", call.Args[1].Value)
+
+	topics, logData, err := contract.PackEvent("Transfer", types.AddressQuota, big.NewInt(7))
+	assert.NoError(t, err)
+	event := r.DecodeVmLog(addr, &ledger.VmLog{Topics: topics, Data: logData})
+	assert.NotNil(t, event)
+	assert.Equal(t, "Transfer", event.Name)
+	assert.Equal(t, "7", event.Args[1].Value)
+
+	assert.Nil(t, r.DecodeVmLog(types.AddressQuota, &ledger.VmLog{Topics: topics, Data: logData}))
+}
+
+func TestRegistry_LoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abi_registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	addr := types.AddressDexFund
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, addr.String()+".json"), []byte(`{"abi":`+testAbiJson+`}`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte(testAbiJson), 0644))
+
+	r := newTestRegistry(t, &testChain{code: map[types.Address][]byte{addr: testCode(t)}})
+	assert.NoError(t, r.LoadDir(dir))
+	assert.Equal(t, []types.Address{addr}, r.Addresses())
+
+	record, err := r.GetContractAbi(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceFile, record.Source)
+}
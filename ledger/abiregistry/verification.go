@@ -0,0 +1,262 @@
+package abiregistry
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+const (
+	MatchTypeFull    = "full"
+	MatchTypePartial = "partial"
+)
+
+var (
+	ErrContractNotFound  = errors.New("contract code is not found on chain")
+	ErrContractNameEmpty = errors.New("contract name is required, the source contains more than one contract")
+	ErrCodeMismatch      = errors.New("compiled code is not equal to the contract code on chain")
+	ErrCreateDataInvalid = errors.New("create block data is not consistent with the contract meta")
+
+	ErrOffChainCodeMismatch = errors.New("off-chain code is not equal to the off-chain code compiled from the source")
+)
+
+// VerifyRequest is the source and the compiler settings used to verify a contract
+type VerifyRequest struct {
+	Address         types.Address `json:"address"`
+	Source          string        `json:"source"`
+	ContractName    string        `json:"contractName"`
+	CompilerVersion string        `json:"compilerVersion"`
+	CompileOptions
+	// OffChainCode is the off-chain code to check against the source, e.g. the one published with a dapp
+	OffChainCode []byte `json:"offchainCode"`
+}
+
+// VerifiedSource is the source of a contract whose compiled code matches the code on chain
+type VerifiedSource struct {
+	Address         types.Address   `json:"address"`
+	ContractName    string          `json:"contractName"`
+	Source          string          `json:"source"`
+	CompilerVersion string          `json:"compilerVersion"`
+	Optimize        bool            `json:"optimize"`
+	OptimizeRuns    int             `json:"optimizeRuns"`
+	Abi             json.RawMessage `json:"abi"`
+	ConstructorArgs string          `json:"constructorArgs"`
+	MatchType       string          `json:"matchType"`
+	VerifyTime      int64           `json:"verifyTime"`
+	// OffChainCode is compiled from the verified source, OffChainMatchType is the match of the off-chain code of the
+	// request against it, empty if the request has none
+	OffChainCode      string `json:"offchainCode,omitempty"`
+	OffChainMatchType string `json:"offchainMatchType,omitempty"`
+}
+
+// Verify compiles the source with the local solppc, compares the output with the contract code and the create block
+// on chain, and stores the source. The abi of the verified source is registered and marked as verified.
+// A full match requires the metadata appended by the compiler to be equal, a partial match ignores it. The off-chain
+// code is not on chain, the one compiled from the source is stored with it once the runtime code matches, so the
+// getters can be called with a trusted off-chain code. The off-chain code of the request is compared with it.
+func (r *Registry) Verify(req *VerifyRequest) (*VerifiedSource, error) {
+	if r.compiler == nil {
+		return nil, ErrCompilerNotConfigured
+	}
+
+	code, err := r.chain.GetContractCode(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, ErrContractNotFound
+	}
+	meta, err := r.chain.GetContractMeta(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, ErrContractNotFound
+	}
+
+	contracts, err := r.compiler.Compile(req.Source, req.CompilerVersion, req.CompileOptions)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := selectContract(contracts, req.ContractName)
+	if err != nil {
+		return nil, err
+	}
+
+	if code[0] != util.SolidityPPContractType {
+		return nil, ErrCodeMismatch
+	}
+	matchType := matchCode(code[1:], compiled.RuntimeBinary)
+	if len(matchType) == 0 {
+		return nil, ErrCodeMismatch
+	}
+
+	constructorArgs, err := r.checkCreateBlock(meta, compiled, matchType)
+	if err != nil {
+		return nil, err
+	}
+
+	var offChainMatchType string
+	if len(req.OffChainCode) > 0 {
+		if offChainMatchType = matchCode(req.OffChainCode, compiled.OffChainBinary); len(offChainMatchType) == 0 {
+			return nil, ErrOffChainCodeMismatch
+		}
+	}
+
+	record := &VerifiedSource{
+		Address:           req.Address,
+		ContractName:      compiled.Name,
+		Source:            req.Source,
+		CompilerVersion:   req.CompilerVersion,
+		Optimize:          req.Optimize,
+		OptimizeRuns:      req.OptimizeRuns,
+		Abi:               compiled.Abi,
+		ConstructorArgs:   hex.EncodeToString(constructorArgs),
+		MatchType:         matchType,
+		VerifyTime:        time.Now().Unix(),
+		OffChainCode:      hex.EncodeToString(compiled.OffChainBinary),
+		OffChainMatchType: offChainMatchType,
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	codeHash := types.DataHash(code)
+	if _, err := r.register(req.Address, compiled.Abi, &codeHash, SourceVerification, true); err != nil {
+		return nil, err
+	}
+	if err := r.db.Put(createVerifiedSourceKey(req.Address), value, nil); err != nil {
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("verify contract %s, match type %s", req.Address, matchType), "method", "Verify")
+	return record, nil
+}
+
+// GetVerifiedSource returns the verified source of the contract, nil if not verified
+func (r *Registry) GetVerifiedSource(addr types.Address) (*VerifiedSource, error) {
+	value, err := r.db.Get(createVerifiedSourceKey(addr), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	record := &VerifiedSource{}
+	if err := json.Unmarshal(value, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// checkCreateBlock checks the create block data against the contract meta and the compiled code,
+// returns the constructor arguments appended to the creation code.
+func (r *Registry) checkCreateBlock(meta *ledger.ContractMeta, compiled *CompiledContract, matchType string) ([]byte, error) {
+	createBlockHash := meta.CreateBlockHash
+	createBlock, err := r.chain.GetAccountBlockByHash(createBlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if createBlock == nil {
+		return nil, fmt.Errorf("create block %s is not found", createBlockHash)
+	}
+	confirmSb, err := r.chain.GetConfirmSnapshotHeaderByAbHash(createBlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if confirmSb == nil {
+		return nil, fmt.Errorf("create block %s is not confirmed", createBlockHash)
+	}
+
+	data := createBlock.Data
+	if len(data) < types.GidSize+4 ||
+		util.GetContractTypeFromCreateContractData(data) != util.SolidityPPContractType ||
+		util.GetGidFromCreateContractData(data) != meta.Gid ||
+		util.GetSnapshotCountFromCreateContractData(data) != meta.SendConfirmedTimes ||
+		seedCountFromCreateData(data, confirmSb.Height, meta) != meta.SeedConfirmedTimes ||
+		util.GetQuotaMultiplierFromCreateContractData(data, confirmSb.Height) != meta.QuotaRatio {
+		return nil, ErrCreateDataInvalid
+	}
+	creationCode := util.GetCodeFromCreateContractData(data, confirmSb.Height)
+
+	if len(creationCode) < len(compiled.Binary) {
+		return nil, ErrCodeMismatch
+	}
+	if matchType == MatchTypeFull {
+		if !bytes.HasPrefix(creationCode, compiled.Binary) {
+			return nil, ErrCodeMismatch
+		}
+		return creationCode[len(compiled.Binary):], nil
+	}
+
+	// the metadata ends the creation code as well, it differs but has the same length,
+	// the constructor arguments follow it
+	stripped := stripMetadata(compiled.Binary)
+	if len(stripped) == len(compiled.Binary) {
+		return nil, ErrCodeMismatch
+	}
+	onChain := creationCode[:len(compiled.Binary)]
+	if !bytes.Equal(stripMetadata(onChain), stripped) {
+		return nil, ErrCodeMismatch
+	}
+	return creationCode[len(compiled.Binary):], nil
+}
+
+// seedCountFromCreateData returns the seed confirmed times of the create data, it is equal to the
+// send confirmed times before the seed fork
+func seedCountFromCreateData(data []byte, height uint64, meta *ledger.ContractMeta) uint8 {
+	if !upgrade.IsSeedUpgrade(height) {
+		return meta.SendConfirmedTimes
+	}
+	return util.GetSnapshotWithSeedCountCountFromCreateContractData(data)
+}
+
+func selectContract(contracts map[string]*CompiledContract, name string) (*CompiledContract, error) {
+	if len(name) > 0 {
+		if c, ok := contracts[name]; ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("contract %s is not found in source", name)
+	}
+	if len(contracts) != 1 {
+		return nil, ErrContractNameEmpty
+	}
+	for _, c := range contracts {
+		return c, nil
+	}
+	return nil, nil
+}
+
+// matchCode compares the runtime code on chain with the compiled runtime code
+func matchCode(onChain []byte, compiled []byte) string {
+	if len(compiled) == 0 {
+		return ""
+	}
+	if bytes.Equal(onChain, compiled) {
+		return MatchTypeFull
+	}
+	stripped := stripMetadata(compiled)
+	if len(stripped) == len(compiled) {
+		return ""
+	}
+	if bytes.Equal(stripMetadata(onChain), stripped) {
+		return MatchTypePartial
+	}
+	return ""
+}
+
+func createVerifiedSourceKey(addr types.Address) []byte {
+	key := make([]byte, 0, 1+types.AddressSize)
+	key = append(key, verifiedSourceKeyPrefix)
+	key = append(key, addr.Bytes()...)
+	return key
+}
@@ -0,0 +1,115 @@
+package abiregistry
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+// writeTestCompiler writes a solppc script printing the compiled code of a contract A
+func writeTestCompiler(t *testing.T, dir string, creation, runtimeCode, offChain []byte) string {
+	script := `#!/bin/sh
+if [ "$1" = "--version" ]; then
+	echo "Version: 0.4.3+commit.2f64e0ea.Linux.g++"
+	exit 0
+fi
+cat <<EOF
+======= contract.solpp:A =======
+Binary:
+` + hex.EncodeToString(creation) + `
+OffChain Binary:
+` + hex.EncodeToString(offChain) + `
+Binary of the runtime part:
+` + hex.EncodeToString(runtimeCode) + `
+Contract JSON ABI
+[]
+EOF
+`
+	path := filepath.Join(dir, "solppc")
+	if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRegistry_Verify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test compiler is a shell script")
+	}
+	upgrade.InitUpgradeBox(upgrade.NewLatestUpgradeBox())
+	defer upgrade.CleanupUpgradeBox(t)
+
+	dir, err := ioutil.TempDir("", "verification")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	metadata := []byte{0xa1, 0x65, 0x01, 0x00, 0x03}
+	withMetadata := func(body ...byte) []byte {
+		return append(body, metadata...)
+	}
+	creation := withMetadata(0x60, 0x80, 0x60, 0x40, 0x52)
+	runtimeCode := withMetadata(0x60, 0x80, 0x60, 0x40)
+	offChain := withMetadata(0x60, 0x80, 0x60, 0x01)
+	constructorArgs := []byte{0x01, 0x02}
+
+	addr := types.AddressDexFund
+	createBlock := &ledger.AccountBlock{
+		Hash: types.DataHash([]byte("create")),
+		Data: util.GetCreateContractData(append(append([]byte{}, creation...), constructorArgs...), util.SolidityPPContractType, 1, 1, 10, types.DELEGATE_GID),
+	}
+	chain := &testChain{
+		code: map[types.Address][]byte{addr: util.PackContractCode(util.SolidityPPContractType, runtimeCode)},
+		metas: map[types.Address]*ledger.ContractMeta{addr: {
+			Gid:                types.DELEGATE_GID,
+			SendConfirmedTimes: 1,
+			SeedConfirmedTimes: 1,
+			QuotaRatio:         10,
+			CreateBlockHash:    createBlock.Hash,
+		}},
+		blocks:  map[types.Hash]*ledger.AccountBlock{createBlock.Hash: createBlock},
+		confirm: &ledger.SnapshotBlock{Height: 10},
+	}
+	r := newTestRegistry(t, chain)
+	r.SetCompiler(NewCompiler(writeTestCompiler(t, dir, creation, runtimeCode, offChain)))
+
+	req := &VerifyRequest{Address: addr, Source: "contract A {}", CompilerVersion: "0.4.3"}
+	record, err := r.Verify(req)
+	assert.NoError(t, err)
+	assert.Equal(t, MatchTypeFull, record.MatchType)
+	assert.Equal(t, hex.EncodeToString(constructorArgs), record.ConstructorArgs)
+	assert.Equal(t, hex.EncodeToString(offChain), record.OffChainCode)
+	assert.Equal(t, "", record.OffChainMatchType)
+
+	// the off-chain code of the request is compared with the compiled one, the metadata may differ
+	req.OffChainCode = offChain
+	record, err = r.Verify(req)
+	assert.NoError(t, err)
+	assert.Equal(t, MatchTypeFull, record.OffChainMatchType)
+	req.OffChainCode = append([]byte{0x60, 0x80, 0x60, 0x01}, 0xa1, 0x65, 0x02, 0x00, 0x03)
+	record, err = r.Verify(req)
+	assert.NoError(t, err)
+	assert.Equal(t, MatchTypePartial, record.OffChainMatchType)
+	req.OffChainCode = withMetadata(0x60, 0x80, 0x60, 0x02)
+	_, err = r.Verify(req)
+	assert.Equal(t, ErrOffChainCodeMismatch, err)
+
+	stored, err := r.GetVerifiedSource(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, MatchTypePartial, stored.OffChainMatchType)
+
+	// the runtime code on chain differs from the compiled one
+	chain.code[addr] = util.PackContractCode(util.SolidityPPContractType, withMetadata(0x60, 0x00))
+	req.OffChainCode = nil
+	_, err = r.Verify(req)
+	assert.Equal(t, ErrCodeMismatch, err)
+}
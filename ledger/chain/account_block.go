@@ -50,7 +50,7 @@ func (c *chain) GetAccountBlockByHeight(addr types.Address, height uint64) (*led
 	}
 
 	// query block
-	block, err := c.blockDB.GetAccountBlock(location)
+	block, err := c.readAccountBlock(location)
 
 	if err != nil {
 		cErr := fmt.Errorf("c.blockDB.GetAccountBlock failed, address is %s, height is %d, location is %+v. Error: %s,  ",
@@ -100,7 +100,7 @@ func (c *chain) GetCompleteBlockByHash(blockHash types.Hash) (*ledger.AccountBlo
 	}
 
 	// query block
-	block, err := c.blockDB.GetAccountBlock(location)
+	block, err := c.readAccountBlock(location)
 
 	if err != nil {
 		cErr := fmt.Errorf("c.blockDB.GetAccountBlock failed, hash is %s, location is %+v. Error: %s",
@@ -310,7 +310,7 @@ func (c *chain) GetLatestAccountBlock(addr types.Address) (*ledger.AccountBlock,
 	}
 
 	// query block
-	block, err := c.blockDB.GetAccountBlock(location)
+	block, err := c.readAccountBlock(location)
 
 	if err != nil {
 		cErr := fmt.Errorf("c.blockDB.GetAccountBlock failed, address is %s, height is %d, location is %+v. Error: %s, ",
@@ -344,16 +344,28 @@ func (c *chain) getAccountBlocks(addr types.Address, locations []*chain_file_man
 	endHeight := heightRange[1]
 	currentHeight := endHeight
 
+	// the old blocks are read from the segments of the cold tier
+	coldBlocks, err := c.getColdAccountBlocks(addr, heightRange)
+	if err != nil {
+		cErr := fmt.Errorf("c.getColdAccountBlocks failed, addr is %s, height range is %v. Error: %s",
+			addr, heightRange, err.Error())
+		c.log.Error(cErr.Error(), "method", "getAccountBlocks")
+		return nil, cErr
+	}
+
 	index := 0
 
 	for currentHeight >= startHeight {
 		block := c.cache.GetAccountBlockByHeight(addr, currentHeight)
+		if block == nil {
+			block = coldBlocks[currentHeight]
+		}
 		if block == nil {
 			if len(locations) <= index || locations[index] == nil {
 				return nil, nil
 			}
 			var err error
-			block, err = c.blockDB.GetAccountBlock(locations[index])
+			block, err = c.readAccountBlock(locations[index])
 			if err != nil {
 				cErr := fmt.Errorf("c.blockDB.GetAccountBlock failed, locations is %+v. Error: %s",
 					locations[index], err.Error())
@@ -0,0 +1,453 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	"github.com/vitelabs/go-vite/common/types"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+)
+
+const (
+	backupInfoFileName  = "backup.json"
+	backupLedgerDirName = "ledger"
+	blockDirName        = "blocks"
+)
+
+// BackupInfo is the meta of a backup, it is saved as backup.json in the backup directory
+type BackupInfo struct {
+	// the ledger in the backup is at the snapshot block
+	SnapshotHeight uint64     `json:"snapshotHeight"`
+	SnapshotHash   types.Hash `json:"snapshotHash"`
+
+	// the block files are copied up to the location of the latest flushed block
+	BlockLocation *chain_file_manager.Location `json:"blockLocation"`
+
+	// the immutable table files of the stores are hard-linked instead of copied
+	HardLink bool      `json:"hardLink"`
+	Time     time.Time `json:"time"`
+}
+
+// Backup flushes the ledger and copies the stores and the block files to the directory. The sealed block files are
+// copied first, then the writing of the ledger is paused while copying the stores and the latest block file. The table files of the stores are hard-linked if hardLink is true, they must be on the
+// same file system as the data directory, otherwise they are copied.
+func (c *chain) Backup(dir string, hardLink bool) (*BackupInfo, error) {
+	if err := checkBackupDir(c.chainDir, dir); err != nil {
+		return nil, err
+	}
+
+	c.backupMu.Lock()
+	defer c.backupMu.Unlock()
+
+	// the pruner and the compactor write the disk without the flusher
+	pruning, compacting := c.pruner.Stop(), c.compactor.Stop()
+	defer func() {
+		if pruning {
+			c.pruner.Start()
+		}
+		if compacting {
+			c.compactor.Start()
+		}
+	}()
+
+	// the sealed block files are not written until a rollback, copy them before pausing the flusher, so the
+	// writing is only paused for the stores and the tail of the block files
+	backupLedgerDir := path.Join(dir, backupLedgerDirName)
+	sealed, err := copySealedBlockFiles(path.Join(c.chainDir, blockDirName), path.Join(backupLedgerDir, blockDirName))
+	if err != nil {
+		return nil, fmt.Errorf("copy the block files failed. Error: %s", err)
+	}
+
+	info := &BackupInfo{HardLink: hardLink}
+	if err := c.flusher.Pause(func() {
+		latest := c.GetLatestSnapshotBlock()
+		info.SnapshotHeight = latest.Height
+		info.SnapshotHash = latest.Hash
+		info.BlockLocation = c.blockDB.LatestLocation()
+	}); err != nil {
+		return nil, fmt.Errorf("flush failed. Error: %s", err)
+	}
+	defer c.flusher.Resume()
+
+	c.StopWrite()
+	defer c.RecoverWrite()
+
+	c.log.Info(fmt.Sprintf("start backup at snapshot height %d to %s", info.SnapshotHeight, dir), "method", "Backup")
+	if err := copyLedgerDir(c.chainDir, backupLedgerDir, info.BlockLocation, hardLink, sealed); err != nil {
+		return nil, fmt.Errorf("copy the ledger failed. Error: %s", err)
+	}
+	if err := verifyLedgerDir(backupLedgerDir, info.BlockLocation); err != nil {
+		return nil, fmt.Errorf("verify the backup failed. Error: %s", err)
+	}
+	info.Time = time.Now()
+	if err := writeBackupInfo(dir, info); err != nil {
+		return nil, err
+	}
+	c.log.Info(fmt.Sprintf("finish backup at snapshot height %d to %s", info.SnapshotHeight, dir), "method", "Backup")
+	return info, nil
+}
+
+// ReadBackupInfo reads the meta of the backup in the directory
+func ReadBackupInfo(dir string) (*BackupInfo, error) {
+	data, err := ioutil.ReadFile(path.Join(dir, backupInfoFileName))
+	if err != nil {
+		return nil, err
+	}
+	info := &BackupInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("invalid %s. Error: %s", backupInfoFileName, err)
+	}
+	return info, nil
+}
+
+// RestoreBackup copies the ledger of the backup to the data directory, the ledger directory of the data directory
+// must not exist. The restored ledger is at the snapshot height of the backup.
+func RestoreBackup(backupDir, dataDir string) (*BackupInfo, error) {
+	info, err := ReadBackupInfo(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	chainDir := path.Join(dataDir, "ledger")
+	if _, err := os.Stat(chainDir); err == nil {
+		return nil, fmt.Errorf("%s already exists, move it away before restoring", chainDir)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := copyLedgerDir(path.Join(backupDir, backupLedgerDirName), chainDir, nil, false, nil); err != nil {
+		os.RemoveAll(chainDir)
+		return nil, err
+	}
+	if err := verifyLedgerDir(chainDir, info.BlockLocation); err != nil {
+		os.RemoveAll(chainDir)
+		return nil, fmt.Errorf("verify the restored ledger failed. Error: %s", err)
+	}
+	return info, nil
+}
+
+// checkBackupDir returns an error if the backup directory is not empty or is in the ledger directory
+func checkBackupDir(chainDir, dir string) error {
+	absChainDir, err := filepath.Abs(chainDir)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if absDir == absChainDir || strings.HasPrefix(absDir, absChainDir+string(filepath.Separator)) {
+		return fmt.Errorf("the backup directory %s is in the ledger directory", dir)
+	}
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(fileInfos) > 0 {
+		return fmt.Errorf("the backup directory %s is not empty", dir)
+	}
+	return nil
+}
+
+func writeBackupInfo(dir string, info *BackupInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(dir, backupInfoFileName), data, 0600)
+}
+
+// copyLedgerDir copies the ledger directory. The block files are copied up to blockLocation if it is not nil,
+// the data after it is not flushed. The table files of leveldb are never modified after written, they are
+// hard-linked if hardLink is true. The block files in sealed are copied by copySealedBlockFiles already, they are
+// skipped if they are before the file of blockLocation and not modified since then, the ones after the file of
+// blockLocation are removed from dst, they are rolled back.
+//
+// The compaction of leveldb keeps running while copying. The CURRENT file and the MANIFEST file of a leveldb are
+// copied before the other files, so every table in the copied MANIFEST is written before the MANIFEST is replaced.
+// The copy fails if any file is removed by the compaction while copying, it can be retried.
+func copyLedgerDir(src, dst string, blockLocation *chain_file_manager.Location, hardLink bool, sealed map[string]os.FileInfo) error {
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return err
+	}
+
+	manifest, err := copyLevelDBMeta(src, dst)
+	if err != nil {
+		return err
+	}
+
+	fileInfos, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		srcPath, dstPath := path.Join(src, name), path.Join(dst, name)
+
+		if fileInfo.IsDir() {
+			if err := copyLedgerDir(srcPath, dstPath, blockLocation, hardLink, sealed); err != nil {
+				return err
+			}
+			continue
+		}
+		if !fileInfo.Mode().IsRegular() || name == "LOCK" {
+			continue
+		}
+		if len(manifest) > 0 && (name == levelDBCurrentFile || strings.HasPrefix(name, levelDBManifestPrefix)) {
+			// copied by copyLevelDBMeta, the other MANIFEST files are not referenced by CURRENT
+			continue
+		}
+
+		limit := int64(-1)
+		if blockLocation != nil && path.Base(src) == blockDirName {
+			fileId, ok := parseBlockFileName(name)
+			if ok && fileId > blockLocation.FileId {
+				if err := os.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				continue
+			}
+			if ok && fileId == blockLocation.FileId {
+				limit = blockLocation.Offset
+			}
+			if ok && fileId < blockLocation.FileId && sameFile(sealed[srcPath], fileInfo) {
+				continue
+			}
+		}
+
+		if hardLink && isTableFile(name) {
+			if err := os.Link(srcPath, dstPath); err == nil {
+				continue
+			} else if os.IsNotExist(err) {
+				return fmt.Errorf("%s is removed while copying", srcPath)
+			}
+		}
+		if err := copyFile(srcPath, dstPath, limit); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%s is removed while copying", srcPath)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// copySealedBlockFiles copies the block files before the latest one, returns the infos of the copied files by the
+// source path. A file modified while copying is not returned, it is copied again by copyLedgerDir.
+func copySealedBlockFiles(src, dst string) (map[string]os.FileInfo, error) {
+	fileInfos, err := ioutil.ReadDir(src)
+	if err != nil {
+		return nil, err
+	}
+	var latestId uint64
+	for _, fileInfo := range fileInfos {
+		if fileId, ok := parseBlockFileName(fileInfo.Name()); ok && fileId > latestId {
+			latestId = fileId
+		}
+	}
+
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return nil, err
+	}
+	sealed := make(map[string]os.FileInfo)
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if fileId, ok := parseBlockFileName(name); !ok || fileId >= latestId || !fileInfo.Mode().IsRegular() {
+			continue
+		}
+
+		srcPath := path.Join(src, name)
+		if err := copyFile(srcPath, path.Join(dst, name), -1); err != nil {
+			if os.IsNotExist(err) {
+				// removed by a rollback
+				continue
+			}
+			return nil, err
+		}
+		if copied, err := os.Stat(srcPath); err == nil && sameFile(fileInfo, copied) {
+			sealed[srcPath] = fileInfo
+		}
+	}
+	return sealed, nil
+}
+
+// sameFile returns true if the file is not modified between the two stats
+func sameFile(a, b os.FileInfo) bool {
+	return a != nil && b != nil && os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+const (
+	levelDBCurrentFile    = "CURRENT"
+	levelDBManifestPrefix = "MANIFEST-"
+)
+
+// copyLevelDBMeta copies the CURRENT file of a leveldb and the MANIFEST file named by it, returns the name of the
+// MANIFEST file, returns "" if the directory is not a leveldb
+func copyLevelDBMeta(src, dst string) (string, error) {
+	current, err := ioutil.ReadFile(path.Join(src, levelDBCurrentFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	manifest := strings.TrimSpace(string(current))
+	if !strings.HasPrefix(manifest, levelDBManifestPrefix) {
+		return "", fmt.Errorf("invalid %s in %s", levelDBCurrentFile, src)
+	}
+
+	if err := copyFile(path.Join(src, manifest), path.Join(dst, manifest), -1); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s in %s is replaced while copying", manifest, src)
+		}
+		return "", err
+	}
+	if err := writeFileSync(path.Join(dst, levelDBCurrentFile), current); err != nil {
+		return "", err
+	}
+	return manifest, nil
+}
+
+// verifyLedgerDir opens every leveldb in the copied ledger directory and checks the tables of them exist, and checks
+// the block files are copied up to blockLocation if it is not nil
+func verifyLedgerDir(dir string, blockLocation *chain_file_manager.Location) error {
+	if _, err := os.Stat(path.Join(dir, levelDBCurrentFile)); err == nil {
+		if err := verifyLevelDB(dir); err != nil {
+			return fmt.Errorf("verify %s failed. Error: %s", dir, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if blockLocation != nil && path.Base(dir) == "blocks" && blockLocation.Offset > 0 {
+		fileInfo, err := os.Stat(path.Join(dir, fmt.Sprintf("f%d", blockLocation.FileId)))
+		if err != nil {
+			return err
+		}
+		if fileInfo.Size() < blockLocation.Offset {
+			return fmt.Errorf("the block file %d is %d bytes, less than the backup location %s", blockLocation.FileId, fileInfo.Size(), blockLocation)
+		}
+	}
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() {
+			continue
+		}
+		if err := verifyLedgerDir(path.Join(dir, fileInfo.Name()), blockLocation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyLevelDB opens the leveldb in read-only mode and checks every table in the MANIFEST exists
+func verifyLevelDB(dir string) error {
+	db, err := leveldb.OpenFile(dir, &opt.Options{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// the tables of every level, "--- level n ---" followed by "num:size[min .. max]" of the tables
+	tables, err := db.GetProperty("leveldb.sstables")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(tables, "\n") {
+		index := strings.Index(line, ":")
+		if index <= 0 || strings.HasPrefix(line, "---") {
+			continue
+		}
+		num, err := strconv.ParseUint(line[:index], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid table %q", line)
+		}
+		if !tableExists(dir, num) {
+			return fmt.Errorf("the table %d is missing", num)
+		}
+	}
+	return nil
+}
+
+// tableExists returns true if the table file of leveldb exists, the table is named by ".ldb" or ".sst"
+func tableExists(dir string, num uint64) bool {
+	for _, ext := range []string{".ldb", ".sst"} {
+		if _, err := os.Stat(path.Join(dir, fmt.Sprintf("%06d%s", num, ext))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isTableFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".ldb" || ext == ".sst"
+}
+
+// the block files are named by "f" and the file id
+func parseBlockFileName(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, "f") {
+		return 0, false
+	}
+	fileId, err := strconv.ParseUint(name[1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return fileId, true
+}
+
+// copyFile copies the first limit bytes of the file and syncs it, the whole file is copied if limit is negative
+func copyFile(src, dst string, limit int64) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	var reader io.Reader = srcFile
+	if limit >= 0 {
+		reader = io.LimitReader(srcFile, limit)
+	}
+	if _, err := io.Copy(dstFile, reader); err != nil {
+		return err
+	}
+	return dstFile.Sync()
+}
+
+func writeFileSync(filename string, data []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/golang/snappy"
 	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
 
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/crypto"
@@ -36,6 +37,12 @@ type BlockDB struct {
 	flushTargetLocation *chain_file_manager.Location
 	flushBuf            *BufWriter
 
+	// the headers of the pruned blocks
+	headerDir      string
+	headerDB       *leveldb.DB
+	prunedLocation *chain_file_manager.Location
+	pruneMu        sync.RWMutex
+
 	log log15.Logger
 }
 
@@ -53,13 +60,19 @@ func NewBlockDBFixedSize(chainDir string, fileSize int64) (*BlockDB, error) {
 		return nil, err
 	}
 
-	return &BlockDB{
+	bDB := &BlockDB{
 		fm:                fm,
 		fileSize:          fileSize,
 		snappyWriteBuffer: make([]byte, fileSize),
 		id:                id,
+		headerDir:         headerDir(chainDir),
 		log:               log15.New("module", "blockDB"),
-	}, nil
+	}
+	if err := bDB.openHeaderDB(false); err != nil {
+		fm.Close()
+		return nil, err
+	}
+	return bDB, nil
 }
 
 // FileSize file size for one data file
@@ -67,6 +80,11 @@ func (bDB *BlockDB) FileSize() int64 {
 	return bDB.fileSize
 }
 
+// LatestLocation returns the location after the last block
+func (bDB *BlockDB) LatestLocation() *chain_file_manager.Location {
+	return bDB.fm.LatestLocation()
+}
+
 // Close close db
 func (bDB *BlockDB) Close() error {
 	if err := bDB.fm.Close(); err != nil {
@@ -74,6 +92,10 @@ func (bDB *BlockDB) Close() error {
 	}
 
 	bDB.fm = nil
+
+	if err := bDB.closeHeaderDB(); err != nil {
+		return fmt.Errorf("bDB.closeHeaderDB failed, error is %s", err)
+	}
 	return nil
 }
 
@@ -110,8 +132,17 @@ func (bDB *BlockDB) Write(ss *ledger.SnapshotChunk) (map[types.Hash]*chain_file_
 }
 
 func (bDB *BlockDB) Read(location *chain_file_manager.Location) ([]byte, error) {
+	if bDB.IsPruned(location) {
+		_, header, _, err := bDB.readHeader(location)
+		return header, err
+	}
+
 	buf, _, err := bDB.fm.Read(location)
 	if err != nil {
+		if bDB.IsPruned(location) {
+			// pruned while reading
+			return bDB.Read(location)
+		}
 		return nil, err
 	}
 	if len(buf) <= 0 {
@@ -125,11 +156,20 @@ func (bDB *BlockDB) Read(location *chain_file_manager.Location) ([]byte, error)
 	return sBuf, nil
 }
 
+// ReadRaw reads the raw block files, returns interfaces.ErrPruned if the location has been pruned
 func (bDB *BlockDB) ReadRaw(startLocation *chain_file_manager.Location, buf []byte) (*chain_file_manager.Location, int, error) {
+	if bDB.IsPruned(startLocation) {
+		return startLocation, 0, interfaces.ErrPruned
+	}
 	return bDB.fm.ReadRaw(startLocation, buf)
 }
 
 func (bDB *BlockDB) ReadUnitBytes(location *chain_file_manager.Location) ([]byte, *chain_file_manager.Location, error) {
+	if bDB.IsPruned(location) {
+		_, header, nextLocation, err := bDB.readHeader(location)
+		return header, nextLocation, err
+	}
+
 	buf, nextLocation, err := bDB.fm.Read(location)
 	if err != nil {
 		return nil, nil, err
@@ -145,25 +185,36 @@ func (bDB *BlockDB) ReadUnitBytes(location *chain_file_manager.Location) ([]byte
 }
 
 func (bDB *BlockDB) ReadUnit(location *chain_file_manager.Location) (*ledger.SnapshotBlock, *ledger.AccountBlock, *chain_file_manager.Location, error) {
-	buf, nextLocation, err := bDB.fm.Read(location)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	if len(buf) <= 0 {
-		return nil, nil, nextLocation, nil
-	}
-	sBuf, err := snappy.Decode(nil, buf[1:])
-	if err != nil {
-		return nil, nil, nil, err
+	var blockType byte
+	var sBuf []byte
+	var nextLocation *chain_file_manager.Location
+
+	if bDB.IsPruned(location) {
+		var err error
+		if blockType, sBuf, nextLocation, err = bDB.readHeader(location); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		buf, next, err := bDB.fm.Read(location)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(buf) <= 0 {
+			return nil, nil, next, nil
+		}
+		if sBuf, err = snappy.Decode(nil, buf[1:]); err != nil {
+			return nil, nil, nil, err
+		}
+		blockType, nextLocation = buf[0], next
 	}
 
-	if buf[0] == BlockTypeSnapshotBlock {
+	if blockType == BlockTypeSnapshotBlock {
 		sb := &ledger.SnapshotBlock{}
 		if err := sb.Deserialize(sBuf); err != nil {
 			return nil, nil, nil, err
 		}
 		return sb, nil, nextLocation, nil
-	} else if buf[0] == BlockTypeAccountBlock {
+	} else if blockType == BlockTypeAccountBlock {
 		ab := &ledger.AccountBlock{}
 		if err := ab.Deserialize(sBuf); err != nil {
 			return nil, nil, nil, err
@@ -198,7 +249,11 @@ func (bDB *BlockDB) ReadChunk(location *chain_file_manager.Location) (*ledger.Sn
 	return nil, nil, errors.New("not a chunk")
 }
 
+// ReadRange reads the chunks in the block files, returns interfaces.ErrPruned if the start location has been pruned
 func (bDB *BlockDB) ReadRange(startLocation *chain_file_manager.Location, endLocation *chain_file_manager.Location) ([]*ledger.SnapshotChunk, error) {
+	if bDB.IsPruned(startLocation) {
+		return nil, interfaces.ErrPruned
+	}
 	bfp := newBlockFileParser()
 
 	endLocation = bDB.maxLocation(endLocation)
@@ -272,6 +327,11 @@ func (bDB *BlockDB) ReadRange(startLocation *chain_file_manager.Location, endLoc
 }
 
 func (bDB *BlockDB) GetNextLocation(location *chain_file_manager.Location) (*chain_file_manager.Location, error) {
+	if bDB.IsPruned(location) {
+		_, _, nextLocation, err := bDB.readHeader(location)
+		return nextLocation, err
+	}
+
 	nextLocation, err := bDB.fm.GetNextLocation(location)
 	if err != nil {
 		if err != io.EOF {
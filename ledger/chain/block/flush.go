@@ -47,6 +47,30 @@ func (bDB *BlockDB) Id() types.Hash {
 	return bDB.id
 }
 
+func (bDB *BlockDB) Name() string {
+	return "blocks"
+}
+
+// SetSyncCommit sets whether the commits of the block files are synced to the disk, it is called by the flusher between flushes
+func (bDB *BlockDB) SetSyncCommit(sync bool) {
+	bDB.fm.SetSyncFlush(sync)
+}
+
+// IsFileCommitter marks the block files are synced by the flusher unless the fsync policy is none
+func (bDB *BlockDB) IsFileCommitter() {}
+
+// DirtySize returns the size of the blocks which are not flushed to the block files, lock write
+func (bDB *BlockDB) DirtySize() uint64 {
+	start := bDB.fm.NextFlushStartLocation()
+	if start == nil {
+		return 0
+	}
+	if distance := start.Distance(bDB.fileSize, bDB.fm.LatestLocation()); distance > 0 {
+		return uint64(distance)
+	}
+	return 0
+}
+
 // lock write
 func (bDB *BlockDB) Prepare() {
 	// set bDB.flushTargetLocation
@@ -0,0 +1,222 @@
+package chain_block
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/golang/snappy"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+// the block headers of the pruned block files are saved in the header db:
+// headerKeyPrefix + location -> block type + next location + serialized header
+const (
+	headerKeyPrefix   = byte(1)
+	prunedLocationKey = byte(2)
+
+	headerDirName = "block_headers"
+)
+
+// openHeaderDB opens the header db if the block files have been pruned
+func (bDB *BlockDB) openHeaderDB(create bool) error {
+	if bDB.headerDB != nil {
+		return nil
+	}
+	if !create {
+		if _, err := os.Stat(bDB.headerDir); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	headerDB, err := leveldb.OpenFile(bDB.headerDir, nil)
+	if err != nil {
+		return fmt.Errorf("open header db failed, dir is %s. Error: %s", bDB.headerDir, err)
+	}
+
+	value, err := headerDB.Get([]byte{prunedLocationKey}, nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		headerDB.Close()
+		return err
+	}
+	if len(value) > 0 {
+		bDB.prunedLocation = chain_utils.DeserializeLocation(value)
+	}
+	bDB.headerDB = headerDB
+	return nil
+}
+
+func (bDB *BlockDB) closeHeaderDB() error {
+	if bDB.headerDB == nil {
+		return nil
+	}
+	err := bDB.headerDB.Close()
+	bDB.headerDB = nil
+	return err
+}
+
+// PrunedLocation returns the location before which the block bodies are pruned, returns nil if not pruned
+func (bDB *BlockDB) PrunedLocation() *chain_file_manager.Location {
+	bDB.pruneMu.RLock()
+	defer bDB.pruneMu.RUnlock()
+
+	if bDB.prunedLocation == nil {
+		return nil
+	}
+	return chain_file_manager.NewLocation(bDB.prunedLocation.FileId, bDB.prunedLocation.Offset)
+}
+
+// IsPruned returns true if the block at the location only has the header
+func (bDB *BlockDB) IsPruned(location *chain_file_manager.Location) bool {
+	bDB.pruneMu.RLock()
+	defer bDB.pruneMu.RUnlock()
+
+	return bDB.prunedLocation != nil && bDB.normalizeLocation(location).Compare(bDB.prunedLocation) < 0
+}
+
+// normalizeLocation returns the location at the start of the next file if the location is at the end of a file,
+// the same block may be located by both of them
+func (bDB *BlockDB) normalizeLocation(location *chain_file_manager.Location) *chain_file_manager.Location {
+	if location.Offset < bDB.fileSize {
+		return location
+	}
+	return chain_file_manager.NewLocation(location.FileId+uint64(location.Offset/bDB.fileSize), location.Offset%bDB.fileSize)
+}
+
+// readHeader reads the block header at the pruned location, returns the block type, the serialized header and the next location
+func (bDB *BlockDB) readHeader(location *chain_file_manager.Location) (byte, []byte, *chain_file_manager.Location, error) {
+	bDB.pruneMu.RLock()
+	headerDB := bDB.headerDB
+	bDB.pruneMu.RUnlock()
+
+	if headerDB == nil {
+		return BlockTypeUnknown, nil, nil, fmt.Errorf("the block at %s is pruned, but the header db is not opened", location)
+	}
+
+	value, err := headerDB.Get(headerKey(bDB.normalizeLocation(location)), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return BlockTypeUnknown, nil, nil, fmt.Errorf("the header of the pruned block at %s is not found", location)
+		}
+		return BlockTypeUnknown, nil, nil, err
+	}
+	if len(value) < 13 {
+		return BlockTypeUnknown, nil, nil, fmt.Errorf("the header of the pruned block at %s is broken", location)
+	}
+	return value[0], value[13:], chain_utils.DeserializeLocation(value[1:13]), nil
+}
+
+// PruneTo saves the headers of the blocks before the location, then deletes the block files before the location.
+// The location must be the start of a block. throttle is called after every batch of the headers is written.
+func (bDB *BlockDB) PruneTo(location *chain_file_manager.Location, batchSize int, throttle func(n int) bool) error {
+	location = bDB.normalizeLocation(location)
+	if location.Compare(bDB.fm.NextFlushStartLocation()) > 0 {
+		return fmt.Errorf("the location %s is not flushed", location)
+	}
+
+	bDB.pruneMu.Lock()
+	err := bDB.openHeaderDB(true)
+	bDB.pruneMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	current := bDB.PrunedLocation()
+	if current == nil {
+		current = chain_file_manager.NewLocation(1, 0)
+	}
+
+	batch := new(leveldb.Batch)
+	for current.Compare(location) < 0 {
+		buf, next, err := bDB.fm.Read(current)
+		if err != nil {
+			return fmt.Errorf("read block at %s failed. Error: %s", current, err)
+		}
+		if len(buf) <= 0 {
+			return fmt.Errorf("no block at %s", current)
+		}
+
+		value, err := makeHeaderValue(buf, next)
+		if err != nil {
+			return fmt.Errorf("make header of the block at %s failed. Error: %s", current, err)
+		}
+		batch.Put(headerKey(current), value)
+
+		current = bDB.normalizeLocation(next)
+		if batch.Len() >= batchSize {
+			if err := bDB.headerDB.Write(batch, nil); err != nil {
+				return err
+			}
+			if !throttle(batch.Len()) {
+				return nil
+			}
+			batch.Reset()
+		}
+	}
+
+	batch.Put([]byte{prunedLocationKey}, chain_utils.SerializeLocation(location))
+	if err := bDB.headerDB.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+
+	bDB.pruneMu.Lock()
+	bDB.prunedLocation = chain_file_manager.NewLocation(location.FileId, location.Offset)
+	bDB.pruneMu.Unlock()
+
+	return bDB.fm.DeleteFilesBefore(location.FileId)
+}
+
+func headerKey(location *chain_file_manager.Location) []byte {
+	return append([]byte{headerKeyPrefix}, chain_utils.SerializeLocation(location)...)
+}
+
+func makeHeaderValue(buf []byte, next *chain_file_manager.Location) ([]byte, error) {
+	sBuf, err := snappy.Decode(nil, buf[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	switch buf[0] {
+	case BlockTypeAccountBlock:
+		ab := &ledger.AccountBlock{}
+		if err := ab.Deserialize(sBuf); err != nil {
+			return nil, err
+		}
+		if header, err = accountBlockHeader(ab).Serialize(); err != nil {
+			return nil, err
+		}
+	case BlockTypeSnapshotBlock:
+		// snapshot blocks are kept entirely
+		header = sBuf
+	default:
+		return nil, fmt.Errorf("unknown block type %d", buf[0])
+	}
+
+	value := make([]byte, 0, 13+len(header))
+	value = append(value, buf[0])
+	value = append(value, chain_utils.SerializeLocation(next)...)
+	return append(value, header...), nil
+}
+
+// accountBlockHeader returns the block without the data and the signature
+func accountBlockHeader(ab *ledger.AccountBlock) *ledger.AccountBlock {
+	header := *ab
+	header.Data = nil
+	header.Signature = nil
+
+	header.SendBlockList = make([]*ledger.AccountBlock, 0, len(ab.SendBlockList))
+	for _, sendBlock := range ab.SendBlockList {
+		header.SendBlockList = append(header.SendBlockList, accountBlockHeader(sendBlock))
+	}
+	return &header
+}
+
+func headerDir(chainDir string) string {
+	return path.Join(chainDir, headerDirName)
+}
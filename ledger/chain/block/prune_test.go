@@ -0,0 +1,115 @@
+package chain_block
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+)
+
+func TestBlockDB_PruneTo(t *testing.T) {
+	chainDir, err := ioutil.TempDir("", "prune_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(chainDir)
+
+	db, err := NewBlockDBFixedSize(chainDir, 1024)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	var sbLocations []*chain_file_manager.Location
+	abLocations := make(map[types.Hash]*chain_file_manager.Location)
+	for i := uint64(1); i <= 40; i++ {
+		data := make([]byte, 500)
+		rand.Read(data)
+		ab := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			Height:         i,
+			AccountAddress: types.AddressGovernance,
+			Amount:         big.NewInt(1),
+			Fee:            big.NewInt(0),
+			Data:           data,
+			Signature:      make([]byte, 64),
+		}
+		ab.Hash = ab.ComputeHash()
+		sb := &ledger.SnapshotBlock{Height: i, Timestamp: &now}
+
+		locations, sbLocation, err := db.Write(&ledger.SnapshotChunk{SnapshotBlock: sb, AccountBlocks: []*ledger.AccountBlock{ab}})
+		assert.NoError(t, err)
+		sbLocations = append(sbLocations, sbLocation)
+		abLocations[ab.Hash] = locations[ab.Hash]
+	}
+	db.Prepare()
+	assert.NoError(t, db.Commit())
+	db.AfterCommit()
+
+	// the unflushed files are kept in the cache, reopen to release them
+	assert.NoError(t, db.Close())
+	db, err = NewBlockDBFixedSize(chainDir, 1024)
+	assert.NoError(t, err)
+
+	// prune the chunks before snapshot block 31
+	pruneLocation, err := db.GetNextLocation(sbLocations[29])
+	assert.NoError(t, err)
+	assert.NoError(t, db.PruneTo(pruneLocation, 3, func(n int) bool { return true }))
+
+	_, err = os.Stat(path.Join(chainDir, "blocks", "f1"))
+	assert.True(t, os.IsNotExist(err))
+
+	check := func(db *BlockDB) {
+		assert.Equal(t, pruneLocation, db.PrunedLocation())
+		for hash, location := range abLocations {
+			ab, err := db.GetAccountBlock(location)
+			assert.NoError(t, err)
+			assert.Equal(t, hash, ab.Hash)
+			if db.IsPruned(location) {
+				assert.Nil(t, ab.Data)
+				assert.Nil(t, ab.Signature)
+			} else {
+				assert.Equal(t, 500, len(ab.Data))
+			}
+		}
+		for i, location := range sbLocations {
+			sb, err := db.GetSnapshotBlock(location)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(i+1), sb.Height)
+		}
+
+		// walk all blocks from the first location
+		count := 0
+		location := chain_file_manager.NewLocation(1, 0)
+		for location.Compare(db.LatestLocation()) < 0 {
+			_, _, next, err := db.ReadUnit(location)
+			assert.NoError(t, err)
+			location = next
+			count++
+		}
+		assert.Equal(t, 80, count)
+
+		// the raw readers refuse the pruned locations
+		_, _, err := db.ReadRaw(chain_file_manager.NewLocation(1, 0), make([]byte, 10))
+		assert.Equal(t, interfaces.ErrPruned, err)
+		_, err = db.ReadRange(chain_file_manager.NewLocation(1, 0), sbLocations[35])
+		assert.Equal(t, interfaces.ErrPruned, err)
+		chunks, err := db.ReadRange(pruneLocation, sbLocations[35])
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(31), chunks[0].SnapshotBlock.Height)
+	}
+	check(db)
+
+	// reopen
+	assert.NoError(t, db.Close())
+	db, err = NewBlockDBFixedSize(chainDir, 1024)
+	assert.NoError(t, err)
+	check(db)
+	assert.NoError(t, db.Close())
+}
@@ -66,6 +66,16 @@ type chain struct {
 
 	plugins *chain_plugins.Plugins
 
+	pruner *pruner
+
+	compactor *compactor
+
+	// backupMu makes the backups run one by one
+	backupMu sync.Mutex
+
+	// the height of the last warning about the version of the producers
+	versionWarnHeight uint64
+
 	status uint32
 }
 
@@ -155,6 +165,10 @@ func (c *chain) Start() error {
 	c.flusher.Start()
 	c.log.Info("Start flusher", "method", "Start")
 
+	c.pruner.Start()
+
+	c.compactor.Start()
+
 	return nil
 }
 
@@ -163,6 +177,10 @@ func (c *chain) Stop() error {
 		return nil
 	}
 
+	c.pruner.Stop()
+
+	c.compactor.Stop()
+
 	c.flusher.Stop()
 
 	c.log.Info("Stop flusher", "method", "Stop")
@@ -197,6 +215,13 @@ func (c *chain) Destroy() error {
 	}
 	c.log.Info("Close blockDB", "method", "Close")
 
+	if err := c.compactor.Close(); err != nil {
+		cErr := fmt.Errorf("c.compactor.Close failed, error is %s", err)
+		c.log.Error(cErr.Error(), "method", "Close")
+		return cErr
+	}
+	c.log.Info("Close compactor", "method", "Close")
+
 	if err := c.syncCache.Close(); err != nil {
 		cErr := fmt.Errorf("c.syncCache.Close failed, error is %s", err)
 		c.log.Error(cErr.Error(), "method", "Close")
@@ -210,6 +235,7 @@ func (c *chain) Destroy() error {
 	c.indexDB = nil
 	c.blockDB = nil
 	c.syncCache = nil
+	c.compactor = nil
 
 	c.log.Info("Complete destruction", "method", "Close")
 
@@ -241,6 +267,11 @@ func (c *chain) SetConsensus(cs Consensus) {
 }
 
 func (c *chain) newDbAndRecover() error {
+	if err := c.chainCfg.CheckStoreBackends(); err != nil {
+		c.log.Error(fmt.Sprintf("check store backends failed, error is %s", err), "method", "newDbAndRecover")
+		return err
+	}
+
 	var err error
 	// new metaDB
 	c.metaDB, err = c.NewDb("chain_meta")
@@ -250,7 +281,7 @@ func (c *chain) newDbAndRecover() error {
 	}
 
 	// new ledger db
-	if c.indexDB, err = chain_index.NewIndexDB(c.chainDir); err != nil {
+	if c.indexDB, err = chain_index.NewIndexDB(c.chainDir, c.chainCfg.GetStoreBackend(config.StoreIndex)); err != nil {
 		c.log.Error(fmt.Sprintf("chain_index.NewIndexDB failed, error is %s, chainDir is %s", err, c.chainDir), "method", "newDbAndRecover")
 		return err
 	}
@@ -272,7 +303,7 @@ func (c *chain) newDbAndRecover() error {
 	// init plugins
 	if c.chainCfg.OpenPlugins {
 		var err error
-		if c.plugins, err = chain_plugins.NewPlugins(c.chainDir, c); err != nil {
+		if c.plugins, err = chain_plugins.NewPlugins(c.chainDir, c, c.chainCfg.GetStoreBackend(config.StorePlugins)); err != nil {
 			cErr := fmt.Errorf("chain_plugins.NewPlugins failed. Error: %s", err)
 			c.log.Error(cErr.Error(), "method", "newDbAndRecover")
 			return cErr
@@ -291,6 +322,12 @@ func (c *chain) newDbAndRecover() error {
 		return cErr
 	}
 
+	if err := c.flusher.SetPolicy(makeFlushPolicy(c.chainCfg)); err != nil {
+		cErr := fmt.Errorf("c.flusher.SetPolicy failed. Error: %s", err)
+		c.log.Error(cErr.Error(), "method", "newDbAndRecover")
+		return cErr
+	}
+
 	// flusher check and recover
 	if err := c.flusher.Recover(); err != nil {
 		cErr := fmt.Errorf("c.flusher.Recover failed. Error: %s", err)
@@ -298,6 +335,20 @@ func (c *chain) newDbAndRecover() error {
 		return cErr
 	}
 
+	// new pruner
+	if c.pruner, err = newPruner(c); err != nil {
+		cErr := fmt.Errorf("newPruner failed. Error: %s", err)
+		c.log.Error(cErr.Error(), "method", "newDbAndRecover")
+		return cErr
+	}
+
+	// new compactor
+	if c.compactor, err = newCompactor(c); err != nil {
+		cErr := fmt.Errorf("newCompactor failed. Error: %s", err)
+		c.log.Error(cErr.Error(), "method", "newDbAndRecover")
+		return cErr
+	}
+
 	// new cache
 	if c.cache, err = chain_cache.NewCache(c); err != nil {
 		cErr := fmt.Errorf("chain_cache.NewCache failed, error is %s", err)
@@ -0,0 +1,235 @@
+package chain_cold
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	lru "github.com/hashicorp/golang-lru"
+
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+)
+
+const (
+	// the max size of a segment file, a segment larger than it takes a whole file
+	DefaultFileSize = int64(256 * 1024 * 1024)
+
+	segmentFileSuffix = ".seg"
+	// segment format: length(4) + crc32(4) + snappy(length(4) + block + length(4) + block ...)
+	segmentHeaderSize = 8
+	// the decoded segments in the cache
+	segmentCacheSize = 32
+)
+
+var errSegmentCorrupted = errors.New("the segment is corrupted")
+
+// SegmentDB is the cold tier of the account blocks. The account blocks of a compacted checkpoint range are
+// serialized, compressed by snappy and appended to the segment files as a segment, so they can be read by one
+// sequential read. The segments are never modified, a segment is located by the file id and the offset in the file.
+type SegmentDB struct {
+	dir      string
+	fileSize int64
+
+	mu          sync.Mutex
+	files       map[uint64]*os.File
+	writeFileId uint64
+	writeOffset int64
+
+	cache *lru.Cache
+}
+
+// NewSegmentDB opens the segment files in the directory, the new segments are appended to the latest file
+func NewSegmentDB(dir string, fileSize int64) (*SegmentDB, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	cache, err := lru.New(segmentCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sDB := &SegmentDB{
+		dir:      dir,
+		fileSize: fileSize,
+		files:    make(map[uint64]*os.File),
+		cache:    cache,
+	}
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fileInfo := range fileInfos {
+		fileId, ok := parseFileName(fileInfo.Name())
+		if !ok {
+			continue
+		}
+		if fileId > sDB.writeFileId {
+			sDB.writeFileId = fileId
+			sDB.writeOffset = fileInfo.Size()
+		}
+	}
+	if sDB.writeFileId <= 0 {
+		sDB.writeFileId = 1
+	}
+	return sDB, nil
+}
+
+// Write appends the account blocks to the segment files as a segment and syncs the file, returns the location of the segment
+func (sDB *SegmentDB) Write(blocks []*ledger.AccountBlock) (*chain_file_manager.Location, error) {
+	var payload []byte
+	for _, block := range blocks {
+		buf, err := block.Serialize()
+		if err != nil {
+			return nil, fmt.Errorf("serialize account block %s failed. Error: %s", block.Hash, err)
+		}
+		payload = appendUint32(payload, uint32(len(buf)))
+		payload = append(payload, buf...)
+	}
+
+	data := snappy.Encode(nil, payload)
+	segment := make([]byte, segmentHeaderSize, segmentHeaderSize+len(data))
+	binary.BigEndian.PutUint32(segment[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(segment[4:], crc32.ChecksumIEEE(data))
+	segment = append(segment, data...)
+
+	sDB.mu.Lock()
+	defer sDB.mu.Unlock()
+
+	if sDB.writeOffset > 0 && sDB.writeOffset+int64(len(segment)) > sDB.fileSize {
+		sDB.writeFileId++
+		sDB.writeOffset = 0
+	}
+	file, err := sDB.getFile(sDB.writeFileId, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.WriteAt(segment, sDB.writeOffset); err != nil {
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+
+	location := chain_file_manager.NewLocation(sDB.writeFileId, sDB.writeOffset)
+	sDB.writeOffset += int64(len(segment))
+	return location, nil
+}
+
+// Read returns the account blocks of the segment at the location, the blocks are in the order they were written
+func (sDB *SegmentDB) Read(location *chain_file_manager.Location) ([]*ledger.AccountBlock, error) {
+	cacheKey := *location
+	if blocks, ok := sDB.cache.Get(cacheKey); ok {
+		return blocks.([]*ledger.AccountBlock), nil
+	}
+
+	sDB.mu.Lock()
+	file, err := sDB.getFile(location.FileId, false)
+	sDB.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, segmentHeaderSize)
+	if _, err := file.ReadAt(header, location.Offset); err != nil {
+		return nil, fmt.Errorf("read the segment header at %s failed. Error: %s", location, err)
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	if _, err := file.ReadAt(data, location.Offset+segmentHeaderSize); err != nil {
+		return nil, fmt.Errorf("read the segment at %s failed. Error: %s", location, err)
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errSegmentCorrupted
+	}
+
+	payload, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*ledger.AccountBlock
+	for len(payload) > 0 {
+		if len(payload) < 4 {
+			return nil, errSegmentCorrupted
+		}
+		size := binary.BigEndian.Uint32(payload[:4])
+		payload = payload[4:]
+		if uint32(len(payload)) < size {
+			return nil, errSegmentCorrupted
+		}
+
+		block := &ledger.AccountBlock{}
+		if err := block.Deserialize(payload[:size]); err != nil {
+			return nil, fmt.Errorf("block.Deserialize failed. Error: %s", err)
+		}
+		blocks = append(blocks, block)
+		payload = payload[size:]
+	}
+
+	sDB.cache.Add(cacheKey, blocks)
+	return blocks, nil
+}
+
+// Close closes the segment files
+func (sDB *SegmentDB) Close() error {
+	sDB.mu.Lock()
+	defer sDB.mu.Unlock()
+
+	var closeErr error
+	for fileId, file := range sDB.files {
+		if err := file.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+		delete(sDB.files, fileId)
+	}
+	sDB.cache.Purge()
+	return closeErr
+}
+
+// getFile returns the opened segment file, the file is created if create is true. The caller must hold the lock.
+func (sDB *SegmentDB) getFile(fileId uint64, create bool) (*os.File, error) {
+	if file, ok := sDB.files[fileId]; ok {
+		return file, nil
+	}
+
+	flag := os.O_RDWR
+	if create {
+		flag |= os.O_CREATE
+	}
+	file, err := os.OpenFile(path.Join(sDB.dir, fileName(fileId)), flag, 0600)
+	if err != nil {
+		return nil, err
+	}
+	sDB.files[fileId] = file
+	return file, nil
+}
+
+func fileName(fileId uint64) string {
+	return strconv.FormatUint(fileId, 10) + segmentFileSuffix
+}
+
+func parseFileName(name string) (uint64, bool) {
+	if !strings.HasSuffix(name, segmentFileSuffix) {
+		return 0, false
+	}
+	fileId, err := strconv.ParseUint(strings.TrimSuffix(name, segmentFileSuffix), 10, 64)
+	if err != nil || fileId <= 0 {
+		return 0, false
+	}
+	return fileId, true
+}
+
+func appendUint32(buf []byte, n uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,88 @@
+package chain_cold
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+)
+
+func newTestBlocks(start, count uint64) []*ledger.AccountBlock {
+	var blocks []*ledger.AccountBlock
+	for height := start; height < start+count; height++ {
+		data := make([]byte, 100)
+		rand.Read(data)
+		ab := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			Height:         height,
+			AccountAddress: types.AddressGovernance,
+			Amount:         big.NewInt(1),
+			Fee:            big.NewInt(0),
+			Data:           data,
+			Signature:      make([]byte, 64),
+		}
+		ab.Hash = ab.ComputeHash()
+		blocks = append(blocks, ab)
+	}
+	return blocks
+}
+
+func TestSegmentDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "segment_db_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// small files, every segment takes a file
+	db, err := NewSegmentDB(dir, 1024)
+	assert.NoError(t, err)
+
+	var segments [][]*ledger.AccountBlock
+	var locations []*chain_file_manager.Location
+	for i := uint64(0); i < 3; i++ {
+		blocks := newTestBlocks(i*10+1, 10)
+		location, err := db.Write(blocks)
+		assert.NoError(t, err)
+		segments = append(segments, blocks)
+		locations = append(locations, location)
+	}
+	assert.Equal(t, *chain_file_manager.NewLocation(1, 0), *locations[0])
+	assert.Equal(t, *chain_file_manager.NewLocation(3, 0), *locations[2])
+
+	check := func(db *SegmentDB) {
+		for i, location := range locations {
+			blocks, err := db.Read(location)
+			assert.NoError(t, err)
+			assert.Equal(t, len(segments[i]), len(blocks))
+			for j, block := range blocks {
+				assert.Equal(t, segments[i][j].Hash, block.Hash)
+				assert.Equal(t, segments[i][j].Height, block.Height)
+			}
+		}
+	}
+	check(db)
+
+	// the segments are appended to the latest file after reopening
+	assert.NoError(t, db.Close())
+	db, err = NewSegmentDB(dir, 1024*1024)
+	assert.NoError(t, err)
+	check(db)
+
+	location, err := db.Write(newTestBlocks(31, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), location.FileId)
+	assert.True(t, location.Offset > 0)
+
+	// the missing file is not created by reading
+	_, err = db.Read(chain_file_manager.NewLocation(10, 0))
+	assert.Error(t, err)
+	_, err = os.Stat(dir + "/10" + segmentFileSuffix)
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, db.Close())
+}
@@ -0,0 +1,350 @@
+package chain
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_cold "github.com/vitelabs/go-vite/ledger/chain/cold"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_index "github.com/vitelabs/go-vite/ledger/chain/index"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	// the account blocks confirmed by the snapshot blocks deeper than compactConfirmDepth are compacted,
+	// they will hardly be rolled back
+	compactConfirmDepth = 3600
+
+	compactInterval        = 10 * time.Minute
+	compactBlocksPerSecond = 20000
+)
+
+// compactor indexes the old account blocks of the accounts by checkpoints in background, and copies them to the
+// segment files of the cold tier if the cold tier is enabled
+type compactor struct {
+	chain *chain
+
+	enabled  bool
+	accounts []types.Address
+	// nil if the cold tier is disabled
+	coldDB *chain_cold.SegmentDB
+
+	// runMu guards the start and the stop
+	runMu  sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	log log15.Logger
+}
+
+func newCompactor(c *chain) (*compactor, error) {
+	if err := c.chainCfg.CheckAccountCompaction(); err != nil {
+		return nil, err
+	}
+
+	cp := &compactor{
+		chain:    c,
+		enabled:  c.chainCfg.AccountCompaction,
+		accounts: c.chainCfg.AccountCompactionList,
+		log:      log15.New("module", "chain_compactor"),
+	}
+	if c.chainCfg.AccountColdTier {
+		var err error
+		if cp.coldDB, err = chain_cold.NewSegmentDB(path.Join(c.chainDir, "cold"), chain_cold.DefaultFileSize); err != nil {
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+func (cp *compactor) Start() {
+	if !cp.enabled {
+		return
+	}
+	cp.runMu.Lock()
+	defer cp.runMu.Unlock()
+	if cp.stopCh != nil {
+		return
+	}
+	cp.stopCh = make(chan struct{})
+	cp.wg.Add(1)
+	go func() {
+		defer cp.wg.Done()
+
+		cp.log.Info(fmt.Sprintf("start compactor, cold tier is %t", cp.coldDB != nil), "method", "Start")
+		ticker := time.NewTicker(compactInterval)
+		defer ticker.Stop()
+		for {
+			if err := cp.compact(); err != nil {
+				cp.log.Error(fmt.Sprintf("compact failed. Error: %s", err), "method", "Start")
+			}
+			select {
+			case <-cp.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the compactor and waits for the running compaction, returns false if the compactor is not running
+func (cp *compactor) Stop() bool {
+	cp.runMu.Lock()
+	defer cp.runMu.Unlock()
+	if cp.stopCh == nil {
+		return false
+	}
+	close(cp.stopCh)
+	cp.wg.Wait()
+	cp.stopCh = nil
+	return true
+}
+
+func (cp *compactor) Close() error {
+	if cp.coldDB == nil {
+		return nil
+	}
+	return cp.coldDB.Close()
+}
+
+// throttle sleeps for the compacted blocks, returns false if the compactor is stopped
+func (cp *compactor) throttle(n int) bool {
+	select {
+	case <-cp.stopCh:
+		return false
+	case <-time.After(time.Duration(n) * time.Second / compactBlocksPerSecond):
+		return true
+	}
+}
+
+func (cp *compactor) compact() error {
+	latest := cp.chain.GetLatestSnapshotBlock()
+	if latest == nil || latest.Height <= compactConfirmDepth {
+		return nil
+	}
+	confirmedHeight := latest.Height - compactConfirmDepth
+
+	if len(cp.accounts) > 0 {
+		for _, addr := range cp.accounts {
+			if ok, err := cp.compactAccount(addr, confirmedHeight); err != nil || !ok {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var compactErr error
+	cp.chain.indexDB.IterateAccounts(func(addr types.Address, accountId uint64, err error) bool {
+		if err != nil {
+			compactErr = err
+			return false
+		}
+		ok, err := cp.compactAccount(addr, confirmedHeight)
+		if err != nil {
+			compactErr = err
+		}
+		return ok && err == nil
+	})
+	return compactErr
+}
+
+// compactAccount writes the checkpoints of the account blocks confirmed before the snapshot height,
+// returns false if the compactor is stopped
+func (cp *compactor) compactAccount(addr types.Address, confirmedHeight uint64) (bool, error) {
+	iDB := cp.chain.indexDB
+
+	latestHeight, _, err := iDB.GetLatestAccountBlock(&addr)
+	if err != nil {
+		return false, err
+	}
+	compactedHeight, err := iDB.GetCompactedHeight(addr)
+	if err != nil {
+		return false, err
+	}
+
+	for compactedHeight+chain_index.AccountCheckpointInterval <= latestHeight {
+		start := compactedHeight + 1
+		end := compactedHeight + chain_index.AccountCheckpointInterval
+
+		endHash, _, err := iDB.GetAccountBlockLocationByHeight(&addr, end)
+		if err != nil {
+			return false, err
+		}
+		if endHash == nil {
+			return true, nil
+		}
+		confirmHeight, err := iDB.GetConfirmHeightByHash(endHash)
+		if err != nil {
+			return false, err
+		}
+		if confirmHeight <= 0 || confirmHeight > confirmedHeight {
+			return true, nil
+		}
+
+		checkpoint, err := cp.makeCheckpoint(addr, start, end)
+		if err != nil {
+			return false, fmt.Errorf("make the checkpoint of %s at height %d failed. Error: %s", addr, start, err)
+		}
+		if checkpoint == nil {
+			return true, nil
+		}
+		if ok, err := cp.insertCheckpoint(addr, checkpoint, *endHash); err != nil || !ok {
+			return true, err
+		}
+		compactedHeight = end
+
+		if !cp.throttle(len(checkpoint.Locations)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// makeCheckpoint returns the checkpoint of the account blocks in [start, end], returns nil if any block is missing
+func (cp *compactor) makeCheckpoint(addr types.Address, start, end uint64) (*chain_index.AccountCheckpoint, error) {
+	locations, heightRange, err := cp.chain.indexDB.GetAccountBlockLocationListByRange(addr, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(locations)) != end-start+1 || heightRange[0] != start || heightRange[1] != end {
+		return nil, nil
+	}
+
+	checkpoint := &chain_index.AccountCheckpoint{
+		StartHeight: start,
+		Locations:   make([]*chain_file_manager.Location, len(locations)),
+	}
+	// the locations are from high to low
+	for i, location := range locations {
+		if location == nil {
+			return nil, nil
+		}
+		checkpoint.Locations[len(locations)-1-i] = location
+	}
+
+	if cp.coldDB != nil {
+		blocks := make([]*ledger.AccountBlock, 0, len(checkpoint.Locations))
+		for i, location := range checkpoint.Locations {
+			block, err := cp.chain.blockDB.GetAccountBlock(location)
+			if err != nil {
+				return nil, err
+			}
+			if cp.chain.blockDB.IsPruned(location) {
+				// only the headers are left, the checkpoint is kept by the block files
+				return checkpoint, nil
+			}
+			if block == nil || block.Height != start+uint64(i) || block.AccountAddress != addr {
+				return nil, fmt.Errorf("the block at %s is not the block of %s at height %d", location, addr, start+uint64(i))
+			}
+			blocks = append(blocks, block)
+		}
+		if checkpoint.Segment, err = cp.coldDB.Write(blocks); err != nil {
+			return nil, err
+		}
+	}
+	return checkpoint, nil
+}
+
+// insertCheckpoint writes the checkpoint if the block at the end height is not rolled back while compacting,
+// the writing of the blocks is stopped while inserting
+func (cp *compactor) insertCheckpoint(addr types.Address, checkpoint *chain_index.AccountCheckpoint, endHash types.Hash) (bool, error) {
+	c := cp.chain
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	hash, _, err := c.indexDB.GetAccountBlockLocationByHeight(&addr, checkpoint.EndHeight())
+	if err != nil {
+		return false, err
+	}
+	if hash == nil || *hash != endHash {
+		return false, nil
+	}
+	return true, c.indexDB.InsertAccountCheckpoint(addr, checkpoint)
+}
+
+// getColdAccountBlocks returns the account blocks in the height range which are kept by the cold tier, height -> block
+func (c *chain) getColdAccountBlocks(addr types.Address, heightRange [2]uint64) (map[uint64]*ledger.AccountBlock, error) {
+	if c.compactor == nil || c.compactor.coldDB == nil || heightRange[0] > heightRange[1] {
+		return nil, nil
+	}
+	checkpoints, err := c.indexDB.GetAccountCheckpoints(addr, heightRange[0], heightRange[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks map[uint64]*ledger.AccountBlock
+	for _, checkpoint := range checkpoints {
+		segment, err := c.readSegment(addr, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if segment == nil {
+			continue
+		}
+		if blocks == nil {
+			blocks = make(map[uint64]*ledger.AccountBlock, heightRange[1]-heightRange[0]+1)
+		}
+		for _, block := range segment {
+			if block.Height >= heightRange[0] && block.Height <= heightRange[1] {
+				blocks[block.Height] = block
+			}
+		}
+	}
+	return blocks, nil
+}
+
+// getColdAccountBlock returns the account block kept by the cold tier, returns nil if the block is not in a segment
+func (c *chain) getColdAccountBlock(addr types.Address, height uint64) (*ledger.AccountBlock, error) {
+	if c.compactor == nil || c.compactor.coldDB == nil {
+		return nil, nil
+	}
+	checkpoints, err := c.indexDB.GetAccountCheckpoints(addr, height, height)
+	if err != nil || len(checkpoints) <= 0 {
+		return nil, err
+	}
+	segment, err := c.readSegment(addr, checkpoints[0])
+	if err != nil || segment == nil {
+		return nil, err
+	}
+	// the blocks of a segment are in the order of the heights
+	index := height - checkpoints[0].StartHeight
+	if index >= uint64(len(segment)) || segment[index].Height != height {
+		return nil, fmt.Errorf("the segment of %s at height %d doesn't keep the block at height %d", addr, checkpoints[0].StartHeight, height)
+	}
+	return segment[index], nil
+}
+
+// readSegment returns the decoded blocks of the segment of the checkpoint, returns nil if the checkpoint has no segment.
+// The decoded segments are cached by the segment db.
+func (c *chain) readSegment(addr types.Address, checkpoint *chain_index.AccountCheckpoint) ([]*ledger.AccountBlock, error) {
+	if checkpoint.Segment == nil {
+		return nil, nil
+	}
+	segment, err := c.compactor.coldDB.Read(checkpoint.Segment)
+	if err != nil {
+		return nil, fmt.Errorf("read the segment of %s at height %d failed. Error: %s", addr, checkpoint.StartHeight, err)
+	}
+	return segment, nil
+}
+
+// readAccountBlock reads the account block at the location of the block files. The block files are handed back to
+// the pruner once the blocks are sealed in the segments, so the body of a pruned block is read from the cold tier.
+// Only the header is returned if the pruned block is not in a segment.
+func (c *chain) readAccountBlock(location *chain_file_manager.Location) (*ledger.AccountBlock, error) {
+	block, err := c.blockDB.GetAccountBlock(location)
+	if err != nil || block == nil || !c.blockDB.IsPruned(location) {
+		return block, err
+	}
+	coldBlock, err := c.getColdAccountBlock(block.AccountAddress, block.Height)
+	if err != nil {
+		return nil, err
+	}
+	if coldBlock == nil || coldBlock.Hash != block.Hash {
+		return block, nil
+	}
+	return coldBlock, nil
+}
@@ -0,0 +1,150 @@
+package chain_db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/memdb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/opt"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/interfaces"
+)
+
+const (
+	BackendLevelDb  = config.StoreBackendLevelDb
+	BackendLogStore = config.StoreBackendLogStore
+	BackendBTree    = config.StoreBackendBTree
+)
+
+// Backend is the persistent key-value database under a Store. The Store keeps unflushed data in its
+// memory db, so the backend only receives the batches committed by the flusher.
+type Backend interface {
+	// Get returns leveldb.ErrNotFound if the key is not found
+	Get(key []byte) ([]byte, error)
+
+	Write(batch *leveldb.Batch) error
+
+	NewIterator(slice *util.Range) interfaces.StorageIterator
+
+	CompactRange(r util.Range) error
+
+	Stats() string
+
+	Close() error
+}
+
+// memDbMerger is implemented by the backend which can merge the memory db of the Store into reads by itself
+type memDbMerger interface {
+	Get2(key []byte, mdb *memdb.DB, seq uint64) ([]byte, error)
+	NewIterator2(slice *util.Range, mdb *memdb.DB, seq uint64) interfaces.StorageIterator
+}
+
+// syncWriter is implemented by the backend which doesn't sync its writes by default
+type syncWriter interface {
+	WriteSync(batch *leveldb.Batch) error
+}
+
+// OpenBackend opens the backend in dataDir. It fails if dataDir contains the data of another backend.
+func OpenBackend(dataDir string, backend string) (Backend, error) {
+	if len(backend) <= 0 {
+		backend = BackendLevelDb
+	}
+
+	existed, err := DetectBackend(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(existed) > 0 && existed != backend {
+		return nil, fmt.Errorf("%s is a %s store, can't open it as %s, migrate it first", dataDir, existed, backend)
+	}
+
+	switch backend {
+	case BackendLevelDb:
+		db, err := leveldb.OpenFile(dataDir, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &levelDbBackend{db: db}, nil
+	case BackendLogStore:
+		return openLogStore(dataDir)
+	case BackendBTree:
+		return openBTreeStore(dataDir)
+	default:
+		return nil, fmt.Errorf("unknown store backend %s", backend)
+	}
+}
+
+// DetectBackend returns the backend of the data in dataDir, returns "" if there is no data
+func DetectBackend(dataDir string) (string, error) {
+	if _, err := os.Stat(path.Join(dataDir, logStoreMarkerFile)); err == nil {
+		return BackendLogStore, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if _, err := os.Stat(path.Join(dataDir, btreeMarkerFile)); err == nil {
+		return BackendBTree, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if _, err := os.Stat(path.Join(dataDir, "CURRENT")); err == nil {
+		return BackendLevelDb, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	return "", nil
+}
+
+type levelDbBackend struct {
+	db *leveldb.DB
+}
+
+func (b *levelDbBackend) Get(key []byte) ([]byte, error) {
+	return b.db.Get(key, nil)
+}
+
+func (b *levelDbBackend) Get2(key []byte, mdb *memdb.DB, seq uint64) ([]byte, error) {
+	return b.db.Get2(key, nil, mdb, seq)
+}
+
+func (b *levelDbBackend) Write(batch *leveldb.Batch) error {
+	return b.db.Write(batch, nil)
+}
+
+func (b *levelDbBackend) WriteSync(batch *leveldb.Batch) error {
+	return b.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+func (b *levelDbBackend) NewIterator(slice *util.Range) interfaces.StorageIterator {
+	return b.db.NewIterator(slice, nil)
+}
+
+func (b *levelDbBackend) NewIterator2(slice *util.Range, mdb *memdb.DB, seq uint64) interfaces.StorageIterator {
+	return b.db.NewIterator2(slice, nil, mdb, seq)
+}
+
+func (b *levelDbBackend) CompactRange(r util.Range) error {
+	return b.db.CompactRange(r)
+}
+
+func (b *levelDbBackend) Stats() string {
+	s := &leveldb.DBStats{}
+	if err := b.db.Stats(s); err != nil {
+		return "Error:" + err.Error()
+	}
+
+	status, err := json.Marshal(s)
+	if err != nil {
+		return "Error:" + err.Error()
+	}
+	return string(status)
+}
+
+func (b *levelDbBackend) Close() error {
+	return b.db.Close()
+}
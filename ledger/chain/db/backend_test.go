@@ -0,0 +1,369 @@
+package chain_db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+)
+
+func newTestStore(t *testing.T, dir string, backend string) *Store {
+	store, err := NewStoreWithBackend(dir, "backendTest", backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func flushTestStore(t *testing.T, store *Store) {
+	store.Prepare()
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	store.AfterCommit()
+}
+
+func testKey(i int) []byte {
+	return []byte(fmt.Sprintf("key%03d", i))
+}
+
+func TestLogStoreReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := newTestStore(t, dir, BackendLogStore)
+	batch := new(leveldb.Batch)
+	for i := 0; i < 10; i++ {
+		batch.Put(testKey(i), []byte{byte(i)})
+	}
+	batch.Delete(testKey(3))
+	store.WriteDirectly(batch)
+	flushTestStore(t, store)
+
+	// rewrite the snapshot, then write to the log again
+	assert.NoError(t, store.CompactRange(util.Range{}))
+	batch = new(leveldb.Batch)
+	batch.Put(testKey(4), []byte("new"))
+	store.WriteDirectly(batch)
+	flushTestStore(t, store)
+	assert.NoError(t, store.Close())
+
+	// a torn record at the tail of the log is dropped
+	logFile, err := os.OpenFile(path.Join(dir, logStoreLogFile), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logFile.Write([]byte{0, 0, 1, 0, 1})
+	logFile.Close()
+
+	_, err = NewStoreWithBackend(dir, "backendTest", BackendLevelDb)
+	assert.Error(t, err)
+
+	store = newTestStore(t, dir, BackendLogStore)
+	defer store.Close()
+
+	value, err := store.Get(testKey(4))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), value)
+
+	ok, err := store.Has(testKey(3))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	count := 0
+	iter := store.NewIterator(nil)
+	for iter.Next() {
+		count++
+	}
+	iter.Release()
+	assert.Equal(t, 9, count)
+}
+
+func TestMemMergedIterator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := newTestStore(t, dir, BackendLogStore)
+	defer store.Close()
+
+	// the backend holds the even keys
+	batch := new(leveldb.Batch)
+	for i := 0; i < 10; i += 2 {
+		batch.Put(testKey(i), []byte("disk"))
+	}
+	store.WriteDirectly(batch)
+	flushTestStore(t, store)
+
+	// the memory db holds the odd keys, overrides key 4 and deletes key 0, key 6 and key 7
+	batch = new(leveldb.Batch)
+	for i := 1; i < 10; i += 2 {
+		batch.Put(testKey(i), []byte("mem"))
+	}
+	batch.Put(testKey(4), []byte("mem"))
+	batch.Delete(testKey(0))
+	batch.Delete(testKey(6))
+	batch.Delete(testKey(7))
+	store.WriteDirectly(batch)
+
+	value, err := store.Get(testKey(4))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("mem"), value)
+	value, err = store.Get(testKey(6))
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	expected := []int{1, 2, 3, 4, 5, 8, 9}
+	iter := store.NewIterator(nil)
+	defer iter.Release()
+
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	assert.Equal(t, len(expected), len(keys))
+	for i, index := range expected {
+		assert.Equal(t, string(testKey(index)), keys[i])
+	}
+
+	keys = keys[:0]
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		keys = append(keys, string(iter.Key()))
+	}
+	assert.Equal(t, len(expected), len(keys))
+	for i, index := range expected {
+		assert.Equal(t, string(testKey(index)), keys[len(keys)-1-i])
+	}
+
+	assert.True(t, iter.Seek(testKey(6)))
+	assert.Equal(t, testKey(8), iter.Key())
+	assert.True(t, iter.Prev())
+	assert.Equal(t, testKey(5), iter.Key())
+	assert.Equal(t, []byte("mem"), iter.Value())
+
+	rangeIter := store.NewIterator(&util.Range{Start: testKey(2), Limit: testKey(5)})
+	defer rangeIter.Release()
+	keys = keys[:0]
+	for rangeIter.Next() {
+		keys = append(keys, string(rangeIter.Key()))
+	}
+	assert.Equal(t, []string{string(testKey(2)), string(testKey(3)), string(testKey(4))}, keys)
+}
+
+func TestLogStoreRewriteFreesMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := openLogStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	batch := new(leveldb.Batch)
+	for i := 0; i < 100; i++ {
+		batch.Put(testKey(i), make([]byte, 1024))
+	}
+	assert.NoError(t, s.Write(batch))
+	batch = new(leveldb.Batch)
+	for i := 0; i < 99; i++ {
+		batch.Delete(testKey(i))
+	}
+	assert.NoError(t, s.Write(batch))
+	// the deleted values are still in the buffer of the table
+	oldTable := s.getTable()
+
+	// an iterator opened before the rewrite keeps reading the old table
+	iter := s.NewIterator(nil)
+	defer iter.Release()
+
+	assert.NoError(t, s.CompactRange(util.Range{}))
+	assert.Equal(t, 1, s.getTable().Len())
+	// the rebuilt table only allocates the buffer of the live key
+	assert.True(t, s.getTable() != oldTable)
+	assert.True(t, s.getTable().Size()+s.getTable().Free() < 10*1024)
+
+	value, err := s.Get(testKey(99))
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, len(value))
+	assert.True(t, iter.Next())
+	assert.Equal(t, testKey(99), iter.Key())
+}
+
+func TestBTreeStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "btree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := openBTreeStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// write enough keys to split the nodes into several levels, then delete most of them to merge the nodes
+	expected := make(map[string][]byte)
+	rnd := rand.New(rand.NewSource(1))
+	for round := 0; round < 20; round++ {
+		batch := new(leveldb.Batch)
+		for i := 0; i < 500; i++ {
+			key := []byte(fmt.Sprintf("key%06d", rnd.Intn(5000)))
+			if round >= 10 && rnd.Intn(3) > 0 {
+				batch.Delete(key)
+				delete(expected, string(key))
+				continue
+			}
+			value := make([]byte, rnd.Intn(100))
+			rnd.Read(value)
+			batch.Put(key, value)
+			expected[string(key)] = value
+		}
+		assert.NoError(t, s.Write(batch))
+	}
+	checkBTreeStore(t, s, expected)
+
+	// the iterator opened before the rewrite keeps reading the old data file
+	iter := s.NewIterator(nil)
+	oldFile := s.file.path
+	assert.True(t, s.meta.deadSize > 0)
+	assert.NoError(t, s.CompactRange(util.Range{}))
+	assert.Equal(t, int64(0), s.meta.deadSize)
+	count := 0
+	for iter.Next() {
+		count++
+	}
+	assert.NoError(t, iter.Error())
+	assert.Equal(t, len(expected), count)
+	_, err = os.Stat(oldFile)
+	assert.NoError(t, err)
+	iter.Release()
+	_, err = os.Stat(oldFile)
+	assert.True(t, os.IsNotExist(err))
+
+	checkBTreeStore(t, s, expected)
+	assert.NoError(t, s.Close())
+
+	// the nodes appended by an interrupted write are dropped
+	dataFile, err := os.OpenFile(btreeDataFile(dir, s.meta.gen), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataFile.Write([]byte{0, 0, 1, 0, 1})
+	dataFile.Close()
+
+	backend, err := DetectBackend(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, BackendBTree, backend)
+
+	s, err = openBTreeStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	checkBTreeStore(t, s, expected)
+
+	batch := new(leveldb.Batch)
+	for key := range expected {
+		batch.Delete([]byte(key))
+	}
+	assert.NoError(t, s.Write(batch))
+	assert.True(t, s.root.empty())
+	checkBTreeStore(t, s, map[string][]byte{})
+}
+
+func checkBTreeStore(t *testing.T, s *btreeStore, expected map[string][]byte) {
+	keys := make([]string, 0, len(expected))
+	for key, value := range expected {
+		keys = append(keys, key)
+		v, err := s.Get([]byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, value, v)
+	}
+	sort.Strings(keys)
+
+	_, err := s.Get([]byte("missing"))
+	assert.Equal(t, leveldb.ErrNotFound, err)
+
+	iter := s.NewIterator(nil)
+	defer iter.Release()
+	var iterated []string
+	for iter.Next() {
+		iterated = append(iterated, string(iter.Key()))
+		assert.Equal(t, expected[string(iter.Key())], iter.Value())
+	}
+	assert.NoError(t, iter.Error())
+	assert.Equal(t, len(keys), len(iterated))
+	assert.True(t, sort.StringsAreSorted(iterated))
+
+	iterated = iterated[:0]
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		iterated = append(iterated, string(iter.Key()))
+	}
+	assert.Equal(t, len(keys), len(iterated))
+	if len(keys) <= 2 {
+		return
+	}
+
+	// range iterator from the second key to the last key
+	rangeIter := s.NewIterator(&util.Range{Start: []byte(keys[1]), Limit: []byte(keys[len(keys)-1])})
+	defer rangeIter.Release()
+	count := 0
+	for rangeIter.Next() {
+		count++
+	}
+	assert.Equal(t, len(keys)-2, count)
+	assert.True(t, rangeIter.Last())
+	assert.Equal(t, keys[len(keys)-2], string(rangeIter.Key()))
+	assert.True(t, rangeIter.Seek([]byte(keys[0])))
+	assert.Equal(t, keys[1], string(rangeIter.Key()))
+	assert.False(t, rangeIter.Prev())
+	assert.False(t, rangeIter.Seek([]byte(keys[len(keys)-1])))
+}
+
+func TestBTreeStoreMemMergedIterator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "btree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := newTestStore(t, dir, BackendBTree)
+	defer store.Close()
+
+	batch := new(leveldb.Batch)
+	for i := 0; i < 10; i += 2 {
+		batch.Put(testKey(i), []byte("disk"))
+	}
+	store.WriteDirectly(batch)
+	flushTestStore(t, store)
+
+	batch = new(leveldb.Batch)
+	batch.Put(testKey(3), []byte("mem"))
+	batch.Delete(testKey(4))
+	store.WriteDirectly(batch)
+
+	var keys []string
+	iter := store.NewIterator(nil)
+	defer iter.Release()
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	assert.Equal(t, []string{string(testKey(0)), string(testKey(2)), string(testKey(3)), string(testKey(6)), string(testKey(8))}, keys)
+}
@@ -0,0 +1,1135 @@
+package chain_db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/interfaces"
+)
+
+const (
+	btreeMarkerFile   = "BTREE"
+	btreeMetaFile     = "btree.meta"
+	btreeDataFileGlob = "data-*.btree"
+
+	// split the nodes larger than btreeNodeSize, merge the nodes smaller than a quarter of it into a sibling
+	btreeNodeSize = 4096
+
+	// rewrite the data file when the dead nodes are larger than the live nodes and this size
+	btreeRewriteSize = 64 * 1024 * 1024
+
+	btreeCacheNodes = 8192
+
+	// meta slot format: txId(8) + generation(8) + root offset(8) + root size(8) + file size(8) + dead size(8) + crc32(4)
+	btreeMetaSlotSize = 52
+
+	btreeNodeLeaf   = 0
+	btreeNodeBranch = 1
+)
+
+var errBTreeCorrupted = errors.New("btree node is corrupted")
+
+// btreeStore is an embedded backend which keeps the keys in a copy-on-write B+tree in a data file. A write
+// appends the modified nodes and their parents to the end of the data file, syncs it, then switches the root
+// in the meta file, which has two slots written alternately, so an interrupted write leaves the previous root
+// intact. Only the root path of the modified keys is kept in memory during a write and the read nodes are
+// cached by an LRU, so the store can be larger than memory. The replaced nodes are dead space, the data file is
+// rewritten into a new generation when the dead space grows larger than the live data.
+type btreeStore struct {
+	dir string
+
+	// mu serializes the writes, the rewrite and the meta
+	mu       sync.Mutex
+	meta     btreeMeta
+	metaFile *os.File
+	closed   bool
+
+	// root and file are replaced by the writes and the rewrite, rootMu guards them for the readers
+	rootMu sync.RWMutex
+	root   btreeRef
+	file   *btreeFile
+
+	cache *lru.Cache
+}
+
+type btreeMeta struct {
+	txId     uint64
+	gen      uint64
+	root     btreeRef
+	fileSize int64
+	deadSize int64
+}
+
+// btreeRef points to a node in the data file, the offset of an empty tree is -1
+type btreeRef struct {
+	offset int64
+	size   int64
+
+	// node is not nil if the node is modified by the pending write
+	node *btreeNode
+}
+
+func (ref *btreeRef) empty() bool {
+	return ref.offset < 0 && ref.node == nil
+}
+
+// btreeNode is a leaf which holds the keys and values, or a branch which holds the children. keys[i] of a branch
+// is the lower bound of the keys in children[i].
+type btreeNode struct {
+	leaf     bool
+	keys     [][]byte
+	values   [][]byte
+	children []btreeRef
+}
+
+// btreeFile is a generation of the data file. It is closed when the store and all iterators release it,
+// the file of an old generation is removed then.
+type btreeFile struct {
+	gen    uint64
+	path   string
+	f      *os.File
+	refs   int32
+	remove int32
+}
+
+func (file *btreeFile) acquire() {
+	atomic.AddInt32(&file.refs, 1)
+}
+
+func (file *btreeFile) release() {
+	if atomic.AddInt32(&file.refs, -1) != 0 {
+		return
+	}
+	file.f.Close()
+	if atomic.LoadInt32(&file.remove) > 0 {
+		os.Remove(file.path)
+	}
+}
+
+type btreeCacheKey struct {
+	gen    uint64
+	offset int64
+}
+
+func btreeDataFile(dir string, gen uint64) string {
+	return path.Join(dir, fmt.Sprintf("data-%06d.btree", gen))
+}
+
+func openBTreeStore(dir string) (*btreeStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, btreeMarkerFile), []byte(BackendBTree), 0600); err != nil {
+		return nil, err
+	}
+
+	metaFile, err := os.OpenFile(path.Join(dir, btreeMetaFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readBTreeMeta(metaFile)
+	if err != nil {
+		metaFile.Close()
+		return nil, err
+	}
+
+	dataPath := btreeDataFile(dir, meta.gen)
+	dataFile, err := os.OpenFile(dataPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		metaFile.Close()
+		return nil, err
+	}
+	// drop the nodes appended by an interrupted write
+	if err := dataFile.Truncate(meta.fileSize); err != nil {
+		dataFile.Close()
+		metaFile.Close()
+		return nil, err
+	}
+
+	// remove the data files of the other generations left by an interrupted rewrite
+	if files, err := filepath.Glob(path.Join(dir, btreeDataFileGlob)); err == nil {
+		for _, file := range files {
+			if file != dataPath {
+				os.Remove(file)
+			}
+		}
+	}
+
+	cache, err := lru.New(btreeCacheNodes)
+	if err != nil {
+		dataFile.Close()
+		metaFile.Close()
+		return nil, err
+	}
+
+	return &btreeStore{
+		dir:      dir,
+		meta:     *meta,
+		metaFile: metaFile,
+		root:     meta.root,
+		file:     &btreeFile{gen: meta.gen, path: dataPath, f: dataFile, refs: 1},
+		cache:    cache,
+	}, nil
+}
+
+// readBTreeMeta returns the valid slot with the largest txId, or the meta of an empty store
+func readBTreeMeta(file *os.File) (*btreeMeta, error) {
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &btreeMeta{gen: 1, root: btreeRef{offset: -1}}
+	for i := 0; (i+1)*btreeMetaSlotSize <= len(data); i++ {
+		slot := data[i*btreeMetaSlotSize : (i+1)*btreeMetaSlotSize]
+		if crc32.ChecksumIEEE(slot[:48]) != binary.BigEndian.Uint32(slot[48:]) {
+			continue
+		}
+		txId := binary.BigEndian.Uint64(slot[0:])
+		if txId <= meta.txId {
+			continue
+		}
+		meta = &btreeMeta{
+			txId: txId,
+			gen:  binary.BigEndian.Uint64(slot[8:]),
+			root: btreeRef{
+				offset: int64(binary.BigEndian.Uint64(slot[16:])),
+				size:   int64(binary.BigEndian.Uint64(slot[24:])),
+			},
+			fileSize: int64(binary.BigEndian.Uint64(slot[32:])),
+			deadSize: int64(binary.BigEndian.Uint64(slot[40:])),
+		}
+	}
+	return meta, nil
+}
+
+// writeMeta writes the meta into the slot of its txId and syncs the meta file, assume locked
+func (s *btreeStore) writeMeta(meta *btreeMeta) error {
+	slot := make([]byte, btreeMetaSlotSize)
+	binary.BigEndian.PutUint64(slot[0:], meta.txId)
+	binary.BigEndian.PutUint64(slot[8:], meta.gen)
+	binary.BigEndian.PutUint64(slot[16:], uint64(meta.root.offset))
+	binary.BigEndian.PutUint64(slot[24:], uint64(meta.root.size))
+	binary.BigEndian.PutUint64(slot[32:], uint64(meta.fileSize))
+	binary.BigEndian.PutUint64(slot[40:], uint64(meta.deadSize))
+	binary.BigEndian.PutUint32(slot[48:], crc32.ChecksumIEEE(slot[:48]))
+
+	if _, err := s.metaFile.WriteAt(slot, int64(meta.txId%2)*btreeMetaSlotSize); err != nil {
+		return err
+	}
+	return s.metaFile.Sync()
+}
+
+// snapshot returns the current root and acquires its data file
+func (s *btreeStore) snapshot() (btreeRef, *btreeFile) {
+	s.rootMu.RLock()
+	defer s.rootMu.RUnlock()
+	s.file.acquire()
+	return s.root, s.file
+}
+
+func (s *btreeStore) readNode(file *btreeFile, ref btreeRef) (*btreeNode, error) {
+	if ref.node != nil {
+		return ref.node, nil
+	}
+
+	key := btreeCacheKey{gen: file.gen, offset: ref.offset}
+	if node, ok := s.cache.Get(key); ok {
+		return node.(*btreeNode), nil
+	}
+
+	data := make([]byte, ref.size)
+	if _, err := file.f.ReadAt(data, ref.offset); err != nil {
+		return nil, err
+	}
+	record, _, err := readRecord(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	node, err := decodeBTreeNode(record)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Add(key, node)
+	return node, nil
+}
+
+func (s *btreeStore) Get(key []byte) ([]byte, error) {
+	root, file := s.snapshot()
+	defer file.release()
+
+	value, ok, err := s.lookup(file, root, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, leveldb.ErrNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (s *btreeStore) lookup(file *btreeFile, ref btreeRef, key []byte) ([]byte, bool, error) {
+	for !ref.empty() {
+		node, err := s.readNode(file, ref)
+		if err != nil {
+			return nil, false, err
+		}
+		if node.leaf {
+			i := node.search(key)
+			if i < len(node.keys) && bytes.Equal(node.keys[i], key) {
+				return node.values[i], true, nil
+			}
+			return nil, false, nil
+		}
+		if bytes.Compare(key, node.keys[0]) < 0 {
+			return nil, false, nil
+		}
+		ref = node.children[node.childIndex(key)]
+	}
+	return nil, false, nil
+}
+
+func (s *btreeStore) Write(batch *leveldb.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("btree store is closed")
+	}
+
+	txn := &btreeTxn{s: s, file: s.file, root: s.meta.root}
+	replay := &btreeReplay{txn: txn}
+	if err := batch.Replay(replay); err != nil {
+		return err
+	}
+	if replay.err != nil {
+		return replay.err
+	}
+	if err := txn.commit(); err != nil {
+		return err
+	}
+
+	if s.meta.deadSize > btreeRewriteSize && s.meta.deadSize > s.meta.fileSize-s.meta.deadSize {
+		return s.rewrite()
+	}
+	return nil
+}
+
+func (s *btreeStore) NewIterator(slice *util.Range) interfaces.StorageIterator {
+	root, file := s.snapshot()
+	return &btreeIterator{
+		s:        s,
+		file:     file,
+		root:     root,
+		slice:    slice,
+		position: -1,
+	}
+}
+
+// CompactRange rewrites the live nodes into a new data file
+func (s *btreeStore) CompactRange(r util.Range) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("btree store is closed")
+	}
+	if s.meta.deadSize <= 0 {
+		return nil
+	}
+	return s.rewrite()
+}
+
+func (s *btreeStore) Stats() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, err := json.Marshal(map[string]int64{
+		"generation":  int64(s.meta.gen),
+		"fileSize":    s.meta.fileSize,
+		"deadSize":    s.meta.deadSize,
+		"cachedNodes": int64(s.cache.Len()),
+	})
+	if err != nil {
+		return "Error:" + err.Error()
+	}
+	return string(status)
+}
+
+func (s *btreeStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.rootMu.Lock()
+	file := s.file
+	s.rootMu.Unlock()
+	file.release()
+
+	s.cache.Purge()
+	return s.metaFile.Close()
+}
+
+// rewrite copies the live nodes into the data file of the next generation and switches to it, assume locked.
+// The iterators opened before keep reading the old data file until they are released.
+func (s *btreeStore) rewrite() error {
+	gen := s.meta.gen + 1
+	dataPath := btreeDataFile(s.dir, gen)
+	dataFile, err := os.OpenFile(dataPath, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		dataFile.Close()
+		os.Remove(dataPath)
+		return err
+	}
+
+	writer := bufio.NewWriter(&offsetWriter{f: dataFile})
+	var offset int64
+	root, err := s.copyNode(writer, s.file, s.meta.root, &offset)
+	if err != nil {
+		return fail(err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := dataFile.Sync(); err != nil {
+		return fail(err)
+	}
+
+	meta := btreeMeta{
+		txId:     s.meta.txId + 1,
+		gen:      gen,
+		root:     root,
+		fileSize: offset,
+	}
+	if err := s.writeMeta(&meta); err != nil {
+		return fail(err)
+	}
+	s.meta = meta
+
+	newFile := &btreeFile{gen: gen, path: dataPath, f: dataFile, refs: 1}
+	s.rootMu.Lock()
+	oldFile := s.file
+	s.file = newFile
+	s.root = root
+	s.rootMu.Unlock()
+
+	atomic.StoreInt32(&oldFile.remove, 1)
+	oldFile.release()
+	return nil
+}
+
+// copyNode writes the subtree of ref into the writer in post order, returns the ref in the new file
+func (s *btreeStore) copyNode(writer *bufio.Writer, file *btreeFile, ref btreeRef, offset *int64) (btreeRef, error) {
+	if ref.empty() {
+		return ref, nil
+	}
+	node, err := s.readNode(file, ref)
+	if err != nil {
+		return btreeRef{}, err
+	}
+	if !node.leaf {
+		node = node.clone()
+		for i := range node.children {
+			if node.children[i], err = s.copyNode(writer, file, node.children[i], offset); err != nil {
+				return btreeRef{}, err
+			}
+		}
+	}
+
+	size, err := writeRecord(writer, node.encode())
+	if err != nil {
+		return btreeRef{}, err
+	}
+	newRef := btreeRef{offset: *offset, size: size}
+	*offset += size
+	return newRef, nil
+}
+
+// offsetWriter writes at the offset of the file, so a failed write is overwritten by the next one
+type offsetWriter struct {
+	f      *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// btreeTxn applies a batch to a copy of the tree, the modified nodes are kept in memory until the commit
+type btreeTxn struct {
+	s    *btreeStore
+	file *btreeFile
+	root btreeRef
+
+	// size of the replaced nodes
+	dead int64
+
+	written []btreeRef
+}
+
+// mutable returns the node of ref which can be modified by the txn
+func (t *btreeTxn) mutable(ref *btreeRef) (*btreeNode, error) {
+	if ref.node != nil {
+		return ref.node, nil
+	}
+	node, err := t.s.readNode(t.file, *ref)
+	if err != nil {
+		return nil, err
+	}
+	ref.node = node.clone()
+	t.dead += ref.size
+	return ref.node, nil
+}
+
+func (t *btreeTxn) put(key, value []byte) error {
+	if t.root.empty() {
+		t.root.node = &btreeNode{leaf: true}
+	}
+	root, err := t.mutable(&t.root)
+	if err != nil {
+		return err
+	}
+	if err := t.insert(root, key, value); err != nil {
+		return err
+	}
+
+	if root.oversized() {
+		left, right := root.split()
+		t.root = btreeRef{
+			offset: -1,
+			node: &btreeNode{
+				keys:     [][]byte{left.keys[0], right.keys[0]},
+				children: []btreeRef{{offset: -1, node: left}, {offset: -1, node: right}},
+			},
+		}
+	}
+	return nil
+}
+
+func (t *btreeTxn) insert(node *btreeNode, key, value []byte) error {
+	if node.leaf {
+		i := node.search(key)
+		if i < len(node.keys) && bytes.Equal(node.keys[i], key) {
+			node.values[i] = append([]byte{}, value...)
+			return nil
+		}
+		node.keys = append(node.keys, nil)
+		copy(node.keys[i+1:], node.keys[i:])
+		node.keys[i] = append([]byte{}, key...)
+		node.values = append(node.values, nil)
+		copy(node.values[i+1:], node.values[i:])
+		node.values[i] = append([]byte{}, value...)
+		return nil
+	}
+
+	if bytes.Compare(key, node.keys[0]) < 0 {
+		node.keys[0] = append([]byte{}, key...)
+	}
+	i := node.childIndex(key)
+	child, err := t.mutable(&node.children[i])
+	if err != nil {
+		return err
+	}
+	if err := t.insert(child, key, value); err != nil {
+		return err
+	}
+
+	if child.oversized() {
+		left, right := child.split()
+		node.children[i] = btreeRef{offset: -1, node: left}
+		node.insertChild(i+1, right.keys[0], btreeRef{offset: -1, node: right})
+	}
+	return nil
+}
+
+func (t *btreeTxn) delete(key []byte) error {
+	if _, ok, err := t.s.lookup(t.file, t.root, key); err != nil || !ok {
+		return err
+	}
+
+	root, err := t.mutable(&t.root)
+	if err != nil {
+		return err
+	}
+	if err := t.remove(root, key); err != nil {
+		return err
+	}
+
+	if len(root.keys) <= 0 {
+		t.root = btreeRef{offset: -1}
+	} else if !root.leaf && len(root.children) == 1 {
+		t.root = root.children[0]
+	}
+	return nil
+}
+
+// remove deletes the key which exists in the subtree of node
+func (t *btreeTxn) remove(node *btreeNode, key []byte) error {
+	if node.leaf {
+		i := node.search(key)
+		node.keys = append(node.keys[:i], node.keys[i+1:]...)
+		node.values = append(node.values[:i], node.values[i+1:]...)
+		return nil
+	}
+
+	i := node.childIndex(key)
+	child, err := t.mutable(&node.children[i])
+	if err != nil {
+		return err
+	}
+	if err := t.remove(child, key); err != nil {
+		return err
+	}
+
+	if len(child.keys) <= 0 {
+		node.removeChild(i)
+		return nil
+	}
+	if child.size() >= btreeNodeSize/4 || len(node.children) <= 1 {
+		return nil
+	}
+
+	// merge the underfull child into a sibling, split it again if the merged node is too large
+	if i+1 >= len(node.children) {
+		i--
+	}
+	left, err := t.mutable(&node.children[i])
+	if err != nil {
+		return err
+	}
+	right, err := t.mutable(&node.children[i+1])
+	if err != nil {
+		return err
+	}
+	left.keys = append(left.keys, right.keys...)
+	left.values = append(left.values, right.values...)
+	left.children = append(left.children, right.children...)
+	node.removeChild(i + 1)
+
+	if left.oversized() {
+		left, right := left.split()
+		node.children[i] = btreeRef{offset: -1, node: left}
+		node.insertChild(i+1, right.keys[0], btreeRef{offset: -1, node: right})
+	}
+	return nil
+}
+
+// commit appends the modified nodes to the data file and switches the root, assume locked
+func (t *btreeTxn) commit() error {
+	s := t.s
+	if t.root.node == nil && t.root.offset == s.meta.root.offset {
+		return nil
+	}
+
+	writer := bufio.NewWriter(&offsetWriter{f: t.file.f, offset: s.meta.fileSize})
+	offset := s.meta.fileSize
+	if err := t.writeNode(writer, &t.root, &offset); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := t.file.f.Sync(); err != nil {
+		return err
+	}
+
+	meta := btreeMeta{
+		txId:     s.meta.txId + 1,
+		gen:      s.meta.gen,
+		root:     t.root,
+		fileSize: offset,
+		deadSize: s.meta.deadSize + t.dead,
+	}
+	if err := s.writeMeta(&meta); err != nil {
+		return err
+	}
+	s.meta = meta
+
+	for _, ref := range t.written {
+		s.cache.Add(btreeCacheKey{gen: t.file.gen, offset: ref.offset}, ref.node)
+	}
+
+	s.rootMu.Lock()
+	s.root = t.root
+	s.rootMu.Unlock()
+	return nil
+}
+
+// writeNode writes the modified nodes of the subtree of ref in post order
+func (t *btreeTxn) writeNode(writer *bufio.Writer, ref *btreeRef, offset *int64) error {
+	node := ref.node
+	if node == nil {
+		return nil
+	}
+	for i := range node.children {
+		if err := t.writeNode(writer, &node.children[i], offset); err != nil {
+			return err
+		}
+	}
+
+	size, err := writeRecord(writer, node.encode())
+	if err != nil {
+		return err
+	}
+	ref.offset = *offset
+	ref.size = size
+	ref.node = nil
+	*offset += size
+
+	t.written = append(t.written, btreeRef{offset: ref.offset, size: size, node: node})
+	return nil
+}
+
+// btreeReplay replays a batch into a txn
+type btreeReplay struct {
+	txn *btreeTxn
+	err error
+}
+
+func (r *btreeReplay) Put(key, value []byte) {
+	if r.err == nil {
+		r.err = r.txn.put(key, value)
+	}
+}
+
+func (r *btreeReplay) Delete(key []byte) {
+	if r.err == nil {
+		r.err = r.txn.delete(key)
+	}
+}
+
+// search returns the index of the first key which is not less than key
+func (node *btreeNode) search(key []byte) int {
+	return sort.Search(len(node.keys), func(i int) bool {
+		return bytes.Compare(node.keys[i], key) >= 0
+	})
+}
+
+// childIndex returns the index of the child whose range contains key
+func (node *btreeNode) childIndex(key []byte) int {
+	i := sort.Search(len(node.keys), func(i int) bool {
+		return bytes.Compare(node.keys[i], key) > 0
+	}) - 1
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+func (node *btreeNode) insertChild(i int, key []byte, child btreeRef) {
+	node.keys = append(node.keys, nil)
+	copy(node.keys[i+1:], node.keys[i:])
+	node.keys[i] = key
+	node.children = append(node.children, btreeRef{})
+	copy(node.children[i+1:], node.children[i:])
+	node.children[i] = child
+}
+
+func (node *btreeNode) removeChild(i int) {
+	node.keys = append(node.keys[:i], node.keys[i+1:]...)
+	node.children = append(node.children[:i], node.children[i+1:]...)
+}
+
+func (node *btreeNode) clone() *btreeNode {
+	return &btreeNode{
+		leaf:     node.leaf,
+		keys:     append([][]byte{}, node.keys...),
+		values:   append([][]byte{}, node.values...),
+		children: append([]btreeRef{}, node.children...),
+	}
+}
+
+func (node *btreeNode) oversized() bool {
+	return len(node.keys) > 1 && node.size() > btreeNodeSize
+}
+
+func (node *btreeNode) entrySize(i int) int {
+	if node.leaf {
+		return uvarintSize(uint64(len(node.keys[i]))) + len(node.keys[i]) +
+			uvarintSize(uint64(len(node.values[i]))) + len(node.values[i])
+	}
+	return uvarintSize(uint64(len(node.keys[i]))) + len(node.keys[i]) +
+		uvarintSize(uint64(node.children[i].offset)) + uvarintSize(uint64(node.children[i].size))
+}
+
+// size returns the encoded size of the node, the offsets of the modified children are estimated
+func (node *btreeNode) size() int {
+	size := 1 + uvarintSize(uint64(len(node.keys)))
+	for i := range node.keys {
+		size += node.entrySize(i)
+	}
+	return size
+}
+
+// split moves the second half of the entries into a new node by size
+func (node *btreeNode) split() (*btreeNode, *btreeNode) {
+	half := node.size() / 2
+	mid, size := 0, 0
+	for mid < len(node.keys)-1 && size < half {
+		size += node.entrySize(mid)
+		mid++
+	}
+	if mid <= 0 {
+		mid = 1
+	}
+
+	left := &btreeNode{leaf: node.leaf, keys: append([][]byte{}, node.keys[:mid]...)}
+	right := &btreeNode{leaf: node.leaf, keys: append([][]byte{}, node.keys[mid:]...)}
+	if node.leaf {
+		left.values = append([][]byte{}, node.values[:mid]...)
+		right.values = append([][]byte{}, node.values[mid:]...)
+	} else {
+		left.children = append([]btreeRef{}, node.children[:mid]...)
+		right.children = append([]btreeRef{}, node.children[mid:]...)
+	}
+	return left, right
+}
+
+// node format: type(1) + count + count * (key + value) for a leaf, count * (key + child offset + child size) for a branch
+func (node *btreeNode) encode() []byte {
+	data := make([]byte, 0, node.size())
+	buf := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(buf, x)
+		data = append(data, buf[:n]...)
+	}
+
+	if node.leaf {
+		data = append(data, btreeNodeLeaf)
+	} else {
+		data = append(data, btreeNodeBranch)
+	}
+	putUvarint(uint64(len(node.keys)))
+	for i, key := range node.keys {
+		putUvarint(uint64(len(key)))
+		data = append(data, key...)
+		if node.leaf {
+			putUvarint(uint64(len(node.values[i])))
+			data = append(data, node.values[i]...)
+		} else {
+			putUvarint(uint64(node.children[i].offset))
+			putUvarint(uint64(node.children[i].size))
+		}
+	}
+	return data
+}
+
+func decodeBTreeNode(data []byte) (*btreeNode, error) {
+	if len(data) <= 0 || data[0] > btreeNodeBranch {
+		return nil, errBTreeCorrupted
+	}
+	node := &btreeNode{leaf: data[0] == btreeNodeLeaf}
+	data = data[1:]
+
+	readUvarint := func() (uint64, error) {
+		x, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, errBTreeCorrupted
+		}
+		data = data[n:]
+		return x, nil
+	}
+	readBytes := func() ([]byte, error) {
+		length, err := readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if length > uint64(len(data)) {
+			return nil, errBTreeCorrupted
+		}
+		b := data[:length:length]
+		data = data[length:]
+		return b, nil
+	}
+
+	count, err := readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(data)) {
+		return nil, errBTreeCorrupted
+	}
+	node.keys = make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		key, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+		node.keys = append(node.keys, key)
+
+		if node.leaf {
+			value, err := readBytes()
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		} else {
+			offset, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			size, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, btreeRef{offset: int64(offset), size: int64(size)})
+		}
+	}
+	if len(data) > 0 || len(node.keys) <= 0 {
+		return nil, errBTreeCorrupted
+	}
+	return node, nil
+}
+
+func uvarintSize(x uint64) int {
+	size := 1
+	for x >= 0x80 {
+		x >>= 7
+		size++
+	}
+	return size
+}
+
+type btreeCursor struct {
+	node  *btreeNode
+	index int
+}
+
+// btreeIterator iterates the tree of the root when it is created
+type btreeIterator struct {
+	s     *btreeStore
+	file  *btreeFile
+	root  btreeRef
+	slice *util.Range
+
+	// path from the root to the current leaf
+	path []btreeCursor
+
+	// -1 before the first key, 1 after the last key, 0 at a key
+	position int
+
+	err      error
+	released bool
+}
+
+func (it *btreeIterator) Next() bool {
+	if it.position > 0 || it.released || it.err != nil {
+		return false
+	}
+	if it.position < 0 {
+		return it.Seek(nil)
+	}
+	return it.checkLimit(it.next())
+}
+
+func (it *btreeIterator) Prev() bool {
+	if it.position < 0 || it.released || it.err != nil {
+		return false
+	}
+	if it.position > 0 {
+		return it.Last()
+	}
+	return it.checkStart(it.prev())
+}
+
+func (it *btreeIterator) Seek(key []byte) bool {
+	if it.released || it.err != nil {
+		return false
+	}
+	if it.slice != nil && it.slice.Start != nil && bytes.Compare(key, it.slice.Start) < 0 {
+		key = it.slice.Start
+	}
+	if !it.seekGE(key) {
+		it.position = 1
+		return false
+	}
+	return it.checkLimit(true)
+}
+
+func (it *btreeIterator) Last() bool {
+	if it.released || it.err != nil {
+		return false
+	}
+	var ok bool
+	if it.slice != nil && it.slice.Limit != nil && it.seekGE(it.slice.Limit) {
+		ok = it.prev()
+	} else {
+		ok = it.err == nil && it.seekLast()
+	}
+	return it.checkStart(ok)
+}
+
+func (it *btreeIterator) Key() []byte {
+	if it.position != 0 {
+		return nil
+	}
+	cursor := it.path[len(it.path)-1]
+	return cursor.node.keys[cursor.index]
+}
+
+func (it *btreeIterator) Value() []byte {
+	if it.position != 0 {
+		return nil
+	}
+	cursor := it.path[len(it.path)-1]
+	return cursor.node.values[cursor.index]
+}
+
+func (it *btreeIterator) Error() error {
+	return it.err
+}
+
+func (it *btreeIterator) Release() {
+	if it.released {
+		return
+	}
+	it.released = true
+	it.path = nil
+	it.file.release()
+}
+
+func (it *btreeIterator) checkLimit(ok bool) bool {
+	if !ok || (it.slice != nil && it.slice.Limit != nil && bytes.Compare(it.current(), it.slice.Limit) >= 0) {
+		it.position = 1
+		return false
+	}
+	it.position = 0
+	return true
+}
+
+func (it *btreeIterator) checkStart(ok bool) bool {
+	if !ok || (it.slice != nil && it.slice.Start != nil && bytes.Compare(it.current(), it.slice.Start) < 0) {
+		it.position = -1
+		return false
+	}
+	it.position = 0
+	return true
+}
+
+// current returns the key of the cursor regardless of the position
+func (it *btreeIterator) current() []byte {
+	cursor := it.path[len(it.path)-1]
+	return cursor.node.keys[cursor.index]
+}
+
+func (it *btreeIterator) read(ref btreeRef) *btreeNode {
+	node, err := it.s.readNode(it.file, ref)
+	if err != nil {
+		it.err = err
+		return nil
+	}
+	return node
+}
+
+// seekGE moves the cursor to the first key which is not less than key, a nil key means the first key
+func (it *btreeIterator) seekGE(key []byte) bool {
+	it.path = it.path[:0]
+	if it.root.empty() {
+		return false
+	}
+	ref := it.root
+	for {
+		node := it.read(ref)
+		if node == nil {
+			return false
+		}
+		if node.leaf {
+			index := node.search(key)
+			if index < len(node.keys) {
+				it.path = append(it.path, btreeCursor{node: node, index: index})
+				return true
+			}
+			it.path = append(it.path, btreeCursor{node: node, index: len(node.keys) - 1})
+			return it.next()
+		}
+		index := node.childIndex(key)
+		it.path = append(it.path, btreeCursor{node: node, index: index})
+		ref = node.children[index]
+	}
+}
+
+func (it *btreeIterator) seekLast() bool {
+	it.path = it.path[:0]
+	if it.root.empty() {
+		return false
+	}
+	return it.descend(it.root, false)
+}
+
+// descend moves to the first or the last key of the subtree of ref
+func (it *btreeIterator) descend(ref btreeRef, first bool) bool {
+	for {
+		node := it.read(ref)
+		if node == nil {
+			return false
+		}
+		index := 0
+		if !first {
+			index = len(node.keys) - 1
+		}
+		it.path = append(it.path, btreeCursor{node: node, index: index})
+		if node.leaf {
+			return true
+		}
+		ref = node.children[index]
+	}
+}
+
+func (it *btreeIterator) next() bool {
+	leaf := &it.path[len(it.path)-1]
+	if leaf.index+1 < len(leaf.node.keys) {
+		leaf.index++
+		return true
+	}
+	for depth := len(it.path) - 2; depth >= 0; depth-- {
+		cursor := &it.path[depth]
+		if cursor.index+1 < len(cursor.node.children) {
+			cursor.index++
+			it.path = it.path[:depth+1]
+			return it.descend(cursor.node.children[cursor.index], true)
+		}
+	}
+	return false
+}
+
+func (it *btreeIterator) prev() bool {
+	leaf := &it.path[len(it.path)-1]
+	if leaf.index > 0 {
+		leaf.index--
+		return true
+	}
+	for depth := len(it.path) - 2; depth >= 0; depth-- {
+		cursor := &it.path[depth]
+		if cursor.index > 0 {
+			cursor.index--
+			it.path = it.path[:depth+1]
+			return it.descend(cursor.node.children[cursor.index], false)
+		}
+	}
+	return false
+}
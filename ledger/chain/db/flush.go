@@ -18,6 +18,20 @@ func (store *Store) Id() types.Hash {
 	return store.id
 }
 
+func (store *Store) Name() string {
+	return store.name
+}
+
+// DirtySize returns the size of the snapshot batch which is not flushed, assume lock write
+func (store *Store) DirtySize() uint64 {
+	return uint64(len(store.snapshotBatch.Dump()))
+}
+
+// SetSyncCommit sets whether the commit is synced to the disk, it is called by the flusher between flushes
+func (store *Store) SetSyncCommit(sync bool) {
+	store.syncCommit = sync
+}
+
 // assume lock write when prepare
 func (store *Store) Prepare() {
 	if store.flushingBatch != nil {
@@ -47,7 +61,11 @@ func (store *Store) RedoLog() ([]byte, error) {
 }
 
 func (store *Store) Commit() error {
-	if err := store.db.Write(store.flushingBatch, nil); err != nil {
+	write := store.db.Write
+	if writer, ok := store.db.(syncWriter); ok && store.syncCommit {
+		write = writer.WriteSync
+	}
+	if err := write(store.flushingBatch); err != nil {
 		return err
 	}
 	return nil
@@ -60,7 +78,7 @@ func (store *Store) PatchRedoLog(redoLog []byte) error {
 		return err
 	}
 
-	if err := store.db.Write(batch, nil); err != nil {
+	if err := store.db.Write(batch); err != nil {
 		return err
 	}
 
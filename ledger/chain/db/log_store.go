@@ -0,0 +1,342 @@
+package chain_db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/comparer"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/memdb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/interfaces"
+)
+
+const (
+	logStoreMarkerFile   = "LOGSTORE"
+	logStoreSnapshotFile = "data.snapshot"
+	logStoreLogFile      = "data.log"
+
+	// rewrite the snapshot when the log is larger than the snapshot and this size
+	logStoreRewriteSize = 256 * 1024 * 1024
+
+	logStoreSnapshotBatchSize = 10000
+	logStoreRecordHeaderSize  = 8
+)
+
+var errLogStoreCorrupted = errors.New("log store record is corrupted")
+
+// logStore is an embedded backend which keeps all keys in a sorted memory table and appends every
+// written batch to a log file. The log is rewritten into a snapshot file when it grows, so there is
+// no background compaction and writes never stall. The memory table never frees the space of deleted or
+// overwritten keys until the rewrite rebuilds it, so all data must fit in memory and the churn must be low.
+// It suits small stores which are mostly appended, like the plugins store, or nodes of a private network.
+// The redo store deletes every log once the snapshot is flushed, so it can't use the log store, larger stores
+// should use the btree store.
+type logStore struct {
+	dir string
+
+	mu sync.Mutex
+
+	// table is replaced by the rewrite, tableMu guards the pointer so reads don't wait for the log sync
+	tableMu sync.RWMutex
+	table   *memdb.DB
+
+	logFile *os.File
+	logSize int64
+
+	snapshotSize int64
+}
+
+func openLogStore(dir string) (*logStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, logStoreMarkerFile), []byte(BackendLogStore), 0600); err != nil {
+		return nil, err
+	}
+
+	s := &logStore{
+		dir:   dir,
+		table: memdb.New(comparer.DefaultComparer, 0),
+	}
+
+	// load snapshot
+	if snapshotSize, err := s.loadFile(path.Join(dir, logStoreSnapshotFile), false); err != nil {
+		return nil, err
+	} else {
+		s.snapshotSize = snapshotSize
+	}
+
+	// load log, the broken tail written by an interrupted write is truncated
+	logSize, err := s.loadFile(path.Join(dir, logStoreLogFile), true)
+	if err != nil {
+		return nil, err
+	}
+
+	logFile, err := os.OpenFile(path.Join(dir, logStoreLogFile), os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := logFile.Truncate(logSize); err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	if _, err := logFile.Seek(logSize, io.SeekStart); err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	s.logFile = logFile
+	s.logSize = logSize
+	return s, nil
+}
+
+func (s *logStore) getTable() *memdb.DB {
+	s.tableMu.RLock()
+	defer s.tableMu.RUnlock()
+	return s.table
+}
+
+func (s *logStore) Get(key []byte) ([]byte, error) {
+	value, err := s.getTable().Get(key)
+	if err != nil {
+		if err == memdb.ErrNotFound {
+			return nil, leveldb.ErrNotFound
+		}
+		return nil, err
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (s *logStore) Write(batch *leveldb.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.logFile == nil {
+		return errors.New("log store is closed")
+	}
+
+	size, err := writeRecord(s.logFile, batch.Dump())
+	if err != nil {
+		return err
+	}
+	if err := s.logFile.Sync(); err != nil {
+		return err
+	}
+	s.logSize += size
+
+	if err := batch.Replay(tableReplay{s.getTable()}); err != nil {
+		return err
+	}
+
+	if s.logSize > logStoreRewriteSize && s.logSize > s.snapshotSize {
+		return s.rewrite()
+	}
+	return nil
+}
+
+func (s *logStore) NewIterator(slice *util.Range) interfaces.StorageIterator {
+	return s.getTable().NewIterator(slice)
+}
+
+// CompactRange rewrites the snapshot file, clears the log and rebuilds the memory table
+func (s *logStore) CompactRange(r util.Range) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.logSize <= 0 {
+		return nil
+	}
+	return s.rewrite()
+}
+
+func (s *logStore) Stats() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table := s.getTable()
+	status, err := json.Marshal(map[string]int64{
+		"keys":         int64(table.Len()),
+		"memorySize":   int64(table.Size()),
+		"snapshotSize": s.snapshotSize,
+		"logSize":      s.logSize,
+	})
+	if err != nil {
+		return "Error:" + err.Error()
+	}
+	return string(status)
+}
+
+func (s *logStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.logFile == nil {
+		return nil
+	}
+	err := s.logFile.Close()
+	s.logFile = nil
+	s.getTable().Reset()
+	return err
+}
+
+// rewrite writes all keys into a new snapshot file and truncates the log, assume locked. The memory table is
+// rebuilt with the live keys to free the space of the deleted and overwritten ones, the iterators opened
+// before keep reading the old table.
+func (s *logStore) rewrite() error {
+	tmpFile := path.Join(s.dir, logStoreSnapshotFile+".tmp")
+	file, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	var size int64
+	batch := new(leveldb.Batch)
+	flush := func() error {
+		if batch.Len() <= 0 {
+			return nil
+		}
+		n, err := writeRecord(writer, batch.Dump())
+		size += n
+		batch.Reset()
+		return err
+	}
+
+	table := memdb.New(comparer.DefaultComparer, 0)
+	iter := s.getTable().NewIterator(nil)
+	for iter.Next() {
+		table.Put(iter.Key(), iter.Value())
+		batch.Put(iter.Key(), iter.Value())
+		if batch.Len() >= logStoreSnapshotBatchSize {
+			if err := flush(); err != nil {
+				iter.Release()
+				file.Close()
+				return err
+			}
+		}
+	}
+	iter.Release()
+
+	if err := flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, path.Join(s.dir, logStoreSnapshotFile)); err != nil {
+		return err
+	}
+
+	if err := s.logFile.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.logFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.snapshotSize = size
+	s.logSize = 0
+
+	s.tableMu.Lock()
+	s.table = table
+	s.tableMu.Unlock()
+	return nil
+}
+
+// loadFile replays the records of the file into the table, returns the size of the valid records.
+// If allowBrokenTail is false, a broken record is treated as an error.
+func (s *logStore) loadFile(fileName string, allowBrokenTail bool) (int64, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var size int64
+	for {
+		data, n, err := readRecord(reader)
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			if allowBrokenTail && (err == io.ErrUnexpectedEOF || err == errLogStoreCorrupted) {
+				return size, nil
+			}
+			return 0, err
+		}
+
+		batch := new(leveldb.Batch)
+		if err := batch.Load(data); err != nil {
+			return 0, err
+		}
+		if err := batch.Replay(tableReplay{s.table}); err != nil {
+			return 0, err
+		}
+		size += n
+	}
+}
+
+// tableReplay replays a batch into the memory table
+type tableReplay struct {
+	table *memdb.DB
+}
+
+func (r tableReplay) Put(key, value []byte) {
+	r.table.Put(key, value)
+}
+
+func (r tableReplay) Delete(key []byte) {
+	r.table.Delete(key)
+}
+
+// record format: length(4) + crc32(4) + data
+func writeRecord(w io.Writer, data []byte) (int64, error) {
+	header := make([]byte, logStoreRecordHeaderSize)
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(data))
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return 0, err
+	}
+	return int64(len(header) + len(data)), nil
+}
+
+func readRecord(r io.Reader) ([]byte, int64, error) {
+	header := make([]byte, logStoreRecordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errLogStoreCorrupted
+	}
+	return data, int64(len(header) + len(data)), nil
+}
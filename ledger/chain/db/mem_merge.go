@@ -0,0 +1,226 @@
+package chain_db
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/comparer"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/memdb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/interfaces"
+)
+
+var memDbComparer = leveldb.NewIComparer(comparer.DefaultComparer)
+
+// getWithMemDb reads the key from the memory db of the Store first, then from the backend
+func getWithMemDb(backend Backend, key []byte, mdb *memdb.DB, seq uint64) ([]byte, error) {
+	if mdb != nil {
+		if ok, value, err := leveldb.MemGet(mdb, leveldb.MakeInternalKey(nil, key, seq, leveldb.KeyTypeSeek), memDbComparer); ok {
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte{}, value...), nil
+		}
+	}
+	return backend.Get(key)
+}
+
+type memEntry struct {
+	key     []byte
+	value   []byte
+	deleted bool
+}
+
+// memEntries returns the latest version of the keys in the memory db which are visible at seq
+func memEntries(mdb *memdb.DB, slice *util.Range, seq uint64) []memEntry {
+	if mdb == nil {
+		return nil
+	}
+	var internalSlice *util.Range
+	if slice != nil {
+		internalSlice = &util.Range{}
+		if slice.Start != nil {
+			internalSlice.Start = leveldb.MakeInternalKey(nil, slice.Start, leveldb.KeyMaxSeq, leveldb.KeyTypeSeek)
+		}
+		if slice.Limit != nil {
+			internalSlice.Limit = leveldb.MakeInternalKey(nil, slice.Limit, leveldb.KeyMaxSeq, leveldb.KeyTypeSeek)
+		}
+	}
+
+	iter := mdb.NewIterator(internalSlice)
+	defer iter.Release()
+
+	var entries []memEntry
+	for iter.Next() {
+		ukey, entrySeq, kt, err := leveldb.ParseInternalKey(iter.Key())
+		if err != nil || entrySeq > seq {
+			continue
+		}
+		// versions of a key are ordered by seq desc, keep the first visible one
+		if len(entries) > 0 && bytes.Equal(entries[len(entries)-1].key, ukey) {
+			continue
+		}
+		entries = append(entries, memEntry{
+			key:     append([]byte{}, ukey...),
+			value:   append([]byte{}, iter.Value()...),
+			deleted: kt == leveldb.KeyTypeDel,
+		})
+	}
+	return entries
+}
+
+// memMergedIterator merges the memory db of the Store into the iterator of a backend. The keys in the
+// memory db override the keys in the backend, and the deleted keys are skipped.
+type memMergedIterator struct {
+	entries []memEntry
+	iter    interfaces.StorageIterator
+
+	key   []byte
+	value []byte
+
+	// -1 before the first key, 1 after the last key, 0 at a key
+	position int
+}
+
+func newMemMergedIterator(backend Backend, slice *util.Range, mdb *memdb.DB, seq uint64) interfaces.StorageIterator {
+	return &memMergedIterator{
+		entries:  memEntries(mdb, slice, seq),
+		iter:     backend.NewIterator(slice),
+		position: -1,
+	}
+}
+
+func (mi *memMergedIterator) Next() bool {
+	if mi.position > 0 {
+		return false
+	}
+	if mi.position < 0 {
+		return mi.forward(nil, true)
+	}
+	return mi.forward(mi.key, false)
+}
+
+func (mi *memMergedIterator) Prev() bool {
+	if mi.position < 0 {
+		return false
+	}
+	if mi.position > 0 {
+		return mi.backward(nil)
+	}
+	return mi.backward(mi.key)
+}
+
+func (mi *memMergedIterator) Seek(key []byte) bool {
+	return mi.forward(key, true)
+}
+
+func (mi *memMergedIterator) Last() bool {
+	return mi.backward(nil)
+}
+
+func (mi *memMergedIterator) Key() []byte {
+	if mi.position != 0 {
+		return nil
+	}
+	return mi.key
+}
+
+func (mi *memMergedIterator) Value() []byte {
+	if mi.position != 0 {
+		return nil
+	}
+	return mi.value
+}
+
+func (mi *memMergedIterator) Error() error {
+	return mi.iter.Error()
+}
+
+func (mi *memMergedIterator) Release() {
+	mi.iter.Release()
+}
+
+// forward moves to the first visible key which is greater than from, or equal to from if inclusive.
+// A nil from means the start of the range.
+func (mi *memMergedIterator) forward(from []byte, inclusive bool) bool {
+	for {
+		// candidate in memory db
+		memIndex := sort.Search(len(mi.entries), func(i int) bool {
+			c := bytes.Compare(mi.entries[i].key, from)
+			return c > 0 || (c == 0 && (inclusive || from == nil))
+		})
+
+		// candidate in backend
+		var ok bool
+		if from == nil {
+			ok = mi.iter.Seek(nil)
+		} else {
+			ok = mi.iter.Seek(from)
+			if ok && !inclusive && bytes.Equal(mi.iter.Key(), from) {
+				ok = mi.iter.Next()
+			}
+		}
+
+		if memIndex >= len(mi.entries) && !ok {
+			mi.position = 1
+			return false
+		}
+
+		if memIndex < len(mi.entries) && (!ok || bytes.Compare(mi.entries[memIndex].key, mi.iter.Key()) <= 0) {
+			entry := mi.entries[memIndex]
+			if entry.deleted {
+				from, inclusive = entry.key, false
+				continue
+			}
+			mi.set(entry.key, entry.value)
+			return true
+		}
+		mi.set(mi.iter.Key(), mi.iter.Value())
+		return true
+	}
+}
+
+// backward moves to the last visible key which is less than from. A nil from means the end of the range.
+func (mi *memMergedIterator) backward(from []byte) bool {
+	for {
+		memIndex := len(mi.entries) - 1
+		if from != nil {
+			memIndex = sort.Search(len(mi.entries), func(i int) bool {
+				return bytes.Compare(mi.entries[i].key, from) >= 0
+			}) - 1
+		}
+
+		var ok bool
+		if from == nil {
+			ok = mi.iter.Last()
+		} else if mi.iter.Seek(from) {
+			ok = mi.iter.Prev()
+		} else {
+			ok = mi.iter.Last()
+		}
+
+		if memIndex < 0 && !ok {
+			mi.position = -1
+			return false
+		}
+
+		if memIndex >= 0 && (!ok || bytes.Compare(mi.entries[memIndex].key, mi.iter.Key()) >= 0) {
+			entry := mi.entries[memIndex]
+			if entry.deleted {
+				from = entry.key
+				continue
+			}
+			mi.set(entry.key, entry.value)
+			return true
+		}
+		mi.set(mi.iter.Key(), mi.iter.Value())
+		return true
+	}
+}
+
+func (mi *memMergedIterator) set(key, value []byte) {
+	mi.key = append(mi.key[:0], key...)
+	mi.value = append(mi.value[:0], value...)
+	mi.position = 0
+}
@@ -0,0 +1,70 @@
+package chain_db
+
+import (
+	"fmt"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+)
+
+const migrateBatchSize = 10000
+
+// MigrateStore copies all keys of the store in srcDir into a new store in dstDir with the backend,
+// returns the number of copied keys. The store in srcDir must not be opened by a running node.
+func MigrateStore(srcDir string, dstDir string, backend string) (uint64, error) {
+	srcBackend, err := DetectBackend(srcDir)
+	if err != nil {
+		return 0, err
+	}
+	if len(srcBackend) <= 0 {
+		return 0, fmt.Errorf("%s is not a store", srcDir)
+	}
+	if existed, err := DetectBackend(dstDir); err != nil {
+		return 0, err
+	} else if len(existed) > 0 {
+		return 0, fmt.Errorf("%s is not empty", dstDir)
+	}
+
+	src, err := OpenBackend(srcDir, srcBackend)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := OpenBackend(dstDir, backend)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	iter := src.NewIterator(nil)
+	defer iter.Release()
+
+	count := uint64(0)
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		count++
+
+		if batch.Len() >= migrateBatchSize {
+			if err := dst.Write(batch); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	if batch.Len() > 0 {
+		if err := dst.Write(batch); err != nil {
+			return 0, err
+		}
+	}
+
+	// rewrite the data into a compact layout
+	if err := dst.CompactRange(util.Range{}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -1,7 +1,6 @@
 package chain_db
 
 import (
-	"encoding/json"
 	"errors"
 	"os"
 	"sync"
@@ -27,24 +26,38 @@ type Store struct {
 
 	unconfirmedBatchs *UnconfirmedBatchs
 
-	dbDir string
-	db    *leveldb.DB
+	dbDir   string
+	backend string
+	db      Backend
+	// sync the writes of the flusher commits to the disk
+	syncCommit bool
 
 	afterRecoverFuncs []func()
 }
 
 func NewStore(dataDir string, name string) (*Store, error) {
-	diskStore, err := leveldb.OpenFile(dataDir, nil)
+	return NewStoreWithBackend(dataDir, name, BackendLevelDb)
+}
+
+// NewStoreWithBackend opens the store in dataDir with the backend, the default backend is leveldb
+func NewStoreWithBackend(dataDir string, name string, backend string) (*Store, error) {
+	if len(backend) <= 0 {
+		backend = BackendLevelDb
+	}
 
+	diskStore, err := OpenBackend(dataDir, backend)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewStoreWithDb(dataDir, name, diskStore)
-
+	return newStore(dataDir, name, backend, diskStore), nil
 }
 
 func NewStoreWithDb(dataDir string, name string, diskStore *leveldb.DB) (*Store, error) {
+	return newStore(dataDir, name, BackendLevelDb, &levelDbBackend{db: diskStore}), nil
+}
+
+func newStore(dataDir string, name string, backend string, diskStore Backend) *Store {
 	id, _ := types.BytesToHash(crypto.Hash256([]byte(name)))
 
 	store := &Store{
@@ -54,13 +67,19 @@ func NewStoreWithDb(dataDir string, name string, diskStore *leveldb.DB) (*Store,
 
 		unconfirmedBatchs: NewUnconfirmedBatchs(),
 
-		dbDir: dataDir,
-		db:    diskStore,
+		dbDir:   dataDir,
+		backend: backend,
+		db:      diskStore,
 	}
 
 	store.snapshotBatch = store.getNewBatch()
 
-	return store, nil
+	return store
+}
+
+// Backend returns the name of the backend
+func (store *Store) Backend() string {
+	return store.backend
 }
 
 func (store *Store) CompactRange(r util.Range) error {
@@ -74,7 +93,7 @@ func (store *Store) NewBatch() *leveldb.Batch {
 func (store *Store) Get(key []byte) ([]byte, error) {
 	mdb, seq := store.getSnapshotMemDb()
 
-	value, err := store.db.Get2(key, nil, mdb, seq)
+	value, err := store.get(key, mdb, seq)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
 			return nil, nil
@@ -87,13 +106,13 @@ func (store *Store) Get(key []byte) ([]byte, error) {
 
 func (store *Store) GetOriginal(key []byte) ([]byte, error) {
 	mdb, seq := store.getSnapshotMemDb()
-	return store.db.Get2(key, nil, mdb, seq)
+	return store.get(key, mdb, seq)
 }
 
 func (store *Store) Has(key []byte) (bool, error) {
 	mdb, seq := store.getSnapshotMemDb()
 
-	_, err := store.db.Get2(key, nil, mdb, seq)
+	_, err := store.get(key, mdb, seq)
 
 	if err != nil {
 		if err == leveldb.ErrNotFound {
@@ -109,7 +128,10 @@ func (store *Store) Has(key []byte) (bool, error) {
 func (store *Store) NewIterator(slice *util.Range) interfaces.StorageIterator {
 	mdb, seq := store.getSnapshotMemDb()
 
-	return store.db.NewIterator2(slice, nil, mdb, seq)
+	if merger, ok := store.db.(memDbMerger); ok {
+		return merger.NewIterator2(slice, mdb, seq)
+	}
+	return newMemMergedIterator(store.db, slice, mdb, seq)
 }
 
 func (store *Store) Close() error {
@@ -155,24 +177,16 @@ func (store *Store) GetStatus() []interfaces.DBStatus {
 		size += store.snapshotBatch.Size()
 	}
 
-	s := &leveldb.DBStats{}
-	store.db.Stats(s)
-
-	status, err := json.Marshal(s)
-	if err != nil {
-		status = []byte("Error:" + err.Error())
-	}
-
 	return []interfaces.DBStatus{{
 		Name:   "mem",
 		Count:  uint64(count),
 		Size:   uint64(size),
 		Status: "",
 	}, {
-		Name:   "levelDB",
+		Name:   backendStatusName(store.backend),
 		Count:  0,
 		Size:   0,
-		Status: string(status),
+		Status: store.db.Stats(),
 	}}
 }
 
@@ -185,6 +199,20 @@ func (store *Store) getSnapshotMemDb() (*memdb.DB, uint64) {
 	return mdb, seq
 }
 
+func (store *Store) get(key []byte, mdb *memdb.DB, seq uint64) ([]byte, error) {
+	if merger, ok := store.db.(memDbMerger); ok {
+		return merger.Get2(key, mdb, seq)
+	}
+	return getWithMemDb(store.db, key, mdb, seq)
+}
+
 func (store *Store) putMemDb(batch *leveldb.Batch) {
 	batch.Replay(store.memDb)
 }
+
+func backendStatusName(backend string) string {
+	if backend == BackendLevelDb {
+		return "levelDB"
+	}
+	return backend
+}
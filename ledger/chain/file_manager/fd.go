@@ -82,7 +82,7 @@ func (fd *fileDescription) Write(buf []byte) (int, error) {
 	return count, nil
 }
 
-func (fd *fileDescription) Flush(startOffset int64, buf []byte) (int, error) {
+func (fd *fileDescription) Flush(startOffset int64, buf []byte, sync bool) (int, error) {
 
 	cacheItem := fd.cacheItem
 
@@ -119,6 +119,9 @@ func (fd *fileDescription) Flush(startOffset int64, buf []byte) (int, error) {
 		return n, err
 	}
 
+	if !sync {
+		return n, nil
+	}
 	if err := file.Sync(); err != nil {
 		return n, err
 	}
@@ -3,6 +3,7 @@ package chain_file_manager
 import (
 	"container/list"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
@@ -204,6 +205,37 @@ func (fdSet *fdManager) DiskDelete(highLocation *Location, lowLocation *Location
 	return nil
 }
 
+// DiskDeleteBefore removes the files before the file id, the files in the cache are kept
+func (fdSet *fdManager) DiskDeleteBefore(fileId uint64) error {
+	fdSet.changeFdMu.RLock()
+	if front := fdSet.fileCache.Front(); front != nil {
+		if cacheFileId := front.Value.(*fileCacheItem).FileId; cacheFileId > 0 && cacheFileId < fileId {
+			fileId = cacheFileId
+		}
+	}
+	fdSet.changeFdMu.RUnlock()
+
+	fileInfos, err := ioutil.ReadDir(fdSet.dirName)
+	if err != nil {
+		return fmt.Errorf("ioutil.ReadDir failed, error is %s, dirName is %s", err.Error(), fdSet.dirName)
+	}
+
+	for _, fileInfo := range fileInfos {
+		filename := fileInfo.Name()
+		if !fdSet.isCorrectFile(filename) {
+			continue
+		}
+		id, err := fdSet.filenameToFileId(filename)
+		if err != nil || id >= fileId {
+			continue
+		}
+		if err := os.Remove(fdSet.fileIdToAbsoluteFilename(id)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 func (fdSet *fdManager) CreateNextFd() error {
 	fdSet.changeFdMu.Lock()
 	defer fdSet.changeFdMu.Unlock()
@@ -16,6 +16,8 @@ type FileManager struct {
 	fdSet                  *fdManager
 	nextFlushStartLocation *Location
 	prevFlushLocation      *Location
+	// the flushed data is not synced to the disk if noSyncFlush is true
+	noSyncFlush bool
 
 	fSyncWg sync.WaitGroup
 	log     log15.Logger
@@ -50,6 +52,11 @@ func (fm *FileManager) SetNextFlushStartLocation(location *Location) {
 	fm.nextFlushStartLocation = NewLocation(location.FileId, location.Offset)
 }
 
+// SetSyncFlush sets whether the flushed data is synced to the disk, it is called between flushes
+func (fm *FileManager) SetSyncFlush(sync bool) {
+	fm.noSyncFlush = !sync
+}
+
 func (fm *FileManager) LatestLocation() *Location {
 	return fm.fdSet.LatestLocation()
 }
@@ -91,6 +98,11 @@ func (fm *FileManager) DeleteTo(location *Location) error {
 	return nil
 }
 
+// DeleteFilesBefore removes the files before the file id from the disk, the data in the files can't be read any more
+func (fm *FileManager) DeleteFilesBefore(fileId uint64) error {
+	return fm.fdSet.DiskDeleteBefore(fileId)
+}
+
 func (fm *FileManager) Flush(startLocation *Location, targetLocation *Location, buf []byte) error {
 	// flush
 	flushLocation := NewLocation(startLocation.FileId, startLocation.Offset)
@@ -120,7 +132,7 @@ func (fm *FileManager) Flush(startLocation *Location, targetLocation *Location,
 
 		bufEnd := bufStart + targetOffset - flushLocation.Offset
 
-		n, err := fd.Flush(flushLocation.Offset, buf[bufStart:bufEnd])
+		n, err := fd.Flush(flushLocation.Offset, buf[bufStart:bufEnd], !fm.noSyncFlush)
 		if err != nil {
 			return fmt.Errorf("fd flush failed, fileId is %d", flushLocation.FileId)
 		}
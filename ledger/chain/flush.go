@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"time"
+
+	"github.com/vitelabs/go-vite/common/config"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_flusher "github.com/vitelabs/go-vite/ledger/chain/flusher"
+)
+
+func makeFlushPolicy(cfg *config.Chain) chain_flusher.Policy {
+	policy := chain_flusher.DefaultPolicy()
+	if cfg == nil {
+		return policy
+	}
+	if cfg.FlushInterval > 0 {
+		policy.Interval = time.Duration(cfg.FlushInterval) * time.Millisecond
+	}
+	policy.DirtyBytes = cfg.FlushDirtyBytes
+	policy.SnapshotCount = cfg.FlushSnapshotCount
+	if len(cfg.FlushFsync) > 0 {
+		policy.Fsync = cfg.FlushFsync
+	}
+	return policy
+}
+
+// GetFlushStats returns the metrics of the flusher
+func (c *chain) GetFlushStats() *chain_flusher.Stats {
+	return c.flusher.Stats()
+}
+
+// FlushCheckpoint flushes all the ledger data to the disk and syncs it, returns the latest snapshot block when
+// flushing. The ledger on the disk is consistent at the returned block until the next flush.
+func (c *chain) FlushCheckpoint() (*ledger.SnapshotBlock, error) {
+	var latest *ledger.SnapshotBlock
+	if err := c.flusher.Checkpoint(func() {
+		latest = c.GetLatestSnapshotBlock()
+	}); err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
@@ -42,6 +42,28 @@ type Storage interface {
 	PatchRedoLog([]byte) error
 }
 
+// DirtySizer is implemented by the store which reports the size of the unflushed data, it is called with the write locked
+type DirtySizer interface {
+	DirtySize() uint64
+}
+
+// SyncCommitter is implemented by the store whose commit can be synced to the disk
+type SyncCommitter interface {
+	SetSyncCommit(sync bool)
+}
+
+// FileCommitter is implemented by the store which appends the commits to its own files, the commits are synced
+// unless the fsync policy is none
+type FileCommitter interface {
+	SyncCommitter
+	IsFileCommitter()
+}
+
+// Namer is implemented by the store which has a readable name in the stats
+type Namer interface {
+	Name() string
+}
+
 type Flusher struct {
 	dirName   string
 	storeList []Storage
@@ -55,8 +77,15 @@ type Flusher struct {
 	syncFlush sync.WaitGroup
 	wg        sync.WaitGroup
 
-	flushInterval time.Duration
-	flushingMu    sync.Mutex
+	flushingMu sync.Mutex
+
+	policyMu sync.RWMutex
+	policy   Policy
+	// the number of the snapshot blocks inserted since the last flush
+	snapshotCount uint64
+
+	statsMu sync.Mutex
+	stats   Stats
 
 	startCommitFlag types.Hash
 	commitWg        sync.WaitGroup
@@ -96,10 +125,14 @@ func NewFlusher(storeList []Storage, flushMu *sync.RWMutex, chainDir string) (*F
 		log: log15.New("module", "flusher"),
 		fd:  fd,
 
-		flushInterval: 900 * time.Millisecond,
+		policy: DefaultPolicy(),
 
 		startCommitFlag: startCommitFlag,
 	}
+	flusher.stats.Triggers = make(map[string]uint64)
+	for _, store := range storeList {
+		flusher.stats.Stores = append(flusher.stats.Stores, &StoreStats{Name: storeName(store)})
+	}
 
 	return flusher, nil
 }
@@ -119,11 +152,52 @@ func (flusher *Flusher) ReplaceStore(id types.Hash, store Storage) {
 	for index, istore := range flusher.storeList {
 		if istore.Id() == id {
 			flusher.storeList[index] = store
+			flusher.applySyncCommit(store, flusher.Policy().Fsync)
 			break
 		}
 	}
 }
 
+// SetPolicy changes the flush policy, it takes effect from the next check of the triggers
+func (flusher *Flusher) SetPolicy(policy Policy) error {
+	if err := policy.Check(); err != nil {
+		return err
+	}
+
+	flusher.flushingMu.Lock()
+	defer flusher.flushingMu.Unlock()
+
+	flusher.policyMu.Lock()
+	flusher.policy = policy
+	flusher.policyMu.Unlock()
+
+	for _, store := range flusher.storeList {
+		flusher.applySyncCommit(store, policy.Fsync)
+	}
+	return nil
+}
+
+func (flusher *Flusher) Policy() Policy {
+	flusher.policyMu.RLock()
+	defer flusher.policyMu.RUnlock()
+	return flusher.policy
+}
+
+// Stats returns a copy of the flushing metrics
+func (flusher *Flusher) Stats() *Stats {
+	flusher.statsMu.Lock()
+	defer flusher.statsMu.Unlock()
+
+	stats := flusher.stats.copy()
+	stats.Policy = flusher.Policy()
+	return stats
+}
+
+// MarkSnapshot counts an inserted snapshot block for the snapshot count trigger
+func (flusher *Flusher) MarkSnapshot() {
+	atomic.AddUint64(&flusher.snapshotCount, 1)
+}
+
 func (flusher *Flusher) Abort() {
 	if !atomic.CompareAndSwapInt32(&flusher.flusherStatus, start, aborted) {
 		return
@@ -151,7 +225,29 @@ func (flusher *Flusher) Stop() {
 
 // force to flush synchronously
 func (flusher *Flusher) Flush() {
-	flusher.flush()
+	flusher.flush(TriggerManual, nil)
+}
+
+// Checkpoint flushes synchronously and syncs the redo log and the commits of all stores to the disk whatever the
+// fsync policy is. onPrepared is called with the write locked after the stores are prepared, so the data read by it
+// is exactly the data flushed.
+func (flusher *Flusher) Checkpoint(onPrepared func()) error {
+	return flusher.flush(TriggerCheckpoint, onPrepared)
+}
+
+// Pause flushes like Checkpoint and stops flushing until Resume is called, so the data on the disk stays at the
+// checkpoint while paused. Resume must not be called if Pause failed.
+func (flusher *Flusher) Pause(onPrepared func()) error {
+	flusher.flushingMu.Lock()
+	if err := flusher.flushLocked(TriggerCheckpoint, onPrepared); err != nil {
+		flusher.flushingMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (flusher *Flusher) Resume() {
+	flusher.flushingMu.Unlock()
 }
 
 func (flusher *Flusher) Recover() error {
@@ -181,6 +277,10 @@ func (flusher *Flusher) loopFlush() {
 	go func() {
 		defer flusher.wg.Done()
 
+		flusher.flush(TriggerInterval, nil)
+
+		ticker := time.NewTicker(triggerCheckInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-flusher.terminal:
@@ -190,34 +290,90 @@ func (flusher *Flusher) loopFlush() {
 					return
 				}
 
-				flusher.flush()
+				flusher.flush(TriggerStop, nil)
 				return
 
-			default:
-				flusher.flush()
-				time.Sleep(flusher.flushInterval)
-
+			case <-ticker.C:
+				if trigger := flusher.trigger(); trigger != "" {
+					flusher.flush(trigger, nil)
+				}
 			}
 		}
 	}()
 }
 
-func (flusher *Flusher) flush() {
+// trigger returns the reached limit of the policy, returns "" if no flush is needed
+func (flusher *Flusher) trigger() string {
+	policy := flusher.Policy()
+
+	flusher.statsMu.Lock()
+	lastFlushTime := flusher.stats.LastFlushTime
+	flusher.statsMu.Unlock()
+
+	if time.Since(lastFlushTime) >= policy.Interval {
+		return TriggerInterval
+	}
+	if policy.SnapshotCount > 0 && atomic.LoadUint64(&flusher.snapshotCount) >= policy.SnapshotCount {
+		return TriggerSnapshotCount
+	}
+	if policy.DirtyBytes > 0 && flusher.dirtySize() >= policy.DirtyBytes {
+		return TriggerDirtyBytes
+	}
+	return ""
+}
+
+func (flusher *Flusher) dirtySize() uint64 {
+	flusher.mu.Lock()
+	defer flusher.mu.Unlock()
+
+	size := uint64(0)
+	for _, store := range flusher.storeList {
+		if sizer, ok := store.(DirtySizer); ok {
+			size += sizer.DirtySize()
+		}
+	}
+	return size
+}
+
+func (flusher *Flusher) flush(trigger string, onPrepared func()) error {
 	flusher.flushingMu.Lock()
 	defer flusher.flushingMu.Unlock()
 
+	return flusher.flushLocked(trigger, onPrepared)
+}
+
+// flushLocked flushes with the flushingMu locked
+func (flusher *Flusher) flushLocked(trigger string, onPrepared func()) (flushErr error) {
+	startTime := time.Now()
+	defer func() {
+		flusher.recordFlush(trigger, startTime, flushErr)
+	}()
+
+	syncAll := trigger == TriggerCheckpoint
+	fsync := flusher.Policy().Fsync
+	if syncAll && fsync != FsyncAlways {
+		for _, store := range flusher.storeList {
+			flusher.applySyncCommit(store, FsyncAlways)
+		}
+		defer func() {
+			for _, store := range flusher.storeList {
+				flusher.applySyncCommit(store, fsync)
+			}
+		}()
+	}
+
 	// prepare, lock write
 	//flusher.log.Info("start prepare")
-	if err := flusher.prepare(); err != nil {
+	if err := flusher.prepare(onPrepared); err != nil {
 		flusher.log.Warn(fmt.Sprintf("flusher.prepare failed, error is %s", err), "method", "flush")
-		return
+		return err
 	}
 	//flusher.log.Info("prepare finish")
 
 	// write redo log
 	//flusher.log.Info("start write redo log")
 	if err := flusher.writeRedoLog(); err != nil {
-		return
+		return err
 	}
 	//flusher.log.Info("finish writing redo log")
 
@@ -225,10 +381,16 @@ func (flusher *Flusher) flush() {
 	// if sync redo log failed, stop flushing and cancel prepare
 	// lock write when cancel prepare
 
+	// the redo log is always synced whatever the fsync policy, the stores are committed together or recovered
+	// together by the redo log after a crash
 	//flusher.log.Info("sync write redo log")
+	syncStartTime := time.Now()
 	if !flusher.syncRedoLog() {
-		return
+		return errors.New("sync redo log failed")
 	}
+	flusher.statsMu.Lock()
+	flusher.stats.LastRedoSyncDuration = time.Since(syncStartTime)
+	flusher.statsMu.Unlock()
 	//flusher.log.Info("finish sync writing redo log")
 
 	// commit
@@ -253,6 +415,46 @@ func (flusher *Flusher) flush() {
 
 	// clean redo log
 	flusher.cleanRedoLog()
+	return nil
+}
+
+func (flusher *Flusher) recordFlush(trigger string, startTime time.Time, err error) {
+	duration := time.Since(startTime)
+
+	flusher.statsMu.Lock()
+	defer flusher.statsMu.Unlock()
+
+	stats := &flusher.stats
+	if err != nil {
+		stats.Failures++
+		return
+	}
+	stats.Flushes++
+	stats.Triggers[trigger]++
+	stats.LastFlushTime = time.Now()
+	stats.LastDuration = duration
+	stats.TotalDuration += duration
+	if duration > stats.MaxDuration {
+		stats.MaxDuration = duration
+	}
+}
+
+// applySyncCommit sets whether the commits of the store are synced by the fsync policy
+func (flusher *Flusher) applySyncCommit(store Storage, fsync string) {
+	if committer, ok := store.(FileCommitter); ok {
+		committer.SetSyncCommit(fsync != FsyncNone)
+		return
+	}
+	if committer, ok := store.(SyncCommitter); ok {
+		committer.SetSyncCommit(fsync == FsyncAlways)
+	}
+}
+
+func storeName(store Storage) string {
+	if namer, ok := store.(Namer); ok {
+		return namer.Name()
+	}
+	return store.Id().String()
 }
 
 func (flusher *Flusher) commitRedo() error {
@@ -356,7 +558,7 @@ func (flusher *Flusher) cleanRedoLog() error {
 	return err
 }
 
-func (flusher *Flusher) prepare() error {
+func (flusher *Flusher) prepare(onPrepared func()) error {
 	// prepare, lock write
 	flusher.mu.Lock()
 	defer flusher.mu.Unlock()
@@ -369,7 +571,11 @@ func (flusher *Flusher) prepare() error {
 	for _, store := range flusher.storeList {
 		store.Prepare()
 	}
+	atomic.StoreUint64(&flusher.snapshotCount, 0)
 
+	if onPrepared != nil {
+		onPrepared()
+	}
 	return nil
 }
 
@@ -381,7 +587,7 @@ func (flusher *Flusher) writeRedoLog() error {
 
 	redoLogLengthBytes := make([]byte, 4)
 
-	for _, store := range flusher.storeList {
+	for index, store := range flusher.storeList {
 
 		redoLog, err := store.RedoLog()
 		if err != nil {
@@ -392,6 +598,12 @@ func (flusher *Flusher) writeRedoLog() error {
 
 		redoLogLength := uint32(len(redoLog))
 
+		flusher.statsMu.Lock()
+		storeStats := flusher.stats.Stores[index]
+		storeStats.LastRedoSize = uint64(redoLogLength)
+		storeStats.TotalRedoSize += uint64(redoLogLength)
+		flusher.statsMu.Unlock()
+
 		if redoLogLength <= 0 {
 			continue
 		}
@@ -459,10 +671,24 @@ func (flusher *Flusher) syncRedoLog() bool {
 func (flusher *Flusher) commit() error {
 	var commitErr error
 	flusher.commitWg.Add(len(flusher.storeList))
-	for _, store := range flusher.storeList {
+	for index, store := range flusher.storeList {
 		commitStore := store
+		storeStats := flusher.stats.Stores[index]
 		go func() {
 			defer flusher.commitWg.Done()
+
+			startTime := time.Now()
+			defer func() {
+				duration := time.Since(startTime)
+				flusher.statsMu.Lock()
+				storeStats.LastCommitDuration = duration
+				storeStats.TotalCommitDuration += duration
+				if duration > storeStats.MaxCommitDuration {
+					storeStats.MaxCommitDuration = duration
+				}
+				flusher.statsMu.Unlock()
+			}()
+
 			if err := commitStore.Commit(); err != nil {
 				commitErr = err
 				flusher.log.Error(fmt.Sprintf("%s commit failed. Error: %s", commitStore.Id(), err.Error()), "method", "Flush")
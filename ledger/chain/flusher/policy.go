@@ -0,0 +1,111 @@
+package chain_flusher
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// The redo log is synced before committing in every fsync policy, the policy only decides the syncs of the
+	// commits of the stores.
+
+	// FsyncRedo syncs the commits of the block files, the commits of the other stores are not synced
+	FsyncRedo = "redo"
+	// FsyncAlways syncs the commits of all the stores which support synced writing
+	FsyncAlways = "always"
+	// FsyncNone syncs no commit of the stores, the latest flushed data may be lost after a power failure
+	FsyncNone = "none"
+
+	DefaultFlushInterval = 900 * time.Millisecond
+
+	// the interval of checking the triggers of the policy
+	triggerCheckInterval = 100 * time.Millisecond
+)
+
+const (
+	TriggerInterval      = "interval"
+	TriggerDirtyBytes    = "dirtyBytes"
+	TriggerSnapshotCount = "snapshotCount"
+	TriggerManual        = "manual"
+	TriggerCheckpoint    = "checkpoint"
+	TriggerStop          = "stop"
+)
+
+// Policy decides when the flusher flushes and how the flushed data is synced to the disk.
+// A flush is triggered by whichever limit is reached first.
+type Policy struct {
+	// the max interval between two flushes
+	Interval time.Duration `json:"interval"`
+	// the size of the unflushed data of the stores, 0 means no limit
+	DirtyBytes uint64 `json:"dirtyBytes"`
+	// the number of the snapshot blocks inserted since the last flush, 0 means no limit
+	SnapshotCount uint64 `json:"snapshotCount"`
+
+	Fsync string `json:"fsync"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Interval: DefaultFlushInterval,
+		Fsync:    FsyncRedo,
+	}
+}
+
+func (p Policy) Check() error {
+	if p.Interval <= 0 {
+		return fmt.Errorf("flush interval must be positive, got %s", p.Interval)
+	}
+	switch p.Fsync {
+	case FsyncRedo, FsyncAlways, FsyncNone:
+	default:
+		return fmt.Errorf("unknown fsync policy %q, must be one of %q, %q, %q", p.Fsync, FsyncRedo, FsyncAlways, FsyncNone)
+	}
+	return nil
+}
+
+// StoreStats is the flushing metrics of a store
+type StoreStats struct {
+	Name string `json:"name"`
+
+	// the size of the redo log written by the last flush and all flushes
+	LastRedoSize  uint64 `json:"lastRedoSize"`
+	TotalRedoSize uint64 `json:"totalRedoSize"`
+
+	LastCommitDuration  time.Duration `json:"lastCommitDuration"`
+	MaxCommitDuration   time.Duration `json:"maxCommitDuration"`
+	TotalCommitDuration time.Duration `json:"totalCommitDuration"`
+}
+
+// Stats is the metrics of the flusher, the durations are in nanoseconds when encoded to json
+type Stats struct {
+	Policy Policy `json:"policy"`
+
+	Flushes  uint64 `json:"flushes"`
+	Failures uint64 `json:"failures"`
+	// the number of the flushes by each trigger
+	Triggers map[string]uint64 `json:"triggers"`
+
+	LastFlushTime time.Time     `json:"lastFlushTime"`
+	LastDuration  time.Duration `json:"lastDuration"`
+	MaxDuration   time.Duration `json:"maxDuration"`
+	TotalDuration time.Duration `json:"totalDuration"`
+
+	// the duration of syncing the redo log of the last flush
+	LastRedoSyncDuration time.Duration `json:"lastRedoSyncDuration"`
+
+	Stores []*StoreStats `json:"stores"`
+}
+
+func (stats *Stats) copy() *Stats {
+	cp := *stats
+	cp.Triggers = make(map[string]uint64, len(stats.Triggers))
+	for trigger, count := range stats.Triggers {
+		cp.Triggers[trigger] = count
+	}
+	cp.Stores = make([]*StoreStats, 0, len(stats.Stores))
+	for _, storeStats := range stats.Stores {
+		s := *storeStats
+		cp.Stores = append(cp.Stores, &s)
+	}
+	return &cp
+}
@@ -2,6 +2,7 @@ package chain_genesis
 
 import (
 	"encoding/hex"
+	"fmt"
 	"math/big"
 	"sort"
 
@@ -21,6 +22,7 @@ func NewGenesisAccountBlocks(cfg *config.Genesis) []*interfaces.VmAccountBlock {
 	list, addrSet = newGenesisGovernanceContractBlocks(cfg, list, addrSet)
 	list, addrSet = newGenesisAssetContractBlocks(cfg, list, addrSet)
 	list, addrSet = newGenesisQuotaContractBlocks(cfg, list, addrSet)
+	list, addrSet = newGenesisUserContractBlocks(cfg, list, addrSet)
 	list = newGenesisNormalAccountBlocks(cfg, list, addrSet)
 	return list
 }
@@ -257,6 +259,46 @@ func newGenesisQuotaContractBlocks(cfg *config.Genesis, list []*interfaces.VmAcc
 	return list, addrSet
 }
 
+func newGenesisUserContractBlocks(cfg *config.Genesis, list []*interfaces.VmAccountBlock, addrSet map[types.Address]interface{}) ([]*interfaces.VmAccountBlock, map[types.Address]interface{}) {
+	for addrStr, contractInfo := range cfg.ContractInfoMap {
+		contractAddr, err := types.HexToAddress(addrStr)
+		dealWithError(err)
+		if !types.IsContractAddr(contractAddr) || types.IsBuiltinContractAddr(contractAddr) {
+			panic(fmt.Sprintf("%s is not a user contract address", addrStr))
+		}
+		block := ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeGenesisReceive,
+			Height:         1,
+			AccountAddress: contractAddr,
+			Amount:         big.NewInt(0),
+			Fee:            big.NewInt(0),
+		}
+		vmdb := vm_db.NewGenesisVmDB(&contractAddr)
+
+		code, err := hex.DecodeString(contractInfo.Code)
+		dealWithError(err)
+		vmdb.SetContractCode(util.PackContractCode(util.SolidityPPContractType, code))
+		vmdb.SetContractMeta(contractAddr, &ledger.ContractMeta{
+			Gid:                contractInfo.Gid,
+			SendConfirmedTimes: contractInfo.SendConfirmedTimes,
+			SeedConfirmedTimes: contractInfo.SeedConfirmedTimes,
+			QuotaRatio:         contractInfo.QuotaRatio,
+		})
+		for keyStr, valueStr := range contractInfo.Storage {
+			key, err := hex.DecodeString(keyStr)
+			dealWithError(err)
+			value, err := hex.DecodeString(valueStr)
+			dealWithError(err)
+			util.SetValue(vmdb, key, value)
+		}
+		updateAccountBalanceMap(cfg, contractAddr, vmdb)
+		block.Hash = block.ComputeHash()
+		list = append(list, &interfaces.VmAccountBlock{AccountBlock: &block, VmDb: vmdb})
+		addrSet[contractAddr] = struct{}{}
+	}
+	return list, addrSet
+}
+
 func newGenesisNormalAccountBlocks(cfg *config.Genesis, list []*interfaces.VmAccountBlock, addrSet map[types.Address]interface{}) []*interfaces.VmAccountBlock {
 	for addrStr, balanceMap := range cfg.AccountBalanceMap {
 		addr, err := types.HexToAddress(addrStr)
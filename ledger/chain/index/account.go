@@ -58,7 +58,16 @@ func (iDB *IndexDB) GetAccountAddress(accountId uint64) (*types.Address, error)
 }
 
 func (iDB *IndexDB) IterateAccounts(iterateFunc func(addr types.Address, accountId uint64, err error) bool) {
-	iter := iDB.store.NewIterator(util.BytesPrefix([]byte{chain_utils.AccountIdKeyPrefix}))
+	iDB.IterateAccountsFrom(0, iterateFunc)
+}
+
+// IterateAccountsFrom iterates the accounts ordered by the account id, from the account after startId
+func (iDB *IndexDB) IterateAccountsFrom(startId uint64, iterateFunc func(addr types.Address, accountId uint64, err error) bool) {
+	if startId >= helper.MaxUint64 {
+		return
+	}
+	startKey := chain_utils.CreateAccountIdKey(startId + 1)
+	iter := iDB.store.NewIterator(&util.Range{Start: startKey.Bytes(), Limit: util.BytesPrefix([]byte{chain_utils.AccountIdKeyPrefix}).Limit})
 	defer iter.Release()
 
 	for iter.Next() {
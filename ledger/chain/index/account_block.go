@@ -86,46 +86,83 @@ func (iDB *IndexDB) GetAccountBlockLocationListByRange(addr types.Address, start
 		return nil, [2]uint64{}, nil
 	}
 
+	// the compacted heights are read from the checkpoints instead of iterating the height keys
+	checkpoints, err := iDB.GetAccountCheckpoints(addr, start, end)
+	if err != nil {
+		return nil, [2]uint64{}, err
+	}
+
 	startHeight := start
+	if len(checkpoints) > 0 {
+		startHeight = checkpoints[len(checkpoints)-1].EndHeight() + 1
+	}
 
 	endHeight := end
 
-	startKey := chain_utils.CreateAccountBlockHeightKey(&addr, startHeight)
-	endKey := chain_utils.CreateAccountBlockHeightKey(&addr, endHeight+1)
+	locationList := make([]*chain_file_manager.Location, 0, end+1-start)
 
-	iter := iDB.store.NewIterator(&util.Range{Start: startKey.Bytes(), Limit: endKey.Bytes()})
-	defer iter.Release()
+	minHeight := end
+	maxHeight := start
 
-	locationList := make([]*chain_file_manager.Location, 0, endHeight+1-startHeight)
+	if startHeight <= endHeight {
+		startKey := chain_utils.CreateAccountBlockHeightKey(&addr, startHeight)
+		endKey := chain_utils.CreateAccountBlockHeightKey(&addr, endHeight+1)
 
-	minHeight := endHeight
-	maxHeight := startHeight
+		iter := iDB.store.NewIterator(&util.Range{Start: startKey.Bytes(), Limit: endKey.Bytes()})
+		defer iter.Release()
 
-	iterOk := iter.Last()
-	for iterOk {
-		height := chain_utils.BytesToUint64(iter.Key()[1+types.AddressSize:])
+		iterOk := iter.Last()
+		for iterOk {
+			height := chain_utils.BytesToUint64(iter.Key()[1+types.AddressSize:])
 
-		if height < minHeight {
-			minHeight = height
-		}
+			if height < minHeight {
+				minHeight = height
+			}
 
-		if height > maxHeight {
-			maxHeight = height
-		}
+			if height > maxHeight {
+				maxHeight = height
+			}
 
-		value := iter.Value()
+			value := iter.Value()
+
+			if len(value) > types.HashSize {
+				locationList = append(locationList, chain_utils.DeserializeLocation(value[types.HashSize:]))
+			} else {
+				locationList = append(locationList, nil)
+			}
 
-		if len(value) > types.HashSize {
-			locationList = append(locationList, chain_utils.DeserializeLocation(value[types.HashSize:]))
-		} else {
-			locationList = append(locationList, nil)
+			iterOk = iter.Prev()
 		}
 
-		iterOk = iter.Prev()
+		if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+			return nil, [2]uint64{}, err
+		}
 	}
 
-	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
-		return nil, [2]uint64{}, err
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		cp := checkpoints[i]
+
+		high := cp.EndHeight()
+		if high > end {
+			high = end
+		}
+		low := cp.StartHeight
+		if low < start {
+			low = start
+		}
+		if high < low {
+			continue
+		}
+
+		if low < minHeight {
+			minHeight = low
+		}
+		if high > maxHeight {
+			maxHeight = high
+		}
+		for height := high; height >= low; height-- {
+			locationList = append(locationList, cp.Locations[height-cp.StartHeight])
+		}
 	}
 
 	return locationList, [2]uint64{minHeight, maxHeight}, nil
@@ -0,0 +1,165 @@
+package chain_index
+
+import (
+	"fmt"
+
+	leveldb "github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+// AccountCheckpointInterval is the number of the account blocks indexed by a checkpoint. The checkpoint of the
+// heights [n*AccountCheckpointInterval+1, (n+1)*AccountCheckpointInterval] is keyed by the start height.
+const AccountCheckpointInterval = uint64(1000)
+
+const (
+	locationSize = 12
+
+	checkpointFlagCold = byte(1)
+)
+
+// AccountCheckpoint is the compacted index of the confirmed account blocks in a checkpoint range.
+// The checkpoints of an account are always continuous from height 1, they are written by the compactor in order
+// and deleted with the blocks when rolling back.
+type AccountCheckpoint struct {
+	StartHeight uint64
+	// the locations of the blocks in the block files, the i-th is the location of the block at StartHeight+i
+	Locations []*chain_file_manager.Location
+	// the location of the segment in the cold tier which keeps the blocks of the range, nil if not in the cold tier
+	Segment *chain_file_manager.Location
+}
+
+// EndHeight returns the height of the last block in the checkpoint
+func (cp *AccountCheckpoint) EndHeight() uint64 {
+	return cp.StartHeight + uint64(len(cp.Locations)) - 1
+}
+
+// CheckpointStartHeight returns the start height of the checkpoint range containing the height
+func CheckpointStartHeight(height uint64) uint64 {
+	if height <= 0 {
+		return 1
+	}
+	return (height-1)/AccountCheckpointInterval*AccountCheckpointInterval + 1
+}
+
+func (cp *AccountCheckpoint) Serialize() []byte {
+	size := 1 + len(cp.Locations)*locationSize
+	if cp.Segment != nil {
+		size += locationSize
+	}
+
+	buf := make([]byte, 0, size)
+	if cp.Segment != nil {
+		buf = append(buf, checkpointFlagCold)
+		buf = append(buf, chain_utils.SerializeLocation(cp.Segment)...)
+	} else {
+		buf = append(buf, 0)
+	}
+	for _, location := range cp.Locations {
+		buf = append(buf, chain_utils.SerializeLocation(location)...)
+	}
+	return buf
+}
+
+func (cp *AccountCheckpoint) Deserialize(buf []byte) error {
+	if len(buf) < 1 {
+		return fmt.Errorf("the checkpoint is empty")
+	}
+	flag := buf[0]
+	buf = buf[1:]
+	if flag == checkpointFlagCold {
+		if len(buf) < locationSize {
+			return fmt.Errorf("the segment location of the checkpoint is missing")
+		}
+		cp.Segment = chain_utils.DeserializeLocation(buf[:locationSize])
+		buf = buf[locationSize:]
+	}
+	if len(buf) <= 0 || len(buf)%locationSize != 0 {
+		return fmt.Errorf("invalid size %d of the checkpoint locations", len(buf))
+	}
+
+	cp.Locations = make([]*chain_file_manager.Location, 0, len(buf)/locationSize)
+	for i := 0; i < len(buf); i += locationSize {
+		cp.Locations = append(cp.Locations, chain_utils.DeserializeLocation(buf[i:i+locationSize]))
+	}
+	return nil
+}
+
+// InsertAccountCheckpoint writes the checkpoint of the account. The caller must make sure the blocks in the
+// checkpoint are confirmed and no block is inserted or deleted while writing.
+func (iDB *IndexDB) InsertAccountCheckpoint(addr types.Address, cp *AccountCheckpoint) error {
+	if cp.StartHeight != CheckpointStartHeight(cp.StartHeight) || uint64(len(cp.Locations)) != AccountCheckpointInterval {
+		return fmt.Errorf("invalid checkpoint of %s, start height is %d, size is %d", addr, cp.StartHeight, len(cp.Locations))
+	}
+
+	batch := iDB.store.NewBatch()
+	batch.Put(chain_utils.CreateAccountCheckpointKey(&addr, cp.StartHeight).Bytes(), cp.Serialize())
+	iDB.store.WriteDirectly(batch)
+	return nil
+}
+
+// GetAccountCheckpoints returns the continuous checkpoints of the account from the checkpoint containing the start
+// height, up to the checkpoint containing the end height
+func (iDB *IndexDB) GetAccountCheckpoints(addr types.Address, start, end uint64) ([]*AccountCheckpoint, error) {
+	if end < start {
+		return nil, nil
+	}
+	startHeight := CheckpointStartHeight(start)
+
+	startKey := chain_utils.CreateAccountCheckpointKey(&addr, startHeight)
+	endKey := chain_utils.CreateAccountCheckpointKey(&addr, CheckpointStartHeight(end)+1)
+
+	iter := iDB.store.NewIterator(&util.Range{Start: startKey.Bytes(), Limit: endKey.Bytes()})
+	defer iter.Release()
+
+	var checkpoints []*AccountCheckpoint
+	nextHeight := startHeight
+	for iter.Next() {
+		height := chain_utils.BytesToUint64(iter.Key()[1+types.AddressSize:])
+		if height != nextHeight {
+			break
+		}
+
+		cp := &AccountCheckpoint{StartHeight: height}
+		if err := cp.Deserialize(iter.Value()); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint of %s at height %d. Error: %s", addr, height, err)
+		}
+		checkpoints = append(checkpoints, cp)
+		nextHeight = cp.EndHeight() + 1
+	}
+
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+// GetCompactedHeight returns the end height of the latest checkpoint of the account, returns 0 if the account is not compacted
+func (iDB *IndexDB) GetCompactedHeight(addr types.Address) (uint64, error) {
+	startKey := chain_utils.CreateAccountCheckpointKey(&addr, 1)
+	endKey := chain_utils.CreateAccountCheckpointKey(&addr, helper.MaxUint64)
+
+	iter := iDB.store.NewIterator(&util.Range{Start: startKey.Bytes(), Limit: endKey.Bytes()})
+	defer iter.Release()
+
+	var height uint64
+	if iter.Last() {
+		cp := &AccountCheckpoint{StartHeight: chain_utils.BytesToUint64(iter.Key()[1+types.AddressSize:])}
+		if err := cp.Deserialize(iter.Value()); err != nil {
+			return 0, err
+		}
+		height = cp.EndHeight()
+	}
+
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return 0, err
+	}
+	return height, nil
+}
+
+func (iDB *IndexDB) deleteAccountCheckpoint(batch *leveldb.Batch, addr types.Address, height uint64) {
+	batch.Delete(chain_utils.CreateAccountCheckpointKey(&addr, CheckpointStartHeight(height)).Bytes())
+}
@@ -0,0 +1,128 @@
+package chain_index
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+)
+
+func TestCheckpointStartHeight(t *testing.T) {
+	assert.Equal(t, uint64(1), CheckpointStartHeight(0))
+	assert.Equal(t, uint64(1), CheckpointStartHeight(1))
+	assert.Equal(t, uint64(1), CheckpointStartHeight(AccountCheckpointInterval))
+	assert.Equal(t, AccountCheckpointInterval+1, CheckpointStartHeight(AccountCheckpointInterval+1))
+	assert.Equal(t, AccountCheckpointInterval+1, CheckpointStartHeight(2*AccountCheckpointInterval))
+}
+
+func TestAccountCheckpoint_Serialize(t *testing.T) {
+	cp := &AccountCheckpoint{
+		StartHeight: 1,
+		Locations:   []*chain_file_manager.Location{chain_file_manager.NewLocation(1, 10), chain_file_manager.NewLocation(2, 20)},
+	}
+	for _, segment := range []*chain_file_manager.Location{nil, chain_file_manager.NewLocation(3, 30)} {
+		cp.Segment = segment
+
+		cp2 := &AccountCheckpoint{StartHeight: 1}
+		assert.NoError(t, cp2.Deserialize(cp.Serialize()))
+		assert.Equal(t, cp, cp2)
+		assert.Equal(t, uint64(2), cp2.EndHeight())
+	}
+	assert.Error(t, (&AccountCheckpoint{}).Deserialize([]byte{0, 1, 2}))
+}
+
+func TestIndexDB_AccountCheckpoint(t *testing.T) {
+	chainDir, err := ioutil.TempDir("", "checkpoint_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(chainDir)
+
+	iDB, err := NewIndexDB(chainDir, "")
+	assert.NoError(t, err)
+	defer iDB.Close()
+
+	addr := types.AddressGovernance
+	latestHeight := 2*AccountCheckpointInterval + 500
+
+	var blocks []*ledger.AccountBlock
+	locations := make(map[types.Hash]*chain_file_manager.Location)
+	var locationList []*chain_file_manager.Location
+	for height := uint64(1); height <= latestHeight; height++ {
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			Height:         height,
+			AccountAddress: addr,
+			ToAddress:      types.AddressQuota,
+			Amount:         big.NewInt(int64(height)),
+			Fee:            big.NewInt(0),
+		}
+		block.Hash = block.ComputeHash()
+		assert.NoError(t, iDB.InsertAccountBlock(block))
+
+		blocks = append(blocks, block)
+		locations[block.Hash] = chain_file_manager.NewLocation(height/100+1, int64(height%100)*1000)
+		locationList = append(locationList, locations[block.Hash])
+	}
+	iDB.InsertSnapshotBlock(&ledger.SnapshotBlock{Height: 2}, blocks, chain_file_manager.NewLocation(100, 0), locations)
+
+	// the locations from the height keys
+	type result struct {
+		locations   []*chain_file_manager.Location
+		heightRange [2]uint64
+	}
+	ranges := [][2]uint64{{1, 10}, {995, 1005}, {1, latestHeight}, {1500, 2200}, {2001, latestHeight + 100}}
+	var expected []result
+	for _, r := range ranges {
+		locations, heightRange, err := iDB.GetAccountBlockLocationListByRange(addr, r[0], r[1])
+		assert.NoError(t, err)
+		expected = append(expected, result{locations, heightRange})
+	}
+
+	// compact the first two ranges
+	height, err := iDB.GetCompactedHeight(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), height)
+	for start := uint64(1); start+AccountCheckpointInterval-1 <= 2*AccountCheckpointInterval; start += AccountCheckpointInterval {
+		cp := &AccountCheckpoint{
+			StartHeight: start,
+			Locations:   locationList[start-1 : start-1+AccountCheckpointInterval],
+		}
+		assert.NoError(t, iDB.InsertAccountCheckpoint(addr, cp))
+	}
+	assert.Error(t, iDB.InsertAccountCheckpoint(addr, &AccountCheckpoint{StartHeight: 2, Locations: locationList[1:1001]}))
+
+	height, err = iDB.GetCompactedHeight(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*AccountCheckpointInterval, height)
+
+	checkpoints, err := iDB.GetAccountCheckpoints(addr, 1500, 2200)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(checkpoints))
+	assert.Equal(t, AccountCheckpointInterval+1, checkpoints[0].StartHeight)
+
+	// the same results with the checkpoints
+	for i, r := range ranges {
+		locations, heightRange, err := iDB.GetAccountBlockLocationListByRange(addr, r[0], r[1])
+		assert.NoError(t, err)
+		assert.Equal(t, expected[i].heightRange, heightRange, "range %v", r)
+		assert.Equal(t, expected[i].locations, locations, "range %v", r)
+	}
+
+	// the checkpoints of the rolled back blocks are deleted
+	assert.NoError(t, iDB.RollbackSnapshotBlocks([]*ledger.SnapshotChunk{{AccountBlocks: blocks[1499:]}}, nil))
+	height, err = iDB.GetCompactedHeight(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, AccountCheckpointInterval, height)
+
+	list, heightRange, err := iDB.GetAccountBlockLocationListByRange(addr, 990, 2000)
+	assert.NoError(t, err)
+	assert.Equal(t, [2]uint64{990, 1499}, heightRange)
+	assert.Equal(t, 1499-990+1, len(list))
+	assert.Equal(t, locationList[1498], list[0])
+	assert.Equal(t, locationList[989], list[len(list)-1])
+}
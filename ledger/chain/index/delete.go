@@ -87,6 +87,9 @@ func (iDB *IndexDB) deleteAccountBlocks(batch *leveldb.Batch, blocks []*ledger.A
 		// delete account block height index
 		iDB.deleteAccountBlockHeight(batch, block.AccountAddress, block.Height)
 
+		// delete the checkpoint containing the block
+		iDB.deleteAccountCheckpoint(batch, block.AccountAddress, block.Height)
+
 		if block.IsReceiveBlock() {
 			// if in send
 			if _, ok := sendBlockHashMap[block.FromBlockHash]; ok {
@@ -28,9 +28,9 @@ type IndexDB struct {
 	log log15.Logger
 }
 
-func NewIndexDB(chainDir string) (*IndexDB, error) {
+func NewIndexDB(chainDir string, backend string) (*IndexDB, error) {
 
-	store, err := chain_db.NewStore(path.Join(chainDir, "index"), "indexDb")
+	store, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "index"), "indexDb", backend)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +122,7 @@ func (iDB *IndexDB) GetStatus() []interfaces.DBStatus {
 		Size:   uint64(statusList[0].Size),
 		Status: statusList[0].Status,
 	}, {
-		Name:   "indexDB.store.levelDB",
+		Name:   "indexDB.store." + statusList[1].Name,
 		Count:  uint64(statusList[1].Count),
 		Size:   uint64(statusList[1].Size),
 		Status: statusList[1].Status,
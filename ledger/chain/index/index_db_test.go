@@ -11,7 +11,7 @@ import (
 
 func TestDumpFileLocation(t *testing.T) {
 	chainDir := path.Join(common.HomeDir(), ".gvite/mockdata/ledger")
-	db, err := NewIndexDB(chainDir)
+	db, err := NewIndexDB(chainDir, "")
 	assert.NoError(t, err)
 	step := uint64(75 * 10)
 	from := types.GenesisHeight
@@ -28,7 +28,7 @@ func TestDumpFileLocation(t *testing.T) {
 
 func TestIndexDB_GetLatestAccountBlock(t *testing.T) {
 	chainDir := path.Join(common.HomeDir(), ".gvite/mockdata/ledger")
-	db, err := NewIndexDB(chainDir)
+	db, err := NewIndexDB(chainDir, "")
 	assert.NoError(t, err)
 	address, err := types.HexToAddress("vite_7c8c9e1e878e8a6ddf59c66a83791a5755a8fcf606c4bd31ea")
 	assert.NoError(t, err)
@@ -116,6 +116,32 @@ func (iDB *IndexDB) GetOnRoadHashList(addr types.Address, pageNum, pageSize int)
 	return hashList, nil
 }
 
+// GetOnRoadHashListAfter returns at most count onroad hashes of the address in order, the hashes start after the
+// hash, or from the first one if after is nil
+func (iDB *IndexDB) GetOnRoadHashListAfter(addr types.Address, after *types.Hash, count int) ([]types.Hash, error) {
+	iterRange := util.BytesPrefix(append([]byte{chain_utils.OnRoadKeyPrefix}, addr.Bytes()...))
+	if after != nil {
+		iterRange.Start = append(chain_utils.CreateOnRoadKey(addr, *after).Bytes(), 0)
+	}
+	iter := iDB.store.NewIterator(iterRange)
+	defer iter.Release()
+
+	hashList := make([]types.Hash, 0, count)
+	for len(hashList) < count && iter.Next() {
+		key := iter.Key()
+		blockHash, err := types.BytesToHash(key[len(key)-types.HashSize:])
+		if err != nil {
+			return nil, err
+		}
+		hashList = append(hashList, blockHash)
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return hashList, nil
+}
+
 func (iDB *IndexDB) insertOnRoad(batch interfaces.Batch, toAddr types.Address, blockHash types.Hash) {
 	batch.Put(chain_utils.CreateOnRoadKey(toAddr, blockHash).Bytes(), []byte{})
 
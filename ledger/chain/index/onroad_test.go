@@ -0,0 +1,61 @@
+package chain_index
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+func TestIndexDB_GetOnRoadHashListAfter(t *testing.T) {
+	chainDir, err := ioutil.TempDir("", "onroad_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(chainDir)
+
+	iDB, err := NewIndexDB(chainDir, "")
+	assert.NoError(t, err)
+	defer iDB.Close()
+
+	toAddr := types.AddressQuota
+	var hashList []types.Hash
+	for height := uint64(1); height <= 10; height++ {
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			Height:         height,
+			AccountAddress: types.AddressGovernance,
+			ToAddress:      toAddr,
+			Amount:         big.NewInt(int64(height)),
+			Fee:            big.NewInt(0),
+		}
+		block.Hash = block.ComputeHash()
+		assert.NoError(t, iDB.InsertAccountBlock(block))
+		hashList = append(hashList, block.Hash)
+	}
+	sort.Slice(hashList, func(i, j int) bool {
+		return bytes.Compare(hashList[i].Bytes(), hashList[j].Bytes()) < 0
+	})
+
+	page, err := iDB.GetOnRoadHashListAfter(toAddr, nil, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, hashList[:4], page)
+
+	// continue after the last hash of the page
+	page, err = iDB.GetOnRoadHashListAfter(toAddr, &page[len(page)-1], 4)
+	assert.NoError(t, err)
+	assert.Equal(t, hashList[4:8], page)
+
+	page, err = iDB.GetOnRoadHashListAfter(toAddr, &page[len(page)-1], 4)
+	assert.NoError(t, err)
+	assert.Equal(t, hashList[8:], page)
+
+	page, err = iDB.GetOnRoadHashListAfter(toAddr, &hashList[len(hashList)-1], 4)
+	assert.NoError(t, err)
+	assert.Empty(t, page)
+}
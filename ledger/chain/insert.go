@@ -8,9 +8,15 @@ import (
 	"github.com/vitelabs/go-vite/common"
 	"github.com/vitelabs/go-vite/interfaces"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 )
 
-func (c *chain) InsertAccountBlock(vmAccountBlock *interfaces.VmAccountBlock) error {
+func (c *chain) InsertAccountBlock(vmAccountBlock *interfaces.VmAccountBlock) (err error) {
+	span := blocktrace.Start(vmAccountBlock.AccountBlock.Hash, blocktrace.StageChainInsert)
+	defer func() {
+		span.Finish(err)
+	}()
+
 	c.flushMu.RLock()
 	defer c.flushMu.RUnlock()
 
@@ -49,6 +55,7 @@ func (c *chain) InsertSnapshotBlock(snapshotBlock *ledger.SnapshotBlock) ([]*led
 	if err := c.insertSnapshotBlock(snapshotBlock); err != nil {
 		return nil, err
 	}
+	c.checkProducerVersion(snapshotBlock)
 
 	// delete invalidBlocks
 	invalidBlocks := c.filterUnconfirmedBlocks(snapshotBlock, true)
@@ -144,6 +151,7 @@ func (c *chain) insertSnapshotBlock(snapshotBlock *ledger.SnapshotBlock) error {
 	}()
 
 	wg.Wait()
+	c.flusher.MarkSnapshot()
 
 	c.em.TriggerInsertSbs(InsertSbsEvent, chunks)
 	return nil
@@ -214,6 +214,15 @@ type Chain interface {
 	// get confirmed snapshot Balance, if history is too old, failed
 	GetConfirmedBalanceList(addrList []types.Address, tokenId types.TokenTypeId, sbHash types.Hash) (map[types.Address]*big.Int, error)
 
+	// get Balance at the snapshot height
+	GetBalanceBySnapshotHeight(addr types.Address, tokenId types.TokenTypeId, snapshotHeight uint64) (*big.Int, error)
+
+	// get holders of the token at the snapshot height, ordered by address and starting after startAddr
+	GetTokenHolders(tokenId types.TokenTypeId, snapshotHeight uint64, startAddr *types.Address, count int) ([]*chain_state.TokenHolder, error)
+
+	// get the iterator of all accounts, balances, storage and contract metas at the snapshot height
+	NewStateIterator(snapshotHeight uint64) (*StateIterator, error)
+
 	// get contract code
 	GetContractCode(contractAddr types.Address) ([]byte, error)
 
@@ -281,6 +290,10 @@ type Chain interface {
 
 	GetOnRoadBlocksByAddr(addr types.Address, pageNum, pageSize int) ([]*ledger.AccountBlock, error)
 
+	// GetOnRoadBlocksAfterHash returns at most count onroad blocks of the address in the order of the hashes, the
+	// blocks start after the hash, or from the first one if after is nil
+	GetOnRoadBlocksAfterHash(addr types.Address, after *types.Hash, count int) ([]*ledger.AccountBlock, error)
+
 	LoadAllOnRoad() (map[types.Address][]types.Hash, error)
 
 	GetAccountOnRoadInfo(addr types.Address) (*ledger.AccountInfo, error)
@@ -304,6 +317,15 @@ type Chain interface {
 
 	Flusher() *chain_flusher.Flusher
 
+	// the metrics of the flusher
+	GetFlushStats() *chain_flusher.Stats
+
+	// flush and sync all the ledger data to the disk, returns the latest snapshot block of the flushed data
+	FlushCheckpoint() (*ledger.SnapshotBlock, error)
+
+	// copy the flushed stores and block files to the directory with the writing paused
+	Backup(dir string, hardLink bool) (*BackupInfo, error)
+
 	StopWrite()
 
 	RecoverWrite()
@@ -312,6 +334,13 @@ type Chain interface {
 
 	QueryGenesisCheckSum() (*types.Hash, error)
 
+	// ====== Prune ======
+	// the lowest snapshot height of the data which is kept, data is one of PruneDataVmLog, PruneDataStateHistory and PruneDataBlockBody
+	GetPrunedHeight(data string) uint64
+
+	// returns a *PrunedError if the data of the confirmed account block has been pruned
+	CheckAccountBlockPruned(data string, blockHash types.Hash) error
+
 	// ====== Check ======
 	CheckRedo() error
 
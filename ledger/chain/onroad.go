@@ -37,6 +37,22 @@ func (c *chain) GetOnRoadBlocksByAddr(addr types.Address, pageNum, pageSize int)
 		return nil, cErr
 	}
 
+	return c.getOnRoadBlocks(addr, hashList)
+}
+
+func (c *chain) GetOnRoadBlocksAfterHash(addr types.Address, after *types.Hash, count int) ([]*ledger.AccountBlock, error) {
+	hashList, err := c.indexDB.GetOnRoadHashListAfter(addr, after, count)
+	if err != nil {
+		cErr := fmt.Errorf("c.GetOnRoadBlocksAfterHash failed, error is %s, address is %s, after is %v, count is %d",
+			err, addr, after, count)
+		c.log.Error(cErr.Error(), "method", "GetOnRoadBlocksAfterHash")
+		return nil, cErr
+	}
+	return c.getOnRoadBlocks(addr, hashList)
+}
+
+// getOnRoadBlocks returns the onroad blocks of the hashes, the onroad of a missing block is deleted
+func (c *chain) getOnRoadBlocks(addr types.Address, hashList []types.Hash) ([]*ledger.AccountBlock, error) {
 	blockList := make([]*ledger.AccountBlock, len(hashList))
 	count := 0
 
@@ -47,7 +63,7 @@ func (c *chain) GetOnRoadBlocksByAddr(addr types.Address, pageNum, pageSize int)
 		}
 		if b == nil {
 			c.DeleteOnRoad(addr, v)
-			c.log.Error(fmt.Sprintf("block is not exit, hash %s. fix onroad, hash %s is deleted", v, v), "method", "GetOnRoadBlocksByAddr")
+			c.log.Error(fmt.Sprintf("block is not exit, hash %s. fix onroad, hash %s is deleted", v, v), "method", "getOnRoadBlocks")
 			continue
 		}
 		blockList[count] = b
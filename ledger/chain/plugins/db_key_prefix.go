@@ -6,6 +6,10 @@ const (
 	OnRoadInfoKeyPrefix = byte(1)
 
 	DiffTokenHash = byte(2)
+
+	QuotaUsageKeyPrefix = byte(3)
+
+	ReceiveQuotaKeyPrefix = byte(4)
 )
 
 func CreateOnRoadInfoKey(addr *types.Address, tId *types.TokenTypeId) []byte {
@@ -428,7 +428,7 @@ func (or *OnRoadInfo) flushWriteBySnapshotLine(batch *leveldb.Batch, confirmedBl
 			key := CreateOnRoadInfoKey(&addr, &tkId)
 			om, err := or.getMeta(key)
 			if err != nil {
-				conflictErr += fmt.Sprintf("%v getMeta addr=%v tkId=%v len=%v", err, addr, len(pendingList)) + " | "
+				conflictErr += fmt.Sprintf("%v getMeta addr=%v tkId=%v len=%v", err, addr, tkId, len(pendingList)) + " | "
 				continue
 			}
 			if om == nil {
@@ -452,7 +452,7 @@ func (or *OnRoadInfo) flushWriteBySnapshotLine(batch *leveldb.Batch, confirmedBl
 			om.TotalAmount = *diffAmount
 			om.Number = diffNum.Uint64()
 			if err := or.writeMeta(batch, key, om); err != nil {
-				conflictErr += fmt.Sprintf("%v writeMeta addr=%v tkId=%v len=%v", err, addr, len(pendingList)) + " | "
+				conflictErr += fmt.Sprintf("%v writeMeta addr=%v tkId=%v len=%v", err, addr, tkId, len(pendingList)) + " | "
 				continue
 			}
 		}
@@ -475,7 +475,7 @@ func (or *OnRoadInfo) flushDeleteBySnapshotLine(batch *leveldb.Batch, confirmedB
 			key := CreateOnRoadInfoKey(&addr, &tkId)
 			om, err := or.getMeta(key)
 			if err != nil {
-				conflictErr += fmt.Sprintf("%v getMeta addr=%v tkId=%v len=%v", err, addr, len(pendingList)) + " | "
+				conflictErr += fmt.Sprintf("%v getMeta addr=%v tkId=%v len=%v", err, addr, tkId, len(pendingList)) + " | "
 				continue
 			}
 			if om == nil {
@@ -498,7 +498,7 @@ func (or *OnRoadInfo) flushDeleteBySnapshotLine(batch *leveldb.Batch, confirmedB
 			om.TotalAmount = *diffAmount
 			om.Number = diffNum.Uint64()
 			if err := or.writeMeta(batch, key, om); err != nil {
-				conflictErr += fmt.Sprintf("%v writeMeta addr=%v tkId=%v len=%v", err, addr, len(pendingList)) + " | "
+				conflictErr += fmt.Sprintf("%v writeMeta addr=%v tkId=%v len=%v", err, addr, tkId, len(pendingList)) + " | "
 				continue
 			}
 		}
@@ -23,6 +23,7 @@ const (
 
 type Plugins struct {
 	dataDir string
+	backend string
 
 	log     log15.Logger
 	chain   Chain
@@ -33,12 +34,12 @@ type Plugins struct {
 	mu          sync.RWMutex
 }
 
-func NewPlugins(chainDir string, chain Chain) (*Plugins, error) {
+func NewPlugins(chainDir string, chain Chain, backend string) (*Plugins, error) {
 	var err error
 
 	dataDir := path.Join(chainDir, "plugins")
 
-	store, err := chain_db.NewStore(dataDir, "plugins")
+	store, err := chain_db.NewStoreWithBackend(dataDir, "plugins", backend)
 	if err != nil {
 		return nil, err
 	}
@@ -46,10 +47,12 @@ func NewPlugins(chainDir string, chain Chain) (*Plugins, error) {
 	plugins := map[string]Plugin{
 		"filterToken": newFilterToken(store, chain),
 		"onRoadInfo":  newOnRoadInfo(store, chain),
+		"quotaUsage":  newQuotaUsage(store, chain),
 	}
 
 	return &Plugins{
 		dataDir:     dataDir,
+		backend:     backend,
 		chain:       chain,
 		store:       store,
 		plugins:     plugins,
@@ -88,7 +91,7 @@ func (p *Plugins) RebuildData() error {
 	os.RemoveAll(p.dataDir)
 
 	// set new store
-	store, err := chain_db.NewStore(p.dataDir, "plugins")
+	store, err := chain_db.NewStoreWithBackend(p.dataDir, "plugins", p.backend)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,295 @@
+package chain_plugins
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/chain/db"
+	"github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+const (
+	quotaUsageValueSize   = 6 * 8
+	receiveQuotaValueSize = methodSelectorSize + 2*8
+	methodSelectorSize    = 4
+
+	// the sizes of the values written before the pow difficulty is recorded
+	legacyQuotaUsageValueSize   = 5 * 8
+	legacyReceiveQuotaValueSize = methodSelectorSize + 8
+
+	// MaxReceiveQuotaScan is the most receive quota samples visited by one GetReceiveQuotaSamples call, a filtered
+	// query over a long history continues from the returned cursor
+	MaxReceiveQuotaScan = 10000
+)
+
+// QuotaUsageRecord is the quota consumed by an address in one snapshot block
+type QuotaUsageRecord struct {
+	SnapshotHeight uint64
+	BlockCount     uint64
+	QuotaUsed      uint64
+	StakeQuotaUsed uint64
+	PoWBlockCount  uint64
+	PoWQuotaUsed   uint64
+	// PoWDifficulty is the sum of the difficulties the nonces of the pow blocks are computed with
+	PoWDifficulty uint64
+}
+
+// ReceiveQuotaSample is the quota used by one receive block of a contract, Difficulty is the pow difficulty of the
+// send block calling the contract, 0 if the send block is not pow
+type ReceiveQuotaSample struct {
+	Height         uint64
+	MethodSelector []byte
+	QuotaUsed      uint64
+	Difficulty     uint64
+}
+
+// QuotaUsage records quota consumption of confirmed account blocks per snapshot block,
+// and the quota used by every receive block of contracts.
+type QuotaUsage struct {
+	store *chain_db.Store
+	chain Chain
+}
+
+func newQuotaUsage(store *chain_db.Store, chain Chain) Plugin {
+	return &QuotaUsage{
+		store: store,
+		chain: chain,
+	}
+}
+
+func (qu *QuotaUsage) SetStore(store *chain_db.Store) {
+	qu.store = store
+}
+
+func (qu *QuotaUsage) InsertAccountBlock(batch *leveldb.Batch, accountBlock *ledger.AccountBlock) error {
+	return nil
+}
+
+func (qu *QuotaUsage) InsertSnapshotBlock(batch *leveldb.Batch, snapshotBlock *ledger.SnapshotBlock, confirmedBlocks []*ledger.AccountBlock) error {
+	recordMap := make(map[types.Address]*QuotaUsageRecord)
+
+	for _, block := range confirmedBlocks {
+		record, ok := recordMap[block.AccountAddress]
+		if !ok {
+			record = &QuotaUsageRecord{SnapshotHeight: snapshotBlock.Height}
+			recordMap[block.AccountAddress] = record
+		}
+		record.BlockCount++
+		record.QuotaUsed += block.QuotaUsed
+		record.StakeQuotaUsed += block.Quota
+		if len(block.Nonce) > 0 {
+			record.PoWBlockCount++
+			if block.QuotaUsed > block.Quota {
+				record.PoWQuotaUsed += block.QuotaUsed - block.Quota
+			}
+			if d := difficultyToUint64(block.Difficulty); record.PoWDifficulty+d >= record.PoWDifficulty {
+				record.PoWDifficulty += d
+			} else {
+				record.PoWDifficulty = helper.MaxUint64
+			}
+		}
+
+		if !block.IsReceiveBlock() || block.BlockType == ledger.BlockTypeGenesisReceive ||
+			!types.IsContractAddr(block.AccountAddress) {
+			continue
+		}
+
+		sendBlock, err := qu.chain.GetAccountBlockByHash(block.FromBlockHash)
+		if err != nil {
+			return fmt.Errorf("qu.chain.GetAccountBlockByHash failed. Error: %s", err)
+		}
+		if sendBlock == nil {
+			return fmt.Errorf("send block %s is nil", block.FromBlockHash)
+		}
+
+		var difficulty uint64
+		if len(sendBlock.Nonce) > 0 {
+			difficulty = difficultyToUint64(sendBlock.Difficulty)
+		}
+		batch.Put(createReceiveQuotaKey(block.AccountAddress, block.Height), serializeReceiveQuota(sendBlock.Data, block.QuotaUsed, difficulty))
+	}
+
+	for addr, record := range recordMap {
+		batch.Put(createQuotaUsageKey(addr, snapshotBlock.Height), serializeQuotaUsageRecord(record))
+	}
+	return nil
+}
+
+func (qu *QuotaUsage) DeleteAccountBlocks(batch *leveldb.Batch, accountBlocks []*ledger.AccountBlock) error {
+	return nil
+}
+
+func (qu *QuotaUsage) DeleteSnapshotBlocks(batch *leveldb.Batch, chunks []*ledger.SnapshotChunk) error {
+	for _, chunk := range chunks {
+		if chunk.SnapshotBlock == nil {
+			continue
+		}
+		for _, block := range chunk.AccountBlocks {
+			batch.Delete(createQuotaUsageKey(block.AccountAddress, chunk.SnapshotBlock.Height))
+
+			if block.IsReceiveBlock() && types.IsContractAddr(block.AccountAddress) {
+				batch.Delete(createReceiveQuotaKey(block.AccountAddress, block.Height))
+			}
+		}
+	}
+	return nil
+}
+
+func (qu *QuotaUsage) RemoveNewUnconfirmed(*leveldb.Batch, []*ledger.AccountBlock) error {
+	return nil
+}
+
+// GetQuotaUsageList returns the quota usage records of addr between snapshot height [start, end]
+func (qu *QuotaUsage) GetQuotaUsageList(addr types.Address, start, end uint64) ([]*QuotaUsageRecord, error) {
+	if start > end {
+		return nil, errors.New("start height is greater than end height")
+	}
+	limit := end + 1
+	if end == helper.MaxUint64 {
+		limit = end
+	}
+
+	iter := qu.store.NewIterator(&util.Range{Start: createQuotaUsageKey(addr, start), Limit: createQuotaUsageKey(addr, limit)})
+	defer iter.Release()
+
+	list := make([]*QuotaUsageRecord, 0)
+	for iter.Next() {
+		key := iter.Key()
+		record, err := deserializeQuotaUsageRecord(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		record.SnapshotHeight = chain_utils.BytesToUint64(key[len(key)-8:])
+		list = append(list, record)
+	}
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetReceiveQuotaSamples returns the latest count receive quota samples of a contract below the account height
+// beforeHeight, or below the latest if beforeHeight is 0, from high to low. If methodSelector is not empty, only
+// receive blocks of send blocks calling that method are returned. At most MaxReceiveQuotaScan samples are visited,
+// if the scan stops there the beforeHeight to continue from is returned, otherwise 0 is returned.
+func (qu *QuotaUsage) GetReceiveQuotaSamples(addr types.Address, methodSelector []byte, beforeHeight uint64, count int) ([]*ReceiveQuotaSample, uint64, error) {
+	limit := createReceiveQuotaKey(addr, helper.MaxUint64)
+	if beforeHeight > 0 {
+		limit = createReceiveQuotaKey(addr, beforeHeight)
+	}
+	iter := qu.store.NewIterator(&util.Range{Start: createReceiveQuotaKey(addr, 0), Limit: limit})
+	defer iter.Release()
+
+	list := make([]*ReceiveQuotaSample, 0, count)
+	scanned := 0
+	next := uint64(0)
+	for iterOk := iter.Last(); iterOk && len(list) < count; iterOk = iter.Prev() {
+		key := iter.Key()
+		height := chain_utils.BytesToUint64(key[len(key)-8:])
+		if scanned >= MaxReceiveQuotaScan {
+			next = height + 1
+			break
+		}
+		scanned++
+
+		sample, err := deserializeReceiveQuota(iter.Value())
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(methodSelector) > 0 && !bytes.Equal(sample.MethodSelector, methodSelector) {
+			continue
+		}
+		sample.Height = height
+		list = append(list, sample)
+	}
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return nil, 0, err
+	}
+	return list, next, nil
+}
+
+func createQuotaUsageKey(addr types.Address, snapshotHeight uint64) []byte {
+	key := make([]byte, 0, 1+types.AddressSize+8)
+	key = append(key, QuotaUsageKeyPrefix)
+	key = append(key, addr.Bytes()...)
+	key = append(key, chain_utils.Uint64ToBytes(snapshotHeight)...)
+	return key
+}
+
+func createReceiveQuotaKey(addr types.Address, height uint64) []byte {
+	key := make([]byte, 0, 1+types.AddressSize+8)
+	key = append(key, ReceiveQuotaKeyPrefix)
+	key = append(key, addr.Bytes()...)
+	key = append(key, chain_utils.Uint64ToBytes(height)...)
+	return key
+}
+
+func serializeQuotaUsageRecord(record *QuotaUsageRecord) []byte {
+	value := make([]byte, quotaUsageValueSize)
+	chain_utils.Uint64Put(value[0:8], record.BlockCount)
+	chain_utils.Uint64Put(value[8:16], record.QuotaUsed)
+	chain_utils.Uint64Put(value[16:24], record.StakeQuotaUsed)
+	chain_utils.Uint64Put(value[24:32], record.PoWBlockCount)
+	chain_utils.Uint64Put(value[32:40], record.PoWQuotaUsed)
+	chain_utils.Uint64Put(value[40:48], record.PoWDifficulty)
+	return value
+}
+
+func deserializeQuotaUsageRecord(value []byte) (*QuotaUsageRecord, error) {
+	if len(value) != quotaUsageValueSize && len(value) != legacyQuotaUsageValueSize {
+		return nil, fmt.Errorf("invalid quota usage record, size is %d", len(value))
+	}
+	record := &QuotaUsageRecord{
+		BlockCount:     chain_utils.BytesToUint64(value[0:8]),
+		QuotaUsed:      chain_utils.BytesToUint64(value[8:16]),
+		StakeQuotaUsed: chain_utils.BytesToUint64(value[16:24]),
+		PoWBlockCount:  chain_utils.BytesToUint64(value[24:32]),
+		PoWQuotaUsed:   chain_utils.BytesToUint64(value[32:40]),
+	}
+	if len(value) == quotaUsageValueSize {
+		record.PoWDifficulty = chain_utils.BytesToUint64(value[40:48])
+	}
+	return record, nil
+}
+
+func serializeReceiveQuota(sendData []byte, quotaUsed uint64, difficulty uint64) []byte {
+	value := make([]byte, receiveQuotaValueSize)
+	copy(value[:methodSelectorSize], sendData)
+	chain_utils.Uint64Put(value[methodSelectorSize:methodSelectorSize+8], quotaUsed)
+	chain_utils.Uint64Put(value[methodSelectorSize+8:], difficulty)
+	return value
+}
+
+func deserializeReceiveQuota(value []byte) (*ReceiveQuotaSample, error) {
+	if len(value) != receiveQuotaValueSize && len(value) != legacyReceiveQuotaValueSize {
+		return nil, fmt.Errorf("invalid receive quota sample, size is %d", len(value))
+	}
+	selector := make([]byte, methodSelectorSize)
+	copy(selector, value[:methodSelectorSize])
+	sample := &ReceiveQuotaSample{
+		MethodSelector: selector,
+		QuotaUsed:      chain_utils.BytesToUint64(value[methodSelectorSize : methodSelectorSize+8]),
+	}
+	if len(value) == receiveQuotaValueSize {
+		sample.Difficulty = chain_utils.BytesToUint64(value[methodSelectorSize+8:])
+	}
+	return sample, nil
+}
+
+// difficultyToUint64 returns the pow difficulty of a block, the difficulties of the quota table fit in an uint64
+func difficultyToUint64(difficulty *big.Int) uint64 {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return 0
+	}
+	if !difficulty.IsUint64() {
+		return helper.MaxUint64
+	}
+	return difficulty.Uint64()
+}
@@ -0,0 +1,231 @@
+package chain_plugins
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	chain_flusher "github.com/vitelabs/go-vite/ledger/chain/flusher"
+)
+
+func TestQuotaUsageRecordSerialize(t *testing.T) {
+	record := &QuotaUsageRecord{
+		BlockCount:     3,
+		QuotaUsed:      63000,
+		StakeQuotaUsed: 42000,
+		PoWBlockCount:  1,
+		PoWQuotaUsed:   21000,
+		PoWDifficulty:  67108863,
+	}
+	result, err := deserializeQuotaUsageRecord(serializeQuotaUsageRecord(record))
+	assert.NoError(t, err)
+	assert.Equal(t, record, result)
+
+	// a record written before the difficulty is recorded
+	result, err = deserializeQuotaUsageRecord(serializeQuotaUsageRecord(record)[:legacyQuotaUsageValueSize])
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), result.PoWDifficulty)
+	assert.Equal(t, record.PoWQuotaUsed, result.PoWQuotaUsed)
+
+	_, err = deserializeQuotaUsageRecord([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestReceiveQuotaSerialize(t *testing.T) {
+	sample, err := deserializeReceiveQuota(serializeReceiveQuota([]byte{1, 2, 3, 4, 5, 6}, 25000, 67108863))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4}, sample.MethodSelector)
+	assert.Equal(t, uint64(25000), sample.QuotaUsed)
+	assert.Equal(t, uint64(67108863), sample.Difficulty)
+
+	sample, err = deserializeReceiveQuota(serializeReceiveQuota(nil, 21000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0}, sample.MethodSelector)
+
+	sample, err = deserializeReceiveQuota(serializeReceiveQuota(nil, 21000, 1)[:legacyReceiveQuotaValueSize])
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(21000), sample.QuotaUsed)
+	assert.Equal(t, uint64(0), sample.Difficulty)
+}
+
+func TestQuotaUsageKeyOrder(t *testing.T) {
+	contract1, _ := types.BytesToAddress([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1})
+	contract2, _ := types.BytesToAddress([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1})
+
+	assert.True(t, bytes.Compare(createQuotaUsageKey(contract1, 255), createQuotaUsageKey(contract1, 256)) < 0)
+	assert.True(t, bytes.Compare(createQuotaUsageKey(contract1, 256), createQuotaUsageKey(contract2, 1)) < 0)
+	assert.True(t, bytes.Compare(createReceiveQuotaKey(contract1, 1), createQuotaUsageKey(contract1, 1)) > 0)
+}
+
+var (
+	quotaCaller, _   = types.BytesToAddress([]byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0})
+	quotaContract, _ = types.BytesToAddress([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1})
+)
+
+type quotaUsageChain struct {
+	blocks map[types.Hash]*ledger.AccountBlock
+}
+
+func (c *quotaUsageChain) Flusher() *chain_flusher.Flusher                     { return nil }
+func (c *quotaUsageChain) GetLatestSnapshotBlock() *ledger.SnapshotBlock       { return nil }
+func (c *quotaUsageChain) IsGenesisAccountBlock(hash types.Hash) bool          { return false }
+func (c *quotaUsageChain) GetAllUnconfirmedBlocks() []*ledger.AccountBlock     { return nil }
+func (c *quotaUsageChain) IsAccountBlockExisted(hash types.Hash) (bool, error) { return false, nil }
+func (c *quotaUsageChain) GetSnapshotBlocksByHeight(height uint64, higher bool, count uint64) ([]*ledger.SnapshotBlock, error) {
+	return nil, nil
+}
+func (c *quotaUsageChain) GetSubLedgerAfterHeight(height uint64) ([]*ledger.SnapshotChunk, error) {
+	return nil, nil
+}
+func (c *quotaUsageChain) GetSubLedger(startHeight, endHeight uint64) ([]*ledger.SnapshotChunk, error) {
+	return nil, nil
+}
+func (c *quotaUsageChain) LoadAllOnRoad() (map[types.Address][]types.Hash, error) { return nil, nil }
+func (c *quotaUsageChain) GetAccountBlockByHash(blockHash types.Hash) (*ledger.AccountBlock, error) {
+	return c.blocks[blockHash], nil
+}
+
+func TestQuotaUsage_InsertAndQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quota_usage")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := chain_db.NewStore(dir, "quota_usage")
+	assert.NoError(t, err)
+	defer store.Close()
+
+	chain := &quotaUsageChain{blocks: make(map[types.Hash]*ledger.AccountBlock)}
+	qu := newQuotaUsage(store, chain).(*QuotaUsage)
+
+	selectorA, selectorB := []byte{1, 1, 1, 1}, []byte{2, 2, 2, 2}
+	send := func(hash byte, data []byte, difficulty int64) *ledger.AccountBlock {
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			Hash:           types.Hash{hash},
+			AccountAddress: quotaCaller,
+			ToAddress:      quotaContract,
+			Data:           data,
+			Quota:          21000,
+			QuotaUsed:      21000,
+		}
+		if difficulty > 0 {
+			block.Nonce = []byte{1}
+			block.Difficulty = big.NewInt(difficulty)
+			block.Quota = 0
+		}
+		chain.blocks[block.Hash] = block
+		return block
+	}
+	receive := func(height uint64, from *ledger.AccountBlock, quotaUsed uint64) *ledger.AccountBlock {
+		return &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeReceive,
+			Height:         height,
+			AccountAddress: quotaContract,
+			FromBlockHash:  from.Hash,
+			Quota:          quotaUsed,
+			QuotaUsed:      quotaUsed,
+		}
+	}
+
+	// snapshot 1: a stake send and a pow send of quotaCaller, both received by quotaContract
+	send1, send2 := send(1, selectorA, 0), send(2, selectorB, 67108863)
+	batch := store.NewBatch()
+	assert.NoError(t, qu.InsertSnapshotBlock(batch, &ledger.SnapshotBlock{Height: 1},
+		[]*ledger.AccountBlock{send1, send2, receive(1, send1, 30000), receive(2, send2, 50000)}))
+	store.WriteDirectly(batch)
+
+	// snapshot 3: more calls of selectorA
+	var blocks []*ledger.AccountBlock
+	for i := uint64(0); i < 3; i++ {
+		s := send(byte(10+i), selectorA, 0)
+		blocks = append(blocks, s, receive(3+i, s, 40000+i))
+	}
+	batch = store.NewBatch()
+	assert.NoError(t, qu.InsertSnapshotBlock(batch, &ledger.SnapshotBlock{Height: 3}, blocks))
+	store.WriteDirectly(batch)
+
+	records, err := qu.GetQuotaUsageList(quotaCaller, 1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, &QuotaUsageRecord{
+		SnapshotHeight: 1,
+		BlockCount:     2,
+		QuotaUsed:      42000,
+		StakeQuotaUsed: 21000,
+		PoWBlockCount:  1,
+		PoWQuotaUsed:   21000,
+		PoWDifficulty:  67108863,
+	}, records[0])
+	assert.Equal(t, uint64(3), records[1].SnapshotHeight)
+	assert.Equal(t, uint64(3), records[1].BlockCount)
+	records, err = qu.GetQuotaUsageList(quotaCaller, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(records))
+
+	samples, next, err := qu.GetReceiveQuotaSamples(quotaContract, nil, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), next)
+	assert.Equal(t, 5, len(samples))
+	assert.Equal(t, uint64(5), samples[0].Height)
+	assert.Equal(t, uint64(40002), samples[0].QuotaUsed)
+
+	samples, _, err = qu.GetReceiveQuotaSamples(quotaContract, selectorB, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(samples))
+	assert.Equal(t, uint64(2), samples[0].Height)
+	assert.Equal(t, uint64(50000), samples[0].QuotaUsed)
+	assert.Equal(t, uint64(67108863), samples[0].Difficulty)
+
+	samples, _, err = qu.GetReceiveQuotaSamples(quotaContract, selectorA, 4, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(samples))
+	assert.Equal(t, uint64(3), samples[0].Height)
+	assert.Equal(t, uint64(1), samples[1].Height)
+	assert.Equal(t, uint64(0), samples[1].Difficulty)
+
+	// the rollback deletes the records and the samples of the snapshot
+	batch = store.NewBatch()
+	assert.NoError(t, qu.DeleteSnapshotBlocks(batch, []*ledger.SnapshotChunk{{SnapshotBlock: &ledger.SnapshotBlock{Height: 3}, AccountBlocks: blocks}}))
+	store.WriteDirectly(batch)
+	records, err = qu.GetQuotaUsageList(quotaCaller, 1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	samples, _, err = qu.GetReceiveQuotaSamples(quotaContract, nil, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(samples))
+}
+
+func TestQuotaUsage_ScanLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quota_usage")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := chain_db.NewStore(dir, "quota_usage")
+	assert.NoError(t, err)
+	defer store.Close()
+	qu := newQuotaUsage(store, &quotaUsageChain{}).(*QuotaUsage)
+
+	// the only sample of selectorB is below MaxReceiveQuotaScan samples of selectorA
+	batch := store.NewBatch()
+	batch.Put(createReceiveQuotaKey(quotaContract, 1), serializeReceiveQuota([]byte{2, 2, 2, 2}, 30000, 0))
+	for height := uint64(2); height <= MaxReceiveQuotaScan+2; height++ {
+		batch.Put(createReceiveQuotaKey(quotaContract, height), serializeReceiveQuota([]byte{1, 1, 1, 1}, 21000, 0))
+	}
+	store.WriteDirectly(batch)
+
+	samples, next, err := qu.GetReceiveQuotaSamples(quotaContract, []byte{2, 2, 2, 2}, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(samples))
+	assert.Equal(t, uint64(3), next)
+
+	samples, next, err = qu.GetReceiveQuotaSamples(quotaContract, []byte{2, 2, 2, 2}, next, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), next)
+	assert.Equal(t, 1, len(samples))
+	assert.Equal(t, uint64(1), samples[0].Height)
+}
@@ -0,0 +1,343 @@
+package chain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	PruneDataVmLog        = "vmLog"
+	PruneDataStateHistory = "stateHistory"
+	PruneDataBlockBody    = "blockBody"
+
+	// meta db key of the pruned heights, PrunedHeightKey + data name -> height
+	PrunedHeightKey = byte(1)
+
+	pruneBatchSize = 1000
+	pruneInterval  = 10 * time.Minute
+)
+
+// PrunedError is returned when the requested data has been pruned by the pruner
+type PrunedError struct {
+	Data string
+	// the requested snapshot height
+	Height uint64
+	// the data at the snapshot heights lower than PrunedHeight have been pruned
+	PrunedHeight uint64
+}
+
+func (e *PrunedError) Error() string {
+	return fmt.Sprintf("the %s at snapshot height %d has been pruned, only the %s since snapshot height %d is available",
+		e.Data, e.Height, e.Data, e.PrunedHeight)
+}
+
+// Unwrap makes errors.Is(err, interfaces.ErrPruned) hold for a *PrunedError
+func (e *PrunedError) Unwrap() error {
+	return interfaces.ErrPruned
+}
+
+// pruner deletes the VM logs, the state history and the block bodies older than the retain days in background
+type pruner struct {
+	chain *chain
+
+	mode          string
+	retain        time.Duration
+	keysPerSecond uint64
+
+	mu            sync.RWMutex
+	prunedHeights map[string]uint64
+
+	// runMu guards the start and the stop
+	runMu  sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	log log15.Logger
+}
+
+func newPruner(c *chain) (*pruner, error) {
+	if err := c.chainCfg.CheckPruneMode(); err != nil {
+		return nil, err
+	}
+	keysPerSecond := c.chainCfg.PruneKeysPerSecond
+	if keysPerSecond <= 0 {
+		keysPerSecond = config.DefaultPruneKeysPerSecond
+	}
+
+	p := &pruner{
+		chain:         c,
+		mode:          c.chainCfg.GetPruneMode(),
+		retain:        time.Duration(c.chainCfg.PruneRetainDays) * 24 * time.Hour,
+		keysPerSecond: keysPerSecond,
+		prunedHeights: make(map[string]uint64),
+		log:           log15.New("module", "chain_pruner"),
+	}
+
+	for _, data := range []string{PruneDataVmLog, PruneDataStateHistory, PruneDataBlockBody} {
+		value, err := c.metaDB.Get(prunedHeightKey(data), nil)
+		if err != nil {
+			if err == leveldb.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		p.prunedHeights[data] = chain_utils.BytesToUint64(value)
+	}
+	return p, nil
+}
+
+func (p *pruner) Start() {
+	if p.mode == config.PruneModeArchive {
+		return
+	}
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+	if p.stopCh != nil {
+		return
+	}
+	p.stopCh = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		p.log.Info(fmt.Sprintf("start pruner, mode is %s, retain %s", p.mode, p.retain), "method", "Start")
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for {
+			if err := p.prune(); err != nil {
+				p.log.Error(fmt.Sprintf("prune failed. Error: %s", err), "method", "Start")
+			}
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the pruner and waits for the running prune, returns false if the pruner is not running
+func (p *pruner) Stop() bool {
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+	if p.stopCh == nil {
+		return false
+	}
+	close(p.stopCh)
+	p.wg.Wait()
+	p.stopCh = nil
+	return true
+}
+
+// PrunedHeight returns the lowest snapshot height of the data which is kept
+func (p *pruner) PrunedHeight(data string) uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.prunedHeights[data]
+}
+
+// check returns a *PrunedError if the data at the snapshot height has been pruned
+func (p *pruner) check(data string, height uint64) error {
+	if prunedHeight := p.PrunedHeight(data); height < prunedHeight {
+		return &PrunedError{Data: data, Height: height, PrunedHeight: prunedHeight}
+	}
+	return nil
+}
+
+func (p *pruner) setPrunedHeight(data string, height uint64) error {
+	if err := p.chain.metaDB.Put(prunedHeightKey(data), chain_utils.Uint64ToBytes(height), nil); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.prunedHeights[data] = height
+	p.mu.Unlock()
+	return nil
+}
+
+// lockWrite runs the write of the pruned keys into the stores exclusively with the inserts and the flushes
+func (p *pruner) lockWrite(write func()) {
+	p.chain.flushMu.Lock()
+	defer p.chain.flushMu.Unlock()
+	write()
+}
+
+// throttle sleeps for the deleted keys, returns false if the pruner is stopped
+func (p *pruner) throttle(n int) bool {
+	select {
+	case <-p.stopCh:
+		return false
+	case <-time.After(time.Duration(n) * time.Second / time.Duration(p.keysPerSecond)):
+		return true
+	}
+}
+
+func (p *pruner) prune() error {
+	cutoff := time.Now().Add(-p.retain)
+	header, err := p.chain.GetSnapshotHeaderBeforeTime(&cutoff)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return nil
+	}
+	// prune the data before the target height
+	target := header.Height
+
+	if err := p.pruneVmLogs(target); err != nil {
+		return fmt.Errorf("prune VM logs failed. Error: %s", err)
+	}
+	if p.mode == config.PruneModePruned {
+		if err := p.pruneBlockBodies(target); err != nil {
+			return fmt.Errorf("prune block bodies failed. Error: %s", err)
+		}
+	}
+	if err := p.pruneStateHistory(target); err != nil {
+		return fmt.Errorf("prune state history failed. Error: %s", err)
+	}
+	return nil
+}
+
+// chunkLocation returns the location of the first block of the snapshot chunk at the height
+func (p *pruner) chunkLocation(height uint64) (*chain_file_manager.Location, error) {
+	if height <= 1 {
+		return chain_file_manager.NewLocation(1, 0), nil
+	}
+	prevLocation, err := p.chain.indexDB.GetSnapshotBlockLocation(height - 1)
+	if err != nil {
+		return nil, err
+	}
+	if prevLocation == nil {
+		return nil, fmt.Errorf("the location of snapshot block %d is not found", height-1)
+	}
+	return p.chain.blockDB.GetNextLocation(prevLocation)
+}
+
+// pruneVmLogs walks the snapshot chunks before the target height and deletes their VM logs
+func (p *pruner) pruneVmLogs(target uint64) error {
+	height := p.PrunedHeight(PruneDataVmLog)
+	if height >= target {
+		return nil
+	}
+	location, err := p.chunkLocation(height)
+	if err != nil {
+		return err
+	}
+
+	var logHashList []types.Hash
+	for location != nil {
+		chunk, next, err := p.chain.blockDB.ReadChunk(location)
+		if err != nil {
+			return fmt.Errorf("read chunk at %s failed. Error: %s", location, err)
+		}
+		if chunk.SnapshotBlock.Height >= target {
+			break
+		}
+
+		for _, block := range chunk.AccountBlocks {
+			if block.LogHash != nil {
+				logHashList = append(logHashList, *block.LogHash)
+			}
+		}
+		location = next
+
+		if len(logHashList) >= pruneBatchSize || chunk.SnapshotBlock.Height+1 >= target {
+			p.lockWrite(func() {
+				p.chain.stateDB.DeleteVmLogs(logHashList)
+			})
+
+			if err := p.setPrunedHeight(PruneDataVmLog, chunk.SnapshotBlock.Height+1); err != nil {
+				return err
+			}
+			if !p.throttle(len(logHashList)) {
+				return nil
+			}
+			logHashList = logHashList[:0]
+		}
+	}
+	return nil
+}
+
+// pruneBlockBodies keeps the headers of the blocks before the target height and deletes the block files
+func (p *pruner) pruneBlockBodies(target uint64) error {
+	// the VM logs are found by walking the block bodies
+	if vmLogHeight := p.PrunedHeight(PruneDataVmLog); vmLogHeight < target {
+		target = vmLogHeight
+	}
+	if p.PrunedHeight(PruneDataBlockBody) >= target {
+		return nil
+	}
+
+	location, err := p.chunkLocation(target)
+	if err != nil {
+		return err
+	}
+	if err := p.chain.blockDB.PruneTo(location, pruneBatchSize, p.throttle); err != nil {
+		return err
+	}
+	if prunedLocation := p.chain.blockDB.PrunedLocation(); prunedLocation == nil || prunedLocation.Compare(location) < 0 {
+		// stopped
+		return nil
+	}
+	return p.setPrunedHeight(PruneDataBlockBody, target)
+}
+
+func (p *pruner) pruneStateHistory(target uint64) error {
+	if p.PrunedHeight(PruneDataStateHistory) >= target {
+		return nil
+	}
+	finished, err := p.chain.stateDB.PruneHistory(target, pruneBatchSize, p.lockWrite, p.throttle)
+	if err != nil || !finished {
+		return err
+	}
+	return p.setPrunedHeight(PruneDataStateHistory, target)
+}
+
+func prunedHeightKey(data string) []byte {
+	return append([]byte{PrunedHeightKey}, []byte(data)...)
+}
+
+// GetPrunedHeight returns the lowest snapshot height of the data which is kept, the data is one of
+// PruneDataVmLog, PruneDataStateHistory and PruneDataBlockBody
+func (c *chain) GetPrunedHeight(data string) uint64 {
+	return c.pruner.PrunedHeight(data)
+}
+
+// CheckAccountBlockPruned returns a *PrunedError if the data of the confirmed account block has been pruned
+func (c *chain) CheckAccountBlockPruned(data string, blockHash types.Hash) error {
+	if c.pruner.PrunedHeight(data) <= 0 {
+		return nil
+	}
+	confirmHeight, err := c.indexDB.GetConfirmHeightByHash(&blockHash)
+	if err != nil {
+		return err
+	}
+	if confirmHeight <= 0 {
+		return nil
+	}
+	pErr := c.pruner.check(data, confirmHeight)
+	if pErr == nil || data != PruneDataBlockBody {
+		return pErr
+	}
+
+	// the pruned body is kept by the cold tier
+	block, err := c.GetCompleteBlockByHash(blockHash)
+	if err != nil {
+		return err
+	}
+	if block != nil {
+		if coldBlock, err := c.getColdAccountBlock(block.AccountAddress, block.Height); err != nil || coldBlock != nil {
+			return err
+		}
+	}
+	return pErr
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/vitelabs/go-vite/common/helper"
 	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
 )
@@ -711,6 +712,9 @@ func (c *chain) GetSubLedger(startHeight, endHeight uint64) ([]*ledger.SnapshotC
 	}
 
 	segList, err := c.blockDB.ReadRange(startLocation, endLocation)
+	if err == interfaces.ErrPruned {
+		return nil, &PrunedError{Data: PruneDataBlockBody, Height: startHeight, PrunedHeight: c.pruner.PrunedHeight(PruneDataBlockBody)}
+	}
 	if err != nil {
 		cErr := fmt.Errorf("c.blockDB.ReadRange failed, startLocation is %+v, endLocation is %+v, . Error: %s,",
 			startLocation, endLocation, err.Error())
@@ -735,6 +739,9 @@ func (c *chain) GetSubLedgerAfterHeight(height uint64) ([]*ledger.SnapshotChunk,
 	}
 
 	segList, err := c.blockDB.ReadRange(startLocation, nil)
+	if err == interfaces.ErrPruned {
+		return nil, &PrunedError{Data: PruneDataBlockBody, Height: height, PrunedHeight: c.pruner.PrunedHeight(PruneDataBlockBody)}
+	}
 	if err != nil {
 		cErr := fmt.Errorf("c.blockDB.ReadRange failed,  startLocation is %+v, endLocation is nil. Error: %s,",
 			startLocation, err.Error())
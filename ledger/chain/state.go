@@ -7,6 +7,7 @@ import (
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/interfaces"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_state "github.com/vitelabs/go-vite/ledger/chain/state"
 	"github.com/vitelabs/go-vite/vm/util"
 )
 
@@ -41,6 +42,32 @@ func (c *chain) GetConfirmedBalanceList(addrList []types.Address, tokenId types.
 	return balanceMap, nil
 }
 
+func (c *chain) GetBalanceBySnapshotHeight(addr types.Address, tokenId types.TokenTypeId, snapshotHeight uint64) (*big.Int, error) {
+	if err := c.pruner.check(PruneDataStateHistory, snapshotHeight); err != nil {
+		return nil, err
+	}
+	result, err := c.stateDB.GetSnapshotBalance(addr, tokenId, snapshotHeight)
+	if err != nil {
+		cErr := fmt.Errorf("c.stateDB.GetSnapshotBalance failed, Addr is %s, tokenId is %s, snapshotHeight is %d. Error: %s", addr, tokenId, snapshotHeight, err)
+		c.log.Error(cErr.Error(), "method", "GetBalanceBySnapshotHeight")
+		return nil, cErr
+	}
+	return result, nil
+}
+
+func (c *chain) GetTokenHolders(tokenId types.TokenTypeId, snapshotHeight uint64, startAddr *types.Address, count int) ([]*chain_state.TokenHolder, error) {
+	if err := c.pruner.check(PruneDataStateHistory, snapshotHeight); err != nil {
+		return nil, err
+	}
+	result, err := c.stateDB.GetTokenHolders(tokenId, snapshotHeight, startAddr, count)
+	if err != nil {
+		cErr := fmt.Errorf("c.stateDB.GetTokenHolders failed, tokenId is %s, snapshotHeight is %d. Error: %s", tokenId, snapshotHeight, err)
+		c.log.Error(cErr.Error(), "method", "GetTokenHolders")
+		return nil, cErr
+	}
+	return result, nil
+}
+
 // get contract code
 func (c *chain) GetContractCode(contractAddress types.Address) ([]byte, error) {
 	code, err := c.stateDB.GetCode(contractAddress)
@@ -53,7 +80,7 @@ func (c *chain) GetContractCode(contractAddress types.Address) ([]byte, error) {
 }
 
 func (c *chain) GetContractMeta(contractAddress types.Address) (*ledger.ContractMeta, error) {
-	if meta := ledger.GetBuiltinContractMeta(contractAddress); meta != nil {
+	if meta := ledger.GetBuiltinContractMeta(contractAddress, c.GetLatestSnapshotBlock().Height); meta != nil {
 		return meta, nil
 	}
 	meta, err := c.stateDB.GetContractMeta(contractAddress)
@@ -66,7 +93,7 @@ func (c *chain) GetContractMeta(contractAddress types.Address) (*ledger.Contract
 }
 
 func (c *chain) GetContractMetaInSnapshot(contractAddress types.Address, snapshotHeight uint64) (*ledger.ContractMeta, error) {
-	if meta := ledger.GetBuiltinContractMeta(contractAddress); meta != nil {
+	if meta := ledger.GetBuiltinContractMeta(contractAddress, snapshotHeight); meta != nil {
 		return meta, nil
 	}
 
@@ -102,7 +129,7 @@ func (c *chain) GetContractList(gid types.Gid) ([]types.Address, error) {
 		return nil, cErr
 	}
 	if util.IsDelegateGid(gid) {
-		addrList = append(addrList, types.BuiltinContracts...)
+		addrList = append(addrList, ledger.GetBuiltinContractList(c.GetLatestSnapshotBlock().Height)...)
 	}
 	return addrList, nil
 }
@@ -132,6 +159,12 @@ func (c *chain) GetVMLogListByAddress(address types.Address, start uint64, end u
 	}
 	var result ledger.VmLogList
 	for _, block := range blocks {
+		if block.LogHash == nil {
+			continue
+		}
+		if err := c.CheckAccountBlockPruned(PruneDataVmLog, block.Hash); err != nil {
+			return nil, err
+		}
 		logs, err := c.GetVmLogList(block.LogHash)
 		if err != nil {
 			return nil, err
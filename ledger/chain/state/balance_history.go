@@ -0,0 +1,98 @@
+package chain_state
+
+import (
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/types"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+type TokenHolder struct {
+	Address types.Address
+	Balance *big.Int
+}
+
+// GetSnapshotBalance returns the balance of the address at the snapshot height, read from the balance history
+func (sDB *StateDB) GetSnapshotBalance(addr types.Address, tokenId types.TokenTypeId, snapshotHeight uint64) (*big.Int, error) {
+	iter := sDB.store.NewIterator(util.BytesPrefix(append([]byte{chain_utils.BalanceHistoryKeyPrefix}, addr.Bytes()...)))
+	defer iter.Release()
+
+	seekKey := chain_utils.CreateHistoryBalanceKey(addr, tokenId, snapshotHeight+1)
+	iter.Seek(seekKey.Bytes())
+
+	balance := big.NewInt(0)
+	if iter.Prev() {
+		key := chain_utils.BalanceHistoryKey{}.Construct(iter.Key())
+		if key != nil && key.EqualAddressAndTokenId(addr, tokenId) {
+			balance.SetBytes(iter.Value())
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// GetTokenHolders returns the addresses which hold the token at the snapshot height, ordered by address.
+// The list starts from the address after startAddr, or from the first address if startAddr is nil.
+// Balance history keys above the snapshot height are skipped, so every page is consistent with the same snapshot.
+func (sDB *StateDB) GetTokenHolders(tokenId types.TokenTypeId, snapshotHeight uint64, startAddr *types.Address, count int) ([]*TokenHolder, error) {
+	iter := sDB.store.NewIterator(util.BytesPrefix([]byte{chain_utils.BalanceHistoryKeyPrefix}))
+	defer iter.Release()
+
+	position := []byte{chain_utils.BalanceHistoryKeyPrefix}
+	if startAddr != nil {
+		next, ok := nextAddress(*startAddr)
+		if !ok {
+			return nil, nil
+		}
+		position = append(position, next.Bytes()...)
+	}
+
+	holders := make([]*TokenHolder, 0, count)
+	seekKey := chain_utils.CreateHistoryBalanceKey(types.Address{}, tokenId, snapshotHeight+1)
+	for len(holders) < count {
+		if !iter.Seek(position) {
+			break
+		}
+		addr, err := types.BytesToAddress(iter.Key()[1 : 1+types.AddressSize])
+		if err != nil {
+			return nil, err
+		}
+
+		seekKey.AddressRefill(addr)
+		iter.Seek(seekKey.Bytes())
+		if iter.Prev() {
+			key := chain_utils.BalanceHistoryKey{}.Construct(iter.Key())
+			if key != nil && key.EqualAddressAndTokenId(addr, tokenId) {
+				balance := new(big.Int).SetBytes(iter.Value())
+				if balance.Sign() > 0 {
+					holders = append(holders, &TokenHolder{Address: addr, Balance: balance})
+				}
+			}
+		}
+
+		next, ok := nextAddress(addr)
+		if !ok {
+			break
+		}
+		position = append(position[:1], next.Bytes()...)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return holders, nil
+}
+
+// nextAddress returns the smallest address greater than addr
+func nextAddress(addr types.Address) (types.Address, bool) {
+	next := addr
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next, true
+		}
+	}
+	return next, false
+}
@@ -0,0 +1,87 @@
+package chain_state
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+func TestStateDB_GetTokenHolders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "balance_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := chain_db.NewStore(dir, "balance_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	sDB := &StateDB{store: store}
+
+	addrList := []types.Address{{1}, {2}, {3}, {4}}
+	otherToken := types.TokenTypeId{1}
+	batch := store.NewBatch()
+	put := func(addr types.Address, tokenId types.TokenTypeId, height uint64, balance int64) {
+		batch.Put(chain_utils.CreateHistoryBalanceKey(addr, tokenId, height).Bytes(), big.NewInt(balance).Bytes())
+	}
+	put(addrList[0], ledger.ViteTokenId, 1, 10)
+	put(addrList[0], ledger.ViteTokenId, 5, 20)
+	put(addrList[1], ledger.ViteTokenId, 3, 30)
+	put(addrList[1], ledger.ViteTokenId, 4, 0)
+	put(addrList[2], otherToken, 1, 40)
+	put(addrList[3], ledger.ViteTokenId, 6, 50)
+	store.WriteDirectly(batch)
+
+	balance, err := sDB.GetSnapshotBalance(addrList[0], ledger.ViteTokenId, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), balance.Int64())
+	balance, err = sDB.GetSnapshotBalance(addrList[0], ledger.ViteTokenId, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), balance.Int64())
+	balance, err = sDB.GetSnapshotBalance(addrList[2], ledger.ViteTokenId, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance.Int64())
+
+	holders, err := sDB.GetTokenHolders(ledger.ViteTokenId, 3, nil, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(holders))
+	assert.Equal(t, addrList[0], holders[0].Address)
+	assert.Equal(t, addrList[1], holders[1].Address)
+
+	holders, err = sDB.GetTokenHolders(ledger.ViteTokenId, 6, nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(holders))
+	assert.Equal(t, int64(20), holders[0].Balance.Int64())
+
+	holders, err = sDB.GetTokenHolders(ledger.ViteTokenId, 6, &holders[0].Address, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(holders))
+	assert.Equal(t, addrList[3], holders[0].Address)
+}
+
+func TestNextAddress(t *testing.T) {
+	addr := types.Address{1}
+	addr[types.AddressSize-1] = 0xff
+	expected := types.Address{1}
+	expected[types.AddressSize-2] = 1
+	next, ok := nextAddress(addr)
+	assert.True(t, ok)
+	assert.Equal(t, expected, next)
+
+	var max types.Address
+	for i := range max {
+		max[i] = 0xff
+	}
+	_, ok = nextAddress(max)
+	assert.False(t, ok)
+}
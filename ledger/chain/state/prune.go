@@ -0,0 +1,85 @@
+package chain_state
+
+import (
+	"bytes"
+
+	leveldb "github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/types"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+// PruneHistory deletes the storage history and the balance history before the snapshot height. The latest history
+// of every key before the height is kept, so the state at the height and after the height can still be queried.
+// Every batch is written into the store through the mem db and flushed by the flusher, lockWrite runs the write
+// exclusively with the inserts. throttle is called after every batch is written, pruning stops if it returns false.
+// Returns true if all history before the height is pruned.
+func (sDB *StateDB) PruneHistory(height uint64, batchSize int, lockWrite func(write func()), throttle func(n int) bool) (bool, error) {
+	for _, prefix := range []byte{chain_utils.StorageHistoryKeyPrefix, chain_utils.BalanceHistoryKeyPrefix} {
+		finished, err := sDB.pruneHistory(prefix, height, batchSize, lockWrite, throttle)
+		if err != nil || !finished {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (sDB *StateDB) pruneHistory(prefix byte, height uint64, batchSize int, lockWrite func(write func()), throttle func(n int) bool) (bool, error) {
+	iter := sDB.store.NewIterator(util.BytesPrefix([]byte{prefix}))
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	// the latest history key before the height of the current key
+	var prevKey []byte
+
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) <= 1+types.HeightSize {
+			continue
+		}
+		// the snapshot height is the suffix of the history key
+		if chain_utils.BytesToUint64(key[len(key)-types.HeightSize:]) >= height {
+			continue
+		}
+
+		if prevKey != nil && bytes.Equal(prevKey[:len(prevKey)-types.HeightSize], key[:len(key)-types.HeightSize]) {
+			batch.Delete(prevKey)
+		}
+		prevKey = append(prevKey[:0], key...)
+
+		if batch.Len() >= batchSize {
+			sDB.writePruneBatch(batch, lockWrite)
+			if !throttle(batch.Len()) {
+				return false, nil
+			}
+			batch = new(leveldb.Batch)
+		}
+	}
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return false, err
+	}
+
+	if batch.Len() > 0 {
+		sDB.writePruneBatch(batch, lockWrite)
+	}
+	return true, nil
+}
+
+func (sDB *StateDB) writePruneBatch(batch *leveldb.Batch, lockWrite func(write func())) {
+	lockWrite(func() {
+		sDB.store.WriteDirectly(batch)
+	})
+}
+
+// DeleteVmLogs deletes the VM logs of the confirmed blocks through the mem db, the deletes are flushed by the flusher.
+// Assume the inserts are locked.
+func (sDB *StateDB) DeleteVmLogs(logHashList []types.Hash) {
+	if len(logHashList) <= 0 {
+		return
+	}
+	batch := new(leveldb.Batch)
+	for i := range logHashList {
+		batch.Delete(chain_utils.CreateVmLogListKey(&logHashList[i]).Bytes())
+	}
+	sDB.store.WriteDirectly(batch)
+}
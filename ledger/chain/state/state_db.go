@@ -48,13 +48,13 @@ type StateDB struct {
 
 func NewStateDB(chain Chain, chainCfg *config.Chain, chainDir string) (*StateDB, error) {
 
-	store, err := chain_db.NewStore(path.Join(chainDir, "state"), "stateDb")
+	store, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "state"), "stateDb", chainCfg.GetStoreBackend(config.StoreState))
 
 	if err != nil {
 		return nil, err
 	}
 
-	redoStore, err := chain_db.NewStore(path.Join(chainDir, "state_redo"), "stateDbRedo")
+	redoStore, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "state_redo"), "stateDbRedo", chainCfg.GetStoreBackend(config.StoreRedo))
 	if err != nil {
 		return nil, err
 	}
@@ -360,7 +360,7 @@ func (sDB *StateDB) GetStatus() []interfaces.DBStatus {
 		Size:   uint64(statusList[0].Size),
 		Status: statusList[0].Status,
 	}, {
-		Name:   "stateDB.store.levelDB",
+		Name:   "stateDB.store." + statusList[1].Name,
 		Count:  uint64(statusList[1].Count),
 		Size:   uint64(statusList[1].Size),
 		Status: statusList[1].Status,
@@ -0,0 +1,180 @@
+package chain_state
+
+import (
+	"bytes"
+	"math/big"
+
+	leveldb "github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+const (
+	// the cursor of the balances is the address and the token id
+	BalanceCursorSize = types.AddressSize + types.TokenTypeIdSize
+	// the cursor of the storage is the address, the storage key padded to 32 bytes and the length of the key
+	StorageCursorSize = types.AddressSize + types.HashSize + 1
+)
+
+// IterateSnapshotBalances iterates the balances at the snapshot height ordered by the address and the token id,
+// from the balance after the cursor, or from the first balance if the cursor is nil. The zero balances are skipped.
+// f returns false to stop, the cursor of the balance is passed to resume the iteration. If maxScan is greater than 0,
+// the iteration stops after scanning maxScan items including the skipped ones, and the cursor of the last scanned
+// item is returned to resume the iteration.
+func (sDB *StateDB) IterateSnapshotBalances(snapshotHeight uint64, cursor []byte, maxScan int,
+	f func(addr types.Address, tokenId types.TokenTypeId, balance *big.Int, cursor []byte) bool) ([]byte, error) {
+	return sDB.iterateHistory(chain_utils.BalanceHistoryKeyPrefix, snapshotHeight, cursor, maxScan, func(item, value []byte) (bool, error) {
+		balance := new(big.Int).SetBytes(value)
+		if balance.Sign() <= 0 {
+			return true, nil
+		}
+		addr, err := types.BytesToAddress(item[:types.AddressSize])
+		if err != nil {
+			return false, err
+		}
+		tokenId, err := types.BytesToTokenTypeId(item[types.AddressSize:])
+		if err != nil {
+			return false, err
+		}
+		return f(addr, tokenId, balance, item), nil
+	})
+}
+
+// IterateSnapshotStorage iterates the contract storage at the snapshot height ordered by the address and the key,
+// from the storage value after the cursor, or from the first value if the cursor is nil. The deleted keys are skipped.
+// f returns false to stop, the cursor of the value is passed to resume the iteration. maxScan limits the scanned
+// items as IterateSnapshotBalances does.
+func (sDB *StateDB) IterateSnapshotStorage(snapshotHeight uint64, cursor []byte, maxScan int,
+	f func(addr types.Address, key, value []byte, cursor []byte) bool) ([]byte, error) {
+	return sDB.iterateHistory(chain_utils.StorageHistoryKeyPrefix, snapshotHeight, cursor, maxScan, func(item, value []byte) (bool, error) {
+		if len(value) <= 0 {
+			return true, nil
+		}
+		addr, err := types.BytesToAddress(item[:types.AddressSize])
+		if err != nil {
+			return false, err
+		}
+		key := chain_utils.StorageRealKey{}.ConstructFix(item[types.AddressSize:]).Extra()
+		return f(addr, key, value, item), nil
+	})
+}
+
+// IterateContractMetas iterates the contract metas ordered by the address, from the contract after startAddr,
+// or from the first contract if startAddr is nil. f returns false to stop.
+func (sDB *StateDB) IterateContractMetas(startAddr *types.Address, f func(addr types.Address, meta *ledger.ContractMeta) bool) error {
+	iter := sDB.store.NewIterator(util.BytesPrefix([]byte{chain_utils.ContractMetaKeyPrefix}))
+	defer iter.Release()
+
+	ok := false
+	if startAddr != nil {
+		next, hasNext := nextAddress(*startAddr)
+		if !hasNext {
+			return nil
+		}
+		ok = iter.Seek(chain_utils.CreateContractMetaKey(next).Bytes())
+	} else {
+		ok = iter.Next()
+	}
+
+	for ; ok; ok = iter.Next() {
+		addr, err := types.BytesToAddress(iter.Key()[1:])
+		if err != nil {
+			return err
+		}
+		meta := &ledger.ContractMeta{}
+		if err := meta.Deserialize(iter.Value()); err != nil {
+			return err
+		}
+		if !f(addr, meta) {
+			break
+		}
+	}
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// iterateHistory iterates the latest values at or below the snapshot height of the history keys with the prefix.
+// A history key is the prefix, the item and the snapshot height, the items are iterated in order from the item
+// after the cursor. f returns false to stop, the item and the value are only valid in f. If maxScan items are
+// scanned, the items which have no value at the snapshot height and the items skipped by f included, the
+// iteration stops and the last scanned item is returned.
+func (sDB *StateDB) iterateHistory(prefix byte, snapshotHeight uint64, cursor []byte, maxScan int, f func(item, value []byte) (bool, error)) ([]byte, error) {
+	iter := sDB.store.NewIterator(util.BytesPrefix([]byte{prefix}))
+	defer iter.Release()
+
+	// seekKey is the prefix, the item and the height
+	var seekKey []byte
+	ok := false
+	if len(cursor) > 0 {
+		seekKey = make([]byte, 0, 1+len(cursor)+types.HeightSize)
+		seekKey = append(append(append(seekKey, prefix), cursor...), chain_utils.Uint64ToBytes(helper.MaxUint64)...)
+		ok = seekAfter(iter, seekKey)
+	} else {
+		ok = iter.Next()
+	}
+
+	scanned := 0
+	for ok {
+		key := iter.Key()
+		if len(key) <= 1+types.HeightSize {
+			ok = iter.Next()
+			continue
+		}
+		itemSize := len(key) - 1 - types.HeightSize
+		if len(seekKey) != len(key) {
+			seekKey = make([]byte, len(key))
+		}
+		copy(seekKey, key)
+
+		// the latest value at or below the snapshot height
+		chain_utils.Uint64Put(seekKey[1+itemSize:], snapshotHeight+1)
+		var value []byte
+		found := false
+		if iter.Seek(seekKey) {
+			found = iter.Prev()
+		} else {
+			found = iter.Last()
+		}
+		if found {
+			prevKey := iter.Key()
+			found = len(prevKey) == len(seekKey) && bytes.Equal(prevKey[:1+itemSize], seekKey[:1+itemSize])
+		}
+		if found {
+			value = iter.Value()
+			if goOn, err := f(seekKey[1:1+itemSize], value); err != nil || !goOn {
+				return nil, err
+			}
+		}
+
+		scanned++
+		if maxScan > 0 && scanned >= maxScan {
+			return append([]byte{}, seekKey[1:1+itemSize]...), nil
+		}
+
+		// the next item
+		chain_utils.Uint64Put(seekKey[1+itemSize:], helper.MaxUint64)
+		ok = seekAfter(iter, seekKey)
+	}
+
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// seekAfter moves the iterator to the first key greater than the key
+func seekAfter(iter interfaces.StorageIterator, key []byte) bool {
+	if !iter.Seek(key) {
+		return false
+	}
+	if bytes.Equal(iter.Key(), key) {
+		return iter.Next()
+	}
+	return true
+}
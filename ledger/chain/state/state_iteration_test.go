@@ -0,0 +1,223 @@
+package chain_state
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+func newIterationStateDB(t *testing.T) (*StateDB, func()) {
+	dir, err := ioutil.TempDir("", "state_iteration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := chain_db.NewStore(dir, "state_iteration")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return &StateDB{store: store}, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStateDB_IterateSnapshotBalances(t *testing.T) {
+	sDB, closeFunc := newIterationStateDB(t)
+	defer closeFunc()
+
+	addrList := []types.Address{{1}, {2}, {3}}
+	otherToken := types.TokenTypeId{1}
+	batch := sDB.store.NewBatch()
+	put := func(addr types.Address, tokenId types.TokenTypeId, height uint64, balance int64) {
+		batch.Put(chain_utils.CreateHistoryBalanceKey(addr, tokenId, height).Bytes(), big.NewInt(balance).Bytes())
+	}
+	put(addrList[0], ledger.ViteTokenId, 1, 10)
+	put(addrList[0], ledger.ViteTokenId, 5, 20)
+	put(addrList[0], otherToken, 2, 15)
+	put(addrList[1], ledger.ViteTokenId, 3, 30)
+	put(addrList[1], ledger.ViteTokenId, 4, 0)
+	put(addrList[2], ledger.ViteTokenId, 6, 40)
+	sDB.store.WriteDirectly(batch)
+
+	type item struct {
+		addr    types.Address
+		tokenId types.TokenTypeId
+		balance int64
+	}
+	iterate := func(snapshotHeight uint64, cursor []byte, count int) ([]item, []byte) {
+		var items []item
+		var lastCursor []byte
+		_, err := sDB.IterateSnapshotBalances(snapshotHeight, cursor, 0, func(addr types.Address, tokenId types.TokenTypeId, balance *big.Int, cursor []byte) bool {
+			items = append(items, item{addr, tokenId, balance.Int64()})
+			lastCursor = append([]byte{}, cursor...)
+			return len(items) < count
+		})
+		assert.NoError(t, err)
+		return items, lastCursor
+	}
+
+	items, _ := iterate(3, nil, 10)
+	assert.Equal(t, []item{
+		{addrList[0], otherToken, 15},
+		{addrList[0], ledger.ViteTokenId, 10},
+		{addrList[1], ledger.ViteTokenId, 30},
+	}, items)
+
+	// the zero balance at height 4 is skipped and the later balances are not seen
+	items, _ = iterate(5, nil, 10)
+	assert.Equal(t, []item{
+		{addrList[0], otherToken, 15},
+		{addrList[0], ledger.ViteTokenId, 20},
+	}, items)
+
+	// resume from the cursor of the first page
+	items, cursor := iterate(6, nil, 1)
+	assert.Equal(t, []item{{addrList[0], otherToken, 15}}, items)
+	assert.Equal(t, BalanceCursorSize, len(cursor))
+	items, _ = iterate(6, cursor, 10)
+	assert.Equal(t, []item{
+		{addrList[0], ledger.ViteTokenId, 20},
+		{addrList[2], ledger.ViteTokenId, 40},
+	}, items)
+}
+
+func TestStateDB_IterateSnapshotBalances_MaxScan(t *testing.T) {
+	sDB, closeFunc := newIterationStateDB(t)
+	defer closeFunc()
+
+	addrList := []types.Address{{1}, {2}, {3}}
+	batch := sDB.store.NewBatch()
+	put := func(addr types.Address, height uint64, balance int64) {
+		batch.Put(chain_utils.CreateHistoryBalanceKey(addr, ledger.ViteTokenId, height).Bytes(), big.NewInt(balance).Bytes())
+	}
+	put(addrList[0], 1, 10)
+	put(addrList[1], 2, 0)
+	put(addrList[2], 6, 40)
+	sDB.store.WriteDirectly(batch)
+
+	var items []types.Address
+	iterate := func(cursor []byte, maxScan int) []byte {
+		next, err := sDB.IterateSnapshotBalances(5, cursor, maxScan, func(addr types.Address, tokenId types.TokenTypeId, balance *big.Int, cursor []byte) bool {
+			items = append(items, addr)
+			return true
+		})
+		assert.NoError(t, err)
+		return next
+	}
+
+	// the zero balance is scanned, the cursor of it is returned to resume
+	next := iterate(nil, 2)
+	assert.Equal(t, []types.Address{addrList[0]}, items)
+	assert.Equal(t, addrList[1].Bytes(), next[:types.AddressSize])
+
+	// the balance created after the snapshot height is scanned, then the iteration ends before the limit
+	next = iterate(next, 2)
+	assert.Nil(t, next)
+	assert.Equal(t, []types.Address{addrList[0]}, items)
+
+	// the cursor is returned when the limit is reached at the last item
+	items = nil
+	next = iterate(nil, 3)
+	assert.Equal(t, addrList[2].Bytes(), next[:types.AddressSize])
+	assert.Nil(t, iterate(next, 3))
+	assert.Equal(t, []types.Address{addrList[0]}, items)
+}
+
+func TestStateDB_IterateSnapshotStorage(t *testing.T) {
+	sDB, closeFunc := newIterationStateDB(t)
+	defer closeFunc()
+
+	addrList := []types.Address{{1}, {2}}
+	batch := sDB.store.NewBatch()
+	put := func(addr types.Address, key []byte, height uint64, value []byte) {
+		batch.Put(chain_utils.CreateHistoryStorageValueKey(&addr, key, height).Bytes(), value)
+	}
+	put(addrList[0], []byte{1}, 1, []byte("a"))
+	put(addrList[0], []byte{1}, 4, nil)
+	put(addrList[0], []byte{1, 2}, 2, []byte("b"))
+	put(addrList[1], []byte{3}, 3, []byte("c"))
+	sDB.store.WriteDirectly(batch)
+
+	type item struct {
+		addr  types.Address
+		key   string
+		value string
+	}
+	var items []item
+	var cursorList [][]byte
+	_, err := sDB.IterateSnapshotStorage(3, nil, 0, func(addr types.Address, key, value []byte, cursor []byte) bool {
+		items = append(items, item{addr, string(key), string(value)})
+		cursorList = append(cursorList, append([]byte{}, cursor...))
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []item{
+		{addrList[0], string([]byte{1}), "a"},
+		{addrList[0], string([]byte{1, 2}), "b"},
+		{addrList[1], string([]byte{3}), "c"},
+	}, items)
+	assert.Equal(t, StorageCursorSize, len(cursorList[0]))
+
+	// the deleted key is skipped
+	items = nil
+	_, err = sDB.IterateSnapshotStorage(4, nil, 0, func(addr types.Address, key, value []byte, cursor []byte) bool {
+		items = append(items, item{addr, string(key), string(value)})
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []item{
+		{addrList[0], string([]byte{1, 2}), "b"},
+		{addrList[1], string([]byte{3}), "c"},
+	}, items)
+
+	items = nil
+	_, err = sDB.IterateSnapshotStorage(3, cursorList[1], 0, func(addr types.Address, key, value []byte, cursor []byte) bool {
+		items = append(items, item{addr, string(key), string(value)})
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{addrList[1], string([]byte{3}), "c"}}, items)
+}
+
+func TestStateDB_IterateContractMetas(t *testing.T) {
+	sDB, closeFunc := newIterationStateDB(t)
+	defer closeFunc()
+
+	addrList := []types.Address{{1}, {2}, {3}}
+	batch := sDB.store.NewBatch()
+	for i, addr := range addrList {
+		meta := &ledger.ContractMeta{Gid: types.DELEGATE_GID, CreateBlockHash: types.Hash{byte(i + 1)}, QuotaRatio: 10}
+		data, err := meta.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch.Put(chain_utils.CreateContractMetaKey(addr).Bytes(), data)
+	}
+	sDB.store.WriteDirectly(batch)
+
+	var addrs []types.Address
+	err := sDB.IterateContractMetas(nil, func(addr types.Address, meta *ledger.ContractMeta) bool {
+		addrs = append(addrs, addr)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, addrList, addrs)
+
+	addrs = nil
+	err = sDB.IterateContractMetas(&addrList[0], func(addr types.Address, meta *ledger.ContractMeta) bool {
+		addrs = append(addrs, addr)
+		assert.Equal(t, types.Hash{addr[0]}, meta.CreateBlockHash)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, addrList[1:], addrs)
+}
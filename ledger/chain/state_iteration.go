@@ -0,0 +1,310 @@
+package chain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_state "github.com/vitelabs/go-vite/ledger/chain/state"
+)
+
+// the kinds of the state items
+const (
+	StateKindAccounts      = "accounts"
+	StateKindBalances      = "balances"
+	StateKindStorage       = "storage"
+	StateKindContractMetas = "contractMetas"
+)
+
+// StateAccount is an account and its latest account block confirmed at the snapshot block
+type StateAccount struct {
+	Address types.Address
+	Height  uint64
+	Hash    types.Hash
+}
+
+type StateBalance struct {
+	Address types.Address
+	TokenId types.TokenTypeId
+	Balance *big.Int
+}
+
+type StateStorage struct {
+	Address types.Address
+	Key     []byte
+	Value   []byte
+}
+
+type StateContractMeta struct {
+	Address types.Address
+	Meta    *ledger.ContractMeta
+}
+
+// MaxStateScanItems is the max items scanned by a page, including the items skipped because they are empty or
+// created after the snapshot block, so a page may have fewer items than the count before the end
+const MaxStateScanItems = 10000
+
+// StatePage is a page of the state items, only the list of the kind is set
+type StatePage struct {
+	Kind string
+
+	Accounts      []*StateAccount
+	Balances      []*StateBalance
+	Storage       []*StateStorage
+	ContractMetas []*StateContractMeta
+
+	// the cursor of the next page, nil if there are no more items
+	NextCursor []byte
+}
+
+// StateIterator reads the state at a snapshot block page by page. Every page is read at the snapshot block however
+// the ledger grows, so the pages are consistent as long as the snapshot block is not rolled back or pruned.
+type StateIterator struct {
+	chain    *chain
+	snapshot *ledger.SnapshotBlock
+}
+
+// OpenOffline opens the ledger in the data directory of a stopped node for reading, e.g. to iterate the state by
+// NewStateIterator. The background workers are not started. The upgrade box must be initialized before opening.
+func OpenOffline(dataDir string, chainCfg *config.Chain, genesisCfg *config.Genesis) (Chain, error) {
+	c := NewChain(dataDir, chainCfg, genesisCfg)
+	if err := c.Init(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewStateIterator returns the iterator of the state at the snapshot height
+func (c *chain) NewStateIterator(snapshotHeight uint64) (*StateIterator, error) {
+	if latest := c.GetLatestSnapshotBlock(); snapshotHeight > latest.Height {
+		return nil, fmt.Errorf("snapshot height %d is higher than the latest snapshot height %d", snapshotHeight, latest.Height)
+	}
+	snapshot, err := c.GetSnapshotHeaderByHeight(snapshotHeight)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot block %d is not found", snapshotHeight)
+	}
+	return &StateIterator{chain: c, snapshot: snapshot}, nil
+}
+
+func (it *StateIterator) SnapshotBlock() *ledger.SnapshotBlock {
+	return it.snapshot
+}
+
+// Next returns at most count items of the kind after the cursor, the first page is returned if the cursor is nil.
+// At most MaxStateScanItems items are scanned, the page has a next cursor until all items are scanned.
+func (it *StateIterator) Next(kind string, cursor []byte, count int) (*StatePage, error) {
+	if count <= 0 {
+		return nil, errors.New("count should be greater than 0")
+	}
+	c := it.chain
+
+	// the snapshot block may be rolled back between the pages
+	hash, err := c.GetSnapshotHashByHeight(it.snapshot.Height)
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil || *hash != it.snapshot.Hash {
+		return nil, fmt.Errorf("snapshot block %d %s has been rolled back", it.snapshot.Height, it.snapshot.Hash)
+	}
+
+	page := &StatePage{Kind: kind}
+	// the cursor of the last item in the page, and whether there is an item after the page
+	var lastCursor []byte
+	hasMore := false
+	add := func(itemCursor []byte) bool {
+		if count <= 0 {
+			hasMore = true
+			return false
+		}
+		count--
+		lastCursor = append(lastCursor[:0], itemCursor...)
+		return true
+	}
+
+	// the cursor of the last scanned item if the scan stops at MaxStateScanItems
+	var scanCursor []byte
+	switch kind {
+	case StateKindAccounts:
+		scanCursor, err = it.nextAccounts(page, cursor, add)
+	case StateKindBalances:
+		scanCursor, err = it.nextBalances(page, cursor, add)
+	case StateKindStorage:
+		scanCursor, err = it.nextStorage(page, cursor, add)
+	case StateKindContractMetas:
+		scanCursor, err = it.nextContractMetas(page, cursor, add)
+	default:
+		return nil, fmt.Errorf("unknown state kind %q, should be one of %s, %s, %s and %s",
+			kind, StateKindAccounts, StateKindBalances, StateKindStorage, StateKindContractMetas)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hasMore {
+		page.NextCursor = lastCursor
+	} else if scanCursor != nil {
+		page.NextCursor = scanCursor
+	}
+	return page, nil
+}
+
+func (it *StateIterator) nextAccounts(page *StatePage, cursor []byte, add func(itemCursor []byte) bool) ([]byte, error) {
+	startId := uint64(0)
+	if len(cursor) > 0 {
+		if len(cursor) != 8 {
+			return nil, fmt.Errorf("invalid cursor size %d of %s", len(cursor), StateKindAccounts)
+		}
+		startId = binary.BigEndian.Uint64(cursor)
+	}
+
+	var iterErr error
+	var scanCursor []byte
+	scanned := 0
+	itemCursor := make([]byte, 8)
+	it.chain.indexDB.IterateAccountsFrom(startId, func(addr types.Address, accountId uint64, err error) bool {
+		if err != nil {
+			iterErr = err
+			return false
+		}
+		height, hash, err := it.chain.getAccountHeightBySnapshot(addr, it.snapshot.Height)
+		if err != nil {
+			iterErr = err
+			return false
+		}
+		binary.BigEndian.PutUint64(itemCursor, accountId)
+		// the accounts created after the snapshot block are skipped
+		if height > 0 {
+			if !add(itemCursor) {
+				return false
+			}
+			page.Accounts = append(page.Accounts, &StateAccount{Address: addr, Height: height, Hash: *hash})
+		}
+
+		if scanned++; scanned >= MaxStateScanItems {
+			scanCursor = append([]byte{}, itemCursor...)
+			return false
+		}
+		return true
+	})
+	return scanCursor, iterErr
+}
+
+func (it *StateIterator) nextBalances(page *StatePage, cursor []byte, add func(itemCursor []byte) bool) ([]byte, error) {
+	if len(cursor) > 0 && len(cursor) != chain_state.BalanceCursorSize {
+		return nil, fmt.Errorf("invalid cursor size %d of %s", len(cursor), StateKindBalances)
+	}
+	if err := it.chain.pruner.check(PruneDataStateHistory, it.snapshot.Height); err != nil {
+		return nil, err
+	}
+	return it.chain.stateDB.IterateSnapshotBalances(it.snapshot.Height, cursor, MaxStateScanItems, func(addr types.Address, tokenId types.TokenTypeId, balance *big.Int, itemCursor []byte) bool {
+		if !add(itemCursor) {
+			return false
+		}
+		page.Balances = append(page.Balances, &StateBalance{Address: addr, TokenId: tokenId, Balance: balance})
+		return true
+	})
+}
+
+func (it *StateIterator) nextStorage(page *StatePage, cursor []byte, add func(itemCursor []byte) bool) ([]byte, error) {
+	if len(cursor) > 0 && len(cursor) != chain_state.StorageCursorSize {
+		return nil, fmt.Errorf("invalid cursor size %d of %s", len(cursor), StateKindStorage)
+	}
+	if err := it.chain.pruner.check(PruneDataStateHistory, it.snapshot.Height); err != nil {
+		return nil, err
+	}
+	return it.chain.stateDB.IterateSnapshotStorage(it.snapshot.Height, cursor, MaxStateScanItems, func(addr types.Address, key, value []byte, itemCursor []byte) bool {
+		if !add(itemCursor) {
+			return false
+		}
+		page.Storage = append(page.Storage, &StateStorage{
+			Address: addr,
+			Key:     append([]byte{}, key...),
+			Value:   append([]byte{}, value...),
+		})
+		return true
+	})
+}
+
+func (it *StateIterator) nextContractMetas(page *StatePage, cursor []byte, add func(itemCursor []byte) bool) ([]byte, error) {
+	var startAddr *types.Address
+	if len(cursor) > 0 {
+		addr, err := types.BytesToAddress(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor of %s. Error: %s", StateKindContractMetas, err)
+		}
+		startAddr = &addr
+	}
+
+	var iterErr error
+	var scanCursor []byte
+	scanned := 0
+	err := it.chain.stateDB.IterateContractMetas(startAddr, func(addr types.Address, meta *ledger.ContractMeta) bool {
+		// the contracts created after the snapshot block are skipped, the genesis contracts have no create block
+		visible := true
+		if !meta.CreateBlockHash.IsZero() {
+			confirmHeight, err := it.chain.indexDB.GetConfirmHeightByHash(&meta.CreateBlockHash)
+			if err != nil {
+				iterErr = err
+				return false
+			}
+			visible = confirmHeight > 0 && confirmHeight <= it.snapshot.Height
+		}
+		if visible {
+			if !add(addr.Bytes()) {
+				return false
+			}
+			page.ContractMetas = append(page.ContractMetas, &StateContractMeta{Address: addr, Meta: meta})
+		}
+
+		if scanned++; scanned >= MaxStateScanItems {
+			scanCursor = addr.Bytes()
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scanCursor, iterErr
+}
+
+// getAccountHeightBySnapshot returns the latest account block of the account confirmed at or below the snapshot
+// height, returns 0 if no block is confirmed. The confirm heights increase with the account heights, so it is
+// found by binary search.
+func (c *chain) getAccountHeightBySnapshot(addr types.Address, snapshotHeight uint64) (uint64, *types.Hash, error) {
+	latestHeight, _, err := c.indexDB.GetLatestAccountBlock(&addr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	height := uint64(0)
+	var hash *types.Hash
+	low, high := uint64(1), latestHeight
+	for low <= high {
+		mid := low + (high-low)/2
+		midHash, _, err := c.indexDB.GetAccountBlockLocationByHeight(&addr, mid)
+		if err != nil {
+			return 0, nil, err
+		}
+		confirmHeight := uint64(0)
+		if midHash != nil {
+			if confirmHeight, err = c.indexDB.GetConfirmHeightByHash(midHash); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		if confirmHeight > 0 && confirmHeight <= snapshotHeight {
+			height, hash = mid, midHash
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	return height, hash, nil
+}
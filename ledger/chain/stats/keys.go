@@ -0,0 +1,123 @@
+package chain_stats
+
+import (
+	"fmt"
+	"sort"
+
+	leveldb "github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/types"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+var indexPrefixNames = map[byte]string{
+	chain_utils.AccountBlockHashKeyPrefix:    "accountBlockHash",
+	chain_utils.AccountBlockHeightKeyPrefix:  "accountBlockHeight",
+	chain_utils.ReceiveKeyPrefix:             "receive",
+	chain_utils.ConfirmHeightKeyPrefix:       "confirmHeight",
+	chain_utils.OnRoadKeyPrefix:              "onRoad",
+	chain_utils.SnapshotBlockHashKeyPrefix:   "snapshotBlockHash",
+	chain_utils.SnapshotBlockHeightKeyPrefix: "snapshotBlockHeight",
+	chain_utils.AccountAddressKeyPrefix:      "accountAddress",
+	chain_utils.AccountIdKeyPrefix:           "accountId",
+	chain_utils.AccountCheckpointKeyPrefix:   "accountCheckpoint",
+}
+
+var statePrefixNames = map[byte]string{
+	chain_utils.StorageKeyPrefix:        "storage",
+	chain_utils.StorageHistoryKeyPrefix: "storageHistory",
+	chain_utils.BalanceKeyPrefix:        "balance",
+	chain_utils.BalanceHistoryKeyPrefix: "balanceHistory",
+	chain_utils.CodeKeyPrefix:           "code",
+	chain_utils.ContractMetaKeyPrefix:   "contractMeta",
+	chain_utils.GidContractKeyPrefix:    "gidContract",
+	chain_utils.VmLogListKeyPrefix:      "vmLogList",
+	chain_utils.CallDepthKeyPrefix:      "callDepth",
+}
+
+func (c *Collector) scanIndex() error {
+	return c.scanStore("index", c.indexStore, indexPrefixNames, nil)
+}
+
+// scanState counts the state keys by the prefix, the keys starting with an address are counted by the address too
+func (c *Collector) scanState() error {
+	return c.scanStore("state", c.stateStore, statePrefixNames, func(key []byte, size uint64) {
+		switch key[0] {
+		case chain_utils.StorageKeyPrefix, chain_utils.StorageHistoryKeyPrefix,
+			chain_utils.BalanceKeyPrefix, chain_utils.BalanceHistoryKeyPrefix,
+			chain_utils.CodeKeyPrefix, chain_utils.ContractMetaKeyPrefix:
+		default:
+			return
+		}
+		if len(key) < 1+types.AddressSize {
+			return
+		}
+		addr, err := types.BytesToAddress(key[1 : 1+types.AddressSize])
+		if err != nil {
+			return
+		}
+
+		usage := c.getAddressUsage(addr)
+		usage.StateKeys++
+		usage.StateSize += size
+		switch key[0] {
+		case chain_utils.StorageKeyPrefix:
+			usage.StorageKeys++
+			usage.StorageSize += size
+		case chain_utils.StorageHistoryKeyPrefix:
+			usage.StorageHistorySize += size
+		}
+	})
+}
+
+func (c *Collector) scanStore(storeName string, store *chain_db.Store, names map[byte]string, onKey func(key []byte, size uint64)) error {
+	prefixes := make(map[byte]*PrefixUsage)
+
+	iter := store.NewIterator(&util.Range{})
+	defer iter.Release()
+
+	var count uint64
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) <= 0 {
+			continue
+		}
+		size := uint64(len(key) + len(iter.Value()))
+
+		usage, ok := prefixes[key[0]]
+		if !ok {
+			name, ok := names[key[0]]
+			if !ok {
+				name = "unknown"
+			}
+			usage = &PrefixUsage{Store: storeName, Prefix: key[0], Name: name}
+			prefixes[key[0]] = usage
+		}
+		usage.Keys++
+		usage.KeySize += uint64(len(key))
+		usage.ValueSize += uint64(len(iter.Value()))
+
+		if onKey != nil {
+			onKey(key, size)
+		}
+
+		count++
+		if count%1000000 == 0 {
+			c.log.Info(fmt.Sprintf("scan %d keys of %s", count, storeName), "method", "scanStore")
+		}
+	}
+	if err := iter.Error(); err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+
+	usages := make([]*PrefixUsage, 0, len(prefixes))
+	for _, usage := range prefixes {
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Prefix < usages[j].Prefix
+	})
+	c.report.Prefixes = append(c.report.Prefixes, usages...)
+	return nil
+}
@@ -0,0 +1,253 @@
+package chain_stats
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/types"
+	chain_block "github.com/vitelabs/go-vite/ledger/chain/block"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const DefaultTopN = 20
+
+// StoreUsage is the disk usage of a file or a directory in the ledger directory
+type StoreUsage struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// PrefixUsage is the size of the keys with the same prefix in a store
+type PrefixUsage struct {
+	Store     string `json:"store"`
+	Prefix    byte   `json:"prefix"`
+	Name      string `json:"name"`
+	Keys      uint64 `json:"keys"`
+	KeySize   uint64 `json:"keySize"`
+	ValueSize uint64 `json:"valueSize"`
+}
+
+// AddressUsage is the data size of an account or a contract
+type AddressUsage struct {
+	Address    types.Address `json:"address"`
+	IsContract bool          `json:"isContract"`
+
+	// the storage, the storage history, the balances, the balance history, the code and the contract meta
+	StateKeys uint64 `json:"stateKeys"`
+	StateSize uint64 `json:"stateSize"`
+
+	StorageKeys        uint64 `json:"storageKeys"`
+	StorageSize        uint64 `json:"storageSize"`
+	StorageHistorySize uint64 `json:"storageHistorySize"`
+
+	// the account blocks in the block files
+	Blocks    uint64 `json:"blocks"`
+	BlockSize uint64 `json:"blockSize"`
+
+	// the VM logs emitted by the contract
+	VmLogs    uint64 `json:"vmLogs"`
+	VmLogSize uint64 `json:"vmLogSize"`
+}
+
+// DailyGrowth is the growth of the block files in a day, by the timestamps of the snapshot blocks
+type DailyGrowth struct {
+	Date           string `json:"date"`
+	SnapshotBlocks uint64 `json:"snapshotBlocks"`
+	AccountBlocks  uint64 `json:"accountBlocks"`
+	Size           uint64 `json:"size"`
+}
+
+type Report struct {
+	Stores   []*StoreUsage  `json:"stores"`
+	Prefixes []*PrefixUsage `json:"prefixes"`
+
+	Accounts  uint64 `json:"accounts"`
+	Contracts uint64 `json:"contracts"`
+
+	// the contracts with the largest storage, including the storage history
+	TopStorageContracts []*AddressUsage `json:"topStorageContracts"`
+	// the contracts with the largest VM logs
+	TopVmLogContracts []*AddressUsage `json:"topVmLogContracts"`
+	// the accounts with the largest account blocks
+	TopBlockAccounts []*AddressUsage `json:"topBlockAccounts"`
+
+	VmLogs    uint64 `json:"vmLogs"`
+	VmLogSize uint64 `json:"vmLogSize"`
+
+	BlockFileGrowth []*DailyGrowth `json:"blockFileGrowth"`
+}
+
+// Collector computes the disk usage of the ledger directory offline. The node must be stopped while collecting.
+type Collector struct {
+	chainDir string
+
+	blockDB    *chain_block.BlockDB
+	indexStore *chain_db.Store
+	stateStore *chain_db.Store
+
+	report    *Report
+	addresses map[types.Address]*AddressUsage
+	growth    map[string]*DailyGrowth
+
+	log log15.Logger
+}
+
+func NewCollector(chainDir string, chainCfg *config.Chain) (*Collector, error) {
+	if _, err := os.Stat(chainDir); err != nil {
+		return nil, err
+	}
+
+	blockDB, err := chain_block.NewBlockDB(chainDir)
+	if err != nil {
+		return nil, err
+	}
+	indexStore, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "index"), "indexDb", chainCfg.GetStoreBackend(config.StoreIndex))
+	if err != nil {
+		blockDB.Close()
+		return nil, err
+	}
+	stateStore, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "state"), "stateDb", chainCfg.GetStoreBackend(config.StoreState))
+	if err != nil {
+		blockDB.Close()
+		indexStore.Close()
+		return nil, err
+	}
+
+	return &Collector{
+		chainDir:   chainDir,
+		blockDB:    blockDB,
+		indexStore: indexStore,
+		stateStore: stateStore,
+		log:        log15.New("module", "chain_stats"),
+	}, nil
+}
+
+// Collect scans the ledger directory and returns the report, only the topN addresses are listed
+func (c *Collector) Collect(topN int) (*Report, error) {
+	if topN <= 0 {
+		topN = DefaultTopN
+	}
+	c.report = &Report{}
+	c.addresses = make(map[types.Address]*AddressUsage)
+	c.growth = make(map[string]*DailyGrowth)
+
+	steps := []struct {
+		name string
+		f    func() error
+	}{
+		{"compute disk usage", c.collectDiskUsage},
+		{"scan index keys", c.scanIndex},
+		{"scan state keys", c.scanState},
+		{"walk blocks", c.walkBlocks},
+	}
+	for _, step := range steps {
+		c.log.Info(fmt.Sprintf("start %s", step.name), "method", "Collect")
+		if err := step.f(); err != nil {
+			return nil, fmt.Errorf("%s failed. Error: %s", step.name, err)
+		}
+	}
+
+	usages := make([]*AddressUsage, 0, len(c.addresses))
+	var contracts []*AddressUsage
+	for _, usage := range c.addresses {
+		usages = append(usages, usage)
+		if usage.IsContract {
+			contracts = append(contracts, usage)
+		}
+	}
+	c.report.Accounts = uint64(len(usages))
+	c.report.Contracts = uint64(len(contracts))
+
+	c.report.TopStorageContracts = topUsages(contracts, topN, func(u *AddressUsage) uint64 {
+		return u.StorageSize + u.StorageHistorySize
+	})
+	c.report.TopVmLogContracts = topUsages(contracts, topN, func(u *AddressUsage) uint64 {
+		return u.VmLogSize
+	})
+	c.report.TopBlockAccounts = topUsages(usages, topN, func(u *AddressUsage) uint64 {
+		return u.BlockSize
+	})
+
+	c.report.BlockFileGrowth = make([]*DailyGrowth, 0, len(c.growth))
+	for _, growth := range c.growth {
+		c.report.BlockFileGrowth = append(c.report.BlockFileGrowth, growth)
+	}
+	sort.Slice(c.report.BlockFileGrowth, func(i, j int) bool {
+		return c.report.BlockFileGrowth[i].Date < c.report.BlockFileGrowth[j].Date
+	})
+	return c.report, nil
+}
+
+func (c *Collector) Close() error {
+	c.blockDB.Close()
+	c.stateStore.Close()
+	return c.indexStore.Close()
+}
+
+// collectDiskUsage computes the size of every file and directory in the ledger directory
+func (c *Collector) collectDiskUsage() error {
+	file, err := os.Open(c.chainDir)
+	if err != nil {
+		return err
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var size int64
+		if err := filepath.Walk(path.Join(c.chainDir, name), func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				size += info.Size()
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		c.report.Stores = append(c.report.Stores, &StoreUsage{Name: name, Size: size})
+	}
+	return nil
+}
+
+func (c *Collector) getAddressUsage(addr types.Address) *AddressUsage {
+	usage, ok := c.addresses[addr]
+	if !ok {
+		usage = &AddressUsage{
+			Address:    addr,
+			IsContract: types.IsContractAddr(addr),
+		}
+		c.addresses[addr] = usage
+	}
+	return usage
+}
+
+// topUsages returns the n largest usages by the size, the usages with zero size are skipped
+func topUsages(usages []*AddressUsage, n int, size func(u *AddressUsage) uint64) []*AddressUsage {
+	result := make([]*AddressUsage, 0, n)
+	for _, usage := range usages {
+		if size(usage) > 0 {
+			result = append(result, usage)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if size(result[i]) != size(result[j]) {
+			return size(result[i]) > size(result[j])
+		}
+		return result[i].Address.String() < result[j].Address.String()
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
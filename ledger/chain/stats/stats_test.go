@@ -0,0 +1,159 @@
+package chain_stats
+
+import (
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_block "github.com/vitelabs/go-vite/ledger/chain/block"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+func TestTopUsages(t *testing.T) {
+	var usages []*AddressUsage
+	for i := 0; i < 5; i++ {
+		addr, err := types.BytesToAddress(append(make([]byte, types.AddressSize-1), byte(i)))
+		assert.NoError(t, err)
+		usages = append(usages, &AddressUsage{Address: addr, VmLogSize: uint64(i % 3)})
+	}
+	vmLogSize := func(u *AddressUsage) uint64 {
+		return u.VmLogSize
+	}
+
+	top := topUsages(usages, 3, vmLogSize)
+	assert.Equal(t, 3, len(top))
+	assert.Equal(t, []uint64{2, 1, 1}, []uint64{top[0].VmLogSize, top[1].VmLogSize, top[2].VmLogSize})
+	// the same sizes are ordered by the address
+	assert.True(t, top[1].Address.String() < top[2].Address.String())
+
+	// the usages with zero size are skipped
+	assert.Equal(t, 3, len(topUsages(usages, 10, vmLogSize)))
+}
+
+// the block files are small, so the chunks span several files
+const testBlockFileSize = 1024
+
+func TestWalkBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chain_stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	alice, _ := types.BytesToAddress(append(make([]byte, types.AddressSize-2), 1, 0))
+	contract, _ := types.BytesToAddress(append(make([]byte, types.AddressSize-2), 2, 1))
+	logHash := types.DataHash([]byte("vm log"))
+	rnd := rand.New(rand.NewSource(1))
+
+	accountBlock := func(addr types.Address, height uint64) *ledger.AccountBlock {
+		block := &ledger.AccountBlock{
+			BlockType:      ledger.BlockTypeSendCall,
+			AccountAddress: addr,
+			ToAddress:      contract,
+			Height:         height,
+			Amount:         big.NewInt(1),
+			Fee:            big.NewInt(0),
+			Data:           make([]byte, 200),
+		}
+		// the random data isn't compressed by snappy
+		rnd.Read(block.Data)
+		block.Hash = types.DataHash(append(addr.Bytes(), byte(height)))
+		return block
+	}
+	snapshotBlock := func(height uint64, timestamp time.Time) *ledger.SnapshotBlock {
+		return &ledger.SnapshotBlock{
+			Hash:      types.DataHash([]byte{byte(height)}),
+			Height:    height,
+			Timestamp: &timestamp,
+		}
+	}
+
+	contractBlock := accountBlock(contract, 1)
+	contractBlock.BlockType = ledger.BlockTypeReceive
+	contractBlock.LogHash = &logHash
+
+	// the snapshot blocks at the first and the last second of 2020-09-13 and 2020-09-14
+	lastSecond := time.Date(2020, 9, 13, 23, 59, 59, 0, time.UTC)
+	chunks := []*ledger.SnapshotChunk{
+		{SnapshotBlock: snapshotBlock(1, lastSecond.Add(-24*time.Hour+time.Second))},
+		{SnapshotBlock: snapshotBlock(2, lastSecond), AccountBlocks: []*ledger.AccountBlock{accountBlock(alice, 1), accountBlock(alice, 2)}},
+		{SnapshotBlock: snapshotBlock(3, lastSecond.Add(time.Second)), AccountBlocks: []*ledger.AccountBlock{contractBlock}},
+		{SnapshotBlock: snapshotBlock(4, lastSecond.Add(24*time.Hour))},
+	}
+
+	blockDB, err := chain_block.NewBlockDBFixedSize(dir, testBlockFileSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blockDB.Close()
+
+	// the size of the chunks by the date
+	sizes := make(map[string]uint64)
+	location := chain_file_manager.NewLocation(1, 0)
+	for _, chunk := range chunks {
+		if _, _, err := blockDB.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+		next := blockDB.LatestLocation()
+		sizes[chunk.SnapshotBlock.Timestamp.UTC().Format(dateLayout)] += uint64(location.Distance(testBlockFileSize, next))
+		location = next
+	}
+	assert.True(t, blockDB.LatestLocation().FileId > 1)
+
+	stateStore, err := chain_db.NewStore(path.Join(dir, "state"), "stateDb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stateStore.Close()
+	batch := new(leveldb.Batch)
+	batch.Put(chain_utils.CreateVmLogListKey(&logHash).Bytes(), make([]byte, 50))
+	stateStore.WriteDirectly(batch)
+
+	c := &Collector{
+		chainDir:   dir,
+		blockDB:    blockDB,
+		stateStore: stateStore,
+		report:     &Report{},
+		addresses:  make(map[types.Address]*AddressUsage),
+		growth:     make(map[string]*DailyGrowth),
+		log:        log15.New("module", "chain_stats"),
+	}
+	assert.NoError(t, c.walkBlocks())
+
+	assert.Equal(t, 2, len(c.growth))
+	for date, growth := range map[string]DailyGrowth{
+		"2020-09-13": {SnapshotBlocks: 2, AccountBlocks: 2},
+		"2020-09-14": {SnapshotBlocks: 2, AccountBlocks: 1},
+	} {
+		assert.Equal(t, growth.SnapshotBlocks, c.growth[date].SnapshotBlocks, date)
+		assert.Equal(t, growth.AccountBlocks, c.growth[date].AccountBlocks, date)
+		assert.Equal(t, sizes[date], c.growth[date].Size, date)
+	}
+
+	// the walk covers the block files from the first location to the latest location
+	var growthSize uint64
+	for _, growth := range c.growth {
+		growthSize += growth.Size
+	}
+	assert.Equal(t, uint64(chain_file_manager.NewLocation(1, 0).Distance(testBlockFileSize, blockDB.LatestLocation())), growthSize)
+
+	assert.Equal(t, uint64(2), c.addresses[alice].Blocks)
+	assert.Equal(t, uint64(1), c.addresses[contract].Blocks)
+	assert.True(t, c.addresses[alice].BlockSize > 400)
+	assert.Equal(t, 2, len(c.addresses))
+	assert.Equal(t, uint64(1), c.addresses[contract].VmLogs)
+	assert.Equal(t, uint64(50), c.addresses[contract].VmLogSize)
+	assert.Equal(t, uint64(50), c.report.VmLogSize)
+}
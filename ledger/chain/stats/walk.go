@@ -0,0 +1,89 @@
+package chain_stats
+
+import (
+	"fmt"
+
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+const dateLayout = "2006-01-02"
+
+// walkBlocks reads all blocks from the block files in order, counts the size of the account blocks by the address,
+// the size of the VM logs by the contract and the growth of the block files by the day of the snapshot blocks.
+// The size of a pruned block is the size it took before pruning.
+func (c *Collector) walkBlocks() error {
+	fileSize := c.blockDB.FileSize()
+	location := chain_file_manager.NewLocation(1, 0)
+	latestLocation := c.blockDB.LatestLocation()
+
+	// the account blocks and the size of the current snapshot chunk
+	var accountBlocks uint64
+	var chunkSize uint64
+
+	for location.Compare(latestLocation) < 0 {
+		sb, ab, next, err := c.blockDB.ReadUnit(location)
+		if err != nil {
+			return fmt.Errorf("read block at %s failed. Error: %s", location, err)
+		}
+		size := uint64(location.Distance(fileSize, next))
+		chunkSize += size
+
+		if ab != nil {
+			accountBlocks++
+			if err := c.countAccountBlock(ab, size); err != nil {
+				return err
+			}
+		} else if sb != nil {
+			c.countSnapshotBlock(sb, accountBlocks, chunkSize)
+			accountBlocks, chunkSize = 0, 0
+		} else {
+			return fmt.Errorf("unknown data at %s", location)
+		}
+		location = next
+	}
+	return nil
+}
+
+func (c *Collector) countAccountBlock(ab *ledger.AccountBlock, size uint64) error {
+	usage := c.getAddressUsage(ab.AccountAddress)
+	usage.Blocks++
+	usage.BlockSize += size
+
+	if ab.LogHash == nil {
+		return nil
+	}
+	value, err := c.stateStore.Get(chain_utils.CreateVmLogListKey(ab.LogHash).Bytes())
+	if err != nil {
+		return err
+	}
+	// the VM logs may be pruned
+	if len(value) <= 0 {
+		return nil
+	}
+	usage.VmLogs++
+	usage.VmLogSize += uint64(len(value))
+	c.report.VmLogs++
+	c.report.VmLogSize += uint64(len(value))
+	return nil
+}
+
+func (c *Collector) countSnapshotBlock(sb *ledger.SnapshotBlock, accountBlocks uint64, chunkSize uint64) {
+	if sb.Height%100000 == 0 {
+		c.log.Info(fmt.Sprintf("walk to snapshot block %d", sb.Height), "method", "walkBlocks")
+	}
+
+	date := "unknown"
+	if sb.Timestamp != nil {
+		date = sb.Timestamp.UTC().Format(dateLayout)
+	}
+	growth, ok := c.growth[date]
+	if !ok {
+		growth = &DailyGrowth{Date: date}
+		c.growth[date] = growth
+	}
+	growth.SnapshotBlocks++
+	growth.AccountBlocks += accountBlocks
+	growth.Size += chunkSize
+}
@@ -21,6 +21,10 @@ func (c *chain) GetLedgerReaderByHeight(startHeight uint64, endHeight uint64) (c
 	if endHeight > latestSnapshotBlock.Height {
 		return nil, fmt.Errorf("endHeight is too big, endHeight is %d, latest snapshot height is %d", endHeight, latestSnapshotBlock.Height)
 	}
+	// the pruned block files only have the headers, they can't be synced
+	if err := c.pruner.check(PruneDataBlockBody, startHeight); err != nil {
+		return nil, err
+	}
 
 	return newLedgerReader(c, startHeight, endHeight)
 
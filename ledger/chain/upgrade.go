@@ -0,0 +1,29 @@
+package chain
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/vitelabs/go-vite/common/upgrade"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+// the warning about the version of the producers is repeated every versionWarnInterval snapshot blocks
+const versionWarnInterval = 100
+
+// checkProducerVersion warns if the producers have started using an upgrade version which the local node can't run
+func (c *chain) checkProducerVersion(snapshotBlock *ledger.SnapshotBlock) {
+	err := upgrade.CheckProducerVersion(snapshotBlock.Height, snapshotBlock.Version)
+	if err == nil {
+		return
+	}
+
+	lastHeight := atomic.LoadUint64(&c.versionWarnHeight)
+	if lastHeight > 0 && snapshotBlock.Height < lastHeight+versionWarnInterval && snapshotBlock.Height >= lastHeight {
+		return
+	}
+	atomic.StoreUint64(&c.versionWarnHeight, snapshotBlock.Height)
+
+	c.log.Error(fmt.Sprintf("!!!!!! UPGRADE REQUIRED !!!!!! %s. The snapshot block is %s %d",
+		err, snapshotBlock.Hash, snapshotBlock.Height), "method", "checkProducerVersion")
+}
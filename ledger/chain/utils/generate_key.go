@@ -71,6 +71,14 @@ func CreateSnapshotBlockHeightKey(snapshotBlockHeight uint64) SnapshotBlockHeigh
 	return key
 }
 
+func CreateAccountCheckpointKey(addr *types.Address, startHeight uint64) AccountCheckpointKey {
+	key := AccountCheckpointKey{}
+	key[0] = AccountCheckpointKeyPrefix
+	key.AddressRefill(*addr)
+	key.HeightRefill(startHeight)
+	return key
+}
+
 // ====== state db ======
 
 func CreateStorageValueKeyPrefix(address *types.Address, prefix []byte) []byte {
@@ -19,6 +19,8 @@ const (
 	AccountAddressKeyPrefix = byte(9)
 
 	AccountIdKeyPrefix = byte(10)
+
+	AccountCheckpointKeyPrefix = byte(11)
 )
 
 // state db
@@ -148,3 +148,22 @@ func (key AccountIdKey) String() string {
 func (key *AccountIdKey) AccountIdRefill(accountId uint64) {
 	Uint64Put(key[1:1+types.AccountIdSize], accountId)
 }
+
+// --------------------------------
+type AccountCheckpointKey [1 + types.AddressSize + types.HeightSize]byte
+
+func (key AccountCheckpointKey) Bytes() []byte {
+	return key[:]
+}
+
+func (key AccountCheckpointKey) String() string {
+	return string(key[:])
+}
+
+func (key *AccountCheckpointKey) AddressRefill(addr types.Address) {
+	copy(key[1:1+types.AddressSize], addr.Bytes())
+}
+
+func (key *AccountCheckpointKey) HeightRefill(height uint64) {
+	Uint64Put(key[1+types.AddressSize:1+types.AddressSize+types.HeightSize], height)
+}
@@ -0,0 +1,317 @@
+package chain_verify
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/types"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+// iterate calls f with a copy of every key and value which have the prefix
+func (v *Verifier) iterate(prefix byte, f func(key, value []byte) error) error {
+	iter := v.indexStore.NewIterator(util.BytesPrefix([]byte{prefix}))
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := f(append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...)); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+func (v *Verifier) hasIndex(key []byte) (bool, error) {
+	if value, ok := v.fixes[string(key)]; ok {
+		return value != nil, nil
+	}
+	return v.indexStore.Has(key)
+}
+
+// checkAccounts checks the id of every account, and deletes the accounts which have no block
+func (v *Verifier) checkAccounts() error {
+	var latestId uint64
+	ids := make(map[types.Address]uint64, len(v.accounts))
+	usedIds := make(map[uint64]bool, len(v.accounts))
+
+	if err := v.iterate(chain_utils.AccountIdKeyPrefix, func(key, value []byte) error {
+		accountId := chain_utils.BytesToUint64(key[1:])
+		if accountId > latestId {
+			latestId = accountId
+		}
+
+		addr, err := types.BytesToAddress(value)
+		if err != nil || v.accounts[addr] == nil {
+			v.addIssue(IssueAccount, fmt.Sprintf("%d", accountId), true, "account id %d maps to an account which has no block", accountId)
+			v.fix(key, nil)
+			if err == nil {
+				if addrValue, err := v.getIndex(chain_utils.CreateAccountAddressKey(&addr).Bytes()); err != nil {
+					return err
+				} else if bytes.Equal(addrValue, key[1:]) {
+					v.fix(chain_utils.CreateAccountAddressKey(&addr).Bytes(), nil)
+				}
+			}
+			return nil
+		}
+		ids[addr] = accountId
+		usedIds[accountId] = true
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for addr := range v.accounts {
+		addr := addr
+		value, err := v.getIndex(chain_utils.CreateAccountAddressKey(&addr).Bytes())
+		if err != nil {
+			return err
+		}
+		if accountId, ok := ids[addr]; ok && bytes.Equal(value, chain_utils.Uint64ToBytes(accountId)) {
+			continue
+		}
+
+		accountId, ok := ids[addr]
+		if !ok {
+			// keep the id in the address index if it is not used by another account
+			if len(value) == 8 && chain_utils.BytesToUint64(value) > 0 && !usedIds[chain_utils.BytesToUint64(value)] {
+				accountId = chain_utils.BytesToUint64(value)
+			} else {
+				latestId++
+				accountId = latestId
+			}
+			usedIds[accountId] = true
+			v.fix(chain_utils.CreateAccountIdKey(accountId).Bytes(), addr.Bytes())
+		}
+		v.addIssue(IssueAccount, addr.String(), true, "the account id of the account is missing or wrong, should be %d", accountId)
+		if !bytes.Equal(value, chain_utils.Uint64ToBytes(accountId)) {
+			v.fix(chain_utils.CreateAccountAddressKey(&addr).Bytes(), chain_utils.Uint64ToBytes(accountId))
+		}
+	}
+	return nil
+}
+
+// checkAccountBlockIndexes deletes the account block indexes which point to no block
+func (v *Verifier) checkAccountBlockIndexes() error {
+	if err := v.iterate(chain_utils.AccountBlockHeightKeyPrefix, func(key, value []byte) error {
+		addr, err := types.BytesToAddress(key[1 : 1+types.AddressSize])
+		if err != nil {
+			return err
+		}
+		height := chain_utils.BytesToUint64(key[1+types.AddressSize:])
+
+		if account := v.accounts[addr]; account == nil || height <= 0 || height > account.height {
+			v.addIssue(IssueAccountIndex, fmt.Sprintf("%s-%d", addr, height), true, "the height index points to no block")
+			v.fix(key, nil)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return v.iterate(chain_utils.AccountBlockHashKeyPrefix, func(key, value []byte) error {
+		hash, err := types.BytesToHash(key[1:])
+		if err != nil {
+			return err
+		}
+		// the value may be rewritten by walking blocks
+		if value, err = v.getIndex(key); err != nil || value == nil {
+			return err
+		}
+		ok, err := v.isIndexedBlock(hash, value)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.addIssue(IssueAccountIndex, hash.String(), true, "the hash index points to no block")
+			v.fix(key, nil)
+		}
+		return nil
+	})
+}
+
+// isIndexedBlock returns true if the block or a send block of the block at addrHeightValue has the hash
+func (v *Verifier) isIndexedBlock(hash types.Hash, addrHeightValue []byte) (bool, error) {
+	if len(addrHeightValue) != types.AddressSize+types.HeightSize {
+		return false, nil
+	}
+	addr, err := types.BytesToAddress(addrHeightValue[:types.AddressSize])
+	if err != nil {
+		return false, nil
+	}
+	height := chain_utils.BytesToUint64(addrHeightValue[types.AddressSize:])
+	if account := v.accounts[addr]; account == nil || height <= 0 || height > account.height {
+		return false, nil
+	}
+
+	// the height index has been verified by walking blocks
+	heightValue, err := v.getIndex(chain_utils.CreateAccountBlockHeightKey(&addr, height).Bytes())
+	if err != nil {
+		return false, err
+	}
+	if len(heightValue) <= types.HashSize {
+		return false, nil
+	}
+	if bytes.Equal(heightValue[:types.HashSize], hash.Bytes()) {
+		return true, nil
+	}
+
+	block, err := v.blockDB.GetAccountBlock(chain_utils.DeserializeLocation(heightValue[types.HashSize:]))
+	if err != nil {
+		return false, err
+	}
+	for _, sendBlock := range block.SendBlockList {
+		if sendBlock.Hash == hash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkSnapshotBlockIndexes deletes the snapshot block indexes which point to no block
+func (v *Verifier) checkSnapshotBlockIndexes() error {
+	var latestHeight uint64
+	if v.latestSb != nil {
+		latestHeight = v.latestSb.Height
+	}
+
+	if err := v.iterate(chain_utils.SnapshotBlockHeightKeyPrefix, func(key, value []byte) error {
+		height := chain_utils.BytesToUint64(key[1:])
+		if height > latestHeight {
+			v.addIssue(IssueSnapshotIndex, fmt.Sprintf("%d", height), true, "the height index points to no block")
+			v.fix(key, nil)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return v.iterate(chain_utils.SnapshotBlockHashKeyPrefix, func(key, value []byte) error {
+		height := chain_utils.BytesToUint64(value)
+
+		heightValue, err := v.getIndex(chain_utils.CreateSnapshotBlockHeightKey(height).Bytes())
+		if err != nil {
+			return err
+		}
+		if height <= 0 || height > latestHeight || len(heightValue) < types.HashSize || !bytes.Equal(heightValue[:types.HashSize], key[1:]) {
+			v.addIssue(IssueSnapshotIndex, fmt.Sprintf("%x", key[1:]), true, "the hash index points to no block")
+			v.fix(key, nil)
+		}
+		return nil
+	})
+}
+
+// checkConfirmHeights deletes the confirm heights of the account blocks which are not existed
+func (v *Verifier) checkConfirmHeights() error {
+	return v.iterate(chain_utils.ConfirmHeightKeyPrefix, func(key, value []byte) error {
+		addr, err := types.BytesToAddress(key[1 : 1+types.AddressSize])
+		if err != nil {
+			return err
+		}
+		height := chain_utils.BytesToUint64(key[1+types.AddressSize:])
+		if account := v.accounts[addr]; account == nil || height > account.height {
+			v.addIssue(IssueConfirmHeight, fmt.Sprintf("%s-%d", addr, height), true, "the confirm height belongs to no block")
+			v.fix(key, nil)
+		}
+		return nil
+	})
+}
+
+// checkOnRoad compares the onroad set in the index with the unreceived send blocks
+func (v *Verifier) checkOnRoad() error {
+	if err := v.iterate(chain_utils.OnRoadKeyPrefix, func(key, value []byte) error {
+		toAddr, err := types.BytesToAddress(key[1 : 1+types.AddressSize])
+		if err != nil {
+			return err
+		}
+		hash, err := types.BytesToHash(key[1+types.AddressSize:])
+		if err != nil {
+			return err
+		}
+		if send, ok := v.pending[hash]; !ok || send.toAddr != toAddr {
+			v.addIssue(IssueOnRoad, hash.String(), true, "the onroad of %s is received or not existed", toAddr)
+			v.fix(key, nil)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for hash, send := range v.pending {
+		key := chain_utils.CreateOnRoadKey(send.toAddr, hash).Bytes()
+		ok, err := v.hasIndex(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.addIssue(IssueOnRoad, hash.String(), true, "the onroad of %s is missing", send.toAddr)
+			v.fix(key, []byte{})
+		}
+
+		hash := hash
+		if ok, err := v.expectIndex(chain_utils.CreateReceiveKey(&hash).Bytes(), unreceivedFlag); err != nil {
+			return err
+		} else if !ok {
+			v.addIssue(IssueReceive, hash.String(), true, "the send block is not received, but the receive index is missing or wrong")
+		}
+	}
+	return nil
+}
+
+// checkReceives deletes the receive indexes of the send blocks which are not existed
+func (v *Verifier) checkReceives() error {
+	return v.iterate(chain_utils.ReceiveKeyPrefix, func(key, value []byte) error {
+		ok, err := v.hasIndex(append([]byte{chain_utils.AccountBlockHashKeyPrefix}, key[1:]...))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.addIssue(IssueReceive, fmt.Sprintf("%x", key[1:]), true, "the receive index belongs to no send block")
+			v.fix(key, nil)
+		}
+		return nil
+	})
+}
+
+// checkBalances compares the balances of the user accounts in the state with the balances recomputed from blocks
+func (v *Verifier) checkBalances() error {
+	for addr, account := range v.accounts {
+		if account.balances == nil {
+			continue
+		}
+		v.report.CheckedBalances++
+
+		stored := make(map[types.TokenTypeId]*big.Int)
+		iter := v.stateStore.NewIterator(util.BytesPrefix(chain_utils.CreateBalanceKeyPrefix(addr)))
+		for iter.Next() {
+			tokenId, err := types.BytesToTokenTypeId(iter.Key()[1+types.AddressSize:])
+			if err != nil {
+				continue
+			}
+			stored[tokenId] = new(big.Int).SetBytes(iter.Value())
+		}
+		err := iter.Error()
+		iter.Release()
+		if err != nil {
+			return err
+		}
+
+		for tokenId, balance := range account.balances {
+			storedBalance, ok := stored[tokenId]
+			if !ok {
+				storedBalance = big.NewInt(0)
+			}
+			if storedBalance.Cmp(balance) != 0 {
+				v.addIssue(IssueBalance, fmt.Sprintf("%s-%s", addr, tokenId), false, "the balance in the state is %s, the balance computed from blocks is %s", storedBalance, balance)
+			}
+			delete(stored, tokenId)
+		}
+		for tokenId, storedBalance := range stored {
+			if storedBalance.Sign() != 0 {
+				v.addIssue(IssueBalance, fmt.Sprintf("%s-%s", addr, tokenId), false, "the balance in the state is %s, the balance computed from blocks is 0", storedBalance)
+			}
+		}
+	}
+	return nil
+}
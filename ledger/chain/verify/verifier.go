@@ -0,0 +1,225 @@
+package chain_verify
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_block "github.com/vitelabs/go-vite/ledger/chain/block"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	IssueBlockFile     = "blockFile"
+	IssueSnapshotIndex = "snapshotBlockIndex"
+	IssueAccountIndex  = "accountBlockIndex"
+	IssueAccount       = "account"
+	IssueConfirmHeight = "confirmHeight"
+	IssueReceive       = "receive"
+	IssueOnRoad        = "onRoad"
+	IssueBalance       = "balance"
+)
+
+var ErrUnfinishedFlush = errors.New("the flusher redo log is not empty, start the node once to recover the ledger before verifying")
+
+// Issue is an inconsistency found by the Verifier
+type Issue struct {
+	Type    string `json:"type"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	// the issue can be fixed by rebuilding the index
+	Repairable bool `json:"repairable"`
+}
+
+type Report struct {
+	SnapshotBlocks  uint64   `json:"snapshotBlocks"`
+	AccountBlocks   uint64   `json:"accountBlocks"`
+	Accounts        uint64   `json:"accounts"`
+	OnRoadBlocks    uint64   `json:"onRoadBlocks"`
+	CheckedBalances uint64   `json:"checkedBalances"`
+	Issues          []*Issue `json:"issues"`
+	// the number of the index keys which are rewritten or deleted, only in repair mode
+	RepairedKeys uint64 `json:"repairedKeys"`
+}
+
+type accountState struct {
+	height uint64
+	hash   types.Hash
+
+	// balances recomputed from blocks, nil if the account is a contract or the balances can't be recomputed
+	balances map[types.TokenTypeId]*big.Int
+}
+
+type pendingSend struct {
+	toAddr  types.Address
+	tokenId types.TokenTypeId
+	amount  *big.Int
+}
+
+// Verifier checks the ledger data directory offline. It walks all blocks in the block files, checks the
+// indexes of every block, the account heights, the onroad set and the balances of the user accounts against
+// the data recomputed from the blocks. The node must be stopped while verifying.
+type Verifier struct {
+	chainDir string
+
+	blockDB    *chain_block.BlockDB
+	indexStore *chain_db.Store
+	stateStore *chain_db.Store
+
+	report *Report
+
+	latestSb *ledger.SnapshotBlock
+	accounts map[types.Address]*accountState
+	pending  map[types.Hash]*pendingSend
+
+	// the index keys to rewrite, a nil value means deleting
+	fixes    map[string][]byte
+	fixOrder []string
+
+	log log15.Logger
+}
+
+func NewVerifier(chainDir string, chainCfg *config.Chain) (*Verifier, error) {
+	if _, err := os.Stat(chainDir); err != nil {
+		return nil, err
+	}
+	if fileInfo, err := os.Stat(path.Join(chainDir, "flush.redo.log")); err == nil && fileInfo.Size() > 0 {
+		return nil, ErrUnfinishedFlush
+	}
+
+	blockDB, err := chain_block.NewBlockDB(chainDir)
+	if err != nil {
+		return nil, err
+	}
+	indexStore, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "index"), "indexDb", chainCfg.GetStoreBackend(config.StoreIndex))
+	if err != nil {
+		blockDB.Close()
+		return nil, err
+	}
+	stateStore, err := chain_db.NewStoreWithBackend(path.Join(chainDir, "state"), "stateDb", chainCfg.GetStoreBackend(config.StoreState))
+	if err != nil {
+		blockDB.Close()
+		indexStore.Close()
+		return nil, err
+	}
+
+	return &Verifier{
+		chainDir:   chainDir,
+		blockDB:    blockDB,
+		indexStore: indexStore,
+		stateStore: stateStore,
+		log:        log15.New("module", "chain_verify"),
+	}, nil
+}
+
+// Verify checks the data directory and returns the report. If repair is true, the broken indexes are rebuilt.
+func (v *Verifier) Verify(repair bool) (*Report, error) {
+	v.report = &Report{Issues: make([]*Issue, 0)}
+	v.latestSb = nil
+	v.accounts = make(map[types.Address]*accountState)
+	v.pending = make(map[types.Hash]*pendingSend)
+	v.fixes = make(map[string][]byte)
+	v.fixOrder = nil
+
+	steps := []struct {
+		name string
+		f    func() error
+	}{
+		{"walk blocks", v.walkBlocks},
+		{"check accounts", v.checkAccounts},
+		{"check account block indexes", v.checkAccountBlockIndexes},
+		{"check snapshot block indexes", v.checkSnapshotBlockIndexes},
+		{"check confirm heights", v.checkConfirmHeights},
+		{"check onroad", v.checkOnRoad},
+		{"check receives", v.checkReceives},
+		{"check balances", v.checkBalances},
+	}
+	for _, step := range steps {
+		v.log.Info(fmt.Sprintf("start %s", step.name), "method", "Verify")
+		if err := step.f(); err != nil {
+			return nil, fmt.Errorf("%s failed. Error: %s", step.name, err)
+		}
+	}
+
+	v.report.Accounts = uint64(len(v.accounts))
+	v.report.OnRoadBlocks = uint64(len(v.pending))
+
+	if repair && len(v.fixOrder) > 0 {
+		if err := v.writeFixes(); err != nil {
+			return nil, fmt.Errorf("repair failed. Error: %s", err)
+		}
+		v.report.RepairedKeys = uint64(len(v.fixOrder))
+	}
+	return v.report, nil
+}
+
+func (v *Verifier) Close() error {
+	v.blockDB.Close()
+	v.stateStore.Close()
+	return v.indexStore.Close()
+}
+
+func (v *Verifier) addIssue(issueType string, key string, repairable bool, format string, args ...interface{}) {
+	v.report.Issues = append(v.report.Issues, &Issue{
+		Type:       issueType,
+		Key:        key,
+		Message:    fmt.Sprintf(format, args...),
+		Repairable: repairable,
+	})
+}
+
+// getIndex reads the index store with the pending fixes applied
+func (v *Verifier) getIndex(key []byte) ([]byte, error) {
+	if value, ok := v.fixes[string(key)]; ok {
+		return value, nil
+	}
+	return v.indexStore.Get(key)
+}
+
+// expectIndex checks the value of the key, records a fix and returns false if the value is different
+func (v *Verifier) expectIndex(key []byte, value []byte) (bool, error) {
+	current, err := v.getIndex(key)
+	if err != nil {
+		return false, err
+	}
+	if current != nil && string(current) == string(value) {
+		return true, nil
+	}
+	v.fix(key, value)
+	return false, nil
+}
+
+func (v *Verifier) fix(key []byte, value []byte) {
+	strKey := string(key)
+	if _, ok := v.fixes[strKey]; !ok {
+		v.fixOrder = append(v.fixOrder, strKey)
+	}
+	v.fixes[strKey] = value
+}
+
+func (v *Verifier) writeFixes() error {
+	batch := new(leveldb.Batch)
+	for _, key := range v.fixOrder {
+		if value := v.fixes[key]; value != nil {
+			batch.Put([]byte(key), value)
+		} else {
+			batch.Delete([]byte(key))
+		}
+	}
+
+	v.indexStore.WriteDirectly(batch)
+	v.indexStore.Prepare()
+	if err := v.indexStore.Commit(); err != nil {
+		v.indexStore.CancelPrepare()
+		return err
+	}
+	v.indexStore.AfterCommit()
+	return nil
+}
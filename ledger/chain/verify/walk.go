@@ -0,0 +1,237 @@
+package chain_verify
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb/util"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_file_manager "github.com/vitelabs/go-vite/ledger/chain/file_manager"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+var unreceivedFlag = []byte{0}
+
+// walkBlocks reads all blocks from the block files in order, and checks the indexes of every block
+func (v *Verifier) walkBlocks() error {
+	location := chain_file_manager.NewLocation(1, 0)
+	latestLocation := v.blockDB.LatestLocation()
+
+	var blocks []*ledger.AccountBlock
+	var locations []*chain_file_manager.Location
+
+	for location.Compare(latestLocation) < 0 {
+		sb, ab, next, err := v.blockDB.ReadUnit(location)
+		if err != nil {
+			v.addIssue(IssueBlockFile, location.String(), false, "read block failed, the rest of the block files are not verified. Error: %s", err)
+			return nil
+		}
+		if ab != nil {
+			blocks = append(blocks, ab)
+			locations = append(locations, location)
+		} else if sb != nil {
+			if err := v.checkChunk(sb, location, blocks, locations); err != nil {
+				return err
+			}
+			blocks, locations = nil, nil
+		} else {
+			v.addIssue(IssueBlockFile, location.String(), false, "unknown data in the block files, the rest of the block files are not verified")
+			return nil
+		}
+		location = next
+	}
+
+	if len(blocks) > 0 {
+		v.addIssue(IssueBlockFile, locations[0].String(), false, "%d account blocks at the end of the block files are not confirmed by a snapshot block", len(blocks))
+	}
+	return nil
+}
+
+func (v *Verifier) checkChunk(sb *ledger.SnapshotBlock, sbLocation *chain_file_manager.Location, blocks []*ledger.AccountBlock, locations []*chain_file_manager.Location) error {
+	v.report.SnapshotBlocks++
+	if sb.Height%10000 == 0 {
+		v.log.Info(fmt.Sprintf("walk to snapshot block %d", sb.Height), "method", "walkBlocks")
+	}
+
+	if sb.Hash != sb.ComputeHash() {
+		v.addIssue(IssueBlockFile, sb.Hash.String(), false, "the hash of snapshot block %d is not equal to the computed hash %s", sb.Height, sb.ComputeHash())
+	}
+	if v.latestSb != nil && (sb.Height != v.latestSb.Height+1 || sb.PrevHash != v.latestSb.Hash) {
+		v.addIssue(IssueBlockFile, sb.Hash.String(), false, "snapshot block %d is not linked to the previous snapshot block %d %s", sb.Height, v.latestSb.Height, v.latestSb.Hash)
+	}
+	v.latestSb = sb
+
+	// snapshot block indexes
+	if ok, err := v.expectIndex(chain_utils.CreateSnapshotBlockHashKey(&sb.Hash).Bytes(), chain_utils.Uint64ToBytes(sb.Height)); err != nil {
+		return err
+	} else if !ok {
+		v.addIssue(IssueSnapshotIndex, sb.Hash.String(), true, "the hash index of snapshot block %d is missing or wrong", sb.Height)
+	}
+	heightValue := append(sb.Hash.Bytes(), chain_utils.SerializeLocation(sbLocation)...)
+	if ok, err := v.expectIndex(chain_utils.CreateSnapshotBlockHeightKey(sb.Height).Bytes(), heightValue); err != nil {
+		return err
+	} else if !ok {
+		v.addIssue(IssueSnapshotIndex, sb.Hash.String(), true, "the height index of snapshot block %d is missing or points to a wrong location", sb.Height)
+	}
+
+	for i, block := range blocks {
+		if err := v.checkAccountBlock(block, locations[i], sb.Height); err != nil {
+			return err
+		}
+	}
+
+	// confirmed heights
+	heightBytes := chain_utils.Uint64ToBytes(sb.Height)
+	for addr, hashHeight := range sb.SnapshotContent {
+		account := v.accounts[addr]
+		if account == nil || account.height != hashHeight.Height || account.hash != hashHeight.Hash {
+			v.addIssue(IssueBlockFile, sb.Hash.String(), false, "snapshot block %d confirms %s %d %s, which is not the latest block of the account in the block files",
+				sb.Height, addr, hashHeight.Height, hashHeight.Hash)
+			continue
+		}
+
+		if ok, err := v.expectIndex(chain_utils.CreateConfirmHeightKey(&addr, hashHeight.Height).Bytes(), heightBytes); err != nil {
+			return err
+		} else if !ok {
+			v.addIssue(IssueConfirmHeight, fmt.Sprintf("%s-%d", addr, hashHeight.Height), true, "the confirm height is missing or wrong, should be %d", sb.Height)
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) checkAccountBlock(block *ledger.AccountBlock, location *chain_file_manager.Location, sbHeight uint64) error {
+	v.report.AccountBlocks++
+	addr := block.AccountAddress
+
+	// the data of the pruned blocks is deleted, the hash can't be computed
+	if !v.blockDB.IsPruned(location) && !(block.IsSendBlock() && block.Height == 0 && block.PrevHash.IsZero()) && block.Hash != block.ComputeHash() {
+		v.addIssue(IssueBlockFile, block.Hash.String(), false, "the hash of account block %s %d is not equal to the computed hash %s", addr, block.Height, block.ComputeHash())
+	}
+
+	// account chain
+	account := v.accounts[addr]
+	if account == nil {
+		account = &accountState{}
+		if !types.IsContractAddr(addr) {
+			account.balances = make(map[types.TokenTypeId]*big.Int)
+		}
+		v.accounts[addr] = account
+	}
+	if block.Height != account.height+1 || block.PrevHash != account.hash {
+		v.addIssue(IssueBlockFile, block.Hash.String(), false, "account block %s %d is not linked to the previous block %d %s", addr, block.Height, account.height, account.hash)
+	}
+	account.height = block.Height
+	account.hash = block.Hash
+
+	// account block indexes
+	addrHeightValue := append(addr.Bytes(), chain_utils.Uint64ToBytes(block.Height)...)
+	if ok, err := v.expectIndex(chain_utils.CreateAccountBlockHashKey(&block.Hash).Bytes(), addrHeightValue); err != nil {
+		return err
+	} else if !ok {
+		v.addIssue(IssueAccountIndex, block.Hash.String(), true, "the hash index of account block %s %d is missing or wrong", addr, block.Height)
+	}
+	heightValue := append(block.Hash.Bytes(), chain_utils.SerializeLocation(location)...)
+	if ok, err := v.expectIndex(chain_utils.CreateAccountBlockHeightKey(&addr, block.Height).Bytes(), heightValue); err != nil {
+		return err
+	} else if !ok {
+		v.addIssue(IssueAccountIndex, block.Hash.String(), true, "the height index of account block %s %d is missing or points to a wrong location", addr, block.Height)
+	}
+	for _, sendBlock := range block.SendBlockList {
+		if ok, err := v.expectIndex(chain_utils.CreateAccountBlockHashKey(&sendBlock.Hash).Bytes(), addrHeightValue); err != nil {
+			return err
+		} else if !ok {
+			v.addIssue(IssueAccountIndex, sendBlock.Hash.String(), true, "the hash index of send block in account block %s %d is missing or wrong", addr, block.Height)
+		}
+	}
+
+	// receive and send
+	if block.BlockType == ledger.BlockTypeGenesisReceive {
+		if err := v.seedGenesisBalances(addr, account, sbHeight); err != nil {
+			return err
+		}
+	} else if block.IsReceiveBlock() {
+		send, ok := v.pending[block.FromBlockHash]
+		if !ok || send.toAddr != addr {
+			v.addIssue(IssueBlockFile, block.Hash.String(), false, "account block %s %d receives %s, which is not an unreceived send block to the account", addr, block.Height, block.FromBlockHash)
+			account.balances = nil
+		} else {
+			delete(v.pending, block.FromBlockHash)
+			addBalance(account, send.tokenId, send.amount)
+		}
+
+		if ok, err := v.expectIndex(chain_utils.CreateReceiveKey(&block.FromBlockHash).Bytes(), block.Hash.Bytes()); err != nil {
+			return err
+		} else if !ok {
+			v.addIssue(IssueReceive, block.FromBlockHash.String(), true, "the receive index of send block is missing or wrong, should be %s", block.Hash)
+		}
+	} else {
+		v.addPending(block)
+		subBalance(account, block.TokenId, block.Amount)
+	}
+
+	for _, sendBlock := range block.SendBlockList {
+		v.addPending(sendBlock)
+		subBalance(account, sendBlock.TokenId, sendBlock.Amount)
+	}
+	subBalance(account, ledger.ViteTokenId, block.Fee)
+	return nil
+}
+
+func (v *Verifier) addPending(sendBlock *ledger.AccountBlock) {
+	amount := sendBlock.Amount
+	if amount == nil {
+		amount = big.NewInt(0)
+	}
+	v.pending[sendBlock.Hash] = &pendingSend{
+		toAddr:  sendBlock.ToAddress,
+		tokenId: sendBlock.TokenId,
+		amount:  amount,
+	}
+}
+
+// seedGenesisBalances loads the balances of a genesis account from the balance history of the genesis snapshot block
+func (v *Verifier) seedGenesisBalances(addr types.Address, account *accountState, sbHeight uint64) error {
+	if account.balances == nil {
+		return nil
+	}
+	iter := v.stateStore.NewIterator(util.BytesPrefix(append([]byte{chain_utils.BalanceHistoryKeyPrefix}, addr.Bytes()...)))
+	defer iter.Release()
+
+	found := false
+	for iter.Next() {
+		key := chain_utils.BalanceHistoryKey{}.Construct(iter.Key())
+		if key == nil || key.ExtraHeight() != sbHeight {
+			continue
+		}
+		found = true
+		addBalance(account, key.ExtraTokenId(), new(big.Int).SetBytes(iter.Value()))
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if !found {
+		// the genesis balances are unknown
+		account.balances = nil
+	}
+	return nil
+}
+
+func addBalance(account *accountState, tokenId types.TokenTypeId, amount *big.Int) {
+	if account.balances == nil || amount == nil {
+		return
+	}
+	balance, ok := account.balances[tokenId]
+	if !ok {
+		balance = new(big.Int)
+		account.balances[tokenId] = balance
+	}
+	balance.Add(balance, amount)
+}
+
+func subBalance(account *accountState, tokenId types.TokenTypeId, amount *big.Int) {
+	if amount == nil || amount.Sign() == 0 {
+		return
+	}
+	addBalance(account, tokenId, new(big.Int).Neg(amount))
+}
@@ -0,0 +1,237 @@
+package chain_verify
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/db/xleveldb"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	chain_block "github.com/vitelabs/go-vite/ledger/chain/block"
+	chain_db "github.com/vitelabs/go-vite/ledger/chain/db"
+	chain_utils "github.com/vitelabs/go-vite/ledger/chain/utils"
+)
+
+func TestBalance(t *testing.T) {
+	account := &accountState{balances: make(map[types.TokenTypeId]*big.Int)}
+
+	addBalance(account, ledger.ViteTokenId, big.NewInt(100))
+	subBalance(account, ledger.ViteTokenId, big.NewInt(30))
+	subBalance(account, ledger.ViteTokenId, nil)
+	assert.Equal(t, int64(70), account.balances[ledger.ViteTokenId].Int64())
+
+	// the balances of the account can't be recomputed
+	account.balances = nil
+	addBalance(account, ledger.ViteTokenId, big.NewInt(100))
+	assert.Nil(t, account.balances)
+}
+
+type testChain struct {
+	dir string
+
+	alice types.Address
+	bob   types.Address
+
+	// the send block of alice, received by bob in the second snapshot block
+	send *ledger.AccountBlock
+}
+
+// newTestChain writes a small chain into dir: alice receives 100 vite in the genesis snapshot block,
+// sends 30 vite to bob, and bob receives it. The indexes are built by repairing the empty index store.
+func newTestChain(t *testing.T) *testChain {
+	dir, err := ioutil.TempDir("", "chain_verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &testChain{dir: dir}
+	tc.alice, _ = types.BytesToAddress(append(make([]byte, types.AddressSize-2), 1, 0))
+	tc.bob, _ = types.BytesToAddress(append(make([]byte, types.AddressSize-2), 2, 0))
+
+	genesisReceive := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeGenesisReceive,
+		AccountAddress: tc.alice,
+		Height:         1,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+	}
+	genesisReceive.Hash = genesisReceive.ComputeHash()
+
+	tc.send = &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeSendCall,
+		AccountAddress: tc.alice,
+		ToAddress:      tc.bob,
+		Height:         2,
+		PrevHash:       genesisReceive.Hash,
+		TokenId:        ledger.ViteTokenId,
+		Amount:         big.NewInt(30),
+		Fee:            big.NewInt(0),
+	}
+	tc.send.Hash = tc.send.ComputeHash()
+
+	receive := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: tc.bob,
+		Height:         1,
+		FromBlockHash:  tc.send.Hash,
+		Amount:         big.NewInt(0),
+		Fee:            big.NewInt(0),
+	}
+	receive.Hash = receive.ComputeHash()
+
+	now := time.Unix(1600000000, 0)
+	sb1 := &ledger.SnapshotBlock{
+		Height:    1,
+		Timestamp: &now,
+		SnapshotContent: ledger.SnapshotContent{
+			tc.alice: {Height: 2, Hash: tc.send.Hash},
+		},
+	}
+	sb1.Hash = sb1.ComputeHash()
+	sb2 := &ledger.SnapshotBlock{
+		Height:    2,
+		PrevHash:  sb1.Hash,
+		Timestamp: &now,
+		SnapshotContent: ledger.SnapshotContent{
+			tc.bob: {Height: 1, Hash: receive.Hash},
+		},
+	}
+	sb2.Hash = sb2.ComputeHash()
+
+	blockDB, err := chain_block.NewBlockDB(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range []*ledger.SnapshotChunk{
+		{SnapshotBlock: sb1, AccountBlocks: []*ledger.AccountBlock{genesisReceive, tc.send}},
+		{SnapshotBlock: sb2, AccountBlocks: []*ledger.AccountBlock{receive}},
+	} {
+		if _, _, err := blockDB.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blockDB.Prepare()
+	if err := blockDB.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	blockDB.AfterCommit()
+	assert.NoError(t, blockDB.Close())
+
+	batch := new(leveldb.Batch)
+	batch.Put(chain_utils.CreateHistoryBalanceKey(tc.alice, ledger.ViteTokenId, 1).Bytes(), big.NewInt(100).Bytes())
+	batch.Put(chain_utils.CreateBalanceKey(tc.alice, ledger.ViteTokenId).Bytes(), big.NewInt(70).Bytes())
+	batch.Put(chain_utils.CreateBalanceKey(tc.bob, ledger.ViteTokenId).Bytes(), big.NewInt(30).Bytes())
+	tc.writeStore(t, "state", "stateDb", batch)
+
+	report := tc.verify(t, true)
+	assert.True(t, report.RepairedKeys > 0)
+	for _, issue := range report.Issues {
+		assert.True(t, issue.Repairable, issue.Message)
+	}
+	return tc
+}
+
+func (tc *testChain) writeStore(t *testing.T, dirName string, name string, batch *leveldb.Batch) {
+	store, err := chain_db.NewStore(path.Join(tc.dir, dirName), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.WriteDirectly(batch)
+	store.Prepare()
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	store.AfterCommit()
+	assert.NoError(t, store.Close())
+}
+
+func (tc *testChain) verify(t *testing.T, repair bool) *Report {
+	verifier, err := NewVerifier(tc.dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer verifier.Close()
+
+	report, err := verifier.Verify(repair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func issueTypes(report *Report) []string {
+	var result []string
+	for _, issue := range report.Issues {
+		result = append(result, issue.Type)
+	}
+	return result
+}
+
+func TestVerify(t *testing.T) {
+	upgrade.InitUpgradeBox(upgrade.NewLatestUpgradeBox())
+	defer upgrade.CleanupUpgradeBox(t)
+
+	tc := newTestChain(t)
+	defer os.RemoveAll(tc.dir)
+
+	report := tc.verify(t, false)
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, uint64(2), report.SnapshotBlocks)
+	assert.Equal(t, uint64(3), report.AccountBlocks)
+	assert.Equal(t, uint64(2), report.Accounts)
+	assert.Equal(t, uint64(0), report.OnRoadBlocks)
+	assert.Equal(t, uint64(2), report.CheckedBalances)
+}
+
+func TestVerify_RepairIndex(t *testing.T) {
+	upgrade.InitUpgradeBox(upgrade.NewLatestUpgradeBox())
+	defer upgrade.CleanupUpgradeBox(t)
+
+	tc := newTestChain(t)
+	defer os.RemoveAll(tc.dir)
+
+	// the hash index of the send block points to a wrong height, and the send block is still onroad
+	batch := new(leveldb.Batch)
+	batch.Put(chain_utils.CreateAccountBlockHashKey(&tc.send.Hash).Bytes(), append(tc.alice.Bytes(), chain_utils.Uint64ToBytes(1)...))
+	batch.Put(chain_utils.CreateOnRoadKey(tc.bob, tc.send.Hash).Bytes(), []byte{})
+	tc.writeStore(t, "index", "indexDb", batch)
+
+	report := tc.verify(t, false)
+	assert.ElementsMatch(t, []string{IssueAccountIndex, IssueOnRoad}, issueTypes(report))
+	assert.Equal(t, uint64(0), report.RepairedKeys)
+	// verifying without repair doesn't change the index
+	assert.Equal(t, 2, len(tc.verify(t, false).Issues))
+
+	report = tc.verify(t, true)
+	assert.Equal(t, 2, len(report.Issues))
+	assert.Equal(t, uint64(2), report.RepairedKeys)
+
+	assert.Empty(t, tc.verify(t, false).Issues)
+}
+
+func TestVerify_Balance(t *testing.T) {
+	upgrade.InitUpgradeBox(upgrade.NewLatestUpgradeBox())
+	defer upgrade.CleanupUpgradeBox(t)
+
+	tc := newTestChain(t)
+	defer os.RemoveAll(tc.dir)
+
+	batch := new(leveldb.Batch)
+	batch.Put(chain_utils.CreateBalanceKey(tc.bob, ledger.ViteTokenId).Bytes(), big.NewInt(31).Bytes())
+	tc.writeStore(t, "state", "stateDb", batch)
+
+	report := tc.verify(t, false)
+	assert.Equal(t, []string{IssueBalance}, issueTypes(report))
+	assert.False(t, report.Issues[0].Repairable)
+
+	// the balance isn't an index, repairing leaves it for the operator
+	report = tc.verify(t, true)
+	assert.Equal(t, []string{IssueBalance}, issueTypes(report))
+	assert.Equal(t, uint64(0), report.RepairedKeys)
+}
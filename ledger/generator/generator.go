@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -44,8 +45,14 @@ type generator struct {
 // the third "addr" needs to be filled with the address of the account chain to be blocked,
 // and the last needs to be filled with the previous/latest block's hash on the account chain.
 func NewGenerator(chain vm_db.Chain, consensus Consensus, addr types.Address, latestSnapshotBlockHash, prevBlockHash *types.Hash) (interfaces.Generator, error) {
+	return NewGeneratorWithContext(context.Background(), chain, consensus, addr, latestSnapshotBlockHash, prevBlockHash)
+}
+
+// NewGeneratorWithContext is the same as NewGenerator, except that the logs of the generator carry the trace id of
+// the ctx, so a block generated for a rpc request can be followed through its logs.
+func NewGeneratorWithContext(ctx context.Context, chain vm_db.Chain, consensus Consensus, addr types.Address, latestSnapshotBlockHash, prevBlockHash *types.Hash) (interfaces.Generator, error) {
 	gen := &generator{
-		log: log15.New("module", "Generator"),
+		log: log15.FromContext(ctx, log15.New("module", "Generator")),
 	}
 	gen.chain = chain
 
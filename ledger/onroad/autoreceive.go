@@ -0,0 +1,228 @@
+package onroad
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+const autoReceiveKeyPrefix = byte(1)
+
+var (
+	errAutoReceiveNotInit = errors.New("auto receive is not initialized")
+	errAutoReceiveLocked  = errors.New("the address is not unlocked in the wallet")
+)
+
+// AccountFinder returns the unlocked account of the address
+type AccountFinder func(addr types.Address) (interfaces.Account, error)
+
+// AutoReceiveFilter decides which onroad blocks are received, the others stay onroad
+type AutoReceiveFilter struct {
+	// the min amount of the send blocks, all amounts are received if nil
+	MinAmount *big.Int `json:"minAmount"`
+	// the whitelist of the tokens, all tokens are received if empty
+	TokenIds []types.TokenTypeId `json:"tokenIds"`
+}
+
+// Match returns whether the send block passes the filter
+func (f *AutoReceiveFilter) Match(sendBlock *ledger.AccountBlock) bool {
+	if f.MinAmount != nil && f.MinAmount.Sign() > 0 {
+		if sendBlock.Amount == nil || sendBlock.Amount.Cmp(f.MinAmount) < 0 {
+			return false
+		}
+	}
+	if len(f.TokenIds) == 0 {
+		return true
+	}
+	for _, tokenId := range f.TokenIds {
+		if tokenId == sendBlock.TokenId {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoReceiveConfig is the config of the auto receive worker of an address, it is saved until the worker is stopped
+// by StopAutoReceive. The worker is resumed whenever the address is unlocked in the wallet.
+type AutoReceiveConfig struct {
+	Address types.Address     `json:"address"`
+	Filter  AutoReceiveFilter `json:"filter"`
+	// calculate the PoW for the receive blocks if the stake quota is not enough
+	PoW bool `json:"pow"`
+}
+
+// InitAutoReceive loads the configs of the auto receive workers from the db. findAccount returns the unlocked
+// account to sign the receive blocks.
+func (manager *Manager) InitAutoReceive(db *leveldb.DB, findAccount AccountFinder) error {
+	manager.autoReceiveMu.Lock()
+	defer manager.autoReceiveMu.Unlock()
+
+	configs := make(map[types.Address]*AutoReceiveConfig)
+	iter := db.NewIterator(util.BytesPrefix([]byte{autoReceiveKeyPrefix}), nil)
+	defer iter.Release()
+	for iter.Next() {
+		cfg := &AutoReceiveConfig{}
+		if err := json.Unmarshal(iter.Value(), cfg); err != nil {
+			return err
+		}
+		configs[cfg.Address] = cfg
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	manager.autoReceiveDb = db
+	manager.findAccount = findAccount
+	manager.autoReceiveConfigs = configs
+	return nil
+}
+
+// StartAutoReceive saves the config and starts the auto receive worker of the address, the worker is restarted with
+// the new config if it is running. The address must be unlocked.
+func (manager *Manager) StartAutoReceive(cfg AutoReceiveConfig) error {
+	manager.autoReceiveMu.Lock()
+	defer manager.autoReceiveMu.Unlock()
+	if manager.autoReceiveDb == nil {
+		return errAutoReceiveNotInit
+	}
+
+	account, err := manager.findAccount(cfg.Address)
+	if err != nil || account == nil {
+		return errAutoReceiveLocked
+	}
+
+	value, err := json.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	if err := manager.autoReceiveDb.Put(createAutoReceiveKey(cfg.Address), value, nil); err != nil {
+		return err
+	}
+	manager.autoReceiveConfigs[cfg.Address] = &cfg
+
+	if w, ok := manager.autoReceiveWorkers.Load(cfg.Address); ok {
+		w.(*AutoReceiveWorker).Stop()
+	}
+	w := NewAutoReceiveWorker(manager, account, cfg)
+	manager.autoReceiveWorkers.Store(cfg.Address, w)
+	w.Start()
+	return nil
+}
+
+// StopAutoReceive stops the auto receive worker of the address and deletes its config
+func (manager *Manager) StopAutoReceive(addr types.Address) error {
+	manager.autoReceiveMu.Lock()
+	defer manager.autoReceiveMu.Unlock()
+	if manager.autoReceiveDb == nil {
+		return errAutoReceiveNotInit
+	}
+
+	if err := manager.autoReceiveDb.Delete(createAutoReceiveKey(addr), nil); err != nil {
+		return err
+	}
+	delete(manager.autoReceiveConfigs, addr)
+
+	if w, ok := manager.autoReceiveWorkers.Load(addr); ok {
+		w.(*AutoReceiveWorker).Stop()
+		manager.autoReceiveWorkers.Delete(addr)
+	}
+	return nil
+}
+
+// ListAutoReceiveWorkers returns the status of all configured auto receive workers, including the stopped ones
+// whose addresses are locked.
+func (manager *Manager) ListAutoReceiveWorkers() []*AutoReceiveStatus {
+	manager.autoReceiveMu.Lock()
+	defer manager.autoReceiveMu.Unlock()
+
+	list := make([]*AutoReceiveStatus, 0, len(manager.autoReceiveConfigs))
+	for addr, cfg := range manager.autoReceiveConfigs {
+		if w, ok := manager.autoReceiveWorkers.Load(addr); ok {
+			list = append(list, w.(*AutoReceiveWorker).Status())
+			continue
+		}
+		list = append(list, &AutoReceiveStatus{Config: *cfg})
+	}
+	return list
+}
+
+// ResumeAutoReceive starts the workers of the unlocked addresses and stops the workers of the locked ones,
+// it is called when the wallet is locked or unlocked.
+func (manager *Manager) ResumeAutoReceive() {
+	manager.autoReceiveMu.Lock()
+	defer manager.autoReceiveMu.Unlock()
+	if manager.autoReceiveDb == nil {
+		return
+	}
+
+	for addr, cfg := range manager.autoReceiveConfigs {
+		account, err := manager.findAccount(addr)
+		unlocked := err == nil && account != nil
+		w, running := manager.autoReceiveWorkers.Load(addr)
+		if unlocked && !running {
+			worker := NewAutoReceiveWorker(manager, account, *cfg)
+			manager.autoReceiveWorkers.Store(addr, worker)
+			manager.log.Info("resume auto receive", "addr", addr)
+			worker.Start()
+		} else if !unlocked && running {
+			manager.log.Info("pause auto receive, the address is locked", "addr", addr)
+			w.(*AutoReceiveWorker).Stop()
+			manager.autoReceiveWorkers.Delete(addr)
+		}
+	}
+}
+
+func (manager *Manager) stopAutoReceive() {
+	manager.autoReceiveMu.Lock()
+	defer manager.autoReceiveMu.Unlock()
+	if manager.autoReceiveDb == nil {
+		return
+	}
+
+	var wg = sync.WaitGroup{}
+	manager.autoReceiveWorkers.Range(func(key, value interface{}) bool {
+		wg.Add(1)
+		worker := value.(*AutoReceiveWorker)
+		common.Go(func() {
+			worker.Stop()
+			wg.Done()
+		})
+		manager.autoReceiveWorkers.Delete(key)
+		return true
+	})
+	wg.Wait()
+
+	if err := manager.autoReceiveDb.Close(); err != nil {
+		manager.log.Error(fmt.Sprintf("close auto receive db failed, err:%v", err))
+	}
+	manager.autoReceiveDb = nil
+}
+
+// newAutoReceiveSignal wakes up the auto receive worker of the address, or all workers if addr is nil.
+// It is called by the chain events while the workers may be inserting blocks, so autoReceiveMu is not held.
+func (manager *Manager) newAutoReceiveSignal(addr *types.Address) {
+	if addr == nil {
+		manager.autoReceiveWorkers.Range(func(key, value interface{}) bool {
+			value.(*AutoReceiveWorker).wakeup()
+			return true
+		})
+		return
+	}
+	if w, ok := manager.autoReceiveWorkers.Load(*addr); ok {
+		w.(*AutoReceiveWorker).wakeup()
+	}
+}
+
+func createAutoReceiveKey(addr types.Address) []byte {
+	return append([]byte{autoReceiveKeyPrefix}, addr.Bytes()...)
+}
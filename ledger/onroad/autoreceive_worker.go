@@ -0,0 +1,281 @@
+package onroad
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/generator"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/net"
+	"github.com/vitelabs/go-vite/vm"
+	"github.com/vitelabs/go-vite/vm/quota"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+const (
+	// the interval of checking the onroad blocks if no new block arrives
+	autoReceiveInterval = time.Second
+	autoReceivePageSize = 100
+	// the max number of the onroad blocks scanned in one round, the next round continues after the last scanned block,
+	// so the blocks rejected by the filter are scanned again only after the end of the onroad list is reached
+	autoReceiveMaxScan = 1000
+)
+
+var errAutoReceiveQuotaNotEnough = errors.New("stake quota is not enough and PoW is disabled")
+
+// AutoReceiveStatus is the status of an auto receive worker
+type AutoReceiveStatus struct {
+	Config  AutoReceiveConfig `json:"config"`
+	Running bool              `json:"running"`
+
+	Received        uint64     `json:"received"`
+	LastReceiveTime *time.Time `json:"lastReceiveTime"`
+	LastError       string     `json:"lastError"`
+}
+
+// AutoReceiveWorker receives the onroad blocks of an unlocked normal account, it uses PoW when the quota is short
+// if enabled by the config.
+type AutoReceiveWorker struct {
+	manager *Manager
+	account interfaces.Account
+	config  AutoReceiveConfig
+
+	status      int
+	statusMutex sync.Mutex
+
+	isCancel     *atomic.Bool
+	newBlockCond *common.TimeoutCond
+	wg           sync.WaitGroup
+
+	// scanCursor is the hash of the last onroad block scanned by the last round, nil to scan from the first one.
+	// It is only used by the work goroutine.
+	scanCursor *types.Hash
+
+	statsMutex      sync.Mutex
+	received        uint64
+	lastReceiveTime *time.Time
+	lastError       error
+
+	log log15.Logger
+}
+
+// NewAutoReceiveWorker creates an AutoReceiveWorker.
+func NewAutoReceiveWorker(manager *Manager, account interfaces.Account, config AutoReceiveConfig) *AutoReceiveWorker {
+	return &AutoReceiveWorker{
+		manager: manager,
+		account: account,
+		config:  config,
+
+		status:       create,
+		isCancel:     atomic.NewBool(false),
+		newBlockCond: common.NewTimeoutCond(),
+
+		log: slog.New("worker", "autoReceive", "addr", config.Address),
+	}
+}
+
+// Start is to start receiving the onroad blocks of the address.
+func (w *AutoReceiveWorker) Start() {
+	w.statusMutex.Lock()
+	defer w.statusMutex.Unlock()
+	if w.status == start {
+		w.wakeup()
+		return
+	}
+	w.isCancel.Store(false)
+	w.wg.Add(1)
+	common.Go(w.work)
+	w.status = start
+	w.log.Info("start")
+}
+
+// Stop is to stop the worker, it waits for the block being received.
+func (w *AutoReceiveWorker) Stop() {
+	w.statusMutex.Lock()
+	defer w.statusMutex.Unlock()
+	if w.status != start {
+		return
+	}
+	w.isCancel.Store(true)
+	w.newBlockCond.Broadcast()
+	w.wg.Wait()
+	w.status = stop
+	w.log.Info("stop")
+}
+
+// Status returns the config and the statistics of the worker.
+func (w *AutoReceiveWorker) Status() *AutoReceiveStatus {
+	w.statusMutex.Lock()
+	running := w.status == start
+	w.statusMutex.Unlock()
+
+	w.statsMutex.Lock()
+	defer w.statsMutex.Unlock()
+	status := &AutoReceiveStatus{
+		Config:          w.config,
+		Running:         running,
+		Received:        w.received,
+		LastReceiveTime: w.lastReceiveTime,
+	}
+	if w.lastError != nil {
+		status.LastError = w.lastError.Error()
+	}
+	return status
+}
+
+func (w *AutoReceiveWorker) wakeup() {
+	w.newBlockCond.Broadcast()
+}
+
+func (w *AutoReceiveWorker) work() {
+	defer w.wg.Done()
+	for !w.isCancel.Load() {
+		if w.manager.Net().SyncState() == net.SyncDone {
+			w.receiveAll()
+		}
+		if w.isCancel.Load() {
+			break
+		}
+		w.newBlockCond.WaitTimeout(autoReceiveInterval)
+	}
+}
+
+// receiveAll receives the onroad blocks passing the filter until an error happens. The onroad blocks are scanned
+// in the order of the hashes from the cursor, the next round continues after the last scanned block, and starts
+// from the first one again after the end is reached. The blocks after a failure are received in the next round
+// which is triggered by a new block or the interval.
+func (w *AutoReceiveWorker) receiveAll() {
+	addr := w.config.Address
+	startCursor := w.scanCursor
+
+	cursor := startCursor
+	sendBlocks := make([]*ledger.AccountBlock, 0)
+	for scanned := 0; scanned < autoReceiveMaxScan; {
+		blocks, err := w.manager.Chain().GetOnRoadBlocksAfterHash(addr, cursor, autoReceivePageSize)
+		if err != nil {
+			w.setError(err)
+			return
+		}
+		for _, block := range blocks {
+			if w.config.Filter.Match(block) {
+				sendBlocks = append(sendBlocks, block)
+			}
+		}
+		scanned += len(blocks)
+		if len(blocks) < autoReceivePageSize {
+			// the end of the onroad blocks, the next round starts from the first one
+			cursor = nil
+			break
+		}
+		cursor = &blocks[len(blocks)-1].Hash
+	}
+
+	for _, sendBlock := range sendBlocks {
+		if w.isCancel.Load() {
+			return
+		}
+		if err := w.receive(sendBlock); err != nil {
+			w.log.Info(fmt.Sprintf("receive failed, err:%v", err), "s", sendBlock.Hash)
+			w.setError(err)
+			// the blocks of this round are scanned again
+			w.scanCursor = startCursor
+			return
+		}
+	}
+	w.scanCursor = cursor
+}
+
+func (w *AutoReceiveWorker) receive(sendBlock *ledger.AccountBlock) error {
+	addr := w.config.Address
+	chain := w.manager.Chain()
+
+	addrState, err := generator.GetAddressStateForGenerator(chain, &addr)
+	if err != nil || addrState == nil {
+		return fmt.Errorf("failed to get account state for generator, err:%v", err)
+	}
+	gen, err := generator.NewGenerator(chain, w.manager.Consensus(), addr, addrState.LatestSnapshotHash, addrState.LatestAccountHash)
+	if err != nil {
+		return err
+	}
+	difficulty, err := w.calcDifficulty(gen.GetVMDB(), *addrState.LatestAccountHash)
+	if err != nil {
+		return err
+	}
+
+	genResult, err := gen.GenerateWithOnRoad(sendBlock, &addr, w.account.Sign, difficulty)
+	if err != nil {
+		return err
+	}
+	if genResult.VMBlock == nil {
+		if genResult.Err != nil {
+			return genResult.Err
+		}
+		return errors.New("no receive block is generated")
+	}
+	if err := w.manager.insertBlockToPool(genResult.VMBlock); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w.statsMutex.Lock()
+	w.received++
+	w.lastReceiveTime = &now
+	w.lastError = nil
+	w.statsMutex.Unlock()
+	w.log.Info(fmt.Sprintf("received %v, s %v", genResult.VMBlock.AccountBlock.Hash, sendBlock.Hash), "difficulty", difficulty)
+	return nil
+}
+
+// calcDifficulty returns nil if the stake quota is enough for the receive block, otherwise returns the PoW
+// difficulty if PoW is enabled.
+func (w *AutoReceiveWorker) calcDifficulty(db interfaces.VmDb, prevHash types.Hash) (*big.Int, error) {
+	addr := w.config.Address
+	sb, err := db.LatestSnapshotBlock()
+	if err != nil {
+		return nil, err
+	}
+	block := &ledger.AccountBlock{
+		BlockType:      ledger.BlockTypeReceive,
+		AccountAddress: addr,
+		PrevHash:       prevHash,
+	}
+	quotaRequired, err := vm.GasRequiredForBlock(db, block, util.QuotaTableByHeight(sb.Height), sb.Height)
+	if err != nil {
+		return nil, err
+	}
+	stakeAmount, err := w.manager.Chain().GetStakeBeneficialAmount(addr)
+	if err != nil {
+		return nil, err
+	}
+	q, err := quota.GetQuota(db, addr, stakeAmount, sb.Height)
+	if err != nil {
+		return nil, err
+	}
+	if q.Current() >= quotaRequired {
+		return nil, nil
+	}
+
+	if !w.config.PoW {
+		return nil, errAutoReceiveQuotaNotEnough
+	}
+	// only one PoW is allowed for a snapshot block, wait for the next one
+	if !quota.CanPoW(db, addr) {
+		return nil, util.ErrCalcPoWTwice
+	}
+	return quota.CalcPoWDifficulty(db, quotaRequired, q, sb.Height)
+}
+
+func (w *AutoReceiveWorker) setError(err error) {
+	w.statsMutex.Lock()
+	w.lastError = err
+	w.statsMutex.Unlock()
+}
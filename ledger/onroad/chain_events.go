@@ -38,6 +38,12 @@ func (manager *Manager) InsertAccountBlocks(blocks []*interfaces.VmAccountBlock)
 	for addr, list := range cutMap {
 		// handle contract onroad
 		if !types.IsContractAddr(addr) {
+			for _, v := range list {
+				if v.IsSendBlock() {
+					manager.newAutoReceiveSignal(&addr)
+					break
+				}
+			}
 			continue
 		}
 		var gid types.Gid
@@ -128,6 +134,8 @@ func (manager *Manager) InsertSnapshotBlocks(chunks []*ledger.SnapshotChunk) err
 		value.(snapshotEventReactFunc)(latestHeight)
 		return true
 	})
+	// the quota of the auto receive accounts grows with the snapshot blocks
+	manager.newAutoReceiveSignal(nil)
 	return nil
 }
 
@@ -400,7 +400,7 @@ func (w *ContractWorker) releaseContractCallers(contract types.Address, state in
 
 // GetStakeQuota returns the available quota the contract can use at current.
 func (w *ContractWorker) GetStakeQuota(addr types.Address) uint64 {
-	if types.IsBuiltinContractAddrInUseWithoutQuota(addr) {
+	if ledger.IsBuiltinContractAddrInUseWithoutQuota(addr, w.manager.Chain().GetLatestSnapshotBlock().Height) {
 		return math.MaxUint64
 	}
 	_, quota, err := w.manager.Chain().GetStakeQuota(addr)
@@ -418,8 +418,9 @@ func (w *ContractWorker) GetStakeQuotas(beneficialList []types.Address) map[type
 	quotas := make(map[types.Address]uint64)
 	if w.gid == types.DELEGATE_GID {
 		commonContractAddressList := make([]types.Address, 0, len(beneficialList))
+		sbHeight := w.manager.Chain().GetLatestSnapshotBlock().Height
 		for _, addr := range beneficialList {
-			if types.IsBuiltinContractAddrInUseWithoutQuota(addr) {
+			if ledger.IsBuiltinContractAddrInUseWithoutQuota(addr, sbHeight) {
 				quotas[addr] = math.MaxUint64
 			} else {
 				commonContractAddressList = append(commonContractAddressList, addr)
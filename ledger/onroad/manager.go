@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/syndtr/goleveldb/leveldb"
+
 	"github.com/vitelabs/go-vite/common"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/interfaces"
@@ -45,6 +47,12 @@ type Manager struct {
 
 	lastProducerAccEvent *producerevent.AccountStartEvent
 
+	autoReceiveDb      *leveldb.DB
+	findAccount        AccountFinder
+	autoReceiveConfigs map[types.Address]*AutoReceiveConfig
+	autoReceiveWorkers sync.Map //map[types.Address]*AutoReceiveWorker
+	autoReceiveMu      sync.Mutex
+
 	log log15.Logger
 }
 
@@ -79,6 +87,7 @@ func (manager *Manager) Start() {
 		manager.producer.SetAccountEventFunc(manager.producerStartEventFunc)
 	}
 	manager.Chain().Register(manager)
+	manager.ResumeAutoReceive()
 }
 
 // Stop method cancel all subscriptions from other modules.
@@ -90,6 +99,7 @@ func (manager *Manager) Stop() {
 	}
 	manager.Chain().UnRegister(manager)
 	manager.stopAllWorks()
+	manager.stopAutoReceive()
 	manager.log.Info("Close end")
 }
 
@@ -112,6 +122,7 @@ func (manager *Manager) netStateChangedFunc(state net.SyncState) {
 	common.Go(func() {
 		if state == net.SyncDone {
 			manager.resumeContractWorks()
+			manager.newAutoReceiveSignal(nil)
 		} else {
 			manager.stopAllWorks()
 		}
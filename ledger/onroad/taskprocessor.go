@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	"github.com/vitelabs/go-vite/ledger/generator"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/vm/quota"
@@ -140,7 +141,7 @@ func (tp *ContractTaskProcessor) processOneAddress(task *contractTask) (canConti
 	} else {
 		if genResult.IsRetry {
 			blog.Info("genResult.IsRetry true")
-			if !types.IsBuiltinContractAddrInUseWithoutQuota(task.Addr) {
+			if !ledger.IsBuiltinContractAddrInUseWithoutQuota(task.Addr, tp.worker.manager.Chain().GetLatestSnapshotBlock().Height) {
 				_, q, err := tp.worker.manager.Chain().GetStakeQuota(task.Addr)
 				if err != nil || q == nil {
 					blog.Error(fmt.Sprintf("failed to get stake quota, err:%v", err))
@@ -2,16 +2,43 @@ package batch
 
 import (
 	"fmt"
+	"runtime"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/vitelabs/go-vite/common"
-	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/tools/toposort"
 )
 
+// maxParallel is the most buckets inserted at the same time
+var maxParallel = int32(runtime.NumCPU())
+
+// SetMaxParallel sets the most buckets inserted at the same time, the number of cpus is used if n <= 0
+func SetMaxParallel(n int) {
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	atomic.StoreInt32(&maxParallel, int32(n))
+}
+
+// bucketTask is a bucket to insert, its inputs are the buckets it depends on
+type bucketTask struct {
+	id     string
+	level  Level
+	bucket Bucket
+	inputs []string
+}
+
+type bucketTasks []*bucketTask
+
+func (a bucketTasks) Len() int              { return len(a) }
+func (a bucketTasks) Swap(i, j int)         { a[i], a[j] = a[j], a[i] }
+func (a bucketTasks) Ids(i int) []string    { return []string{a[i].id} }
+func (a bucketTasks) Inputs(i int) []string { return a[i].inputs }
+
 type batchExecutor struct {
 	p           Batch
 	snapshotFn  BucketExecutorFn
@@ -23,112 +50,125 @@ type batchExecutor struct {
 func newBatchExecutor(p Batch, snapshotFn BucketExecutorFn, accountFn BucketExecutorFn) *batchExecutor {
 	executor := &batchExecutor{p: p, snapshotFn: snapshotFn, accountFn: accountFn}
 	executor.log = log15.New("module", "pool/batch", "batchId", p.Id())
-	executor.maxParallel = 5
+	executor.maxParallel = int(atomic.LoadInt32(&maxParallel))
 	return executor
 }
 
+// execute inserts the buckets by their dependency instead of level by level, a bucket starts as soon as the
+// buckets it depends on are inserted, so that the accounts of the following levels needn't wait for the slowest
+// bucket of a level. The blocks of a bucket are verified by the vm when it's inserted, so the independent accounts
+// are re-executed in parallel too.
 func (self *batchExecutor) execute() error {
 	levels := self.p.Levels()
-	for i, level := range levels {
-		if level == nil {
-			continue
-		}
-		self.log.Info(fmt.Sprintf("insert queue level[%d][%t] insert.", i, level.Snapshot()))
-		err := self.insertLevel(level)
-		if err != nil {
-			return err
-		}
-		level.Done()
+	tasks := self.tasks(levels)
+	if len(tasks) == 0 {
+		return nil
 	}
-	return nil
-}
 
-func (self *batchExecutor) insertLevel(l Level) error {
-	if l.Snapshot() {
-		return self.insertSnapshotLevel(l)
-	} else {
-		return self.insertAccountLevel(l)
+	// a level is done when all its buckets are inserted
+	var mu sync.Mutex
+	remain := make(map[Level]int)
+	for _, task := range tasks {
+		remain[task.level]++
 	}
-}
-func (self *batchExecutor) insertSnapshotLevel(l Level) error {
+
+	version := self.p.Version()
+	var num int32
 	t1 := time.Now()
-	num := 0
-	defer func() {
-		now := time.Now()
-		if !t1.After(now) {
-			levelInfo := fmt.Sprintf("\tlevel[%d][%d][%s][%d]->%dS", l.Index(), -1, "-1", num, num)
-			self.log.Info(levelInfo)
+	err := toposort.Run(tasks, self.maxParallel, func(i int) error {
+		task := tasks[i]
+		var err error
+		if task.level.Snapshot() {
+			err = self.snapshotFn(self.p, task.level, task.bucket, version)
 		} else {
-			sub := now.Sub(t1)
-			levelInfo := fmt.Sprintf("\tlevel[%d][%d][%s][%d]->%dS", l.Index(), (int64(num)*time.Second.Nanoseconds())/sub.Nanoseconds(), sub, num, num)
-			self.log.Info(levelInfo)
+			err = self.accountFn(self.p, task.level, task.bucket, version)
 		}
-	}()
-	version := self.p.Version()
-	for _, b := range l.Buckets() {
-		num = num + len(b.Items())
-		return self.snapshotFn(self.p, l, b, version)
-	}
-	return nil
-}
+		if err != nil {
+			self.log.Info(fmt.Sprintf("error[%s] for insert bucket[%s].", err, task.id))
+			return err
+		}
+		atomic.AddInt32(&num, int32(len(task.bucket.Items())))
 
-func (self *batchExecutor) insertAccountLevel(l Level) error {
-	version := self.p.Version()
-	bs := l.Buckets()
-	lenBs := len(bs)
-	if lenBs == 0 {
+		mu.Lock()
+		defer mu.Unlock()
+		remain[task.level]--
+		if remain[task.level] == 0 {
+			task.level.Done()
+		}
 		return nil
+	})
+
+	sub := time.Since(t1)
+	rate := int64(-1)
+	if sub > 0 {
+		rate = int64(num) * time.Second.Nanoseconds() / sub.Nanoseconds()
 	}
+	self.log.Info(fmt.Sprintf("insert levels[%d] buckets[%d][%d][%s][%d], %v", len(levels), len(tasks), rate, sub, num, err))
+	return err
+}
 
-	N := helper.MinInt(lenBs, self.maxParallel)
-	bucketCh := make(chan Bucket, lenBs)
+// tasks returns the buckets of the levels with their dependencies:
+// 1. a bucket depends on the previous bucket of the same account;
+// 2. a bucket depends on the buckets of the account blocks its items refer to;
+// 3. a snapshot level is a barrier, it depends on all the buckets since the previous snapshot level,
+// and all the following buckets depend on it.
+func (self *batchExecutor) tasks(levels []Level) bucketTasks {
+	var tasks bucketTasks
+	var snapshotIds, sinceSnapshot []string
+	lastOfOwner := make(map[types.Address]string)
+	bucketOfItem := make(map[types.Hash]string)
 
-	var wg sync.WaitGroup
-	wg.Add(N)
+	for _, l := range levels {
+		if l == nil {
+			continue
+		}
+		if l.Snapshot() {
+			var ids []string
+			for i, b := range l.Buckets() {
+				task := &bucketTask{
+					id:     strconv.Itoa(l.Index()) + ":S" + strconv.Itoa(i),
+					level:  l,
+					bucket: b,
+				}
+				task.inputs = append(task.inputs, snapshotIds...)
+				task.inputs = append(task.inputs, sinceSnapshot...)
+				task.inputs = append(task.inputs, ids...)
+				tasks = append(tasks, task)
+				ids = append(ids, task.id)
+			}
+			snapshotIds = ids
+			sinceSnapshot = nil
+			continue
+		}
 
-	var num int32
-	t1 := time.Now()
-	var globalErr error
-	for i := 0; i < N; i++ {
-		common.Go(func() {
-			defer wg.Done()
-			for b := range bucketCh {
-				if globalErr != nil {
-					return
+		for _, b := range l.Buckets() {
+			task := &bucketTask{
+				id:     strconv.Itoa(l.Index()) + ":" + b.Owner().String(),
+				level:  l,
+				bucket: b,
+			}
+			task.inputs = append(task.inputs, snapshotIds...)
+			if last, ok := lastOfOwner[*b.Owner()]; ok {
+				task.inputs = append(task.inputs, last)
+			}
+			for _, item := range b.Items() {
+				_, accounts, _ := item.ReferHashes()
+				for _, hash := range accounts {
+					if id, ok := bucketOfItem[hash]; ok && id != task.id {
+						task.inputs = append(task.inputs, id)
+					}
 				}
-				err := self.accountFn(self.p, l, b, version)
-				atomic.AddInt32(&num, int32(len(b.Items())))
-				if err != nil {
-					globalErr = err
-					self.log.Info(fmt.Sprintf("error[%s] for insert account block.\n", err))
-					return
+			}
+			for _, item := range b.Items() {
+				keys, _, _ := item.ReferHashes()
+				for _, hash := range keys {
+					bucketOfItem[hash] = task.id
 				}
 			}
-		})
-	}
-	levelInfo := ""
-	for _, bucket := range bs {
-		levelInfo += "|" + strconv.Itoa(len(bucket.Items()))
-		if bucket.Owner() == nil {
-			levelInfo += "S"
+			lastOfOwner[*b.Owner()] = task.id
+			sinceSnapshot = append(sinceSnapshot, task.id)
+			tasks = append(tasks, task)
 		}
-
-		bucketCh <- bucket
-	}
-	close(bucketCh)
-	wg.Wait()
-
-	now := time.Now()
-	if !t1.After(now) {
-		levelInfo = fmt.Sprintf("\tlevel[%d][%d][%s][%d]->%s, %s", l.Index(), -1, "-1", num, levelInfo, globalErr)
-		self.log.Info(levelInfo)
-	} else {
-		sub := now.Sub(t1)
-		levelInfo = fmt.Sprintf("\tlevel[%d][%d][%s][%d]->%s, %s", l.Index(), (int64(num)*time.Second.Nanoseconds())/sub.Nanoseconds(), sub, num, levelInfo, globalErr)
-		self.log.Info(levelInfo)
-	}
-	if globalErr != nil {
-		return globalErr
 	}
-	return nil
+	return tasks
 }
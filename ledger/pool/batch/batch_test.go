@@ -2,7 +2,9 @@ package batch
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -363,3 +365,90 @@ func verifyCase1(t *testing.T, batch Batch) {
 	assert.False(t, levels[1].Snapshot())
 	assert.True(t, levels[2].Snapshot())
 }
+
+func TestBatchExecutor_Dependency(t *testing.T) {
+	for _, genCaseFn := range []func(chain *mockChain) []*mockItem{genCase1, genCase3, genCase4} {
+		chain := newMockChain()
+		batch := NewBatch(chain.exists, chain.exists, 1, 100)
+		for _, v := range genCaseFn(chain) {
+			if v.expectedErr != nil {
+				break
+			}
+			if v.Owner() != nil {
+				assert.NoError(t, batch.AddAItem(v, nil))
+			} else {
+				assert.NoError(t, batch.AddSItem(v))
+			}
+		}
+
+		// every bucket starts after the blocks it refers to are inserted
+		var mu sync.Mutex
+		execute := func(p Batch, l Level, bucket Bucket, version uint64) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, v := range bucket.Items() {
+				_, accounts, sHash := v.ReferHashes()
+				for _, hash := range accounts {
+					if err := chain.exists(hash); err != nil {
+						return errors.New(fmt.Sprintf("account[%s] of [%s] is not inserted", hash, v.Hash()))
+					}
+				}
+				if sHash != nil {
+					if err := chain.exists(*sHash); err != nil {
+						return errors.New(fmt.Sprintf("snapshot[%s] of [%s] is not inserted", sHash, v.Hash()))
+					}
+				}
+				if err := chain.insert(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		assert.NoError(t, batch.Batch(execute, execute))
+		for _, level := range batch.Levels() {
+			assert.True(t, level.HasDone())
+		}
+	}
+}
+
+func TestBatchExecutor_Parallel(t *testing.T) {
+	SetMaxParallel(2)
+	defer SetMaxParallel(0)
+
+	chain := newMockChain()
+	addrA := common.MockAddress(0)
+	addrB := common.MockAddress(1)
+	blocks, _ := initChain([]types.Address{addrA, addrB}, chain)
+	batch := NewBatch(chain.exists, chain.exists, 1, 100)
+	assert.NoError(t, batch.AddAItem(NewMockSendBlcok(blocks[addrA]), nil))
+	assert.NoError(t, batch.AddAItem(NewMockSendBlcok(blocks[addrB]), nil))
+
+	// the buckets of the independent accounts are verified and inserted at the same time, each one waits for the
+	// other to start
+	var mu sync.Mutex
+	started := 0
+	bothStarted := make(chan struct{})
+	execute := func(p Batch, l Level, bucket Bucket, version uint64) error {
+		mu.Lock()
+		started++
+		if started == 2 {
+			close(bothStarted)
+		}
+		mu.Unlock()
+		select {
+		case <-bothStarted:
+		case <-time.After(5 * time.Second):
+			return errors.New("the buckets of the independent accounts are not inserted in parallel")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, v := range bucket.Items() {
+			if err := chain.insert(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	assert.NoError(t, batch.Batch(execute, execute))
+	assert.Equal(t, 2, started)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/vitelabs/go-vite/ledger/verifier"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 	"github.com/vitelabs/go-vite/net"
 )
 
@@ -369,6 +370,8 @@ func (pl *pool) AddAccountBlock(address types.Address, block *ledger.AccountBloc
 	if pl.bc.IsGenesisAccountBlock(block.Hash) {
 		return
 	}
+	// the waiting in the pool ends when the block is verified
+	blocktrace.Start(block.Hash, blocktrace.StagePoolQueue, "source", source.String())
 	ac := pl.selfPendingAc(address)
 	ac.addBlock(newAccountPoolBlock(block, nil, pl.version, source))
 
@@ -377,15 +380,19 @@ func (pl *pool) AddAccountBlock(address types.Address, block *ledger.AccountBloc
 	pl.worker.bus.newABlockEvent()
 }
 
-func (pl *pool) AddDirectAccountBlock(address types.Address, block *interfaces.VmAccountBlock) error {
+func (pl *pool) AddDirectAccountBlock(address types.Address, block *interfaces.VmAccountBlock) (err error) {
 	pl.log.Info(fmt.Sprintf("receive account block from direct. addr:%s, height:%d, hash:%s.", address, block.AccountBlock.Height, block.AccountBlock.Hash))
 	defer monitor.LogTime("pool", "addDirectAccount", time.Now())
+	span := blocktrace.Start(block.AccountBlock.Hash, blocktrace.StagePoolDirect)
+	defer func() {
+		span.Finish(err)
+	}()
 	pl.RLockInsert()
 	defer pl.RUnLockInsert()
 
 	ac := pl.selfPendingAc(address)
 
-	err := ac.v.verifyAccountData(block.AccountBlock)
+	err = ac.v.verifyAccountData(block.AccountBlock)
 	if err != nil {
 		pl.log.Error("account err", "err", err, "height", block.AccountBlock.Height, "hash", block.AccountBlock.Hash, "addr", address)
 		return err
@@ -9,6 +9,7 @@ import (
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	"github.com/vitelabs/go-vite/ledger/verifier"
 	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 )
 
 type verifyTask interface {
@@ -60,6 +61,7 @@ func (accV *accountVerifier) verifyAccount(b *accountPoolBlock, latest *ledger.S
 	result := &poolAccountVerifyStat{}
 	// todo how to fix for stat
 
+	blocktrace.FinishStage(b.block.Hash, blocktrace.StagePoolQueue, nil)
 	task, blocks, err := accV.v.VerifyPoolAccountBlock(b.block, latest)
 	if err != nil {
 		result.err = err
@@ -0,0 +1,263 @@
+package statediff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/chain"
+	chain_state "github.com/vitelabs/go-vite/ledger/chain/state"
+	"github.com/vitelabs/go-vite/ledger/generator"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+const (
+	SourceRedo        = "redo"
+	SourceReexecution = "reexecution"
+)
+
+var (
+	ErrBlockNotFound         = errors.New("account block is not found")
+	ErrGenesisBlock          = errors.New("state diff of blocks in the genesis snapshot is not supported")
+	ErrReexecuteInconsistent = errors.New("the re-executed block is not equal to the block on chain")
+)
+
+// StorageChange is the value of a storage key before and after the block, nil means the key is not set
+type StorageChange struct {
+	Key    []byte
+	Before []byte
+	After  []byte
+}
+
+// BalanceChange is the balance of a token before and after the block
+type BalanceChange struct {
+	TokenId types.TokenTypeId
+	Before  *big.Int
+	After   *big.Int
+}
+
+// ContractMetaChange is the contract meta set by the block, Before is nil if the contract is created by the block
+type ContractMetaChange struct {
+	Address types.Address
+	Before  *ledger.ContractMeta
+	After   *ledger.ContractMeta
+}
+
+// StateDiff is every state change caused by an account block
+type StateDiff struct {
+	Address   types.Address
+	BlockHash types.Hash
+	Height    uint64
+	Source    string
+
+	Storage       []*StorageChange
+	Balances      []*BalanceChange
+	ContractMetas []*ContractMetaChange
+	Code          []byte
+}
+
+// Differ computes the state diff of account blocks. The diff is read from the redo log of chain_state,
+// and the block is re-executed on the state of the previous snapshot block if the redo log has been pruned.
+type Differ struct {
+	chain     chain.Chain
+	consensus generator.Consensus
+
+	log log15.Logger
+}
+
+func NewDiffer(chain chain.Chain, consensus generator.Consensus) *Differ {
+	return &Differ{
+		chain:     chain,
+		consensus: consensus,
+		log:       log15.New("module", "state_diff"),
+	}
+}
+
+// GetStateDiff returns the state diff of the account block
+func (d *Differ) GetStateDiff(blockHash types.Hash) (*StateDiff, error) {
+	block, err := d.chain.GetAccountBlockByHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, ErrBlockNotFound
+	}
+
+	sbHeight, err := d.confirmHeight(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if sbHeight <= 1 {
+		return nil, ErrGenesisBlock
+	}
+	prevSb, err := d.chain.GetSnapshotHeaderByHeight(sbHeight - 1)
+	if err != nil {
+		return nil, err
+	}
+	if prevSb == nil {
+		return nil, fmt.Errorf("snapshot block %d is not found", sbHeight-1)
+	}
+
+	_, _, stateDB := d.chain.DBs()
+	snapshotLog, _, err := stateDB.Redo().QueryLog(sbHeight)
+	if err != nil {
+		return nil, err
+	}
+	logList := snapshotLog[block.AccountAddress]
+	for i := range logList {
+		if logList[i].Height == block.Height {
+			return d.diffByRedo(block, prevSb, logList[:i], logList[i])
+		}
+	}
+
+	d.log.Info(fmt.Sprintf("redo log of snapshot %d is not found, re-execute block %s", sbHeight, blockHash), "method", "GetStateDiff")
+	return d.diffByReexecution(block, sbHeight, prevSb)
+}
+
+// confirmHeight returns the height of the snapshot block which confirms the account block,
+// the height of the next snapshot block is returned if the block is unconfirmed.
+func (d *Differ) confirmHeight(blockHash types.Hash) (uint64, error) {
+	confirmSb, err := d.chain.GetConfirmSnapshotHeaderByAbHash(blockHash)
+	if err != nil {
+		return 0, err
+	}
+	if confirmSb != nil {
+		return confirmSb.Height, nil
+	}
+	return d.chain.GetLatestSnapshotBlock().Height + 1, nil
+}
+
+func (d *Differ) diffByRedo(block *ledger.AccountBlock, prevSb *ledger.SnapshotBlock, prevLogs []chain_state.LogItem, redoLog chain_state.LogItem) (*StateDiff, error) {
+	view := newHistoryView(d.chain, block.AccountAddress, prevSb)
+	for _, item := range prevLogs {
+		view.apply(item.Storage, item.BalanceMap, decodeContractMetas(item.ContractMeta), item.Code)
+	}
+	diff, err := view.diff(redoLog.Storage, redoLog.BalanceMap, decodeContractMetas(redoLog.ContractMeta))
+	if err != nil {
+		return nil, err
+	}
+	diff.Address = block.AccountAddress
+	diff.BlockHash = block.Hash
+	diff.Height = block.Height
+	diff.Source = SourceRedo
+	diff.Code = redoLog.Code
+	return diff, nil
+}
+
+// diffByReexecution re-executes the blocks of the account confirmed by the same snapshot block, in order,
+// on the state of the previous snapshot block. Every state is read at the previous snapshot block by the history view,
+// the re-execution fails if it reads a state the view can't read, and the result is rejected if the re-executed block
+// is not equal to the block on chain.
+func (d *Differ) diffByReexecution(block *ledger.AccountBlock, sbHeight uint64, prevSb *ledger.SnapshotBlock) (*StateDiff, error) {
+	blocks := []*ledger.AccountBlock{block}
+	for current := block; current.Height > 1; {
+		prev, err := d.chain.GetAccountBlockByHash(current.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+		if prev == nil {
+			break
+		}
+		prevHeight, err := d.confirmHeight(prev.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if prevHeight != sbHeight {
+			break
+		}
+		blocks = append([]*ledger.AccountBlock{prev}, blocks...)
+		current = prev
+	}
+
+	view := newHistoryView(d.chain, block.AccountAddress, prevSb)
+	if first := blocks[0]; first.Height > 1 {
+		prevBlock, err := d.chain.GetAccountBlockByHash(first.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+		view.prevBlock = prevBlock
+	}
+	for _, item := range blocks {
+		var fromBlock *ledger.AccountBlock
+		if item.IsReceiveBlock() {
+			var err error
+			if fromBlock, err = d.chain.GetAccountBlockByHash(item.FromBlockHash); err != nil {
+				return nil, err
+			}
+			if fromBlock == nil {
+				return nil, fmt.Errorf("send block %s is not found", item.FromBlockHash)
+			}
+		}
+
+		gen, err := generator.NewGenerator(view, d.consensus, item.AccountAddress, &prevSb.Hash, &item.PrevHash)
+		if err != nil {
+			return nil, err
+		}
+		result, err := gen.GenerateWithBlock(item, fromBlock)
+		if view.err != nil {
+			return nil, view.err
+		}
+		if err != nil {
+			return nil, err
+		}
+		if result == nil || result.VMBlock == nil {
+			if result != nil && result.Err != nil {
+				return nil, result.Err
+			}
+			return nil, ErrReexecuteInconsistent
+		}
+		if result.VMBlock.AccountBlock.Hash != item.Hash {
+			return nil, ErrReexecuteInconsistent
+		}
+
+		vmDb := result.VMBlock.VmDb
+		if item.Hash != block.Hash {
+			view.apply(vmDb.GetUnsavedStorage(), vmDb.GetUnsavedBalanceMap(), vmDb.GetUnsavedContractMeta(), vmDb.GetUnsavedContractCode())
+			view.applyBlock(item)
+			continue
+		}
+
+		diff, err := view.diff(vmDb.GetUnsavedStorage(), vmDb.GetUnsavedBalanceMap(), vmDb.GetUnsavedContractMeta())
+		if err != nil {
+			return nil, err
+		}
+		diff.Address = block.AccountAddress
+		diff.BlockHash = block.Hash
+		diff.Height = block.Height
+		diff.Source = SourceReexecution
+		diff.Code = vmDb.GetUnsavedContractCode()
+		return diff, nil
+	}
+	return nil, ErrReexecuteInconsistent
+}
+
+func decodeContractMetas(metaMap map[types.Address][]byte) map[types.Address]*ledger.ContractMeta {
+	if len(metaMap) == 0 {
+		return nil
+	}
+	result := make(map[types.Address]*ledger.ContractMeta, len(metaMap))
+	for addr, value := range metaMap {
+		meta := &ledger.ContractMeta{}
+		if err := meta.Deserialize(value); err != nil {
+			continue
+		}
+		result[addr] = meta
+	}
+	return result
+}
+
+func sortDiff(diff *StateDiff) {
+	sort.Slice(diff.Storage, func(i, j int) bool {
+		return bytes.Compare(diff.Storage[i].Key, diff.Storage[j].Key) < 0
+	})
+	sort.Slice(diff.Balances, func(i, j int) bool {
+		return bytes.Compare(diff.Balances[i].TokenId.Bytes(), diff.Balances[j].TokenId.Bytes()) < 0
+	})
+	sort.Slice(diff.ContractMetas, func(i, j int) bool {
+		return bytes.Compare(diff.ContractMetas[i].Address.Bytes(), diff.ContractMetas[j].Address.Bytes()) < 0
+	})
+}
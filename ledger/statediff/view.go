@@ -0,0 +1,338 @@
+package statediff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/db"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/chain"
+	"github.com/vitelabs/go-vite/vm/contracts/abi"
+	"github.com/vitelabs/go-vite/vm_db"
+)
+
+// quotaUsedHeight is the number of confirmed snapshot blocks whose quota is accumulated by the quota list of the chain
+const quotaUsedHeight = 74
+
+// ErrNonHistoricalRead is returned if the re-execution reads a state which can't be read at the snapshot block
+var ErrNonHistoricalRead = errors.New("the state can't be read at the snapshot block")
+
+// historyView is the state of an account at a snapshot block, with the changes of the
+// account blocks after the snapshot block applied. It overrides every state reader used by vm_db, so that
+// nothing is read from the latest state of the chain. The accounts other than addr are read at the snapshot block.
+type historyView struct {
+	chain.Chain
+
+	addr   types.Address
+	prevSb *ledger.SnapshotBlock
+
+	storage  *vm_db.Unsaved
+	balances map[types.TokenTypeId]*big.Int
+	metas    map[types.Address]*ledger.ContractMeta
+	code     []byte
+
+	// prevBlock is the latest block of addr confirmed by prevSb, unconfirmed are the applied blocks after it
+	prevBlock   *ledger.AccountBlock
+	unconfirmed []*ledger.AccountBlock
+
+	// quotaList and globalQuota are loaded on the first read
+	quotaList   []types.QuotaInfo
+	globalQuota *types.QuotaInfo
+
+	// err is the first error of the readers which can't return one
+	err error
+}
+
+func newHistoryView(c chain.Chain, addr types.Address, prevSb *ledger.SnapshotBlock) *historyView {
+	return &historyView{
+		Chain:    c,
+		addr:     addr,
+		prevSb:   prevSb,
+		storage:  vm_db.NewUnsaved(),
+		balances: make(map[types.TokenTypeId]*big.Int),
+		metas:    make(map[types.Address]*ledger.ContractMeta),
+	}
+}
+
+func (v *historyView) GetValue(addr types.Address, key []byte) ([]byte, error) {
+	if addr == v.addr {
+		if value, ok := v.storage.GetValue(key); ok {
+			return value, nil
+		}
+	}
+	_, _, stateDB := v.Chain.DBs()
+	return stateDB.GetSnapshotValue(v.prevSb.Height, addr, key)
+}
+
+func (v *historyView) GetStorageIterator(addr types.Address, prefix []byte) (interfaces.StorageIterator, error) {
+	_, _, stateDB := v.Chain.DBs()
+	iter, err := stateDB.NewSnapshotStorageIteratorByHeight(v.prevSb.Height, addr, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if addr != v.addr {
+		return iter, nil
+	}
+	return db.NewMergedIterator([]interfaces.StorageIterator{
+		v.storage.NewStorageIterator(prefix),
+		iter,
+	}, v.storage.IsDelete), nil
+}
+
+func (v *historyView) GetBalance(addr types.Address, tokenId types.TokenTypeId) (*big.Int, error) {
+	if addr == v.addr {
+		if balance, ok := v.balances[tokenId]; ok {
+			return new(big.Int).Set(balance), nil
+		}
+	}
+	balanceMap, err := v.Chain.GetConfirmedBalanceList([]types.Address{addr}, tokenId, v.prevSb.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if balance, ok := balanceMap[addr]; ok && balance != nil {
+		return balance, nil
+	}
+	return big.NewInt(0), nil
+}
+
+func (v *historyView) GetContractMeta(addr types.Address) (*ledger.ContractMeta, error) {
+	if meta, ok := v.metas[addr]; ok {
+		return meta, nil
+	}
+	return v.Chain.GetContractMetaInSnapshot(addr, v.prevSb.Height)
+}
+
+// GetContractCode returns the code of a contract created at the snapshot block, the code of a contract is never changed
+func (v *historyView) GetContractCode(addr types.Address) ([]byte, error) {
+	if addr == v.addr && v.code != nil {
+		return v.code, nil
+	}
+	meta, err := v.GetContractMeta(addr)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	return v.Chain.GetContractCode(addr)
+}
+
+func (v *historyView) GetStakeBeneficialAmount(addr types.Address) (*big.Int, error) {
+	_, _, stateDB := v.Chain.DBs()
+	sd, err := stateDB.NewStorageDatabase(v.prevSb.Hash, types.AddressQuota)
+	if err != nil {
+		return nil, err
+	}
+	return abi.GetStakeBeneficialAmount(sd, addr)
+}
+
+func (v *historyView) GetLatestAccountBlock(addr types.Address) (*ledger.AccountBlock, error) {
+	if addr != v.addr {
+		return nil, ErrNonHistoricalRead
+	}
+	if len(v.unconfirmed) > 0 {
+		return v.unconfirmed[len(v.unconfirmed)-1], nil
+	}
+	return v.prevBlock, nil
+}
+
+func (v *historyView) GetUnconfirmedBlocks(addr types.Address) []*ledger.AccountBlock {
+	if addr != v.addr {
+		v.fail(ErrNonHistoricalRead)
+		return nil
+	}
+	return v.unconfirmed
+}
+
+// GetConfirmSnapshotHeaderByAbHash returns nil if the block is confirmed after the snapshot block
+func (v *historyView) GetConfirmSnapshotHeaderByAbHash(abHash types.Hash) (*ledger.SnapshotBlock, error) {
+	confirmSb, err := v.Chain.GetConfirmSnapshotHeaderByAbHash(abHash)
+	if err != nil || confirmSb == nil || confirmSb.Height > v.prevSb.Height {
+		return nil, err
+	}
+	return confirmSb, nil
+}
+
+func (v *historyView) GetConfirmedTimes(blockHash types.Hash) (uint64, error) {
+	confirmSb, err := v.GetConfirmSnapshotHeaderByAbHash(blockHash)
+	if err != nil || confirmSb == nil {
+		return 0, err
+	}
+	return v.prevSb.Height + 1 - confirmSb.Height, nil
+}
+
+func (v *historyView) GetSnapshotBlockByHeight(height uint64) (*ledger.SnapshotBlock, error) {
+	if height > v.prevSb.Height {
+		return nil, nil
+	}
+	return v.Chain.GetSnapshotBlockByHeight(height)
+}
+
+func (v *historyView) GetSnapshotBlockByContractMeta(addr types.Address, fromHash types.Hash) (*ledger.SnapshotBlock, error) {
+	meta, err := v.GetContractMeta(addr)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil || meta.SendConfirmedTimes == 0 {
+		return nil, nil
+	}
+	firstConfirmedSb, err := v.GetConfirmSnapshotHeaderByAbHash(fromHash)
+	if err != nil {
+		return nil, err
+	}
+	if firstConfirmedSb == nil {
+		return nil, errors.New("failed to find referred sendBlock' confirmSnapshotBlock")
+	}
+	limitSb, err := v.GetSnapshotBlockByHeight(firstConfirmedSb.Height + uint64(meta.SendConfirmedTimes) - 1)
+	if err != nil {
+		return nil, err
+	}
+	if limitSb == nil {
+		return nil, errors.New("fromBlock confirmed times not enough")
+	}
+	return limitSb, nil
+}
+
+// GetQuotaUsedList returns the quota used by addr in the confirmed snapshot blocks up to prevSb and by the
+// unconfirmed blocks, the same as the quota list of the chain at prevSb
+func (v *historyView) GetQuotaUsedList(addr types.Address) []types.QuotaInfo {
+	if addr != v.addr {
+		v.fail(ErrNonHistoricalRead)
+		return nil
+	}
+	if err := v.loadQuota(); err != nil {
+		v.fail(err)
+		return nil
+	}
+	var back types.QuotaInfo
+	for _, block := range v.unconfirmed {
+		back.BlockCount++
+		back.QuotaTotal += block.Quota
+		back.QuotaUsedTotal += block.QuotaUsed
+	}
+	return append(append(make([]types.QuotaInfo, 0, len(v.quotaList)+1), v.quotaList...), back)
+}
+
+// GetGlobalQuota returns the quota used by the confirmed snapshot blocks up to prevSb
+func (v *historyView) GetGlobalQuota() types.QuotaInfo {
+	if err := v.loadQuota(); err != nil {
+		v.fail(err)
+		return types.QuotaInfo{}
+	}
+	return *v.globalQuota
+}
+
+func (v *historyView) loadQuota() error {
+	if v.globalQuota != nil {
+		return nil
+	}
+	start := uint64(1)
+	if v.prevSb.Height > quotaUsedHeight {
+		start = v.prevSb.Height - quotaUsedHeight
+	}
+	// the first chunk is the snapshot block at start
+	chunks, err := v.Chain.GetSubLedger(start, v.prevSb.Height)
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("sub ledger %d-%d is not found", start, v.prevSb.Height)
+	}
+
+	global := &types.QuotaInfo{}
+	list := make([]types.QuotaInfo, 0, len(chunks)-1)
+	for _, chunk := range chunks[1:] {
+		var item types.QuotaInfo
+		for _, block := range chunk.AccountBlocks {
+			global.BlockCount++
+			global.QuotaTotal += block.Quota
+			global.QuotaUsedTotal += block.QuotaUsed
+			if block.AccountAddress == v.addr {
+				item.BlockCount++
+				item.QuotaTotal += block.Quota
+				item.QuotaUsedTotal += block.QuotaUsed
+			}
+		}
+		list = append(list, item)
+	}
+	v.quotaList = list
+	v.globalQuota = global
+	return nil
+}
+
+func (v *historyView) fail(err error) {
+	if v.err == nil {
+		v.err = err
+	}
+}
+
+// apply applies the changes of a block which is not the target block
+func (v *historyView) apply(storage [][2][]byte, balances map[types.TokenTypeId]*big.Int, metas map[types.Address]*ledger.ContractMeta, code []byte) {
+	for _, kv := range storage {
+		v.storage.SetValue(kv[0], kv[1])
+	}
+	for tokenId, balance := range balances {
+		v.balances[tokenId] = balance
+	}
+	for addr, meta := range metas {
+		v.metas[addr] = meta
+	}
+	if len(code) > 0 {
+		v.code = code
+	}
+}
+
+// applyBlock appends a re-executed block which is not the target block to the unconfirmed blocks
+func (v *historyView) applyBlock(block *ledger.AccountBlock) {
+	v.unconfirmed = append(v.unconfirmed, block)
+}
+
+// diff reads the values before the changes of the target block and returns the diff
+func (v *historyView) diff(storage [][2][]byte, balances map[types.TokenTypeId]*big.Int, metas map[types.Address]*ledger.ContractMeta) (*StateDiff, error) {
+	diff := &StateDiff{}
+	for _, kv := range storage {
+		before, err := v.GetValue(v.addr, kv[0])
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(before, kv[1]) {
+			continue
+		}
+		diff.Storage = append(diff.Storage, &StorageChange{
+			Key:    kv[0],
+			Before: before,
+			After:  kv[1],
+		})
+	}
+	for tokenId, balance := range balances {
+		before, err := v.GetBalance(v.addr, tokenId)
+		if err != nil {
+			return nil, err
+		}
+		if before.Cmp(balance) == 0 {
+			continue
+		}
+		diff.Balances = append(diff.Balances, &BalanceChange{
+			TokenId: tokenId,
+			Before:  before,
+			After:   balance,
+		})
+	}
+	for addr, meta := range metas {
+		before, err := v.GetContractMeta(addr)
+		if err != nil {
+			return nil, err
+		}
+		diff.ContractMetas = append(diff.ContractMetas, &ContractMetaChange{
+			Address: addr,
+			Before:  before,
+			After:   meta,
+		})
+	}
+	sortDiff(diff)
+	return diff, nil
+}
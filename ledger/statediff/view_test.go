@@ -0,0 +1,84 @@
+package statediff
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+func TestHistoryView_Diff(t *testing.T) {
+	addr := types.AddressDexFund
+	view := newHistoryView(nil, addr, &ledger.SnapshotBlock{Height: 10})
+
+	metaBefore := &ledger.ContractMeta{QuotaRatio: 10}
+	view.apply([][2][]byte{
+		{[]byte("b"), []byte{1}},
+		{[]byte("a"), []byte{2}},
+		{[]byte("c"), []byte{3}},
+	}, map[types.TokenTypeId]*big.Int{
+		ledger.ViteTokenId: big.NewInt(100),
+	}, map[types.Address]*ledger.ContractMeta{
+		addr: metaBefore,
+	}, nil)
+
+	metaAfter := &ledger.ContractMeta{QuotaRatio: 20}
+	diff, err := view.diff([][2][]byte{
+		{[]byte("b"), []byte{4}},
+		{[]byte("a"), nil},
+		{[]byte("c"), []byte{3}},
+	}, map[types.TokenTypeId]*big.Int{
+		ledger.ViteTokenId: big.NewInt(60),
+	}, map[types.Address]*ledger.ContractMeta{
+		addr: metaAfter,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, len(diff.Storage))
+	assert.Equal(t, []byte("a"), diff.Storage[0].Key)
+	assert.Equal(t, []byte{2}, diff.Storage[0].Before)
+	assert.Nil(t, diff.Storage[0].After)
+	assert.Equal(t, []byte("b"), diff.Storage[1].Key)
+	assert.Equal(t, []byte{4}, diff.Storage[1].After)
+
+	assert.Equal(t, 1, len(diff.Balances))
+	assert.Equal(t, int64(100), diff.Balances[0].Before.Int64())
+	assert.Equal(t, int64(60), diff.Balances[0].After.Int64())
+
+	assert.Equal(t, 1, len(diff.ContractMetas))
+	assert.Equal(t, metaBefore, diff.ContractMetas[0].Before)
+	assert.Equal(t, metaAfter, diff.ContractMetas[0].After)
+}
+
+func TestHistoryView_Unconfirmed(t *testing.T) {
+	addr := types.AddressDexFund
+	prevBlock := &ledger.AccountBlock{AccountAddress: addr, Height: 1}
+	view := newHistoryView(nil, addr, &ledger.SnapshotBlock{Height: 10})
+	view.prevBlock = prevBlock
+
+	latest, err := view.GetLatestAccountBlock(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, prevBlock, latest)
+	assert.Equal(t, 0, len(view.GetUnconfirmedBlocks(addr)))
+
+	block := &ledger.AccountBlock{AccountAddress: addr, Height: 2}
+	view.apply(nil, nil, nil, []byte{1, 2})
+	view.applyBlock(block)
+	latest, err = view.GetLatestAccountBlock(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, block, latest)
+	assert.Equal(t, []*ledger.AccountBlock{block}, view.GetUnconfirmedBlocks(addr))
+	code, err := view.GetContractCode(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, code)
+
+	// the other accounts are not tracked
+	_, err = view.GetLatestAccountBlock(types.AddressQuota)
+	assert.Equal(t, ErrNonHistoricalRead, err)
+	assert.Nil(t, view.err)
+	assert.Nil(t, view.GetUnconfirmedBlocks(types.AddressQuota))
+	assert.Equal(t, ErrNonHistoricalRead, view.err)
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 
@@ -12,6 +15,7 @@ import (
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/common/upgrade"
 	"github.com/vitelabs/go-vite/crypto"
+	"github.com/vitelabs/go-vite/crypto/ed25519"
 	"github.com/vitelabs/go-vite/interfaces"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	"github.com/vitelabs/go-vite/ledger/generator"
@@ -320,6 +324,76 @@ func (v *AccountVerifier) verifyHash(block *ledger.AccountBlock) error {
 	return nil
 }
 
+// verifyNetBlocks verifies the hashes, the nonces and the signatures of the blocks independent of the chain as
+// verifyHash, verifyNonce and verifySignature do, the hashes, the nonces and then the signatures are checked one by
+// one by the workers on all the cpus. The errors are in the order of the blocks, nil for the valid ones.
+// The signatures are not verified in a cryptographic batch, see ed25519.VerifyParallel. The blocks are not executed
+// by the vm here, it needs the state of the blocks they depend on: the pool re-executes them when inserting the
+// buckets, which the batch executor inserts in parallel for the independent accounts.
+func (v *AccountVerifier) verifyNetBlocks(blocks []*ledger.AccountBlock) []error {
+	errs := make([]error, len(blocks))
+	parallelFor(len(blocks), func(i int) {
+		if err := v.verifyHash(blocks[i]); err != nil {
+			errs[i] = err
+			return
+		}
+		errs[i] = v.verifyNonce(blocks[i])
+	})
+
+	var (
+		indexes  []int
+		pubKeys  []ed25519.PublicKey
+		messages [][]byte
+		sigs     [][]byte
+	)
+	for i, block := range blocks {
+		if errs[i] != nil || v.chain.IsGenesisAccountBlock(block.Hash) {
+			continue
+		}
+		if types.IsContractAddr(block.AccountAddress) && block.IsSendBlock() {
+			errs[i] = v.verifySignature(block)
+			continue
+		}
+		if len(block.Signature) <= 0 || len(block.PublicKey) <= 0 {
+			errs[i] = errors.New("signature and publicKey all must have value")
+			continue
+		}
+		indexes = append(indexes, i)
+		pubKeys = append(pubKeys, block.PublicKey)
+		messages = append(messages, block.Hash.Bytes())
+		sigs = append(sigs, block.Signature)
+	}
+	for j, ok := range ed25519.VerifyParallel(pubKeys, messages, sigs) {
+		if !ok {
+			errs[indexes[j]] = ErrVerifySignatureFailed
+		}
+	}
+	return errs
+}
+
+// parallelFor calls fn for each index in [0, n) by the workers on all the cpus and waits for them
+func parallelFor(n int, fn func(i int)) {
+	workers := helper.MinInt(runtime.NumCPU(), n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var next int32 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := int(atomic.AddInt32(&next, 1)); i < n; i = int(atomic.AddInt32(&next, 1)) {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func (v *AccountVerifier) verifyNonce(block *ledger.AccountBlock) error {
 	if len(block.Nonce) != 0 {
 		if types.IsContractAddr(block.AccountAddress) {
@@ -2,6 +2,7 @@ package verifier
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/vitelabs/go-vite/common/db/xleveldb/errors"
 	"github.com/vitelabs/go-vite/crypto"
@@ -11,12 +12,16 @@ import (
 	"github.com/vitelabs/go-vite/ledger/consensus"
 	"github.com/vitelabs/go-vite/ledger/onroad"
 	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 )
 
 // Verifier provides methods that external modules can use.
 type Verifier interface {
 	VerifyNetSnapshotBlock(block *ledger.SnapshotBlock) error
 	VerifyNetAccountBlock(block *ledger.AccountBlock) error
+	// VerifyNetAccountBlocks verifies the blocks as VerifyNetAccountBlock and their nonces in parallel, the errors
+	// are in the order of the blocks
+	VerifyNetAccountBlocks(blocks []*ledger.AccountBlock) []error
 
 	VerifyRPCAccountBlock(block *ledger.AccountBlock, snapshot *ledger.SnapshotBlock) (*interfaces.VmAccountBlock, error)
 	VerifyPoolAccountBlock(block *ledger.AccountBlock, snapshot *ledger.SnapshotBlock) (*AccBlockPendingTask, *interfaces.VmAccountBlock, error)
@@ -82,6 +87,10 @@ func (v *verifier) VerifyNetAccountBlock(block *ledger.AccountBlock) error {
 	return nil
 }
 
+func (v *verifier) VerifyNetAccountBlocks(blocks []*ledger.AccountBlock) []error {
+	return v.Av.verifyNetBlocks(blocks)
+}
+
 func (v *verifier) VerifyPoolAccountBlock(block *ledger.AccountBlock, snapshot *ledger.SnapshotBlock) (*AccBlockPendingTask, *interfaces.VmAccountBlock, error) {
 	eLog := v.log.New("method", "VerifyPoolAccountBlock")
 
@@ -93,25 +102,47 @@ func (v *verifier) VerifyPoolAccountBlock(block *ledger.AccountBlock, snapshot *
 		Height: snapshot.Height,
 		Hash:   snapshot.Hash,
 	}
+	span := blocktrace.Start(block.Hash, blocktrace.StageVerify, "snapshot", strconv.FormatUint(snapshot.Height, 10))
 	verifyResult, task, err := v.Av.verifyReferred(block, snapshotHashHeight)
 	if err != nil {
 		eLog.Error(err.Error()+":"+err.Detail(), "d", detail)
 	}
 	switch verifyResult {
 	case PENDING:
+		span.SetAttr("result", "pending")
+		span.Finish(nil)
 		return task, nil, nil
 	case SUCCESS:
-		blocks, err := v.Av.vmVerify(block, snapshotHashHeight)
+		blocks, err := v.vmVerify(span, block, snapshotHashHeight)
+		finishSpan(span, err)
 		if err != nil {
 			eLog.Error(err.Error()+":"+err.Detail(), "d", detail)
 			return nil, nil, err
 		}
 		return nil, blocks, nil
 	default:
+		finishSpan(span, err)
 		return nil, nil, err
 	}
 }
 
+// vmVerify runs the block in the vm in a span of the verification
+func (v *verifier) vmVerify(span *blocktrace.Span, block *ledger.AccountBlock, snapshotHashHeight *ledger.HashHeight) (*interfaces.VmAccountBlock, *VerifierError) {
+	vmSpan := span.Child(blocktrace.StageVm)
+	vmBlock, err := v.Av.vmVerify(block, snapshotHashHeight)
+	finishSpan(vmSpan, err)
+	return vmBlock, err
+}
+
+// finishSpan ends the span with the error, a nil *VerifierError must not end it as a non-nil error
+func finishSpan(span *blocktrace.Span, err *VerifierError) {
+	if err != nil {
+		span.Finish(err)
+		return
+	}
+	span.Finish(nil)
+}
+
 func (v *verifier) VerifyRPCAccountBlock(block *ledger.AccountBlock, snapshot *ledger.SnapshotBlock) (*interfaces.VmAccountBlock, error) {
 	log := v.log.New("method", "VerifyRPCAccountBlock")
 
@@ -123,21 +154,26 @@ func (v *verifier) VerifyRPCAccountBlock(block *ledger.AccountBlock, snapshot *l
 		Height: snapshot.Height,
 		Hash:   snapshot.Hash,
 	}
+	span := blocktrace.Start(block.Hash, blocktrace.StageVerify, "snapshot", strconv.FormatUint(snapshot.Height, 10), "source", "rpc")
 	if err := v.VerifyNetAccountBlock(block); err != nil {
 		log.Error(err.Error(), "d", detail)
+		span.Finish(err)
 		return nil, err
 	}
 
 	if verifyResult, task, err := v.Av.verifyReferred(block, snapshotHashHeight); verifyResult != SUCCESS {
 		if err != nil {
 			log.Error(err.Error()+":"+err.Detail(), "d", detail)
+			span.Finish(err)
 			return nil, err
 		}
 		log.Error("verify block failed, pending for:"+task.pendingHashListToStr(), "d", detail)
+		span.Finish(ErrVerifyRPCBlockPendingState)
 		return nil, ErrVerifyRPCBlockPendingState
 	}
 
-	vmBlock, err := v.Av.vmVerify(block, snapshotHashHeight)
+	vmBlock, err := v.vmVerify(span, block, snapshotHashHeight)
+	finishSpan(span, err)
 	if err != nil {
 		log.Error(err.Error()+":"+err.Detail(), "d", detail)
 		return nil, err
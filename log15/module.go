@@ -0,0 +1,94 @@
+package log15
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModuleKey is the context key of the module of a logger, e.g. log15.New("module", "net")
+const ModuleKey = "module"
+
+// ModuleLevels holds the levels of the modules which can be changed at runtime. The level of a module applies
+// to its sub modules separated by '/', e.g. the level of "pool" applies to "pool/tree" unless "pool/tree" has its
+// own level. The level of the empty module applies to all the modules without levels.
+type ModuleLevels struct {
+	mu      sync.RWMutex
+	modules map[string]Lvl
+}
+
+func NewModuleLevels() *ModuleLevels {
+	return &ModuleLevels{modules: make(map[string]Lvl)}
+}
+
+// Set sets the level of the module, the empty module sets the default level of all the modules
+func (m *ModuleLevels) Set(module string, lvl Lvl) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.modules[module] = lvl
+}
+
+// Unset removes the level of the module, the module falls back to the level of its parent
+func (m *ModuleLevels) Unset(module string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.modules, module)
+}
+
+// Get returns the level of the module, its parent modules or the empty module in turn
+func (m *ModuleLevels) Get(module string) (Lvl, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.modules) == 0 {
+		return 0, false
+	}
+	for {
+		if lvl, ok := m.modules[module]; ok {
+			return lvl, true
+		}
+		if module == "" {
+			return 0, false
+		}
+		if i := strings.LastIndex(module, "/"); i >= 0 {
+			module = module[:i]
+		} else {
+			module = ""
+		}
+	}
+}
+
+// Modules returns the modules with levels in order
+func (m *ModuleLevels) Modules() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	modules := make([]string, 0, len(m.modules))
+	for module := range m.modules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// ModuleLvlFilterHandler returns a Handler that only writes records which are less than the level of their
+// modules in levels, the records of the modules without levels are filtered by maxLvl as LvlFilterHandler.
+func ModuleLvlFilterHandler(levels *ModuleLevels, maxLvl Lvl, h Handler) Handler {
+	return FilterHandler(func(r *Record) bool {
+		if lvl, ok := levels.Get(recordModule(r)); ok {
+			return r.Lvl <= lvl
+		}
+		return r.Lvl <= maxLvl
+	}, h)
+}
+
+// recordModule returns the last module in the context of the record, which is of the most derived logger
+func recordModule(r *Record) string {
+	module := ""
+	for i := 0; i+1 < len(r.Ctx); i += 2 {
+		if r.Ctx[i] == ModuleKey {
+			if s, ok := r.Ctx[i+1].(string); ok {
+				module = s
+			}
+		}
+	}
+	return module
+}
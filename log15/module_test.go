@@ -0,0 +1,59 @@
+package log15
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleLevels_Get(t *testing.T) {
+	levels := NewModuleLevels()
+	_, ok := levels.Get("net")
+	assert.False(t, ok)
+
+	levels.Set("pool", LvlDebug)
+	lvl, ok := levels.Get("pool/tree")
+	assert.True(t, ok)
+	assert.Equal(t, LvlDebug, lvl)
+	_, ok = levels.Get("pooling")
+	assert.False(t, ok)
+
+	levels.Set("pool/tree", LvlError)
+	lvl, _ = levels.Get("pool/tree/branch")
+	assert.Equal(t, LvlError, lvl)
+
+	levels.Set("", LvlWarn)
+	lvl, ok = levels.Get("net")
+	assert.True(t, ok)
+	assert.Equal(t, LvlWarn, lvl)
+	assert.Equal(t, []string{"", "pool", "pool/tree"}, levels.Modules())
+
+	levels.Unset("pool/tree")
+	lvl, _ = levels.Get("pool/tree")
+	assert.Equal(t, LvlDebug, lvl)
+}
+
+func TestModuleLvlFilterHandler(t *testing.T) {
+	levels := NewModuleLevels()
+	var records []string
+	h := ModuleLvlFilterHandler(levels, LvlInfo, FuncHandler(func(r *Record) error {
+		records = append(records, r.Msg)
+		return nil
+	}))
+
+	root := New()
+	root.SetHandler(h)
+	netLog := root.New(ModuleKey, "net")
+	syncerLog := netLog.New(ModuleKey, "net/syncer")
+
+	netLog.Debug("net debug")
+	netLog.Info("net info")
+	levels.Set("net", LvlDebug)
+	levels.Set("net/syncer", LvlError)
+	netLog.Debug("net debug again")
+	syncerLog.Info("syncer info")
+	syncerLog.Error("syncer error")
+	root.Debug("root debug")
+
+	assert.Equal(t, []string{"net info", "net debug again", "syncer error"}, records)
+}
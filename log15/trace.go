@@ -0,0 +1,71 @@
+package log15
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// TraceKey is the context key of the trace id of an incoming request in the logs
+	TraceKey = "trace"
+
+	// MaxTraceIdLength is the max length of a trace id brought by the client
+	MaxTraceIdLength = 64
+)
+
+type traceIdKey struct{}
+
+var (
+	tracePrefix  = newTracePrefix()
+	traceCounter uint64
+)
+
+func newTracePrefix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewTraceId returns an id unique in the process and unlikely to collide across the processes, it is cheap enough
+// to be generated for every incoming request.
+func NewTraceId() string {
+	return tracePrefix + "-" + strconv.FormatUint(atomic.AddUint64(&traceCounter, 1), 36)
+}
+
+// ValidTraceId reports whether the trace id from the client is short and plain enough to be put into the logs
+func ValidTraceId(traceId string) bool {
+	if len(traceId) == 0 || len(traceId) > MaxTraceIdLength {
+		return false
+	}
+	for _, c := range traceId {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithTraceId returns a copy of ctx carrying the trace id
+func ContextWithTraceId(ctx context.Context, traceId string) context.Context {
+	return context.WithValue(ctx, traceIdKey{}, traceId)
+}
+
+// TraceIdFromContext returns the trace id carried by ctx, or the empty string
+func TraceIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceId, _ := ctx.Value(traceIdKey{}).(string)
+	return traceId
+}
+
+// FromContext returns a logger with the trace id of ctx in its context, or l itself if ctx carries none
+func FromContext(ctx context.Context, l Logger) Logger {
+	if traceId := TraceIdFromContext(ctx); traceId != "" {
+		return l.New(TraceKey, traceId)
+	}
+	return l
+}
@@ -0,0 +1,79 @@
+package log15
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTraceId(t *testing.T) {
+	ids := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		id := NewTraceId()
+		assert.True(t, ValidTraceId(id), id)
+		_, ok := ids[id]
+		assert.False(t, ok, id)
+		ids[id] = struct{}{}
+	}
+}
+
+func TestValidTraceId(t *testing.T) {
+	assert.True(t, ValidTraceId("client-trace.1"))
+	assert.True(t, ValidTraceId("A_b-9"))
+	assert.True(t, ValidTraceId(strings.Repeat("a", MaxTraceIdLength)))
+
+	assert.False(t, ValidTraceId(""))
+	assert.False(t, ValidTraceId(strings.Repeat("a", MaxTraceIdLength+1)))
+	assert.False(t, ValidTraceId("bad trace"))
+	assert.False(t, ValidTraceId("bad\ntrace"))
+	assert.False(t, ValidTraceId("bad=trace"))
+}
+
+func TestTraceIdFromContext(t *testing.T) {
+	assert.Equal(t, "", TraceIdFromContext(nil))
+	assert.Equal(t, "", TraceIdFromContext(context.Background()))
+
+	ctx := ContextWithTraceId(context.Background(), "abc")
+	assert.Equal(t, "abc", TraceIdFromContext(ctx))
+	ctx = ContextWithTraceId(ctx, "def")
+	assert.Equal(t, "def", TraceIdFromContext(ctx))
+}
+
+func TestFromContext(t *testing.T) {
+	var ctx []interface{}
+	l := New()
+	l.SetHandler(FuncHandler(func(r *Record) error {
+		ctx = r.Ctx
+		return nil
+	}))
+
+	FromContext(context.Background(), l).Info("no trace")
+	assert.Empty(t, ctx)
+
+	traceId := NewTraceId()
+	assert.NotEqual(t, traceId, NewTraceId())
+	FromContext(ContextWithTraceId(context.Background(), traceId), l).Info("trace")
+	assert.Equal(t, []interface{}{TraceKey, traceId}, ctx)
+}
+
+func TestFromContext_ModuleLogger(t *testing.T) {
+	var records []*Record
+	root := New(ModuleKey, "rpc")
+	root.SetHandler(FuncHandler(func(r *Record) error {
+		records = append(records, r)
+		return nil
+	}))
+
+	l := FromContext(context.Background(), root)
+	assert.Equal(t, root, l)
+	l.Info("no trace")
+
+	ctx := ContextWithTraceId(context.Background(), "abc")
+	FromContext(ctx, root).Info("with trace", "k", 1)
+
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, []interface{}{ModuleKey, "rpc"}, records[0].Ctx)
+	assert.Equal(t, []interface{}{ModuleKey, "rpc", TraceKey, "abc", "k", 1}, records[1].Ctx)
+}
@@ -0,0 +1,83 @@
+// Package blocktrace records the time an account block spends in each stage between the receipt from the network
+// and the insertion into the chain, the spans are keyed by the block hash and exported in the otlp format.
+package blocktrace
+
+import (
+	"sync/atomic"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// Config is the tracing of the blocks, the spans are only kept in memory if File and OtlpEndpoint are empty
+type Config struct {
+	Capacity     int
+	File         string
+	OtlpEndpoint string
+}
+
+// the tracer of the node, nil if the tracing is disabled
+var std atomic.Value
+
+type tracerHolder struct {
+	t *Tracer
+}
+
+func current() *Tracer {
+	h, _ := std.Load().(tracerHolder)
+	return h.t
+}
+
+// Enable starts the tracing of the blocks by cfg, the previous tracer is stopped
+func Enable(cfg Config) error {
+	var exporters []Exporter
+	if cfg.File != "" {
+		e, err := NewFileExporter(cfg.File)
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, e)
+	}
+	if cfg.OtlpEndpoint != "" {
+		e, err := NewOtlpExporter(cfg.OtlpEndpoint)
+		if err != nil {
+			for _, e := range exporters {
+				e.Close()
+			}
+			return err
+		}
+		exporters = append(exporters, e)
+	}
+	SetTracer(NewTracer(cfg.Capacity, exporters...))
+	return nil
+}
+
+// Disable stops the tracing of the blocks
+func Disable() {
+	SetTracer(nil)
+}
+
+// SetTracer replaces the tracer of the node, the previous one is stopped
+func SetTracer(t *Tracer) {
+	prev := current()
+	std.Store(tracerHolder{t})
+	prev.Stop()
+}
+
+func Enabled() bool {
+	return current() != nil
+}
+
+// Start starts a span of the block, it returns nil if the tracing is disabled and the nil span can be finished
+func Start(hash types.Hash, name string, attrs ...string) *Span {
+	return current().Start(hash, name, attrs...)
+}
+
+// FinishStage ends the latest unfinished span of the stage of the block
+func FinishStage(hash types.Hash, name string, err error) {
+	current().FinishStage(hash, name, err)
+}
+
+// GetTimeline returns the spans of the block, or nil if the block is not traced or the tracing is disabled
+func GetTimeline(hash types.Hash) *Timeline {
+	return current().Timeline(hash)
+}
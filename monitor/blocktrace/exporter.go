@@ -0,0 +1,203 @@
+package blocktrace
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	serviceName   = "gvite"
+	scopeName     = "github.com/vitelabs/go-vite/monitor/blocktrace"
+	otlpTracePath = "/v1/traces"
+	otlpTimeout   = 5 * time.Second
+
+	// the span kind and the status codes of otlp
+	otlpSpanKindInternal = 1
+	otlpStatusOk         = 1
+	otlpStatusError      = 2
+)
+
+// Exporter sends the finished spans out of the process
+type Exporter interface {
+	Export(spans []Span) error
+	Close() error
+}
+
+// the otlp/json encoding of the spans, see opentelemetry-proto/opentelemetry/proto/trace/v1/trace.proto
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// encodeOtlp encodes the spans as an otlp export request, the trace id of a block is the first 16 bytes of its
+// hash so that the spans exported by the nodes for the same block join the same trace.
+func encodeOtlp(spans []Span) ([]byte, error) {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceId:           hex.EncodeToString(span.Hash[:16]),
+			SpanId:            span.SpanId,
+			ParentSpanId:      span.ParentId,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        []otlpKeyValue{{Key: "block.hash", Value: otlpAnyValue{span.Hash.String()}}},
+			Status:            otlpStatus{Code: otlpStatusOk},
+		}
+		for _, attr := range span.Attrs {
+			s.Attributes = append(s.Attributes, otlpKeyValue{Key: attr.Key, Value: otlpAnyValue{attr.Value}})
+		}
+		if span.Err != "" {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.Err}
+		}
+		encoded = append(encoded, s)
+	}
+	return json.Marshal(&otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: encoded,
+			}},
+		}},
+	})
+}
+
+// FileExporter appends the spans to a file, one otlp/json export request per line as the file exporter of the
+// opentelemetry collector, so that the file can be replayed into a collector.
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileExporter(path string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{file: file}, nil
+}
+
+func (e *FileExporter) Export(spans []Span) error {
+	data, err := encodeOtlp(spans)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.file.Write(append(data, '\n'))
+	return err
+}
+
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// OtlpExporter posts the spans to an otlp/http collector in json, e.g. http://127.0.0.1:4318
+type OtlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewOtlpExporter returns an exporter to the collector at endpoint, the path /v1/traces is appended if the
+// endpoint has no path.
+func NewOtlpExporter(endpoint string) (*OtlpExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported otlp endpoint %v", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracePath
+	}
+	return &OtlpExporter{
+		endpoint: u.String(),
+		client:   &http.Client{Timeout: otlpTimeout},
+	}, nil
+}
+
+func (e *OtlpExporter) Export(spans []Span) error {
+	data, err := encodeOtlp(spans)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp collector responds %v", resp.Status)
+	}
+	return nil
+}
+
+func (e *OtlpExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
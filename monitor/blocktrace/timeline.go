@@ -0,0 +1,83 @@
+package blocktrace
+
+import (
+	"sort"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+// SpanInfo is a span in the timeline, the times are in milliseconds
+type SpanInfo struct {
+	SpanId   string            `json:"spanId"`
+	ParentId string            `json:"parentId,omitempty"`
+	Name     string            `json:"name"`
+	Start    int64             `json:"start"`
+	Offset   int64             `json:"offset"`
+	Duration int64             `json:"duration"`
+	Finished bool              `json:"finished"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Err      string            `json:"err,omitempty"`
+}
+
+// Timeline is the spans of a block in order of their start. Offset of a span is from the start of the first one,
+// and Duration of the unfinished spans is until now.
+type Timeline struct {
+	Hash     types.Hash  `json:"hash"`
+	Start    int64       `json:"start"`
+	Duration int64       `json:"duration"`
+	Spans    []*SpanInfo `json:"spans"`
+}
+
+func newTimeline(hash types.Hash, spans []*Span) *Timeline {
+	sorted := make([]*Span, len(spans))
+	copy(sorted, spans)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.Before(sorted[j].StartTime)
+	})
+
+	now := time.Now()
+	start := sorted[0].StartTime
+	end := start
+	timeline := &Timeline{
+		Hash:  hash,
+		Start: toMillis(start),
+		Spans: make([]*SpanInfo, 0, len(sorted)),
+	}
+	for _, span := range sorted {
+		spanEnd := span.EndTime
+		if spanEnd.IsZero() {
+			spanEnd = now
+		}
+		if spanEnd.After(end) {
+			end = spanEnd
+		}
+		info := &SpanInfo{
+			SpanId:   span.SpanId,
+			ParentId: span.ParentId,
+			Name:     span.Name,
+			Start:    toMillis(span.StartTime),
+			Offset:   durationMillis(span.StartTime.Sub(start)),
+			Duration: durationMillis(spanEnd.Sub(span.StartTime)),
+			Finished: !span.EndTime.IsZero(),
+			Err:      span.Err,
+		}
+		if len(span.Attrs) > 0 {
+			info.Attrs = make(map[string]string, len(span.Attrs))
+			for _, attr := range span.Attrs {
+				info.Attrs[attr.Key] = attr.Value
+			}
+		}
+		timeline.Spans = append(timeline.Spans, info)
+	}
+	timeline.Duration = durationMillis(end.Sub(start))
+	return timeline
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func durationMillis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -0,0 +1,312 @@
+package blocktrace
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+// the stages of the lifecycle of an account block
+const (
+	StageNetReceive  = "net.receive"
+	StagePoolQueue   = "pool.queue"
+	StagePoolDirect  = "pool.direct"
+	StageVerify      = "verifier.verify"
+	StageVm          = "vm.run"
+	StageChainInsert = "chain.insert"
+)
+
+const (
+	DefaultCapacity = 10000
+
+	exportQueueSize = 4096
+	exportBatchSize = 512
+	exportInterval  = time.Second
+)
+
+var tracerLog = log15.New("module", "blocktrace")
+
+// Attr is an attribute of a span
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// Span is a stage of the lifecycle of a block, the spans of a block share the trace keyed by its hash.
+type Span struct {
+	Hash      types.Hash
+	SpanId    string
+	ParentId  string
+	Name      string
+	StartTime time.Time
+	EndTime   time.Time
+	Attrs     []Attr
+	Err       string
+
+	tracer *Tracer
+}
+
+// Child starts a span of the same block inside s, it returns nil if s is nil
+func (s *Span) Child(name string, attrs ...string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.start(s.Hash, name, s.SpanId, attrs)
+}
+
+// SetAttr adds an attribute known after the start, it does nothing if s is nil
+func (s *Span) SetAttr(key, value string) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Attrs = append(s.Attrs, Attr{Key: key, Value: value})
+}
+
+// Finish ends the span with the error of the stage, it does nothing if s is nil or finished
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.tracer.finish(s, err)
+}
+
+type trace struct {
+	spans []*Span
+	elem  *list.Element
+}
+
+// Tracer records the spans of the recent capacity blocks in memory and exports the finished spans.
+type Tracer struct {
+	capacity  int
+	exporters []Exporter
+
+	mu     sync.Mutex
+	traces map[types.Hash]*trace
+	order  *list.List
+
+	queue    chan *Span
+	dropped  uint64
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewTracer returns a tracer keeping the spans of the recent capacity blocks, DefaultCapacity is used if
+// capacity <= 0. The finished spans are exported by the exporters in the background until Stop.
+func NewTracer(capacity int, exporters ...Exporter) *Tracer {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	t := &Tracer{
+		capacity:  capacity,
+		exporters: exporters,
+		traces:    make(map[types.Hash]*trace),
+		order:     list.New(),
+		queue:     make(chan *Span, exportQueueSize),
+		quit:      make(chan struct{}),
+	}
+	if len(exporters) > 0 {
+		t.wg.Add(1)
+		go t.exportLoop()
+	}
+	return t
+}
+
+// Start starts a span of the block, it returns nil if t is nil
+func (t *Tracer) Start(hash types.Hash, name string, attrs ...string) *Span {
+	if t == nil {
+		return nil
+	}
+	return t.start(hash, name, "", attrs)
+}
+
+// FinishStage ends the latest unfinished span of the stage of the block, it is for the stages which begin and end
+// in different places, e.g. the waiting in the pool.
+func (t *Tracer) FinishStage(hash types.Hash, name string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	tr, ok := t.traces[hash]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	var span *Span
+	for i := len(tr.spans) - 1; i >= 0; i-- {
+		if tr.spans[i].Name == name && tr.spans[i].EndTime.IsZero() {
+			span = tr.spans[i]
+			break
+		}
+	}
+	t.mu.Unlock()
+	if span != nil {
+		t.finish(span, err)
+	}
+}
+
+// Timeline returns the spans of the block in order of their start, or nil if the block is not traced
+func (t *Tracer) Timeline(hash types.Hash) *Timeline {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr, ok := t.traces[hash]
+	if !ok {
+		return nil
+	}
+	return newTimeline(hash, tr.spans)
+}
+
+// Stop exports the finished spans in the queue and closes the exporters
+func (t *Tracer) Stop() {
+	if t == nil {
+		return
+	}
+	t.stopOnce.Do(func() {
+		close(t.quit)
+		t.wg.Wait()
+		for _, e := range t.exporters {
+			if err := e.Close(); err != nil {
+				tracerLog.Warn("close exporter failed", "err", err)
+			}
+		}
+	})
+}
+
+func (t *Tracer) start(hash types.Hash, name string, parentId string, attrs []string) *Span {
+	span := &Span{
+		Hash:      hash,
+		SpanId:    newSpanId(),
+		ParentId:  parentId,
+		Name:      name,
+		StartTime: time.Now(),
+		Attrs:     makeAttrs(attrs),
+		tracer:    t,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr, ok := t.traces[hash]
+	if !ok {
+		tr = &trace{elem: t.order.PushBack(hash)}
+		t.traces[hash] = tr
+		if t.order.Len() > t.capacity {
+			oldest := t.order.Front()
+			t.order.Remove(oldest)
+			delete(t.traces, oldest.Value.(types.Hash))
+		}
+	}
+	tr.spans = append(tr.spans, span)
+	return span
+}
+
+func (t *Tracer) finish(span *Span, err error) {
+	t.mu.Lock()
+	if !span.EndTime.IsZero() {
+		t.mu.Unlock()
+		return
+	}
+	span.EndTime = time.Now()
+	if err != nil {
+		span.Err = err.Error()
+	}
+	t.mu.Unlock()
+
+	if len(t.exporters) == 0 {
+		return
+	}
+	select {
+	case t.queue <- span:
+	default:
+		// never block the block processing on a slow exporter
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+func (t *Tracer) exportLoop() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		case <-t.quit:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					t.export(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) export(batch []*Span) []*Span {
+	if dropped := atomic.SwapUint64(&t.dropped, 0); dropped > 0 {
+		tracerLog.Warn("export queue is full, spans dropped", "count", dropped)
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+	spans := t.snapshot(batch)
+	for _, e := range t.exporters {
+		if err := e.Export(spans); err != nil {
+			tracerLog.Warn("export spans failed", "count", len(spans), "err", err)
+		}
+	}
+	return batch[:0]
+}
+
+// snapshot copies the spans under the lock so that the exporters read them without racing the tracer
+func (t *Tracer) snapshot(batch []*Span) []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]Span, len(batch))
+	for i, span := range batch {
+		spans[i] = *span
+		spans[i].tracer = nil
+	}
+	return spans
+}
+
+func makeAttrs(kv []string) []Attr {
+	if len(kv) == 0 {
+		return nil
+	}
+	attrs := make([]Attr, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		attr := Attr{Key: kv[i]}
+		if i+1 < len(kv) {
+			attr.Value = kv[i+1]
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+func newSpanId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,121 @@
+package blocktrace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+)
+
+func testHash(b byte) types.Hash {
+	var hash types.Hash
+	for i := range hash {
+		hash[i] = b
+	}
+	return hash
+}
+
+func TestTracer_Timeline(t *testing.T) {
+	tracer := NewTracer(0)
+	defer tracer.Stop()
+	hash := testHash(1)
+
+	assert.Nil(t, tracer.Timeline(hash))
+
+	tracer.Start(hash, StageNetReceive, "peer", "p1").Finish(nil)
+	tracer.Start(hash, StagePoolQueue)
+	time.Sleep(2 * time.Millisecond)
+	tracer.FinishStage(hash, StagePoolQueue, nil)
+	verify := tracer.Start(hash, StageVerify)
+	vm := verify.Child(StageVm)
+	vm.Finish(errors.New("vm failed"))
+	verify.SetAttr("result", "fail")
+	verify.Finish(nil)
+	tracer.Start(hash, StageChainInsert)
+
+	timeline := tracer.Timeline(hash)
+	assert.Equal(t, hash, timeline.Hash)
+	var names []string
+	for _, span := range timeline.Spans {
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{StageNetReceive, StagePoolQueue, StageVerify, StageVm, StageChainInsert}, names)
+	assert.Equal(t, map[string]string{"peer": "p1"}, timeline.Spans[0].Attrs)
+	assert.True(t, timeline.Spans[1].Finished)
+	assert.True(t, timeline.Spans[1].Duration >= 2)
+	assert.Equal(t, timeline.Spans[2].SpanId, timeline.Spans[3].ParentId)
+	assert.Equal(t, "vm failed", timeline.Spans[3].Err)
+	assert.Equal(t, "fail", timeline.Spans[2].Attrs["result"])
+	assert.False(t, timeline.Spans[4].Finished)
+	assert.True(t, timeline.Duration >= timeline.Spans[1].Duration)
+}
+
+func TestTracer_Capacity(t *testing.T) {
+	tracer := NewTracer(2)
+	defer tracer.Stop()
+
+	for i := byte(1); i <= 3; i++ {
+		tracer.Start(testHash(i), StageNetReceive).Finish(nil)
+	}
+	assert.Nil(t, tracer.Timeline(testHash(1)))
+	assert.NotNil(t, tracer.Timeline(testHash(2)))
+	assert.NotNil(t, tracer.Timeline(testHash(3)))
+}
+
+func TestDisabled(t *testing.T) {
+	Disable()
+	assert.False(t, Enabled())
+	span := Start(testHash(1), StageVerify)
+	assert.Nil(t, span)
+	span.Child(StageVm).Finish(nil)
+	span.Finish(nil)
+	FinishStage(testHash(1), StagePoolQueue, nil)
+	assert.Nil(t, GetTimeline(testHash(1)))
+}
+
+func TestExporters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blocktrace")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	received := make(chan *otlpTraces, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, otlpTracePath, r.URL.Path)
+		traces := &otlpTraces{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(traces))
+		received <- traces
+	}))
+	defer server.Close()
+
+	file := filepath.Join(dir, "trace", "blocks.json")
+	assert.NoError(t, Enable(Config{File: file, OtlpEndpoint: server.URL}))
+	hash := testHash(2)
+	Start(hash, StageChainInsert).Finish(errors.New("insert failed"))
+	Disable()
+
+	traces := <-received
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "02020202020202020202020202020202", spans[0].TraceId)
+	assert.Equal(t, StageChainInsert, spans[0].Name)
+	assert.Equal(t, otlpStatusError, spans[0].Status.Code)
+
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	assert.True(t, scanner.Scan())
+	fileTraces := &otlpTraces{}
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), fileTraces))
+	assert.Equal(t, spans, fileTraces.ResourceSpans[0].ScopeSpans[0].Spans)
+}
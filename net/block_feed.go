@@ -3,6 +3,7 @@ package net
 import (
 	"github.com/vitelabs/go-vite/common/types"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 )
 
 type blockFeeder interface {
@@ -92,8 +93,13 @@ type safeBlockNotifier struct {
 	Verifier
 }
 
-func (s *safeBlockNotifier) receiveAccountBlock(block *ledger.AccountBlock, source types.BlockSource) error {
-	err := s.Verifier.VerifyNetAccountBlock(block)
+func (s *safeBlockNotifier) receiveAccountBlock(block *ledger.AccountBlock, source types.BlockSource) (err error) {
+	span := blocktrace.Start(block.Hash, blocktrace.StageNetReceive, "source", source.String())
+	defer func() {
+		span.Finish(err)
+	}()
+
+	err = s.Verifier.VerifyNetAccountBlock(block)
 	if err != nil {
 		return err
 	}
@@ -13,6 +13,7 @@ import (
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 	"github.com/vitelabs/go-vite/tools/circle"
 )
 
@@ -538,6 +539,11 @@ func (b *broadcaster) handle(msg Msg) (err error) {
 			}
 		}
 
+		span := blocktrace.Start(hash, blocktrace.StageNetReceive, "peer", msg.Sender.String(), "trace", msg.TraceId, "source", types.BlockSource(types.RemoteBroadcast).String())
+		defer func() {
+			span.Finish(err)
+		}()
+
 		if err = b.verifier.VerifyNetAccountBlock(block); err != nil {
 			b.log.Error(fmt.Sprintf("verify new accountblock %s from %s error: %v", hash, msg.Sender, err))
 			return err
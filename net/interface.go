@@ -67,6 +67,8 @@ type Consensus interface {
 type Verifier interface {
 	VerifyNetSnapshotBlock(block *ledger.SnapshotBlock) error
 	VerifyNetAccountBlock(block *ledger.AccountBlock) error
+	// VerifyNetAccountBlocks verifies the blocks in parallel, the errors are in the order of the blocks
+	VerifyNetAccountBlocks(blocks []*ledger.AccountBlock) []error
 }
 
 // SnapshotBlockCallback will be invoked when receive a block,
@@ -28,6 +28,7 @@ import (
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/common/vitepb"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/log15"
 )
 
 const (
@@ -65,6 +66,16 @@ type Msg struct {
 	Payload    []byte
 	ReceivedAt int64
 	Sender     *Peer
+	// TraceId is assigned when the message is read, the logs of its handling carry it
+	TraceId string
+}
+
+// Log returns the logger carrying the trace id of the message
+func (m Msg) Log(l log15.Logger) log15.Logger {
+	if m.TraceId == "" {
+		return l
+	}
+	return l.New(log15.TraceKey, m.TraceId)
 }
 
 // Recycle will put Msg.Payload back to pool
@@ -179,7 +179,7 @@ func (q *queryHandler) loop() {
 			now := time.Now().Unix()
 			for _, msg := range tasks[:index] {
 				if now-msg.ReceivedAt > 20 {
-					q.log.Warn(fmt.Sprintf("fetch message from %s is expired", msg.Sender))
+					msg.Log(q.log).Warn(fmt.Sprintf("fetch message from %s is expired", msg.Sender))
 					continue
 				}
 				// allocate to handlers
@@ -270,6 +270,10 @@ func (c *checkHandler) handleGetHashHeightList(get *GetHashHeightList) (code Cod
 		point := points[i]
 		reader, err = c.chain.GetLedgerReaderByHeight(start, point.Height)
 		if err != nil {
+			if errors.Is(err, interfaces.ErrPruned) {
+				// the chunks can't be downloaded from this node
+				return CodeException, ExpMissing
+			}
 			break
 		}
 		point.Size = uint64(reader.Size())
@@ -315,7 +319,8 @@ func (s *getSnapshotBlocksHandler) handle(msg Msg) (err error) {
 	}
 	msg.Recycle()
 
-	netLog.Info(fmt.Sprintf("receive %s from %s", req, msg.Sender))
+	msgLog := msg.Log(netLog)
+	msgLog.Info(fmt.Sprintf("receive %s from %s", req, msg.Sender))
 
 	var block *ledger.SnapshotBlock
 	if req.From.Hash != types.ZERO_HASH {
@@ -325,7 +330,7 @@ func (s *getSnapshotBlocksHandler) handle(msg Msg) (err error) {
 	}
 
 	if err != nil || block == nil {
-		netLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
+		msgLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
 		return msg.Sender.send(CodeException, msg.Id, ExpMissing)
 	}
 
@@ -349,15 +354,15 @@ func (s *getSnapshotBlocksHandler) handle(msg Msg) (err error) {
 	for _, c := range chunks {
 		blocks, err = s.chain.GetSnapshotBlocksByHeight(c[0], true, c[1]-c[0]+1)
 		if err != nil || len(blocks) == 0 {
-			netLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
+			msgLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
 			return msg.Sender.send(CodeException, msg.Id, ExpMissing)
 		}
 
 		if err = msg.Sender.sendSnapshotBlocks(blocks, msg.Id); err != nil {
-			netLog.Error(fmt.Sprintf("send %d SnapshotBlocks to %s error: %v", len(blocks), msg.Sender, err))
+			msgLog.Error(fmt.Sprintf("send %d SnapshotBlocks to %s error: %v", len(blocks), msg.Sender, err))
 			return
 		} else {
-			netLog.Info(fmt.Sprintf("send %d SnapshotBlocks [%s/%d - %s/%d] to %s done", len(blocks), blocks[0].Hash, blocks[0].Height, blocks[len(blocks)-1].Hash, blocks[len(blocks)-1].Height, msg.Sender))
+			msgLog.Info(fmt.Sprintf("send %d SnapshotBlocks [%s/%d - %s/%d] to %s done", len(blocks), blocks[0].Hash, blocks[0].Height, blocks[len(blocks)-1].Hash, blocks[len(blocks)-1].Height, msg.Sender))
 		}
 	}
 
@@ -391,7 +396,8 @@ func (a *getAccountBlocksHandler) handle(msg Msg) (err error) {
 	}
 	msg.Recycle()
 
-	netLog.Info(fmt.Sprintf("receive %s from %s", req, msg.Sender))
+	msgLog := msg.Log(netLog)
+	msgLog.Info(fmt.Sprintf("receive %s from %s", req, msg.Sender))
 
 	var block *ledger.AccountBlock
 	if req.From.Hash != types.ZERO_HASH {
@@ -406,7 +412,7 @@ func (a *getAccountBlocksHandler) handle(msg Msg) (err error) {
 	}
 
 	if err != nil || block == nil {
-		netLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
+		msgLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
 		return msg.Sender.send(CodeException, msg.Id, ExpMissing)
 	}
 
@@ -432,15 +438,15 @@ func (a *getAccountBlocksHandler) handle(msg Msg) (err error) {
 	for _, c := range chunks {
 		blocks, err = a.chain.GetAccountBlocksByHeight(address, c[1], c[1]-c[0]+1)
 		if err != nil || len(blocks) == 0 {
-			netLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
+			msgLog.Warn(fmt.Sprintf("handle %s from %s error: %v", req, msg.Sender, err))
 			return msg.Sender.send(CodeException, msg.Id, ExpMissing)
 		}
 
 		if err = msg.Sender.sendAccountBlocks(blocks, msg.Id); err != nil {
-			netLog.Error(fmt.Sprintf("send %d AccountBlocks to %s error: %v", len(blocks), msg.Sender, err))
+			msgLog.Error(fmt.Sprintf("send %d AccountBlocks to %s error: %v", len(blocks), msg.Sender, err))
 			return
 		} else {
-			netLog.Info(fmt.Sprintf("send %d AccountBlocks [%s/%d - %s/%d] to %s done", len(blocks), blocks[0].Hash, blocks[0].Height, blocks[len(blocks)-1].Hash, blocks[len(blocks)-1].Height, msg.Sender))
+			msgLog.Info(fmt.Sprintf("send %d AccountBlocks [%s/%d - %s/%d] to %s done", len(blocks), blocks[0].Hash, blocks[0].Height, blocks[len(blocks)-1].Hash, blocks[len(blocks)-1].Height, msg.Sender))
 		}
 	}
 
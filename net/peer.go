@@ -296,6 +296,7 @@ func (p *Peer) readLoop() (err error) {
 
 		msg.ReceivedAt = time.Now().Unix()
 		msg.Sender = p
+		msg.TraceId = log15.NewTraceId()
 
 		switch msg.Code {
 		case CodeDisconnect:
@@ -331,7 +332,7 @@ func (p *Peer) handleLoop() (err error) {
 	for msg = range p.readQueue {
 		err = p.handler.handle(msg)
 		if err != nil {
-			p.log.Warn(fmt.Sprintf("failed to handle msg %d: %v", msg.Code, err))
+			msg.Log(p.log).Warn(fmt.Sprintf("failed to handle msg %d: %v", msg.Code, err))
 			return
 		}
 	}
@@ -39,6 +39,9 @@ var errReaderInterrupt = errors.New("cache reader interrupt")
 const maxQueueSize = 10 << 20 // 10MB
 const maxQueueLength = 5
 
+// verifyBatchSize is the most account blocks verified in a batch when reading a segment
+const verifyBatchSize = 1000
+
 type syncCacheReader interface {
 	ChunkReader
 	start()
@@ -633,6 +636,29 @@ func (s *cacheReader) read(c interfaces.Segment) (chunk *Chunk, fatal bool, err
 
 	chunk = newChunk(c.PrevHash, c.From-1, c.Hash, c.To, types.RemoteSync)
 
+	// the account blocks are verified in batches before they are added to the chunk in order
+	var pending []*ledger.AccountBlock
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if verified == false {
+			for i, verr := range s.verifier.VerifyNetAccountBlocks(pending) {
+				if verr != nil {
+					s.log.Warn(fmt.Sprintf("verify accountblock %s/%d error: %v", pending[i].Hash, pending[i].Height, verr))
+					return verr
+				}
+			}
+		}
+		for _, pab := range pending {
+			if aerr := chunk.addAccountBlock(pab); aerr != nil {
+				return aerr
+			}
+		}
+		pending = pending[:0]
+		return nil
+	}
+
 	var ab *ledger.AccountBlock
 	var sb *ledger.SnapshotBlock
 	for {
@@ -656,22 +682,23 @@ func (s *cacheReader) read(c interfaces.Segment) (chunk *Chunk, fatal bool, err
 				break
 			}
 
-			if verified == false {
-				if err = s.verifier.VerifyNetAccountBlock(ab); err != nil {
+			pending = append(pending, ab)
+			if len(pending) >= verifyBatchSize {
+				if err = flush(); err != nil {
 					break
 				}
 			}
 
-			if err = chunk.addAccountBlock(ab); err != nil {
-				break
-			}
-
 		} else if sb != nil {
 			if _, ok := s.blackBlocks[sb.Hash]; ok {
 				s.log.Warn(fmt.Sprintf("snapshotblock %s is in blacklist", sb.Hash))
 				break
 			}
 
+			if err = flush(); err != nil {
+				break
+			}
+
 			if verified == false {
 				if err = s.verifier.VerifyNetSnapshotBlock(sb); err != nil {
 					break
@@ -687,7 +714,9 @@ func (s *cacheReader) read(c interfaces.Segment) (chunk *Chunk, fatal bool, err
 	_ = reader.Close()
 
 	if err == io.EOF {
-		err = chunk.done()
+		if err = flush(); err == nil {
+			err = chunk.done()
+		}
 	}
 
 	// no error, set reader verified
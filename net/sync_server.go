@@ -189,12 +189,19 @@ func (s *syncServer) handleConn(conn net2.Conn) {
 		var reader interfaces.LedgerReader
 		reader, err = s.chain.GetLedgerReaderByHeight(request.from, request.to)
 		if err != nil {
-			s.log.Error(fmt.Sprintf("failed to read chunk<%d-%d> from %s error: %v", request.from, request.to, conn.RemoteAddr(), err))
+			exp := ExpServerError
+			if errors.Is(err, interfaces.ErrPruned) {
+				// the block files are pruned, the peer should download the chunk from others
+				exp = ExpMissing
+				s.log.Warn(fmt.Sprintf("chunk<%d-%d> requested by %s is pruned", request.from, request.to, conn.RemoteAddr()))
+			} else {
+				s.log.Error(fmt.Sprintf("failed to read chunk<%d-%d> from %s error: %v", request.from, request.to, conn.RemoteAddr(), err))
+			}
 
 			_ = sconn.c.WriteMsg(Msg{
 				Code:    CodeException,
 				Id:      msg.Id,
-				Payload: []byte{byte(ExpServerError)},
+				Payload: []byte{byte(exp)},
 			})
 
 			continue
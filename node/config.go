@@ -14,6 +14,8 @@ import (
 	"github.com/vitelabs/go-vite/common/config"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/crypto/ed25519"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
+	"github.com/vitelabs/go-vite/rpcapi/api/filters/sink"
 )
 
 type Config struct {
@@ -23,6 +25,12 @@ type Config struct {
 
 	KeyStoreDir string `json:"KeyStoreDir"`
 
+	KeystoreKDF          string `json:"KeystoreKDF"`          // opt in the version 2 entropy store files with the kdf, scrypt or argon2id
+	KeystoreScryptN      int    `json:"KeystoreScryptN"`      // scrypt N, 0 for the default
+	KeystoreArgon2Time   uint32 `json:"KeystoreArgon2Time"`   // argon2id iterations, 0 for the default
+	KeystoreArgon2Memory uint32 `json:"KeystoreArgon2Memory"` // argon2id memory in KiB, 0 for the default
+	KeystoreUpgrade      bool   `json:"KeystoreUpgrade"`      // upgrade the old entropy store files on unlock
+
 	// chain
 	LedgerGcRetain uint64          `json:"LedgerGcRetain"`
 	LedgerGc       *bool           `json:"LedgerGc"`
@@ -30,6 +38,21 @@ type Config struct {
 	VmLogWhiteList []types.Address `json:"vmLogWhiteList"` // contract address white list which save VM logs
 	VmLogAll       *bool           `json:"vmLogAll"`       // save all VM logs, it will cost more disk space
 
+	StoreBackends map[string]string `json:"StoreBackends"` // storage backend of index, state, redo and plugins store
+
+	PruneMode          string `json:"PruneMode"`          // archive, full or pruned
+	PruneRetainDays    uint64 `json:"PruneRetainDays"`    // days of VM logs, state history and block bodies to retain
+	PruneKeysPerSecond uint64 `json:"PruneKeysPerSecond"` // throttle of the background pruner
+
+	AccountCompaction     bool            `json:"AccountCompaction"`     // index the old account blocks by checkpoints
+	AccountCompactionList []types.Address `json:"AccountCompactionList"` // the accounts to compact, all accounts if empty
+	AccountColdTier       bool            `json:"AccountColdTier"`       // copy the compacted account blocks to compressed segment files
+
+	FlushInterval      uint64 `json:"FlushInterval"`      // max interval between two flushes of the ledger in milliseconds
+	FlushDirtyBytes    uint64 `json:"FlushDirtyBytes"`    // flush when the unflushed data reaches the size
+	FlushSnapshotCount uint64 `json:"FlushSnapshotCount"` // flush when the number of the inserted snapshot blocks reaches it
+	FlushFsync         string `json:"FlushFsync"`         // fsync of the store commits: redo, always or none, the redo log is always synced
+
 	// genesis
 	GenesisFile string `json:"GenesisFile"`
 
@@ -87,10 +110,37 @@ type Config struct {
 	TestTokenTti        string   `json:"TestTokenTti"`
 
 	PowServerUrl string `json:"PowServerUrl"`
+	// PowThreads is the number of the workers of the local pow solver, the number of cpus is used if 0
+	PowThreads int `json:"PowThreads"`
+	// PowLocalSolver makes the pow rpc solve the pow by the local solver if PowServerUrl is empty, it's disabled by
+	// default since the rpc callers can use up the cpus of the node
+	PowLocalSolver bool `json:"PowLocalSolver"`
+	// InsertParallel is the most accounts whose blocks are verified and inserted at the same time during the sync,
+	// the number of cpus is used if 0
+	InsertParallel int `json:"InsertParallel"`
 
 	//Log level
 	LogLevel    string `json:"LogLevel"`
 	ErrorLogDir string `json:"ErrorLogDir"`
+	// LogModuleLevels are the levels of the modules keyed on the module context key of the loggers, e.g. {"net": "warn"}
+	LogModuleLevels map[string]string `json:"LogModuleLevels"`
+	// LogFormat is logfmt or json
+	LogFormat string `json:"LogFormat"`
+	// LogMaxSize in MB, LogMaxAge in days and LogMaxBackups are the rotation of the log files, the unset ones are the
+	// defaults, a LogMaxAge or LogMaxBackups of 0 keeps the rotated files regardless of their age or number
+	LogMaxSize    int  `json:"LogMaxSize"`
+	LogMaxAge     *int `json:"LogMaxAge"`
+	LogMaxBackups *int `json:"LogMaxBackups"`
+
+	// BlockTrace records the stages of the account blocks from the receipt to the insertion for debug_blockTimeline,
+	// the spans are exported to the file relative to the data dir and to the otlp/http collector if set
+	BlockTraceEnabled  bool   `json:"BlockTraceEnabled"`
+	BlockTraceCapacity int    `json:"BlockTraceCapacity"`
+	BlockTraceFile     string `json:"BlockTraceFile"`
+	BlockTraceEndpoint string `json:"BlockTraceEndpoint"`
+
+	// Sinks deliver the matched account blocks and vm logs to webhooks, files and queues, see rpcapi/api/filters/sink
+	Sinks []*sink.Config `json:"Sinks"`
 
 	//VM
 	VMTestEnabled         bool `json:"VMTestEnabled"`
@@ -104,6 +154,10 @@ type Config struct {
 	// dashboard
 	DashboardTargetURL string
 
+	// abi registry
+	AbiDir     string `json:"AbiDir"`
+	SolppcPath string `json:"SolppcPath"`
+
 	// reward
 	RewardAddr string `json:"RewardAddr"`
 
@@ -118,7 +172,14 @@ type Config struct {
 }
 
 func (c *Config) makeWalletConfig() *config.Wallet {
-	return &config.Wallet{DataDir: c.KeyStoreDir}
+	return &config.Wallet{
+		DataDir:              c.KeyStoreDir,
+		KeystoreKDF:          c.KeystoreKDF,
+		KeystoreScryptN:      c.KeystoreScryptN,
+		KeystoreArgon2Time:   c.KeystoreArgon2Time,
+		KeystoreArgon2Memory: c.KeystoreArgon2Memory,
+		KeystoreUpgrade:      c.KeystoreUpgrade,
+	}
 }
 
 func (c *Config) makeViteConfig() *config.Config {
@@ -131,6 +192,7 @@ func (c *Config) makeViteConfig() *config.Config {
 		Subscribe:  c.makeSubscribeConfig(),
 		NodeReward: c.makeRewardConfig(),
 		Genesis:    config.MakeGenesisConfig(c.GenesisFile),
+		Registry:   c.makeRegistryConfig(),
 		LogLevel:   c.LogLevel,
 	}
 }
@@ -188,6 +250,13 @@ func (c *Config) makeSubscribeConfig() *config.Subscribe {
 		IsSubscribe: c.SubscribeEnabled,
 	}
 }
+func (c *Config) makeRegistryConfig() *config.Registry {
+	return &config.Registry{
+		AbiDir:     c.AbiDir,
+		SolppcPath: c.SolppcPath,
+	}
+}
+
 func (c *Config) makeMinerConfig() *config.Producer {
 	cfg := &config.Producer{
 		Producer:         c.MinerEnabled,
@@ -220,12 +289,35 @@ func (c *Config) makeChainConfig() *config.Chain {
 	if c.VmLogAll != nil {
 		vmLogAll = *c.VmLogAll
 	}
+
+	pruneRetainDays := c.PruneRetainDays
+	if pruneRetainDays <= 0 {
+		pruneRetainDays = config.DefaultPruneRetainDays
+	}
+	pruneKeysPerSecond := c.PruneKeysPerSecond
+	if pruneKeysPerSecond <= 0 {
+		pruneKeysPerSecond = config.DefaultPruneKeysPerSecond
+	}
 	return &config.Chain{
 		LedgerGcRetain: c.LedgerGcRetain,
 		LedgerGc:       ledgerGc,
 		OpenPlugins:    openPlugins,
 		VmLogWhiteList: c.VmLogWhiteList,
 		VmLogAll:       vmLogAll,
+		StoreBackends:  c.StoreBackends,
+
+		PruneMode:          c.PruneMode,
+		PruneRetainDays:    pruneRetainDays,
+		PruneKeysPerSecond: pruneKeysPerSecond,
+
+		AccountCompaction:     c.AccountCompaction,
+		AccountCompactionList: c.AccountCompactionList,
+		AccountColdTier:       c.AccountColdTier,
+
+		FlushInterval:      c.FlushInterval,
+		FlushDirtyBytes:    c.FlushDirtyBytes,
+		FlushSnapshotCount: c.FlushSnapshotCount,
+		FlushFsync:         c.FlushFsync,
 	}
 }
 
@@ -280,6 +372,24 @@ func (c *Config) IPCEndpoint() string {
 	return c.IPCPath
 }
 
+// BlockTraceConfig returns the tracing of the blocks, nil if it is disabled
+func (c *Config) BlockTraceConfig() *blocktrace.Config {
+	if !c.BlockTraceEnabled {
+		return nil
+	}
+	cfg := &blocktrace.Config{
+		Capacity:     c.BlockTraceCapacity,
+		OtlpEndpoint: c.BlockTraceEndpoint,
+	}
+	if c.BlockTraceFile != "" {
+		cfg.File = c.BlockTraceFile
+		if !filepath.IsAbs(cfg.File) {
+			cfg.File = filepath.Join(c.DataDir, cfg.File)
+		}
+	}
+	return cfg
+}
+
 func (c *Config) RunLogDir() string {
 	return filepath.Join(c.DataDir, "runlog", time.Now().Format("2006-01-02T15-04"))
 }
@@ -15,13 +15,16 @@ import (
 	"github.com/vitelabs/go-vite"
 	"github.com/vitelabs/go-vite/cmd/utils/flock"
 	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/ledger/pool/batch"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/monitor"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
 	"github.com/vitelabs/go-vite/pow"
 	"github.com/vitelabs/go-vite/pow/remote"
 	"github.com/vitelabs/go-vite/rpc"
 	"github.com/vitelabs/go-vite/rpcapi"
 	"github.com/vitelabs/go-vite/rpcapi/api/filters"
+	"github.com/vitelabs/go-vite/rpcapi/api/filters/sink"
 	"github.com/vitelabs/go-vite/wallet"
 )
 
@@ -121,6 +124,16 @@ func (node *Node) Prepare() (err error) {
 	//init rpc_PowServerUrl
 	remote.InitRawUrl(node.Config().PowServerUrl)
 	pow.Init(node.Config().VMTestParamEnabled)
+	pow.SetThreads(node.Config().PowThreads)
+	pow.EnableLocalSolver(node.Config().PowLocalSolver)
+	batch.SetMaxParallel(node.Config().InsertParallel)
+
+	if cfg := node.Config().BlockTraceConfig(); cfg != nil {
+		if err = blocktrace.Enable(*cfg); err != nil {
+			log.Error(fmt.Sprintf("block trace enable error: %v", err))
+			return err
+		}
+	}
 
 	// Start vite
 	if err = node.viteServer.Init(); err != nil {
@@ -266,7 +279,23 @@ func (node *Node) startWallet() (err error) {
 }
 
 func (node *Node) startVite() error {
-	return node.viteServer.Start()
+	if err := node.viteServer.Start(); err != nil {
+		return err
+	}
+	return node.startSinks()
+}
+
+func (node *Node) startSinks() error {
+	if len(node.config.Sinks) == 0 {
+		return nil
+	}
+	m, err := sink.NewManager(node.viteServer.Chain(), node.config.DataDir, node.config.Sinks)
+	if err != nil {
+		return err
+	}
+	sink.Sinks = m
+	m.Start()
+	return nil
 }
 
 func (node *Node) startRPC() (e error) {
@@ -377,7 +406,12 @@ func (node *Node) stopVite() error {
 		return ErrNodeStopped
 	}
 
+	if sink.Sinks != nil {
+		sink.Sinks.Stop()
+		sink.Sinks = nil
+	}
 	node.viteServer.Stop()
+	blocktrace.Disable()
 	return nil
 }
 
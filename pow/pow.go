@@ -23,11 +23,20 @@ const (
 var defaultTarget = new(big.Int).SetUint64(FullThreshold)
 var VMTestParamEnabled = false
 
+var defaultSolver = NewSolver(0)
+
+// localSolverEnabled is whether the rpc solves the pow by the local solver when no remote pow server is configured
+var localSolverEnabled = false
+
+const solveTimeout = 10 * time.Minute
+
 func Init(vMTestParamEnabled bool) {
 	VMTestParamEnabled = vMTestParamEnabled
 }
 
 // data = Hash(address + prev_hash); data + nonce < target.
+// GetPowNonce searches the nonce by the parallel workers of the default solver, it returns ErrPowCancelled if
+// the work is cancelled by CancelPowNonce.
 func GetPowNonce(difficulty *big.Int, dataHash types.Hash) ([]byte, error) {
 	var target *big.Int = nil
 	if VMTestParamEnabled {
@@ -41,26 +50,27 @@ func GetPowNonce(difficulty *big.Int, dataHash types.Hash) ([]byte, error) {
 			return nil, errors.New("target too long")
 		}
 	}
+	return defaultSolver.Solve(target, dataHash, solveTimeout)
+}
 
-	data := dataHash.Bytes()
-	target256 := helper.LeftPadBytes(target.Bytes(), 32)
-	i := 0
-	t := time.Now()
-	for {
-		nonce := crypto.GetEntropyCSPRNG(8)
-		out := powHash256(nonce, data)
-		if QuickGreater(out, target256) {
-			return nonce, nil
-		}
-		i++
-		if i > 10000 {
-			if time.Now().Sub(t).Minutes() > 10 {
-				break
-			}
-			i = 0
-		}
-	}
-	return nil, errors.New("get pow nonce error")
+// CancelPowNonce cancels the works of GetPowNonce on the data hash in progress, it returns false if there is none.
+func CancelPowNonce(dataHash types.Hash) bool {
+	return defaultSolver.Cancel(dataHash)
+}
+
+// EnableLocalSolver makes the rpc solve the pow by the local solver when no remote pow server is configured
+func EnableLocalSolver(enabled bool) {
+	localSolverEnabled = enabled
+}
+
+// LocalSolverEnabled returns whether the rpc can solve the pow by the local solver
+func LocalSolverEnabled() bool {
+	return localSolverEnabled
+}
+
+// SetThreads sets the number of the workers of GetPowNonce, the number of cpus is used if threads <= 0
+func SetThreads(threads int) {
+	defaultSolver.SetThreads(threads)
 }
 
 // data = Hash(address + prev_hash); data + nonce < target.
@@ -170,6 +180,15 @@ func CheckPowNonce(difficulty *big.Int, nonce []byte, data []byte) bool {
 	return QuickGreater(out, helper.LeftPadBytes(target.Bytes(), 32))
 }
 
+// CheckPowNonceByTarget checks the nonce against the target directly, as the threshold of the remote works
+func CheckPowNonceByTarget(target *big.Int, nonce []byte, data []byte) bool {
+	if target == nil || target.Sign() < 0 || target.BitLen() > 256 {
+		return false
+	}
+	out := powHash256(nonce, data)
+	return QuickGreater(out, helper.LeftPadBytes(target.Bytes(), 32))
+}
+
 func QuickInc(x []byte) []byte {
 	for i := 1; i <= len(x); i++ {
 		x[len(x)-i] = x[len(x)-i] + 1
@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/pow"
+)
+
+var powServerLog = log15.New("module", "pow_server")
+
+const (
+	maxRequestSize = 1024
+
+	// DefaultWorkTimeout is the longest time of a generate request
+	DefaultWorkTimeout = 10 * time.Minute
+)
+
+// Server is a local stand-in of the remote pow service, it serves the generate, validate and cancel works of
+// the remote protocol by a parallel solver.
+type Server struct {
+	solver  *pow.Solver
+	timeout time.Duration
+	mux     *http.ServeMux
+}
+
+// NewServer returns a server solving each work by the threads workers, the number of cpus is used if threads <= 0
+func NewServer(threads int, timeout time.Duration) *Server {
+	if timeout <= 0 {
+		timeout = DefaultWorkTimeout
+	}
+	s := &Server{
+		solver:  pow.NewSolver(threads),
+		timeout: timeout,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc(ApiActionGenerate, s.handleGenerate)
+	s.mux.HandleFunc(ApiActionValidate, s.handleValidate)
+	s.mux.HandleFunc(ApiActionCancel, s.handleCancel)
+	return s
+}
+
+func (s *Server) Threads() int {
+	return s.solver.Threads()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	req := &workGenerate{}
+	if err := readRequest(w, r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	dataHash, err := parseDataHash(req.DataHash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	target, err := parseThreshold(req.Threshold)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	startTime := time.Now()
+	nonce, err := s.solver.Solve(target, dataHash, s.timeout)
+	if err != nil {
+		powServerLog.Info("generate work failed", "hash", dataHash, "err", err)
+		writeError(w, err)
+		return
+	}
+	powServerLog.Info("work generated", "hash", dataHash, "elapsed", time.Since(startTime))
+	writeResult(w, &workGenerateResult{
+		Work: strconv.FormatUint(binary.LittleEndian.Uint64(nonce), 16),
+	})
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	req := &workValidate{}
+	if err := readRequest(w, r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	dataHash, err := parseDataHash(req.DataHash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	target, err := parseThreshold(req.Threshold)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	work, err := hex.DecodeString(req.Work)
+	if err != nil {
+		writeError(w, errors.Wrap(err, "invalid work"))
+		return
+	}
+	result := &workValidateResult{Valid: "0"}
+	if pow.CheckPowNonceByTarget(target, work, dataHash.Bytes()) {
+		result.Valid = "1"
+	}
+	writeResult(w, result)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	req := &workCancel{}
+	if err := readRequest(w, r, req); err != nil {
+		writeError(w, err)
+		return
+	}
+	dataHash, err := parseDataHash(req.DataHash)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if s.solver.Cancel(dataHash) {
+		powServerLog.Info("work cancelled", "hash", dataHash)
+	}
+	writeResult(w, &workCancelResult{})
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request, req interface{}) error {
+	if r.Method != http.MethodPost {
+		return errors.Errorf("method %v not allowed", r.Method)
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, req)
+}
+
+func parseDataHash(s string) (types.Hash, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return types.Hash{}, errors.Wrap(err, "invalid hash")
+	}
+	return types.BytesToHash(data)
+}
+
+func parseThreshold(s string) (*big.Int, error) {
+	target, ok := new(big.Int).SetString(s, 16)
+	if !ok || target.Sign() < 0 || target.BitLen() > 256 {
+		return nil, errors.Errorf("invalid threshold %v", s)
+	}
+	return target, nil
+}
+
+func writeResult(w http.ResponseWriter, data interface{}) {
+	writeResponse(w, &ResponseJson{Data: data})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeResponse(w, &ResponseJson{Code: 1, Error: err.Error()})
+}
+
+func writeResponse(w http.ResponseWriter, resp *ResponseJson) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		powServerLog.Error("write response failed", "err", err)
+	}
+}
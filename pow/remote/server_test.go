@@ -0,0 +1,44 @@
+package remote
+
+import (
+	"encoding/binary"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/pow"
+)
+
+func TestServer(t *testing.T) {
+	server := httptest.NewServer(NewServer(2, time.Minute))
+	defer server.Close()
+	defer InitRawUrl(requestUrl)
+	InitRawUrl(server.URL)
+
+	difficulty := big.NewInt(1 << 20)
+	data := types.DataHash([]byte{1})
+	work, err := GenerateWork(data.Bytes(), difficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonceBig, ok := new(big.Int).SetString(*work, 16)
+	assert.True(t, ok)
+	nonce := make([]byte, 8)
+	binary.LittleEndian.PutUint64(nonce, nonceBig.Uint64())
+	assert.True(t, pow.CheckPowNonce(difficulty, nonce, data.Bytes()))
+
+	valid, err := VaildateWork(data.Bytes(), pow.DifficultyToTarget(difficulty), nonce)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = VaildateWork(data.Bytes(), pow.DifficultyToTarget(difficulty), []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	assert.NoError(t, CancelWork(data.Bytes()))
+	_, err = GenerateWork([]byte{1}, difficulty)
+	assert.Error(t, err)
+}
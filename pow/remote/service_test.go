@@ -15,7 +15,6 @@ import (
 
 func init() {
 	flag.StringVar(&requestUrl, "url", "", "")
-	flag.Parse()
 }
 
 func TestPowGenerate(t *testing.T) {
@@ -0,0 +1,163 @@
+package pow
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/crypto"
+)
+
+var (
+	ErrPowCancelled = errors.New("pow cancelled")
+	ErrPowTimeout   = errors.New("get pow nonce error")
+)
+
+// the workers check the quit signal once every checkInterval nonces
+const checkInterval = 1 << 12
+
+type solverWork struct {
+	quit chan struct{}
+	once sync.Once
+}
+
+func (w *solverWork) stop() {
+	w.once.Do(func() {
+		close(w.quit)
+	})
+}
+
+// Solver searches the pow nonces by parallel workers, the works in progress can be cancelled by their data hashes.
+type Solver struct {
+	threads int32
+
+	mu    sync.Mutex
+	works map[types.Hash]map[*solverWork]struct{}
+}
+
+// NewSolver returns a solver running the threads workers for each work, the number of cpus is used if threads <= 0
+func NewSolver(threads int) *Solver {
+	s := &Solver{works: make(map[types.Hash]map[*solverWork]struct{})}
+	s.SetThreads(threads)
+	return s
+}
+
+// SetThreads changes the number of the workers of the subsequent works, the number of cpus is used if threads <= 0
+func (s *Solver) SetThreads(threads int) {
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	atomic.StoreInt32(&s.threads, int32(threads))
+}
+
+func (s *Solver) Threads() int {
+	return int(atomic.LoadInt32(&s.threads))
+}
+
+// Solve returns a nonce whose pow hash with the data hash is not less than the target. ErrPowCancelled is returned
+// if the work is cancelled, and ErrPowTimeout if no nonce is found in the timeout.
+func (s *Solver) Solve(target *big.Int, dataHash types.Hash, timeout time.Duration) ([]byte, error) {
+	if target == nil || target.Sign() < 0 || target.BitLen() > 256 {
+		return nil, errors.New("target too long")
+	}
+	target256 := helper.LeftPadBytes(target.Bytes(), 32)
+
+	w := &solverWork{quit: make(chan struct{})}
+	s.addWork(dataHash, w)
+	defer s.removeWork(dataHash, w)
+	defer w.stop()
+
+	found := make(chan []byte, 1)
+	threads := s.Threads()
+	for i := 0; i < threads; i++ {
+		go solveWorker(dataHash.Bytes(), target256, w.quit, found)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case nonce := <-found:
+		return nonce, nil
+	case <-w.quit:
+		return nil, ErrPowCancelled
+	case <-timer.C:
+		return nil, ErrPowTimeout
+	}
+}
+
+// Cancel stops all the works of the data hash in progress, it returns false if there is none.
+func (s *Solver) Cancel(dataHash types.Hash) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	works, ok := s.works[dataHash]
+	if !ok {
+		return false
+	}
+	for w := range works {
+		w.stop()
+	}
+	delete(s.works, dataHash)
+	return true
+}
+
+func (s *Solver) addWork(dataHash types.Hash, w *solverWork) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	works, ok := s.works[dataHash]
+	if !ok {
+		works = make(map[*solverWork]struct{})
+		s.works[dataHash] = works
+	}
+	works[w] = struct{}{}
+}
+
+func (s *Solver) removeWork(dataHash types.Hash, w *solverWork) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	works, ok := s.works[dataHash]
+	if !ok {
+		return
+	}
+	delete(works, w)
+	if len(works) == 0 {
+		delete(s.works, dataHash)
+	}
+}
+
+// solveWorker iterates the nonces from a random start, the nonce is encoded in little endian as the remote works.
+func solveWorker(data []byte, target256 []byte, quit chan struct{}, found chan []byte) {
+	n := binary.LittleEndian.Uint64(crypto.GetEntropyCSPRNG(8))
+	nonce := make([]byte, 8)
+	out := make([]byte, 0, blake2b.Size256)
+	hash, _ := blake2b.New256(nil)
+	for {
+		for i := 0; i < checkInterval; i++ {
+			binary.LittleEndian.PutUint64(nonce, n)
+			hash.Write(nonce)
+			hash.Write(data)
+			out = hash.Sum(out[:0])
+			if QuickGreater(out, target256) {
+				select {
+				case found <- nonce:
+				default:
+				}
+				return
+			}
+			hash.Reset()
+			n++
+		}
+		select {
+		case <-quit:
+			return
+		default:
+		}
+	}
+}
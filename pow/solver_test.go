@@ -0,0 +1,55 @@
+package pow_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/pow"
+)
+
+func TestSolver_Solve(t *testing.T) {
+	difficulty := big.NewInt(1 << 20)
+	data := types.DataHash([]byte{1})
+	solver := pow.NewSolver(4)
+	assert.Equal(t, 4, solver.Threads())
+
+	nonce, err := solver.Solve(pow.DifficultyToTarget(difficulty), data, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, pow.CheckPowNonce(difficulty, nonce, data.Bytes()))
+	assert.True(t, pow.CheckPowNonceByTarget(pow.DifficultyToTarget(difficulty), nonce, data.Bytes()))
+	assert.False(t, solver.Cancel(data))
+}
+
+func TestSolver_Cancel(t *testing.T) {
+	// the target can't be reached
+	target := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	data := types.DataHash([]byte{2})
+	solver := pow.NewSolver(2)
+
+	result := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := solver.Solve(target, data, time.Minute)
+			result <- err
+		}()
+	}
+	timeout := time.After(10 * time.Second)
+	for i := 0; i < 2; {
+		solver.Cancel(data)
+		select {
+		case err := <-result:
+			assert.Equal(t, pow.ErrPowCancelled, err)
+			i++
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("work not cancelled")
+		}
+	}
+
+	_, err := solver.Solve(target, data, 100*time.Millisecond)
+	assert.Equal(t, pow.ErrPowTimeout, err)
+}
@@ -261,6 +261,16 @@ func (c *Client) SupportedModules() (map[string]string, error) {
 	return result, err
 }
 
+// SupportedMethods calls the rpc_methods method, retrieving the methods and
+// their argument types of the APIs that are available on the server.
+func (c *Client) SupportedMethods() (map[string][]*MethodInfo, error) {
+	var result map[string][]*MethodInfo
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+	err := c.CallContext(ctx, &result, "rpc_methods")
+	return result, err
+}
+
 // Close closes the client, aborting any in-flight requests.
 func (c *Client) Close() {
 	if c.isHTTP {
@@ -39,6 +39,9 @@ import (
 const (
 	contentType             = "application/json"
 	maxRequestContentLength = 1024 * 128
+
+	// TraceIdHeader is the header of the trace id of a request, it is echoed in the response
+	TraceIdHeader = "X-Trace-Id"
 )
 
 var nullAddr, _ = net.ResolveTCPAddr("tcp", "127.0.0.1:0")
@@ -251,6 +254,12 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
+	traceId := r.Header.Get(TraceIdHeader)
+	if !log.ValidTraceId(traceId) {
+		traceId = log.NewTraceId()
+	}
+	ctx = log.ContextWithTraceId(ctx, traceId)
+	w.Header().Set(TraceIdHeader, traceId)
 
 	body := io.LimitReader(r.Body, maxRequestContentLength)
 	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
@@ -17,10 +17,14 @@
 package rpc
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	log "github.com/vitelabs/go-vite/log15"
 )
 
 func TestHTTPErrorResponseWithDelete(t *testing.T) {
@@ -52,3 +56,42 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+type TraceService struct{}
+
+func (s *TraceService) TraceId(ctx context.Context) string {
+	return log.TraceIdFromContext(ctx)
+}
+
+func TestHTTPTraceId(t *testing.T) {
+	server := newTestServer("test", new(TraceService))
+	defer server.Stop()
+
+	call := func(traceId string) (string, string) {
+		body := `{"jsonrpc":"2.0","id":1,"method":"test_traceId","params":[]}`
+		request := httptest.NewRequest(http.MethodPost, "http://url.com", strings.NewReader(body))
+		request.Header.Set("content-type", contentType)
+		if traceId != "" {
+			request.Header.Set(TraceIdHeader, traceId)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+
+		var resp struct {
+			Result string `json:"result"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Result, recorder.Header().Get(TraceIdHeader)
+	}
+
+	result, header := call("client-trace.1")
+	if result != "client-trace.1" || header != "client-trace.1" {
+		t.Fatalf("the trace id of the client should be used, got %q in the api and %q in the header", result, header)
+	}
+	result, header = call("bad trace\n")
+	if result == "" || result == "bad trace\n" || header != result {
+		t.Fatalf("an invalid trace id should be replaced, got %q in the api and %q in the header", result, header)
+	}
+}
@@ -22,9 +22,11 @@ import (
 	"github.com/vitelabs/go-vite/rpcapi/api"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	log "github.com/vitelabs/go-vite/log15"
@@ -74,6 +76,41 @@ func (s *RPCService) Modules() map[string]string {
 	return modules
 }
 
+// MethodInfo describes an RPC method and the types of its arguments, the trailing pointer arguments are optional
+type MethodInfo struct {
+	Name      string   `json:"name"`
+	Args      []string `json:"args"`
+	Subscribe bool     `json:"subscribe,omitempty"`
+}
+
+// Methods returns the methods of the RPC services sorted by name, the subscriptions are included with the
+// subscribe flag set
+func (s *RPCService) Methods() map[string][]*MethodInfo {
+	methods := make(map[string][]*MethodInfo)
+	for name, svc := range s.server.services {
+		infos := make([]*MethodInfo, 0, len(svc.callbacks)+len(svc.subscriptions))
+		for method, cb := range svc.callbacks {
+			infos = append(infos, newMethodInfo(method, cb))
+		}
+		for method, cb := range svc.subscriptions {
+			infos = append(infos, newMethodInfo(method, cb))
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Name < infos[j].Name
+		})
+		methods[name] = infos
+	}
+	return methods
+}
+
+func newMethodInfo(name string, cb *callback) *MethodInfo {
+	args := make([]string, len(cb.argTypes))
+	for i, t := range cb.argTypes {
+		args[i] = t.String()
+	}
+	return &MethodInfo{Name: name, Args: args, Subscribe: cb.isSubscribe}
+}
+
 // RegisterName will create chain service for the given rcvr type under the given name. When no methods on the given rcvr
 // match the criteria to be either chain RPC method or chain subscription an error is returned. Otherwise chain new service is
 // created and added to the service collection this server instance serves.
@@ -265,6 +302,11 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
 
+	// every request carries a trace id into the logs of the api, http requests may bring their own
+	if log.TraceIdFromContext(ctx) == "" {
+		ctx = log.ContextWithTraceId(ctx, log.NewTraceId())
+	}
+
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
 			notifier, supported := NotifierFromContext(ctx)
@@ -321,7 +363,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		}
 	}()
 	// execute RPC method and return result
+	startTime := time.Now()
 	reply := req.callb.method.Func.Call(arguments)
+	log.Debug("rpc call", log.TraceKey, log.TraceIdFromContext(ctx),
+		"method", req.svcname+serviceMethodSeparator+req.callb.method.Name, "elapsed", time.Since(startTime))
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
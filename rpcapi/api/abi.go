@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sort"
+
+	"github.com/vitelabs/go-vite"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger/abiregistry"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+var errAbiRegistryUnavailable = errors.New("abi registry is not available")
+
+type PublicAbiApi struct {
+	registry *abiregistry.Registry
+	log      log15.Logger
+}
+
+func NewPublicAbiApi(vite *vite.Vite) *PublicAbiApi {
+	return &PublicAbiApi{
+		registry: vite.AbiRegistry(),
+		log:      log15.New("module", "rpc_api/abi_api"),
+	}
+}
+
+func (p PublicAbiApi) String() string {
+	return "PublicAbiApi"
+}
+
+func (p *PublicAbiApi) GetContractAbi(addr types.Address) (*abiregistry.ContractAbi, error) {
+	if p.registry == nil {
+		return nil, errAbiRegistryUnavailable
+	}
+	return p.registry.GetContractAbi(addr)
+}
+
+func (p *PublicAbiApi) GetContractAbiAddressList() ([]types.Address, error) {
+	if p.registry == nil {
+		return nil, errAbiRegistryUnavailable
+	}
+	list := p.registry.Addresses()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].String() < list[j].String()
+	})
+	return list, nil
+}
+
+func (p *PublicAbiApi) DecodeCallData(addr types.Address, data []byte) (*abiregistry.DecodedCall, error) {
+	if p.registry == nil {
+		return nil, errAbiRegistryUnavailable
+	}
+	return p.registry.DecodeCallData(addr, data), nil
+}
+
+func (p *PublicAbiApi) GetVerifiedSource(addr types.Address) (*abiregistry.VerifiedSource, error) {
+	if p.registry == nil {
+		return nil, errAbiRegistryUnavailable
+	}
+	return p.registry.GetVerifiedSource(addr)
+}
+
+type PrivateAbiApi struct {
+	registry *abiregistry.Registry
+	log      log15.Logger
+}
+
+func NewPrivateAbiApi(vite *vite.Vite) *PrivateAbiApi {
+	return &PrivateAbiApi{
+		registry: vite.AbiRegistry(),
+		log:      log15.New("module", "rpc_api/abi_api"),
+	}
+}
+
+func (p PrivateAbiApi) String() string {
+	return "PrivateAbiApi"
+}
+
+// RegisterContractAbiParam registers an unverified abi, CodeHash optionally pins the code on chain the abi is for
+type RegisterContractAbiParam struct {
+	Addr     types.Address `json:"address"`
+	Abi      string        `json:"abi"`
+	CodeHash *types.Hash   `json:"codeHash"`
+}
+
+func (p *PrivateAbiApi) RegisterContractAbi(ctx context.Context, param RegisterContractAbiParam) (*abiregistry.ContractAbi, error) {
+	logger := log15.FromContext(ctx, p.log)
+	if p.registry == nil {
+		return nil, errAbiRegistryUnavailable
+	}
+	record, err := p.registry.Register(param.Addr, []byte(param.Abi), param.CodeHash, abiregistry.SourceRpc)
+	if err != nil {
+		logger.Info("register contract abi failed", "addr", param.Addr, "err", err)
+		return nil, err
+	}
+	return record, nil
+}
+
+func (p *PrivateAbiApi) RemoveContractAbi(addr types.Address) error {
+	if p.registry == nil {
+		return errAbiRegistryUnavailable
+	}
+	return p.registry.Remove(addr)
+}
+
+type VerifyContractParam struct {
+	Addr            types.Address `json:"address"`
+	Source          string        `json:"source"`
+	ContractName    string        `json:"contractName"`
+	CompilerVersion string        `json:"compilerVersion"`
+	Optimize        bool          `json:"optimize"`
+	OptimizeRuns    int           `json:"optimizeRuns"`
+	// OffChainCode in hex is optional, it is checked against the off-chain code compiled from the source
+	OffChainCode *string `json:"offchainCode"`
+}
+
+func (p *PrivateAbiApi) VerifyContract(ctx context.Context, param VerifyContractParam) (*abiregistry.VerifiedSource, error) {
+	logger := log15.FromContext(ctx, p.log)
+	if p.registry == nil {
+		return nil, errAbiRegistryUnavailable
+	}
+	var offChainCode []byte
+	if param.OffChainCode != nil {
+		code, err := hex.DecodeString(*param.OffChainCode)
+		if err != nil {
+			return nil, err
+		}
+		offChainCode = code
+	}
+	record, err := p.registry.Verify(&abiregistry.VerifyRequest{
+		Address:         param.Addr,
+		Source:          param.Source,
+		ContractName:    param.ContractName,
+		CompilerVersion: param.CompilerVersion,
+		CompileOptions: abiregistry.CompileOptions{
+			Optimize:     param.Optimize,
+			OptimizeRuns: param.OptimizeRuns,
+		},
+		OffChainCode: offChainCode,
+	})
+	if err != nil {
+		logger.Info("verify contract failed", "addr", param.Addr, "err", err)
+		return nil, err
+	}
+	return record, nil
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vitelabs/go-vite"
+	"github.com/vitelabs/go-vite/common"
+	"github.com/vitelabs/go-vite/ledger/chain"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+type AdminApi struct {
+	chain chain.Chain
+	log   log15.Logger
+}
+
+func NewAdminApi(vite *vite.Vite) *AdminApi {
+	return &AdminApi{
+		chain: vite.Chain(),
+		log:   log15.New("module", "rpc_api/admin_api"),
+	}
+}
+
+func (a AdminApi) String() string {
+	return "AdminApi"
+}
+
+// private: admin_backup, flushes the ledger and copies it to the directory on the node's host with the writing
+// paused, the table files are hard-linked if hardLink is true
+func (a AdminApi) Backup(ctx context.Context, dir string, hardLink *bool) (*chain.BackupInfo, error) {
+	logger := log15.FromContext(ctx, a.log)
+	if len(dir) <= 0 {
+		return nil, errors.New("the backup directory is required")
+	}
+	link := false
+	if hardLink != nil {
+		link = *hardLink
+	}
+	info, err := a.chain.Backup(dir, link)
+	if err != nil {
+		logger.Error("backup failed", "dir", dir, "err", err)
+		return nil, err
+	}
+	return info, nil
+}
+
+type ModuleLogLevel struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+// private: admin_setLogLevel, changes the level of the module in the log files without restart, the level applies
+// to the sub modules as well, e.g. "net" to "net/syncer", and the empty module changes the default level
+func (a AdminApi) SetLogLevel(ctx context.Context, module string, level string) error {
+	logger := log15.FromContext(ctx, a.log)
+	lvl, err := log15.LvlFromString(level)
+	if err != nil {
+		return err
+	}
+	common.LogLevels.Set(module, lvl)
+	logger.Info("log level changed", "target", module, "level", lvl)
+	return nil
+}
+
+// private: admin_unsetLogLevel, removes the level of the module set by admin_setLogLevel or the config, the module
+// falls back to the level of its parent module
+func (a AdminApi) UnsetLogLevel(ctx context.Context, module string) {
+	logger := log15.FromContext(ctx, a.log)
+	common.LogLevels.Unset(module)
+	logger.Info("log level unset", "target", module)
+}
+
+// private: admin_getLogLevels, returns the modules with their own levels
+func (a AdminApi) GetLogLevels() []*ModuleLogLevel {
+	modules := common.LogLevels.Modules()
+	levels := make([]*ModuleLogLevel, 0, len(modules))
+	for _, module := range modules {
+		if lvl, ok := common.LogLevels.Get(module); ok {
+			levels = append(levels, &ModuleLogLevel{Module: module, Level: lvl.String()})
+		}
+	}
+	return levels
+}
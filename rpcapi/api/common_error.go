@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrStrToBigInt                    = errors.New("convert to big.Int failed")
 	ErrPoWNotSupportedUnderCongestion = errors.New("PoW service not supported")
+	ErrPoWDifficultyTooLarge          = errors.New("PoW difficulty too large")
+	ErrPoWServerNotConfigured         = errors.New("PoW server not configured")
 )
@@ -70,6 +70,11 @@ func (c *ContractApi) GetCreateContractData(param CreateContractDataParam) ([]by
 
 // Private
 func (c *ContractApi) GetCallContractData(abiStr string, methodName string, params []string) ([]byte, error) {
+	return PackCallData(abiStr, methodName, params)
+}
+
+// PackCallData packs the call of the abi method with the params in strings, the arrays are in json
+func PackCallData(abiStr string, methodName string, params []string) ([]byte, error) {
 	abiContract, err := abi.JSONToABIContract(strings.NewReader(abiStr))
 	if err != nil {
 		return nil, err
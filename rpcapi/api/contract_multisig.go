@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/chain"
+	"github.com/vitelabs/go-vite/vm/contracts/abi"
+)
+
+type MultisigCreateParam struct {
+	Owners    []types.Address `json:"owners"`
+	Threshold uint8           `json:"threshold"`
+	TimeLock  uint64          `json:"timeLock"`
+}
+
+type MultisigTransferParam struct {
+	WalletId types.Hash        `json:"walletId"`
+	To       types.Address     `json:"toAddress"`
+	TokenId  types.TokenTypeId `json:"tokenId"`
+	Amount   string            `json:"amount"`
+	Data     []byte            `json:"data,omitempty"`
+}
+
+type MultisigOwnerChangeParam struct {
+	WalletId  types.Hash      `json:"walletId"`
+	Owners    []types.Address `json:"owners"`
+	Threshold uint8           `json:"threshold"`
+	TimeLock  uint64          `json:"timeLock"`
+}
+
+type MultisigWalletInfo struct {
+	WalletId  types.Hash         `json:"walletId"`
+	Owners    []types.Address    `json:"owners"`
+	Threshold uint8              `json:"threshold"`
+	TimeLock  uint64             `json:"timeLock"`
+	Balances  []*MultisigBalance `json:"balances"`
+}
+
+type MultisigBalance struct {
+	TokenId types.TokenTypeId `json:"tokenId"`
+	Amount  string            `json:"amount"`
+}
+
+type MultisigProposalInfo struct {
+	Id         types.Hash         `json:"id"`
+	WalletId   types.Hash         `json:"walletId"`
+	Proposer   types.Address      `json:"proposer"`
+	Kind       uint8              `json:"kind"`
+	To         *types.Address     `json:"toAddress,omitempty"`
+	TokenId    *types.TokenTypeId `json:"tokenId,omitempty"`
+	Amount     *string            `json:"amount,omitempty"`
+	Data       []byte             `json:"data,omitempty"`
+	Owners     []types.Address    `json:"owners,omitempty"`
+	Threshold  uint8              `json:"threshold,omitempty"`
+	TimeLock   uint64             `json:"timeLock,omitempty"`
+	Approvals  []types.Address    `json:"approvals"`
+	UnlockTime int64              `json:"unlockTime"`
+}
+
+func newMultisigProposalInfo(p *abi.MultisigProposal) *MultisigProposalInfo {
+	info := &MultisigProposalInfo{
+		Id:         p.Id,
+		WalletId:   p.WalletId,
+		Proposer:   p.Proposer,
+		Kind:       p.Kind,
+		Approvals:  p.Approvals,
+		UnlockTime: p.UnlockTime,
+	}
+	if p.Kind == abi.MultisigProposalTransfer {
+		info.To = &p.To
+		info.TokenId = &p.TokenId
+		info.Amount = bigIntToString(p.Amount)
+		info.Data = p.Data
+	} else {
+		info.Owners = p.Owners
+		info.Threshold = p.Threshold
+		info.TimeLock = p.TimeLock
+	}
+	return info
+}
+
+func (c *ContractApi) GetMultisigCreateData(param MultisigCreateParam) ([]byte, error) {
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigCreate, param.Owners, param.Threshold, param.TimeLock)
+}
+
+func (c *ContractApi) GetMultisigDepositData(walletId types.Hash) ([]byte, error) {
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigDeposit, walletId)
+}
+
+func (c *ContractApi) GetMultisigProposeTransferData(param MultisigTransferParam) ([]byte, error) {
+	amount, err := stringToBigInt(&param.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigProposeTransfer, param.WalletId, param.To, param.TokenId, amount, param.Data)
+}
+
+// GetMultisigProposeCallData builds the proposal of calling a contract from the multisig wallet, the call data is
+// packed with the abi of the target contract
+func (c *ContractApi) GetMultisigProposeCallData(param MultisigTransferParam, abiStr string, methodName string, params []string) ([]byte, error) {
+	data, err := c.GetCallContractData(abiStr, methodName, params)
+	if err != nil {
+		return nil, err
+	}
+	param.Data = data
+	return c.GetMultisigProposeTransferData(param)
+}
+
+func (c *ContractApi) GetMultisigProposeOwnerChangeData(param MultisigOwnerChangeParam) ([]byte, error) {
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigProposeOwnerChange, param.WalletId, param.Owners, param.Threshold, param.TimeLock)
+}
+
+func (c *ContractApi) GetMultisigApproveData(walletId types.Hash, proposalId types.Hash) ([]byte, error) {
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigApprove, walletId, proposalId)
+}
+
+func (c *ContractApi) GetMultisigCancelProposalData(walletId types.Hash, proposalId types.Hash) ([]byte, error) {
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigCancelProposal, walletId, proposalId)
+}
+
+func (c *ContractApi) GetMultisigExecuteData(walletId types.Hash, proposalId types.Hash) ([]byte, error) {
+	return abi.ABIMultisig.PackMethod(abi.MethodNameMultisigExecute, walletId, proposalId)
+}
+
+func (c *ContractApi) GetMultisigWallet(walletId types.Hash) (*MultisigWalletInfo, error) {
+	db, err := getVmDb(c.chain, types.AddressMultisig)
+	if err != nil {
+		return nil, err
+	}
+	info, err := abi.GetMultisigInfo(db, walletId)
+	if err != nil || info == nil {
+		return nil, err
+	}
+	balanceMap, err := abi.GetMultisigBalanceMap(db, walletId)
+	if err != nil {
+		return nil, err
+	}
+	result := &MultisigWalletInfo{
+		WalletId:  walletId,
+		Owners:    info.Owners,
+		Threshold: info.Threshold,
+		TimeLock:  info.TimeLock,
+		Balances:  make([]*MultisigBalance, 0, len(balanceMap)),
+	}
+	for tokenId, amount := range balanceMap {
+		result.Balances = append(result.Balances, &MultisigBalance{tokenId, *bigIntToString(amount)})
+	}
+	sort.Slice(result.Balances, func(i, j int) bool {
+		return result.Balances[i].TokenId.String() < result.Balances[j].TokenId.String()
+	})
+	return result, nil
+}
+
+func (c *ContractApi) GetMultisigWalletsByOwner(owner types.Address) ([]types.Hash, error) {
+	db, err := getVmDb(c.chain, types.AddressMultisig)
+	if err != nil {
+		return nil, err
+	}
+	infoMap, err := abi.GetMultisigInfoListByOwner(db, owner)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]types.Hash, 0, len(infoMap))
+	for walletId := range infoMap {
+		list = append(list, walletId)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].String() < list[j].String()
+	})
+	return list, nil
+}
+
+func (c *ContractApi) GetMultisigProposal(walletId types.Hash, proposalId types.Hash) (*MultisigProposalInfo, error) {
+	db, err := getVmDb(c.chain, types.AddressMultisig)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := abi.GetMultisigProposal(db, walletId, proposalId)
+	if err != nil || proposal == nil {
+		return nil, err
+	}
+	return newMultisigProposalInfo(proposal), nil
+}
+
+func (c *ContractApi) GetMultisigProposalList(walletId types.Hash) ([]*MultisigProposalInfo, error) {
+	db, err := getVmDb(c.chain, types.AddressMultisig)
+	if err != nil {
+		return nil, err
+	}
+	proposalList, err := abi.GetMultisigProposalList(db, walletId)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*MultisigProposalInfo, len(proposalList))
+	for i, proposal := range proposalList {
+		list[i] = newMultisigProposalInfo(proposal)
+	}
+	return list, nil
+}
+
+// GetMultisigPendingApprovals returns the proposals of the multisig wallet which are not approved by the owner yet
+func (c *ContractApi) GetMultisigPendingApprovals(walletId types.Hash, owner types.Address) ([]*MultisigProposalInfo, error) {
+	proposalList, err := getMultisigPendingApprovals(c.chain, walletId, owner)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*MultisigProposalInfo, len(proposalList))
+	for i, proposal := range proposalList {
+		list[i] = newMultisigProposalInfo(proposal)
+	}
+	return list, nil
+}
+
+func getMultisigPendingApprovals(c chain.Chain, walletId types.Hash, owner types.Address) ([]*abi.MultisigProposal, error) {
+	db, err := getVmDb(c, types.AddressMultisig)
+	if err != nil {
+		return nil, err
+	}
+	info, err := abi.GetMultisigInfo(db, walletId)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || !info.IsOwner(owner) {
+		return nil, errors.New("not an owner of the multisig wallet")
+	}
+	proposalList, err := abi.GetMultisigProposalList(db, walletId)
+	if err != nil {
+		return nil, err
+	}
+	pendingList := make([]*abi.MultisigProposal, 0, len(proposalList))
+	for _, proposal := range proposalList {
+		approved := false
+		for _, approval := range proposal.Approvals {
+			if approval == owner {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			pendingList = append(pendingList, proposal)
+		}
+	}
+	return pendingList, nil
+}
+
+type MultisigApproveParams struct {
+	EntropystoreFile *string       `json:"entropystoreFile,omitempty"`
+	SelfAddr         types.Address `json:"selfAddr"`
+	Passphrase       string        `json:"passphrase"`
+	WalletId         types.Hash    `json:"walletId"`
+	ProposalIds      []types.Hash  `json:"proposalIds,omitempty"`
+	Difficulty       *string       `json:"difficulty,omitempty"`
+}
+
+// ApproveMultisigProposals sends an approval from the owner for each of the proposals, or for all the proposals of
+// the multisig wallet not approved by the owner yet if no proposal is given
+func (m WalletApi) ApproveMultisigProposals(ctx context.Context, params MultisigApproveParams) ([]*types.Hash, error) {
+	proposalIds := params.ProposalIds
+	if len(proposalIds) == 0 {
+		pendingList, err := getMultisigPendingApprovals(m.chain, params.WalletId, params.SelfAddr)
+		if err != nil {
+			return nil, err
+		}
+		for _, proposal := range pendingList {
+			proposalIds = append(proposalIds, proposal.Id)
+		}
+	}
+	hashList := make([]*types.Hash, 0, len(proposalIds))
+	for _, proposalId := range proposalIds {
+		data, err := abi.ABIMultisig.PackMethod(abi.MethodNameMultisigApprove, params.WalletId, proposalId)
+		if err != nil {
+			return hashList, err
+		}
+		hash, err := m.CreateTxWithPassphrase(ctx, CreateTransferTxParms{
+			EntropystoreFile: params.EntropystoreFile,
+			SelfAddr:         params.SelfAddr,
+			ToAddr:           types.AddressMultisig,
+			TokenTypeId:      ledger.ViteTokenId,
+			Passphrase:       params.Passphrase,
+			Amount:           "0",
+			Data:             data,
+			Difficulty:       params.Difficulty,
+		})
+		if err != nil {
+			return hashList, err
+		}
+		hashList = append(hashList, hash)
+	}
+	return hashList, nil
+}
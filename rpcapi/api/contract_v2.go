@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"sort"
 	"time"
 
@@ -57,6 +58,9 @@ type ContractInfo struct {
 	RandomDegree    uint8     `json:"randomDegree"`
 	QuotaRatio      uint8     `json:"quotaRatio"` // Deprecated: use quotaMultiplier instead
 	QuotaMultiplier uint8     `json:"quotaMultiplier"`
+	// Abi is the abi registered for the contract, AbiVerified is true if it comes from a verified source
+	Abi         json.RawMessage `json:"abi,omitempty"`
+	AbiVerified bool            `json:"abiVerified"`
 }
 
 func (c *ContractApi) GetContractInfo(addr types.Address) (*ContractInfo, error) {
@@ -71,7 +75,7 @@ func (c *ContractApi) GetContractInfo(addr types.Address) (*ContractInfo, error)
 	if meta == nil {
 		return nil, nil
 	}
-	return &ContractInfo{
+	info := &ContractInfo{
 		Code:            code,
 		Gid:             meta.Gid,
 		ConfirmTime:     meta.SendConfirmedTimes,
@@ -80,7 +84,18 @@ func (c *ContractApi) GetContractInfo(addr types.Address) (*ContractInfo, error)
 		RandomDegree:    meta.SeedConfirmedTimes,
 		QuotaRatio:      meta.QuotaRatio,
 		QuotaMultiplier: meta.QuotaRatio,
-	}, nil
+	}
+	if registry := c.vite.AbiRegistry(); registry != nil {
+		record, err := registry.GetContractAbi(addr)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			info.Abi = record.Abi
+			info.AbiVerified = record.Verified
+		}
+	}
+	return info, nil
 }
 
 type CallOffChainMethodParam struct {
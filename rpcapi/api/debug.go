@@ -1,5 +1,13 @@
 package api
 
+import (
+	"errors"
+
+	"github.com/vitelabs/go-vite"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/monitor/blocktrace"
+)
+
 type Deprecated struct {
 }
 
@@ -14,3 +22,25 @@ func (p Deprecated) String() string {
 func (p *Deprecated) Hello() (string, error) {
 	return "hello world", nil
 }
+
+// DebugApi serves the diagnosis of the node in the debug namespace, debug_hello is kept for the old clients
+type DebugApi struct {
+	Deprecated
+}
+
+func NewDebugApi(vite *vite.Vite) *DebugApi {
+	return &DebugApi{}
+}
+
+func (d DebugApi) String() string {
+	return "DebugApi"
+}
+
+// BlockTimeline returns the stages of the account block recorded since the receipt, from net receive, pool queue,
+// verification and vm run to chain insert, it returns null if the block is not traced recently.
+func (d *DebugApi) BlockTimeline(hash types.Hash) (*blocktrace.Timeline, error) {
+	if !blocktrace.Enabled() {
+		return nil, errors.New("block trace is disabled, set BlockTraceEnabled in the node config")
+	}
+	return blocktrace.GetTimeline(hash), nil
+}
@@ -0,0 +1,35 @@
+package sink
+
+import (
+	"github.com/vitelabs/go-vite/log15"
+)
+
+// SinkApi inspects and replays the sinks of the node
+type SinkApi struct {
+	log log15.Logger
+}
+
+func NewSinkApi() *SinkApi {
+	return &SinkApi{log: log15.New("module", "rpc_api/sink_api")}
+}
+
+func (s SinkApi) String() string {
+	return "SinkApi"
+}
+
+// GetSinks returns the cursors and the delivery stats of the sinks
+func (s *SinkApi) GetSinks() ([]*Status, error) {
+	if Sinks == nil {
+		return nil, ErrNotEnabled
+	}
+	return Sinks.Status(), nil
+}
+
+// Replay delivers the blocks of the sink again from the snapshot height, the message being delivered is aborted
+func (s *SinkApi) Replay(name string, height uint64) error {
+	if Sinks == nil {
+		return ErrNotEnabled
+	}
+	s.log.Info("replay sink", "name", name, "height", height)
+	return Sinks.Replay(name, height)
+}
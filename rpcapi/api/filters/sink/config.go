@@ -0,0 +1,120 @@
+package sink
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/rpcapi/api"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Config declares a sink in node_config.json, it is a filter of the account blocks and exactly one of the
+// destinations Webhook, File and Queue.
+type Config struct {
+	// Name identifies the sink, the cursor is saved in the data dir by the name
+	Name string `json:"Name"`
+	// Addresses are the accounts whose blocks are delivered, all the accounts if empty
+	Addresses []string `json:"Addresses"`
+	// Topics matches the vm logs of the blocks as the topics of subscribe_createVmLogFilter, a block is
+	// delivered with its matched logs if Topics is set
+	Topics [][]string `json:"Topics"`
+	// BlockTypes are the types of the blocks delivered, e.g. 2 for the send call and 4 for the receive, all the
+	// types if empty
+	BlockTypes []int `json:"BlockTypes"`
+	// FromHeight is the snapshot height the sink starts from without a saved cursor, the next snapshot block if 0
+	FromHeight uint64 `json:"FromHeight"`
+	// Confirmations is the number of the snapshot blocks after a snapshot block before its blocks are delivered
+	Confirmations uint64 `json:"Confirmations"`
+
+	Webhook *WebhookConfig `json:"Webhook"`
+	// File is the path of the file the messages are appended to, relative to the data dir
+	File  string       `json:"File"`
+	Queue *QueueConfig `json:"Queue"`
+}
+
+// WebhookConfig posts the messages to Url, the body is signed by hmac-sha256 with Secret if it is set
+type WebhookConfig struct {
+	Url    string `json:"Url"`
+	Secret string `json:"Secret"`
+	// Timeout of a post in seconds, 10 if 0
+	Timeout int `json:"Timeout"`
+}
+
+// QueueConfig publishes the messages to Subject of a nats server, e.g. nats://127.0.0.1:4222
+type QueueConfig struct {
+	Url     string `json:"Url"`
+	Subject string `json:"Subject"`
+}
+
+// filter matches the account blocks of a sink
+type filter struct {
+	addrs      map[types.Address]struct{}
+	blockTypes map[byte]struct{}
+	param      *api.FilterParam
+}
+
+func (c *Config) check() error {
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("invalid sink name %q", c.Name)
+	}
+	destinations := 0
+	if c.Webhook != nil {
+		if c.Webhook.Url == "" {
+			return fmt.Errorf("sink %v: webhook url is empty", c.Name)
+		}
+		destinations++
+	}
+	if c.File != "" {
+		destinations++
+	}
+	if c.Queue != nil {
+		if c.Queue.Url == "" || c.Queue.Subject == "" {
+			return fmt.Errorf("sink %v: queue url or subject is empty", c.Name)
+		}
+		destinations++
+	}
+	if destinations != 1 {
+		return fmt.Errorf("sink %v: exactly one of Webhook, File and Queue must be set", c.Name)
+	}
+	return nil
+}
+
+func (c *Config) filter() (*filter, error) {
+	f := &filter{}
+	if len(c.Addresses) > 0 {
+		f.addrs = make(map[types.Address]struct{}, len(c.Addresses))
+		for _, hexAddr := range c.Addresses {
+			addr, err := types.HexToAddress(hexAddr)
+			if err != nil {
+				return nil, errors.WithMessage(err, "sink "+c.Name)
+			}
+			f.addrs[addr] = struct{}{}
+		}
+	}
+	if len(c.BlockTypes) > 0 {
+		f.blockTypes = make(map[byte]struct{}, len(c.BlockTypes))
+		for _, t := range c.BlockTypes {
+			if t <= 0 || t > 0xff {
+				return nil, fmt.Errorf("sink %v: invalid block type %d", c.Name, t)
+			}
+			f.blockTypes[byte(t)] = struct{}{}
+		}
+	}
+	if len(c.Topics) > 0 {
+		f.param = &api.FilterParam{Topics: make([][]types.Hash, len(c.Topics))}
+		for i, topics := range c.Topics {
+			for _, hexTopic := range topics {
+				topic, err := types.HexToHash(hexTopic)
+				if err != nil {
+					return nil, errors.WithMessage(err, "sink "+c.Name)
+				}
+				f.param.Topics[i] = append(f.param.Topics[i], topic)
+			}
+		}
+	}
+	return f, nil
+}
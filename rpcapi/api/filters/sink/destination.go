@@ -0,0 +1,263 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// the headers of the webhook posts
+	HeaderName      = "X-Sink-Name"
+	HeaderDelivery  = "X-Sink-Delivery"
+	HeaderSignature = "X-Sink-Signature"
+
+	defaultWebhookTimeout = 10 * time.Second
+	queueTimeout          = 10 * time.Second
+	defaultQueuePort      = "4222"
+)
+
+// Destination receives the messages of a sink, a message is delivered once Send returns nil
+type Destination interface {
+	// Send delivers the message encoded in data
+	Send(msg *Message, data []byte) error
+	String() string
+	Close() error
+}
+
+func newDestination(dataDir string, c *Config) (Destination, error) {
+	switch {
+	case c.Webhook != nil:
+		return NewWebhookDestination(c.Name, c.Webhook), nil
+	case c.File != "":
+		path := c.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dataDir, path)
+		}
+		return NewFileDestination(path)
+	default:
+		return NewQueueDestination(c.Queue), nil
+	}
+}
+
+// Sign returns the signature of the body of a webhook post, the hex of its hmac-sha256 prefixed by "sha256="
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDestination posts the messages in json, the response of a 2xx status acknowledges a message
+type WebhookDestination struct {
+	name   string
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func NewWebhookDestination(name string, c *WebhookConfig) *WebhookDestination {
+	timeout := defaultWebhookTimeout
+	if c.Timeout > 0 {
+		timeout = time.Duration(c.Timeout) * time.Second
+	}
+	return &WebhookDestination{
+		name:   name,
+		url:    c.Url,
+		secret: []byte(c.Secret),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (d *WebhookDestination) Send(msg *Message, data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderName, d.name)
+	req.Header.Set(HeaderDelivery, msg.DeliveryId())
+	if len(d.secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(d.secret, data))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responds %v", resp.Status)
+	}
+	return nil
+}
+
+func (d *WebhookDestination) String() string {
+	return "webhook " + d.url
+}
+
+func (d *WebhookDestination) Close() error {
+	d.client.CloseIdleConnections()
+	return nil
+}
+
+// FileDestination appends the messages to a file, one json per line, a message is synced to the disk before it
+// is acknowledged
+type FileDestination struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func NewFileDestination(path string) (*FileDestination, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDestination{path: path, file: file}, nil
+}
+
+func (d *FileDestination) Send(msg *Message, data []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return d.file.Sync()
+}
+
+func (d *FileDestination) String() string {
+	return "file " + d.path
+}
+
+func (d *FileDestination) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
+
+// QueueDestination publishes the messages to a nats server by its text protocol, a message is acknowledged by
+// the PONG of the PING following the PUB, so it has been processed by the server. The connection is dialed on
+// demand and dropped on any error.
+type QueueDestination struct {
+	addr    string
+	subject string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func NewQueueDestination(c *QueueConfig) *QueueDestination {
+	addr := strings.TrimPrefix(c.Url, "nats://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultQueuePort)
+	}
+	return &QueueDestination{addr: addr, subject: c.Subject}
+}
+
+func (d *QueueDestination) Send(msg *Message, data []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.publish(data); err != nil {
+		d.close()
+		return err
+	}
+	return nil
+}
+
+func (d *QueueDestination) publish(data []byte) error {
+	if d.conn == nil {
+		if err := d.connect(); err != nil {
+			return err
+		}
+	}
+	d.conn.SetDeadline(time.Now().Add(queueTimeout))
+
+	var buf bytes.Buffer
+	buf.WriteString("PUB " + d.subject + " " + strconv.Itoa(len(data)) + "\r\n")
+	buf.Write(data)
+	buf.WriteString("\r\nPING\r\n")
+	if _, err := d.conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := io.WriteString(d.conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("queue responds %v", line)
+		}
+	}
+}
+
+func (d *QueueDestination) connect() error {
+	conn, err := net.DialTimeout("tcp", d.addr, queueTimeout)
+	if err != nil {
+		return err
+	}
+	d.conn = conn
+	d.r = bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(queueTimeout))
+
+	line, err := d.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("unexpected queue greeting %q", line)
+	}
+	_, err = io.WriteString(conn, `CONNECT {"verbose":false,"pedantic":false,"name":"gvite"}`+"\r\n")
+	return err
+}
+
+func (d *QueueDestination) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (d *QueueDestination) close() {
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+		d.r = nil
+	}
+}
+
+func (d *QueueDestination) String() string {
+	return "queue " + d.addr + " " + d.subject
+}
+
+func (d *QueueDestination) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.close()
+	return nil
+}
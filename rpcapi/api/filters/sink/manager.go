@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotEnabled = errors.New("no sink is declared in node_config.json")
+
+	// Sinks are the sinks of the node, nil if no sink is declared
+	Sinks *Manager
+)
+
+// Manager runs the sinks declared in the config
+type Manager struct {
+	sinks []*sink
+	names map[string]*sink
+}
+
+// NewManager checks the sinks and loads their cursors in dataDir
+func NewManager(chain Chain, dataDir string, cfgs []*Config) (*Manager, error) {
+	m := &Manager{names: make(map[string]*sink, len(cfgs))}
+	for _, cfg := range cfgs {
+		if _, ok := m.names[cfg.Name]; ok {
+			m.close()
+			return nil, fmt.Errorf("duplicate sink %v", cfg.Name)
+		}
+		s, err := newSink(chain, dataDir, cfg)
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		m.sinks = append(m.sinks, s)
+		m.names[cfg.Name] = s
+	}
+	return m, nil
+}
+
+func (m *Manager) Start() {
+	for _, s := range m.sinks {
+		s.start()
+	}
+}
+
+// Stop stops the deliveries, a message being retried is delivered again after the restart
+func (m *Manager) Stop() {
+	for _, s := range m.sinks {
+		s.stopAndClose()
+	}
+}
+
+func (m *Manager) close() {
+	for _, s := range m.sinks {
+		s.dest.Close()
+	}
+}
+
+func (m *Manager) Status() []*Status {
+	result := make([]*Status, 0, len(m.sinks))
+	for _, s := range m.sinks {
+		result = append(result, s.Status())
+	}
+	return result
+}
+
+// Replay delivers the blocks of the sink again from the snapshot height
+func (m *Manager) Replay(name string, height uint64) error {
+	s, ok := m.names[name]
+	if !ok {
+		return fmt.Errorf("sink %v not found", name)
+	}
+	s.Replay(height)
+	return nil
+}
@@ -0,0 +1,515 @@
+// Package sink delivers the account blocks and the vm logs matching the filters declared in the config to webhooks,
+// files and message queues without a client connection. The blocks are read from the chain by snapshot height, a
+// sink saves the height and the hash of the snapshot block it has delivered as its cursor after each delivery, so
+// the delivery is at least once and it can be replayed from a height. The cursor is checked against the chain on
+// each step, if the snapshot chain is rolled back under it, even when it has regrown past the cursor since, the
+// cursor is rewound by reorgDepth heights and the heights are delivered again. The consumers dedupe the messages
+// by the delivery id or the block hashes.
+package sink
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/rpcapi/api"
+)
+
+const (
+	// fetchSize is the most snapshot heights read from the chain at a time
+	fetchSize = 100
+	// reorgDepth is the snapshot heights delivered again below the cursor when the snapshot chain is rolled back
+	// under it, the rollbacks of the snapshot chain are much shallower in practice
+	reorgDepth = 100
+
+	pollInterval = time.Second
+	minBackoff   = time.Second
+	maxBackoff   = time.Minute
+)
+
+var (
+	errStopped  = errors.New("sink stopped")
+	errReplayed = errors.New("delivery aborted by replay")
+)
+
+// Chain is the part of the chain read by the sinks
+type Chain interface {
+	GetLatestSnapshotBlock() *ledger.SnapshotBlock
+	GetSnapshotHashByHeight(height uint64) (*types.Hash, error)
+	GetSubLedger(startHeight, endHeight uint64) ([]*ledger.SnapshotChunk, error)
+	GetVmLogList(logListHash *types.Hash) (ledger.VmLogList, error)
+}
+
+// Message is the matched blocks of a snapshot block, a snapshot block without matched blocks is skipped
+type Message struct {
+	Sink           string     `json:"sink"`
+	SnapshotHeight uint64     `json:"snapshotHeight"`
+	SnapshotHash   types.Hash `json:"snapshotHash"`
+	Events         []*Event   `json:"events"`
+}
+
+// DeliveryId identifies the message among the deliveries of the sink, a replayed message has the same id unless
+// the snapshot block at the height is replaced by a rollback
+func (m *Message) DeliveryId() string {
+	return m.Sink + "-" + strconv.FormatUint(m.SnapshotHeight, 10) + "-" + m.SnapshotHash.String()
+}
+
+// Event is a matched account block
+type Event struct {
+	BlockType     byte            `json:"blockType"`
+	Hash          types.Hash      `json:"hash"`
+	Height        uint64          `json:"height"`
+	Address       types.Address   `json:"address"`
+	ToAddress     types.Address   `json:"toAddress"`
+	FromBlockHash types.Hash      `json:"fromBlockHash"`
+	Logs          []*ledger.VmLog `json:"logs,omitempty"`
+}
+
+// Status is the delivery of a sink
+type Status struct {
+	Name        string `json:"name"`
+	Destination string `json:"destination"`
+	// Height and Hash are the cursor, the snapshot block delivered, the hash is zero before the first snapshot block
+	Height           uint64     `json:"height"`
+	Hash             types.Hash `json:"hash"`
+	Delivered        uint64     `json:"delivered"`
+	Failures         uint64     `json:"failures"`
+	LastError        string     `json:"lastError,omitempty"`
+	LastDeliveryTime int64      `json:"lastDeliveryTime,omitempty"`
+}
+
+type sink struct {
+	cfg        *Config
+	filter     *filter
+	chain      Chain
+	dest       Destination
+	cursorPath string
+	log        log15.Logger
+
+	mu     sync.Mutex
+	status Status
+	replay *uint64
+
+	wakeup chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newSink(chain Chain, dataDir string, cfg *Config) (*sink, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	f, err := cfg.filter()
+	if err != nil {
+		return nil, err
+	}
+	dest, err := newDestination(dataDir, cfg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "sink "+cfg.Name)
+	}
+	s := &sink{
+		cfg:        cfg,
+		filter:     f,
+		chain:      chain,
+		dest:       dest,
+		cursorPath: filepath.Join(dataDir, "sinks", cfg.Name+".cursor"),
+		log:        log15.New("module", "rpc_api/sink", "sink", cfg.Name),
+		wakeup:     make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+	s.status = Status{Name: cfg.Name, Destination: dest.String()}
+
+	height, hash, ok, err := s.loadCursor()
+	if err != nil {
+		dest.Close()
+		return nil, err
+	}
+	if !ok {
+		if cfg.FromHeight > 0 {
+			height = cfg.FromHeight - 1
+		} else {
+			height = s.target(chain.GetLatestSnapshotBlock().Height)
+		}
+		if hash, err = s.hashAt(height); err != nil {
+			dest.Close()
+			return nil, err
+		}
+		if err := s.saveCursor(height, hash); err != nil {
+			dest.Close()
+			return nil, err
+		}
+	}
+	s.status.Height = height
+	s.status.Hash = hash
+	return s, nil
+}
+
+func (s *sink) start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+func (s *sink) stopAndClose() {
+	close(s.stop)
+	s.wg.Wait()
+	if err := s.dest.Close(); err != nil {
+		s.log.Warn("close destination failed", "err", err)
+	}
+}
+
+// Replay delivers the blocks again from the snapshot height
+func (s *sink) Replay(height uint64) {
+	s.mu.Lock()
+	s.replay = &height
+	s.mu.Unlock()
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (s *sink) Status() *Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	return &status
+}
+
+func (s *sink) loop() {
+	defer s.wg.Done()
+	s.log.Info("start sink", "destination", s.dest.String(), "height", s.height())
+	for {
+		s.applyReplay()
+		more, err := s.step()
+		wait := time.Duration(0)
+		if err == errStopped {
+			return
+		} else if err == errReplayed {
+			continue
+		} else if err != nil {
+			s.log.Error("sink step failed", "err", err)
+			wait = pollInterval
+		} else if !more {
+			wait = pollInterval
+		}
+		if wait == 0 {
+			continue
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-s.wakeup:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// target is the highest snapshot height to deliver
+func (s *sink) target(latest uint64) uint64 {
+	if latest < s.cfg.Confirmations {
+		return 0
+	}
+	return latest - s.cfg.Confirmations
+}
+
+func (s *sink) height() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status.Height
+}
+
+func (s *sink) cursor() (uint64, types.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status.Height, s.status.Hash
+}
+
+func (s *sink) setCursor(height uint64, hash types.Hash) error {
+	s.mu.Lock()
+	s.status.Height = height
+	s.status.Hash = hash
+	s.mu.Unlock()
+	return s.saveCursor(height, hash)
+}
+
+// hashAt returns the hash of the snapshot block at the height, zero if there is no snapshot block at the height
+func (s *sink) hashAt(height uint64) (types.Hash, error) {
+	if height == 0 {
+		return types.Hash{}, nil
+	}
+	hash, err := s.chain.GetSnapshotHashByHeight(height)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	if hash == nil {
+		return types.Hash{}, nil
+	}
+	return *hash, nil
+}
+
+// rewind moves the cursor reorgDepth heights below the height after a rollback of the snapshot chain, but not
+// below the FromHeight of the config
+func (s *sink) rewind(height uint64) error {
+	to := uint64(0)
+	if height > reorgDepth {
+		to = height - reorgDepth
+	}
+	if from := s.cfg.FromHeight; from > 0 && to < from-1 {
+		to = from - 1
+		if to > height {
+			to = height
+		}
+	}
+	hash, err := s.hashAt(to)
+	if err != nil {
+		return err
+	}
+	return s.setCursor(to, hash)
+}
+
+func (s *sink) applyReplay() {
+	s.mu.Lock()
+	replay := s.replay
+	s.replay = nil
+	s.mu.Unlock()
+	if replay == nil {
+		return
+	}
+	height := uint64(0)
+	if *replay > 0 {
+		height = *replay - 1
+	}
+	s.log.Info("replay sink", "from", *replay)
+	hash, err := s.hashAt(height)
+	if err == nil {
+		err = s.setCursor(height, hash)
+	}
+	if err != nil {
+		s.log.Error("save cursor failed", "err", err)
+	}
+}
+
+// step delivers the snapshot heights after the cursor up to fetchSize, it reports whether there are more heights
+// to deliver.
+func (s *sink) step() (bool, error) {
+	cursor, hash := s.cursor()
+	latest := s.chain.GetLatestSnapshotBlock().Height
+	if latest < cursor {
+		// the snapshot blocks are rolled back, deliver the new blocks at the heights
+		s.log.Warn("snapshot chain rolled back below the cursor", "cursor", cursor, "latest", latest)
+		return true, s.rewind(latest)
+	}
+	current, err := s.hashAt(cursor)
+	if err != nil {
+		return false, err
+	}
+	if hash == (types.Hash{}) {
+		// the cursor of an older version or above the chain when it was saved
+		hash = current
+	} else if current != hash {
+		// the snapshot blocks are rolled back and regrown past the cursor
+		s.log.Warn("snapshot block at the cursor replaced", "cursor", cursor, "hash", hash, "current", current)
+		return true, s.rewind(cursor)
+	}
+	target := s.target(latest)
+	if target <= cursor {
+		return false, nil
+	}
+	end := cursor + fetchSize
+	if end > target {
+		end = target
+	}
+
+	start := cursor
+	chunks, err := s.chain.GetSubLedger(start, end)
+	if err != nil {
+		return false, err
+	}
+	for _, chunk := range chunks {
+		sb := chunk.SnapshotBlock
+		if sb == nil || sb.Height <= cursor {
+			continue
+		}
+		if sb.Height > end {
+			break
+		}
+		if hash != (types.Hash{}) && sb.PrevHash != hash {
+			s.log.Warn("snapshot chain rolled back while reading", "height", sb.Height, "prevHash", sb.PrevHash, "hash", hash)
+			return true, s.rewind(cursor)
+		}
+		msg, err := s.message(chunk)
+		if err != nil {
+			return false, err
+		}
+		if msg != nil {
+			if err := s.deliver(msg); err != nil {
+				return false, err
+			}
+			if err := s.setCursor(sb.Height, sb.Hash); err != nil {
+				return false, err
+			}
+		}
+		cursor, hash = sb.Height, sb.Hash
+	}
+	if cursor == start {
+		return false, nil
+	}
+	if err := s.setCursor(cursor, hash); err != nil {
+		return false, err
+	}
+	return cursor < target, nil
+}
+
+// message returns the matched blocks of the snapshot chunk, nil if no block is matched
+func (s *sink) message(chunk *ledger.SnapshotChunk) (*Message, error) {
+	var events []*Event
+	for _, block := range chunk.AccountBlocks {
+		if s.filter.addrs != nil {
+			if _, ok := s.filter.addrs[block.AccountAddress]; !ok {
+				continue
+			}
+		}
+		if s.filter.blockTypes != nil {
+			if _, ok := s.filter.blockTypes[block.BlockType]; !ok {
+				continue
+			}
+		}
+
+		var logs []*ledger.VmLog
+		if block.LogHash != nil {
+			logList, err := s.chain.GetVmLogList(block.LogHash)
+			if err != nil {
+				return nil, errors.WithMessage(err, "get vm logs of "+block.Hash.String())
+			}
+			for _, l := range logList {
+				if s.filter.param == nil || api.FilterLog(s.filter.param, l) {
+					logs = append(logs, l)
+				}
+			}
+		}
+		if s.filter.param != nil && len(logs) == 0 {
+			continue
+		}
+
+		events = append(events, &Event{
+			BlockType:     block.BlockType,
+			Hash:          block.Hash,
+			Height:        block.Height,
+			Address:       block.AccountAddress,
+			ToAddress:     block.ToAddress,
+			FromBlockHash: block.FromBlockHash,
+			Logs:          logs,
+		})
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &Message{
+		Sink:           s.cfg.Name,
+		SnapshotHeight: chunk.SnapshotBlock.Height,
+		SnapshotHash:   chunk.SnapshotBlock.Hash,
+		Events:         events,
+	}, nil
+}
+
+// deliver sends the message until it is acknowledged, the sink is stopped or replayed, the retries back off
+// exponentially up to maxBackoff.
+func (s *sink) deliver(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	backoff := minBackoff
+	for {
+		err := s.dest.Send(msg, data)
+		s.mu.Lock()
+		if err == nil {
+			s.status.Delivered++
+			s.status.LastError = ""
+			s.status.LastDeliveryTime = time.Now().Unix()
+		} else {
+			s.status.Failures++
+			s.status.LastError = err.Error()
+		}
+		replaying := s.replay != nil
+		s.mu.Unlock()
+		if err == nil {
+			return nil
+		}
+
+		s.log.Warn("deliver failed", "delivery", msg.DeliveryId(), "retry", backoff, "err", err)
+		if replaying {
+			return errReplayed
+		}
+		select {
+		case <-s.stop:
+			return errStopped
+		case <-s.wakeup:
+			// a replay aborts the delivery
+			return errReplayed
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// loadCursor reads the height and the hash of the cursor file, the hash is zero in the cursor file of an older
+// version holding the height only
+func (s *sink) loadCursor() (uint64, types.Hash, bool, error) {
+	data, err := ioutil.ReadFile(s.cursorPath)
+	if os.IsNotExist(err) {
+		return 0, types.Hash{}, false, nil
+	} else if err != nil {
+		return 0, types.Hash{}, false, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 1 || len(fields) > 2 {
+		return 0, types.Hash{}, false, errors.New("invalid cursor " + s.cursorPath)
+	}
+	height, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, types.Hash{}, false, errors.WithMessage(err, "invalid cursor "+s.cursorPath)
+	}
+	var hash types.Hash
+	if len(fields) == 2 {
+		if hash, err = types.HexToHash(fields[1]); err != nil {
+			return 0, types.Hash{}, false, errors.WithMessage(err, "invalid cursor "+s.cursorPath)
+		}
+	}
+	return height, hash, true, nil
+}
+
+// saveCursor replaces the cursor file by a synced temporary file so that a crash never leaves a broken cursor
+func (s *sink) saveCursor(height uint64, hash types.Hash) error {
+	if err := os.MkdirAll(filepath.Dir(s.cursorPath), 0700); err != nil {
+		return err
+	}
+	tmp := s.cursorPath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(strconv.FormatUint(height, 10) + " " + hash.String()); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.cursorPath)
+}
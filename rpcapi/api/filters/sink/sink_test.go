@@ -0,0 +1,374 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+var (
+	testAddr1  = types.HexToAddressPanic("vite_00000000000000000000000000000000000000042d7ef71894")
+	testAddr2  = types.HexToAddressPanic("vite_0000000000000000000000000000000000000003f6af7459b9")
+	testTopic  = types.HexToHashPanic("0000000000000000000000000000000000000000000000000000000000000001")
+	testTopic2 = types.HexToHashPanic("0000000000000000000000000000000000000000000000000000000000000002")
+)
+
+type mockChain struct {
+	mu     sync.Mutex
+	chunks []*ledger.SnapshotChunk
+	logs   map[types.Hash]ledger.VmLogList
+	// fork is the times the chain is rolled back, it changes the hashes of the snapshot blocks added later
+	fork int
+}
+
+func testSnapshotHash(height uint64, fork int) types.Hash {
+	return types.DataHash([]byte(strconv.FormatUint(height, 10) + "-" + strconv.Itoa(fork)))
+}
+
+func newMockChain() *mockChain {
+	c := &mockChain{logs: make(map[types.Hash]ledger.VmLogList)}
+	c.add()
+	return c
+}
+
+// add appends a snapshot block with the account blocks, a block of testAddr1 has a log of testTopic
+func (c *mockChain) add(blocks ...*ledger.AccountBlock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	height := uint64(len(c.chunks) + 1)
+	for _, b := range blocks {
+		b.Hash = types.DataHash([]byte(strconv.FormatUint(height, 10) + b.AccountAddress.String() + strconv.Itoa(int(b.Height))))
+		if b.AccountAddress == testAddr1 {
+			logHash := b.Hash
+			b.LogHash = &logHash
+			c.logs[logHash] = ledger.VmLogList{{Topics: []types.Hash{testTopic}}, {Topics: []types.Hash{testTopic2}}}
+		}
+	}
+	sb := &ledger.SnapshotBlock{Height: height, Hash: testSnapshotHash(height, c.fork)}
+	if len(c.chunks) > 0 {
+		sb.PrevHash = c.chunks[len(c.chunks)-1].SnapshotBlock.Hash
+	}
+	c.chunks = append(c.chunks, &ledger.SnapshotChunk{SnapshotBlock: sb, AccountBlocks: blocks})
+}
+
+// rollback deletes the snapshot blocks above the height
+func (c *mockChain) rollback(height uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chunks = c.chunks[:height]
+	c.fork++
+}
+
+func (c *mockChain) GetLatestSnapshotBlock() *ledger.SnapshotBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.chunks[len(c.chunks)-1].SnapshotBlock
+}
+
+func (c *mockChain) GetSnapshotHashByHeight(height uint64) (*types.Hash, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height < 1 || height > uint64(len(c.chunks)) {
+		return nil, nil
+	}
+	return &c.chunks[height-1].SnapshotBlock.Hash, nil
+}
+
+func (c *mockChain) GetSubLedger(startHeight, endHeight uint64) ([]*ledger.SnapshotChunk, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if startHeight < 1 {
+		startHeight = 1
+	}
+	if endHeight > uint64(len(c.chunks)) {
+		endHeight = uint64(len(c.chunks))
+	}
+	return c.chunks[startHeight-1 : endHeight], nil
+}
+
+func (c *mockChain) GetVmLogList(logListHash *types.Hash) (ledger.VmLogList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.logs[*logListHash], nil
+}
+
+func readMessages(t *testing.T, path string, n int) []*Message {
+	var msgs []*Message
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) >= n && lines[0] != "" {
+			for _, line := range lines {
+				msg := &Message{}
+				assert.NoError(t, json.Unmarshal([]byte(line), msg))
+				msgs = append(msgs, msg)
+			}
+			return msgs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%d messages not received", n)
+	return nil
+}
+
+func TestSink_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	chain := newMockChain()
+	cfg := &Config{
+		Name:       "events",
+		Addresses:  []string{testAddr1.String()},
+		Topics:     [][]string{{testTopic.String()}},
+		BlockTypes: []int{int(ledger.BlockTypeReceive)},
+		FromHeight: 2,
+		File:       "sinks/events.json",
+	}
+	m, err := NewManager(chain, dir, []*Config{cfg})
+	assert.NoError(t, err)
+
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeReceive, AccountAddress: testAddr1, Height: 1})
+	chain.add(
+		&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr1, Height: 2},
+		&ledger.AccountBlock{BlockType: ledger.BlockTypeReceive, AccountAddress: testAddr2, Height: 1},
+	)
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeReceive, AccountAddress: testAddr1, Height: 3})
+	m.Start()
+
+	path := filepath.Join(dir, "sinks", "events.json")
+	msgs := readMessages(t, path, 2)
+	assert.Equal(t, 2, len(msgs))
+	assert.Equal(t, uint64(2), msgs[0].SnapshotHeight)
+	assert.Equal(t, uint64(4), msgs[1].SnapshotHeight)
+	assert.Equal(t, "events-4-"+testSnapshotHash(4, 0).String(), msgs[1].DeliveryId())
+	assert.Equal(t, 1, len(msgs[1].Events))
+	assert.Equal(t, testAddr1, msgs[1].Events[0].Address)
+	assert.Equal(t, uint64(3), msgs[1].Events[0].Height)
+	// only the matched log
+	assert.Equal(t, 1, len(msgs[1].Events[0].Logs))
+	assert.Equal(t, testTopic, msgs[1].Events[0].Logs[0].Topics[0])
+
+	// the cursor survives the restart, nothing is delivered again
+	for i := 0; i < 100 && m.Status()[0].Height != 4; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.Stop()
+	m, err = NewManager(chain, dir, []*Config{cfg})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), m.Status()[0].Height)
+	assert.Equal(t, testSnapshotHash(4, 0), m.Status()[0].Hash)
+
+	// replay from the height
+	m.Start()
+	assert.NoError(t, m.Replay("events", 3))
+	msgs = readMessages(t, path, 3)
+	assert.Equal(t, uint64(4), msgs[2].SnapshotHeight)
+	assert.Error(t, m.Replay("unknown", 1))
+	m.Stop()
+}
+
+func TestSink_Rollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	chain := newMockChain()
+	cfg := &Config{
+		Name:       "reorg",
+		Addresses:  []string{testAddr2.String()},
+		FromHeight: 2,
+		File:       "sinks/reorg.json",
+	}
+	m, err := NewManager(chain, dir, []*Config{cfg})
+	assert.NoError(t, err)
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 1})
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 2})
+	m.Start()
+	defer m.Stop()
+
+	path := filepath.Join(dir, "sinks", "reorg.json")
+	msgs := readMessages(t, path, 2)
+	for i := 0; i < 100 && m.Status()[0].Height != 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, testSnapshotHash(3, 0), m.Status()[0].Hash)
+
+	// the snapshot chain is rolled back and regrown past the cursor, the new blocks are delivered from FromHeight
+	chain.rollback(1)
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 1})
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 2})
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 3})
+	msgs = readMessages(t, path, 5)
+	assert.Equal(t, 5, len(msgs))
+	for i, height := range []uint64{2, 3} {
+		assert.Equal(t, height, msgs[i].SnapshotHeight)
+		assert.Equal(t, testSnapshotHash(height, 0), msgs[i].SnapshotHash)
+	}
+	for i, height := range []uint64{2, 3, 4} {
+		assert.Equal(t, height, msgs[i+2].SnapshotHeight)
+		assert.Equal(t, testSnapshotHash(height, 1), msgs[i+2].SnapshotHash)
+	}
+	assert.NotEqual(t, msgs[0].DeliveryId(), msgs[2].DeliveryId())
+}
+
+func TestSink_LegacyCursor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the cursor of an older version holds the height only
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sinks"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sinks", "old.cursor"), []byte("1\n"), 0600))
+	m, err := NewManager(newMockChain(), dir, []*Config{{Name: "old", File: "sinks/old.json"}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), m.Status()[0].Height)
+	assert.Equal(t, types.Hash{}, m.Status()[0].Hash)
+	m.close()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sinks", "old.cursor"), []byte("1 2 3"), 0600))
+	_, err = NewManager(newMockChain(), dir, []*Config{{Name: "old", File: "sinks/old.json"}})
+	assert.Error(t, err)
+}
+
+func TestSink_Webhook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	calls := 0
+	received := make(chan *Message, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign([]byte("secret"), body), r.Header.Get(HeaderSignature))
+		assert.Equal(t, "hook", r.Header.Get(HeaderName))
+		assert.Equal(t, "hook-2-"+testSnapshotHash(2, 0).String(), r.Header.Get(HeaderDelivery))
+
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			// the delivery is retried
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		msg := &Message{}
+		assert.NoError(t, json.Unmarshal(body, msg))
+		received <- msg
+	}))
+	defer server.Close()
+
+	chain := newMockChain()
+	m, err := NewManager(chain, dir, []*Config{{
+		Name:    "hook",
+		Webhook: &WebhookConfig{Url: server.URL, Secret: "secret"},
+	}})
+	assert.NoError(t, err)
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 1})
+	m.Start()
+	defer m.Stop()
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, uint64(2), msg.SnapshotHeight)
+		assert.Equal(t, testAddr2, msg.Events[0].Address)
+		assert.Nil(t, msg.Events[0].Logs)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+	status := m.Status()[0]
+	assert.Equal(t, uint64(1), status.Failures)
+}
+
+func TestSink_Queue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a nats server speaking the text protocol
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	published := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		var subject string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PUB":
+				subject = fields[1]
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				published <- subject + " " + string(payload[:size])
+			case "PING":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	chain := newMockChain()
+	m, err := NewManager(chain, dir, []*Config{{
+		Name:  "queue",
+		Queue: &QueueConfig{Url: "nats://" + ln.Addr().String(), Subject: "vite.blocks"},
+	}})
+	assert.NoError(t, err)
+	chain.add(&ledger.AccountBlock{BlockType: ledger.BlockTypeSendCall, AccountAddress: testAddr2, Height: 1})
+	m.Start()
+	defer m.Stop()
+
+	select {
+	case p := <-published:
+		assert.True(t, strings.HasPrefix(p, "vite.blocks {"))
+		msg := &Message{}
+		assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(p, "vite.blocks ")), msg))
+		assert.Equal(t, uint64(2), msg.SnapshotHeight)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not published")
+	}
+}
+
+func TestConfig_Check(t *testing.T) {
+	_, err := NewManager(newMockChain(), "", []*Config{{Name: "a/b", File: "a"}})
+	assert.Error(t, err)
+	_, err = NewManager(newMockChain(), "", []*Config{{Name: "a"}})
+	assert.Error(t, err)
+	_, err = NewManager(newMockChain(), "", []*Config{{Name: "a", File: "a", Webhook: &WebhookConfig{Url: "http://127.0.0.1"}}})
+	assert.Error(t, err)
+	_, err = NewManager(newMockChain(), "", []*Config{{Name: "a", Queue: &QueueConfig{Url: "127.0.0.1"}, BlockTypes: []int{0}}})
+	assert.Error(t, err)
+}
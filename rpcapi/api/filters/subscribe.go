@@ -9,6 +9,7 @@ import (
 	"github.com/vitelabs/go-vite"
 	"github.com/vitelabs/go-vite/common/types"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/abiregistry"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/rpc"
 	"github.com/vitelabs/go-vite/rpcapi/api"
@@ -126,11 +127,20 @@ type Logs struct {
 	Removed          bool           `json:"removed"`
 }
 type LogsV2 struct {
-	Log              *ledger.VmLog  `json:"vmlog"`
-	AccountBlockHash types.Hash     `json:"accountBlockHash"`
-	AccountHeight    string         `json:"accountBlockHeight"`
-	Addr             *types.Address `json:"address"`
-	Removed          bool           `json:"removed"`
+	Log              *ledger.VmLog             `json:"vmlog"`
+	AccountBlockHash types.Hash                `json:"accountBlockHash"`
+	AccountHeight    string                    `json:"accountBlockHeight"`
+	Addr             *types.Address            `json:"address"`
+	Removed          bool                      `json:"removed"`
+	Decoded          *abiregistry.DecodedEvent `json:"decoded,omitempty"`
+}
+
+func (s *SubscribeApi) toLogsV2(l *Logs) *LogsV2 {
+	logs := &LogsV2{l.Log, l.AccountBlockHash, l.AccountHeight, l.Addr, l.Removed, nil}
+	if registry := s.vite.AbiRegistry(); registry != nil {
+		logs.Decoded = registry.DecodeVmLog(*l.Addr, l.Log)
+	}
+	return logs
 }
 
 // Deprecated: use subscribe_createSnapshotBlockFilter instead
@@ -444,7 +454,7 @@ func (s *SubscribeApi) getChangesByFilterId(id rpc.ID) (interface{}, error) {
 			f.logs = nil
 			result := make([]*LogsV2, len(logs))
 			for i, l := range logs {
-				result[i] = &LogsV2{l.Log, l.AccountBlockHash, l.AccountHeight, l.Addr, l.Removed}
+				result[i] = s.toLogsV2(l)
 			}
 			return LogsMsgV2{result, id}, nil
 		case SnapshotBlocksSubscription:
@@ -659,7 +669,7 @@ func (s *SubscribeApi) createVmLogSubscription(ctx context.Context, rangeMap map
 				if ft == LogsSubscriptionV2 {
 					result := make([]*LogsV2, len(msg))
 					for i, l := range msg {
-						result[i] = &LogsV2{l.Log, l.AccountBlockHash, l.AccountHeight, l.Addr, l.Removed}
+						result[i] = s.toLogsV2(l)
 					}
 					notifier.Notify(rpcSub.ID, result)
 				} else {
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -80,7 +81,14 @@ func (l *LedgerApi) ledgerChunksToRpcChunksV2(list []*ledger.SnapshotChunk) ([]*
 }
 
 func (l *LedgerApi) ledgerBlockToRpcBlock(block *ledger.AccountBlock) (*AccountBlock, error) {
-	return ledgerToRpcBlock(l.chain, block)
+	rpcBlock, err := ledgerToRpcBlock(l.chain, block)
+	if err != nil {
+		return nil, err
+	}
+	if registry := l.vite.AbiRegistry(); registry != nil {
+		rpcBlock.DecodedData = registry.DecodeAccountBlock(block)
+	}
+	return rpcBlock, nil
 }
 
 func (l *LedgerApi) ledgerBlocksToRpcBlocks(list []*ledger.AccountBlock) ([]*AccountBlock, error) {
@@ -116,11 +124,12 @@ func (l *LedgerApi) GetRawBlockByHash(blockHash types.Hash) (*ledger.AccountBloc
 	return l.chain.GetAccountBlockByHash(blockHash)
 }
 
-func (l *LedgerApi) GetCompleteBlockByHash(blockHash types.Hash) (*AccountBlock, error) {
+func (l *LedgerApi) GetCompleteBlockByHash(ctx context.Context, blockHash types.Hash) (*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	block, getError := l.chain.GetCompleteBlockByHash(blockHash)
 
 	if getError != nil {
-		l.log.Error("GetCompleteBlockByHash failed, error is "+getError.Error(), "method", "GetCompleteBlockByHash")
+		logger.Error("GetCompleteBlockByHash failed, error is "+getError.Error(), "method", "GetCompleteBlockByHash")
 
 		return nil, getError
 	}
@@ -132,8 +141,8 @@ func (l *LedgerApi) GetCompleteBlockByHash(blockHash types.Hash) (*AccountBlock,
 }
 
 // in token
-func (l *LedgerApi) GetBlocksByHashInToken(addr types.Address, originBlockHash *types.Hash, tokenTypeId types.TokenTypeId, count uint64) ([]*AccountBlock, error) {
-	return l.GetAccountBlocks(addr, originBlockHash, &tokenTypeId, count)
+func (l *LedgerApi) GetBlocksByHashInToken(ctx context.Context, addr types.Address, originBlockHash *types.Hash, tokenTypeId types.TokenTypeId, count uint64) ([]*AccountBlock, error) {
+	return l.GetAccountBlocks(ctx, addr, originBlockHash, &tokenTypeId, count)
 }
 
 func (l *LedgerApi) GetSnapshotBlockBeforeTime(timestamp int64) (*SnapshotBlock, error) {
@@ -151,16 +160,18 @@ func (l *LedgerApi) GetSnapshotBlockBeforeTime(timestamp int64) (*SnapshotBlock,
 	return l.ledgerSnapshotBlockToRpcBlock(sb)
 }
 
-func (l *LedgerApi) GetVmLogListByHash(logHash types.Hash) (ledger.VmLogList, error) {
+func (l *LedgerApi) GetVmLogListByHash(ctx context.Context, logHash types.Hash) (ledger.VmLogList, error) {
+	logger := log15.FromContext(ctx, l.log)
 	logList, err := l.chain.GetVmLogList(&logHash)
 	if err != nil {
-		l.log.Error("GetVmLogList failed, error is "+err.Error(), "method", "GetVmLogListByHash")
+		logger.Error("GetVmLogList failed, error is "+err.Error(), "method", "GetVmLogListByHash")
 		return nil, err
 	}
 	return logList, err
 }
 
-func (l *LedgerApi) GetBlocksByHeight(addr types.Address, height interface{}, count uint64) ([]*AccountBlock, error) {
+func (l *LedgerApi) GetBlocksByHeight(ctx context.Context, addr types.Address, height interface{}, count uint64) ([]*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	heightUint64, err := parseHeight(height)
 	if err != nil {
 		return nil, err
@@ -168,7 +179,7 @@ func (l *LedgerApi) GetBlocksByHeight(addr types.Address, height interface{}, co
 
 	accountBlocks, err := l.chain.GetAccountBlocksByHeight(addr, heightUint64, count)
 	if err != nil {
-		l.log.Error("GetAccountBlocksByHeight failed, error is "+err.Error(), "method", "GetBlocksByHeight")
+		logger.Error("GetAccountBlocksByHeight failed, error is "+err.Error(), "method", "GetBlocksByHeight")
 		return nil, err
 	}
 	if len(accountBlocks) <= 0 {
@@ -177,16 +188,18 @@ func (l *LedgerApi) GetBlocksByHeight(addr types.Address, height interface{}, co
 	return l.ledgerBlocksToRpcBlocks(accountBlocks)
 }
 
-func (l *LedgerApi) GetSnapshotBlockByHash(hash types.Hash) (*SnapshotBlock, error) {
+func (l *LedgerApi) GetSnapshotBlockByHash(ctx context.Context, hash types.Hash) (*SnapshotBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	block, err := l.chain.GetSnapshotBlockByHash(hash)
 	if err != nil {
-		l.log.Error("GetSnapshotBlockByHash failed, error is "+err.Error(), "method", "GetSnapshotBlockByHash")
+		logger.Error("GetSnapshotBlockByHash failed, error is "+err.Error(), "method", "GetSnapshotBlockByHash")
 		return nil, err
 	}
 	return l.ledgerSnapshotBlockToRpcBlock(block)
 }
 
-func (l *LedgerApi) GetSnapshotBlockByHeight(height interface{}) (*SnapshotBlock, error) {
+func (l *LedgerApi) GetSnapshotBlockByHeight(ctx context.Context, height interface{}) (*SnapshotBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	heightUint64, err := parseHeight(height)
 	if err != nil {
 		return nil, err
@@ -194,13 +207,14 @@ func (l *LedgerApi) GetSnapshotBlockByHeight(height interface{}) (*SnapshotBlock
 
 	block, err := l.chain.GetSnapshotBlockByHeight(heightUint64)
 	if err != nil {
-		l.log.Error("GetSnapshotBlockByHash failed, error is "+err.Error(), "method", "GetSnapshotBlockByHeight")
+		logger.Error("GetSnapshotBlockByHash failed, error is "+err.Error(), "method", "GetSnapshotBlockByHeight")
 		return nil, err
 	}
 	return l.ledgerSnapshotBlockToRpcBlock(block)
 }
 
-func (l *LedgerApi) GetSnapshotBlocks(height interface{}, count int) ([]*SnapshotBlock, error) {
+func (l *LedgerApi) GetSnapshotBlocks(ctx context.Context, height interface{}, count int) ([]*SnapshotBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	heightUint64, err := parseHeight(height)
 	if err != nil {
 		return nil, err
@@ -208,7 +222,7 @@ func (l *LedgerApi) GetSnapshotBlocks(height interface{}, count int) ([]*Snapsho
 
 	blocks, err := l.chain.GetSnapshotBlocksByHeight(heightUint64, false, uint64(count))
 	if err != nil {
-		l.log.Error("GetSnapshotBlocksByHeight failed, error is "+err.Error(), "method", "GetSnapshotBlocks")
+		logger.Error("GetSnapshotBlocksByHeight failed, error is "+err.Error(), "method", "GetSnapshotBlocks")
 		return nil, err
 	}
 	return l.ledgerSnapshotBlocksToRpcBlocks(blocks)
@@ -239,14 +253,15 @@ func (l *LedgerApi) GetChunks(startHeight interface{}, endHeight interface{}) ([
 
 }
 
-func (l *LedgerApi) GetSnapshotChainHeight() string {
-	l.log.Info("GetLatestSnapshotChainHeight")
+func (l *LedgerApi) GetSnapshotChainHeight(ctx context.Context) string {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetLatestSnapshotChainHeight")
 	return strconv.FormatUint(l.chain.GetLatestSnapshotBlock().Height, 10)
 }
 
 // old api
-func (l *LedgerApi) GetLatestSnapshotChainHash() *types.Hash {
-	return l.GetLatestSnapshotHash()
+func (l *LedgerApi) GetLatestSnapshotChainHash(ctx context.Context) *types.Hash {
+	return l.GetLatestSnapshotHash(ctx)
 }
 
 func (l *LedgerApi) GetLatestSnapshotBlock() (*SnapshotBlock, error) {
@@ -255,8 +270,8 @@ func (l *LedgerApi) GetLatestSnapshotBlock() (*SnapshotBlock, error) {
 }
 
 // old api
-func (l *LedgerApi) GetLatestBlock(addr types.Address) (*AccountBlock, error) {
-	return l.GetLatestAccountBlock(addr)
+func (l *LedgerApi) GetLatestBlock(ctx context.Context, addr types.Address) (*AccountBlock, error) {
+	return l.GetLatestAccountBlock(ctx, addr)
 }
 
 // old api
@@ -347,28 +362,28 @@ func parseHeight(height interface{}) (uint64, error) {
 // ------------------------------------------------------------------------------------------------------------------------
 
 // old api
-func (l *LedgerApi) GetBlockByHash(blockHash types.Hash) (*AccountBlock, error) {
-	return l.GetAccountBlockByHash(blockHash)
+func (l *LedgerApi) GetBlockByHash(ctx context.Context, blockHash types.Hash) (*AccountBlock, error) {
+	return l.GetAccountBlockByHash(ctx, blockHash)
 }
 
 // old api
-func (l *LedgerApi) GetBlocksByHash(addr types.Address, originBlockHash *types.Hash, count uint64) ([]*AccountBlock, error) {
-	return l.GetAccountBlocks(addr, originBlockHash, nil, count)
+func (l *LedgerApi) GetBlocksByHash(ctx context.Context, addr types.Address, originBlockHash *types.Hash, count uint64) ([]*AccountBlock, error) {
+	return l.GetAccountBlocks(ctx, addr, originBlockHash, nil, count)
 }
 
 // old api
-func (l *LedgerApi) GetBlockByHeight(addr types.Address, height interface{}) (*AccountBlock, error) {
-	return l.GetAccountBlockByHeight(addr, height)
+func (l *LedgerApi) GetBlockByHeight(ctx context.Context, addr types.Address, height interface{}) (*AccountBlock, error) {
+	return l.GetAccountBlockByHeight(ctx, addr, height)
 }
 
 // old api
-func (l *LedgerApi) GetBlocksByAccAddr(addr types.Address, index int, count int) ([]*AccountBlock, error) {
-	return l.GetAccountBlocksByAddress(addr, index, count)
+func (l *LedgerApi) GetBlocksByAccAddr(ctx context.Context, addr types.Address, index int, count int) ([]*AccountBlock, error) {
+	return l.GetAccountBlocksByAddress(ctx, addr, index, count)
 }
 
 // old api
-func (l *LedgerApi) GetAccountByAccAddr(addr types.Address) (*RpcAccountInfo, error) {
-	info, err := l.getAccountInfoByAddress(addr)
+func (l *LedgerApi) GetAccountByAccAddr(ctx context.Context, addr types.Address) (*RpcAccountInfo, error) {
+	info, err := l.getAccountInfoByAddress(ctx, addr)
 	if err != nil {
 		return nil, err
 	}
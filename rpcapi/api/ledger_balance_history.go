@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/chain"
+)
+
+const maxTokenHolderPageSize = 1000
+
+type TokenHolder struct {
+	Address types.Address `json:"address"`
+	Balance *string       `json:"balance"`
+}
+
+type TokenHolderPage struct {
+	TokenId        types.TokenTypeId `json:"tokenId"`
+	SnapshotHeight string            `json:"snapshotHeight"`
+	SnapshotHash   types.Hash        `json:"snapshotHash"`
+	Holders        []*TokenHolder    `json:"holders"`
+	// the start address of the next page, nil if there is no more holder
+	NextAddress *types.Address `json:"nextAddress"`
+}
+
+// GetBalanceBySnapshotHeight returns the balance of the address at the snapshot height
+func (l *LedgerApi) GetBalanceBySnapshotHeight(addr types.Address, tokenId types.TokenTypeId, snapshotHeight interface{}) (*string, error) {
+	sb, err := l.getHistorySnapshotBlock(snapshotHeight)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := l.chain.GetBalanceBySnapshotHeight(addr, tokenId, sb.Height)
+	if err != nil {
+		return nil, err
+	}
+	return bigIntToString(balance), nil
+}
+
+// private: ledgerdebug_getTokenHolders, returns a page of the holders of the token at the snapshot height, ordered
+// by address. Pass the nextAddress of the previous page as startAddr with the same snapshot height to get the next page.
+// A page scans the balance history of every account from startAddr, so it is not served on the public namespaces.
+func (ld LedgerDebugApi) GetTokenHolders(tokenId types.TokenTypeId, snapshotHeight interface{}, startAddr *types.Address, count int) (*TokenHolderPage, error) {
+	if count <= 0 || count > maxTokenHolderPageSize {
+		return nil, fmt.Errorf("count should be between 1 and %d", maxTokenHolderPageSize)
+	}
+	sb, err := getHistorySnapshotBlock(ld.chain, snapshotHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	holders, err := ld.chain.GetTokenHolders(tokenId, sb.Height, startAddr, count+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TokenHolderPage{
+		TokenId:        tokenId,
+		SnapshotHeight: Uint64ToString(sb.Height),
+		SnapshotHash:   sb.Hash,
+		Holders:        make([]*TokenHolder, 0, count),
+	}
+	if len(holders) > count {
+		holders = holders[:count]
+		next := holders[count-1].Address
+		page.NextAddress = &next
+	}
+	for _, holder := range holders {
+		page.Holders = append(page.Holders, &TokenHolder{
+			Address: holder.Address,
+			Balance: bigIntToString(holder.Balance),
+		})
+	}
+	return page, nil
+}
+
+func (l *LedgerApi) getHistorySnapshotBlock(snapshotHeight interface{}) (*ledger.SnapshotBlock, error) {
+	return getHistorySnapshotBlock(l.chain, snapshotHeight)
+}
+
+func getHistorySnapshotBlock(c chain.Chain, snapshotHeight interface{}) (*ledger.SnapshotBlock, error) {
+	height, err := parseHeight(snapshotHeight)
+	if err != nil {
+		return nil, err
+	}
+	if height > c.GetLatestSnapshotBlock().Height {
+		return nil, errors.New("snapshot height is higher than the latest snapshot block")
+	}
+	sb, err := c.GetSnapshotHeaderByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	if sb == nil {
+		return nil, fmt.Errorf("snapshot block %d is not found", height)
+	}
+	return sb, nil
+}
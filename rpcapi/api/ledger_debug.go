@@ -1,19 +1,24 @@
 package api
 
 import (
+	"context"
 	"github.com/vitelabs/go-vite"
 	"github.com/vitelabs/go-vite/common/db/xleveldb/errors"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/ledger/chain"
+	chain_flusher "github.com/vitelabs/go-vite/ledger/chain/flusher"
 	"github.com/vitelabs/go-vite/ledger/onroad"
+	"github.com/vitelabs/go-vite/log15"
 )
 
 type LedgerDebugApi struct {
+	chain      chain.Chain
 	unreceived *UnreceivedDebugApi
 }
 
 func NewLedgerDebugApi(vite *vite.Vite) *LedgerDebugApi {
 	return &LedgerDebugApi{
+		chain:      vite.Chain(),
 		unreceived: NewUnreceivedDebugApi(vite),
 	}
 }
@@ -21,6 +26,30 @@ func (ld LedgerDebugApi) String() string {
 	return "LedgerDebugApi"
 }
 
+type FlushCheckpoint struct {
+	SnapshotHeight uint64               `json:"snapshotHeight"`
+	SnapshotHash   types.Hash           `json:"snapshotHash"`
+	Stats          *chain_flusher.Stats `json:"stats"`
+}
+
+// private: ledgerdebug_getFlushStats
+func (ld LedgerDebugApi) GetFlushStats() *chain_flusher.Stats {
+	return ld.chain.GetFlushStats()
+}
+
+// private: ledgerdebug_flushCheckpoint, flushes and syncs the ledger to the disk, e.g. before copying the data directory
+func (ld LedgerDebugApi) FlushCheckpoint() (*FlushCheckpoint, error) {
+	latest, err := ld.chain.FlushCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	return &FlushCheckpoint{
+		SnapshotHeight: latest.Height,
+		SnapshotHash:   latest.Hash,
+		Stats:          ld.chain.GetFlushStats(),
+	}, nil
+}
+
 type UnreceivedDebugApi struct {
 	manager *onroad.Manager
 	chain   chain.Chain
@@ -34,7 +63,8 @@ func NewUnreceivedDebugApi(vite *vite.Vite) *UnreceivedDebugApi {
 }
 
 // private: unreceived_getContractUnreceivedTransactionCount <- onroad_getContractOnRoadTotalNum
-func (ud *UnreceivedDebugApi) GetContractUnreceivedTransactionCount(addr types.Address, gid *types.Gid) (uint64, error) {
+func (ud *UnreceivedDebugApi) GetContractUnreceivedTransactionCount(ctx context.Context, addr types.Address, gid *types.Gid) (uint64, error) {
+	logger := log15.FromContext(ctx, log)
 	if !types.IsContractAddr(addr) {
 		return 0, errors.New("Address must be the type of Contract.")
 	}
@@ -50,12 +80,13 @@ func (ud *UnreceivedDebugApi) GetContractUnreceivedTransactionCount(addr types.A
 	if err != nil {
 		return 0, err
 	}
-	log.Info("GetContractUnreceivedTransactionCount", "gid", gid, "addr", addr, "num", num)
+	logger.Info("GetContractUnreceivedTransactionCount", "gid", gid, "addr", addr, "num", num)
 	return num, nil
 }
 
 // private: unreceived_getContractUnreceivedFrontBlocks <- onorad_getContractOnRoadFrontBlocks
-func (pu *UnreceivedDebugApi) GetContractUnreceivedFrontBlocks(addr types.Address, gid *types.Gid) ([]*AccountBlock, error) {
+func (pu *UnreceivedDebugApi) GetContractUnreceivedFrontBlocks(ctx context.Context, addr types.Address, gid *types.Gid) ([]*AccountBlock, error) {
+	logger := log15.FromContext(ctx, log)
 	if !types.IsContractAddr(addr) {
 		return nil, errors.New("Address must be the type of Contract.")
 	}
@@ -70,7 +101,7 @@ func (pu *UnreceivedDebugApi) GetContractUnreceivedFrontBlocks(addr types.Addres
 	if err != nil {
 		return nil, err
 	}
-	log.Info("GetContractUnreceivedFrontBlocks", "gid", gid, "addr", addr, "len", len(blockList))
+	logger.Info("GetContractUnreceivedFrontBlocks", "gid", gid, "addr", addr, "len", len(blockList))
 	rpcBlockList := make([]*AccountBlock, len(blockList))
 	sum := 0
 	for _, v := range blockList {
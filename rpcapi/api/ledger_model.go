@@ -7,6 +7,7 @@ import (
 
 	"github.com/vitelabs/go-vite/common/types"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/abiregistry"
 	"github.com/vitelabs/go-vite/ledger/chain"
 	"github.com/vitelabs/go-vite/vm/quota"
 )
@@ -79,6 +80,8 @@ type AccountBlock struct {
 	ReceiveBlockHeight *string     `json:"receiveBlockHeight"`
 	ReceiveBlockHash   *types.Hash `json:"receiveBlockHash"`
 
+	DecodedData *abiregistry.DecodedCall `json:"decodedData,omitempty"`
+
 	Timestamp int64 `json:"timestamp"`
 }
 
@@ -285,7 +288,11 @@ func ledgerSnapshotBlockToRpcBlock(sb *ledger.SnapshotBlock) (*SnapshotBlock, er
 	return rpcBlock, nil
 }
 
-func ledgerToRpcBlock(chain chain.Chain, lAb *ledger.AccountBlock) (*AccountBlock, error) {
+func ledgerToRpcBlock(c chain.Chain, lAb *ledger.AccountBlock) (*AccountBlock, error) {
+	// only the header of a pruned block is kept
+	if err := c.CheckAccountBlockPruned(chain.PruneDataBlockBody, lAb.Hash); err != nil {
+		return nil, err
+	}
 	rpcBlock := &AccountBlock{
 		BlockType:    lAb.BlockType,
 		Hash:         lAb.Hash,
@@ -337,7 +344,7 @@ func ledgerToRpcBlock(chain chain.Chain, lAb *ledger.AccountBlock) (*AccountBloc
 			rpcBlock.Fee = &fee
 		}
 	} else {
-		sendBlock, err := chain.GetAccountBlockByHash(lAb.FromBlockHash)
+		sendBlock, err := c.GetAccountBlockByHash(lAb.FromBlockHash)
 		if err != nil {
 			return nil, err
 		}
@@ -365,7 +372,7 @@ func ledgerToRpcBlock(chain chain.Chain, lAb *ledger.AccountBlock) (*AccountBloc
 		rpcBlock.Difficulty = &difficulty
 	}
 
-	if err := rpcBlock.addExtraInfo(chain); err != nil {
+	if err := rpcBlock.addExtraInfo(c); err != nil {
 		return nil, err
 	}
 
@@ -373,7 +380,7 @@ func ledgerToRpcBlock(chain chain.Chain, lAb *ledger.AccountBlock) (*AccountBloc
 	if len(lAb.SendBlockList) > 0 {
 		subBlockList := make([]*AccountBlock, len(lAb.SendBlockList))
 		for k, v := range lAb.SendBlockList {
-			subRpcTx, err := ledgerToRpcBlock(chain, v)
+			subRpcTx, err := ledgerToRpcBlock(c, v)
 			if err != nil {
 				return nil, err
 			}
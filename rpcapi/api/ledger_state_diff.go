@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/chain"
+	"github.com/vitelabs/go-vite/ledger/statediff"
+	"github.com/vitelabs/go-vite/log15"
+)
+
+type StorageDiff struct {
+	Key    string `json:"key"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type BalanceDiff struct {
+	TokenId types.TokenTypeId `json:"tokenId"`
+	Before  *string           `json:"before"`
+	After   *string           `json:"after"`
+}
+
+type ContractMetaDiff struct {
+	Address types.Address        `json:"address"`
+	Before  *ledger.ContractMeta `json:"before"`
+	After   *ledger.ContractMeta `json:"after"`
+}
+
+type StateDiff struct {
+	Address   types.Address `json:"address"`
+	BlockHash types.Hash    `json:"blockHash"`
+	Height    string        `json:"height"`
+	Source    string        `json:"source"`
+
+	Storage       []*StorageDiff      `json:"storage"`
+	Balances      []*BalanceDiff      `json:"balances"`
+	ContractMetas []*ContractMetaDiff `json:"contractMetas"`
+	Code          string              `json:"code,omitempty"`
+}
+
+// GetStateDiff returns the storage, balance and contract meta changes caused by the account block
+func (l *LedgerApi) GetStateDiff(ctx context.Context, blockHash types.Hash) (*StateDiff, error) {
+	logger := log15.FromContext(ctx, l.log)
+	if err := l.chain.CheckAccountBlockPruned(chain.PruneDataStateHistory, blockHash); err != nil {
+		return nil, err
+	}
+	diff, err := statediff.NewDiffer(l.chain, l.vite.Consensus()).GetStateDiff(blockHash)
+	if err != nil {
+		logger.Info("get state diff failed", "hash", blockHash, "err", err)
+		return nil, err
+	}
+	return toRpcStateDiff(diff), nil
+}
+
+func toRpcStateDiff(diff *statediff.StateDiff) *StateDiff {
+	result := &StateDiff{
+		Address:       diff.Address,
+		BlockHash:     diff.BlockHash,
+		Height:        Uint64ToString(diff.Height),
+		Source:        diff.Source,
+		Storage:       make([]*StorageDiff, 0, len(diff.Storage)),
+		Balances:      make([]*BalanceDiff, 0, len(diff.Balances)),
+		ContractMetas: make([]*ContractMetaDiff, 0, len(diff.ContractMetas)),
+		Code:          hex.EncodeToString(diff.Code),
+	}
+	for _, item := range diff.Storage {
+		result.Storage = append(result.Storage, &StorageDiff{
+			Key:    hex.EncodeToString(item.Key),
+			Before: hex.EncodeToString(item.Before),
+			After:  hex.EncodeToString(item.After),
+		})
+	}
+	for _, item := range diff.Balances {
+		result.Balances = append(result.Balances, &BalanceDiff{
+			TokenId: item.TokenId,
+			Before:  bigIntToString(item.Before),
+			After:   bigIntToString(item.After),
+		})
+	}
+	for _, item := range diff.ContractMetas {
+		result.ContractMetas = append(result.ContractMetas, &ContractMetaDiff{
+			Address: item.Address,
+			Before:  item.Before,
+			After:   item.After,
+		})
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+const maxStatePageSize = 1000
+
+type StateAccount struct {
+	Address types.Address `json:"address"`
+	Height  string        `json:"height"`
+	Hash    types.Hash    `json:"hash"`
+}
+
+type StateBalance struct {
+	Address types.Address     `json:"address"`
+	TokenId types.TokenTypeId `json:"tokenId"`
+	Balance *string           `json:"balance"`
+}
+
+type StateStorage struct {
+	Address types.Address `json:"address"`
+	Key     string        `json:"key"`
+	Value   string        `json:"value"`
+}
+
+type StateContractMeta struct {
+	Address types.Address        `json:"address"`
+	Meta    *ledger.ContractMeta `json:"meta"`
+}
+
+type StatePage struct {
+	Kind           string     `json:"kind"`
+	SnapshotHeight string     `json:"snapshotHeight"`
+	SnapshotHash   types.Hash `json:"snapshotHash"`
+
+	Accounts      []*StateAccount      `json:"accounts,omitempty"`
+	Balances      []*StateBalance      `json:"balances,omitempty"`
+	Storage       []*StateStorage      `json:"storage,omitempty"`
+	ContractMetas []*StateContractMeta `json:"contractMetas,omitempty"`
+
+	// the cursor of the next page in hex, nil if there are no more items
+	NextCursor *string `json:"nextCursor"`
+}
+
+// GetStatePage returns a page of the accounts, balances, storage or contract metas at the snapshot height, kind is
+// one of "accounts", "balances", "storage" and "contractMetas". Pass the nextCursor of the previous page as cursor
+// with the same kind and snapshot height to get the next page, the pages are consistent with the snapshot block.
+// A call scans at most 10000 items, so a page may have fewer items than count, even none, before the end. Keep
+// reading until nextCursor is nil.
+func (l *LedgerApi) GetStatePage(kind string, snapshotHeight interface{}, cursor *string, count int) (*StatePage, error) {
+	if count <= 0 || count > maxStatePageSize {
+		return nil, fmt.Errorf("count should be between 1 and %d", maxStatePageSize)
+	}
+	var cursorBytes []byte
+	if cursor != nil && len(*cursor) > 0 {
+		var err error
+		if cursorBytes, err = hex.DecodeString(*cursor); err != nil {
+			return nil, fmt.Errorf("invalid cursor. Error: %s", err)
+		}
+	}
+	sb, err := l.getHistorySnapshotBlock(snapshotHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := l.chain.NewStateIterator(sb.Height)
+	if err != nil {
+		return nil, err
+	}
+	statePage, err := iterator.Next(kind, cursorBytes, count)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &StatePage{
+		Kind:           kind,
+		SnapshotHeight: Uint64ToString(sb.Height),
+		SnapshotHash:   sb.Hash,
+	}
+	for _, account := range statePage.Accounts {
+		page.Accounts = append(page.Accounts, &StateAccount{
+			Address: account.Address,
+			Height:  Uint64ToString(account.Height),
+			Hash:    account.Hash,
+		})
+	}
+	for _, balance := range statePage.Balances {
+		page.Balances = append(page.Balances, &StateBalance{
+			Address: balance.Address,
+			TokenId: balance.TokenId,
+			Balance: bigIntToString(balance.Balance),
+		})
+	}
+	for _, storage := range statePage.Storage {
+		page.Storage = append(page.Storage, &StateStorage{
+			Address: storage.Address,
+			Key:     hex.EncodeToString(storage.Key),
+			Value:   hex.EncodeToString(storage.Value),
+		})
+	}
+	for _, contractMeta := range statePage.ContractMetas {
+		page.ContractMetas = append(page.ContractMetas, &StateContractMeta{
+			Address: contractMeta.Address,
+			Meta:    contractMeta.Meta,
+		})
+	}
+	if statePage.NextCursor != nil {
+		next := hex.EncodeToString(statePage.NextCursor)
+		page.NextCursor = &next
+	}
+	return page, nil
+}
@@ -1,15 +1,19 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"strconv"
 
 	"github.com/vitelabs/go-vite/common/db/xleveldb/errors"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/common/upgrade"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/ledger/abiregistry"
 	"github.com/vitelabs/go-vite/ledger/chain"
 	chain_plugins "github.com/vitelabs/go-vite/ledger/chain/plugins"
+	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/vm"
 	"github.com/vitelabs/go-vite/vm/contracts/dex"
 	"github.com/vitelabs/go-vite/vm/quota"
@@ -18,7 +22,8 @@ import (
 )
 
 // new api
-func (l *LedgerApi) GetAccountBlocks(addr types.Address, originBlockHash *types.Hash, tokenTypeId *types.TokenTypeId, count uint64) ([]*AccountBlock, error) {
+func (l *LedgerApi) GetAccountBlocks(ctx context.Context, addr types.Address, originBlockHash *types.Hash, tokenTypeId *types.TokenTypeId, count uint64) ([]*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	if tokenTypeId == nil {
 		if originBlockHash == nil {
 			block, err := l.chain.GetLatestAccountBlock(addr)
@@ -40,7 +45,7 @@ func (l *LedgerApi) GetAccountBlocks(addr types.Address, originBlockHash *types.
 		}
 
 		if blocks, err := l.ledgerBlocksToRpcBlocks(list); err != nil {
-			l.log.Error("GetConfirmTimes failed, error is "+err.Error(), "method", "GetAccountBlocks")
+			logger.Error("GetConfirmTimes failed, error is "+err.Error(), "method", "GetAccountBlocks")
 			return nil, err
 		} else {
 			return blocks, nil
@@ -67,10 +72,11 @@ func (l *LedgerApi) GetAccountBlocks(addr types.Address, originBlockHash *types.
 }
 
 // new api
-func (l *LedgerApi) GetAccountBlockByHash(blockHash types.Hash) (*AccountBlock, error) {
+func (l *LedgerApi) GetAccountBlockByHash(ctx context.Context, blockHash types.Hash) (*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	block, getError := l.chain.GetAccountBlockByHash(blockHash)
 	if getError != nil {
-		l.log.Error("GetAccountBlockByHash failed, error is "+getError.Error(), "method", "GetAccountBlockByHash")
+		logger.Error("GetAccountBlockByHash failed, error is "+getError.Error(), "method", "GetAccountBlockByHash")
 
 		return nil, getError
 	}
@@ -82,7 +88,8 @@ func (l *LedgerApi) GetAccountBlockByHash(blockHash types.Hash) (*AccountBlock,
 }
 
 // new api
-func (l *LedgerApi) GetAccountBlockByHeight(addr types.Address, height interface{}) (*AccountBlock, error) {
+func (l *LedgerApi) GetAccountBlockByHeight(ctx context.Context, addr types.Address, height interface{}) (*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
 	heightUint64, err := parseHeight(height)
 	if err != nil {
 		return nil, err
@@ -90,7 +97,7 @@ func (l *LedgerApi) GetAccountBlockByHeight(addr types.Address, height interface
 
 	accountBlock, err := l.chain.GetAccountBlockByHeight(addr, heightUint64)
 	if err != nil {
-		l.log.Error("GetAccountBlockByHeight failed, error is "+err.Error(), "method", "GetAccountBlockByHeight")
+		logger.Error("GetAccountBlockByHeight failed, error is "+err.Error(), "method", "GetAccountBlockByHeight")
 		return nil, err
 	}
 
@@ -101,12 +108,13 @@ func (l *LedgerApi) GetAccountBlockByHeight(addr types.Address, height interface
 }
 
 // new api
-func (l *LedgerApi) GetAccountBlocksByAddress(addr types.Address, index int, count int) ([]*AccountBlock, error) {
-	l.log.Info("GetAccountBlocksByAddress")
+func (l *LedgerApi) GetAccountBlocksByAddress(ctx context.Context, addr types.Address, index int, count int) ([]*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetAccountBlocksByAddress")
 
 	height, err := l.chain.GetLatestAccountHeight(addr)
 	if err != nil {
-		l.log.Error(fmt.Sprintf("GetLatestAccountHeight, addr is %s", addr), "err", err, "method", "GetAccountBlocksByAddress")
+		logger.Error(fmt.Sprintf("GetLatestAccountHeight, addr is %s", addr), "err", err, "method", "GetAccountBlocksByAddress")
 		return nil, err
 	}
 
@@ -118,12 +126,12 @@ func (l *LedgerApi) GetAccountBlocksByAddress(addr types.Address, index int, cou
 	list, getErr := l.chain.GetAccountBlocksByHeight(addr, height-num, uint64(count))
 
 	if getErr != nil {
-		l.log.Info("GetBlocksByAccAddr", "err", getErr, "method", "GetAccountBlocksByAddress")
+		logger.Info("GetBlocksByAccAddr", "err", getErr, "method", "GetAccountBlocksByAddress")
 		return nil, getErr
 	}
 
 	if blocks, err := l.ledgerBlocksToRpcBlocks(list); err != nil {
-		l.log.Error("GetConfirmTimes failed, error is "+err.Error(), "method", "GetAccountBlocksByAddress")
+		logger.Error("GetConfirmTimes failed, error is "+err.Error(), "method", "GetAccountBlocksByAddress")
 		return nil, err
 	} else {
 		return blocks, nil
@@ -146,20 +154,22 @@ func (l *LedgerApi) GetAccountBlocksByHeightRange(addr types.Address, start uint
 }
 
 // new api
-func (l *LedgerApi) GetAccountInfoByAddress(addr types.Address) (*AccountInfo, error) {
-	l.log.Info("GetAccountInfoByAddress")
+func (l *LedgerApi) GetAccountInfoByAddress(ctx context.Context, addr types.Address) (*AccountInfo, error) {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetAccountInfoByAddress")
 
-	info, err := l.getAccountInfoByAddress(addr)
+	info, err := l.getAccountInfoByAddress(ctx, addr)
 	if err != nil {
 		return nil, err
 	}
 	return ToAccountInfo(l.chain, info), nil
 }
 
-func (l *LedgerApi) getAccountInfoByAddress(addr types.Address) (*ledger.AccountInfo, error) {
+func (l *LedgerApi) getAccountInfoByAddress(ctx context.Context, addr types.Address) (*ledger.AccountInfo, error) {
+	logger := log15.FromContext(ctx, l.log)
 	latestAccountBlock, err := l.chain.GetLatestAccountBlock(addr)
 	if err != nil {
-		l.log.Error("GetLatestAccountBlock failed, error is "+err.Error(), "method", "GetAccountInfoByAddress")
+		logger.Error("GetLatestAccountBlock failed, error is "+err.Error(), "method", "GetAccountInfoByAddress")
 		return nil, err
 	}
 
@@ -170,7 +180,7 @@ func (l *LedgerApi) getAccountInfoByAddress(addr types.Address) (*ledger.Account
 
 	balanceMap, err := l.chain.GetBalanceMap(addr)
 	if err != nil {
-		l.log.Error("GetAccountBalance failed, error is "+err.Error(), "method", "GetAccountInfoByAddress")
+		logger.Error("GetAccountBalance failed, error is "+err.Error(), "method", "GetAccountInfoByAddress")
 		return nil, err
 	}
 
@@ -197,17 +207,19 @@ func (l *LedgerApi) getAccountInfoByAddress(addr types.Address) (*ledger.Account
 }
 
 // new api
-func (l *LedgerApi) GetLatestSnapshotHash() *types.Hash {
-	l.log.Info("GetLatestSnapshotHash")
+func (l *LedgerApi) GetLatestSnapshotHash(ctx context.Context) *types.Hash {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetLatestSnapshotHash")
 	return &l.chain.GetLatestSnapshotBlock().Hash
 }
 
 // new api
-func (l *LedgerApi) GetLatestAccountBlock(addr types.Address) (*AccountBlock, error) {
-	l.log.Info("GetLatestAccountBlock")
+func (l *LedgerApi) GetLatestAccountBlock(ctx context.Context, addr types.Address) (*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetLatestAccountBlock")
 	block, getError := l.chain.GetLatestAccountBlock(addr)
 	if getError != nil {
-		l.log.Error("GetLatestAccountBlock failed, error is "+getError.Error(), "method", "GetLatestAccountBlock")
+		logger.Error("GetLatestAccountBlock failed, error is "+getError.Error(), "method", "GetLatestAccountBlock")
 		return nil, getError
 	}
 
@@ -227,12 +239,54 @@ func (l *LedgerApi) GetVmLogs(blockHash types.Hash) (ledger.VmLogList, error) {
 		}
 		return nil, errors.New("get block failed")
 	}
+	if block.LogHash != nil {
+		if err := l.chain.CheckAccountBlockPruned(chain.PruneDataVmLog, blockHash); err != nil {
+			return nil, err
+		}
+	}
 
 	return l.chain.GetVmLogList(block.LogHash)
 }
 
+type DecodedVmLog struct {
+	Log     *ledger.VmLog             `json:"vmlog"`
+	Decoded *abiregistry.DecodedEvent `json:"decoded"`
+}
+
+// new api
+func (l *LedgerApi) GetDecodedVmLogs(blockHash types.Hash) ([]*DecodedVmLog, error) {
+	block, err := l.chain.GetAccountBlockByHash(blockHash)
+	if block == nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("get block failed")
+	}
+	if block.LogHash != nil {
+		if err := l.chain.CheckAccountBlockPruned(chain.PruneDataVmLog, blockHash); err != nil {
+			return nil, err
+		}
+	}
+
+	list, err := l.chain.GetVmLogList(block.LogHash)
+	if err != nil {
+		return nil, err
+	}
+	registry := l.vite.AbiRegistry()
+	result := make([]*DecodedVmLog, len(list))
+	for i, vmLog := range list {
+		result[i] = &DecodedVmLog{Log: vmLog}
+		if registry != nil {
+			result[i].Decoded = registry.DecodeVmLog(block.AccountAddress, vmLog)
+		}
+	}
+	return result, nil
+}
+
 // new api
-func (l *LedgerApi) SendRawTransaction(block *AccountBlock) error {
+func (l *LedgerApi) SendRawTransaction(ctx context.Context, block *AccountBlock) error {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("SendRawTransaction")
 
 	if block == nil {
 		return errors.New("empty block")
@@ -260,20 +314,26 @@ func (l *LedgerApi) SendRawTransaction(block *AccountBlock) error {
 
 	result, err := l.vite.Verifier().VerifyRPCAccountBlock(lb, latestSb)
 	if err != nil {
+		logger.Info("verify raw transaction failed", "hash", lb.Hash, "err", err)
 		return err
 	}
 
 	if result != nil {
-		return l.vite.Pool().AddDirectAccountBlock(result.AccountBlock.AccountAddress, result)
+		if err := l.vite.Pool().AddDirectAccountBlock(result.AccountBlock.AccountAddress, result); err != nil {
+			logger.Info("add raw transaction failed", "hash", lb.Hash, "err", err)
+			return err
+		}
+		logger.Info("raw transaction added", "addr", lb.AccountAddress, "height", lb.Height, "hash", lb.Hash)
+		return nil
 	} else {
 		return errors.New("generator gen an empty block")
 	}
 }
 
 // new api: ledger_getUnreceivedBlocksByAddress <- onroad_getOnroadBlocksByAddress
-func (l *LedgerApi) GetUnreceivedBlocksByAddress(address types.Address, index, count uint64) ([]*AccountBlock, error) {
-
-	log.Info("GetUnreceivedBlocksByAddress", "addr", address, "index", index, "count", count)
+func (l *LedgerApi) GetUnreceivedBlocksByAddress(ctx context.Context, address types.Address, index, count uint64) ([]*AccountBlock, error) {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetUnreceivedBlocksByAddress", "addr", address, "index", index, "count", count)
 
 	blockList, err := l.chain.GetOnRoadBlocksByAddr(address, int(index), int(count))
 	if err != nil {
@@ -295,9 +355,9 @@ func (l *LedgerApi) GetUnreceivedBlocksByAddress(address types.Address, index, c
 }
 
 // new api: ledger_getUnreceivedTransactionSummaryByAddress <- onroad_getOnroadInfoByAddress
-func (l *LedgerApi) GetUnreceivedTransactionSummaryByAddress(address types.Address) (*AccountInfo, error) {
-
-	log.Info("GetUnreceivedTransactionSummaryByAddress", "addr", address)
+func (l *LedgerApi) GetUnreceivedTransactionSummaryByAddress(ctx context.Context, address types.Address) (*AccountInfo, error) {
+	logger := log15.FromContext(ctx, l.log)
+	logger.Info("GetUnreceivedTransactionSummaryByAddress", "addr", address)
 
 	info, e := l.chain.GetAccountOnRoadInfo(address)
 	if e != nil || info == nil {
@@ -307,13 +367,13 @@ func (l *LedgerApi) GetUnreceivedTransactionSummaryByAddress(address types.Addre
 }
 
 // new api: ledger_getUnreceivedBlocksInBatch <- onroad_getOnroadBlocksInBatch
-func (l *LedgerApi) GetUnreceivedBlocksInBatch(queryList []PagingQueryBatch) (map[types.Address][]*AccountBlock, error) {
+func (l *LedgerApi) GetUnreceivedBlocksInBatch(ctx context.Context, queryList []PagingQueryBatch) (map[types.Address][]*AccountBlock, error) {
 	resultMap := make(map[types.Address][]*AccountBlock)
 	for _, q := range queryList {
 		if l, ok := resultMap[q.Address]; ok && l != nil {
 			continue
 		}
-		blockList, err := l.GetUnreceivedBlocksByAddress(q.Address, q.PageNumber, q.PageCount)
+		blockList, err := l.GetUnreceivedBlocksByAddress(ctx, q.Address, q.PageNumber, q.PageCount)
 		if err != nil {
 			return nil, err
 		}
@@ -326,7 +386,7 @@ func (l *LedgerApi) GetUnreceivedBlocksInBatch(queryList []PagingQueryBatch) (ma
 }
 
 // new api:  ledger_getUnreceivedTransactionSummaryInBatch <-  onroad_getOnroadInfoInBatch
-func (l *LedgerApi) GetUnreceivedTransactionSummaryInBatch(addressList []types.Address) ([]*AccountInfo, error) {
+func (l *LedgerApi) GetUnreceivedTransactionSummaryInBatch(ctx context.Context, addressList []types.Address) ([]*AccountInfo, error) {
 
 	// Remove duplicate
 	addrMap := make(map[types.Address]bool, 0)
@@ -336,7 +396,7 @@ func (l *LedgerApi) GetUnreceivedTransactionSummaryInBatch(addressList []types.A
 
 	resultList := make([]*AccountInfo, 0)
 	for addr, _ := range addrMap {
-		info, err := l.GetUnreceivedTransactionSummaryByAddress(addr)
+		info, err := l.GetUnreceivedTransactionSummaryByAddress(ctx, addr)
 		if err != nil {
 			return nil, err
 		}
@@ -412,14 +472,24 @@ func ToFilterParam(rangeMap map[string]*Range, topics [][]types.Hash) (*FilterPa
 }
 
 type Logs struct {
-	Log              *ledger.VmLog  `json:"vmlog"`
-	AccountBlockHash types.Hash     `json:"accountBlockHash"`
-	AccountHeight    string         `json:"accountBlockHeight"`
-	Addr             *types.Address `json:"address"`
+	Log              *ledger.VmLog             `json:"vmlog"`
+	AccountBlockHash types.Hash                `json:"accountBlockHash"`
+	AccountHeight    string                    `json:"accountBlockHeight"`
+	Addr             *types.Address            `json:"address"`
+	Decoded          *abiregistry.DecodedEvent `json:"decoded,omitempty"`
 }
 
 func (l *LedgerApi) GetVmLogsByFilter(param VmLogFilterParam) ([]*Logs, error) {
-	return GetLogs(l.chain, param.AddrRange, param.Topics)
+	logs, err := GetLogs(l.chain, param.AddrRange, param.Topics)
+	if err != nil {
+		return nil, err
+	}
+	if registry := l.vite.AbiRegistry(); registry != nil {
+		for _, item := range logs {
+			item.Decoded = registry.DecodeVmLog(*item.Addr, item.Log)
+		}
+	}
+	return logs, nil
 }
 func GetLogs(c chain.Chain, rangeMap map[string]*Range, topics [][]types.Hash) ([]*Logs, error) {
 	filterParam, err := ToFilterParam(rangeMap, topics)
@@ -455,13 +525,16 @@ func GetLogs(c chain.Chain, rangeMap map[string]*Range, topics [][]types.Hash) (
 			}
 			for i := len(blocks); i > 0; i-- {
 				if blocks[i-1].LogHash != nil {
+					if err := c.CheckAccountBlockPruned(chain.PruneDataVmLog, blocks[i-1].Hash); err != nil {
+						return nil, err
+					}
 					list, err := c.GetVmLogList(blocks[i-1].LogHash)
 					if err != nil {
 						return nil, err
 					}
 					for _, l := range list {
 						if FilterLog(filterParam, l) {
-							logs = append(logs, &Logs{l, blocks[i-1].Hash, Uint64ToString(blocks[i-1].Height), &addr})
+							logs = append(logs, &Logs{Log: l, AccountBlockHash: blocks[i-1].Hash, AccountHeight: Uint64ToString(blocks[i-1].Height), Addr: &addr})
 						}
 					}
 				}
@@ -675,3 +748,42 @@ func (l *LedgerApi) GetChunksV2(startHeight interface{}, endHeight interface{})
 func (l LedgerApi) GetUpgradeInfo() (interface{}, error) {
 	return upgrade.GetAllPoints(), nil
 }
+
+type UpgradeReport struct {
+	Height string `json:"height"`
+	// the latest upgrade version active at the height
+	ActiveVersion uint32 `json:"activeVersion"`
+	// the highest upgrade version supported by the binary of the node
+	SupportedVersion uint32 `json:"supportedVersion"`
+	// the version of the latest snapshot block, set by the producer
+	ProducerVersion uint32                 `json:"producerVersion"`
+	Points          []*upgrade.PointStatus `json:"points"`
+	// not empty if the node can't run the version of the producers
+	Warning string `json:"warning,omitempty"`
+}
+
+// GetUpgradeReport returns the upgrade points active at the snapshot height, the latest height if snapshotHeight is nil
+func (l LedgerApi) GetUpgradeReport(snapshotHeight *string) (*UpgradeReport, error) {
+	latestSb := l.chain.GetLatestSnapshotBlock()
+	height := latestSb.Height
+	if snapshotHeight != nil {
+		var err error
+		if height, err = strconv.ParseUint(*snapshotHeight, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &UpgradeReport{
+		Height:           strconv.FormatUint(height, 10),
+		SupportedVersion: upgrade.MaxSupportedVersion,
+		ProducerVersion:  latestSb.Version,
+		Points:           upgrade.GetPointStatus(height),
+	}
+	if point := upgrade.GetCurPoint(height); point != nil {
+		report.ActiveVersion = point.Version
+	}
+	if err := upgrade.CheckProducerVersion(latestSb.Height, latestSb.Version); err != nil {
+		report.Warning = err.Error()
+	}
+	return report, nil
+}
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"context"
+	"math/big"
+
 	"github.com/vitelabs/go-vite"
 	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/ledger/onroad"
+	"github.com/vitelabs/go-vite/log15"
 )
 
 type PublicOnroadApi struct {
@@ -20,11 +25,13 @@ func (pub PublicOnroadApi) String() string {
 
 type PrivateOnroadApi struct {
 	ledgerApi *LedgerApi
+	manager   *onroad.Manager
 }
 
 func NewPrivateOnroadApi(vite *vite.Vite) *PrivateOnroadApi {
 	return &PrivateOnroadApi{
 		ledgerApi: NewLedgerApi(vite),
+		manager:   vite.OnRoad(),
 	}
 }
 
@@ -39,18 +46,90 @@ type OnroadPagingQuery struct {
 	PageCount uint64 `json:"pageCount"`
 }
 
+type AutoReceiveFilter struct {
+	// the min amount of the send blocks, all amounts are received if nil
+	MinAmount *string `json:"minAmount"`
+	// the whitelist of the tokens, all tokens are received if empty
+	TokenIds []types.TokenTypeId `json:"tokenIds"`
+	// calculate the PoW for the receive blocks if the stake quota is not enough
+	PoW bool `json:"pow"`
+}
+
+type AutoReceiveWorker struct {
+	Address types.Address      `json:"address"`
+	Filter  *AutoReceiveFilter `json:"filter"`
+	Running bool               `json:"running"`
+
+	Received        string `json:"received"`
+	LastReceiveTime *int64 `json:"lastReceiveTime"`
+	LastError       string `json:"lastError"`
+}
+
+// StartAutoReceive starts receiving the onroad blocks of the unlocked address by the filter, the config is saved and
+// the worker is resumed whenever the address is unlocked after restarts until stopAutoReceive is called
+func (pri PrivateOnroadApi) StartAutoReceive(addr types.Address, filter *AutoReceiveFilter) error {
+	cfg := onroad.AutoReceiveConfig{Address: addr}
+	if filter != nil {
+		var minAmount *big.Int
+		if filter.MinAmount != nil {
+			var err error
+			if minAmount, err = stringToBigInt(filter.MinAmount); err != nil {
+				return err
+			}
+		}
+		cfg.Filter = onroad.AutoReceiveFilter{
+			MinAmount: minAmount,
+			TokenIds:  filter.TokenIds,
+		}
+		cfg.PoW = filter.PoW
+	}
+	return pri.manager.StartAutoReceive(cfg)
+}
+
+// StopAutoReceive stops the auto receive worker of the address and deletes its config
+func (pri PrivateOnroadApi) StopAutoReceive(addr types.Address) error {
+	return pri.manager.StopAutoReceive(addr)
+}
+
+// ListWorkingAutoReceiveWorker returns all configured auto receive workers, the workers of the locked addresses
+// are not running
+func (pri PrivateOnroadApi) ListWorkingAutoReceiveWorker() []*AutoReceiveWorker {
+	statusList := pri.manager.ListAutoReceiveWorkers()
+	workers := make([]*AutoReceiveWorker, 0, len(statusList))
+	for _, status := range statusList {
+		worker := &AutoReceiveWorker{
+			Address: status.Config.Address,
+			Filter: &AutoReceiveFilter{
+				MinAmount: bigIntToString(status.Config.Filter.MinAmount),
+				TokenIds:  status.Config.Filter.TokenIds,
+				PoW:       status.Config.PoW,
+			},
+			Running:   status.Running,
+			Received:  Uint64ToString(status.Received),
+			LastError: status.LastError,
+		}
+		if status.LastReceiveTime != nil {
+			t := status.LastReceiveTime.Unix()
+			worker.LastReceiveTime = &t
+		}
+		workers = append(workers, worker)
+	}
+	return workers
+}
+
 // ------------------------------------------------------------------------------------------------------------------------
 // ---------------------deprecated-----------------------------------------------------------------------------------------
 // ------------------------------------------------------------------------------------------------------------------------
 
 // Deprecated: to use ledger_getUnreceivedBlocksByAddress instead
-func (pri PrivateOnroadApi) GetOnroadBlocksByAddress(address types.Address, index, count uint64) ([]*AccountBlock, error) {
-	return pri.ledgerApi.GetUnreceivedBlocksByAddress(address, index, count)
+func (pri PrivateOnroadApi) GetOnroadBlocksByAddress(ctx context.Context, address types.Address, index, count uint64) ([]*AccountBlock, error) {
+	return pri.ledgerApi.GetUnreceivedBlocksByAddress(ctx, address, index, count)
 }
 
 // Deprecated: to use ledger_getUnreceivedTransactionSummaryByAddress instead
-func (pri PrivateOnroadApi) GetOnroadInfoByAddress(address types.Address) (*RpcAccountInfo, error) {
-	log.Info("GetUnreceivedTransactionSummaryByAddress", "addr", address)
+func (pri PrivateOnroadApi) GetOnroadInfoByAddress(ctx context.Context, address types.Address) (*RpcAccountInfo, error) {
+	logger := log15.FromContext(ctx, log)
+	logger.Info("GetUnreceivedTransactionSummaryByAddress", "addr", address)
 
 	info, e := pri.ledgerApi.chain.GetAccountOnRoadInfo(address)
 	if e != nil || info == nil {
@@ -60,7 +139,7 @@ func (pri PrivateOnroadApi) GetOnroadInfoByAddress(address types.Address) (*RpcA
 }
 
 // Deprecated: to use unreceived_getUnreceivedBlocksInBatch instead
-func (pri PrivateOnroadApi) GetOnroadBlocksInBatch(queryList []OnroadPagingQuery) (map[types.Address][]*AccountBlock, error) {
+func (pri PrivateOnroadApi) GetOnroadBlocksInBatch(ctx context.Context, queryList []OnroadPagingQuery) (map[types.Address][]*AccountBlock, error) {
 	querys := make([]PagingQueryBatch, 0)
 	for _, v := range queryList {
 		querys = append(querys, PagingQueryBatch{
@@ -69,7 +148,7 @@ func (pri PrivateOnroadApi) GetOnroadBlocksInBatch(queryList []OnroadPagingQuery
 			PageCount:  v.PageCount,
 		})
 	}
-	return pri.ledgerApi.GetUnreceivedBlocksInBatch(querys)
+	return pri.ledgerApi.GetUnreceivedBlocksInBatch(ctx, querys)
 }
 
 // Deprecated: to use unreceived_getUnreceivedTransactionSummaryInBatch instead
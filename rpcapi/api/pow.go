@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"math/big"
@@ -8,6 +9,7 @@ import (
 	"github.com/vitelabs/go-vite"
 	"github.com/vitelabs/go-vite/common/hexutil"
 	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/pow"
 	"github.com/vitelabs/go-vite/pow/remote"
 	"github.com/vitelabs/go-vite/vm/quota"
@@ -40,50 +42,90 @@ func NewPow(vite *vite.Vite) *Pow {
 	}
 }
 
-func (p Pow) GetPowNonce(difficulty string, data types.Hash) ([]byte, error) {
-	log.Info("GetPowNonce")
+func (p Pow) GetPowNonce(ctx context.Context, difficulty string, data types.Hash) ([]byte, error) {
+	logger := log15.FromContext(ctx, log)
+	logger.Info("GetPowNonce")
 
 	if pow.VMTestParamEnabled {
-		log.Info("use defaultTarget to calc")
+		logger.Info("use defaultTarget to calc")
 		return pow.GetPowNonce(nil, data)
 	}
 
+	realDifficulty, err := parsePowDifficulty(difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, isCongestion := quota.CalcQc(p.vite.Chain(), p.vite.Chain().GetLatestSnapshotBlock().Height); isCongestion {
+		return nil, ErrPoWNotSupportedUnderCongestion
+	}
+
+	return generatePowNonce(logger, realDifficulty, data)
+}
+
+// parsePowDifficulty parses the difficulty of the rpc, the difficulty larger than the one of the max quota is
+// rejected since it only burns the cpu of the pow solver
+func parsePowDifficulty(difficulty string) (*big.Int, error) {
 	realDifficulty, ok := new(big.Int).SetString(difficulty, 10)
 	if !ok {
 		return nil, ErrStrToBigInt
 	}
+	if realDifficulty.Cmp(quota.MaxPoWDifficulty()) > 0 {
+		return nil, ErrPoWDifficultyTooLarge
+	}
+	return realDifficulty, nil
+}
 
-	if _, _, isCongestion := quota.CalcQc(p.vite.Chain(), p.vite.Chain().GetLatestSnapshotBlock().Height); isCongestion {
-		return nil, ErrPoWNotSupportedUnderCongestion
+// generatePowNonce requests the nonce from the remote pow server if it is configured, or else solves it by the
+// local parallel solver if it's enabled in the node config
+func generatePowNonce(logger log15.Logger, difficulty *big.Int, data types.Hash) ([]byte, error) {
+	if !remote.Working() {
+		if !pow.LocalSolverEnabled() {
+			return nil, ErrPoWServerNotConfigured
+		}
+		return pow.GetPowNonce(difficulty, data)
 	}
 
-	work, e := remote.GenerateWork(data.Bytes(), realDifficulty)
+	work, e := remote.GenerateWork(data.Bytes(), difficulty)
 	if e != nil {
+		logger.Error("remote pow failed", "data", data, "difficulty", difficulty, "err", e)
 		return nil, e
 	}
 
-	nonceStr := *work
-	nonceBig, ok := new(big.Int).SetString(nonceStr, 16)
+	nonceBig, ok := new(big.Int).SetString(*work, 16)
 	if !ok {
 		return nil, errors.New("wrong nonce str")
 	}
-	nonceUint64 := nonceBig.Uint64()
 	nn := make([]byte, 8)
-	binary.LittleEndian.PutUint64(nn[:], nonceUint64)
-
-	bd, ok := new(big.Int).SetString(difficulty, 10)
-	if !ok {
-		return nil, errors.New("wrong nonce difficulty")
-	}
+	binary.LittleEndian.PutUint64(nn[:], nonceBig.Uint64())
 
-	if !pow.CheckPowNonce(bd, nn, data.Bytes()) {
+	if !pow.CheckPowNonce(difficulty, nn, data.Bytes()) {
+		logger.Error("remote pow nonce is invalid", "data", data, "difficulty", difficulty)
 		return nil, errors.New("check nonce failed")
 	}
 
 	return nn, nil
 }
 
-func (p Pow) CancelPow(data types.Hash) error {
+// PrivatePowApi is private since any caller could cancel the pow works of the others
+type PrivatePowApi struct {
+}
+
+func NewPrivatePowApi(vite *vite.Vite) *PrivatePowApi {
+	return &PrivatePowApi{}
+}
+
+func (p PrivatePowApi) String() string {
+	return "PrivatePowApi"
+}
+
+// private: CancelPow cancels the work of the data in progress on the remote pow server, or on the local solver if
+// no remote server is configured
+func (p PrivatePowApi) CancelPow(data types.Hash) error {
+	if !remote.Working() {
+		pow.CancelPowNonce(data)
+		return nil
+	}
 	if err := remote.CancelWork(data.Bytes()); err != nil {
 		return errors.New("pow cancel failed")
 	}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/pow"
+	"github.com/vitelabs/go-vite/vm/quota"
+)
+
+func TestGeneratePowNonce(t *testing.T) {
+	quota.InitQuotaConfig(false, true)
+
+	_, err := parsePowDifficulty("abc")
+	assert.Equal(t, ErrStrToBigInt, err)
+	maxDifficulty := quota.MaxPoWDifficulty()
+	difficulty, err := parsePowDifficulty(maxDifficulty.String())
+	assert.NoError(t, err)
+	assert.Equal(t, maxDifficulty, difficulty)
+	_, err = parsePowDifficulty(new(big.Int).Add(maxDifficulty, big.NewInt(1)).String())
+	assert.Equal(t, ErrPoWDifficultyTooLarge, err)
+
+	// the local solver is disabled by default
+	data := types.DataHash([]byte{1})
+	_, err = generatePowNonce(log, big.NewInt(1000), data)
+	assert.Equal(t, ErrPoWServerNotConfigured, err)
+
+	pow.EnableLocalSolver(true)
+	defer pow.EnableLocalSolver(false)
+	nonce, err := generatePowNonce(log, big.NewInt(1000), data)
+	assert.NoError(t, err)
+	assert.True(t, pow.CheckPowNonce(big.NewInt(1000), nonce, data.Bytes()))
+}
@@ -1,19 +1,29 @@
 package api
 
 import (
+	"errors"
 	"math"
+	"math/big"
 	"sort"
 
 	"github.com/vitelabs/go-vite"
+	"github.com/vitelabs/go-vite/common/hexutil"
 	"github.com/vitelabs/go-vite/common/types"
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	"github.com/vitelabs/go-vite/ledger/chain"
+	chain_plugins "github.com/vitelabs/go-vite/ledger/chain/plugins"
 	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/vm/contracts/abi"
 	"github.com/vitelabs/go-vite/vm/quota"
 	"github.com/vitelabs/go-vite/vm/util"
 )
 
+const (
+	maxQuotaUsageHeightRange        = uint64(10000)
+	defaultStakeForecastSampleCount = 100
+	maxStakeForecastSampleCount     = 1000
+)
+
 type QuotaApi struct {
 	chain     chain.Chain
 	log       log15.Logger
@@ -149,6 +159,176 @@ func (p *QuotaApi) GetQuotaCoefficient() (*QuotaCoefficientInfo, error) {
 	return &QuotaCoefficientInfo{bigIntToString(qc), Uint64ToString(globalQuota), Float64ToString(float64(globalQuota)/21000/74, 2), isCongestion}, nil
 }
 
+type QuotaUsageRecord struct {
+	SnapshotHeight string `json:"snapshotHeight"`
+	BlockCount     string `json:"blockCount"`
+	QuotaUsed      string `json:"quotaUsed"`
+	StakeQuotaUsed string `json:"stakeQuotaUsed"`
+	PoWBlockCount  string `json:"powBlockCount"`
+	PoWQuotaUsed   string `json:"powQuotaUsed"`
+	PoWDifficulty  string `json:"powDifficulty"`
+}
+
+// Private
+func (p *QuotaApi) GetQuotaUsageHistory(addr types.Address, startHeight string, endHeight string) ([]*QuotaUsageRecord, error) {
+	plugin, err := p.getQuotaUsagePlugin()
+	if err != nil {
+		return nil, err
+	}
+	start, err := StringToUint64(startHeight)
+	if err != nil {
+		return nil, err
+	}
+	end, err := StringToUint64(endHeight)
+	if err != nil {
+		return nil, err
+	}
+	if end < start || end-start >= maxQuotaUsageHeightRange {
+		return nil, errors.New("invalid snapshot height range")
+	}
+	list, err := plugin.GetQuotaUsageList(addr, start, end)
+	if err != nil {
+		return nil, err
+	}
+	resultList := make([]*QuotaUsageRecord, len(list))
+	for i, record := range list {
+		resultList[i] = &QuotaUsageRecord{
+			SnapshotHeight: Uint64ToString(record.SnapshotHeight),
+			BlockCount:     Uint64ToString(record.BlockCount),
+			QuotaUsed:      Uint64ToString(record.QuotaUsed),
+			StakeQuotaUsed: Uint64ToString(record.StakeQuotaUsed),
+			PoWBlockCount:  Uint64ToString(record.PoWBlockCount),
+			PoWQuotaUsed:   Uint64ToString(record.PoWQuotaUsed),
+			PoWDifficulty:  Uint64ToString(record.PoWDifficulty),
+		}
+	}
+	return resultList, nil
+}
+
+type StakeForecastParam struct {
+	ContractAddr   types.Address `json:"contractAddress"`
+	MethodSelector hexutil.Bytes `json:"methodSelector"`
+	TxPerSecond    string        `json:"txPerSecond"`
+	SampleCount    int           `json:"sampleCount"`
+	// BeforeHeight continues a forecast whose samples were cut by the scan limit from its NextHeight
+	BeforeHeight *string `json:"beforeHeight"`
+}
+
+type StakeForecastResult struct {
+	SampleCount       int     `json:"sampleCount"`
+	AvgQuota          string  `json:"avgQuota"`
+	MaxQuota          string  `json:"maxQuota"`
+	RequiredQuota     string  `json:"requiredQuota"`
+	StakeAmount       *string `json:"stakeAmount"`
+	StaticStakeAmount *string `json:"staticStakeAmount"`
+	Qc                *string `json:"qc"`
+	IsCongestion      bool    `json:"isCongestion"`
+	// PoWSampleCount is the samples whose send blocks are pow, AvgPoWDifficulty is the average difficulty of them
+	PoWSampleCount   int     `json:"powSampleCount"`
+	AvgPoWDifficulty *string `json:"avgPowDifficulty"`
+	// NextHeight is set if the samples are cut by the scan limit, it's the beforeHeight to continue from
+	NextHeight *string `json:"nextHeight"`
+}
+
+// Private
+func (p *QuotaApi) GetStakeAmountForecast(param StakeForecastParam) (*StakeForecastResult, error) {
+	plugin, err := p.getQuotaUsagePlugin()
+	if err != nil {
+		return nil, err
+	}
+	txPerSecond, err := StringToFloat64(param.TxPerSecond)
+	if err != nil {
+		return nil, err
+	}
+	if txPerSecond <= 0 {
+		return nil, errors.New("txPerSecond must be positive")
+	}
+	sampleCount := param.SampleCount
+	if sampleCount <= 0 {
+		sampleCount = defaultStakeForecastSampleCount
+	} else if sampleCount > maxStakeForecastSampleCount {
+		sampleCount = maxStakeForecastSampleCount
+	}
+
+	beforeHeight := uint64(0)
+	if param.BeforeHeight != nil {
+		if beforeHeight, err = StringToUint64(*param.BeforeHeight); err != nil {
+			return nil, err
+		}
+	}
+	samples, next, err := plugin.GetReceiveQuotaSamples(param.ContractAddr, param.MethodSelector, beforeHeight, sampleCount)
+	if err != nil {
+		return nil, err
+	}
+	var nextHeight *string
+	if next > 0 {
+		nextHeightStr := Uint64ToString(next)
+		nextHeight = &nextHeightStr
+	}
+	if len(samples) == 0 {
+		if nextHeight != nil {
+			// nothing matched in the scanned samples, the client continues from nextHeight
+			return &StakeForecastResult{NextHeight: nextHeight}, nil
+		}
+		return nil, errors.New("no receive block found for the contract call")
+	}
+
+	total, max := uint64(0), uint64(0)
+	powCount, powDifficulty := 0, new(big.Int)
+	for _, sample := range samples {
+		total += sample.QuotaUsed
+		if sample.QuotaUsed > max {
+			max = sample.QuotaUsed
+		}
+		if sample.Difficulty > 0 {
+			powCount++
+			powDifficulty.Add(powDifficulty, new(big.Int).SetUint64(sample.Difficulty))
+		}
+	}
+	avg := float64(total) / float64(len(samples))
+	var avgPoWDifficulty *string
+	if powCount > 0 {
+		avgPoWDifficulty = bigIntToString(powDifficulty.Div(powDifficulty, big.NewInt(int64(powCount))))
+	}
+
+	requiredQuota := uint64(math.Ceil(txPerSecond * avg))
+	stakeAmount, err := quota.CalcStakeAmountByQuota(requiredQuota)
+	if err != nil {
+		return nil, err
+	}
+	staticStakeAmount, err := quota.CalcStakeAmountByQuota(uint64(math.Ceil(txPerSecond * float64(quota.QuotaPerUt))))
+	if err != nil {
+		return nil, err
+	}
+
+	qc, _, isCongestion := quota.CalcQc(p.chain, p.chain.GetLatestSnapshotBlock().Height)
+	return &StakeForecastResult{
+		SampleCount:       len(samples),
+		AvgQuota:          Float64ToString(avg, 2),
+		MaxQuota:          Uint64ToString(max),
+		RequiredQuota:     Uint64ToString(requiredQuota),
+		StakeAmount:       bigIntToString(stakeAmount),
+		StaticStakeAmount: bigIntToString(staticStakeAmount),
+		Qc:                bigIntToString(qc),
+		IsCongestion:      isCongestion,
+		PoWSampleCount:    powCount,
+		AvgPoWDifficulty:  avgPoWDifficulty,
+		NextHeight:        nextHeight,
+	}, nil
+}
+
+func (p *QuotaApi) getQuotaUsagePlugin() (*chain_plugins.QuotaUsage, error) {
+	plugins := p.chain.Plugins()
+	if plugins == nil {
+		return nil, errors.New("config.OpenPlugins is false, api can't work")
+	}
+	plugin, ok := plugins.GetPlugin("quotaUsage").(*chain_plugins.QuotaUsage)
+	if !ok || plugin == nil {
+		return nil, errors.New("plugin quotaUsage is not available")
+	}
+	return plugin, nil
+}
+
 // ------------------------------------------------------------
 // ---------------------deprecated-----------------------------
 // ------------------------------------------------------------
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -17,6 +18,7 @@ import (
 	ledger "github.com/vitelabs/go-vite/interfaces/core"
 	"github.com/vitelabs/go-vite/ledger/consensus"
 	"github.com/vitelabs/go-vite/ledger/generator"
+	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/net"
 	"github.com/vitelabs/go-vite/vm/contracts/dex"
 	"github.com/vitelabs/go-vite/wallet"
@@ -36,8 +38,9 @@ func NewTxApi(vite *vite.Vite) *Tx {
 	return tx
 }
 
-func (t Tx) SendRawTx(block *AccountBlock) error {
-	log.Info("SendRawTx")
+func (t Tx) SendRawTx(ctx context.Context, block *AccountBlock) error {
+	txLog := log15.FromContext(ctx, log)
+	txLog.Info("SendRawTx")
 	if block == nil {
 		return errors.New("empty block")
 	}
@@ -64,18 +67,23 @@ func (t Tx) SendRawTx(block *AccountBlock) error {
 
 	result, err := t.vite.Verifier().VerifyRPCAccountBlock(lb, latestSb)
 	if err != nil {
+		txLog.Info("verify raw tx failed", "hash", lb.Hash, "err", err)
 		return err
 	}
 
 	if result != nil {
-		return t.vite.Pool().AddDirectAccountBlock(result.AccountBlock.AccountAddress, result)
+		if err := t.vite.Pool().AddDirectAccountBlock(result.AccountBlock.AccountAddress, result); err != nil {
+			txLog.Info("add raw tx failed", "hash", lb.Hash, "err", err)
+			return err
+		}
+		txLog.Info("raw tx added", "addr", lb.AccountAddress, "height", lb.Height, "hash", lb.Hash)
+		return nil
 	} else {
 		return errors.New("generator gen an empty block")
 	}
 }
 
-func (t Tx) SendTxWithPrivateKey(param SendTxWithPrivateKeyParam) (*AccountBlock, error) {
-
+func (t Tx) SendTxWithPrivateKey(ctx context.Context, param SendTxWithPrivateKeyParam) (*AccountBlock, error) {
 	if param.Amount == nil {
 		return nil, errors.New("amount is nil")
 	}
@@ -141,7 +149,7 @@ func (t Tx) SendTxWithPrivateKey(param SendTxWithPrivateKeyParam) (*AccountBlock
 	if err != nil || addrState == nil {
 		return nil, fmt.Errorf("failed to get addr state for generator, err:%v", err)
 	}
-	g, e := generator.NewGenerator(t.vite.Chain(), t.vite.Consensus(), msg.AccountAddress, addrState.LatestSnapshotHash, addrState.LatestAccountHash)
+	g, e := generator.NewGeneratorWithContext(ctx, t.vite.Chain(), t.vite.Consensus(), msg.AccountAddress, addrState.LatestSnapshotHash, addrState.LatestAccountHash)
 	if e != nil {
 		return nil, e
 	}
@@ -416,7 +424,7 @@ func (tx Tx) autoSend() {
 				addr := v
 				key := fromHexPrivKeys[k]
 
-				block, err := tx.SendTxWithPrivateKey(SendTxWithPrivateKeyParam{
+				block, err := tx.SendTxWithPrivateKey(context.Background(), SendTxWithPrivateKeyParam{
 					SelfAddr:     &addr,
 					ToAddr:       &toAddr,
 					TokenTypeId:  ledger.ViteTokenId,
@@ -443,7 +451,7 @@ func (tx Tx) autoSend() {
 				key := fromHexPrivKeys[k]
 
 				mToAddr := types.HexToAddressPanic(InitContractAddr[rand.Intn(len(InitContractAddr))])
-				block, err := tx.SendTxWithPrivateKey(SendTxWithPrivateKeyParam{
+				block, err := tx.SendTxWithPrivateKey(context.Background(), SendTxWithPrivateKeyParam{
 					SelfAddr:     &addr,
 					ToAddr:       &mToAddr,
 					TokenTypeId:  ledger.ViteTokenId,
@@ -1,7 +1,7 @@
 package api
 
 import (
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -11,8 +11,8 @@ import (
 	"github.com/vitelabs/go-vite/common/hexutil"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/crypto"
+	"github.com/vitelabs/go-vite/log15"
 	"github.com/vitelabs/go-vite/pow"
-	"github.com/vitelabs/go-vite/pow/remote"
 	"github.com/vitelabs/go-vite/vm/quota"
 )
 
@@ -26,56 +26,35 @@ func NewUtilApi(vite *vite.Vite) *UtilApi {
 	}
 }
 
-func (p UtilApi) GetPoWNonce(difficulty string, data types.Hash) ([]byte, error) {
-	log.Info("GetPowNonce")
+func (p UtilApi) GetPoWNonce(ctx context.Context, difficulty string, data types.Hash) ([]byte, error) {
+	logger := log15.FromContext(ctx, log)
+	logger.Info("GetPowNonce")
 
 	if pow.VMTestParamEnabled {
-		log.Info("use defaultTarget to calc")
+		logger.Info("use defaultTarget to calc")
 		return pow.GetPowNonce(nil, data)
 	}
 
-	realDifficulty, ok := new(big.Int).SetString(difficulty, 10)
-	if !ok {
-		return nil, ErrStrToBigInt
+	realDifficulty, err := parsePowDifficulty(difficulty)
+	if err != nil {
+		return nil, err
 	}
 
 	if _, _, isCongestion := quota.CalcQc(p.vite.Chain(), p.vite.Chain().GetLatestSnapshotBlock().Height); isCongestion {
 		return nil, ErrPoWNotSupportedUnderCongestion
 	}
 
-	work, e := remote.GenerateWork(data.Bytes(), realDifficulty)
-	if e != nil {
-		return nil, e
-	}
-
-	nonceStr := *work
-	nonceBig, ok := new(big.Int).SetString(nonceStr, 16)
-	if !ok {
-		return nil, errors.New("wrong nonce str")
-	}
-	nonceUint64 := nonceBig.Uint64()
-	nn := make([]byte, 8)
-	binary.LittleEndian.PutUint64(nn[:], nonceUint64)
-
-	bd, ok := new(big.Int).SetString(difficulty, 10)
-	if !ok {
-		return nil, errors.New("wrong nonce difficulty")
-	}
-
-	if !pow.CheckPowNonce(bd, nn, data.Bytes()) {
-		return nil, errors.New("check nonce failed")
-	}
-
-	return nn, nil
+	return generatePowNonce(logger, realDifficulty, data)
 }
 
 // Pow Plan Ref[] todo
-func (p Pow) GetPowNoncePrivate(address types.Address, height uint64, difficulty string, data types.Hash, timestamp uint64, sig []byte, cnt uint64) (result []byte, e error) {
-	log.Info("GetPowNoncePrivate ", "address", address, "height", height,
+func (p Pow) GetPowNoncePrivate(ctx context.Context, address types.Address, height uint64, difficulty string, data types.Hash, timestamp uint64, sig []byte, cnt uint64) (result []byte, e error) {
+	logger := log15.FromContext(ctx, log)
+	logger.Info("GetPowNoncePrivate ", "address", address, "height", height,
 		"difficulty", difficulty, "data", data.Hex(), "timestamp", timestamp, "sig", hexutil.Encode(sig))
 	s := time.Now()
 	defer func() {
-		log.Info("GetPowNoncePrivate ", "address", address, "height", height,
+		logger.Info("GetPowNoncePrivate ", "address", address, "height", height,
 			"difficulty", difficulty, "data", data.Hex(), "timestamp", timestamp, "sig", hexutil.Encode(sig),
 			"err", e, "duration_ms", time.Now().Sub(s).Nanoseconds())
 	}()
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -124,7 +125,7 @@ func (m WalletApi) DeriveByFullPath(entropyStore string, fullpath string) (*Deri
 		return nil, err
 	}
 
-	privateKey, err := key.PrivateKey()
+	privateKey, err := m.exportPrivateKey(entropyStore, key)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +152,7 @@ func (m WalletApi) DeriveByIndex(entropyStore string, index uint32) (*DeriveResu
 		return nil, err
 	}
 
-	privateKey, err := key.PrivateKey()
+	privateKey, err := m.exportPrivateKey(entropyStore, key)
 	if err != nil {
 		return nil, err
 	}
@@ -257,7 +258,7 @@ func (m WalletApi) SignData(addr types.Address, hexMsg string) (*HexSignedTuple,
 		return nil, e
 	}
 
-	signedData, pubkey, err := account.Sign(hash.Bytes())
+	signedData, pubkey, err := account.SignData(hash.Bytes())
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +272,7 @@ func (m WalletApi) SignData(addr types.Address, hexMsg string) (*HexSignedTuple,
 	return &t, nil
 }
 
-func (m WalletApi) CreateTxWithPassphrase(params CreateTransferTxParms) (*types.Hash, error) {
+func (m WalletApi) CreateTxWithPassphrase(ctx context.Context, params CreateTransferTxParms) (*types.Hash, error) {
 	if !checkTxToAddressAvailable(params.ToAddr) {
 		return nil, errors.New("ToAddress is invalid")
 	}
@@ -308,7 +309,7 @@ func (m WalletApi) CreateTxWithPassphrase(params CreateTransferTxParms) (*types.
 	if err != nil || addrState == nil {
 		return nil, fmt.Errorf("failed to get addr state for generator, err:%v", err)
 	}
-	g, e := generator.NewGenerator(m.chain, m.consensus, msg.AccountAddress, addrState.LatestSnapshotHash, addrState.LatestAccountHash)
+	g, e := generator.NewGeneratorWithContext(ctx, m.chain, m.consensus, msg.AccountAddress, addrState.LatestSnapshotHash, addrState.LatestAccountHash)
 	if e != nil {
 		return nil, e
 	}
@@ -317,20 +318,32 @@ func (m WalletApi) CreateTxWithPassphrase(params CreateTransferTxParms) (*types.
 	if err != nil {
 		return nil, err
 	}
-	result, e := g.GenerateWithMessage(msg, &msg.AccountAddress, account.Sign)
-
+	// the amount is reserved in the daily limit of the signing policy until the block is generated
+	session, err := account.NewTransaction(&wallet.SigningRequest{
+		ToAddress: params.ToAddr,
+		TokenId:   params.TokenTypeId,
+		Amount:    amount,
+		Data:      params.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, e := g.GenerateWithMessage(msg, &msg.AccountAddress, session.Sign)
+	if e == nil && result.Err != nil {
+		e = result.Err
+	}
+	if e == nil && result.VMBlock == nil {
+		e = errors.New("generator gen an empty block")
+	}
 	if e != nil {
+		session.Rollback(e)
 		return nil, e
 	}
-	if result.Err != nil {
-		return nil, result.Err
-	}
-	if result.VMBlock != nil {
-		return &result.VMBlock.AccountBlock.Hash, m.pool.AddDirectAccountBlock(params.SelfAddr, result.VMBlock)
-	} else {
-		return nil, errors.New("generator gen an empty block")
+	// the signed block is audited before it's sent
+	if err := session.Commit(); err != nil {
+		return nil, err
 	}
-
+	return &result.VMBlock.AccountBlock.Hash, m.pool.AddDirectAccountBlock(params.SelfAddr, result.VMBlock)
 }
 
 func (m WalletApi) SignDataWithPassphrase(addr types.Address, hexMsg string, passphrase string) (*HexSignedTuple, error) {
@@ -342,7 +355,7 @@ func (m WalletApi) SignDataWithPassphrase(addr types.Address, hexMsg string, pas
 	if err != nil {
 		return nil, err
 	}
-	signedData, pubkey, err := account.Sign(hash.Bytes())
+	signedData, pubkey, err := account.SignData(hash.Bytes())
 	if err != nil {
 		return nil, err
 	}
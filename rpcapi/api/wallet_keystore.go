@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/vitelabs/go-vite/wallet/entropystore"
+)
+
+// ChangePassphrase encrypts the entropy file with the new passphrase in place, the kdf parameters of the file are
+// kept if kdf is nil
+func (m WalletApi) ChangePassphrase(entropyFile string, oldPassphrase string, newPassphrase string, kdf *entropystore.KDFParams) error {
+	return m.wallet.ChangePassphrase(entropyFile, oldPassphrase, newPassphrase, kdf)
+}
+
+// UpgradeEntropyFile encrypts the entropy file in the version 2 with the kdf parameters, or with the ones of the
+// node config if kdf is nil
+func (m WalletApi) UpgradeEntropyFile(entropyFile string, passphrase string, kdf *entropystore.KDFParams) error {
+	return m.wallet.UpgradeEntropyStore(entropyFile, passphrase, kdf)
+}
+
+func (m WalletApi) GetEntropyFileInfo(entropyFile string) (*entropystore.StoreInfo, error) {
+	return m.wallet.GetEntropyStoreInfo(entropyFile)
+}
+
+// ExportEntropyFiles returns the entropy files in the standard dir as a bundle encrypted by the passphrase
+func (m WalletApi) ExportEntropyFiles(passphrase string) ([]byte, error) {
+	return m.wallet.ExportEntropyStores(passphrase)
+}
+
+// ImportEntropyFiles writes the entropy files of the bundle into the standard dir, the existing files are skipped
+// unless overwrite is set. It returns the imported files.
+func (m WalletApi) ImportEntropyFiles(bundle []byte, passphrase string, overwrite bool) ([]string, error) {
+	return m.wallet.ImportEntropyStores(bundle, passphrase, overwrite)
+}
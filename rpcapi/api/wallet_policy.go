@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/wallet"
+	"github.com/vitelabs/go-vite/wallet/hd-bip/derivation"
+)
+
+type SpendLimit struct {
+	TokenId types.TokenTypeId `json:"tokenId"`
+	Amount  string            `json:"amount"`
+}
+
+type AddressPolicy struct {
+	DailyLimits       []*SpendLimit   `json:"dailyLimits,omitempty"`
+	AllowedRecipients []types.Address `json:"allowedRecipients,omitempty"`
+	AllowedMethods    []string        `json:"allowedMethods,omitempty"`
+}
+
+type AccountPolicy struct {
+	Address types.Address `json:"address"`
+	AddressPolicy
+}
+
+type SigningPolicy struct {
+	Default       *AddressPolicy   `json:"default,omitempty"`
+	Accounts      []*AccountPolicy `json:"accounts,omitempty"`
+	AllowSignData bool             `json:"allowSignData"`
+	UnlockTimeout uint64           `json:"unlockTimeout"`
+}
+
+func (p *AddressPolicy) toWallet() (*wallet.AddressPolicy, error) {
+	result := &wallet.AddressPolicy{
+		AllowedRecipients: p.AllowedRecipients,
+		AllowedMethods:    p.AllowedMethods,
+	}
+	for _, limit := range p.DailyLimits {
+		if limit == nil {
+			return nil, errors.New("nil daily limit")
+		}
+		amount, err := stringToBigInt(&limit.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid daily limit of %s", limit.TokenId)
+		}
+		result.DailyLimits = append(result.DailyLimits, &wallet.SpendLimit{TokenId: limit.TokenId, Amount: amount})
+	}
+	return result, nil
+}
+
+func fromWalletAddressPolicy(p *wallet.AddressPolicy) *AddressPolicy {
+	result := &AddressPolicy{
+		AllowedRecipients: p.AllowedRecipients,
+		AllowedMethods:    p.AllowedMethods,
+	}
+	for _, limit := range p.DailyLimits {
+		result.DailyLimits = append(result.DailyLimits, &SpendLimit{TokenId: limit.TokenId, Amount: *bigIntToString(limit.Amount)})
+	}
+	return result
+}
+
+// SetSigningPolicy attaches the signing policy to the entropy store, the policy is removed if it's nil. Every
+// transaction and data signed by the accounts of the store is checked against the policy and recorded in the audit
+// trail, the private keys can't be derived without the passphrase once a policy is set.
+func (m WalletApi) SetSigningPolicy(entropyStore string, passphrase string, policy *SigningPolicy) error {
+	if policy == nil {
+		return m.wallet.SetSigningPolicy(entropyStore, passphrase, nil)
+	}
+	walletPolicy := &wallet.SigningPolicy{
+		AllowSignData: policy.AllowSignData,
+		UnlockTimeout: policy.UnlockTimeout,
+	}
+	if policy.Default != nil {
+		addrPolicy, err := policy.Default.toWallet()
+		if err != nil {
+			return err
+		}
+		walletPolicy.Default = addrPolicy
+	}
+	for _, accountPolicy := range policy.Accounts {
+		if accountPolicy == nil {
+			return errors.New("nil account policy")
+		}
+		addrPolicy, err := accountPolicy.toWallet()
+		if err != nil {
+			return err
+		}
+		walletPolicy.Accounts = append(walletPolicy.Accounts, &wallet.AccountPolicy{Address: accountPolicy.Address, AddressPolicy: *addrPolicy})
+	}
+	return m.wallet.SetSigningPolicy(entropyStore, passphrase, walletPolicy)
+}
+
+// GetSigningPolicy returns the signing policy of the entropy store, nil if it has no policy
+func (m WalletApi) GetSigningPolicy(entropyStore string) (*SigningPolicy, error) {
+	walletPolicy, err := m.wallet.GetSigningPolicy(entropyStore)
+	if err != nil || walletPolicy == nil {
+		return nil, err
+	}
+	policy := &SigningPolicy{
+		AllowSignData: walletPolicy.AllowSignData,
+		UnlockTimeout: walletPolicy.UnlockTimeout,
+	}
+	if walletPolicy.Default != nil {
+		policy.Default = fromWalletAddressPolicy(walletPolicy.Default)
+	}
+	for _, accountPolicy := range walletPolicy.Accounts {
+		policy.Accounts = append(policy.Accounts, &AccountPolicy{
+			Address:       accountPolicy.Address,
+			AddressPolicy: *fromWalletAddressPolicy(&accountPolicy.AddressPolicy),
+		})
+	}
+	return policy, nil
+}
+
+// exportPrivateKey returns the private key of the derived key, or nil if the entropy store has a signing policy
+// which would be bypassed by the exported key
+func (m WalletApi) exportPrivateKey(entropyStore string, key *derivation.Key) ([]byte, error) {
+	policy, err := m.wallet.GetSigningPolicy(entropyStore)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		return nil, nil
+	}
+	return key.PrivateKey()
+}
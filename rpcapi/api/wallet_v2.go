@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 
 	"github.com/vitelabs/go-vite/common/types"
@@ -85,7 +86,7 @@ func (m WalletApi) DeriveAddressByIndex(entropyFile string, index uint32) (*Deri
 		return nil, err
 	}
 
-	privateKey, err := key.PrivateKey()
+	privateKey, err := m.exportPrivateKey(entropyFile, key)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +113,7 @@ func (m WalletApi) DeriveAddressByPath(entropyFile string, bip44Path string) (*D
 		return nil, err
 	}
 
-	privateKey, err := key.PrivateKey()
+	privateKey, err := m.exportPrivateKey(entropyFile, key)
 	if err != nil {
 		return nil, err
 	}
@@ -179,8 +180,8 @@ type CreateTransactionParms struct {
 	Difficulty  *string           `json:"difficulty,omitempty"`
 }
 
-func (m WalletApi) CreateTransaction(params CreateTransactionParms) (*types.Hash, error) {
-	return m.CreateTxWithPassphrase(CreateTransferTxParms{
+func (m WalletApi) CreateTransaction(ctx context.Context, params CreateTransactionParms) (*types.Hash, error) {
+	return m.CreateTxWithPassphrase(ctx, CreateTransferTxParms{
 		EntropystoreFile: params.EntropyFile,
 		SelfAddr:         params.Address,
 		ToAddr:           params.ToAddress,
@@ -5,6 +5,7 @@ import (
 	"github.com/vitelabs/go-vite/rpc"
 	"github.com/vitelabs/go-vite/rpcapi/api"
 	"github.com/vitelabs/go-vite/rpcapi/api/filters"
+	"github.com/vitelabs/go-vite/rpcapi/api/filters/sink"
 )
 
 func Init(dir, lvl string, testApi_prikey, testApi_tti string, netId uint, dexAvailable *bool) {
@@ -46,11 +47,18 @@ func GetApi(vite *vite.Vite, apiModule string) rpc.API {
 			Service:   api.NewPow(vite),
 			Public:    true,
 		}
+	case "private_pow":
+		return rpc.API{
+			Namespace: "pow",
+			Version:   "1.0",
+			Service:   api.NewPrivatePowApi(vite),
+			Public:    false,
+		}
 	case "debug":
 		return rpc.API{
 			Namespace: "debug",
 			Version:   "1.0",
-			Service:   api.NewDeprecated(),
+			Service:   api.NewDebugApi(vite),
 			Public:    true,
 		}
 	case "consensusGroup":
@@ -165,6 +173,13 @@ func GetApi(vite *vite.Vite, apiModule string) rpc.API {
 			Service:   filters.NewSubscribeApi(vite),
 			Public:    true,
 		}
+	case "sink":
+		return rpc.API{
+			Namespace: "sink",
+			Version:   "1.0",
+			Service:   sink.NewSinkApi(),
+			Public:    false,
+		}
 	case "sbpstats":
 		return rpc.API{
 			Namespace: "sbpstats",
@@ -193,6 +208,27 @@ func GetApi(vite *vite.Vite, apiModule string) rpc.API {
 			Service:   api.NewLedgerDebugApi(vite),
 			Public:    false,
 		}
+	case "admin":
+		return rpc.API{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   api.NewAdminApi(vite),
+			Public:    false,
+		}
+	case "abi":
+		return rpc.API{
+			Namespace: "abi",
+			Version:   "1.0",
+			Service:   api.NewPublicAbiApi(vite),
+			Public:    true,
+		}
+	case "private_abi":
+		return rpc.API{
+			Namespace: "abi",
+			Version:   "1.0",
+			Service:   api.NewPrivateAbiApi(vite),
+			Public:    false,
+		}
 	case "miner":
 		return rpc.API{
 			Namespace: "miner",
@@ -0,0 +1,135 @@
+package toposort
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Run calls fn for the elements of data in the order of their dependency, an element is called after all its
+// inputs in data returned, the inputs which are not in data are ignored. At most parallel calls run at the same
+// time, independent elements run in parallel. After a call returns an error, no element starts and the first error
+// is returned when the running calls return. The swap of data is never called, and nothing is called if data has
+// a cycle.
+func Run(data Interface, parallel int, fn func(i int) error) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+	nodes := newNodes(data)
+	if err := checkCycle(nodes); err != nil {
+		return err
+	}
+
+	inputCnt := make([]int, len(nodes))
+	var ready []*node
+	for i, n := range nodes {
+		inputCnt[i] = n.inputCnt
+		if n.inputCnt == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	type result struct {
+		n   *node
+		err error
+	}
+	done := make(chan result, len(nodes))
+	running := 0
+	var firstErr error
+	for {
+		for firstErr == nil && running < parallel && len(ready) > 0 {
+			n := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				done <- result{n: n, err: fn(n.index)}
+			}()
+		}
+		if running == 0 {
+			return firstErr
+		}
+		r := <-done
+		running--
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		for _, output := range r.n.outputIndexes {
+			inputCnt[output]--
+			if inputCnt[output] == 0 {
+				ready = append(ready, nodes[output])
+			}
+		}
+	}
+}
+
+// newNodes returns the nodes of data by index, the outputs of a node are the indexes of the nodes it is an
+// input of.
+func newNodes(data Interface) []*node {
+	len := data.Len()
+	nodes := make([]*node, len)
+	alias := make(map[string]int)
+	for i := 0; i < len; i++ {
+		ids := data.Ids(i)
+		nodes[i] = &node{
+			id:     ids[0],
+			alias:  ids,
+			inputs: data.Inputs(i),
+			index:  i,
+		}
+		for _, id := range ids {
+			alias[id] = i
+		}
+	}
+	for _, n := range nodes {
+		for _, input := range n.inputs {
+			real, ok := alias[input]
+			if ok {
+				n.inputCnt = n.inputCnt + 1
+				nodes[real].outputIndexes = append(nodes[real].outputIndexes, n.index)
+			}
+		}
+	}
+	return nodes
+}
+
+func checkCycle(nodes []*node) error {
+	inputCnt := make([]int, len(nodes))
+	var ready []int
+	for i, n := range nodes {
+		inputCnt[i] = n.inputCnt
+		if n.inputCnt == 0 {
+			ready = append(ready, i)
+		}
+	}
+	visited := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		visited++
+		for _, output := range nodes[i].outputIndexes {
+			inputCnt[output]--
+			if inputCnt[output] == 0 {
+				ready = append(ready, output)
+			}
+		}
+	}
+	if visited == len(nodes) {
+		return nil
+	}
+
+	var ids []string
+	for i, n := range nodes {
+		if inputCnt[i] > 0 {
+			ids = append(ids, n.id)
+		}
+	}
+	sort.Strings(ids)
+	var result string
+	for _, id := range ids {
+		result += id + " "
+	}
+	return errors.Errorf("cycle error %s", result)
+}
@@ -0,0 +1,112 @@
+package toposort
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRun(t *testing.T) {
+	nodes := []items{
+		{
+			ids:    []string{"3", "03"},
+			inputs: []string{"1", "02"},
+		},
+		{
+			ids:    []string{"1", "01"},
+			inputs: []string{"0"},
+		},
+		{
+			ids:    []string{"0", "00"},
+			inputs: []string{"x"},
+		},
+		{
+			ids:    []string{"2", "02"},
+			inputs: []string{"00"},
+		},
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var running, maxRunning int32
+	err := Run(byTop(nodes), 2, func(i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mu.Lock()
+		if n > maxRunning {
+			maxRunning = n
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		order = append(order, nodes[i].ids[0])
+		mu.Unlock()
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(order), 4)
+	assert.Equal(t, order[0], "0")
+	assert.Equal(t, order[3], "3")
+	// 1 and 2 only depend on 0
+	assert.Equal(t, maxRunning, int32(2))
+}
+
+func TestRunError(t *testing.T) {
+	nodes := []items{
+		{
+			ids: []string{"0"},
+		},
+		{
+			ids:    []string{"1"},
+			inputs: []string{"0"},
+		},
+		{
+			ids:    []string{"2"},
+			inputs: []string{"1"},
+		},
+	}
+
+	var called []string
+	err := Run(byTop(nodes), 4, func(i int) error {
+		called = append(called, nodes[i].ids[0])
+		if i == 1 {
+			return errors.New("fail")
+		}
+		return nil
+	})
+	assert.Error(t, err, "fail")
+	assert.DeepEqual(t, called, []string{"0", "1"})
+}
+
+func TestRunCycle(t *testing.T) {
+	nodes := []items{
+		{
+			ids:    []string{"A", "a"},
+			inputs: []string{"C"},
+		},
+		{
+			ids:    []string{"B", "b"},
+			inputs: []string{"a"},
+		},
+		{
+			ids:    []string{"C", "c"},
+			inputs: []string{"b"},
+		},
+		{
+			ids: []string{"D"},
+		},
+	}
+
+	called := false
+	err := Run(byTop(nodes), 1, func(i int) error {
+		called = true
+		return nil
+	})
+	assert.Error(t, err, "cycle error A B C ")
+	assert.Assert(t, !called)
+}
@@ -23,6 +23,8 @@ type node struct {
 	inputCnt    int
 	outputs     []string
 	sortedIndex int
+
+	outputIndexes []int
 }
 
 func TopoSort(data Interface) error {
@@ -6,9 +6,12 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/vitelabs/go-vite/common"
 	"github.com/vitelabs/go-vite/common/config"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/common/upgrade"
+	"github.com/vitelabs/go-vite/interfaces"
+	"github.com/vitelabs/go-vite/ledger/abiregistry"
 	"github.com/vitelabs/go-vite/ledger/chain"
 	"github.com/vitelabs/go-vite/ledger/consensus"
 	"github.com/vitelabs/go-vite/ledger/onroad"
@@ -19,6 +22,7 @@ import (
 	"github.com/vitelabs/go-vite/producer"
 	"github.com/vitelabs/go-vite/vm"
 	"github.com/vitelabs/go-vite/wallet"
+	"github.com/vitelabs/go-vite/wallet/entropystore"
 )
 
 var (
@@ -36,6 +40,9 @@ type Vite struct {
 	pool          pool.BlockPool
 	consensus     consensus.Consensus
 	onRoad        *onroad.Manager
+	abiRegistry   *abiregistry.Registry
+
+	walletLid int
 }
 
 func New(cfg *config.Config, walletManager *wallet.Manager) (vite *Vite, err error) {
@@ -63,6 +70,24 @@ func New(cfg *config.Config, walletManager *wallet.Manager) (vite *Vite, err err
 	if err != nil {
 		return nil, err
 	}
+	// abi registry
+	registryDb, err := chain.NewDb("abi_registry")
+	if err != nil {
+		return nil, err
+	}
+	abiRegistry, err := abiregistry.NewRegistry(chain, registryDb)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Registry != nil && len(cfg.Registry.AbiDir) > 0 {
+		if err := abiRegistry.LoadDir(cfg.Registry.AbiDir); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Registry != nil && len(cfg.Registry.SolppcPath) > 0 {
+		abiRegistry.SetCompiler(abiregistry.NewCompiler(cfg.Registry.SolppcPath))
+	}
+
 	// pool
 	pl, err := pool.NewPool(chain)
 	if err != nil {
@@ -88,6 +113,7 @@ func New(cfg *config.Config, walletManager *wallet.Manager) (vite *Vite, err err
 		pool:          pl,
 		consensus:     cs,
 		verifier:      verifier,
+		abiRegistry:   abiRegistry,
 	}
 
 	if account != nil {
@@ -113,11 +139,28 @@ func (v *Vite) Init() (err error) {
 	v.onRoad.Init(v.chain)
 	v.verifier.InitOnRoadPool(v.onRoad)
 
+	// the auto receive workers sign by the unlocked accounts of the wallet
+	if v.walletManager != nil {
+		autoReceiveDb, err := v.chain.NewDb("auto_receive")
+		if err != nil {
+			return err
+		}
+		if err := v.onRoad.InitAutoReceive(autoReceiveDb, v.findUnlockedAccount); err != nil {
+			log.Error("Init auto receive failed, error is "+err.Error(), "method", "vite.Init")
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (v *Vite) Start() (err error) {
 	v.onRoad.Start()
+	if v.walletManager != nil {
+		v.walletLid = v.walletManager.AddLockEventListener(func(event entropystore.UnlockEvent) {
+			common.Go(v.onRoad.ResumeAutoReceive)
+		})
+	}
 
 	v.chain.Start()
 
@@ -147,6 +190,9 @@ func (v *Vite) Start() (err error) {
 }
 
 func (v *Vite) Stop() (err error) {
+	if v.walletManager != nil {
+		v.walletManager.RemoveUnlockChangeChannel(v.walletLid)
+	}
 
 	v.net.Stop()
 	v.pool.Stop()
@@ -160,9 +206,20 @@ func (v *Vite) Stop() (err error) {
 	v.consensus.Stop()
 	v.chain.Stop()
 	v.onRoad.Stop()
+	if err := v.abiRegistry.Close(); err != nil {
+		log.Error("abiRegistry.Close failed, error is "+err.Error(), "method", "vite.Stop")
+	}
 	return nil
 }
 
+func (v *Vite) findUnlockedAccount(addr types.Address) (interfaces.Account, error) {
+	account, err := v.walletManager.ReceiveAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
 func (v *Vite) Chain() chain.Chain {
 	return v.chain
 }
@@ -199,6 +256,10 @@ func (v *Vite) Verifier() verifier.Verifier {
 	return v.verifier
 }
 
+func (v *Vite) AbiRegistry() *abiregistry.Registry {
+	return v.abiRegistry
+}
+
 func parseCoinbase(coinbaseCfg string) (*types.Address, uint32, error) {
 	splits := strings.Split(coinbaseCfg, ":")
 	if len(splits) != 2 {
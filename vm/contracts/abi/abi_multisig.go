@@ -0,0 +1,369 @@
+package abi
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/vm/abi"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+const (
+	jsonMultisig = `
+	[
+		{"type":"function","name":"CreateMultisig","inputs":[{"name":"owners","type":"address[]"},{"name":"threshold","type":"uint8"},{"name":"timeLock","type":"uint64"}]},
+		{"type":"function","name":"Deposit","inputs":[{"name":"walletId","type":"bytes32"}]},
+		{"type":"function","name":"ProposeTransfer","inputs":[{"name":"walletId","type":"bytes32"},{"name":"to","type":"address"},{"name":"tokenId","type":"tokenId"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}]},
+		{"type":"function","name":"ProposeOwnerChange","inputs":[{"name":"walletId","type":"bytes32"},{"name":"owners","type":"address[]"},{"name":"threshold","type":"uint8"},{"name":"timeLock","type":"uint64"}]},
+		{"type":"function","name":"Approve","inputs":[{"name":"walletId","type":"bytes32"},{"name":"proposalId","type":"bytes32"}]},
+		{"type":"function","name":"CancelProposal","inputs":[{"name":"walletId","type":"bytes32"},{"name":"proposalId","type":"bytes32"}]},
+		{"type":"function","name":"Execute","inputs":[{"name":"walletId","type":"bytes32"},{"name":"proposalId","type":"bytes32"}]},
+		{"type":"function","name":"Refund","inputs":[{"name":"callHash","type":"bytes32"}]},
+
+		{"type":"variable","name":"multisigInfo","inputs":[{"name":"owners","type":"address[]"},{"name":"threshold","type":"uint8"},{"name":"timeLock","type":"uint64"}]},
+		{"type":"variable","name":"multisigBalance","inputs":[{"name":"amount","type":"uint256"}]},
+		{"type":"variable","name":"multisigProposal","inputs":[{"name":"proposer","type":"address"},{"name":"kind","type":"uint8"},{"name":"to","type":"address"},{"name":"tokenId","type":"tokenId"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"},{"name":"owners","type":"address[]"},{"name":"threshold","type":"uint8"},{"name":"timeLock","type":"uint64"},{"name":"approvals","type":"address[]"},{"name":"unlockTime","type":"int64"}]},
+		{"type":"variable","name":"multisigCall","inputs":[{"name":"walletId","type":"bytes32"},{"name":"to","type":"address"},{"name":"tokenId","type":"tokenId"},{"name":"amount","type":"uint256"}]},
+
+		{"type":"event","name":"createMultisig","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"owners","type":"address[]"},{"name":"threshold","type":"uint8"},{"name":"timeLock","type":"uint64"}]},
+		{"type":"event","name":"deposit","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"tokenId","type":"tokenId"},{"name":"amount","type":"uint256"}]},
+		{"type":"event","name":"propose","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"proposalId","type":"bytes32"},{"name":"proposer","type":"address"}]},
+		{"type":"event","name":"approve","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"proposalId","type":"bytes32"},{"name":"owner","type":"address"}]},
+		{"type":"event","name":"cancelProposal","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"proposalId","type":"bytes32"}]},
+		{"type":"event","name":"execute","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"proposalId","type":"bytes32"}]},
+		{"type":"event","name":"refund","inputs":[{"name":"walletId","type":"bytes32","indexed":true},{"name":"callHash","type":"bytes32"},{"name":"tokenId","type":"tokenId"},{"name":"amount","type":"uint256"}]}
+	]`
+
+	MethodNameMultisigCreate             = "CreateMultisig"
+	MethodNameMultisigDeposit            = "Deposit"
+	MethodNameMultisigProposeTransfer    = "ProposeTransfer"
+	MethodNameMultisigProposeOwnerChange = "ProposeOwnerChange"
+	MethodNameMultisigApprove            = "Approve"
+	MethodNameMultisigCancelProposal     = "CancelProposal"
+	MethodNameMultisigExecute            = "Execute"
+	MethodNameMultisigRefund             = "Refund"
+
+	VariableNameMultisigInfo     = "multisigInfo"
+	VariableNameMultisigBalance  = "multisigBalance"
+	VariableNameMultisigProposal = "multisigProposal"
+	VariableNameMultisigCall     = "multisigCall"
+
+	EventNameMultisigCreate  = "createMultisig"
+	EventNameMultisigDeposit = "deposit"
+	EventNameMultisigPropose = "propose"
+	EventNameMultisigApprove = "approve"
+	EventNameMultisigCancel  = "cancelProposal"
+	EventNameMultisigExecute = "execute"
+	EventNameMultisigRefund  = "refund"
+)
+
+const (
+	// MultisigProposalTransfer sends the tokens of the multisig wallet, or calls a contract with data
+	MultisigProposalTransfer = uint8(1)
+	// MultisigProposalOwnerChange replaces the owners, the threshold and the time lock of the multisig wallet
+	MultisigProposalOwnerChange = uint8(2)
+)
+
+var (
+	// ABIMultisig is abi definition of multisig contract
+	ABIMultisig, _ = abi.JSONToABIContract(strings.NewReader(jsonMultisig))
+
+	multisigInfoKeyPrefix     = []byte{1}
+	multisigBalanceKeyPrefix  = []byte{2}
+	multisigProposalKeyPrefix = []byte{3}
+	multisigCallKeyPrefix     = []byte{4}
+)
+
+type ParamCreateMultisig struct {
+	Owners    []types.Address
+	Threshold uint8
+	TimeLock  uint64
+}
+
+type ParamMultisigDeposit struct {
+	WalletId types.Hash
+}
+
+type ParamMultisigProposeTransfer struct {
+	WalletId types.Hash
+	To       types.Address
+	TokenId  types.TokenTypeId
+	Amount   *big.Int
+	Data     []byte
+}
+
+type ParamMultisigProposeOwnerChange struct {
+	WalletId  types.Hash
+	Owners    []types.Address
+	Threshold uint8
+	TimeLock  uint64
+}
+
+type ParamMultisigProposal struct {
+	WalletId   types.Hash
+	ProposalId types.Hash
+}
+
+type ParamMultisigRefund struct {
+	CallHash types.Hash
+}
+
+type VariableMultisigBalance struct {
+	Amount *big.Int
+}
+
+// MultisigInfo is the owner set of a multisig wallet, a proposal is executable after approved by threshold owners
+// and the time lock in seconds passed
+type MultisigInfo struct {
+	Owners    []types.Address
+	Threshold uint8
+	TimeLock  uint64
+}
+
+// IsOwner returns whether the address is one of the owners
+func (m *MultisigInfo) IsOwner(addr types.Address) bool {
+	for _, owner := range m.Owners {
+		if owner == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// MultisigProposal is a pending transfer, contract call or owner change of a multisig wallet
+type MultisigProposal struct {
+	Id       types.Hash
+	WalletId types.Hash
+
+	Proposer types.Address
+	Kind     uint8
+	// the transaction of MultisigProposalTransfer
+	To      types.Address
+	TokenId types.TokenTypeId
+	Amount  *big.Int
+	Data    []byte
+	// the new owner set of MultisigProposalOwnerChange
+	Owners    []types.Address
+	Threshold uint8
+	TimeLock  uint64
+
+	Approvals []types.Address
+	// the time when the proposal can be executed, 0 if it has not been approved by enough owners
+	UnlockTime int64
+}
+
+// MultisigCall is a contract call sent by an executed proposal, it's kept until the call is refunded to the wallet
+type MultisigCall struct {
+	WalletId types.Hash
+	To       types.Address
+	TokenId  types.TokenTypeId
+	Amount   *big.Int
+}
+
+// GetMultisigInfoKey generate db key for multisig wallet info
+func GetMultisigInfoKey(walletId types.Hash) []byte {
+	return append(append([]byte{}, multisigInfoKeyPrefix...), walletId.Bytes()...)
+}
+
+// GetMultisigBalanceKey generate db key for the token balance of a multisig wallet
+func GetMultisigBalanceKey(walletId types.Hash, tokenId types.TokenTypeId) []byte {
+	return append(getMultisigBalanceKeyPrefix(walletId), tokenId.Bytes()...)
+}
+
+func getMultisigBalanceKeyPrefix(walletId types.Hash) []byte {
+	return append(append([]byte{}, multisigBalanceKeyPrefix...), walletId.Bytes()...)
+}
+
+// GetMultisigProposalKey generate db key for a proposal of a multisig wallet
+func GetMultisigProposalKey(walletId types.Hash, proposalId types.Hash) []byte {
+	return append(getMultisigProposalKeyPrefix(walletId), proposalId.Bytes()...)
+}
+
+func getMultisigProposalKeyPrefix(walletId types.Hash) []byte {
+	return append(append([]byte{}, multisigProposalKeyPrefix...), walletId.Bytes()...)
+}
+
+// GetMultisigCallKey generate db key for a contract call of a multisig wallet by the hash of the send block
+func GetMultisigCallKey(callHash types.Hash) []byte {
+	return append(append([]byte{}, multisigCallKeyPrefix...), callHash.Bytes()...)
+}
+
+// GetMultisigInfo query multisig wallet info by id
+func GetMultisigInfo(db StorageDatabase, walletId types.Hash) (*MultisigInfo, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	data, err := db.GetValue(GetMultisigInfoKey(walletId))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return UnpackMultisigInfo(data)
+}
+
+// GetMultisigInfoListByOwner query all multisig wallets owned by the address
+func GetMultisigInfoListByOwner(db StorageDatabase, owner types.Address) (map[types.Hash]*MultisigInfo, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	iterator, err := db.NewStorageIterator(multisigInfoKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Release()
+	infoMap := make(map[types.Hash]*MultisigInfo)
+	for {
+		if !iterator.Next() {
+			if iterator.Error() != nil {
+				return nil, iterator.Error()
+			}
+			break
+		}
+		if !filterKeyValue(iterator.Key(), iterator.Value(), nil) {
+			continue
+		}
+		info, err := UnpackMultisigInfo(iterator.Value())
+		if err != nil || !info.IsOwner(owner) {
+			continue
+		}
+		walletId, _ := types.BytesToHash(iterator.Key()[len(multisigInfoKeyPrefix):])
+		infoMap[walletId] = info
+	}
+	return infoMap, nil
+}
+
+// GetMultisigBalance query the token balance of a multisig wallet
+func GetMultisigBalance(db StorageDatabase, walletId types.Hash, tokenId types.TokenTypeId) (*big.Int, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	data, err := db.GetValue(GetMultisigBalanceKey(walletId, tokenId))
+	if err != nil {
+		return nil, err
+	}
+	return unpackMultisigBalance(data), nil
+}
+
+// GetMultisigBalanceMap query all token balances of a multisig wallet
+func GetMultisigBalanceMap(db StorageDatabase, walletId types.Hash) (map[types.TokenTypeId]*big.Int, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	prefix := getMultisigBalanceKeyPrefix(walletId)
+	iterator, err := db.NewStorageIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Release()
+	balanceMap := make(map[types.TokenTypeId]*big.Int)
+	for {
+		if !iterator.Next() {
+			if iterator.Error() != nil {
+				return nil, iterator.Error()
+			}
+			break
+		}
+		if !filterKeyValue(iterator.Key(), iterator.Value(), nil) {
+			continue
+		}
+		tokenId, _ := types.BytesToTokenTypeId(iterator.Key()[len(prefix):])
+		balanceMap[tokenId] = unpackMultisigBalance(iterator.Value())
+	}
+	return balanceMap, nil
+}
+
+// GetMultisigProposal query a proposal of a multisig wallet
+func GetMultisigProposal(db StorageDatabase, walletId types.Hash, proposalId types.Hash) (*MultisigProposal, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	data, err := db.GetValue(GetMultisigProposalKey(walletId, proposalId))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return UnpackMultisigProposal(walletId, proposalId, data)
+}
+
+// GetMultisigProposalList query all pending proposals of a multisig wallet
+func GetMultisigProposalList(db StorageDatabase, walletId types.Hash) ([]*MultisigProposal, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	prefix := getMultisigProposalKeyPrefix(walletId)
+	iterator, err := db.NewStorageIterator(prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Release()
+	proposalList := make([]*MultisigProposal, 0)
+	for {
+		if !iterator.Next() {
+			if iterator.Error() != nil {
+				return nil, iterator.Error()
+			}
+			break
+		}
+		if !filterKeyValue(iterator.Key(), iterator.Value(), nil) {
+			continue
+		}
+		proposalId, _ := types.BytesToHash(iterator.Key()[len(prefix):])
+		if proposal, err := UnpackMultisigProposal(walletId, proposalId, iterator.Value()); err == nil {
+			proposalList = append(proposalList, proposal)
+		}
+	}
+	return proposalList, nil
+}
+
+// GetMultisigCall query a contract call sent by a multisig wallet which is not refunded
+func GetMultisigCall(db StorageDatabase, callHash types.Hash) (*MultisigCall, error) {
+	if *db.Address() != types.AddressMultisig {
+		return nil, util.ErrAddressNotMatch
+	}
+	data, err := db.GetValue(GetMultisigCallKey(callHash))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	call := new(MultisigCall)
+	if err := ABIMultisig.UnpackVariable(call, VariableNameMultisigCall, data); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// UnpackMultisigInfo decode multisig wallet info
+func UnpackMultisigInfo(data []byte) (*MultisigInfo, error) {
+	info := new(MultisigInfo)
+	if err := ABIMultisig.UnpackVariable(info, VariableNameMultisigInfo, data); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// UnpackMultisigProposal decode a proposal of a multisig wallet
+func UnpackMultisigProposal(walletId types.Hash, proposalId types.Hash, data []byte) (*MultisigProposal, error) {
+	proposal := new(MultisigProposal)
+	if err := ABIMultisig.UnpackVariable(proposal, VariableNameMultisigProposal, data); err != nil {
+		return nil, err
+	}
+	proposal.Id = proposalId
+	proposal.WalletId = walletId
+	return proposal, nil
+}
+
+func unpackMultisigBalance(data []byte) *big.Int {
+	if len(data) == 0 {
+		return big.NewInt(0)
+	}
+	balance := new(VariableMultisigBalance)
+	ABIMultisig.UnpackVariable(balance, VariableNameMultisigBalance, data)
+	return balance.Amount
+}
@@ -13,7 +13,7 @@ import (
 )
 
 func TestContractsABIInit(t *testing.T) {
-	tests := []string{jsonQuota, jsonGovernance, jsonAsset}
+	tests := []string{jsonQuota, jsonGovernance, jsonAsset, jsonMultisig}
 	for _, data := range tests {
 		if _, err := abi.JSONToABIContract(strings.NewReader(data)); err != nil {
 			t.Fatalf("json to abi failed, %v, %v", data, err)
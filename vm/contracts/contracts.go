@@ -62,6 +62,7 @@ var (
 	dexRobotContracts        = newDexRobotContracts()
 	dexStableMarketContracts = newDexStableMarketContracts()
 	dexEnrichOrderContracts  = newDexEnrichOrderContracts()
+	multisigContracts        = newMultisigContracts()
 )
 
 func newSimpleContracts() map[types.Address]*builtinContract {
@@ -243,10 +244,30 @@ func newDexEnrichOrderContracts() map[types.Address]*builtinContract {
 	return contracts
 }
 
+func newMultisigContracts() map[types.Address]*builtinContract {
+	contracts := newDexEnrichOrderContracts()
+	contracts[types.AddressMultisig] = &builtinContract{
+		map[string]BuiltinContractMethod{
+			cabi.MethodNameMultisigCreate:             &MethodMultisigCreate{cabi.MethodNameMultisigCreate},
+			cabi.MethodNameMultisigDeposit:            &MethodMultisigDeposit{cabi.MethodNameMultisigDeposit},
+			cabi.MethodNameMultisigProposeTransfer:    &MethodMultisigProposeTransfer{cabi.MethodNameMultisigProposeTransfer},
+			cabi.MethodNameMultisigProposeOwnerChange: &MethodMultisigProposeOwnerChange{cabi.MethodNameMultisigProposeOwnerChange},
+			cabi.MethodNameMultisigApprove:            &MethodMultisigApprove{cabi.MethodNameMultisigApprove},
+			cabi.MethodNameMultisigCancelProposal:     &MethodMultisigCancelProposal{cabi.MethodNameMultisigCancelProposal},
+			cabi.MethodNameMultisigExecute:            &MethodMultisigExecute{cabi.MethodNameMultisigExecute},
+			cabi.MethodNameMultisigRefund:             &MethodMultisigRefund{cabi.MethodNameMultisigRefund},
+		},
+		cabi.ABIMultisig,
+	}
+	return contracts
+}
+
 // GetBuiltinContractMethod finds method instance of built-in contract method by address and method id
 func GetBuiltinContractMethod(addr types.Address, methodSelector []byte, sbHeight uint64) (BuiltinContractMethod, bool, error) {
 	var contractsMap map[types.Address]*builtinContract
-	if upgrade.IsVersionXUpgrade(sbHeight) {
+	if upgrade.IsMultisigUpgrade(sbHeight) {
+		contractsMap = multisigContracts
+	} else if upgrade.IsVersionXUpgrade(sbHeight) {
 		contractsMap = dexEnrichOrderContracts
 	} else if upgrade.IsDexStableMarketUpgrade(sbHeight) {
 		contractsMap = dexStableMarketContracts
@@ -0,0 +1,517 @@
+package contracts
+
+import (
+	"math/big"
+
+	"github.com/vitelabs/go-vite/common/helper"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/interfaces"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/vm/contracts/abi"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+// The multisig contract holds the funds of many multisig wallets, a wallet is identified by the hash of the
+// create block. Owners propose transfers and owner changes, a proposal is executed by any owner after it's
+// approved by threshold owners and the time lock of the wallet passed.
+// A proposal calling a contract is tracked by the hash of its send block, the refund of a failed receive is sent
+// back as a Refund call with the hash and credited to the wallet. Proposals can't call the builtin contracts, whose
+// fees and callbacks are charged to and sent to the multisig contract without the wallet they belong to.
+
+type MethodMultisigCreate struct {
+	MethodName string
+}
+
+func (p *MethodMultisigCreate) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigCreate) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigCreate) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return util.BlockGasCost(data, gasTable.MultisigCreateQuota, 0, gasTable)
+}
+func (p *MethodMultisigCreate) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigCreate) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	param := new(abi.ParamCreateMultisig)
+	if err := abi.ABIMultisig.UnpackMethod(param, p.MethodName, block.Data); err != nil {
+		return util.ErrInvalidMethodParam
+	}
+	if err := checkMultisigOwners(param.Owners, param.Threshold, param.TimeLock); err != nil {
+		return err
+	}
+	block.Data, _ = abi.ABIMultisig.PackMethod(p.MethodName, param.Owners, param.Threshold, param.TimeLock)
+	return nil
+}
+func (p *MethodMultisigCreate) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	param := new(abi.ParamCreateMultisig)
+	abi.ABIMultisig.UnpackMethod(param, p.MethodName, sendBlock.Data)
+	walletId := sendBlock.Hash
+	key := abi.GetMultisigInfoKey(walletId)
+	if v := util.GetValue(db, key); len(v) > 0 {
+		return nil, util.ErrIDCollision
+	}
+	info, _ := abi.ABIMultisig.PackVariable(abi.VariableNameMultisigInfo, param.Owners, param.Threshold, param.TimeLock)
+	util.SetValue(db, key, info)
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigCreate, walletId, param.Owners, param.Threshold, param.TimeLock))
+	if sendBlock.Amount.Sign() > 0 {
+		addMultisigBalance(db, walletId, sendBlock.TokenId, sendBlock.Amount)
+		db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigDeposit, walletId, sendBlock.TokenId, sendBlock.Amount))
+	}
+	return nil, nil
+}
+
+type MethodMultisigDeposit struct {
+	MethodName string
+}
+
+func (p *MethodMultisigDeposit) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigDeposit) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigDeposit) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return gasTable.MultisigDepositQuota, nil
+}
+func (p *MethodMultisigDeposit) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigDeposit) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	if block.Amount.Sign() <= 0 {
+		return util.ErrInvalidMethodParam
+	}
+	param := new(abi.ParamMultisigDeposit)
+	if err := abi.ABIMultisig.UnpackMethod(param, p.MethodName, block.Data); err != nil {
+		return util.ErrInvalidMethodParam
+	}
+	block.Data, _ = abi.ABIMultisig.PackMethod(p.MethodName, param.WalletId)
+	return nil
+}
+func (p *MethodMultisigDeposit) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	param := new(abi.ParamMultisigDeposit)
+	abi.ABIMultisig.UnpackMethod(param, p.MethodName, sendBlock.Data)
+	info, err := abi.GetMultisigInfo(db, param.WalletId)
+	util.DealWithErr(err)
+	if info == nil {
+		return nil, util.ErrInvalidMethodParam
+	}
+	addMultisigBalance(db, param.WalletId, sendBlock.TokenId, sendBlock.Amount)
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigDeposit, param.WalletId, sendBlock.TokenId, sendBlock.Amount))
+	return nil, nil
+}
+
+type MethodMultisigProposeTransfer struct {
+	MethodName string
+}
+
+func (p *MethodMultisigProposeTransfer) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigProposeTransfer) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigProposeTransfer) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return util.BlockGasCost(data, gasTable.MultisigProposeQuota, 0, gasTable)
+}
+func (p *MethodMultisigProposeTransfer) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigProposeTransfer) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	if block.Amount.Sign() > 0 {
+		return util.ErrInvalidMethodParam
+	}
+	param := new(abi.ParamMultisigProposeTransfer)
+	if err := abi.ABIMultisig.UnpackMethod(param, p.MethodName, block.Data); err != nil {
+		return util.ErrInvalidMethodParam
+	}
+	if param.Amount.Sign() < 0 || param.Amount.Cmp(helper.Tt256m1) > 0 ||
+		(param.Amount.Sign() == 0 && len(param.Data) == 0) ||
+		types.IsBuiltinContractAddr(param.To) {
+		return util.ErrInvalidMethodParam
+	}
+	block.Data, _ = abi.ABIMultisig.PackMethod(p.MethodName, param.WalletId, param.To, param.TokenId, param.Amount, param.Data)
+	return nil
+}
+func (p *MethodMultisigProposeTransfer) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	param := new(abi.ParamMultisigProposeTransfer)
+	abi.ABIMultisig.UnpackMethod(param, p.MethodName, sendBlock.Data)
+	proposal := &abi.MultisigProposal{
+		WalletId: param.WalletId,
+		Kind:     abi.MultisigProposalTransfer,
+		To:       param.To,
+		TokenId:  param.TokenId,
+		Amount:   param.Amount,
+		Data:     param.Data,
+	}
+	return nil, doReceiveMultisigPropose(db, sendBlock, vm, proposal)
+}
+
+type MethodMultisigProposeOwnerChange struct {
+	MethodName string
+}
+
+func (p *MethodMultisigProposeOwnerChange) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigProposeOwnerChange) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigProposeOwnerChange) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return util.BlockGasCost(data, gasTable.MultisigProposeQuota, 0, gasTable)
+}
+func (p *MethodMultisigProposeOwnerChange) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigProposeOwnerChange) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	if block.Amount.Sign() > 0 {
+		return util.ErrInvalidMethodParam
+	}
+	param := new(abi.ParamMultisigProposeOwnerChange)
+	if err := abi.ABIMultisig.UnpackMethod(param, p.MethodName, block.Data); err != nil {
+		return util.ErrInvalidMethodParam
+	}
+	if err := checkMultisigOwners(param.Owners, param.Threshold, param.TimeLock); err != nil {
+		return err
+	}
+	block.Data, _ = abi.ABIMultisig.PackMethod(p.MethodName, param.WalletId, param.Owners, param.Threshold, param.TimeLock)
+	return nil
+}
+func (p *MethodMultisigProposeOwnerChange) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	param := new(abi.ParamMultisigProposeOwnerChange)
+	abi.ABIMultisig.UnpackMethod(param, p.MethodName, sendBlock.Data)
+	proposal := &abi.MultisigProposal{
+		WalletId:  param.WalletId,
+		Kind:      abi.MultisigProposalOwnerChange,
+		Amount:    big.NewInt(0),
+		Owners:    param.Owners,
+		Threshold: param.Threshold,
+		TimeLock:  param.TimeLock,
+	}
+	return nil, doReceiveMultisigPropose(db, sendBlock, vm, proposal)
+}
+
+func doReceiveMultisigPropose(db interfaces.VmDb, sendBlock *ledger.AccountBlock, vm vmEnvironment, proposal *abi.MultisigProposal) error {
+	info, err := abi.GetMultisigInfo(db, proposal.WalletId)
+	util.DealWithErr(err)
+	if info == nil || !info.IsOwner(sendBlock.AccountAddress) {
+		return util.ErrInvalidMethodParam
+	}
+	key := abi.GetMultisigProposalKey(proposal.WalletId, sendBlock.Hash)
+	if v := util.GetValue(db, key); len(v) > 0 {
+		return util.ErrIDCollision
+	}
+	proposal.Id = sendBlock.Hash
+	proposal.Proposer = sendBlock.AccountAddress
+	// the proposer approves the proposal
+	proposal.Approvals = []types.Address{sendBlock.AccountAddress}
+	updateMultisigUnlockTime(info, proposal, getMultisigTime(vm))
+	saveMultisigProposal(db, proposal)
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigPropose, proposal.WalletId, proposal.Id, proposal.Proposer))
+	return nil
+}
+
+type MethodMultisigApprove struct {
+	MethodName string
+}
+
+func (p *MethodMultisigApprove) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigApprove) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigApprove) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return gasTable.MultisigApproveQuota, nil
+}
+func (p *MethodMultisigApprove) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigApprove) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	return doSendMultisigProposal(block, p.MethodName)
+}
+func (p *MethodMultisigApprove) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	info, proposal, err := getMultisigProposalByOwner(db, p.MethodName, sendBlock)
+	if err != nil {
+		return nil, err
+	}
+	for _, approval := range proposal.Approvals {
+		if approval == sendBlock.AccountAddress {
+			return nil, util.ErrInvalidMethodParam
+		}
+	}
+	proposal.Approvals = append(proposal.Approvals, sendBlock.AccountAddress)
+	updateMultisigUnlockTime(info, proposal, getMultisigTime(vm))
+	saveMultisigProposal(db, proposal)
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigApprove, proposal.WalletId, proposal.Id, sendBlock.AccountAddress))
+	return nil, nil
+}
+
+type MethodMultisigCancelProposal struct {
+	MethodName string
+}
+
+func (p *MethodMultisigCancelProposal) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigCancelProposal) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigCancelProposal) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return gasTable.MultisigCancelProposalQuota, nil
+}
+func (p *MethodMultisigCancelProposal) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigCancelProposal) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	return doSendMultisigProposal(block, p.MethodName)
+}
+
+// DoReceive deletes the proposal, only the proposer can cancel it
+func (p *MethodMultisigCancelProposal) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	param := new(abi.ParamMultisigProposal)
+	abi.ABIMultisig.UnpackMethod(param, p.MethodName, sendBlock.Data)
+	proposal, err := abi.GetMultisigProposal(db, param.WalletId, param.ProposalId)
+	util.DealWithErr(err)
+	if proposal == nil || proposal.Proposer != sendBlock.AccountAddress {
+		return nil, util.ErrInvalidMethodParam
+	}
+	util.SetValue(db, abi.GetMultisigProposalKey(param.WalletId, param.ProposalId), nil)
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigCancel, param.WalletId, param.ProposalId))
+	return nil, nil
+}
+
+type MethodMultisigExecute struct {
+	MethodName string
+}
+
+func (p *MethodMultisigExecute) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigExecute) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigExecute) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return gasTable.MultisigExecuteQuota, nil
+}
+func (p *MethodMultisigExecute) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigExecute) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	return doSendMultisigProposal(block, p.MethodName)
+}
+func (p *MethodMultisigExecute) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	info, proposal, err := getMultisigProposalByOwner(db, p.MethodName, sendBlock)
+	if err != nil {
+		return nil, err
+	}
+	// the approvals of the removed owners are not counted
+	now := getMultisigTime(vm)
+	if countMultisigApprovals(info, proposal) < int(info.Threshold) || proposal.UnlockTime == 0 || now < proposal.UnlockTime {
+		return nil, util.ErrInvalidMethodParam
+	}
+	util.SetValue(db, abi.GetMultisigProposalKey(proposal.WalletId, proposal.Id), nil)
+
+	var blockList []*ledger.AccountBlock
+	switch proposal.Kind {
+	case abi.MultisigProposalTransfer:
+		if proposal.Amount.Sign() > 0 && !subMultisigBalance(db, proposal.WalletId, proposal.TokenId, proposal.Amount) {
+			return nil, util.ErrInsufficientBalance
+		}
+		sendBlock := &ledger.AccountBlock{
+			AccountAddress: block.AccountAddress,
+			ToAddress:      proposal.To,
+			BlockType:      ledger.BlockTypeSendCall,
+			Amount:         proposal.Amount,
+			TokenId:        proposal.TokenId,
+			Data:           proposal.Data,
+		}
+		blockList = []*ledger.AccountBlock{sendBlock}
+		// the call is kept until it's refunded, so that the refund is credited back to the wallet
+		if types.IsContractAddr(proposal.To) && proposal.Amount.Sign() > 0 {
+			callHash := util.ComputeSendBlockHash(block, sendBlock, 0)
+			data, _ := abi.ABIMultisig.PackVariable(abi.VariableNameMultisigCall, proposal.WalletId, proposal.To, proposal.TokenId, proposal.Amount)
+			util.SetValue(db, abi.GetMultisigCallKey(callHash), data)
+		}
+	case abi.MultisigProposalOwnerChange:
+		newInfo := &abi.MultisigInfo{Owners: proposal.Owners, Threshold: proposal.Threshold, TimeLock: proposal.TimeLock}
+		data, _ := abi.ABIMultisig.PackVariable(abi.VariableNameMultisigInfo, newInfo.Owners, newInfo.Threshold, newInfo.TimeLock)
+		util.SetValue(db, abi.GetMultisigInfoKey(proposal.WalletId), data)
+		// the pending proposals are approved by the new owner set from now on
+		proposalList, err := abi.GetMultisigProposalList(db, proposal.WalletId)
+		util.DealWithErr(err)
+		for _, pending := range proposalList {
+			oldUnlockTime := pending.UnlockTime
+			updateMultisigUnlockTime(newInfo, pending, now)
+			if pending.UnlockTime != oldUnlockTime {
+				saveMultisigProposal(db, pending)
+			}
+		}
+	default:
+		return nil, util.ErrInvalidMethodParam
+	}
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigExecute, proposal.WalletId, proposal.Id))
+	return blockList, nil
+}
+
+type MethodMultisigRefund struct {
+	MethodName string
+}
+
+func (p *MethodMultisigRefund) GetFee(block *ledger.AccountBlock) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+func (p *MethodMultisigRefund) GetRefundData(sendBlock *ledger.AccountBlock, sbHeight uint64) ([]byte, bool) {
+	return []byte{}, false
+}
+func (p *MethodMultisigRefund) GetSendQuota(data []byte, gasTable *util.QuotaTable) (uint64, error) {
+	return gasTable.MultisigDepositQuota, nil
+}
+func (p *MethodMultisigRefund) GetReceiveQuota(gasTable *util.QuotaTable) uint64 {
+	return 0
+}
+func (p *MethodMultisigRefund) DoSend(db interfaces.VmDb, block *ledger.AccountBlock) error {
+	if block.Amount.Sign() <= 0 {
+		return util.ErrInvalidMethodParam
+	}
+	param := new(abi.ParamMultisigRefund)
+	if err := abi.ABIMultisig.UnpackMethod(param, p.MethodName, block.Data); err != nil {
+		return util.ErrInvalidMethodParam
+	}
+	block.Data, _ = abi.ABIMultisig.PackMethod(p.MethodName, param.CallHash)
+	return nil
+}
+
+// DoReceive credits the refund of a contract call back to the wallet sending it, the refund should be sent by the
+// contract called and not exceed the amount of the call
+func (p *MethodMultisigRefund) DoReceive(db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, vm vmEnvironment) ([]*ledger.AccountBlock, error) {
+	param := new(abi.ParamMultisigRefund)
+	abi.ABIMultisig.UnpackMethod(param, p.MethodName, sendBlock.Data)
+	call, err := abi.GetMultisigCall(db, param.CallHash)
+	util.DealWithErr(err)
+	if call == nil || call.To != sendBlock.AccountAddress || call.TokenId != sendBlock.TokenId ||
+		sendBlock.Amount.Cmp(call.Amount) > 0 {
+		return nil, util.ErrInvalidMethodParam
+	}
+	util.SetValue(db, abi.GetMultisigCallKey(param.CallHash), nil)
+	addMultisigBalance(db, call.WalletId, sendBlock.TokenId, sendBlock.Amount)
+	db.AddLog(NewLog(abi.ABIMultisig, abi.EventNameMultisigRefund, call.WalletId, param.CallHash, sendBlock.TokenId, sendBlock.Amount))
+	return nil, nil
+}
+
+// GetMultisigRefundData returns the data of the refund sent to the multisig contract for the failed call
+func GetMultisigRefundData(callHash types.Hash) []byte {
+	data, _ := abi.ABIMultisig.PackMethod(abi.MethodNameMultisigRefund, callHash)
+	return data
+}
+
+func checkMultisigOwners(owners []types.Address, threshold uint8, timeLock uint64) error {
+	if len(owners) == 0 || len(owners) > multisigOwnerCountMax ||
+		threshold == 0 || int(threshold) > len(owners) ||
+		timeLock > multisigTimeLockMax {
+		return util.ErrInvalidMethodParam
+	}
+	ownerSet := make(map[types.Address]struct{}, len(owners))
+	for _, owner := range owners {
+		if _, ok := ownerSet[owner]; ok || types.IsBuiltinContractAddr(owner) {
+			return util.ErrInvalidMethodParam
+		}
+		ownerSet[owner] = struct{}{}
+	}
+	return nil
+}
+
+func doSendMultisigProposal(block *ledger.AccountBlock, methodName string) error {
+	if block.Amount.Sign() > 0 {
+		return util.ErrInvalidMethodParam
+	}
+	param := new(abi.ParamMultisigProposal)
+	if err := abi.ABIMultisig.UnpackMethod(param, methodName, block.Data); err != nil {
+		return util.ErrInvalidMethodParam
+	}
+	block.Data, _ = abi.ABIMultisig.PackMethod(methodName, param.WalletId, param.ProposalId)
+	return nil
+}
+
+func getMultisigProposalByOwner(db interfaces.VmDb, methodName string, sendBlock *ledger.AccountBlock) (*abi.MultisigInfo, *abi.MultisigProposal, error) {
+	param := new(abi.ParamMultisigProposal)
+	abi.ABIMultisig.UnpackMethod(param, methodName, sendBlock.Data)
+	info, err := abi.GetMultisigInfo(db, param.WalletId)
+	util.DealWithErr(err)
+	if info == nil || !info.IsOwner(sendBlock.AccountAddress) {
+		return nil, nil, util.ErrInvalidMethodParam
+	}
+	proposal, err := abi.GetMultisigProposal(db, param.WalletId, param.ProposalId)
+	util.DealWithErr(err)
+	if proposal == nil {
+		return nil, nil, util.ErrInvalidMethodParam
+	}
+	return info, proposal, nil
+}
+
+func countMultisigApprovals(info *abi.MultisigInfo, proposal *abi.MultisigProposal) int {
+	count := 0
+	for _, approval := range proposal.Approvals {
+		if info.IsOwner(approval) {
+			count++
+		}
+	}
+	return count
+}
+
+// updateMultisigUnlockTime starts the time lock when the proposal is approved by threshold owners, and resets it
+// when the approvals are not enough after an owner change
+func updateMultisigUnlockTime(info *abi.MultisigInfo, proposal *abi.MultisigProposal, now int64) {
+	if countMultisigApprovals(info, proposal) < int(info.Threshold) {
+		proposal.UnlockTime = 0
+	} else if proposal.UnlockTime == 0 {
+		proposal.UnlockTime = now + int64(info.TimeLock)
+	}
+}
+
+func getMultisigTime(vm vmEnvironment) int64 {
+	return vm.GlobalStatus().SnapshotBlock().Timestamp.Unix()
+}
+
+func saveMultisigProposal(db interfaces.VmDb, proposal *abi.MultisigProposal) {
+	data, _ := abi.ABIMultisig.PackVariable(
+		abi.VariableNameMultisigProposal,
+		proposal.Proposer,
+		proposal.Kind,
+		proposal.To,
+		proposal.TokenId,
+		proposal.Amount,
+		proposal.Data,
+		proposal.Owners,
+		proposal.Threshold,
+		proposal.TimeLock,
+		proposal.Approvals,
+		proposal.UnlockTime)
+	util.SetValue(db, abi.GetMultisigProposalKey(proposal.WalletId, proposal.Id), data)
+}
+
+func addMultisigBalance(db interfaces.VmDb, walletId types.Hash, tokenId types.TokenTypeId, amount *big.Int) {
+	balance, err := abi.GetMultisigBalance(db, walletId, tokenId)
+	util.DealWithErr(err)
+	balance.Add(balance, amount)
+	data, _ := abi.ABIMultisig.PackVariable(abi.VariableNameMultisigBalance, balance)
+	util.SetValue(db, abi.GetMultisigBalanceKey(walletId, tokenId), data)
+}
+
+func subMultisigBalance(db interfaces.VmDb, walletId types.Hash, tokenId types.TokenTypeId, amount *big.Int) bool {
+	balance, err := abi.GetMultisigBalance(db, walletId, tokenId)
+	util.DealWithErr(err)
+	if balance.Cmp(amount) < 0 {
+		return false
+	}
+	balance.Sub(balance, amount)
+	if balance.Sign() == 0 {
+		util.SetValue(db, abi.GetMultisigBalanceKey(walletId, tokenId), nil)
+		return true
+	}
+	data, _ := abi.ABIMultisig.PackVariable(abi.VariableNameMultisigBalance, balance)
+	util.SetValue(db, abi.GetMultisigBalanceKey(walletId, tokenId), data)
+	return true
+}
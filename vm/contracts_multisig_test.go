@@ -0,0 +1,232 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/common/upgrade"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+	"github.com/vitelabs/go-vite/vm/contracts"
+	"github.com/vitelabs/go-vite/vm/contracts/abi"
+	"github.com/vitelabs/go-vite/vm/util"
+)
+
+type multisigTester struct {
+	t      *testing.T
+	db     *testDatabase
+	vm     *VM
+	now    time.Time
+	height byte
+}
+
+func (m *multisigTester) run(from types.Address, amount int64, method string, params ...interface{}) (*ledger.AccountBlock, []*ledger.AccountBlock, error) {
+	data, err := abi.ABIMultisig.PackMethod(method, params...)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	m.height++
+	sendBlock := &ledger.AccountBlock{
+		AccountAddress: from,
+		ToAddress:      types.AddressMultisig,
+		BlockType:      ledger.BlockTypeSendCall,
+		Amount:         big.NewInt(amount),
+		TokenId:        ledger.ViteTokenId,
+		Data:           data,
+		Hash:           types.DataHash([]byte{m.height}),
+	}
+	sb := &ledger.SnapshotBlock{Height: 1, Timestamp: &m.now}
+	m.vm.globalStatus = NewTestGlobalStatus(0, sb)
+	contractMethod, ok, err := contracts.GetBuiltinContractMethod(types.AddressMultisig, data[:4], sb.Height)
+	if !ok || err != nil {
+		m.t.Fatalf("get multisig method failed, %v", err)
+	}
+	if err := contractMethod.DoSend(m.db, sendBlock); err != nil {
+		return sendBlock, nil, err
+	}
+	receiveBlock := &ledger.AccountBlock{AccountAddress: types.AddressMultisig, Height: uint64(m.height)}
+	blockList, err := contractMethod.DoReceive(m.db, receiveBlock, sendBlock, m.vm)
+	if err == nil {
+		for i, block := range blockList {
+			block.Fee = big.NewInt(0)
+			block.Hash = util.ComputeSendBlockHash(receiveBlock, block, uint8(i))
+		}
+	}
+	return sendBlock, blockList, err
+}
+
+// approve proposes the transfer from owner1 and approves it by owner2
+func (m *multisigTester) approve(walletId types.Hash, to types.Address, amount int64, data []byte) types.Hash {
+	proposeBlock, _, err := m.run(types.Address{1}, 0, abi.MethodNameMultisigProposeTransfer, walletId, to, ledger.ViteTokenId, big.NewInt(amount), data)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	if _, _, err := m.run(types.Address{2}, 0, abi.MethodNameMultisigApprove, walletId, proposeBlock.Hash); err != nil {
+		m.t.Fatal(err)
+	}
+	return proposeBlock.Hash
+}
+
+func TestContractsMultisig(t *testing.T) {
+	upgrade.CleanupUpgradeBox(t)
+	upgrade.InitUpgradeBox(upgrade.NewLatestUpgradeBox())
+
+	db := NewNoDatabase()
+	db.addr = types.AddressMultisig
+	m := &multisigTester{t: t, db: db, vm: NewVM(nil), now: time.Unix(1600000000, 0)}
+	owner1, owner2, owner3 := types.Address{1}, types.Address{2}, types.Address{3}
+	recipient := types.Address{4}
+
+	// invalid owners
+	_, _, err := m.run(owner1, 0, abi.MethodNameMultisigCreate, []types.Address{owner1, owner1}, uint8(1), uint64(0))
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	_, _, err = m.run(owner1, 0, abi.MethodNameMultisigCreate, []types.Address{owner1, owner2}, uint8(3), uint64(0))
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+
+	createBlock, _, err := m.run(owner1, 100, abi.MethodNameMultisigCreate, []types.Address{owner1, owner2, owner3}, uint8(2), uint64(60))
+	assert.NoError(t, err)
+	walletId := createBlock.Hash
+	_, _, err = m.run(owner3, 50, abi.MethodNameMultisigDeposit, walletId)
+	assert.NoError(t, err)
+	balance, _ := abi.GetMultisigBalance(db, walletId, ledger.ViteTokenId)
+	assert.Equal(t, big.NewInt(150), balance)
+	infoMap, _ := abi.GetMultisigInfoListByOwner(db, owner2)
+	assert.Equal(t, 1, len(infoMap))
+
+	// propose a transfer, only owners can propose
+	_, _, err = m.run(recipient, 0, abi.MethodNameMultisigProposeTransfer, walletId, recipient, ledger.ViteTokenId, big.NewInt(120), []byte{})
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	// the builtin contracts can't be called
+	_, _, err = m.run(owner1, 0, abi.MethodNameMultisigProposeTransfer, walletId, types.AddressAsset, ledger.ViteTokenId, big.NewInt(120), []byte{1})
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	proposeBlock, _, err := m.run(owner1, 0, abi.MethodNameMultisigProposeTransfer, walletId, recipient, ledger.ViteTokenId, big.NewInt(120), []byte{})
+	assert.NoError(t, err)
+	proposalId := proposeBlock.Hash
+
+	// not approved by enough owners
+	_, _, err = m.run(owner1, 0, abi.MethodNameMultisigExecute, walletId, proposalId)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	_, _, err = m.run(owner1, 0, abi.MethodNameMultisigApprove, walletId, proposalId)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	_, _, err = m.run(owner2, 0, abi.MethodNameMultisigApprove, walletId, proposalId)
+	assert.NoError(t, err)
+	proposal, _ := abi.GetMultisigProposal(db, walletId, proposalId)
+	assert.Equal(t, m.now.Unix()+60, proposal.UnlockTime)
+
+	// time locked
+	_, _, err = m.run(owner3, 0, abi.MethodNameMultisigExecute, walletId, proposalId)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	m.now = m.now.Add(time.Minute)
+	_, blockList, err := m.run(owner3, 0, abi.MethodNameMultisigExecute, walletId, proposalId)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(blockList)) {
+		assert.Equal(t, recipient, blockList[0].ToAddress)
+		assert.Equal(t, big.NewInt(120), blockList[0].Amount)
+	}
+	balance, _ = abi.GetMultisigBalance(db, walletId, ledger.ViteTokenId)
+	assert.Equal(t, big.NewInt(30), balance)
+	proposal, _ = abi.GetMultisigProposal(db, walletId, proposalId)
+	assert.Nil(t, proposal)
+
+	// the pending proposal needs to be approved again after owner2 is removed
+	proposeBlock, _, err = m.run(owner1, 0, abi.MethodNameMultisigProposeTransfer, walletId, recipient, ledger.ViteTokenId, big.NewInt(10), []byte{})
+	assert.NoError(t, err)
+	pendingId := proposeBlock.Hash
+	_, _, err = m.run(owner2, 0, abi.MethodNameMultisigApprove, walletId, pendingId)
+	assert.NoError(t, err)
+	changeBlock, _, err := m.run(owner1, 0, abi.MethodNameMultisigProposeOwnerChange, walletId, []types.Address{owner1, owner3}, uint8(2), uint64(0))
+	assert.NoError(t, err)
+	_, _, err = m.run(owner3, 0, abi.MethodNameMultisigApprove, walletId, changeBlock.Hash)
+	assert.NoError(t, err)
+	m.now = m.now.Add(time.Minute)
+	_, _, err = m.run(owner3, 0, abi.MethodNameMultisigExecute, walletId, changeBlock.Hash)
+	assert.NoError(t, err)
+	info, _ := abi.GetMultisigInfo(db, walletId)
+	assert.Equal(t, []types.Address{owner1, owner3}, info.Owners)
+	proposal, _ = abi.GetMultisigProposal(db, walletId, pendingId)
+	assert.Equal(t, int64(0), proposal.UnlockTime)
+	_, _, err = m.run(owner3, 0, abi.MethodNameMultisigExecute, walletId, pendingId)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+
+	// only the proposer can cancel the proposal
+	_, _, err = m.run(owner3, 0, abi.MethodNameMultisigCancelProposal, walletId, pendingId)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	_, _, err = m.run(owner1, 0, abi.MethodNameMultisigCancelProposal, walletId, pendingId)
+	assert.NoError(t, err)
+	proposalList, _ := abi.GetMultisigProposalList(db, walletId)
+	assert.Equal(t, 0, len(proposalList))
+}
+
+func TestContractsMultisig_Call(t *testing.T) {
+	upgrade.CleanupUpgradeBox(t)
+	upgrade.InitUpgradeBox(upgrade.NewLatestUpgradeBox())
+
+	db := NewNoDatabase()
+	db.addr = types.AddressMultisig
+	m := &multisigTester{t: t, db: db, vm: NewVM(nil), now: time.Unix(1600000000, 0)}
+	owner1, owner2 := types.Address{1}, types.Address{2}
+	contractAddr := types.CreateContractAddress([]byte{1})
+	callData := []byte{1, 2, 3, 4}
+
+	createBlock, _, err := m.run(owner1, 100, abi.MethodNameMultisigCreate, []types.Address{owner1, owner2}, uint8(2), uint64(0))
+	assert.NoError(t, err)
+	walletId := createBlock.Hash
+
+	// an approved call is sent with the call data and kept by its send hash
+	proposalId := m.approve(walletId, contractAddr, 30, callData)
+	_, blockList, err := m.run(owner2, 0, abi.MethodNameMultisigExecute, walletId, proposalId)
+	assert.NoError(t, err)
+	if !assert.Equal(t, 1, len(blockList)) {
+		return
+	}
+	succeeded := blockList[0]
+	assert.Equal(t, contractAddr, succeeded.ToAddress)
+	assert.Equal(t, big.NewInt(30), succeeded.Amount)
+	assert.Equal(t, callData, succeeded.Data)
+	call, _ := abi.GetMultisigCall(db, succeeded.Hash)
+	assert.Equal(t, &abi.MultisigCall{WalletId: walletId, To: contractAddr, TokenId: ledger.ViteTokenId, Amount: big.NewInt(30)}, call)
+	balance, _ := abi.GetMultisigBalance(db, walletId, ledger.ViteTokenId)
+	assert.Equal(t, big.NewInt(70), balance)
+
+	// the refund of a failed call is sent back as a refund call with the send hash
+	proposalId = m.approve(walletId, contractAddr, 50, callData)
+	_, blockList, err = m.run(owner2, 0, abi.MethodNameMultisigExecute, walletId, proposalId)
+	assert.NoError(t, err)
+	failed := blockList[0]
+	balance, _ = abi.GetMultisigBalance(db, walletId, ledger.ViteTokenId)
+	assert.Equal(t, big.NewInt(20), balance)
+
+	calleeDb := NewNoDatabase()
+	calleeDb.addr = contractAddr
+	callee := NewVM(nil)
+	callee.latestSnapshotHeight = 1
+	assert.True(t, doRefund(callee, calleeDb, &ledger.AccountBlock{AccountAddress: contractAddr}, failed, []byte{}, false, ledger.BlockTypeSendRefund))
+	if !assert.Equal(t, 1, len(callee.sendBlockList)) {
+		return
+	}
+	refund := callee.sendBlockList[0]
+	assert.Equal(t, ledger.BlockTypeSendCall, refund.BlockType)
+	assert.Equal(t, types.AddressMultisig, refund.ToAddress)
+	assert.Equal(t, big.NewInt(50), refund.Amount)
+	param := new(abi.ParamMultisigRefund)
+	assert.NoError(t, abi.ABIMultisig.UnpackMethod(param, abi.MethodNameMultisigRefund, refund.Data))
+	assert.Equal(t, failed.Hash, param.CallHash)
+
+	// only the contract called can refund, and not more than the amount of the call
+	_, _, err = m.run(owner1, 50, abi.MethodNameMultisigRefund, failed.Hash)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	_, _, err = m.run(contractAddr, 51, abi.MethodNameMultisigRefund, failed.Hash)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+	_, _, err = m.run(contractAddr, 50, abi.MethodNameMultisigRefund, failed.Hash)
+	assert.NoError(t, err)
+	balance, _ = abi.GetMultisigBalance(db, walletId, ledger.ViteTokenId)
+	assert.Equal(t, big.NewInt(70), balance)
+	call, _ = abi.GetMultisigCall(db, failed.Hash)
+	assert.Nil(t, call)
+	// the call is refunded only once
+	_, _, err = m.run(contractAddr, 50, abi.MethodNameMultisigRefund, failed.Hash)
+	assert.Equal(t, util.ErrInvalidMethodParam, err)
+}
@@ -276,6 +276,12 @@ func CanPoW(db quotaDb, address types.Address) bool {
 	return true
 }
 
+// MaxPoWDifficulty returns the difficulty of the max quota a block can get by PoW when the network is not congested,
+// a larger difficulty doesn't get more quota
+func MaxPoWDifficulty() *big.Int {
+	return new(big.Int).Set(quotaConfig.difficultyList[len(quotaConfig.difficultyList)-1])
+}
+
 // CalcPoWDifficulty calculate pow difficulty by quota
 func CalcPoWDifficulty(db quotaDb, quotaRequired uint64, q types.Quota, sbHeight uint64) (*big.Int, error) {
 	if quotaRequired > quotaLimitForBlock {
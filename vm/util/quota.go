@@ -243,11 +243,19 @@ type QuotaTable struct {
 	DexFundTransferQuota                      uint64
 	DexFundAgentDepositQuota                  uint64
 	DexFundAssignedWithdrawQuota              uint64
+	MultisigCreateQuota                       uint64
+	MultisigDepositQuota                      uint64
+	MultisigProposeQuota                      uint64
+	MultisigApproveQuota                      uint64
+	MultisigCancelProposalQuota               uint64
+	MultisigExecuteQuota                      uint64
 }
 
 // QuotaTableByHeight returns different quota table by hard fork version
 func QuotaTableByHeight(sbHeight uint64) *QuotaTable {
-	if upgrade.IsVersionXUpgrade(sbHeight) {
+	if upgrade.IsMultisigUpgrade(sbHeight) {
+		return &multisigQuotaTable
+	} else if upgrade.IsVersionXUpgrade(sbHeight) {
 		return &versionXQuotaTable
 	} else if upgrade.IsVersion10Upgrade(sbHeight) {
 		return &version10QuotaTable
@@ -370,6 +378,7 @@ var (
 	dexStableMarketQuotaTable = newDexStableMarketQuotaTable()
 	version10QuotaTable       = newVersion10QuotaTable()
 	versionXQuotaTable        = newVersionXQuotaTable()
+	multisigQuotaTable        = newMultisigQuotaTable()
 )
 
 func newViteQuotaTable() QuotaTable {
@@ -539,3 +548,14 @@ func newVersionXQuotaTable() QuotaTable {
 	gt.DexFundAssignedWithdrawQuota = 10500
 	return gt
 }
+
+func newMultisigQuotaTable() QuotaTable {
+	gt := newVersionXQuotaTable()
+	gt.MultisigCreateQuota = 105000
+	gt.MultisigDepositQuota = 52500
+	gt.MultisigProposeQuota = 84000
+	gt.MultisigApproveQuota = 63000
+	gt.MultisigCancelProposalQuota = 52500
+	gt.MultisigExecuteQuota = 84000
+	return gt
+}
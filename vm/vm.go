@@ -661,6 +661,12 @@ func (vm *VM) receiveCall(db interfaces.VmDb, block *ledger.AccountBlock, sendBl
 }
 
 func doRefund(vm *VM, db interfaces.VmDb, block *ledger.AccountBlock, sendBlock *ledger.AccountBlock, refundData []byte, needRefund bool, refundBlockType byte) bool {
+	// the refund to the multisig contract is a call crediting the wallet of the failed call
+	if sendBlock.AccountAddress == types.AddressMultisig && upgrade.IsMultisigUpgrade(vm.latestSnapshotHeight) {
+		refundData = contracts.GetMultisigRefundData(sendBlock.Hash)
+		refundBlockType = ledger.BlockTypeSendCall
+		needRefund = false
+	}
 	refundFlag := false
 	if sendBlock.Amount.Sign() > 0 && sendBlock.Fee.Sign() > 0 && sendBlock.TokenId == ledger.ViteTokenId {
 		refundAmount := new(big.Int).Add(sendBlock.Amount, sendBlock.Fee)
@@ -763,7 +769,7 @@ func (vm *VM) receiveReward(db interfaces.VmDb, block *ledger.AccountBlock, send
 	quotaLeft := uint64(0)
 	quotaAddition := uint64(0)
 	var err error
-	if !types.IsBuiltinContractAddrInUse(block.AccountAddress) {
+	if !ledger.IsBuiltinContractAddrInUse(block.AccountAddress, vm.latestSnapshotHeight) {
 		quotaTotal, quotaAddition, err = quota.GetQuotaForBlock(
 			db,
 			block.AccountAddress,
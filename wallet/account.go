@@ -1,7 +1,12 @@
 package wallet
 
 import (
-	"github.com/vitelabs/go-vite/common/errors"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	walleterrors "github.com/vitelabs/go-vite/common/errors"
 	"github.com/vitelabs/go-vite/common/types"
 	"github.com/vitelabs/go-vite/crypto/ed25519"
 	"github.com/vitelabs/go-vite/interfaces"
@@ -12,6 +17,17 @@ type Account struct {
 	interfaces.Account
 	address types.Address
 	priv    ed25519.PrivateKey
+
+	// the entropy store deriving the account, empty if the account is not from the wallet
+	entropyStore string
+	// the signing policy the account is bound to, nil if the account isn't restricted
+	guard *accountGuard
+}
+
+// accountGuard binds an account to the signing policy of its entropy store
+type accountGuard struct {
+	signing     *signingGuard
+	primaryAddr types.Address
 }
 
 func newAccount(address types.Address, key *derivation.Key) (*Account, error) {
@@ -21,11 +37,20 @@ func newAccount(address types.Address, key *derivation.Key) (*Account, error) {
 	}
 	target := types.PrikeyToAddress(priv)
 	if target != address {
-		panic(errors.NotFound)
+		panic(walleterrors.NotFound)
 	}
 	return &Account{address: address, priv: priv}, nil
 }
 
+func newStoreAccount(address types.Address, key *derivation.Key, entropyStore string) (*Account, error) {
+	account, err := newAccount(address, key)
+	if err != nil {
+		return nil, err
+	}
+	account.entropyStore = entropyStore
+	return account, nil
+}
+
 func NewAccountFromHexKey(hexPriv string) (*Account, error) {
 	key, err := ed25519.HexToPrivateKey(hexPriv)
 	if err != nil {
@@ -55,10 +80,97 @@ func (acct Account) Address() types.Address {
 	return acct.address
 }
 
+// Sign signs the message without checking it. It's denied if the account is bound to a signing policy, sign with
+// SignData or a transaction session instead.
 func (acct Account) Sign(msg []byte) (signData []byte, pub ed25519.PublicKey, err error) {
+	if acct.guard != nil && acct.guard.signing.hasPolicy(acct.guard.primaryAddr) {
+		return nil, nil, errors.Wrap(ErrSigningDenied, "signing a message unchecked is not allowed")
+	}
+	return acct.sign(msg)
+}
+
+func (acct Account) sign(msg []byte) (signData []byte, pub ed25519.PublicKey, err error) {
 	return ed25519.Sign(acct.priv, msg), acct.priv.PubByte(), nil
 }
 
+// SignData signs the data if the signing policy of the account allows it, the request is recorded in the audit
+// trail
+func (acct Account) SignData(data []byte) (signData []byte, pub ed25519.PublicKey, err error) {
+	if acct.guard != nil {
+		req := &SigningRequest{Method: SigningSignData, Address: acct.address}
+		if err := acct.guard.signing.authorize(acct.entropyStore, acct.guard.primaryAddr, req, time.Now()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return acct.sign(data)
+}
+
+// NewTransaction checks the transaction against the signing policy of the account and returns the session to sign
+// it. The amount of the transaction is reserved in the daily limit until the session is committed, which records it
+// in the audit trail, or rolled back, which releases the amount.
+func (acct Account) NewTransaction(req *SigningRequest) (*SigningSession, error) {
+	txReq := *req
+	txReq.Method = SigningCreateTransaction
+	txReq.Address = acct.address
+
+	session := &SigningSession{account: acct}
+	if acct.guard != nil {
+		grant, err := acct.guard.signing.reserve(acct.entropyStore, acct.guard.primaryAddr, &txReq, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		session.grant = grant
+	}
+	return session, nil
+}
+
+// SigningSession signs a transaction allowed by the signing policy, it can't sign after being committed or rolled
+// back
+type SigningSession struct {
+	account Account
+	grant   *signingGrant
+
+	mu       sync.Mutex
+	finished bool
+}
+
+// Sign signs the transaction of the session
+func (s *SigningSession) Sign(msg []byte) (signData []byte, pub ed25519.PublicKey, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return nil, nil, errors.New("signing session is finished")
+	}
+	return s.account.sign(msg)
+}
+
+// Commit records the transaction as signed, it should be called before the signed transaction is sent
+func (s *SigningSession) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return errors.New("signing session is finished")
+	}
+	s.finished = true
+	if s.grant == nil {
+		return nil
+	}
+	return s.grant.commit()
+}
+
+// Rollback records the transaction as failed by the cause and releases its amount from the daily limit
+func (s *SigningSession) Rollback(cause error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished {
+		return
+	}
+	s.finished = true
+	if s.grant != nil {
+		s.grant.rollback(cause)
+	}
+}
+
 func (acct Account) Verify(pub ed25519.PublicKey, message, signdata []byte) error {
 	return ed25519.VerifySig(pub, message, signdata)
 }
@@ -0,0 +1,90 @@
+package entropystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ExportBundle packs the entropy store files into a bundle encrypted by the passphrase. The files are still
+// encrypted by their own passphrases inside the bundle.
+func ExportBundle(files []string, passphrase string, params *KDFParams) ([]byte, error) {
+	if err := params.Check(); err != nil {
+		return nil, err
+	}
+	bundleFiles := make([]*bundleFile, 0, len(files))
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := parseJson(content); err != nil {
+			return nil, fmt.Errorf("invalid entropy store file %v: %v", file, err)
+		}
+		bundleFiles = append(bundleFiles, &bundleFile{Name: filepath.Base(file), Content: content})
+	}
+	data, err := json.Marshal(bundleFiles)
+	if err != nil {
+		return nil, err
+	}
+	c, err := encryptWithKDF(data, passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(bundleJSON{
+		Crypto:    *c,
+		Version:   bundleVersion,
+		Timestamp: time.Now().UTC().Unix(),
+	})
+}
+
+// ImportBundle decrypts the bundle and writes its entropy store files into the dir, the files are named by their
+// primary addresses. The existing files are skipped unless overwrite is set. It returns the paths of the written files.
+func ImportBundle(dir string, bundle []byte, passphrase string, overwrite bool) ([]string, error) {
+	b := new(bundleJSON)
+	if err := json.Unmarshal(bundle, b); err != nil {
+		return nil, err
+	}
+	if b.Version != bundleVersion {
+		return nil, fmt.Errorf("bundle version number error : %v", b.Version)
+	}
+	if err := checkCrypto(&b.Crypto); err != nil {
+		return nil, err
+	}
+	data, err := decryptWithKDF(&b.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var bundleFiles []*bundleFile
+	if err := json.Unmarshal(data, &bundleFiles); err != nil {
+		return nil, err
+	}
+
+	// check all the files before writing any of them
+	filenames := make([]string, len(bundleFiles))
+	for i, f := range bundleFiles {
+		if f == nil {
+			return nil, fmt.Errorf("nil file in bundle")
+		}
+		_, addr, err := parseJson(f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entropy store file %v: %v", f.Name, err)
+		}
+		filenames[i] = FullKeyFileName(dir, *addr)
+	}
+
+	written := make([]string, 0, len(bundleFiles))
+	for i, f := range bundleFiles {
+		if _, err := os.Stat(filenames[i]); err == nil && !overwrite {
+			continue
+		}
+		if err := writeKeyFile(filenames[i], f.Content); err != nil {
+			return written, err
+		}
+		written = append(written, filenames[i])
+	}
+	return written, nil
+}
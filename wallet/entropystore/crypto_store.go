@@ -2,7 +2,6 @@ package entropystore
 
 import (
 	"bytes"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -11,11 +10,8 @@ import (
 	"time"
 
 	"github.com/tyler-smith/go-bip39"
-	"golang.org/x/crypto/scrypt"
 
-	walleterrors "github.com/vitelabs/go-vite/common/errors"
 	"github.com/vitelabs/go-vite/common/types"
-	vcrypto "github.com/vitelabs/go-vite/crypto"
 	"github.com/vitelabs/go-vite/wallet/hd-bip/derivation"
 )
 
@@ -31,8 +27,7 @@ const (
 	scryptR      = 8
 	scryptKeyLen = 32
 
-	aesMode    = "aes-256-gcm"
-	scryptName = "scrypt"
+	aesMode = "aes-256-gcm"
 )
 
 type CryptoStore struct {
@@ -67,8 +62,13 @@ func (ks CryptoStore) ExtractEntropy(passphrase string) ([]byte, error) {
 }
 
 func (ks CryptoStore) StoreEntropy(entropy []byte, primaryAddr types.Address, passphrase string) error {
+	return ks.StoreEntropyWithParams(entropy, primaryAddr, passphrase, nil)
+}
 
-	keyjson, e := EncryptEntropy(entropy, primaryAddr, passphrase)
+// StoreEntropyWithParams writes the entropy store file in the version 2 with the kdf parameters, or in the first
+// version with the standard scrypt parameters if params is nil
+func (ks CryptoStore) StoreEntropyWithParams(entropy []byte, primaryAddr types.Address, passphrase string, params *KDFParams) error {
+	keyjson, e := EncryptEntropyWithParams(entropy, primaryAddr, passphrase, params)
 	if e != nil {
 		return e
 	}
@@ -80,68 +80,92 @@ func (ks CryptoStore) StoreEntropy(entropy []byte, primaryAddr types.Address, pa
 	return nil
 }
 
-func parseJson(keyjson []byte) (k *entropyJSON, kAddress *types.Address, cipherData, nonce, salt []byte, err error) {
-	k = new(entropyJSON)
-	// parse and check entropyJSON params
-	if err := json.Unmarshal(keyjson, k); err != nil {
-		return nil, nil, nil, nil, nil, err
+// Info returns the format version and the kdf parameters of the entropy store file
+func (ks CryptoStore) Info() (*StoreInfo, error) {
+	keyjson, err := ioutil.ReadFile(ks.EntropyStoreFilename)
+	if err != nil {
+		return nil, err
 	}
-	if k.Version != cryptoStoreVersion {
-		return nil, nil, nil, nil, nil, fmt.Errorf("version number error : %v", k.Version)
+	k, addr, err := parseJson(keyjson)
+	if err != nil {
+		return nil, err
 	}
+	return &StoreInfo{PrimaryAddr: *addr, Version: k.Version, KDF: k.Crypto.kdfParams()}, nil
+}
 
-	if !types.IsValidHexAddress(k.PrimaryAddress) {
-		return nil, nil, nil, nil, nil, fmt.Errorf("address invalid ： %v", k.PrimaryAddress)
+// ChangePassphrase encrypts the entropy again with the new passphrase in place, the version and the kdf parameters
+// of the file are kept if params is nil, otherwise the file is written in the version 2 with params.
+func (ks CryptoStore) ChangePassphrase(oldPassphrase, newPassphrase string, params *KDFParams) error {
+	keyjson, err := ioutil.ReadFile(ks.EntropyStoreFilename)
+	if err != nil {
+		return err
 	}
-	addr, err := types.HexToAddress(k.PrimaryAddress)
+	k, addr, err := parseJson(keyjson)
 	if err != nil {
-		return nil, nil, nil, nil, nil, err
+		return err
 	}
-
-	// parse and check  cryptoJSON params
-	if k.Crypto.CipherName != aesMode {
-		return nil, nil, nil, nil, nil, fmt.Errorf("cipherName  error : %v", k.Crypto.CipherName)
+	entropy, err := DecryptEntropy(keyjson, oldPassphrase)
+	if err != nil {
+		return err
 	}
-	if k.Crypto.KDF != scryptName {
-		return nil, nil, nil, nil, nil, fmt.Errorf("scryptName  error : %v", k.Crypto.KDF)
+	var newjson []byte
+	if params == nil {
+		newjson, err = encryptEntropy(entropy, *addr, newPassphrase, k.Crypto.kdfParams(), k.Version)
+	} else {
+		newjson, err = EncryptEntropyWithParams(entropy, *addr, newPassphrase, params)
 	}
-	cipherData, err = hex.DecodeString(k.Crypto.CipherText)
 	if err != nil {
-		return nil, nil, nil, nil, nil, err
+		return err
 	}
-	nonce, err = hex.DecodeString(k.Crypto.Nonce)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
+	return writeKeyFile(ks.EntropyStoreFilename, newjson)
+}
+
+// StoreInfo is the format of an entropy store file
+type StoreInfo struct {
+	PrimaryAddr types.Address `json:"primaryAddr"`
+	Version     int           `json:"version"`
+	KDF         *KDFParams    `json:"kdf"`
+}
+
+func parseJson(keyjson []byte) (k *entropyJSON, kAddress *types.Address, err error) {
+	k = new(entropyJSON)
+	// parse and check entropyJSON params
+	if err := json.Unmarshal(keyjson, k); err != nil {
+		return nil, nil, err
+	}
+	if k.Version != cryptoStoreVersion && k.Version != cryptoStoreVersionV2 {
+		return nil, nil, fmt.Errorf("version number error : %v", k.Version)
 	}
 
-	// parse and check  scryptParams params
-	scryptParams := k.Crypto.ScryptParams
-	salt, err = hex.DecodeString(scryptParams.Salt)
+	if !types.IsValidHexAddress(k.PrimaryAddress) {
+		return nil, nil, fmt.Errorf("address invalid ： %v", k.PrimaryAddress)
+	}
+	addr, err := types.HexToAddress(k.PrimaryAddress)
 	if err != nil {
-		return nil, nil, nil, nil, nil, err
+		return nil, nil, err
 	}
 
-	return k, &addr, cipherData, nonce, salt, nil
+	// the first version only supports scrypt
+	if k.Version == cryptoStoreVersion && k.Crypto.KDF != KDFScrypt {
+		return nil, nil, fmt.Errorf("scryptName  error : %v", k.Crypto.KDF)
+	}
+	if err := checkCrypto(&k.Crypto); err != nil {
+		return nil, nil, err
+	}
+	return k, &addr, nil
 }
 
 func DecryptEntropy(entropyJson []byte, passphrase string) ([]byte, error) {
-	k, kAddress, cipherData, nonce, salt, err := parseJson(entropyJson)
+	k, kAddress, err := parseJson(entropyJson)
 	if err != nil {
 		return nil, err
 	}
-	scryptParams := k.Crypto.ScryptParams
 
-	// begin decrypt
-	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptParams.N, scryptParams.R, scryptParams.P, scryptParams.KeyLen)
+	entropy, err := decryptWithKDF(&k.Crypto, passphrase)
 	if err != nil {
 		return nil, err
 	}
 
-	entropy, err := vcrypto.AesGCMDecrypt(derivedKey[:32], cipherData, []byte(nonce))
-	if err != nil {
-		return nil, walleterrors.ErrDecryptEntropy
-	}
-
 	mnemonic, e := bip39.NewMnemonic(entropy)
 	if e != nil {
 		return nil, e
@@ -161,43 +185,36 @@ func DecryptEntropy(entropyJson []byte, passphrase string) ([]byte, error) {
 	return entropy, nil
 }
 
+// EncryptEntropy encrypts the entropy in the first version with the standard scrypt parameters
 func EncryptEntropy(seed []byte, addr types.Address, passphrase string) ([]byte, error) {
-	n := StandardScryptN
-	p := StandardScryptP
-	pwdArray := []byte(passphrase)
-	salt := vcrypto.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(pwdArray, salt, n, scryptR, p, scryptKeyLen)
-	if err != nil {
-		return nil, err
-	}
-	encryptKey := derivedKey[:32]
+	return encryptEntropy(seed, addr, passphrase, DefaultKDFParams(), cryptoStoreVersion)
+}
 
-	ciphertext, nonce, err := vcrypto.AesGCMEncrypt(encryptKey, seed)
-	if err != nil {
+// EncryptEntropyWithParams encrypts the entropy in the version 2 with the kdf parameters, the version 2 is only
+// written on opt-in since the older nodes can't read it. The first version is written if params is nil.
+func EncryptEntropyWithParams(seed []byte, addr types.Address, passphrase string, params *KDFParams) ([]byte, error) {
+	if params == nil {
+		return EncryptEntropy(seed, addr, passphrase)
+	}
+	if err := params.Check(); err != nil {
 		return nil, err
 	}
+	return encryptEntropy(seed, addr, passphrase, params, cryptoStoreVersionV2)
+}
 
-	ScryptParams := scryptParams{
-		N:      n,
-		R:      scryptR,
-		P:      p,
-		KeyLen: scryptKeyLen,
-		Salt:   hex.EncodeToString(salt),
+func encryptEntropy(seed []byte, addr types.Address, passphrase string, params *KDFParams, version int) ([]byte, error) {
+	if version == cryptoStoreVersion && params.KDF != KDFScrypt {
+		return nil, fmt.Errorf("the first version only supports scrypt")
 	}
-
-	cryptoJSON := cryptoJSON{
-		CipherName:   aesMode,
-		CipherText:   hex.EncodeToString(ciphertext),
-		Nonce:        hex.EncodeToString(nonce),
-		KDF:          scryptName,
-		ScryptParams: ScryptParams,
+	c, err := encryptWithKDF(seed, passphrase, params)
+	if err != nil {
+		return nil, err
 	}
 
 	encryptedKeyJSON := entropyJSON{
-
 		PrimaryAddress: addr.String(),
-		Crypto:         cryptoJSON,
-		Version:        cryptoStoreVersion,
+		Crypto:         *c,
+		Version:        version,
 		Timestamp:      time.Now().UTC().Unix(),
 	}
 
@@ -0,0 +1,248 @@
+package entropystore
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+
+	walleterrors "github.com/vitelabs/go-vite/common/errors"
+	vcrypto "github.com/vitelabs/go-vite/crypto"
+)
+
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+
+	// DefaultArgon2Time, DefaultArgon2Memory and DefaultArgon2Threads are the argon2id costs, using 256MB memory
+	// and taking approximately the same CPU time as the standard scrypt parameters.
+	DefaultArgon2Time    = 3
+	DefaultArgon2Memory  = 256 * 1024 // in KiB
+	DefaultArgon2Threads = 4
+
+	// the maximum costs of the kdf, the files and bundles with larger costs are rejected before the key is derived
+	// since they can be imported by rpc
+	scryptNMax      = 1 << 20
+	scryptRMax      = 8
+	scryptPMax      = 16
+	argon2TimeMax   = 100
+	argon2MemoryMax = 1024 * 1024 // in KiB
+	kdfKeyLenMax    = 64
+
+	kdfSaltLen   = 32
+	argon2KeyLen = 32
+)
+
+// KDFParams is the key derivation function and its costs used to encrypt an entropy store
+type KDFParams struct {
+	KDF           string `json:"kdf"`
+	ScryptN       int    `json:"scryptN,omitempty"`
+	ScryptP       int    `json:"scryptP,omitempty"`
+	Argon2Time    uint32 `json:"argon2Time,omitempty"`
+	Argon2Memory  uint32 `json:"argon2Memory,omitempty"` // in KiB
+	Argon2Threads uint8  `json:"argon2Threads,omitempty"`
+}
+
+// DefaultKDFParams returns the scrypt parameters used before the keystore v2
+func DefaultKDFParams() *KDFParams {
+	return &KDFParams{KDF: KDFScrypt, ScryptN: StandardScryptN, ScryptP: StandardScryptP}
+}
+
+// DefaultArgon2idParams returns the default argon2id parameters
+func DefaultArgon2idParams() *KDFParams {
+	return &KDFParams{KDF: KDFArgon2id, Argon2Time: DefaultArgon2Time, Argon2Memory: DefaultArgon2Memory, Argon2Threads: DefaultArgon2Threads}
+}
+
+// Check fills the unset costs with the defaults of the kdf and validates them
+func (p *KDFParams) Check() error {
+	switch p.KDF {
+	case "", KDFScrypt:
+		p.KDF = KDFScrypt
+		if p.ScryptN == 0 {
+			p.ScryptN = StandardScryptN
+		}
+		if p.ScryptP == 0 {
+			p.ScryptP = StandardScryptP
+		}
+		if err := checkScryptCosts(p.ScryptN, scryptR, p.ScryptP); err != nil {
+			return err
+		}
+	case KDFArgon2id:
+		if p.Argon2Time == 0 {
+			p.Argon2Time = DefaultArgon2Time
+		}
+		if p.Argon2Memory == 0 {
+			p.Argon2Memory = DefaultArgon2Memory
+		}
+		if p.Argon2Threads == 0 {
+			p.Argon2Threads = DefaultArgon2Threads
+		}
+		if err := checkArgon2Costs(p.Argon2Time, p.Argon2Memory, p.Argon2Threads); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown kdf %v", p.KDF)
+	}
+	return nil
+}
+
+// encryptWithKDF encrypts the data by the key derived from the passphrase
+func encryptWithKDF(data []byte, passphrase string, params *KDFParams) (*cryptoJSON, error) {
+	salt := vcrypto.GetEntropyCSPRNG(kdfSaltLen)
+	c := &cryptoJSON{CipherName: aesMode, KDF: params.KDF}
+	var derivedKey []byte
+	switch params.KDF {
+	case KDFScrypt:
+		var err error
+		derivedKey, err = scrypt.Key([]byte(passphrase), salt, params.ScryptN, scryptR, params.ScryptP, scryptKeyLen)
+		if err != nil {
+			return nil, err
+		}
+		c.ScryptParams = &scryptParams{
+			N:      params.ScryptN,
+			R:      scryptR,
+			P:      params.ScryptP,
+			KeyLen: scryptKeyLen,
+			Salt:   hex.EncodeToString(salt),
+		}
+	case KDFArgon2id:
+		derivedKey = argon2.IDKey([]byte(passphrase), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, argon2KeyLen)
+		c.Argon2Params = &argon2Params{
+			Time:    params.Argon2Time,
+			Memory:  params.Argon2Memory,
+			Threads: params.Argon2Threads,
+			KeyLen:  argon2KeyLen,
+			Salt:    hex.EncodeToString(salt),
+		}
+	default:
+		return nil, fmt.Errorf("unknown kdf %v", params.KDF)
+	}
+
+	ciphertext, nonce, err := vcrypto.AesGCMEncrypt(derivedKey[:32], data)
+	if err != nil {
+		return nil, err
+	}
+	c.CipherText = hex.EncodeToString(ciphertext)
+	c.Nonce = hex.EncodeToString(nonce)
+	return c, nil
+}
+
+// decryptWithKDF decrypts the data with the key derived from the passphrase, ErrDecryptEntropy is returned if the
+// passphrase is wrong
+func decryptWithKDF(c *cryptoJSON, passphrase string) ([]byte, error) {
+	if err := checkCrypto(c); err != nil {
+		return nil, err
+	}
+	cipherData, err := hex.DecodeString(c.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(c.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	var derivedKey []byte
+	switch c.KDF {
+	case KDFScrypt:
+		p := c.ScryptParams
+		salt, err := hex.DecodeString(p.Salt)
+		if err != nil {
+			return nil, err
+		}
+		derivedKey, err = scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, p.KeyLen)
+		if err != nil {
+			return nil, err
+		}
+	case KDFArgon2id:
+		p := c.Argon2Params
+		salt, err := hex.DecodeString(p.Salt)
+		if err != nil {
+			return nil, err
+		}
+		derivedKey = argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	default:
+		return nil, fmt.Errorf("unknown kdf %v", c.KDF)
+	}
+	if len(derivedKey) < 32 {
+		return nil, fmt.Errorf("derived key too short")
+	}
+
+	data, err := vcrypto.AesGCMDecrypt(derivedKey[:32], cipherData, nonce)
+	if err != nil {
+		return nil, walleterrors.ErrDecryptEntropy
+	}
+	return data, nil
+}
+
+// checkCrypto validates the cipher and the kdf parameters of the crypto section
+func checkCrypto(c *cryptoJSON) error {
+	if c.CipherName != aesMode {
+		return fmt.Errorf("cipherName  error : %v", c.CipherName)
+	}
+	switch c.KDF {
+	case KDFScrypt:
+		p := c.ScryptParams
+		if p == nil {
+			return fmt.Errorf("missing scrypt params")
+		}
+		if err := checkScryptCosts(p.N, p.R, p.P); err != nil {
+			return err
+		}
+		if p.KeyLen < 32 || p.KeyLen > kdfKeyLenMax {
+			return fmt.Errorf("scrypt keylen must be in [32, %v]", kdfKeyLenMax)
+		}
+	case KDFArgon2id:
+		p := c.Argon2Params
+		if p == nil {
+			return fmt.Errorf("missing argon2 params")
+		}
+		if err := checkArgon2Costs(p.Time, p.Memory, p.Threads); err != nil {
+			return err
+		}
+		if p.KeyLen < 32 || p.KeyLen > kdfKeyLenMax {
+			return fmt.Errorf("argon2 keylen must be in [32, %v]", kdfKeyLenMax)
+		}
+	default:
+		return fmt.Errorf("kdf error : %v", c.KDF)
+	}
+	return nil
+}
+
+func checkScryptCosts(n, r, p int) error {
+	if n <= 1 || n&(n-1) != 0 || n > scryptNMax {
+		return fmt.Errorf("scrypt N must be a power of 2 not larger than %v", scryptNMax)
+	}
+	if r < 1 || r > scryptRMax {
+		return fmt.Errorf("scrypt R must be in [1, %v]", scryptRMax)
+	}
+	if p < 1 || p > scryptPMax {
+		return fmt.Errorf("scrypt P must be in [1, %v]", scryptPMax)
+	}
+	return nil
+}
+
+func checkArgon2Costs(time, memory uint32, threads uint8) error {
+	if time < 1 || time > argon2TimeMax {
+		return fmt.Errorf("argon2 time must be in [1, %v]", argon2TimeMax)
+	}
+	if threads == 0 {
+		return fmt.Errorf("argon2 threads must be positive")
+	}
+	if memory < 8*uint32(threads) || memory > argon2MemoryMax {
+		return fmt.Errorf("argon2 memory must be in [%v, %v] KiB", 8*uint32(threads), argon2MemoryMax)
+	}
+	return nil
+}
+
+// kdfParams returns the parameters the crypto section was encrypted with
+func (c *cryptoJSON) kdfParams() *KDFParams {
+	switch c.KDF {
+	case KDFScrypt:
+		return &KDFParams{KDF: KDFScrypt, ScryptN: c.ScryptParams.N, ScryptP: c.ScryptParams.P}
+	case KDFArgon2id:
+		return &KDFParams{KDF: KDFArgon2id, Argon2Time: c.Argon2Params.Time, Argon2Memory: c.Argon2Params.Memory, Argon2Threads: c.Argon2Params.Threads}
+	}
+	return nil
+}
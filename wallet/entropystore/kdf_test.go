@@ -0,0 +1,175 @@
+package entropystore_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tyler-smith/go-bip39"
+
+	walleterrors "github.com/vitelabs/go-vite/common/errors"
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/wallet/entropystore"
+	"github.com/vitelabs/go-vite/wallet/hd-bip/derivation"
+)
+
+var (
+	testScryptParams = &entropystore.KDFParams{KDF: entropystore.KDFScrypt, ScryptN: 1 << 10}
+	testArgon2Params = &entropystore.KDFParams{KDF: entropystore.KDFArgon2id, Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1}
+)
+
+func testEntropy(t *testing.T) ([]byte, types.Address) {
+	entropy, _ := hex.DecodeString(TestEntropy)
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := derivation.GetPrimaryAddress(bip39.NewSeed(mnemonic, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entropy, *addr
+}
+
+func TestEncryptEntropyWithParams(t *testing.T) {
+	entropy, addr := testEntropy(t)
+	for _, params := range []*entropystore.KDFParams{testScryptParams, testArgon2Params} {
+		keyjson, err := entropystore.EncryptEntropyWithParams(entropy, addr, "123456", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := entropystore.DecryptEntropy(keyjson, "123456")
+		assert.NoError(t, err)
+		assert.Equal(t, entropy, decrypted)
+		_, err = entropystore.DecryptEntropy(keyjson, "1234567")
+		assert.Equal(t, walleterrors.ErrDecryptEntropy, err)
+	}
+
+	// the first version is written by default and is still readable
+	keyjson, _ := entropystore.EncryptEntropyWithParams(entropy, addr, "123456", nil)
+	var m map[string]interface{}
+	json.Unmarshal(keyjson, &m)
+	assert.Equal(t, float64(1), m["seedstoreversion"])
+	decrypted, err := entropystore.DecryptEntropy(keyjson, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, entropy, decrypted)
+
+	// the costs above the maximums are rejected before the key is derived
+	keyjson, _ = entropystore.EncryptEntropyWithParams(entropy, addr, "123456", testScryptParams)
+	json.Unmarshal(keyjson, &m)
+	m["crypto"].(map[string]interface{})["scryptparams"].(map[string]interface{})["n"] = 1 << 30
+	keyjson, _ = json.Marshal(m)
+	_, err = entropystore.DecryptEntropy(keyjson, "123456")
+	assert.Error(t, err)
+	assert.NotEqual(t, walleterrors.ErrDecryptEntropy, err)
+
+	assert.Error(t, (&entropystore.KDFParams{KDF: entropystore.KDFScrypt, ScryptN: 1000}).Check())
+	assert.Error(t, (&entropystore.KDFParams{KDF: "pbkdf2"}).Check())
+}
+
+func TestCryptoStore_ChangePassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	entropy, addr := testEntropy(t)
+	store := entropystore.CryptoStore{EntropyStoreFilename: entropystore.FullKeyFileName(dir, addr)}
+	assert.NoError(t, store.StoreEntropyWithParams(entropy, addr, "123456", testScryptParams))
+
+	assert.Equal(t, walleterrors.ErrDecryptEntropy, store.ChangePassphrase("1234567", "abcdef", nil))
+	assert.NoError(t, store.ChangePassphrase("123456", "abcdef", nil))
+	info, err := store.Info()
+	assert.NoError(t, err)
+	assert.Equal(t, testScryptParams.ScryptN, info.KDF.ScryptN)
+	_, err = store.ExtractEntropy("123456")
+	assert.Error(t, err)
+	extracted, err := store.ExtractEntropy("abcdef")
+	assert.NoError(t, err)
+	assert.Equal(t, entropy, extracted)
+
+	// rotate the kdf
+	assert.NoError(t, store.ChangePassphrase("abcdef", "abcdef", testArgon2Params))
+	info, err = store.Info()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, info.Version)
+	assert.Equal(t, entropystore.KDFArgon2id, info.KDF.KDF)
+	extracted, err = store.ExtractEntropy("abcdef")
+	assert.NoError(t, err)
+	assert.Equal(t, entropy, extracted)
+}
+
+func TestBundle(t *testing.T) {
+	srcDir, _ := ioutil.TempDir("", "keystore")
+	dstDir, _ := ioutil.TempDir("", "keystore")
+	defer os.RemoveAll(srcDir)
+	defer os.RemoveAll(dstDir)
+
+	entropy, addr := testEntropy(t)
+	filename := entropystore.FullKeyFileName(srcDir, addr)
+	store := entropystore.CryptoStore{EntropyStoreFilename: filename}
+	assert.NoError(t, store.StoreEntropyWithParams(entropy, addr, "123456", testScryptParams))
+
+	bundle, err := entropystore.ExportBundle([]string{filename}, "bundle", testArgon2Params)
+	assert.NoError(t, err)
+	_, err = entropystore.ImportBundle(dstDir, bundle, "wrong", false)
+	assert.Equal(t, walleterrors.ErrDecryptEntropy, err)
+
+	files, err := entropystore.ImportBundle(dstDir, bundle, "bundle", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dstDir, addr.Hex())}, files)
+	imported := entropystore.CryptoStore{EntropyStoreFilename: files[0]}
+	extracted, err := imported.ExtractEntropy("123456")
+	assert.NoError(t, err)
+	assert.Equal(t, entropy, extracted)
+
+	// the existing files are skipped
+	files, err = entropystore.ImportBundle(dstDir, bundle, "bundle", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(files))
+
+	// the bundle with the costs above the maximums is rejected
+	var m map[string]interface{}
+	json.Unmarshal(bundle, &m)
+	m["crypto"].(map[string]interface{})["argon2params"].(map[string]interface{})["memory"] = 1 << 30
+	bundle, _ = json.Marshal(m)
+	_, err = entropystore.ImportBundle(dstDir, bundle, "bundle", true)
+	assert.Error(t, err)
+	assert.NotEqual(t, walleterrors.ErrDecryptEntropy, err)
+}
+
+func TestManager_UpgradeOnUnlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// write a file of the first version
+	entropy, addr := testEntropy(t)
+	keyjson, _ := entropystore.EncryptEntropyWithParams(entropy, addr, "123456", testScryptParams)
+	var m map[string]interface{}
+	json.Unmarshal(keyjson, &m)
+	m["seedstoreversion"] = 1
+	keyjson, _ = json.Marshal(m)
+	filename := entropystore.FullKeyFileName(dir, addr)
+	assert.NoError(t, ioutil.WriteFile(filename, keyjson, 0600))
+
+	manager := entropystore.NewManager(filename, addr, entropystore.DefaultMaxIndex)
+	manager.SetUpgradeOnUnlock(testArgon2Params)
+	assert.Error(t, manager.Unlock("1234567"))
+	info, _ := manager.Info()
+	assert.Equal(t, 1, info.Version)
+
+	assert.NoError(t, manager.Unlock("123456"))
+	info, _ = manager.Info()
+	assert.Equal(t, 2, info.Version)
+	assert.Equal(t, entropystore.KDFArgon2id, info.KDF.KDF)
+	manager.Lock()
+	assert.NoError(t, manager.Unlock("123456"))
+}
@@ -44,6 +44,9 @@ type Manager struct {
 
 	unlockChangedLis func(event UnlockEvent)
 
+	// the kdf parameters to upgrade the old version file with when it's unlocked, nil if upgrade is not enabled
+	upgradeParams *KDFParams
+
 	log log15.Logger
 }
 
@@ -105,6 +108,10 @@ func (km *Manager) Unlock(passphrase string) error {
 	km.unlockedSeed = seed
 	km.unlockedEntropy = entropy
 
+	if km.upgradeParams != nil {
+		km.upgrade(passphrase)
+	}
+
 	if km.unlockChangedLis != nil {
 		km.unlockChangedLis(UnlockEvent{
 			EntropyStoreFile: km.GetEntropyStoreFile(),
@@ -114,6 +121,40 @@ func (km *Manager) Unlock(passphrase string) error {
 	return nil
 }
 
+// SetUpgradeOnUnlock makes the entropy store file be upgraded to the latest version with the kdf parameters when
+// it's unlocked, the upgrade is disabled if params is nil
+func (km *Manager) SetUpgradeOnUnlock(params *KDFParams) {
+	km.upgradeParams = params
+}
+
+func (km *Manager) upgrade(passphrase string) {
+	info, err := km.ks.Info()
+	if err != nil {
+		km.log.Error("read entropy store info", "file", km.GetEntropyStoreFile(), "err", err)
+		return
+	}
+	if info.Version >= cryptoStoreVersionV2 {
+		return
+	}
+	params := *km.upgradeParams
+	if err := km.ks.ChangePassphrase(passphrase, passphrase, &params); err != nil {
+		km.log.Error("upgrade entropy store", "file", km.GetEntropyStoreFile(), "err", err)
+		return
+	}
+	km.log.Info("entropy store upgraded", "file", km.GetEntropyStoreFile(), "version", cryptoStoreVersionV2, "kdf", params.KDF)
+}
+
+// ChangePassphrase encrypts the entropy store file with the new passphrase, the kdf parameters of the file are kept
+// if params is nil. The unlock state of the store is not changed.
+func (km *Manager) ChangePassphrase(oldPassphrase, newPassphrase string, params *KDFParams) error {
+	return km.ks.ChangePassphrase(oldPassphrase, newPassphrase, params)
+}
+
+// Info returns the format version and the kdf parameters of the entropy store file
+func (km *Manager) Info() (*StoreInfo, error) {
+	return km.ks.Info()
+}
+
 func (km *Manager) Lock() {
 
 	km.unlockedSeed = nil
@@ -225,6 +266,12 @@ func (km Manager) ExtractMnemonic(passphrase string) (string, error) {
 }
 
 func StoreNewEntropy(storeDir string, mnemonic string, pwd string, maxSearchIndex uint32) (*Manager, error) {
+	return StoreNewEntropyWithParams(storeDir, mnemonic, pwd, maxSearchIndex, nil)
+}
+
+// StoreNewEntropyWithParams writes the entropy store file in the version 2 with the kdf parameters, or in the first
+// version if params is nil
+func StoreNewEntropyWithParams(storeDir string, mnemonic string, pwd string, maxSearchIndex uint32, params *KDFParams) (*Manager, error) {
 	entropy, e := bip39.EntropyFromMnemonic(mnemonic)
 	if e != nil {
 		return nil, e
@@ -234,7 +281,7 @@ func StoreNewEntropy(storeDir string, mnemonic string, pwd string, maxSearchInde
 
 	filename := FullKeyFileName(storeDir, *primaryAddress)
 	ss := CryptoStore{filename}
-	e = ss.StoreEntropy(entropy, *primaryAddress, pwd)
+	e = ss.StoreEntropyWithParams(entropy, *primaryAddress, pwd, params)
 	if e != nil {
 		return nil, e
 	}
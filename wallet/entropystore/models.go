@@ -2,6 +2,10 @@ package entropystore
 
 const (
 	cryptoStoreVersion = 1
+	// cryptoStoreVersionV2 stores the kdf with its parameters, scrypt or argon2id
+	cryptoStoreVersionV2 = 2
+
+	bundleVersion = 1
 )
 
 type entropyJSON struct {
@@ -12,11 +16,12 @@ type entropyJSON struct {
 }
 
 type cryptoJSON struct {
-	CipherName   string       `json:"ciphername"`
-	CipherText   string       `json:"ciphertext"`
-	Nonce        string       `json:"nonce"`
-	KDF          string       `json:"kdf"`
-	ScryptParams scryptParams `json:"scryptparams"`
+	CipherName   string        `json:"ciphername"`
+	CipherText   string        `json:"ciphertext"`
+	Nonce        string        `json:"nonce"`
+	KDF          string        `json:"kdf"`
+	ScryptParams *scryptParams `json:"scryptparams,omitempty"`
+	Argon2Params *argon2Params `json:"argon2params,omitempty"`
 }
 
 type scryptParams struct {
@@ -26,3 +31,23 @@ type scryptParams struct {
 	KeyLen int    `json:"keylen"`
 	Salt   string `json:"salt"`
 }
+
+type argon2Params struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"keylen"`
+	Salt    string `json:"salt"`
+}
+
+// bundleJSON is an encrypted export of entropy store files
+type bundleJSON struct {
+	Crypto    cryptoJSON `json:"crypto"`
+	Version   int        `json:"bundleversion"`
+	Timestamp int64      `json:"timestamp"`
+}
+
+type bundleFile struct {
+	Name    string `json:"name"`
+	Content []byte `json:"content"`
+}
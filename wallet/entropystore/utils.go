@@ -31,7 +31,7 @@ func IsMayValidEntropystoreFile(path string) (bool, *types.Address, error) {
 	if err != nil {
 		return false, nil, err
 	}
-	_, addr, _, _, _, err := parseJson(b)
+	_, addr, err := parseJson(b)
 	if err != nil {
 		return false, nil, err
 	}
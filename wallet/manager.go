@@ -25,6 +25,8 @@ type Manager struct {
 	unlockChangedLis    map[int]func(event entropystore.UnlockEvent)
 	mutex               sync.Mutex
 
+	signing *signingGuard
+
 	log log15.Logger
 }
 
@@ -41,6 +43,7 @@ func New(config *config.Wallet) *Manager {
 		config:              config,
 		unlockChangedLis:    make(map[int]func(event entropystore.UnlockEvent)),
 		entropyStoreManager: make(map[string]*entropystore.Manager),
+		signing:             newSigningGuard(config.DataDir),
 
 		log: log15.New("module", "wallet"),
 	}
@@ -98,6 +101,7 @@ func (m *Manager) RefreshCache() {
 	}
 }
 
+// Account returns the unlocked account of the address, it's restricted by the signing policy of its entropy store
 func (m Manager) Account(address types.Address) (*Account, error) {
 	for _, em := range m.entropyStoreManager {
 		if em.IsUnlocked() {
@@ -109,11 +113,31 @@ func (m Manager) Account(address types.Address) (*Account, error) {
 				return nil, err
 			}
 
-			return newAccount(address, key)
+			return m.bind(newStoreAccount(address, key, em.GetEntropyStoreFile()))
 		}
 	}
 	return nil, walleterrors.ErrAddressNotFound
 }
+
+// ReceiveAccount returns the unlocked account of the address to sign the receive blocks of the auto receive. A
+// receive block spends nothing, so the account isn't restricted by the signing policy.
+func (m Manager) ReceiveAccount(address types.Address) (*Account, error) {
+	for _, em := range m.entropyStoreManager {
+		if em.IsUnlocked() {
+			key, _, err := em.FindAddr(address)
+			if err == walleterrors.ErrAddressNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			return newStoreAccount(address, key, em.GetEntropyStoreFile())
+		}
+	}
+	return nil, walleterrors.ErrAddressNotFound
+}
+
+// AccountAtIndex returns the account producing the snapshot blocks, which isn't restricted by the signing policy
 func (m Manager) AccountAtIndex(entryPath string, target types.Address, index uint32) (*Account, error) {
 	manager, err := m.GetEntropyStoreManager(entryPath)
 	if err != nil {
@@ -131,16 +155,18 @@ func (m Manager) AccountAtIndex(entryPath string, target types.Address, index ui
 		return nil, errors.New("address do not match.")
 	}
 
-	return newAccount(target, key)
+	return newStoreAccount(target, key, manager.GetEntropyStoreFile())
 }
 
+// AccountSearch returns the account of the address with the passphrase of its entropy store, it's restricted by the
+// signing policy of the store
 func (m Manager) AccountSearch(entryPath *string, target types.Address, passphrase string) (*Account, error) {
 	if entryPath == nil {
-		_, key, _, err := m.GlobalFindAddrWithPassphrase(target, passphrase)
+		path, key, _, err := m.GlobalFindAddrWithPassphrase(target, passphrase)
 		if err != nil {
 			return nil, err
 		}
-		return newAccount(target, key)
+		return m.bind(newStoreAccount(target, key, path))
 	}
 	manager, err := m.GetEntropyStoreManager(*entryPath)
 	if err != nil {
@@ -154,7 +180,7 @@ func (m Manager) AccountSearch(entryPath *string, target types.Address, passphra
 	if err == walleterrors.ErrAddressNotFound {
 		return nil, err
 	}
-	return newAccount(target, key)
+	return m.bind(newStoreAccount(target, key, manager.GetEntropyStoreFile()))
 }
 
 func (m Manager) GlobalFindAddr(targetAdr types.Address) (path string, key *derivation.Key, index uint32, err error) {
@@ -252,14 +278,12 @@ func (m *Manager) AddEntropyStore(entropyStore string) error {
 	if _, ok := m.entropyStoreManager[absPath]; ok {
 		return nil
 	}
-	m.entropyStoreManager[absPath] = entropystore.NewManager(absPath, *addr, m.config.MaxSearchIndex)
-	m.entropyStoreManager[absPath].SetLockEventListener(func(event entropystore.UnlockEvent) {
-		for _, lis := range m.unlockChangedLis {
-			if lis != nil {
-				lis(event)
-			}
-		}
-	})
+	em := entropystore.NewManager(absPath, *addr, m.config.MaxSearchIndex)
+	m.entropyStoreManager[absPath] = em
+	em.SetLockEventListener(m.newLockEventListener(em))
+	if m.config.KeystoreUpgrade {
+		em.SetUpgradeOnUnlock(m.upgradeKDFParams())
+	}
 	return nil
 }
 
@@ -277,18 +301,15 @@ func (m *Manager) RemoveEntropyStore(entropyStore string) {
 }
 
 func (m *Manager) RecoverEntropyStoreFromMnemonic(mnemonic string, passphrase string) (em *entropystore.Manager, err error) {
-	sm, e := entropystore.StoreNewEntropy(m.config.DataDir, mnemonic, passphrase, entropystore.DefaultMaxIndex)
+	sm, e := entropystore.StoreNewEntropyWithParams(m.config.DataDir, mnemonic, passphrase, entropystore.DefaultMaxIndex, m.kdfParams())
 	if e != nil {
 		return nil, e
 	}
 	m.entropyStoreManager[sm.GetEntropyStoreFile()] = sm
-	sm.SetLockEventListener(func(event entropystore.UnlockEvent) {
-		for _, lis := range m.unlockChangedLis {
-			if lis != nil {
-				lis(event)
-			}
-		}
-	})
+	sm.SetLockEventListener(m.newLockEventListener(sm))
+	if m.config.KeystoreUpgrade {
+		sm.SetUpgradeOnUnlock(m.upgradeKDFParams())
+	}
 	return sm, nil
 }
 
@@ -309,11 +330,89 @@ func (m *Manager) NewMnemonicAndEntropyStore(passphrase string) (mnemonic string
 	return mnemonic, em, nil
 }
 
+// kdfParams returns the configured kdf parameters of the new entropy store files, nil if no kdf is configured and
+// the files are written in the first version
+func (m *Manager) kdfParams() *entropystore.KDFParams {
+	if m.config.KeystoreKDF == "" {
+		return nil
+	}
+	return &entropystore.KDFParams{
+		KDF:          m.config.KeystoreKDF,
+		ScryptN:      m.config.KeystoreScryptN,
+		Argon2Time:   m.config.KeystoreArgon2Time,
+		Argon2Memory: m.config.KeystoreArgon2Memory,
+	}
+}
+
+// upgradeKDFParams returns the kdf parameters to upgrade the entropy store files with, the standard scrypt parameters
+// are used if no kdf is configured
+func (m *Manager) upgradeKDFParams() *entropystore.KDFParams {
+	if params := m.kdfParams(); params != nil {
+		return params
+	}
+	return entropystore.DefaultKDFParams()
+}
+
+// ChangePassphrase encrypts the entropy store with the new passphrase in place, the kdf parameters of the file are
+// kept if params is nil
+func (m *Manager) ChangePassphrase(entropyStore string, oldPassphrase, newPassphrase string, params *entropystore.KDFParams) error {
+	manager, err := m.GetEntropyStoreManager(entropyStore)
+	if err != nil {
+		return err
+	}
+	return manager.ChangePassphrase(oldPassphrase, newPassphrase, params)
+}
+
+// UpgradeEntropyStore encrypts the entropy store in the version 2 with the kdf parameters, or with the
+// configured ones if params is nil
+func (m *Manager) UpgradeEntropyStore(entropyStore string, passphrase string, params *entropystore.KDFParams) error {
+	if params == nil {
+		params = m.upgradeKDFParams()
+	}
+	return m.ChangePassphrase(entropyStore, passphrase, passphrase, params)
+}
+
+// GetEntropyStoreInfo returns the format version and the kdf parameters of the entropy store file
+func (m *Manager) GetEntropyStoreInfo(entropyStore string) (*entropystore.StoreInfo, error) {
+	manager, err := m.GetEntropyStoreManager(entropyStore)
+	if err != nil {
+		return nil, err
+	}
+	return manager.Info()
+}
+
+// ExportEntropyStores exports the entropy store files in the standard dir as a bundle encrypted by the passphrase
+func (m *Manager) ExportEntropyStores(passphrase string) ([]byte, error) {
+	files, err := m.ListEntropyFilesInStandardDir()
+	if err != nil {
+		return nil, err
+	}
+	return entropystore.ExportBundle(files, passphrase, m.upgradeKDFParams())
+}
+
+// ImportEntropyStores writes the entropy store files of the bundle into the standard dir and indexes them, the
+// existing files are skipped unless overwrite is set
+func (m *Manager) ImportEntropyStores(bundle []byte, passphrase string, overwrite bool) ([]string, error) {
+	files, err := entropystore.ImportBundle(m.config.DataDir, bundle, passphrase, overwrite)
+	for _, file := range files {
+		if e := m.AddEntropyStore(file); e != nil {
+			return files, e
+		}
+	}
+	return files, err
+}
+
 func (m Manager) GetDataDir() string {
 	return m.config.DataDir
 }
 
 func (m *Manager) Start() error {
+	if params := m.kdfParams(); params != nil {
+		if e := params.Check(); e != nil {
+			m.log.Error("wallet start invalid keystore kdf", "err", e)
+			return e
+		}
+	}
 	m.entropyStoreManager = make(map[string]*entropystore.Manager)
 	files, e := m.ListEntropyFilesInStandardDir()
 	if e != nil {
@@ -326,6 +425,10 @@ func (m *Manager) Start() error {
 			return e
 		}
 	}
+	if e = m.signing.open(); e != nil {
+		m.log.Error("wallet start open signing policies", "err", e)
+		return e
+	}
 	return nil
 }
 
@@ -335,6 +438,58 @@ func (m *Manager) Stop() {
 		em.RemoveUnlockChangeChannel()
 	}
 	m.entropyStoreManager = nil
+	m.signing.close()
+}
+
+// newLockEventListener forwards the lock events of the entropy store to the listeners, and locks the store after
+// the unlock timeout of its signing policy
+func (m *Manager) newLockEventListener(em *entropystore.Manager) func(event entropystore.UnlockEvent) {
+	return func(event entropystore.UnlockEvent) {
+		m.signing.onUnlockChanged(event, em.Lock)
+		for _, lis := range m.unlockChangedLis {
+			if lis != nil {
+				lis(event)
+			}
+		}
+	}
+}
+
+// SetSigningPolicy attaches the policy to the entropy store, or removes its policy if policy is nil.
+// The passphrase of the store is required.
+func (m *Manager) SetSigningPolicy(entropyStore string, passphrase string, policy *SigningPolicy) error {
+	manager, err := m.GetEntropyStoreManager(entropyStore)
+	if err != nil {
+		return err
+	}
+	if _, _, err := manager.DeriveForIndexPathWithPassphrase(0, passphrase); err != nil {
+		return err
+	}
+	if policy != nil {
+		if err := policy.check(); err != nil {
+			return err
+		}
+	}
+	return m.signing.setPolicy(manager.GetPrimaryAddr(), policy)
+}
+
+// GetSigningPolicy returns the policy of the entropy store, nil if it has no policy
+func (m *Manager) GetSigningPolicy(entropyStore string) (*SigningPolicy, error) {
+	manager, err := m.GetEntropyStoreManager(entropyStore)
+	if err != nil {
+		return nil, err
+	}
+	return m.signing.getPolicy(manager.GetPrimaryAddr()), nil
+}
+
+// bind restricts the account of the entropy store by the signing policy of the store
+func (m *Manager) bind(account *Account, err error) (*Account, error) {
+	if err != nil {
+		return nil, err
+	}
+	if em, ok := m.entropyStoreManager[account.entropyStore]; ok {
+		account.guard = &accountGuard{signing: m.signing, primaryAddr: em.GetPrimaryAddr()}
+	}
+	return account, nil
 }
 
 func (m Manager) AddLockEventListener(lis func(event entropystore.UnlockEvent)) int {
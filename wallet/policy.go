@@ -0,0 +1,551 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vitelabs/go-vite/common/types"
+	"github.com/vitelabs/go-vite/log15"
+	"github.com/vitelabs/go-vite/wallet/entropystore"
+)
+
+const (
+	SigningCreateTransaction = "createTransaction"
+	SigningSignData          = "signData"
+
+	signingDirName     = "signing"
+	policiesFileName   = "policies.json"
+	auditTrailFileName = "audit.log"
+
+	selectorSize = 4
+)
+
+var ErrSigningDenied = errors.New("denied by the signing policy")
+
+// SpendLimit is the max amount of a token sent in a UTC day
+type SpendLimit struct {
+	TokenId types.TokenTypeId `json:"tokenId"`
+	Amount  *big.Int          `json:"amount"`
+}
+
+// AddressPolicy restricts the transactions of an address
+type AddressPolicy struct {
+	// the tokens not listed have no limit
+	DailyLimits []*SpendLimit `json:"dailyLimits,omitempty"`
+	// the recipients allowed, all recipients are allowed if empty
+	AllowedRecipients []types.Address `json:"allowedRecipients,omitempty"`
+	// the hex ABI selectors of the contract methods allowed to call, all methods are allowed if empty
+	AllowedMethods []string `json:"allowedMethods,omitempty"`
+}
+
+// AccountPolicy is the policy of a specific address
+type AccountPolicy struct {
+	Address types.Address `json:"address"`
+	AddressPolicy
+}
+
+// SigningPolicy is attached to an entropy store, every CreateTransaction and SignData of its accounts is checked
+// against it. The accounts of a store with a policy can't export their private keys without the passphrase.
+type SigningPolicy struct {
+	// the policy of the addresses not in Accounts, nil means no restriction
+	Default  *AddressPolicy   `json:"default,omitempty"`
+	Accounts []*AccountPolicy `json:"accounts,omitempty"`
+	// whether SignData is allowed, the data signed can't be checked by the policy
+	AllowSignData bool `json:"allowSignData"`
+	// the store is locked automatically after being unlocked for the seconds, 0 means no timeout
+	UnlockTimeout uint64 `json:"unlockTimeout"`
+}
+
+func (p *SigningPolicy) check() error {
+	policies := make([]*AddressPolicy, 0, len(p.Accounts)+1)
+	if p.Default != nil {
+		policies = append(policies, p.Default)
+	}
+	addrSet := make(map[types.Address]struct{}, len(p.Accounts))
+	for _, accountPolicy := range p.Accounts {
+		if accountPolicy == nil {
+			return errors.New("nil account policy")
+		}
+		if _, ok := addrSet[accountPolicy.Address]; ok {
+			return fmt.Errorf("duplicate policy of %s", accountPolicy.Address)
+		}
+		addrSet[accountPolicy.Address] = struct{}{}
+		policies = append(policies, &accountPolicy.AddressPolicy)
+	}
+	for _, addrPolicy := range policies {
+		tokenSet := make(map[types.TokenTypeId]struct{}, len(addrPolicy.DailyLimits))
+		for _, limit := range addrPolicy.DailyLimits {
+			if limit == nil || limit.Amount == nil || limit.Amount.Sign() < 0 {
+				return errors.New("invalid daily limit")
+			}
+			if _, ok := tokenSet[limit.TokenId]; ok {
+				return fmt.Errorf("duplicate daily limit of %s", limit.TokenId)
+			}
+			tokenSet[limit.TokenId] = struct{}{}
+		}
+		for _, method := range addrPolicy.AllowedMethods {
+			if selector, err := hex.DecodeString(method); err != nil || len(selector) != selectorSize {
+				return fmt.Errorf("invalid method selector %q, should be %d bytes in hex", method, selectorSize)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *SigningPolicy) addressPolicy(addr types.Address) *AddressPolicy {
+	for _, accountPolicy := range p.Accounts {
+		if accountPolicy.Address == addr {
+			return &accountPolicy.AddressPolicy
+		}
+	}
+	return p.Default
+}
+
+func (p *AddressPolicy) dailyLimit(tokenId types.TokenTypeId) *big.Int {
+	for _, limit := range p.DailyLimits {
+		if limit.TokenId == tokenId {
+			return limit.Amount
+		}
+	}
+	return nil
+}
+
+// storedPolicy is an item of the policies file
+type storedPolicy struct {
+	PrimaryAddr types.Address  `json:"primaryAddr"`
+	Policy      *SigningPolicy `json:"policy"`
+}
+
+// SigningRequest is what an account is going to sign
+type SigningRequest struct {
+	Method  string
+	Address types.Address
+
+	// the fields of the transaction of CreateTransaction
+	ToAddress types.Address
+	TokenId   types.TokenTypeId
+	Amount    *big.Int
+	Data      []byte
+}
+
+// AuditEntry is a line of the audit trail, which records every signing request and whether it's allowed
+type AuditEntry struct {
+	Time         time.Time     `json:"time"`
+	EntropyStore string        `json:"entropyStore"`
+	Method       string        `json:"method"`
+	Address      types.Address `json:"address"`
+
+	ToAddress *types.Address     `json:"toAddress,omitempty"`
+	TokenId   *types.TokenTypeId `json:"tokenId,omitempty"`
+	Amount    string             `json:"amount,omitempty"`
+	Selector  string             `json:"selector,omitempty"`
+
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type spentKey struct {
+	addr    types.Address
+	tokenId types.TokenTypeId
+}
+
+// signingGuard keeps the signing policies of the entropy stores, the amounts spent today and the audit trail
+type signingGuard struct {
+	dir string
+
+	// key is the primary address of the entropy store
+	policies map[types.Address]*SigningPolicy
+
+	spentDay string
+	spent    map[spentKey]*big.Int
+
+	auditFile *os.File
+
+	unlockTimers map[string]*time.Timer
+
+	mu  sync.Mutex
+	log log15.Logger
+}
+
+func newSigningGuard(dataDir string) *signingGuard {
+	return &signingGuard{
+		dir:          filepath.Join(dataDir, signingDirName),
+		policies:     make(map[types.Address]*SigningPolicy),
+		spent:        make(map[spentKey]*big.Int),
+		unlockTimers: make(map[string]*time.Timer),
+		log:          log15.New("module", "wallet/signing"),
+	}
+}
+
+// open loads the policies, rebuilds the amounts spent today from the audit trail and opens it for appending
+func (g *signingGuard) open() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := os.MkdirAll(g.dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(g.dir, policiesFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	policies := make(map[types.Address]*SigningPolicy)
+	if len(data) > 0 {
+		var stored []*storedPolicy
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return errors.Wrap(err, "invalid signing policies")
+		}
+		for _, item := range stored {
+			policies[item.PrimaryAddr] = item.Policy
+		}
+	}
+	g.policies = policies
+
+	auditPath := filepath.Join(g.dir, auditTrailFileName)
+	if err := g.loadSpent(auditPath, time.Now()); err != nil {
+		return err
+	}
+	g.auditFile, err = os.OpenFile(auditPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	return err
+}
+
+func (g *signingGuard) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for path, timer := range g.unlockTimers {
+		timer.Stop()
+		delete(g.unlockTimers, path)
+	}
+	if g.auditFile != nil {
+		g.auditFile.Close()
+		g.auditFile = nil
+	}
+}
+
+func (g *signingGuard) loadSpent(auditPath string, now time.Time) error {
+	g.spentDay = spentDayOf(now)
+	g.spent = make(map[spentKey]*big.Int)
+
+	file, err := os.Open(auditPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := &AuditEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			// a line may be broken by a crash while appending
+			continue
+		}
+		if !entry.Allowed || entry.Method != SigningCreateTransaction || entry.TokenId == nil ||
+			spentDayOf(entry.Time) != g.spentDay {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(entry.Amount, 10)
+		if !ok {
+			continue
+		}
+		g.addSpent(spentKey{entry.Address, *entry.TokenId}, amount)
+	}
+	return scanner.Err()
+}
+
+func (g *signingGuard) addSpent(key spentKey, amount *big.Int) {
+	if spent, ok := g.spent[key]; ok {
+		spent.Add(spent, amount)
+		return
+	}
+	g.spent[key] = new(big.Int).Set(amount)
+}
+
+func (g *signingGuard) getPolicy(primaryAddr types.Address) *SigningPolicy {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.policies[primaryAddr]
+}
+
+func (g *signingGuard) setPolicy(primaryAddr types.Address, policy *SigningPolicy) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	policies := make(map[types.Address]*SigningPolicy, len(g.policies)+1)
+	for addr, p := range g.policies {
+		policies[addr] = p
+	}
+	if policy == nil {
+		delete(policies, primaryAddr)
+	} else {
+		policies[primaryAddr] = policy
+	}
+
+	stored := make([]*storedPolicy, 0, len(policies))
+	for addr, p := range policies {
+		stored = append(stored, &storedPolicy{PrimaryAddr: addr, Policy: p})
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(g.dir, policiesFileName)
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	g.policies = policies
+	return nil
+}
+
+// authorize checks the request against the policy of the entropy store, records it in the audit trail and counts
+// the amount of the allowed transaction into the amount spent today
+func (g *signingGuard) authorize(entropyStore string, primaryAddr types.Address, req *SigningRequest, now time.Time) error {
+	grant, err := g.reserve(entropyStore, primaryAddr, req, now)
+	if err != nil {
+		return err
+	}
+	return grant.commit()
+}
+
+// reserve checks the request against the policy of the entropy store and reserves the amount of the allowed
+// transaction in the amount spent today. The denied request is recorded in the audit trail at once, the allowed
+// one is recorded by the commit or the rollback of the grant returned.
+func (g *signingGuard) reserve(entropyStore string, primaryAddr types.Address, req *SigningRequest, now time.Time) (*signingGrant, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if day := spentDayOf(now); day != g.spentDay {
+		g.spentDay = day
+		g.spent = make(map[spentKey]*big.Int)
+	}
+
+	reason := g.check(g.policies[primaryAddr], req)
+
+	entry := &AuditEntry{
+		Time:         now,
+		EntropyStore: entropyStore,
+		Method:       req.Method,
+		Address:      req.Address,
+		Allowed:      len(reason) == 0,
+		Reason:       reason,
+	}
+	if req.Method == SigningCreateTransaction {
+		toAddr, tokenId := req.ToAddress, req.TokenId
+		entry.ToAddress = &toAddr
+		entry.TokenId = &tokenId
+		if req.Amount != nil {
+			entry.Amount = req.Amount.String()
+		}
+		if len(req.Data) >= selectorSize {
+			entry.Selector = hex.EncodeToString(req.Data[:selectorSize])
+		}
+	}
+
+	if !entry.Allowed {
+		// the request is denied anyway, a failed write is only logged
+		if err := g.writeAudit(entry); err != nil {
+			g.log.Error(fmt.Sprintf("write audit trail failed, err:%v", err), "addr", req.Address)
+		}
+		g.log.Warn("signing denied", "addr", req.Address, "method", req.Method, "reason", reason)
+		return nil, errors.Wrap(ErrSigningDenied, reason)
+	}
+
+	grant := &signingGrant{guard: g, entry: entry, day: g.spentDay}
+	if req.Method == SigningCreateTransaction && req.Amount != nil && req.Amount.Sign() > 0 {
+		grant.key = spentKey{req.Address, req.TokenId}
+		grant.amount = new(big.Int).Set(req.Amount)
+		g.addSpent(grant.key, grant.amount)
+	}
+	return grant, nil
+}
+
+// signingGrant is an allowed request whose signing is in progress, its amount is reserved in the amount spent
+// today until it's committed or rolled back
+type signingGrant struct {
+	guard *signingGuard
+	entry *AuditEntry
+
+	day    string
+	key    spentKey
+	amount *big.Int
+
+	done bool
+}
+
+// commit records the grant as allowed in the audit trail and keeps its amount in the amount spent today. The
+// reservation is released if the grant can't be audited.
+func (grant *signingGrant) commit() error {
+	g := grant.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if grant.done {
+		return errors.New("signing request is finished")
+	}
+	grant.done = true
+	// the request is denied if it can't be audited
+	if err := g.writeAudit(grant.entry); err != nil {
+		g.log.Error(fmt.Sprintf("write audit trail failed, err:%v", err), "addr", grant.entry.Address)
+		grant.release()
+		return errors.Wrap(err, "write audit trail failed")
+	}
+	return nil
+}
+
+// rollback releases the amount reserved by the grant and records the failure in the audit trail
+func (grant *signingGrant) rollback(cause error) {
+	g := grant.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if grant.done {
+		return
+	}
+	grant.done = true
+	grant.release()
+
+	entry := *grant.entry
+	entry.Allowed = false
+	entry.Reason = "signing failed"
+	if cause != nil {
+		entry.Reason = fmt.Sprintf("signing failed: %v", cause)
+	}
+	if err := g.writeAudit(&entry); err != nil {
+		g.log.Error(fmt.Sprintf("write audit trail failed, err:%v", err), "addr", entry.Address)
+	}
+}
+
+// release subtracts the reserved amount from the amount spent today, nothing is done if the day has changed since
+// the reservation. The lock of the guard should be held.
+func (grant *signingGrant) release() {
+	if grant.amount == nil || grant.day != grant.guard.spentDay {
+		return
+	}
+	if spent, ok := grant.guard.spent[grant.key]; ok {
+		spent.Sub(spent, grant.amount)
+		if spent.Sign() <= 0 {
+			delete(grant.guard.spent, grant.key)
+		}
+	}
+}
+
+// hasPolicy returns whether the entropy store has a signing policy
+func (g *signingGuard) hasPolicy(primaryAddr types.Address) bool {
+	return g.getPolicy(primaryAddr) != nil
+}
+
+// check returns the reason if the request is denied by the policy
+func (g *signingGuard) check(policy *SigningPolicy, req *SigningRequest) string {
+	if policy == nil {
+		return ""
+	}
+	if req.Method == SigningSignData {
+		if !policy.AllowSignData {
+			return "signing data is not allowed"
+		}
+		return ""
+	}
+
+	addrPolicy := policy.addressPolicy(req.Address)
+	if addrPolicy == nil {
+		return ""
+	}
+
+	if len(addrPolicy.AllowedRecipients) > 0 {
+		allowed := false
+		for _, recipient := range addrPolicy.AllowedRecipients {
+			if recipient == req.ToAddress {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("recipient %s is not allowed", req.ToAddress)
+		}
+	}
+
+	if len(addrPolicy.AllowedMethods) > 0 && types.IsContractAddr(req.ToAddress) {
+		if len(req.Data) < selectorSize {
+			return fmt.Sprintf("calling %s without a method is not allowed", req.ToAddress)
+		}
+		selector := hex.EncodeToString(req.Data[:selectorSize])
+		allowed := false
+		for _, method := range addrPolicy.AllowedMethods {
+			if strings.EqualFold(method, selector) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("method %s of %s is not allowed", selector, req.ToAddress)
+		}
+	}
+
+	if limit := addrPolicy.dailyLimit(req.TokenId); limit != nil && req.Amount != nil {
+		total := new(big.Int).Set(req.Amount)
+		if spent, ok := g.spent[spentKey{req.Address, req.TokenId}]; ok {
+			total.Add(total, spent)
+		}
+		if total.Cmp(limit) > 0 {
+			return fmt.Sprintf("daily limit %s of %s is exceeded", limit, req.TokenId)
+		}
+	}
+	return ""
+}
+
+func (g *signingGuard) writeAudit(entry *AuditEntry) error {
+	if g.auditFile == nil {
+		return errors.New("audit trail is not opened")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := g.auditFile.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return g.auditFile.Sync()
+}
+
+// onUnlockChanged locks the entropy store after the unlock timeout of its policy
+func (g *signingGuard) onUnlockChanged(event entropystore.UnlockEvent, lock func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if timer, ok := g.unlockTimers[event.EntropyStoreFile]; ok {
+		timer.Stop()
+		delete(g.unlockTimers, event.EntropyStoreFile)
+	}
+	if !event.Unlocked() {
+		return
+	}
+	policy := g.policies[event.PrimaryAddr]
+	if policy == nil || policy.UnlockTimeout == 0 {
+		return
+	}
+	g.unlockTimers[event.EntropyStoreFile] = time.AfterFunc(time.Duration(policy.UnlockTimeout)*time.Second, func() {
+		g.log.Info("lock the entropy store after the unlock timeout", "entropyStore", event.EntropyStoreFile)
+		lock()
+	})
+}
+
+func spentDayOf(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,232 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vitelabs/go-vite/common/config"
+	"github.com/vitelabs/go-vite/common/types"
+	ledger "github.com/vitelabs/go-vite/interfaces/core"
+)
+
+func newTestSigningGuard(t *testing.T) (*signingGuard, func()) {
+	dir, err := ioutil.TempDir("", "signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newSigningGuard(dir)
+	if err := g.open(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return g, func() {
+		g.close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSigningGuard_Authorize(t *testing.T) {
+	g, closeFunc := newTestSigningGuard(t)
+	defer closeFunc()
+
+	primaryAddr := types.Address{1}
+	recipient := types.Address{2}
+	contractAddr := types.AddressQuota
+	err := g.setPolicy(primaryAddr, &SigningPolicy{
+		Default: &AddressPolicy{
+			DailyLimits:       []*SpendLimit{{TokenId: ledger.ViteTokenId, Amount: big.NewInt(100)}},
+			AllowedRecipients: []types.Address{recipient, contractAddr},
+			AllowedMethods:    []string{"0a0b0c0d"},
+		},
+	})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	transfer := func(to types.Address, amount int64, data []byte) error {
+		return g.authorize("store", primaryAddr, &SigningRequest{
+			Method:    SigningCreateTransaction,
+			Address:   primaryAddr,
+			ToAddress: to,
+			TokenId:   ledger.ViteTokenId,
+			Amount:    big.NewInt(amount),
+			Data:      data,
+		}, now)
+	}
+
+	assert.NoError(t, transfer(recipient, 60, nil))
+	// the daily limit is exceeded
+	assert.Equal(t, ErrSigningDenied, errors.Cause(transfer(recipient, 50, nil)))
+	assert.NoError(t, transfer(recipient, 40, nil))
+	// the recipient is not allowed
+	assert.Equal(t, ErrSigningDenied, errors.Cause(transfer(types.Address{3}, 0, nil)))
+	// the method is not allowed
+	assert.Equal(t, ErrSigningDenied, errors.Cause(transfer(contractAddr, 0, []byte{1, 2, 3, 4})))
+	assert.NoError(t, transfer(contractAddr, 0, []byte{10, 11, 12, 13, 1}))
+	// signing data is not allowed by default
+	err = g.authorize("store", primaryAddr, &SigningRequest{Method: SigningSignData, Address: primaryAddr}, now)
+	assert.Equal(t, ErrSigningDenied, errors.Cause(err))
+	// the accounts of the stores without a policy are not restricted
+	err = g.authorize("other", types.Address{4}, &SigningRequest{Method: SigningSignData, Address: types.Address{4}}, now)
+	assert.NoError(t, err)
+
+	// the limit is reset in the next day
+	assert.NoError(t, g.authorize("store", primaryAddr, &SigningRequest{
+		Method:    SigningCreateTransaction,
+		Address:   primaryAddr,
+		ToAddress: recipient,
+		TokenId:   ledger.ViteTokenId,
+		Amount:    big.NewInt(100),
+	}, now.Add(24*time.Hour)))
+}
+
+func TestSigningGuard_Reopen(t *testing.T) {
+	g, closeFunc := newTestSigningGuard(t)
+	defer closeFunc()
+
+	primaryAddr := types.Address{1}
+	policy := &SigningPolicy{
+		Default: &AddressPolicy{
+			DailyLimits: []*SpendLimit{{TokenId: ledger.ViteTokenId, Amount: big.NewInt(100)}},
+		},
+		UnlockTimeout: 60,
+	}
+	assert.NoError(t, g.setPolicy(primaryAddr, policy))
+	req := &SigningRequest{
+		Method:    SigningCreateTransaction,
+		Address:   primaryAddr,
+		ToAddress: types.Address{2},
+		TokenId:   ledger.ViteTokenId,
+		Amount:    big.NewInt(70),
+	}
+	assert.NoError(t, g.authorize("store", primaryAddr, req, time.Now()))
+
+	// the policies and the amounts spent today are loaded again
+	g.close()
+	assert.NoError(t, g.open())
+	assert.Equal(t, policy, g.getPolicy(primaryAddr))
+	assert.Equal(t, ErrSigningDenied, errors.Cause(g.authorize("store", primaryAddr, req, time.Now())))
+}
+
+func TestSigningPolicy_Check(t *testing.T) {
+	policy := &SigningPolicy{Default: &AddressPolicy{AllowedMethods: []string{"0a0b0c"}}}
+	assert.Error(t, policy.check())
+
+	policy = &SigningPolicy{Accounts: []*AccountPolicy{{
+		Address:       types.Address{1},
+		AddressPolicy: AddressPolicy{DailyLimits: []*SpendLimit{{TokenId: ledger.ViteTokenId, Amount: big.NewInt(-1)}}},
+	}}}
+	assert.Error(t, policy.check())
+
+	policy = &SigningPolicy{Accounts: []*AccountPolicy{{Address: types.Address{1}}, {Address: types.Address{1}}}}
+	assert.Error(t, policy.check())
+
+	policy = &SigningPolicy{Default: &AddressPolicy{AllowedMethods: []string{"0A0B0C0D"}}}
+	assert.NoError(t, policy.check())
+}
+
+func TestSigningGuard_Rollback(t *testing.T) {
+	g, closeFunc := newTestSigningGuard(t)
+	defer closeFunc()
+
+	primaryAddr := types.Address{1}
+	assert.NoError(t, g.setPolicy(primaryAddr, &SigningPolicy{
+		Default: &AddressPolicy{
+			DailyLimits: []*SpendLimit{{TokenId: ledger.ViteTokenId, Amount: big.NewInt(100)}},
+		},
+	}))
+	req := &SigningRequest{
+		Method:    SigningCreateTransaction,
+		Address:   primaryAddr,
+		ToAddress: types.Address{2},
+		TokenId:   ledger.ViteTokenId,
+		Amount:    big.NewInt(70),
+	}
+
+	grant, err := g.reserve("store", primaryAddr, req, time.Now())
+	assert.NoError(t, err)
+	// the amount is reserved until the grant is finished
+	_, err = g.reserve("store", primaryAddr, req, time.Now())
+	assert.Equal(t, ErrSigningDenied, errors.Cause(err))
+	grant.rollback(errors.New("generate failed"))
+
+	// the failed request doesn't consume the limit after reopening either
+	g.close()
+	assert.NoError(t, g.open())
+	grant, err = g.reserve("store", primaryAddr, req, time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, grant.commit())
+	assert.Error(t, grant.commit())
+
+	g.close()
+	assert.NoError(t, g.open())
+	assert.Equal(t, ErrSigningDenied, errors.Cause(g.authorize("store", primaryAddr, req, time.Now())))
+}
+
+func TestManager_SigningPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manager := New(&config.Wallet{DataDir: dir})
+	assert.NoError(t, manager.Start())
+	defer manager.Stop()
+	_, em, err := manager.NewMnemonicAndEntropyStore("123456")
+	assert.NoError(t, err)
+	addr := em.GetPrimaryAddr()
+	store := em.GetEntropyStoreFile()
+
+	assert.NoError(t, manager.SetSigningPolicy(store, "123456", &SigningPolicy{
+		Default: &AddressPolicy{
+			DailyLimits: []*SpendLimit{{TokenId: ledger.ViteTokenId, Amount: big.NewInt(100)}},
+		},
+	}))
+	account, err := manager.AccountSearch(&store, addr, "123456")
+	assert.NoError(t, err)
+	msg := []byte("message")
+
+	// the account bound to the policy can't sign unchecked
+	_, _, err = account.Sign(msg)
+	assert.Equal(t, ErrSigningDenied, errors.Cause(err))
+	_, _, err = account.SignData(msg)
+	assert.Equal(t, ErrSigningDenied, errors.Cause(err))
+
+	req := &SigningRequest{ToAddress: types.Address{2}, TokenId: ledger.ViteTokenId, Amount: big.NewInt(70)}
+	session, err := account.NewTransaction(req)
+	assert.NoError(t, err)
+	_, err = account.NewTransaction(req)
+	assert.Equal(t, ErrSigningDenied, errors.Cause(err))
+	_, _, err = session.Sign(msg)
+	assert.NoError(t, err)
+	session.Rollback(errors.New("generate failed"))
+	_, _, err = session.Sign(msg)
+	assert.Error(t, err)
+
+	session, err = account.NewTransaction(req)
+	assert.NoError(t, err)
+	signData, pub, err := session.Sign(msg)
+	assert.NoError(t, err)
+	assert.NoError(t, account.Verify(pub, msg, signData))
+	assert.NoError(t, session.Commit())
+	_, err = account.NewTransaction(req)
+	assert.Equal(t, ErrSigningDenied, errors.Cause(err))
+
+	// the account receiving isn't restricted
+	assert.NoError(t, manager.Unlock(store, "123456"))
+	receiveAccount, err := manager.ReceiveAccount(addr)
+	assert.NoError(t, err)
+	_, _, err = receiveAccount.Sign(msg)
+	assert.NoError(t, err)
+
+	// the accounts are not restricted after the policy is removed
+	assert.NoError(t, manager.SetSigningPolicy(store, "123456", nil))
+	_, _, err = account.Sign(msg)
+	assert.NoError(t, err)
+}